@@ -0,0 +1,172 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/apikeys"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+)
+
+var apiKeyApi string
+
+var apiKeysCmd = &cobra.Command{
+	Use:   "apikeys",
+	Short: "Manage API keys for local APIs",
+	Long: `Manage API keys for local APIs.
+
+APIs configured with require-api-key in nitric.yaml only accept requests with a valid key in the x-api-key header
+when they're run with nitric run or nitric start. No cloud provider applies the api-key-required setting yet, so
+deploying a stack with APIs requiring keys fails rather than leaving them open, see nitric provider capabilities.`,
+	Example: `nitric apikeys create partner-a --api main
+nitric apikeys list
+nitric apikeys revoke partner-a`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if cmd.Root().PersistentPreRun != nil {
+			cmd.Root().PersistentPreRun(cmd, args)
+		}
+	},
+}
+
+var apiKeysCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a new API key",
+	Long:  `Create a new API key, the key is only displayed once.`,
+	Example: `# Create a key for all APIs
+nitric apikeys create partner-a
+
+# Create a key that only grants access to the main API
+nitric apikeys create partner-a --api main`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := loadApiKeys()
+
+		key, err := store.Create(args[0], apiKeyApi)
+		tui.CheckErr(err)
+
+		err = store.Save()
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Created API key %s, store it somewhere safe as it won't be shown again:\n\n%s\n", args[0], key)
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+var apiKeysRevokeCmd = &cobra.Command{
+	Use:     "revoke [name]",
+	Short:   "Revoke an API key",
+	Long:    `Revoke an API key, requests using the key are rejected immediately, including by running local APIs.`,
+	Example: `nitric apikeys revoke partner-a`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := loadApiKeys()
+
+		err := store.Revoke(args[0])
+		tui.CheckErr(err)
+
+		err = store.Save()
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Revoked API key %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+var apiKeysListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List API keys",
+	Long:    `List API keys, including revoked keys.`,
+	Example: `nitric apikeys list`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := loadApiKeys()
+
+		if len(store.Keys) == 0 {
+			fmt.Fprintln(progressOutput, "no api keys found, to create one run `nitric apikeys create`")
+			return
+		}
+
+		nameLength := len("name")
+		apiLength := len("api")
+
+		for _, key := range store.Keys {
+			nameLength = max(nameLength, len(key.Name))
+			apiLength = max(apiLength, len(key.Api))
+		}
+
+		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(nameLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		apiStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(apiLength + 2).PaddingLeft(1)
+		keyStyle := lipgloss.NewStyle().Width(10).PaddingLeft(1)
+		createdStyle := lipgloss.NewStyle().Width(22).PaddingLeft(1)
+		statusStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("name").WithStyle(nameStyle)
+		v.Add("api").WithStyle(apiStyle)
+		v.Add("key").WithStyle(keyStyle)
+		v.Add("created").WithStyle(createdStyle)
+		v.Addln("status").WithStyle(statusStyle)
+		v.Break()
+
+		for _, key := range store.Keys {
+			api := key.Api
+			if api == "" {
+				api = "*"
+			}
+
+			v.Add(key.Name).WithStyle(nameStyle)
+			v.Add(api).WithStyle(apiStyle)
+			v.Add("...%s", key.Hint).WithStyle(keyStyle)
+			v.Add(key.CreatedAt.Local().Format(time.DateTime)).WithStyle(createdStyle)
+
+			if key.Revoked() {
+				v.Addln("revoked %s", key.RevokedAt.Local().Format(time.DateTime)).WithStyle(statusStyle.Copy().Foreground(tui.Colors.Gray))
+			} else {
+				v.Addln("active").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Green))
+			}
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func loadApiKeys() *apikeys.Store {
+	proj, err := project.ConfigurationFromFile(afero.NewOsFs(), "")
+	tui.CheckErr(err)
+
+	store, err := apikeys.Load(proj.Directory)
+	tui.CheckErr(err)
+
+	return store
+}
+
+func init() {
+	apiKeysCreateCmd.Flags().StringVar(&apiKeyApi, "api", "", "name of the API the key grants access to, defaults to all APIs")
+
+	apiKeysCmd.AddCommand(apiKeysCreateCmd)
+	apiKeysCmd.AddCommand(apiKeysRevokeCmd)
+	apiKeysCmd.AddCommand(apiKeysListCmd)
+
+	rootCmd.AddCommand(apiKeysCmd)
+}
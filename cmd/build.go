@@ -17,19 +17,189 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/docker/go-units"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/nitrictech/cli/pkg/exitcode"
 	"github.com/nitrictech/cli/pkg/project"
 	"github.com/nitrictech/cli/pkg/view/tui"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/build"
 	"github.com/nitrictech/cli/pkg/view/tui/teax"
 )
 
+var (
+	reproducibleBuild bool
+	buildBuilder      string
+	buildPlatforms    []string
+	buildNoCache      bool
+	buildSkipDisk     bool
+	buildScanLicenses bool
+)
+
+// applyBuildFlags - overrides the build configuration in nitric.yaml with the --builder and --platform flags
+func applyBuildFlags(proj *project.Project) {
+	if buildBuilder != "" {
+		proj.Build.Builder = buildBuilder
+	}
+
+	if len(buildPlatforms) > 0 {
+		for _, platform := range buildPlatforms {
+			tui.CheckErr(project.ValidatePlatform(platform))
+		}
+
+		proj.Build.Platforms = buildPlatforms
+	}
+}
+
+// buildFlagOptions - returns the build options for the --no-cache flag
+func buildFlagOptions() []project.BuildOption {
+	if buildNoCache {
+		return []project.BuildOption{project.WithoutBuildCache()}
+	}
+
+	return []project.BuildOption{}
+}
+
+// checkBuildDiskSpace - fails before building when the container engine doesn't have the disk space projected for the
+// services that will be rebuilt, rather than part way through the builds, and warns when space is low
+func checkBuildDiskSpace(fs afero.Fs, proj *project.Project, opts ...project.BuildOption) {
+	if buildSkipDisk {
+		return
+	}
+
+	check, ok, err := proj.CheckDiskSpace(fs, opts...)
+	tui.CheckErr(err)
+
+	if !ok || len(check.Services) == 0 {
+		return
+	}
+
+	projected := units.HumanSize(float64(check.Projected))
+	available := units.HumanSize(float64(check.Available))
+
+	if check.Insufficient() {
+		tui.CheckErr(fmt.Errorf("not enough disk space to build %d services, %s is projected to be used but only %s is available to the container engine. "+
+			"Run nitric clean to remove this project's images, or docker system prune to remove unused data, then try again. Use --skip-disk-check to build anyway",
+			len(check.Services), projected, available))
+	}
+
+	if check.Low() {
+		tui.Warning.Printfln("disk space is low, building %d services is projected to use %s of the %s available to the container engine, run nitric clean to free space", len(check.Services), projected, available)
+	}
+}
+
+// addBuildFlags - adds the --builder, --platform, --no-cache and --skip-disk-check flags to commands that build the project's services
+func addBuildFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&buildBuilder, "builder", "", "docker buildx builder or remote BuildKit address to build images on, e.g. tcp://buildkit.example.com:1234, overrides build.builder in nitric.yaml")
+	cmd.Flags().StringSliceVar(&buildPlatforms, "platform", []string{}, "platforms to build images for, e.g. linux/amd64,linux/arm64, overrides build.platforms in nitric.yaml")
+	cmd.Flags().BoolVar(&buildNoCache, "no-cache", false, "rebuild all services, even those unchanged since they were last built, without using cached image layers")
+	cmd.Flags().BoolVar(&buildSkipDisk, "skip-disk-check", false, "build without checking the container engine has enough disk space for the images")
+}
+
+// checkLicenses - scans the built service images for the licenses of their dependencies when enabled, writing the report
+// and failing on packages with denied licenses, or warning when build.licenses.action is warn
+func checkLicenses(fs afero.Fs, proj *project.Project) {
+	config := proj.Build.Licenses
+	if !buildScanLicenses && !config.Enabled() {
+		return
+	}
+
+	if plainOutput() {
+		fmt.Fprintln(progressOutput, "scanning service images for licenses")
+	}
+
+	report, err := proj.ScanLicenses()
+	tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
+	reportFile := config.ReportFile()
+
+	err = report.ToFile(fs, reportFile)
+	tui.CheckErr(err)
+
+	violations := report.Violations()
+	if len(violations) == 0 {
+		tui.Info.Printfln("no denied licenses found in %d service images, the license report was written to %s", len(report.Services), reportFile)
+
+		if unlicensed := report.Unlicensed(); unlicensed > 0 {
+			tui.Warning.Printfln("no license was detected for %d packages, see %s", unlicensed, reportFile)
+		}
+
+		return
+	}
+
+	for _, violation := range violations {
+		tui.Warning.Printfln("%s: %s %s (%s) is licensed under %s", violation.Service, violation.Name, violation.Version, violation.Type, strings.Join(violation.Denied, ", "))
+	}
+
+	if config.Action == project.LicenseActionWarn {
+		tui.Warning.Printfln("%d packages have licenses denied by build.licenses.deny, the license report was written to %s", len(violations), reportFile)
+
+		return
+	}
+
+	tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("%d packages have licenses denied by build.licenses.deny, see %s for the full report. "+
+		"Add packages to build.licenses.ignore-packages to exclude them, or set build.licenses.action to warn", len(violations), reportFile)))
+}
+
+// addLicenseFlags - adds the --scan-licenses flag to commands that build and ship the project's services
+func addLicenseFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&buildScanLicenses, "scan-licenses", false, "scan built images for the licenses of their dependencies with syft, enabled by build.licenses in nitric.yaml")
+}
+
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build a Nitric project",
-	Long:  `Build all services in a nitric project as docker container images`,
+	Long: `Build all services in a nitric project as docker container images
+
+Images are built with the first available container engine, docker, podman or nerdctl. Set container-engines in nitric.yaml,
+or NITRIC_CONTAINER_ENGINE, to change the order engines are detected in, e.g. NITRIC_CONTAINER_ENGINE=podman.
+Builders and multi-platform builds require docker, running services with nitric run requires docker or podman.
+
+Use --reproducible to pin base images to their digests and zero timestamps (or use SOURCE_DATE_EPOCH),
+a build attestation is written to .nitric/build/attestations for each service. Builds of the same commit
+with matching attestation inputs produce matching image IDs.
+
+Images are built on the local docker engine by default. Set build.builder in nitric.yaml, or use --builder, to build on
+an existing docker buildx builder or a remote BuildKit instance, images are loaded into the local docker engine once built.
+
+Images are built for linux/amd64 unless build.platforms is set in nitric.yaml, or --platform is used. Building for more
+than one platform, e.g. linux/amd64 and linux/arm64, requires the containerd image store of the docker engine.
+
+Services are only rebuilt when their build context, dockerfile, build args or build configuration have changed since
+they were last built, the inputs of each build are recorded in .nitric/build/cache.json. Use --no-cache to rebuild
+every service without using cached image layers.
+
+The disk space available to the container engine is checked before services are built, using the size of their previous
+images to project the space needed. Builds that would run out of space fail before they start, use --skip-disk-check to
+build anyway.
+
+Package manager caches, e.g. yarn, pip, maven, gradle, cargo, pub and nuget, are kept between builds in BuildKit cache
+mounts, so dependencies that haven't changed aren't downloaded again when a service is rebuilt. Custom runtimes can do the
+same with RUN --mount=type=cache, e.g. for go RUN --mount=type=cache,target=/go/pkg/mod --mount=type=cache,target=/root/.cache/go-build go build.
+Use docker builder prune --filter type=exec.cachemount to clear them.
+
+Set build.licenses in nitric.yaml, or use --scan-licenses, to scan the built images for the licenses of their dependencies
+with syft (https://github.com/anchore/syft). A report of each package's licenses is written to .nitric/build/licenses.json,
+or build.licenses.report, and packages with licenses matching build.licenses.deny fail the build, or are warned about when
+build.licenses.action is warn.`,
+	Example: `nitric build
+
+# Build on a remote BuildKit instance
+nitric build --builder tcp://buildkit.example.com:1234
+
+# Build multi-architecture images
+nitric build --platform linux/amd64,linux/arm64
+
+# Rebuild all services from scratch
+nitric build --no-cache
+
+# Scan the built images for the licenses of their dependencies
+nitric build --scan-licenses`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// info.Run(cmd.Context())
 		fs := afero.NewOsFs()
@@ -37,16 +207,41 @@ var buildCmd = &cobra.Command{
 		proj, err := project.FromFile(fs, "")
 		tui.CheckErr(err)
 
-		updates, err := proj.BuildServices(fs)
-		tui.CheckErr(err)
+		applyBuildFlags(proj)
+
+		buildOpts := buildFlagOptions()
+		if reproducibleBuild {
+			buildOpts = append(buildOpts, project.WithReproducibleBuild())
+		}
 
-		prog := teax.NewProgram(build.NewModel(updates, "Building Services"))
+		checkBuildDiskSpace(fs, proj, buildOpts...)
+
+		updates, err := proj.BuildServices(fs, buildOpts...)
+		tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
+		teaOptions := []tea.ProgramOption{}
+		if plainOutput() {
+			teaOptions = append(teaOptions, teax.PlainOptions()...)
+		}
+
+		prog := teax.NewProgram(build.NewModel(updates, "Building Services"), teaOptions...)
 		// blocks but quits once the above updates channel is closed by the build process
-		_, err = prog.Run()
+		buildModel, err := prog.Run()
 		tui.CheckErr(err)
+
+		if buildModel.(build.Model).Err != nil {
+			exitCode = exitcode.Build
+
+			return
+		}
+
+		checkLicenses(fs, proj)
 	},
 }
 
 func init() {
+	buildCmd.Flags().BoolVar(&reproducibleBuild, "reproducible", false, "pin base image digests, zero timestamps and record build attestations")
+	addBuildFlags(buildCmd)
+	addLicenseFlags(buildCmd)
 	rootCmd.AddCommand(tui.AddDependencyCheck(buildCmd, tui.Docker, tui.DockerBuildx))
 }
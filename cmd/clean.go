@@ -0,0 +1,126 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/localenv"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var cleanBuildCache bool
+
+// cleanResult - what was removed by nitric clean
+type cleanResult struct {
+	Images          []string `json:"images"`
+	BuildCache      bool     `json:"buildCache"`
+	BuilderPruned   bool     `json:"builderPruned"`
+	WarmPoolCleared bool     `json:"warmPoolCleared"`
+}
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove the images and containers built for your project",
+	Long: `Remove the images and containers built for your project, to free disk space used by the container engine.
+
+The images of the project's services, the stopped containers kept in the warm pool by nitric run and the record of
+previous builds in .nitric/build/cache.json are removed, the next build rebuilds every service. Images labeled with the
+project's name by earlier builds are removed too, including images kept to roll back failed deployments.
+
+Use --build-cache to also prune the cache of the nitric builder, including the package manager caches mounted into
+builds. The build cache is shared by all projects built with the nitric builder.`,
+	Example: `nitric clean
+
+# Also prune the build cache
+nitric clean --build-cache`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		dir, err := filepath.Abs(proj.Directory)
+		tui.CheckErr(err)
+
+		running, err := localenv.List()
+		tui.CheckErr(err)
+
+		if existing, ok := lo.Find(running, func(e localenv.Environment) bool { return e.Directory == dir }); ok {
+			tui.CheckErr(fmt.Errorf("%s is running with nitric %s (pid %d), stop it before cleaning the project", existing.Project, existing.Command, existing.Pid))
+		}
+
+		result := cleanResult{}
+
+		// pooled containers are removed first, images can't be removed while containers use them
+		err = project.ClearWarmPool(proj.Directory)
+		tui.CheckErr(err)
+
+		result.WarmPoolCleared = true
+
+		result.Images, err = proj.RemoveServiceImages()
+		tui.CheckErr(err)
+
+		err = project.RemoveBuildCache(fs)
+		tui.CheckErr(err)
+
+		result.BuildCache = true
+
+		if cleanBuildCache {
+			dockerClient, err := docker.NewBuilder()
+			tui.CheckErr(err)
+
+			err = dockerClient.PruneBuildCache()
+			tui.CheckErr(err)
+
+			result.BuilderPruned = true
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(result))
+
+			return
+		}
+
+		for _, image := range result.Images {
+			tui.Info.Printfln("removed image %s", image)
+		}
+
+		if len(result.Images) == 0 {
+			tui.Info.Printfln("no service images to remove")
+		}
+
+		if result.BuilderPruned {
+			tui.Info.Printfln("pruned the build cache of the nitric builder")
+		}
+
+		tui.Info.Printfln("cleaned project %s", proj.Name)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanBuildCache, "build-cache", false, "also prune the build cache of the nitric builder, shared by all projects")
+	rootCmd.AddCommand(tui.AddDependencyCheck(cleanCmd, tui.Docker))
+}
@@ -0,0 +1,170 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/nitrictech/cli/pkg/cloud"
+	"github.com/nitrictech/cli/pkg/conformance"
+	"github.com/nitrictech/cli/pkg/localcloud"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+)
+
+var (
+	conformanceRemote       string
+	conformanceResourceName string
+)
+
+// runConformance - declares the suite's resources on the nitric server at address and runs the suite against it
+func runConformance(ctx context.Context, address string, resources conformance.Resources) ([]conformance.Result, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	if err := conformance.Declare(ctx, conn, resources); err != nil {
+		return nil, fmt.Errorf("unable to declare conformance resources on %s: %w", address, err)
+	}
+
+	return conformance.Run(ctx, conn, resources), nil
+}
+
+// resultSummary - formats a result for the table output, e.g. OK [a b c]
+func resultSummary(result conformance.Result) string {
+	if len(result.Values) == 0 {
+		return result.Code
+	}
+
+	return fmt.Sprintf("%s [%s]", result.Code, strings.Join(result.Values, " "))
+}
+
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Compare the behavior of the local cloud with a deployed stack",
+	Long: `Run a standard suite of resource operations against the local cloud and a deployed stack, reporting where their behavior differs.
+
+Each case of the suite performs operations on a bucket, key/value store, queue, secret or topic and records the status
+code returned and the values observed, e.g. the order keys are listed in. Error messages are reported but not compared.
+
+The deployed stack is reached through a nitric server connected to its resources, passed with --remote. For example, run
+the runtime of the stack's provider locally with the stack's credentials, or forward the nitric server port of a deployed
+service to this machine. The stack must declare a bucket, key/value store, queue, secret and topic named after --resource-name,
+keys and blobs written by the suite are prefixed with a unique id for each run.
+
+Exits with a non-zero status when any case behaves differently.`,
+	Example: `# Compare with a nitric server for the deployed stack listening on port 50051
+nitric conformance --remote localhost:50051
+
+# Output the full report as json
+nitric conformance --remote localhost:50051 -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		resources := conformance.NewResources(conformanceResourceName)
+
+		runDir, err := os.MkdirTemp("", "nitric-conformance-")
+		tui.CheckErr(err)
+		defer os.RemoveAll(runDir)
+
+		// the local cloud doesn't start a database container, since the suite doesn't use sql databases
+		local, err := localcloud.New("conformance", "conformance", cloud.LocalCloudOptions{
+			RunDir:           runDir,
+			DisableDatabases: true,
+		})
+		tui.CheckErr(err)
+		defer local.Stop()
+
+		fmt.Fprintf(progressOutput, "Running %d conformance cases against the local cloud\n", len(conformance.Cases()))
+
+		localResults, err := runConformance(ctx, local.ServiceAddress(), resources)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Running %d conformance cases against %s\n", len(conformance.Cases()), conformanceRemote)
+
+		remoteResults, err := runConformance(ctx, conformanceRemote, resources)
+		tui.CheckErr(err)
+
+		report := conformance.Report{
+			Resources:   resources,
+			Local:       localResults,
+			Remote:      remoteResults,
+			Differences: conformance.Compare(localResults, remoteResults),
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(report))
+		} else {
+			caseLength := len("case")
+			for _, c := range conformance.Cases() {
+				caseLength = max(caseLength, len(c.Name))
+			}
+
+			caseStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(caseLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+			resultStyle := lipgloss.NewStyle().Width(30).PaddingLeft(1)
+			statusStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+			v := view.New()
+			v.Break()
+			v.Add("case").WithStyle(caseStyle)
+			v.Add("local").WithStyle(resultStyle)
+			v.Add("remote").WithStyle(resultStyle)
+			v.Addln("status").WithStyle(statusStyle)
+			v.Break()
+
+			for i, result := range localResults {
+				remote := remoteResults[i]
+
+				v.Add(result.Case).WithStyle(caseStyle)
+				v.Add(resultSummary(result)).WithStyle(resultStyle)
+				v.Add(resultSummary(remote)).WithStyle(resultStyle)
+
+				if len(conformance.Compare([]conformance.Result{result}, []conformance.Result{remote})) > 0 {
+					v.Addln("differs").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Red))
+				} else {
+					v.Addln("matches").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Green))
+				}
+			}
+
+			fmt.Fprintln(progressOutput, v.Render())
+		}
+
+		if len(report.Differences) > 0 {
+			tui.CheckErr(fmt.Errorf("%d of %d conformance cases behave differently on the local cloud and %s", len(report.Differences), len(localResults), conformanceRemote))
+		}
+
+		fmt.Fprintf(progressOutput, "All %d conformance cases behave the same on the local cloud and %s\n", len(localResults), conformanceRemote)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	conformanceCmd.Flags().StringVar(&conformanceRemote, "remote", "", "address of a nitric server connected to the deployed stack's resources, e.g. localhost:50051")
+	conformanceCmd.Flags().StringVar(&conformanceResourceName, "resource-name", conformance.DefaultResourceName, "name of the bucket, key/value store, queue, secret and topic the suite uses")
+	tui.CheckErr(conformanceCmd.MarkFlagRequired("remote"))
+
+	rootCmd.AddCommand(conformanceCmd)
+}
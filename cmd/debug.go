@@ -19,25 +19,61 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/encoding/protojson"
 
 	"github.com/nitrictech/cli/pkg/collector"
 	"github.com/nitrictech/cli/pkg/env"
+	"github.com/nitrictech/cli/pkg/exitcode"
 	"github.com/nitrictech/cli/pkg/project"
 	"github.com/nitrictech/cli/pkg/view/tui"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/build"
 	"github.com/nitrictech/cli/pkg/view/tui/teax"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
 )
 
 var (
-	debugEnvFile string
-	debugFile    string
+	debugEnvFile     string
+	debugFile        string
+	specSnapshotFile string
+	openApiService   string
+	openApiFormat    string
+	openApiOutDir    string
+
+	// specOutputFile - set when the spec output file was given with the deprecated -o flag
+	specOutputFile bool
 )
 
+// specOutputValue - the spec command's -o flag, a result format or, for older scripts, the spec output file
+type specOutputValue struct{}
+
+func (specOutputValue) String() string {
+	return outputFormat
+}
+
+func (specOutputValue) Set(value string) error {
+	if lo.Contains(outputFormats, value) {
+		outputFormat = value
+
+		return nil
+	}
+
+	debugFile = value
+	specOutputFile = true
+
+	return nil
+}
+
+func (specOutputValue) Type() string {
+	return "string"
+}
+
 var debugCmd = &cobra.Command{
 	Use:     "debug",
 	Short:   "Debug Operations (utilities for debugging nitric applications)",
@@ -50,102 +86,147 @@ var debugCmd = &cobra.Command{
 	},
 }
 
-var specCmd = &cobra.Command{
-	Use:   "spec",
-	Short: "Output the nitric application cloud spec.",
-	Long:  `Output the nitric application cloud spec.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fs := afero.NewOsFs()
+// collectSpec - builds the project's services and collects their requirements into a deployment spec
+func collectSpec(fs afero.Fs, envFile string) *deploymentspb.Spec {
+	proj, err := project.FromFile(fs, "")
+	tui.CheckErr(err)
 
-		proj, err := project.FromFile(fs, "")
-		tui.CheckErr(err)
+	applyBuildFlags(proj)
 
-		// Build the Project's Services (Containers)
-		buildUpdates, err := proj.BuildServices(fs)
-		tui.CheckErr(err)
+	// Build the Project's Services (Containers)
+	checkBuildDiskSpace(fs, proj, buildFlagOptions()...)
+
+	buildUpdates, err := proj.BuildServices(fs, buildFlagOptions()...)
+	tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
+	if plainOutput() {
+		fmt.Fprintln(progressOutput, "building project services")
 
-		if isNonInteractive() {
-			fmt.Println("building project services")
-			for _, service := range proj.GetServices() {
-				fmt.Printf("service matched '%s', auto-naming this service '%s'\n", service.GetFilePath(), service.Name)
+		for _, service := range proj.GetServices() {
+			fmt.Fprintf(progressOutput, "service matched '%s', auto-naming this service '%s'\n", service.GetFilePath(), service.Name)
+		}
+
+		// non-interactive environment
+		buildFailed := false
+
+		for update := range buildUpdates {
+			if update.Status == project.ServiceBuildStatus_Error {
+				buildFailed = true
 			}
 
-			// non-interactive environment
-			for update := range buildUpdates {
-				for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
-					fmt.Printf("%s [%s]: %s\n", update.ServiceName, update.Status, line)
-				}
+			// step names from progress updates are already included in the build logs
+			if update.Progress != nil {
+				continue
 			}
-		} else {
-			prog := teax.NewProgram(build.NewModel(buildUpdates, "Building Services"))
-			// blocks but quits once the above updates channel is closed by the build process
-			buildModel, err := prog.Run()
-			tui.CheckErr(err)
-			if buildModel.(build.Model).Err != nil {
-				tui.CheckErr(fmt.Errorf("error building services"))
+
+			for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
+				fmt.Fprintf(progressOutput, "%s [%s]: %s\n", update.ServiceName, update.Status, line)
 			}
 		}
 
-		// Step 2. Start the collectors and containers (respectively in pairs)
-		// Step 3. Merge requirements from collectors into a specification
-		serviceRequirements, err := proj.CollectServicesRequirements()
+		if buildFailed {
+			tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
+		}
+	} else {
+		prog := teax.NewProgram(build.NewModel(buildUpdates, "Building Services"))
+		// blocks but quits once the above updates channel is closed by the build process
+		buildModel, err := prog.Run()
 		tui.CheckErr(err)
 
-		additionalEnvFiles := []string{}
-
-		if debugEnvFile != "" {
-			additionalEnvFiles = append(additionalEnvFiles, envFile)
+		if buildModel.(build.Model).Err != nil {
+			tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
 		}
+	}
 
-		envVariables, err := env.ReadLocalEnv(additionalEnvFiles...)
-		if err != nil && os.IsNotExist(err) {
-			if !os.IsNotExist(err) {
-				tui.CheckErr(err)
-			}
-			// If it doesn't exist set blank
-			envVariables = map[string]string{}
-		}
+	// Step 2. Start the collectors and containers (respectively in pairs)
+	// Step 3. Merge requirements from collectors into a specification
+	serviceRequirements, err := proj.CollectServicesRequirements()
+	tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
 
-		defaultImageName, ok := proj.DefaultMigrationImage(fs)
-		if !ok {
-			defaultImageName = ""
-		}
+	additionalEnvFiles := []string{}
 
-		migrationImageContexts, err := collector.GetMigrationImageBuildContexts(serviceRequirements, fs)
-		tui.CheckErr(err)
-		// Build images from contexts and provide updates on the builds
+	if envFile != "" {
+		additionalEnvFiles = append(additionalEnvFiles, envFile)
+	}
 
-		if len(migrationImageContexts) > 0 {
-			migrationBuildUpdates, err := project.BuildMigrationImages(fs, migrationImageContexts)
+	envVariables, err := env.ReadLocalEnv(additionalEnvFiles...)
+	if err != nil {
+		if !os.IsNotExist(err) {
 			tui.CheckErr(err)
+		}
+		// If it doesn't exist set blank
+		envVariables = map[string]string{}
+	}
+
+	envVariables = lo.Assign(project.FlagsToEnv(proj.Flags), envVariables)
 
-			if isNonInteractive() {
-				fmt.Println("building project migration images")
-				// non-interactive environment
-				for update := range migrationBuildUpdates {
-					for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
-						fmt.Printf("%s [%s]: %s\n", update.ServiceName, update.Status, line)
-					}
+	defaultImageName, ok := proj.DefaultMigrationImage(fs)
+	if !ok {
+		defaultImageName = ""
+	}
+
+	migrationImageContexts, err := collector.GetMigrationImageBuildContexts(serviceRequirements, fs)
+	tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+	// Build images from contexts and provide updates on the builds
+
+	if len(migrationImageContexts) > 0 {
+		migrationBuildUpdates, err := project.BuildMigrationImages(fs, migrationImageContexts, project.WithBuildConfiguration(proj.Build))
+		tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
+		if plainOutput() {
+			fmt.Fprintln(progressOutput, "building project migration images")
+			// non-interactive environment
+			buildFailed := false
+
+			for update := range migrationBuildUpdates {
+				if update.Status == project.ServiceBuildStatus_Error {
+					buildFailed = true
 				}
-			} else {
-				prog := teax.NewProgram(build.NewModel(migrationBuildUpdates, "Building Database Migrations"))
-				// blocks but quits once the above updates channel is closed by the build process
-				buildModel, err := prog.Run()
-				tui.CheckErr(err)
-				if buildModel.(build.Model).Err != nil {
-					tui.CheckErr(fmt.Errorf("error building services"))
+
+				for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
+					fmt.Fprintf(progressOutput, "%s [%s]: %s\n", update.ServiceName, update.Status, line)
 				}
 			}
+
+			if buildFailed {
+				tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building database migrations")))
+			}
+		} else {
+			prog := teax.NewProgram(build.NewModel(migrationBuildUpdates, "Building Database Migrations"))
+			// blocks but quits once the above updates channel is closed by the build process
+			buildModel, err := prog.Run()
+			tui.CheckErr(err)
+
+			if buildModel.(build.Model).Err != nil {
+				tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
+			}
+		}
+	}
+
+	spec, err := collector.ServiceRequirementsToSpec(proj.Name, envVariables, serviceRequirements, defaultImageName)
+	tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
+
+	return spec
+}
+
+var specCmd = &cobra.Command{
+	Use:   "spec",
+	Short: "Output the nitric application cloud spec.",
+	Long:  `Output the nitric application cloud spec.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if specOutputFile {
+			tui.Warning.Printfln("passing the spec file with -o/--output is deprecated and will be removed in a future release, use -f/--file instead")
 		}
 
+		fs := afero.NewOsFs()
+
+		spec := collectSpec(fs, debugEnvFile)
+
 		outputFile := debugFile
 		if outputFile == "" {
 			outputFile = "./nitric-spec.json"
 		}
 
-		spec, err := collector.ServiceRequirementsToSpec(proj.Name, envVariables, serviceRequirements, defaultImageName)
-		tui.CheckErr(err)
-
 		marshaler := protojson.MarshalOptions{
 			Multiline: true,
 			Indent:    "  ",
@@ -158,14 +239,147 @@ var specCmd = &cobra.Command{
 		err = os.WriteFile(outputFile, specJson, 0o644)
 		tui.CheckErr(err)
 
-		fmt.Printf("Successfully outputted deployment spec to %s\n", outputFile)
+		fmt.Fprintf(progressOutput, "Successfully outputted deployment spec to %s\n", outputFile)
 	},
 	Aliases: []string{"spec"},
 }
 
+var specSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Store a snapshot of the nitric application cloud spec",
+	Long: `Store a canonical snapshot of the nitric application cloud spec.
+
+Commit the snapshot to your repository and run nitric spec verify in CI to catch unexpected changes to resources and permissions.`,
+	Example: `nitric spec snapshot`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		snapshot, err := collector.SpecSnapshot(collectSpec(fs, ""))
+		tui.CheckErr(err)
+
+		err = afero.WriteFile(fs, specSnapshotFile, snapshot, 0o644)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Successfully stored spec snapshot in %s\n", specSnapshotFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var specVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the nitric application cloud spec matches the stored snapshot",
+	Long: `Verify the nitric application cloud spec matches the snapshot stored with nitric spec snapshot.
+
+Exits with a non-zero status and prints the differences if the spec has changed.`,
+	Example: `nitric spec verify`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		expected, err := afero.ReadFile(fs, specSnapshotFile)
+		if os.IsNotExist(err) {
+			tui.CheckErr(fmt.Errorf("spec snapshot %s not found, run nitric spec snapshot to create it", specSnapshotFile))
+		}
+		tui.CheckErr(err)
+
+		actual, err := collector.SpecSnapshot(collectSpec(fs, ""))
+		tui.CheckErr(err)
+
+		diff := collector.DiffSpecSnapshots(specSnapshotFile, expected, "current spec", actual)
+		if diff != "" {
+			fmt.Fprint(progressOutput, diff)
+			tui.CheckErr(fmt.Errorf("spec has changed since the snapshot in %s was taken, run nitric spec snapshot to accept the changes", specSnapshotFile))
+		}
+
+		fmt.Fprintf(progressOutput, "Spec matches snapshot %s\n", specSnapshotFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var specOpenApiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Output the APIs declared by your services as OpenAPI 3 documents",
+	Long: `Output the APIs declared by your services as OpenAPI 3 documents.
+
+Services are built and their API routes and security definitions collected, then a document is written to the
+output directory for each API, e.g. ./openapi/main.json. Publish the documents or use them to generate clients
+without deploying the project.
+
+Use --service to only include the routes handled by one service.`,
+	Example: `nitric spec openapi
+
+# Output the routes handled by the api service as yaml
+nitric spec openapi --service api --format yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !lo.Contains([]string{"json", "yaml"}, openApiFormat) {
+			tui.CheckErr(fmt.Errorf("unsupported format %s, supported formats are json and yaml", openApiFormat))
+		}
+
+		fs := afero.NewOsFs()
+
+		spec := collectSpec(fs, debugEnvFile)
+
+		serviceName := ""
+
+		if openApiService != "" {
+			services := lo.FilterMap(spec.Resources, func(r *deploymentspb.Resource, _ int) (string, bool) {
+				return r.Id.Name, r.GetService() != nil
+			})
+
+			// services can be referred to without the project name prefix, e.g. api for my-project_api
+			name, ok := lo.Find(services, func(name string) bool {
+				return name == openApiService || strings.HasSuffix(name, "_"+openApiService)
+			})
+			if !ok {
+				tui.CheckErr(fmt.Errorf("service %s not found, available services are %s", openApiService, strings.Join(services, ", ")))
+			}
+
+			serviceName = name
+		}
+
+		docs, err := collector.OpenApiDocuments(spec, serviceName)
+		tui.CheckErr(err)
+
+		if len(docs) == 0 {
+			tui.CheckErr(fmt.Errorf("no APIs found, declare an API in your services to output its OpenAPI document"))
+		}
+
+		err = fs.MkdirAll(openApiOutDir, 0o755)
+		tui.CheckErr(err)
+
+		apiNames := lo.Keys(docs)
+		slices.Sort(apiNames)
+
+		for _, apiName := range apiNames {
+			contents, err := collector.MarshalOpenApiDocument(docs[apiName], openApiFormat)
+			tui.CheckErr(err)
+
+			outputFile := filepath.Join(openApiOutDir, fmt.Sprintf("%s.%s", apiName, openApiFormat))
+
+			err = afero.WriteFile(fs, outputFile, contents, 0o644)
+			tui.CheckErr(err)
+
+			fmt.Fprintf(progressOutput, "Successfully outputted OpenAPI document for api %s to %s\n", apiName, outputFile)
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
 func init() {
 	specCmd.Flags().StringVarP(&debugEnvFile, "env-file", "e", "", "--env-file config/.my-env")
-	specCmd.Flags().StringVarP(&debugFile, "output", "o", "", "--file my-example-spec.json")
+	specCmd.Flags().StringVarP(&debugFile, "file", "f", "", "--file my-example-spec.json")
+	// shadows the global output flag so scripts that pass the spec file with -o keep working
+	specCmd.Flags().VarP(specOutputValue{}, "output", "o", "output format of command results, one of table, json or yaml (a file path is deprecated, use --file)")
+
+	specSnapshotCmd.Flags().StringVarP(&specSnapshotFile, "file", "f", collector.DefaultSpecSnapshotFile, "the spec snapshot file")
+	specVerifyCmd.Flags().StringVarP(&specSnapshotFile, "file", "f", collector.DefaultSpecSnapshotFile, "the spec snapshot file")
+	specCmd.AddCommand(specSnapshotCmd)
+	specCmd.AddCommand(specVerifyCmd)
+
+	specOpenApiCmd.Flags().StringVarP(&debugEnvFile, "env-file", "e", "", "--env-file config/.my-env")
+	specOpenApiCmd.Flags().StringVar(&openApiService, "service", "", "only include the routes handled by this service")
+	specOpenApiCmd.Flags().StringVar(&openApiFormat, "format", "json", "format of the documents, one of json or yaml")
+	specOpenApiCmd.Flags().StringVar(&openApiOutDir, "out-dir", "./openapi", "directory the documents are written to")
+	specCmd.AddCommand(specOpenApiCmd)
 
 	// Debug spec
 	debugCmd.AddCommand(specCmd)
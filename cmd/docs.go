@@ -0,0 +1,89 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/docs"
+	"github.com/nitrictech/cli/pkg/pflagx"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	docsFormat     string
+	docsOutputFile string
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate documentation for your project",
+	Long:  `Generate documentation for your project.`,
+	Example: `nitric docs generate
+
+# Output a standalone html page
+nitric docs generate --format html`,
+}
+
+var docsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate an architecture document for your project",
+	Long: `Generate an architecture document for your project.
+
+Services are built and their resource requirements collected, then a document describing the project's services,
+APIs and routes, topics and bucket notifications, schedules, storage and the permissions granted to each service
+is written in markdown or html, ready to commit to your repository or publish.`,
+	Example: `nitric docs generate
+
+# Output a standalone html page
+nitric docs generate --format html --file ./site/architecture.html`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		doc, err := docs.FromSpec(proj.Name, collectSpec(fs, ""))
+		tui.CheckErr(err)
+
+		content, err := doc.Render(docsFormat)
+		tui.CheckErr(err)
+
+		outputFile := docsOutputFile
+		if outputFile == "" {
+			outputFile = docs.DefaultFileNames[docsFormat]
+		}
+
+		err = afero.WriteFile(fs, outputFile, []byte(content), 0o644)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Successfully generated architecture document %s\n", outputFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	docsGenerateCmd.Flags().VarP(pflagx.NewStringEnumVar(&docsFormat, docs.Formats, docs.Format_Markdown), "format", "f", "document format, one of markdown or html")
+	docsGenerateCmd.Flags().StringVar(&docsOutputFile, "file", "", "file to write the document to, defaults to ARCHITECTURE.md or architecture.html")
+
+	docsCmd.AddCommand(tui.AddDependencyCheck(docsGenerateCmd, tui.Docker, tui.DockerBuildx))
+	rootCmd.AddCommand(docsCmd)
+}
@@ -0,0 +1,208 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/secrets"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+const secretsEditHeader = `# Secrets are encrypted for each recipient when saved, recipients are age public keys or ssh public keys.
+# Add the public key of a teammate to share the secrets with them.
+`
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage the project's encrypted environment variables",
+	Long: `Manage the project's encrypted environment variables.
+
+Variables are stored in .nitric/secrets.enc.yaml, encrypted with age for each recipient. The file can be committed,
+each variable is encrypted separately so diffs show which variables changed. Variables are decrypted into the
+environment of nitric run, nitric start and deployments, taking precedence over .env and overridden by --env-file.
+
+Variables are decrypted with the identity in $NITRIC_SECRETS_KEY, the file in $NITRIC_SECRETS_KEY_FILE, the nitric
+secrets key or ~/.ssh/id_ed25519 and ~/.ssh/id_rsa, in that order. Requires age, see https://github.com/FiloSottile/age.
+
+Use nitric secret to set the values of the secret resources declared by your services.`,
+	Example: `nitric env edit`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if cmd.Root().PersistentPreRun != nil {
+			cmd.Root().PersistentPreRun(cmd, args)
+		}
+	},
+}
+
+var envEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the project's encrypted environment variables",
+	Long: `Edit the project's encrypted environment variables.
+
+The variables are decrypted into a temporary file and opened with $VISUAL or $EDITOR, then encrypted again when the
+editor is closed. An age identity is created when none is found, the file is created on first edit.`,
+	Example: `nitric env edit
+
+# Edit with a different editor
+EDITOR="code --wait" nitric env edit`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		if isNonInteractive() {
+			tui.CheckErr(fmt.Errorf("nitric env edit requires an interactive terminal"))
+		}
+
+		proj, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		secretsPath := paths.NitricSecretsFile(proj.Directory)
+
+		secretsFile, err := secrets.FromFile(fs, secretsPath)
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		identityFile, cleanup, err := secrets.IdentityFile()
+		if errors.Is(err, secrets.ErrNoIdentity) && secretsFile == nil {
+			identityFile, err = secrets.GenerateIdentity()
+			tui.CheckErr(err)
+
+			cleanup = func() {}
+
+			tui.Info.Printfln("Created an age identity at %s, back it up to keep access to the secrets", identityFile)
+		}
+
+		tui.CheckErr(err)
+		defer cleanup()
+
+		var previous *secrets.Plaintext
+
+		if secretsFile != nil {
+			previous, err = secretsFile.Decrypt(identityFile)
+			tui.CheckErr(err)
+		} else {
+			publicKey, err := secrets.PublicKey(identityFile)
+			tui.CheckErr(err)
+
+			secretsFile = &secrets.File{Secrets: map[string]string{}}
+			previous = &secrets.Plaintext{Recipients: []string{publicKey}, Secrets: map[string]string{}}
+		}
+
+		edited, err := editSecrets(previous)
+		tui.CheckErr(err)
+
+		if edited == nil {
+			fmt.Fprintln(progressOutput, "No changes made to the secrets")
+			return
+		}
+
+		err = edited.Validate()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = secretsFile.Encrypt(previous, edited)
+		tui.CheckErr(err)
+
+		err = fs.MkdirAll(filepath.Dir(secretsPath), os.ModePerm)
+		tui.CheckErr(err)
+
+		err = secretsFile.Save(fs, secretsPath)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Saved %d secret(s) for %d recipient(s) to %s\n", len(edited.Secrets), len(edited.Recipients), secretsPath)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// editSecrets - opens the decrypted secrets in the user's editor, returning nil when they weren't changed
+func editSecrets(previous *secrets.Plaintext) (*secrets.Plaintext, error) {
+	contents, err := secrets.Marshal(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	original := append([]byte(secretsEditHeader), contents...)
+
+	// the plaintext is only readable by the current user and removed once the editor is closed
+	tmpFile, err := os.CreateTemp("", "nitric-secrets-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(original)
+	tmpFile.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := openEditor(tmpFile.Name()); err != nil {
+		return nil, err
+	}
+
+	updated, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(original, updated) {
+		return nil, nil
+	}
+
+	edited := &secrets.Plaintext{}
+	if err := yaml.Unmarshal(updated, edited); err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("unable to parse the edited secrets, no changes were saved: %w", err))
+	}
+
+	if edited.Secrets == nil {
+		edited.Secrets = map[string]string{}
+	}
+
+	return edited, nil
+}
+
+// secretsCmd - the previous name of nitric env, kept so existing scripts keep working
+var secretsCmd = &cobra.Command{
+	Use:        "secrets",
+	Hidden:     true,
+	Deprecated: "use nitric env, nitric secret sets the values of secret resources",
+	Args:       cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] != "edit" {
+			tui.CheckErr(fmt.Errorf("unknown command %s, run nitric env edit", args[0]))
+		}
+
+		envEditCmd.PreRun(cmd, nil)
+		envEditCmd.Run(cmd, nil)
+	},
+}
+
+func init() {
+	envCmd.AddCommand(tui.AddDependencyCheck(envEditCmd, tui.Age))
+
+	rootCmd.AddCommand(envCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
@@ -0,0 +1,99 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/version"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	exportComposeFile     string
+	exportComposeCliImage string
+	exportComposeEnvFiles []string
+)
+
+var releaseVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+
+// defaultCliImage - the published image of this version of the CLI, or the latest image for development builds
+func defaultCliImage() string {
+	if releaseVersion.MatchString(version.Version) {
+		return fmt.Sprintf("nitrictech/cli:%s", version.Version)
+	}
+
+	return "nitrictech/cli:latest"
+}
+
+var exportCmd = &cobra.Command{
+	Use:     "export",
+	Short:   "Export your project to run with other tools",
+	Long:    `Export your project to run with other tools.`,
+	Example: `nitric export compose`,
+}
+
+var exportComposeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Export your project as a docker compose file",
+	Long: `Export your project as a docker compose file, so it can be run with docker compose up by developers without the nitric CLI.
+
+Each service is built from its generated dockerfile, written to the nitric-compose directory next to the compose file.
+The local cloud runs in a container of the nitric CLI image with nitric local serve, serving buckets, key value stores,
+queues, topics, secrets and the project's APIs on ports from 4000. SQL databases are created on a postgres container.
+The project directory is mounted into the local cloud container, local state is kept in .nitric/run as it is by nitric run.
+
+Services receive the .env file and any --env-file given. Export the project again when services are added or removed.
+
+Services using http servers and SQL databases with migrations aren't supported.`,
+	Example: `nitric export compose
+
+# Write the compose file to another directory
+nitric export compose --file deploy/local/docker-compose.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		written, err := proj.ExportCompose(fs, exportComposeFile, project.ComposeOptions{
+			CliImage: exportComposeCliImage,
+			EnvFiles: exportComposeEnvFiles,
+		})
+		tui.CheckErr(err)
+
+		for _, file := range written {
+			fmt.Fprintf(progressOutput, "Wrote %s\n", file)
+		}
+
+		fmt.Fprintf(progressOutput, "Run the project with docker compose -f %s up --build\n", exportComposeFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	exportComposeCmd.Flags().StringVarP(&exportComposeFile, "file", "f", "docker-compose.yaml", "the compose file to write")
+	exportComposeCmd.Flags().StringVar(&exportComposeCliImage, "cli-image", defaultCliImage(), "image of the nitric CLI that runs the local cloud")
+	exportComposeCmd.Flags().StringArrayVarP(&exportComposeEnvFiles, "env-file", "e", []string{}, "additional env files for the services, e.g. --env-file config/.my-env")
+	exportCmd.AddCommand(exportComposeCmd)
+	rootCmd.AddCommand(exportCmd)
+}
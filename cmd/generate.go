@@ -0,0 +1,104 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/generate"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	generateLanguages []string
+	generateOutDir    string
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate typed accessors for the resources declared by your services",
+	Long: `Generate typed accessors for the resources declared by your services.
+
+Services are built and their resource requirements collected, then constants for the names of buckets, topics,
+queues, key value stores, secrets, databases, websockets, schedules and API routes are written to the output
+directory. Reference resources by these constants instead of strings to catch typos at build time.
+
+Languages are detected from your service entrypoints unless specified with --lang.`,
+	Example: `nitric generate
+
+# Generate TypeScript and Python accessors into ./src/generated
+nitric generate --lang typescript --lang python --out-dir ./src/generated`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		languages := generateLanguages
+
+		if len(languages) == 0 {
+			proj, err := project.FromFile(fs, "")
+			tui.CheckErr(err)
+
+			languages = lo.Uniq(lo.FilterMap(proj.GetServices(), func(service project.Service, _ int) (string, bool) {
+				language := generate.LanguageFromExtension(filepath.Ext(service.GetFilePath()))
+				return language, language != ""
+			}))
+
+			if len(languages) == 0 {
+				tui.CheckErr(fmt.Errorf("unable to detect the language of your services, specify one or more with --lang"))
+			}
+		}
+
+		for _, language := range languages {
+			if !slices.Contains(generate.Languages, language) {
+				tui.CheckErr(fmt.Errorf("unsupported language %s, expected one of %s", language, strings.Join(generate.Languages, ", ")))
+			}
+		}
+
+		resources, err := generate.FromSpec(collectSpec(fs, ""))
+		tui.CheckErr(err)
+
+		for _, language := range languages {
+			source, err := generate.Generate(language, resources)
+			tui.CheckErr(err)
+
+			outFile := filepath.Join(generateOutDir, generate.FileNames[language])
+
+			err = fs.MkdirAll(filepath.Dir(outFile), 0o755)
+			tui.CheckErr(err)
+
+			err = afero.WriteFile(fs, outFile, source, 0o644)
+			tui.CheckErr(err)
+
+			fmt.Fprintf(progressOutput, "Generated %s resource accessors in %s\n", language, outFile)
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	generateCmd.Flags().StringArrayVar(&generateLanguages, "lang", []string{}, fmt.Sprintf("language to generate accessors for, one of %s, may be repeated", strings.Join(generate.Languages, ", ")))
+	generateCmd.Flags().StringVar(&generateOutDir, "out-dir", "./generated", "directory the generated accessors are written to")
+
+	rootCmd.AddCommand(tui.AddDependencyCheck(generateCmd, tui.Docker, tui.DockerBuildx))
+}
@@ -0,0 +1,235 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/go-units"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/project/stack"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+)
+
+// imageDeployment - a recorded deployment of a stack that used an image
+type imageDeployment struct {
+	Stack      string `json:"stack"`
+	Deployment string `json:"deployment"`
+	// True when the deployment is the stack's latest successful deployment, i.e. the image is deployed
+	Current bool `json:"current"`
+}
+
+// imageListEntry - a local image of a service and the recorded deployments that used it
+type imageListEntry struct {
+	project.ServiceImage
+	Deployments []imageDeployment `json:"deployments"`
+}
+
+// imageDeployments - returns the deployments recorded on this machine for each image ID, across the project's stacks,
+// along with the commit each image was deployed from
+func imageDeployments(fs afero.Fs, projectName string) (map[string][]imageDeployment, map[string]string, error) {
+	deployments := map[string][]imageDeployment{}
+	commits := map[string]string{}
+
+	stackFiles, err := stack.GetAllStackFiles(fs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, stackFile := range stackFiles {
+		stackName, err := stack.GetStackNameFromFileName(stackFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		history, err := digest.History(projectName, stackName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		current, _ := lo.Find(history, func(d *digest.Digest) bool { return d.Success })
+
+		for _, d := range history {
+			for _, id := range lo.Uniq(lo.Values(d.Images)) {
+				deployments[id] = append(deployments[id], imageDeployment{Stack: stackName, Deployment: d.ID(), Current: d == current})
+
+				if _, ok := commits[id]; !ok && d.Commit != "" {
+					commits[id] = d.Commit
+				}
+			}
+		}
+	}
+
+	return deployments, commits, nil
+}
+
+// imageTags - the tags of an image without its repository, e.g. my-service:latest is latest
+func imageTags(references []string) []string {
+	return lo.Map(references, func(ref string, _ int) string {
+		return ref[strings.LastIndex(ref, ":")+1:]
+	})
+}
+
+var imagesCmd = &cobra.Command{
+	Use:     "images",
+	Short:   "Manage the images built for your project's services",
+	Long:    `Manage the images built for your project's services.`,
+	Example: `nitric images list`,
+}
+
+var imagesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the images built for your project's services",
+	Long: `List the local images built for your project's services, including earlier builds and images kept for rollbacks.
+Images are found by the repository of each service's image name, and by the dev.nitric.project label set on every image
+nitric builds, so builds of removed services and builds named with an earlier image name template are listed too.
+
+Each image is shown with its tags, size, creation time and the git commit it was built from, read from the
+org.opencontainers.image.revision label of the image, or the commit of a deployment that used it.
+The stacks column lists the stacks whose deployments from this machine used the image, marked current when the image
+is part of the stack's latest successful deployment. Images no longer used by any stack can be removed with
+docker image rm, nitric clean removes every image of the project.`,
+	Example: `nitric images list
+
+# Output machine readable JSON
+nitric images ls -o json`,
+	Aliases: []string{"ls"},
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		images, err := proj.ServiceImages()
+		tui.CheckErr(err)
+
+		deployments, commits, err := imageDeployments(fs, proj.Name)
+		tui.CheckErr(err)
+
+		entries := lo.Map(images, func(image project.ServiceImage, _ int) imageListEntry {
+			if image.Commit == "" {
+				image.Commit = commits[image.ID]
+			}
+
+			return imageListEntry{ServiceImage: image, Deployments: lo.Ternary(deployments[image.ID] != nil, deployments[image.ID], []imageDeployment{})}
+		})
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(entries))
+
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintf(progressOutput, "No images have been built for %s, run nitric build to build them\n", proj.Name)
+
+			return
+		}
+
+		serviceLength := lo.Max(append(lo.Map(entries, func(e imageListEntry, _ int) int { return len(e.Service) }), len("service")))
+		tagsLength := lo.Max(append(lo.Map(entries, func(e imageListEntry, _ int) int { return len(strings.Join(imageTags(e.Tags), ", ")) }), len("tags")))
+
+		serviceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(serviceLength + 1).PaddingRight(1)
+		tagsStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(tagsLength + 2).PaddingLeft(1)
+		idStyle := lipgloss.NewStyle().Width(14).PaddingLeft(1)
+		sizeStyle := lipgloss.NewStyle().Width(10).PaddingLeft(1)
+		createdStyle := lipgloss.NewStyle().Width(21).PaddingLeft(1)
+		commitStyle := lipgloss.NewStyle().Width(9).PaddingLeft(1)
+		stacksStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("service").WithStyle(serviceStyle)
+		v.Add("tags").WithStyle(tagsStyle)
+		v.Add("image id").WithStyle(idStyle)
+		v.Add("size").WithStyle(sizeStyle)
+		v.Add("created").WithStyle(createdStyle)
+		v.Add("commit").WithStyle(commitStyle)
+		v.Addln("stacks").WithStyle(stacksStyle)
+		v.Break()
+
+		for _, e := range entries {
+			stacks := lo.Uniq(lo.Map(e.Deployments, func(d imageDeployment, _ int) string {
+				return d.Stack + lo.Ternary(lo.ContainsBy(e.Deployments, func(other imageDeployment) bool { return other.Stack == d.Stack && other.Current }), " (current)", "")
+			}))
+
+			v.Add("%s", lo.Ternary(e.Service != "", e.Service, "-")).WithStyle(serviceStyle)
+			v.Add("%s", lo.Ternary(len(e.Tags) > 0, strings.Join(imageTags(e.Tags), ", "), "<none>")).WithStyle(tagsStyle.Copy().Faint(!e.Current))
+			v.Add("%s", shortImageId(e.ID)).WithStyle(idStyle)
+			v.Add("%s", units.HumanSize(float64(e.Size))).WithStyle(sizeStyle)
+			v.Add("%s", e.Created.Local().Format(time.DateTime)).WithStyle(createdStyle)
+			v.Add("%s", lo.Ternary(e.Commit != "", e.Commit[:min(7, len(e.Commit))], "-")).WithStyle(commitStyle)
+			v.Addln("%s", lo.Ternary(len(stacks) > 0, strings.Join(stacks, ", "), "-")).WithStyle(stacksStyle.Copy().Foreground(lo.Ternary(len(stacks) > 0, tui.Colors.Green, tui.Colors.Gray)))
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	imagesCmd.AddCommand(tui.AddDependencyCheck(imagesListCmd, tui.Docker))
+	rootCmd.AddCommand(imagesCmd)
+}
+
+// shortImageId - returns the first 12 characters of an image ID, as shown by docker images
+func shortImageId(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+
+	return id[:min(12, len(id))]
+}
+
+// describeDeployedImage - describes an image recorded in a deployment digest using its labels, when it's still available locally
+func describeDeployedImage(id string) string {
+	shortId := shortImageId(id)
+
+	dockerClient, err := docker.NewBuilder()
+	if err != nil {
+		return shortId
+	}
+
+	image, err := dockerClient.InspectImage(id)
+	if err != nil {
+		return fmt.Sprintf("%s (no longer available locally)", shortId)
+	}
+
+	details := []string{shortId}
+
+	if commit := image.Labels[project.ImageRevisionLabel]; commit != "" {
+		details = append(details, "commit "+commit)
+	}
+
+	if created := image.Labels[project.ImageCreatedLabel]; created != "" {
+		details = append(details, "built "+created)
+	}
+
+	if cliVersion := image.Labels[project.ImageCliVersionLabel]; cliVersion != "" {
+		details = append(details, "by nitric "+cliVersion)
+	}
+
+	return strings.Join(details, ", ")
+}
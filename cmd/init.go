@@ -0,0 +1,140 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	initFromExisting bool
+	initConfirm      bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init --from-existing",
+	Short: "Create a nitric.yaml for an existing codebase",
+	Long: `Create a nitric.yaml for an existing codebase.
+
+Scans the current directory for service entrypoints using the nitric SDK and proposes service match patterns,
+start commands and runtimes for them. Detected services are added to the services already in nitric.yaml, which keep their
+settings, and the file's comments are kept. The changes to nitric.yaml are shown for review before they're written.`,
+	Example: `nitric init --from-existing
+
+# Write the proposed nitric.yaml without being prompted
+nitric init --from-existing -y`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !initFromExisting {
+			tui.CheckErr(fmt.Errorf("nitric init requires --from-existing, to create a new project from a template run nitric new"))
+		}
+
+		fs := afero.NewOsFs()
+
+		currentDir, err := os.Getwd()
+		tui.CheckErr(err)
+
+		existingYaml, err := afero.ReadFile(fs, "nitric.yaml")
+		if err != nil && !os.IsNotExist(err) {
+			tui.CheckErr(err)
+		}
+
+		detected, err := project.DetectServices(fs, ".")
+		tui.CheckErr(err)
+
+		if len(detected) == 0 {
+			tui.CheckErr(fmt.Errorf("no service entrypoints using the nitric SDK were found in %s", currentDir))
+		}
+
+		fmt.Fprintln(progressOutput, "Detected services:")
+
+		for _, d := range detected {
+			fmt.Fprintf(progressOutput, "  %s (%s): %s\n", d.Match, d.Language, strings.Join(d.Entrypoints, ", "))
+		}
+
+		fmt.Fprintln(progressOutput)
+
+		var proposedYaml []byte
+
+		// an existing nitric.yaml is edited in place, keeping its comments and settings
+		if len(existingYaml) > 0 {
+			proposedYaml, err = project.ProposeConfigurationFile(existingYaml, detected)
+		} else {
+			proposedYaml, err = yaml.Marshal(project.ProposeConfiguration(project.ProjectConfiguration{Name: filepath.Base(currentDir)}, detected))
+		}
+
+		tui.CheckErr(err)
+
+		proposed := project.ProjectConfiguration{}
+		tui.CheckErr(yaml.Unmarshal(proposedYaml, &proposed))
+
+		if string(existingYaml) == string(proposedYaml) {
+			fmt.Fprintln(progressOutput, "nitric.yaml is already up to date")
+			return
+		}
+
+		edits := myers.ComputeEdits(span.URIFromPath("nitric.yaml"), string(existingYaml), string(proposedYaml))
+		fmt.Fprint(progressOutput, gotextdiff.ToUnified("nitric.yaml", "nitric.yaml (proposed)", string(existingYaml), edits))
+		fmt.Fprintln(progressOutput)
+
+		if !initConfirm {
+			if isNonInteractive() {
+				tui.CheckErr(fmt.Errorf("writing nitric.yaml requires confirmation, use -y to confirm"))
+			}
+
+			_ = tui.AskOne(&survey.Confirm{
+				Message: "Write these changes to nitric.yaml?",
+				Default: true,
+			}, &initConfirm)
+
+			if !initConfirm {
+				return
+			}
+		}
+
+		err = afero.WriteFile(fs, "nitric.yaml", proposedYaml, os.ModePerm)
+		tui.CheckErr(err)
+
+		fmt.Fprintln(progressOutput, "Successfully wrote nitric.yaml")
+
+		for name, runtime := range proposed.Runtimes {
+			if _, err := fs.Stat(runtime.Dockerfile); os.IsNotExist(err) {
+				tui.Warning.Printfln("runtime %s builds services with %s, which doesn't exist yet. See https://nitric.io/docs/reference/custom-containers", name, runtime.Dockerfile)
+			}
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initFromExisting, "from-existing", false, "detect services in the existing code in the current directory")
+	initCmd.Flags().BoolVarP(&initConfirm, "yes", "y", false, "write nitric.yaml without confirmation")
+	rootCmd.AddCommand(initCmd)
+}
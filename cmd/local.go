@@ -0,0 +1,634 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/docker/go-units"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/apikeys"
+	"github.com/nitrictech/cli/pkg/certs"
+	"github.com/nitrictech/cli/pkg/cloud"
+	"github.com/nitrictech/cli/pkg/cloud/env"
+	"github.com/nitrictech/cli/pkg/cloud/sql"
+	"github.com/nitrictech/cli/pkg/dashboard"
+	"github.com/nitrictech/cli/pkg/localenv"
+	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/system"
+	"github.com/nitrictech/cli/pkg/tunnel"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+)
+
+// localEnvironmentStatus - a running local environment and the endpoints it's serving
+type localEnvironmentStatus struct {
+	localenv.Environment
+	Endpoints []tunnel.Endpoint `json:"endpoints"`
+}
+
+var localCmd = &cobra.Command{
+	Use:     "local",
+	Short:   "Manage local environments started by nitric run and nitric start",
+	Long:    `Manage local environments started by nitric run and nitric start.`,
+	Example: `nitric local ps`,
+}
+
+var localPsCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List the running local environments of all projects",
+	Long: `List the running local environments of all projects, started with nitric run or nitric start.
+
+Each environment has a namespace naming its containers and volumes. This is the project name, unless a project with
+the same name was already running from another directory when the environment started.
+The JSON output includes the token required by the dashboard api of each environment as dashboardToken.`,
+	Example: `nitric local ps
+
+# Output machine readable JSON
+nitric local ps -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		environments, err := localenv.List()
+		tui.CheckErr(err)
+
+		statuses := lo.Map(environments, func(env localenv.Environment, _ int) localEnvironmentStatus {
+			// environments that haven't started serving yet have no endpoints
+			endpoints, _ := tunnel.ReadEndpoints(env.Directory)
+
+			return localEnvironmentStatus{Environment: env, Endpoints: lo.Ternary(endpoints != nil, endpoints, []tunnel.Endpoint{})}
+		})
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(statuses))
+
+			return
+		}
+
+		if len(statuses) == 0 {
+			tui.Info.Printfln("No local environments are running, start one with nitric run or nitric start")
+			return
+		}
+
+		namespaceLength := len("namespace")
+		for _, s := range statuses {
+			namespaceLength = max(namespaceLength, len(s.Namespace))
+		}
+
+		namespaceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(namespaceLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		commandStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(9).PaddingLeft(1)
+		pidStyle := lipgloss.NewStyle().Width(9).PaddingLeft(1)
+		startedStyle := lipgloss.NewStyle().Width(22).PaddingLeft(1)
+		dashboardStyle := lipgloss.NewStyle().Width(25).PaddingLeft(1)
+		directoryStyle := lipgloss.NewStyle().PaddingLeft(1)
+		endpointStyle := lipgloss.NewStyle().Foreground(tui.Colors.Gray).PaddingLeft(namespaceLength + 4)
+
+		v := view.New()
+		v.Break()
+		v.Add("namespace").WithStyle(namespaceStyle)
+		v.Add("command").WithStyle(commandStyle)
+		v.Add("pid").WithStyle(pidStyle)
+		v.Add("started").WithStyle(startedStyle)
+		v.Add("dashboard").WithStyle(dashboardStyle)
+		v.Addln("directory").WithStyle(directoryStyle)
+		v.Break()
+
+		for _, s := range statuses {
+			v.Add(s.Namespace).WithStyle(namespaceStyle)
+			v.Add(s.Command).WithStyle(commandStyle)
+			v.Add("%d", s.Pid).WithStyle(pidStyle)
+			v.Add(s.StartedAt.Local().Format(time.DateTime)).WithStyle(startedStyle)
+			v.Add(lo.Ternary(s.Dashboard != "", s.Dashboard, "-")).WithStyle(dashboardStyle)
+			v.Addln(s.Directory).WithStyle(directoryStyle)
+
+			for _, endpoint := range s.Endpoints {
+				v.Addln("%s/%s %s", endpoint.Type, endpoint.Name, endpoint.Url).WithStyle(endpointStyle)
+			}
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// runningEnvironment - returns the local environment running the project, its dashboard serves the local cloud's API
+func runningEnvironment(proj *project.Project) (*localenv.Environment, error) {
+	dir, err := filepath.Abs(proj.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := localenv.List()
+	if err != nil {
+		return nil, err
+	}
+
+	environment, ok := lo.Find(running, func(e localenv.Environment) bool { return e.Directory == dir })
+	if !ok || environment.Dashboard == "" {
+		return nil, fmt.Errorf("%s isn't running, start it with nitric run or nitric start", proj.Name)
+	}
+
+	return &environment, nil
+}
+
+var localUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show the resources the project's services have called while running locally",
+	Long: `Show the resources the project's services have called while running locally with nitric run or nitric start,
+compared to the resources and permissions the services declare.
+
+Calls to resources a service hasn't declared it uses are listed as undeclared, these calls would be denied once the
+project is deployed. Declared permissions that haven't been used are listed as unused, these may no longer be needed.
+The same comparison is shown in the architecture view of the local dashboard.`,
+	Example: `nitric local usage
+
+# Output machine readable JSON
+nitric local usage -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		environment, err := runningEnvironment(proj)
+		tui.CheckErr(err)
+
+		resp, err := environment.DashboardRequest(http.MethodGet, "/api/v1/usage", "", nil)
+		tui.CheckErr(err)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			tui.CheckErr(fmt.Errorf("unable to fetch usage from the local dashboard: %s", resp.Status))
+		}
+
+		usage := &dashboard.UsageResponse{}
+		err = json.NewDecoder(resp.Body).Decode(usage)
+		tui.CheckErr(err)
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(usage))
+
+			return
+		}
+
+		if len(usage.Observed) == 0 && len(usage.Unused) == 0 {
+			tui.Info.Printfln("No resources have been called by %s's services yet", proj.Name)
+			return
+		}
+
+		statusStyle := lipgloss.NewStyle().Width(12)
+		serviceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).PaddingRight(1)
+		countStyle := lipgloss.NewStyle().Foreground(tui.Colors.Gray).PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+
+		for _, edge := range usage.Observed {
+			if edge.Declared {
+				v.Add("declared").WithStyle(statusStyle.Foreground(tui.Colors.Green))
+			} else {
+				v.Add("undeclared").WithStyle(statusStyle.Foreground(tui.Colors.Red))
+			}
+
+			v.Add(edge.Service).WithStyle(serviceStyle)
+			v.Add("%s %s/%s", lo.Ternary(edge.Action != "", edge.Action, "Connect"), edge.Type, edge.Resource)
+			v.Addln("%d calls", edge.Count).WithStyle(countStyle)
+		}
+
+		for _, permission := range usage.Unused {
+			v.Add("unused").WithStyle(statusStyle.Foreground(tui.Colors.Gray))
+			v.Add(permission.Service).WithStyle(serviceStyle)
+			v.Addln("%s %s/%s", permission.Action, permission.Type, permission.Resource)
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var localExportOut string
+
+var localExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the state of the project's local resources to a snapshot file",
+	Long: `Export the state of the project's local resources to a snapshot file, for reproducing the same environment on
+a teammate's machine or attaching it to a bug report.
+
+The snapshot is a gzipped tar of the files in buckets, the key/value stores, the messages in queues and a SQL dump of
+each database. Secrets aren't included. The project must be running with nitric run or nitric start.`,
+	Example: `nitric local export
+
+# Write the snapshot to a specific file
+nitric local export --out snapshot.tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		environment, err := runningEnvironment(proj)
+		tui.CheckErr(err)
+
+		resp, err := environment.DashboardRequest(http.MethodGet, "/api/v1/snapshot", "", nil)
+		tui.CheckErr(err)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			tui.CheckErr(fmt.Errorf("unable to export the local resources: %s", apiErrorMessage(resp)))
+		}
+
+		out := localExportOut
+		if out == "" {
+			out = fmt.Sprintf("%s-%s.tar.gz", proj.Name, time.Now().Format("20060102-150405"))
+		}
+
+		f, err := fs.Create(out)
+		tui.CheckErr(err)
+
+		size, err := io.Copy(f, resp.Body)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+
+		tui.CheckErr(err)
+
+		tui.Info.Printfln("Exported the local resources of %s to %s (%s)", proj.Name, out, units.HumanSize(float64(size)))
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var localImportCmd = &cobra.Command{
+	Use:   "import <snapshot>",
+	Short: "Import a snapshot file written by nitric local export",
+	Long: `Import a snapshot file written by nitric local export into the project's running local environment.
+
+The contents of the buckets, key/value stores, queues and databases in the snapshot replace their current contents,
+resources that aren't in the snapshot are left unchanged. The project must be running with nitric run or nitric start.`,
+	Example: `nitric local import snapshot.tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		environment, err := runningEnvironment(proj)
+		tui.CheckErr(err)
+
+		f, err := fs.Open(args[0])
+		tui.CheckErr(err)
+		defer f.Close()
+
+		resp, err := environment.DashboardRequest(http.MethodPost, "/api/v1/snapshot", "application/gzip", f)
+		tui.CheckErr(err)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			tui.CheckErr(fmt.Errorf("unable to import %s: %s", args[0], apiErrorMessage(resp)))
+		}
+
+		manifest := &cloud.SnapshotManifest{}
+		err = json.NewDecoder(resp.Body).Decode(manifest)
+		tui.CheckErr(err)
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(manifest))
+
+			return
+		}
+
+		if manifest.Project != "" && manifest.Project != proj.Name {
+			tui.Warning.Printfln("the snapshot was exported from the project %s", manifest.Project)
+		}
+
+		tui.Info.Printfln("Imported %d buckets, %d key/value stores, %d queues and %d databases into %s", len(manifest.Buckets), len(manifest.KeyValueStores), len(manifest.Queues), len(manifest.Databases), proj.Name)
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+var localResetConfirm bool
+
+// localResetResult - what was removed by nitric local reset
+type localResetResult struct {
+	Directories []string `json:"directories"`
+	Databases   bool     `json:"databases"`
+}
+
+var localResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Remove the state of the project's local cloud",
+	Long: `Remove the state of the project's local cloud, so the next nitric run or nitric start begins from empty resources.
+
+The files of local buckets, key/value stores and secrets, the messages in queues persisted with --persist, the record
+of applied seeds and the volume of the local SQL databases are removed. The project mustn't be running.`,
+	Example: `nitric local reset
+
+# Reset without confirmation
+nitric local reset -y`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		dir, err := filepath.Abs(proj.Directory)
+		tui.CheckErr(err)
+
+		running, err := localenv.List()
+		tui.CheckErr(err)
+
+		if existing, ok := lo.Find(running, func(e localenv.Environment) bool { return e.Directory == dir }); ok {
+			tui.CheckErr(fmt.Errorf("%s is running with nitric %s (pid %d), stop it before resetting its local state", existing.Project, existing.Command, existing.Pid))
+		}
+
+		if !localResetConfirm {
+			if isNonInteractive() {
+				tui.CheckErr(fmt.Errorf("resetting the local state requires confirmation, use -y to confirm"))
+			}
+
+			_ = tui.AskOne(&survey.Confirm{
+				Message: fmt.Sprintf("Remove the local buckets, key/value stores, secrets, queues and databases of %s?", proj.Name),
+				Default: false,
+			}, &localResetConfirm)
+
+			if !localResetConfirm {
+				return
+			}
+		}
+
+		result := localResetResult{Directories: []string{}}
+
+		for _, stateDir := range []string{env.NITRIC_LOCAL_RUN_DIR.String(), paths.NitricStateDir(proj.Directory)} {
+			exists, err := afero.DirExists(fs, stateDir)
+			tui.CheckErr(err)
+
+			if !exists {
+				continue
+			}
+
+			err = fs.RemoveAll(stateDir)
+			tui.CheckErr(err)
+
+			result.Directories = append(result.Directories, stateDir)
+		}
+
+		// the databases of the default namespace, other namespaces are only used while another copy of the project is running
+		result.Databases, err = sql.RemoveVolume(proj.Name)
+		tui.CheckErr(err)
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(result))
+
+			return
+		}
+
+		for _, stateDir := range result.Directories {
+			tui.Info.Printfln("removed %s", stateDir)
+		}
+
+		if result.Databases {
+			tui.Info.Printfln("removed the local databases")
+		}
+
+		tui.Info.Printfln("reset the local state of %s", proj.Name)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+type localTrustResult struct {
+	Authority string `json:"authority"`
+	CertFile  string `json:"certFile"`
+	Trusted   bool   `json:"trusted"`
+}
+
+var localTrustCheck bool
+
+var localTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Trust the certificate authority of local HTTPS endpoints",
+	Long: `Add the local certificate authority to the system trust store, so browsers accept the certificates of the
+endpoints served by nitric run --https and nitric start --https.
+
+The authority is created in the nitric home directory the first time it's needed and is only used to sign certificates
+for localhost. Adding it to the trust store may prompt for an administrator password. Browsers with their own trust
+store, e.g. Firefox, need the authority's certificate imported separately.`,
+	Example: `nitric local trust
+
+# Check whether the authority is trusted without changing the trust store
+nitric local trust --check`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		authority, _, err := certs.LoadAuthority(fs, paths.NitricLocalCADir())
+		tui.CheckErr(err)
+
+		result := localTrustResult{
+			Authority: authority.Name(),
+			CertFile:  authority.CertFile,
+			Trusted:   authority.Trusted(),
+		}
+
+		if !result.Trusted && !localTrustCheck {
+			tui.CheckErr(authority.Trust())
+
+			result.Trusted = authority.Trusted()
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(result))
+
+			return
+		}
+
+		if !result.Trusted {
+			if localTrustCheck {
+				tui.Warning.Printfln("%s isn't trusted, run nitric local trust to trust it, its certificate is %s", result.Authority, result.CertFile)
+			} else {
+				// some trust stores, e.g. the keychain on macOS, are only read by new processes
+				tui.Warning.Printfln("added %s to the trust store, restart your browser if it doesn't accept local https endpoints", result.Authority)
+			}
+
+			return
+		}
+
+		tui.Info.Printfln("%s is trusted, its certificate is %s", result.Authority, result.CertFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// apiErrorMessage - returns the error message of a failed request to the local dashboard's API
+func apiErrorMessage(resp *http.Response) string {
+	apiErr := struct {
+		Error string `json:"error"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+		return resp.Status
+	}
+
+	return apiErr.Error
+}
+
+var (
+	localServeServices map[string]int
+	localServeDatabase string
+)
+
+var localServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the local cloud for service containers run by other tools",
+	Long: `Serve the local cloud for service containers run by other tools, e.g. docker compose files exported with nitric export compose.
+
+A nitric server is started for each service on the given port, services connect to it with SERVICE_ADDRESS.
+APIs, websockets and HTTP proxies are served on the ports configured in local.nitric.yaml, otherwise on the first free ports from 4000.
+SQL databases are created on the postgres server at --database, or on a local postgres container when it isn't set.`,
+	Example: `nitric local serve --service services/api.ts=50051 --service services/worker.ts=50052 --database postgres:5432`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(localServeServices) == 0 {
+			tui.CheckErr(fmt.Errorf("no services to serve, add a service and the port of its nitric server with --service <name>=<port>"))
+		}
+
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		apiWebhooks, err := proj.ApiWebhooks(map[string]string{})
+		tui.CheckErr(err)
+
+		apiRequestValidators, err := proj.ApiRequestValidators(fs)
+		tui.CheckErr(err)
+
+		localCloud, err := cloud.New(proj.Name, cloud.LocalCloudOptions{
+			LogWriter:            progressOutput,
+			LocalConfig:          proj.LocalConfig,
+			MigrationRunner:      project.BuildAndRunMigrations,
+			Flags:                proj.Flags,
+			ApiRateLimits:        proj.ApiRateLimits(),
+			ApiKeyRequired:       proj.ApisRequiringApiKey(),
+			ValidateApiKey:       apikeys.NewValidator(proj.Directory),
+			ApiMiddleware:        proj.ApiMiddleware(),
+			ApiWebhooks:          apiWebhooks,
+			ApiRequestValidators: apiRequestValidators,
+			HttpProxyPorts:       proj.HttpProxyPorts(),
+			DatabaseAddress:      localServeDatabase,
+			CaptureEmail:         proj.Email != nil,
+		})
+		tui.CheckErr(err)
+
+		system.SubscribeToLogs(func(msg string) {
+			fmt.Fprintln(progressOutput, msg)
+		})
+
+		serviceNames := lo.Keys(localServeServices)
+		slices.Sort(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			port, err := localCloud.AddServiceWithPort(serviceName, localServeServices[serviceName])
+			tui.CheckErr(err)
+
+			if port != localServeServices[serviceName] {
+				localCloud.Stop()
+				tui.CheckErr(fmt.Errorf("port %d for service %s is in use", localServeServices[serviceName], serviceName))
+			}
+
+			fmt.Fprintf(progressOutput, "serving %s on port %d\n", serviceName, port)
+		}
+
+		if localCloud.Email != nil {
+			fmt.Fprintf(progressOutput, "capturing email on smtp port %d\n", localCloud.Email.Port())
+		}
+
+		// addresses are only known once services declare their APIs and websockets
+		stopReporting := make(chan struct{})
+
+		go reportLocalAddresses(localCloud, stopReporting)
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+
+		<-sigChan
+
+		close(stopReporting)
+		fmt.Fprintln(progressOutput, "Stopping local cloud")
+		localCloud.Stop()
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// reportLocalAddresses - prints the addresses of APIs and websockets as they're served, until stopped
+func reportLocalAddresses(localCloud *cloud.LocalCloud, stop <-chan struct{}) {
+	reported := map[string]string{}
+	ticker := time.NewTicker(time.Second)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			addresses := map[string]string{}
+
+			for name, address := range localCloud.Gateway.GetApiAddresses() {
+				addresses["api "+name] = address
+			}
+
+			for name, address := range localCloud.Gateway.GetWebsocketAddresses() {
+				addresses["websocket "+name] = address
+			}
+
+			for name, address := range addresses {
+				if reported[name] != address {
+					fmt.Fprintf(progressOutput, "%s serving on %s\n", name, address)
+					reported[name] = address
+				}
+			}
+		}
+	}
+}
+
+func init() {
+	localServeCmd.Flags().StringToIntVar(&localServeServices, "service", map[string]int{}, "a service and the port of its nitric server, e.g. --service services/api.ts=50051")
+	localServeCmd.Flags().StringVar(&localServeDatabase, "database", "", "host and port of a postgres server to create SQL databases on, e.g. postgres:5432")
+	localCmd.AddCommand(localServeCmd)
+	localCmd.AddCommand(localPsCmd)
+	localCmd.AddCommand(localUsageCmd)
+
+	localExportCmd.Flags().StringVar(&localExportOut, "out", "", "file to write the snapshot to, defaults to <project>-<timestamp>.tar.gz")
+	localCmd.AddCommand(localExportCmd)
+	localCmd.AddCommand(localImportCmd)
+
+	localResetCmd.Flags().BoolVarP(&localResetConfirm, "yes", "y", false, "reset the local state without confirmation")
+	localCmd.AddCommand(tui.AddDependencyCheck(localResetCmd, tui.Docker))
+
+	localTrustCmd.Flags().BoolVar(&localTrustCheck, "check", false, "report whether the local certificate authority is trusted without trusting it")
+	localCmd.AddCommand(localTrustCmd)
+	rootCmd.AddCommand(localCmd)
+}
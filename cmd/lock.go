@@ -0,0 +1,75 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the base images locked in nitric.lock",
+	Long: `Manage the base images locked in nitric.lock.
+
+When a project has a nitric.lock file, the base images of its services are pinned to the digests in the lock file.
+Commit the lock file so teammates and CI build the same images and collect the same requirements.`,
+	Example: `nitric lock update`,
+}
+
+var lockUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Resolve the base images of the project's services and write them to nitric.lock",
+	Long: `Resolve the base images of the project's services to their current digests and write them to nitric.lock.
+
+Run this after adding services or changing their dockerfiles, or to pick up new releases of base images.`,
+	Example: `nitric lock update`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		dockerClient, err := docker.NewBuilder()
+		tui.CheckErr(err)
+
+		lockFile, unlocked, err := proj.LockBaseImages(dockerClient.ResolveImageDigest)
+		tui.CheckErr(err)
+
+		lockFilePath, err := lockFile.ToFile(fs, proj.Directory)
+		tui.CheckErr(err)
+
+		if len(unlocked) > 0 {
+			tui.Warning.Printfln("base images referenced by build args can't be locked: %s", strings.Join(unlocked, ", "))
+		}
+
+		fmt.Fprintf(progressOutput, "Locked %d base images in %s\n", len(lockFile.Images), lockFilePath)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	lockCmd.AddCommand(tui.AddDependencyCheck(lockUpdateCmd, tui.Docker, tui.DockerBuildx))
+	rootCmd.AddCommand(lockCmd)
+}
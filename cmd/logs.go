@@ -0,0 +1,186 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/dashboard"
+	"github.com/nitrictech/cli/pkg/localenv"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	logsServices []string
+	logsLevel    string
+	logsSince    time.Duration
+	logsFollow   bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the output of the project's services while running locally",
+	Long: `Show the output of the project's services while running locally with nitric run or nitric start.
+
+Levels are detected from structured logs, e.g. {"level":"warn"} or level=warn, and from lines prefixed with a level,
+e.g. [WARN] or WARNING:. Output without a detected level is treated as info, or as error when written to stderr.
+Services can also set a log-level in nitric.yaml, output below it isn't shown or kept by the local run.`,
+	Example: `nitric logs
+
+# Show warnings and errors from the api service, following new output
+nitric logs --level warn --service api --follow
+
+# Show output from the last 5 minutes
+nitric logs --since 5m`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		if logsLevel != "" {
+			_, err := project.ParseLogLevel(logsLevel)
+			tui.CheckErr(err)
+		}
+
+		// services are matched by name or file path, the same as nitric run --service
+		err = proj.SelectServices(logsServices, nil)
+		tui.CheckErr(err)
+
+		serviceNames := lo.Map(proj.GetServices(), func(svc project.Service, _ int) string { return svc.Name })
+
+		environment, err := runningEnvironment(proj)
+		tui.CheckErr(err)
+
+		since := time.Time{}
+		if logsSince > 0 {
+			since = time.Now().Add(-logsSince)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		for {
+			entries, err := fetchLogs(environment, since)
+			tui.CheckErr(err)
+
+			entries = lo.Filter(entries, func(entry dashboard.LogEntry, _ int) bool {
+				return len(logsServices) == 0 || lo.Contains(serviceNames, entry.Service)
+			})
+
+			if len(entries) > 0 {
+				since = entries[len(entries)-1].Time
+			}
+
+			if structuredOutput() && !logsFollow {
+				tui.CheckErr(printResult(entries))
+
+				return
+			}
+
+			for _, entry := range entries {
+				printLogEntry(entry)
+			}
+
+			if !logsFollow {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// fetchLogs - returns the output kept by the local dashboard after since, filtered by the selected level
+func fetchLogs(environment *localenv.Environment, since time.Time) ([]dashboard.LogEntry, error) {
+	query := url.Values{}
+
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339Nano))
+	}
+
+	if logsLevel != "" {
+		query.Set("level", logsLevel)
+	}
+
+	resp, err := environment.DashboardRequest(http.MethodGet, "/api/v1/logs?"+query.Encode(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch logs from the local dashboard: %s", apiErrorMessage(resp))
+	}
+
+	entries := []dashboard.LogEntry{}
+
+	return entries, json.NewDecoder(resp.Body).Decode(&entries)
+}
+
+var logLevelColors = map[string]lipgloss.CompleteColor{
+	string(project.LogLevel_Debug): tui.Colors.Gray,
+	string(project.LogLevel_Warn):  tui.Colors.Yellow,
+	string(project.LogLevel_Error): tui.Colors.Red,
+}
+
+func printLogEntry(entry dashboard.LogEntry) {
+	// followed output is written as json lines
+	if structuredOutput() {
+		line, err := json.Marshal(entry)
+		tui.CheckErr(err)
+
+		fmt.Fprintln(resultOutput, string(line))
+
+		return
+	}
+
+	timeStyle := lipgloss.NewStyle().Foreground(tui.Colors.Gray)
+	serviceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue)
+	messageStyle := lipgloss.NewStyle()
+
+	if color, ok := logLevelColors[entry.Level]; ok {
+		messageStyle = messageStyle.Foreground(color)
+	}
+
+	fmt.Fprintf(progressOutput, "%s %s %s\n", timeStyle.Render(entry.Time.Local().Format(time.TimeOnly)), serviceStyle.Render(entry.Service), messageStyle.Render(entry.Message))
+}
+
+func init() {
+	logsCmd.Flags().StringSliceVar(&logsServices, "service", nil, "only show output from services matching a glob on their name or file path, can be repeated")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "minimum level of output to show, one of debug, info, warn or error")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "only show output from this long ago, e.g. 5m")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "keep showing new output until interrupted")
+	rootCmd.AddCommand(logsCmd)
+}
@@ -54,15 +54,28 @@ nitric new hello-world "official/TypeScript - Starter" `,
 			return fmt.Errorf(`non-interactive environment detected, please provide all mandatory arguments e.g. nitric new hello-world "official/TypeScript - Starter"`)
 		}
 
-		projectModel, err := project.New(afero.NewOsFs(), project.Args{
+		newArgs := project.Args{
 			ProjectName:  projectName,
 			TemplateName: templateName,
 			Force:        force,
-		})
+		}
+
+		teaOptions := []tea.ProgramOption{tea.WithANSICompressor()}
+
+		if tui.Accessible() {
+			var err error
+
+			newArgs, err = project.Ask(newArgs)
+			tui.CheckErr(err)
+
+			teaOptions = append(teaOptions, teax.PlainOptions()...)
+		}
+
+		projectModel, err := project.New(afero.NewOsFs(), newArgs)
 		tui.CheckErr(err)
 
 		// TODO add --force
-		if _, err := teax.NewProgram(projectModel, tea.WithANSICompressor()).Run(); err != nil {
+		if _, err := teax.NewProgram(projectModel, teaOptions...).Run(); err != nil {
 			return err
 		}
 
@@ -0,0 +1,224 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AlecAivazis/survey/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	new_project "github.com/nitrictech/cli/pkg/view/tui/commands/project"
+	"github.com/nitrictech/cli/pkg/view/tui/teax"
+)
+
+const (
+	onboardNewProject      = "Create a new project from a template"
+	onboardExistingProject = "Add nitric to the existing code in this directory"
+	onboardShowHelp        = "Show available commands"
+)
+
+// shouldOnboard - returns true when nitric is run interactively outside of a nitric project, a nitric.yaml that can't be read
+// is returned as an error rather than treated as a missing project
+func shouldOnboard() (bool, error) {
+	if isNonInteractive() {
+		return false, nil
+	}
+
+	fs := afero.NewOsFs()
+
+	if _, err := fs.Stat("nitric.yaml"); err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	_, err := project.ConfigurationFromFile(fs, "")
+
+	return false, err
+}
+
+// runOnboarding - guides first time users through creating a project, creating a stack and starting the project locally
+func runOnboarding(cmd *cobra.Command) {
+	fs := afero.NewOsFs()
+
+	choice := ""
+
+	err := tui.AskOne(&survey.Select{
+		Message: "No nitric project found in this directory, what would you like to do?",
+		Options: []string{onboardNewProject, onboardExistingProject, onboardShowHelp},
+	}, &choice)
+	if err != nil {
+		return
+	}
+
+	projectDir := ""
+
+	switch choice {
+	case onboardNewProject:
+		projectDir = onboardFromTemplate(fs)
+	case onboardExistingProject:
+		projectDir = onboardFromExisting(fs)
+	default:
+		_ = cmd.Help()
+		return
+	}
+
+	if projectDir == "" {
+		return
+	}
+
+	err = os.Chdir(projectDir)
+	tui.CheckErr(err)
+
+	createStack := false
+	_ = tui.AskOne(&survey.Confirm{
+		Message: "Create a stack to deploy your project to the cloud?",
+		Default: true,
+	}, &createStack)
+
+	if createStack {
+		tui.CheckErr(newStackCmd.RunE(newStackCmd, []string{}))
+	}
+
+	if err := docker.VerifyDockerIsAvailable(); err != nil {
+		tui.Warning.Printfln("docker is required to run your project locally: %v", err)
+		printOnboardingNextSteps(projectDir)
+
+		return
+	}
+
+	runLocally := false
+	_ = tui.AskOne(&survey.Confirm{
+		Message: "Start your project locally now?",
+		Default: true,
+	}, &runLocally)
+
+	if !runLocally {
+		printOnboardingNextSteps(projectDir)
+		return
+	}
+
+	tui.CheckErr(runCmd.RunE(runCmd, []string{}))
+}
+
+// onboardFromTemplate - creates a new project from a template, returning the new project's directory
+func onboardFromTemplate(fs afero.Fs) string {
+	args := new_project.Args{}
+	teaOptions := []tea.ProgramOption{tea.WithANSICompressor()}
+
+	if tui.Accessible() {
+		var err error
+
+		args, err = new_project.Ask(args)
+		tui.CheckErr(err)
+
+		teaOptions = append(teaOptions, teax.PlainOptions()...)
+	}
+
+	projectModel, err := new_project.New(fs, args)
+	tui.CheckErr(err)
+
+	model, err := teax.NewProgram(projectModel, teaOptions...).Run()
+	tui.CheckErr(err)
+
+	created, ok := model.(new_project.Model)
+	if !ok || !created.Created() {
+		return ""
+	}
+
+	return created.ProjectName()
+}
+
+// onboardFromExisting - creates a nitric.yaml in the current directory, matching the services detected in the existing code
+func onboardFromExisting(fs afero.Fs) string {
+	currentDir, err := os.Getwd()
+	tui.CheckErr(err)
+
+	projectName := ""
+
+	err = tui.AskOne(&survey.Input{
+		Message: "What should we name this project?",
+		Default: filepath.Base(currentDir),
+	}, &projectName, survey.WithValidator(survey.Required))
+	if err != nil {
+		return ""
+	}
+
+	detected, err := project.DetectServices(fs, ".")
+	tui.CheckErr(err)
+
+	if len(detected) > 0 {
+		matches := lo.Map(detected, func(d project.DetectedService, _ int) string { return d.Match })
+
+		err = tui.AskOne(&survey.MultiSelect{
+			Message: "Found services using the nitric SDK, which should be included in the project?",
+			Options: matches,
+			Default: matches,
+		}, &matches)
+		if err != nil {
+			return ""
+		}
+
+		detected = lo.Filter(detected, func(d project.DetectedService, _ int) bool { return lo.Contains(matches, d.Match) })
+	}
+
+	if len(detected) == 0 {
+		match := ""
+
+		err = tui.AskOne(&survey.Input{
+			Message: "Which files contain your services?",
+			Default: "services/*.ts",
+		}, &match, survey.WithValidator(survey.Required))
+		if err != nil {
+			return ""
+		}
+
+		detected = []project.DetectedService{{Match: match}}
+	}
+
+	projectConfig := project.ProposeConfiguration(project.ProjectConfiguration{Name: projectName}, detected)
+
+	err = projectConfig.ToFile(fs, "")
+	tui.CheckErr(err)
+
+	tui.Info.Printfln("Created nitric.yaml for project %s", projectName)
+
+	return "."
+}
+
+func printOnboardingNextSteps(projectDir string) {
+	fmt.Fprintln(progressOutput, "You're all set! Next steps:")
+
+	if projectDir != "." {
+		fmt.Fprintf(progressOutput, "  cd ./%s\n", projectDir)
+	}
+
+	fmt.Fprintln(progressOutput, "  nitric start       run your project locally")
+	fmt.Fprintln(progressOutput, "  nitric stack new   create a stack to deploy to")
+	fmt.Fprintln(progressOutput, "  nitric up          deploy your project")
+}
@@ -0,0 +1,80 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var outputFormat = "table"
+
+var outputFormats = []string{"table", "json", "yaml"}
+
+// resultOutput - where command results are written
+var resultOutput io.Writer = os.Stdout
+
+// progressOutput - where progress, messages and tables are written, stderr when a structured output format is selected
+var progressOutput io.Writer = os.Stdout
+
+// structuredOutput - whether command results are output as json or yaml rather than tables
+func structuredOutput() bool {
+	return outputFormat == "json" || outputFormat == "yaml"
+}
+
+// routeProgressOutput - sends progress output to stderr when a structured output format is selected, so stdout only contains the result
+func routeProgressOutput() {
+	if !structuredOutput() {
+		return
+	}
+
+	progressOutput = os.Stderr
+	tui.SetOutput(os.Stderr)
+}
+
+// printResult - writes the result of a command to stdout in the selected structured output format
+func printResult(result any) error {
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "yaml" {
+		// round trip through json so yaml keys match the json field names
+		var value any
+
+		if err := json.Unmarshal(out, &value); err != nil {
+			return err
+		}
+
+		out, err = yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(resultOutput, strings.TrimSuffix(string(out), "\n"))
+
+	return err
+}
@@ -0,0 +1,181 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/preview"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Manage the preview features enabled for this project",
+	Long: `Manage the preview features enabled for this project.
+
+Preview features are stored in the preview section of your nitric.yaml file.`,
+	Example: `nitric preview list
+nitric preview enable sql-databases
+nitric preview disable sql-databases`,
+}
+
+// featureCompletion - provides shell completion for preview feature names
+func featureCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return lo.Without(preview.Features(), args...), cobra.ShellCompDirectiveNoFileComp
+}
+
+var previewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available preview features and whether they're enabled",
+	Long:  `List available preview features and whether they're enabled`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		features := preview.Features()
+
+		nameLength := len("feature")
+		for _, feature := range features {
+			nameLength = max(nameLength, len(feature))
+		}
+
+		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(nameLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		statusStyle := lipgloss.NewStyle().Width(10).PaddingLeft(1)
+		descriptionStyle := lipgloss.NewStyle().Foreground(tui.Colors.Gray).PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("feature").WithStyle(nameStyle)
+		v.Add("status").WithStyle(statusStyle)
+		v.Addln("description").WithStyle(descriptionStyle)
+		v.Break()
+
+		for _, feature := range features {
+			v.Add(feature).WithStyle(nameStyle)
+
+			if slices.Contains(projectConfig.Preview, feature) {
+				v.Add("enabled").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Green))
+			} else {
+				v.Add("disabled").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Gray))
+			}
+
+			v.Addln(preview.Description(feature)).WithStyle(descriptionStyle)
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var previewEnableCmd = &cobra.Command{
+	Use:               "enable [feature...]",
+	Short:             "Enable one or more preview features",
+	Long:              `Enable one or more preview features by adding them to the preview section of nitric.yaml`,
+	Example:           `nitric preview enable sql-databases`,
+	ValidArgsFunction: featureCompletion,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		for _, feature := range args {
+			tui.CheckErr(preview.Validate(feature))
+		}
+
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		for _, feature := range args {
+			if slices.Contains(projectConfig.Preview, feature) {
+				fmt.Fprintf(progressOutput, "preview feature %s is already enabled\n", feature)
+				continue
+			}
+
+			projectConfig.Preview = append(projectConfig.Preview, feature)
+
+			fmt.Fprintf(progressOutput, "enabled preview feature %s\n", feature)
+		}
+
+		tui.CheckErr(writePreviewFeatures(fs, projectConfig.Preview))
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+var previewDisableCmd = &cobra.Command{
+	Use:               "disable [feature...]",
+	Short:             "Disable one or more preview features",
+	Long:              `Disable one or more preview features by removing them from the preview section of nitric.yaml`,
+	Example:           `nitric preview disable sql-databases`,
+	ValidArgsFunction: featureCompletion,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		for _, feature := range args {
+			if !slices.Contains(projectConfig.Preview, feature) {
+				fmt.Fprintf(progressOutput, "preview feature %s is not enabled\n", feature)
+				continue
+			}
+
+			projectConfig.Preview = lo.Without(projectConfig.Preview, feature)
+
+			fmt.Fprintf(progressOutput, "disabled preview feature %s\n", feature)
+		}
+
+		tui.CheckErr(writePreviewFeatures(fs, projectConfig.Preview))
+	},
+	Args: cobra.MinimumNArgs(1),
+}
+
+// writePreviewFeatures - updates the preview section of nitric.yaml, leaving the rest of the file as it's written
+func writePreviewFeatures(fs afero.Fs, features []string) error {
+	contents, err := afero.ReadFile(fs, "nitric.yaml")
+	if err != nil {
+		return err
+	}
+
+	updated, err := project.PreviewConfigurationFile(contents, features)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(updated, contents) {
+		return nil
+	}
+
+	return afero.WriteFile(fs, "nitric.yaml", updated, 0o644)
+}
+
+func init() {
+	previewCmd.AddCommand(previewListCmd)
+	previewCmd.AddCommand(previewEnableCmd)
+	previewCmd.AddCommand(previewDisableCmd)
+
+	rootCmd.AddCommand(previewCmd)
+}
@@ -0,0 +1,186 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/provider"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var providerCmd = &cobra.Command{
+	Use:   "provider",
+	Short: "Manage the providers used to deploy stacks",
+	Long: `Manage the providers used to deploy stacks.
+
+Versioned providers, e.g. nitric/aws@1.11.6, are downloaded from nitric releases when a stack is deployed.
+Providers without a version are plugins, a stack with the provider acme/edge is deployed by running the
+nitric-provider-acme-edge executable found on the PATH.
+
+Plugins serve the nitric deployment gRPC service. They are started with PORT set to a free port and
+NITRIC_PROVIDER_PROTOCOL set to the protocol version, and write NITRIC_PROVIDER|<protocol>|<address> to stdout
+once they're ready, e.g. NITRIC_PROVIDER|1|127.0.0.1:50051.`,
+	Example: `nitric provider list
+
+# List the features a provider can deploy
+nitric provider capabilities nitric/azure`,
+}
+
+// providerListResult - the providers available to deploy stacks with
+type providerListResult struct {
+	Downloaded []provider.DownloadedProvider `json:"downloaded"`
+	Plugins    []provider.Plugin             `json:"plugins"`
+}
+
+var providerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List downloaded providers and provider plugins found on the PATH",
+	Example: `nitric provider list
+
+# Output machine readable JSON
+nitric provider list -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		downloaded, err := provider.ListDownloaded()
+		tui.CheckErr(err)
+
+		result := providerListResult{
+			Downloaded: downloaded,
+			Plugins:    provider.ListPlugins(),
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(result))
+
+			return
+		}
+
+		if len(result.Downloaded) == 0 && len(result.Plugins) == 0 {
+			fmt.Fprintln(progressOutput, "No providers have been downloaded and no provider plugins were found on the PATH")
+			return
+		}
+
+		idLength := 0
+
+		for _, d := range result.Downloaded {
+			idLength = max(idLength, len(d.Id))
+		}
+
+		for _, p := range result.Plugins {
+			idLength = max(idLength, len(p.Name))
+		}
+
+		if len(result.Downloaded) > 0 {
+			fmt.Fprintln(progressOutput, "Downloaded:")
+
+			for _, d := range result.Downloaded {
+				fmt.Fprintf(progressOutput, "  %-*s  %s\n", idLength, d.Id, d.Path)
+			}
+		}
+
+		if len(result.Plugins) > 0 {
+			fmt.Fprintln(progressOutput, lo.Ternary(len(result.Downloaded) > 0, "\nPlugins:", "Plugins:"))
+
+			for _, p := range result.Plugins {
+				fmt.Fprintf(progressOutput, "  %-*s  %s\n", idLength, p.Name, p.Path)
+			}
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var providerCapabilitiesCmd = &cobra.Command{
+	Use:   "capabilities <provider>",
+	Short: "List the nitric resources and features a provider can deploy",
+	Long: `List the nitric resources and features a provider can deploy, e.g. whether it supports websockets or SQL
+databases, and how often it can run schedules. Services and policies are deployed by every provider.
+
+nitric stack preview checks the project against the capabilities of the stack's provider, and fails before
+deploying when the project uses features the provider doesn't support.
+
+Settings are the stack file settings the provider applies, e.g. the security policies of APIs. Deployments fail when
+the provider doesn't apply a setting that would leave resources open, and warn about other settings it ignores.
+The capabilities of provider plugins and docker providers aren't known, so they aren't checked and their settings
+are warned about.`,
+	Example: `nitric provider capabilities nitric/gcp
+
+# Output machine readable JSON
+nitric provider capabilities cloudflare -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		capabilities, ok := provider.CapabilitiesOf(args[0])
+		if !ok {
+			tui.CheckErr(fmt.Errorf("the capabilities of %s aren't known, known providers are: %s", args[0], strings.Join(provider.KnownProviders(), ", ")))
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(capabilities))
+
+			return
+		}
+
+		featureLength := 0
+
+		for _, feature := range provider.Features {
+			featureLength = max(featureLength, len(feature))
+		}
+
+		for _, setting := range provider.Settings {
+			featureLength = max(featureLength, len(setting))
+		}
+
+		fmt.Fprintf(progressOutput, "Capabilities of %s:\n", capabilities.Provider)
+
+		for _, feature := range provider.Features {
+			fmt.Fprintf(progressOutput, "  %-*s  %s\n", featureLength, feature, lo.Ternary(capabilities.Supports(feature), "supported", "unsupported"))
+		}
+
+		fmt.Fprintln(progressOutput, "\nSettings:")
+
+		for _, setting := range provider.Settings {
+			fmt.Fprintf(progressOutput, "  %-*s  %s\n", featureLength, setting, lo.Ternary(capabilities.Applies(setting), "applied", "ignored"))
+		}
+
+		if capabilities.ScheduleGranularity != "" {
+			fmt.Fprintf(progressOutput, "\nSchedules run at most once every %s\n", capabilities.ScheduleGranularity)
+		}
+
+		if capabilities.GenerateOnly {
+			fmt.Fprintf(progressOutput, "\n%s generates configuration that's applied with other tools, its deployments aren't recorded in the stack's history\n", capabilities.Provider)
+		}
+	},
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: providerCompletion,
+}
+
+// providerCompletion - provides shell completion for the names of providers with known capabilities
+func providerCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return provider.KnownProviders(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	providerCmd.AddCommand(providerListCmd)
+	providerCmd.AddCommand(providerCapabilitiesCmd)
+	rootCmd.AddCommand(providerCmd)
+}
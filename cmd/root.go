@@ -19,12 +19,17 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"runtime/debug"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/nitrictech/cli/pkg/exitcode"
 	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/pflagx"
+	"github.com/nitrictech/cli/pkg/preferences"
 	"github.com/nitrictech/cli/pkg/update"
 	"github.com/nitrictech/cli/pkg/view/tui"
 )
@@ -41,6 +46,12 @@ For further details visit our docs https://nitric.io/docs`
 
 var CI bool
 
+var accessible bool
+
+// exitCode - the code the CLI exits with once the command returns, set by commands that fail without an error to report,
+// e.g. a deployment with failed resources, so deferred cleanup like stopping providers still runs
+var exitCode = exitcode.Success
+
 func usageString() string {
 	return usageTemplate
 }
@@ -50,6 +61,9 @@ var rootCmd = &cobra.Command{
 	Use:   "nitric",
 	Short: "CLI for Nitric applications",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		routeProgressOutput()
+		tui.SetAccessible(accessible)
+
 		// if output.VerboseLevel > 1 {
 		// 	pterm.EnableDebugMessages()
 		// }
@@ -66,12 +80,28 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		update.FetchLatestVersion()
+		prefs, err := preferences.Current()
+		tui.CheckErr(err)
+
+		if prefs.TelemetryEnabled() {
+			update.FetchLatestVersion()
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		onboard, err := shouldOnboard()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		if onboard {
+			runOnboarding(cmd)
+			return
+		}
+
+		_ = cmd.Help()
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		update.PrintOutdatedWarning()
 		// an unstyled \n is always needed at the end of the view to ensure the last line renders
-		fmt.Println()
+		fmt.Fprintln(progressOutput)
 	},
 }
 
@@ -88,17 +118,22 @@ func Execute() {
 	}()
 
 	tui.CheckErr(rootCmd.Execute())
+
+	if exitCode != exitcode.Success {
+		os.Exit(int(exitCode))
+	}
 }
 
 func init() {
 	// rootCmd.PersistentFlags().IntVarP(&output.VerboseLevel, "verbose", "v", 1, "set the verbosity of output (larger is more verbose)")
 	rootCmd.PersistentFlags().BoolVar(&CI, "ci", false, "CI mode, disable output styling and auto-confirm all operations")
-	// rootCmd.PersistentFlags().VarP(output.OutputTypeFlag, "output", "o", "output format")
+	rootCmd.PersistentFlags().BoolVar(&accessible, "accessible", false, "accessible mode, ask prompts as plain questions and show progress as plain text for screen readers, also set with NITRIC_ACCESSIBLE=true")
+	rootCmd.PersistentFlags().VarP(pflagx.NewStringEnumVar(&outputFormat, outputFormats, "table"), "output", "o", "output format of command results, one of table, json or yaml")
 
-	// err := rootCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	// 	return output.OutputTypeFlag.Allowed, cobra.ShellCompDirectiveDefault
-	// })
-	// tui.CheckErr(err)
+	err := rootCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return outputFormats, cobra.ShellCompDirectiveDefault
+	})
+	tui.CheckErr(err)
 
 	rootCmd.Long = usageString()
 }
@@ -161,7 +196,7 @@ func cmdUsage(prefix []string, c *cobra.Command, commonOnly bool) []string {
 		add = false
 	}
 
-	if !c.HasParent() {
+	if !c.HasParent() || c.Hidden {
 		add = false
 	}
 
@@ -183,5 +218,40 @@ func cmdUsage(prefix []string, c *cobra.Command, commonOnly bool) []string {
 
 // isNonInteractive returns true if the CLI is running in a non-interactive environment
 func isNonInteractive() bool {
-	return CI || !tui.IsTerminal()
+	// structured output is written to stdout once the command completes, so progress is never rendered interactively
+	return CI || structuredOutput() || !tui.IsTerminal()
+}
+
+// plainOutput returns true if progress is written as plain lines rather than rendered interactively, in non-interactive
+// environments and in accessible mode, where redrawn views and spinners can't be followed by screen readers
+func plainOutput() bool {
+	return isNonInteractive() || tui.Accessible()
+}
+
+// openEditor opens a file with the user's editor from $VISUAL or $EDITOR, waiting for the editor to close
+func openEditor(path string) error {
+	editor := "vi"
+	if runtime.GOOS == "windows" {
+		editor = "notepad"
+	}
+
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if value := strings.TrimSpace(os.Getenv(env)); value != "" {
+			editor = value
+			break
+		}
+	}
+
+	args := append(strings.Fields(editor), path)
+
+	editorCmd := exec.Command(args[0], args[1:]...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor %s exited with an error: %w", args[0], err)
+	}
+
+	return nil
 }
@@ -20,6 +20,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -27,14 +30,20 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/nitrictech/cli/pkg/apikeys"
 	"github.com/nitrictech/cli/pkg/cloud"
 	"github.com/nitrictech/cli/pkg/cloud/gateway"
+	"github.com/nitrictech/cli/pkg/cloud/schedules"
 	"github.com/nitrictech/cli/pkg/dashboard"
 	docker "github.com/nitrictech/cli/pkg/docker"
 	"github.com/nitrictech/cli/pkg/env"
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/localenv"
 	"github.com/nitrictech/cli/pkg/paths"
 	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/session"
 	"github.com/nitrictech/cli/pkg/system"
+	"github.com/nitrictech/cli/pkg/tunnel"
 	"github.com/nitrictech/cli/pkg/view/tui"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/build"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/local"
@@ -42,16 +51,86 @@ import (
 	"github.com/nitrictech/cli/pkg/view/tui/teax"
 )
 
-var runNoBrowser bool
+var (
+	runNoBrowser bool
+	runRecord    string
+	runReplay    string
+	runNetwork   string
+	runNoWarm    bool
+	runDebug     bool
+	runReseed    bool
+	runPersist   bool
+)
+
+// localCloudReplayTarget - replays recorded sessions against the local cloud's gateway
+type localCloudReplayTarget struct {
+	localCloud *cloud.LocalCloud
+}
+
+var _ session.Target = localCloudReplayTarget{}
+
+func (t localCloudReplayTarget) ApiAddress(apiName string) (string, bool) {
+	address, ok := t.localCloud.Gateway.GetApiAddresses()[apiName]
+	return address, ok
+}
+
+func (t localCloudReplayTarget) HttpAddress() (string, bool) {
+	for _, address := range t.localCloud.Gateway.GetHttpWorkerAddresses() {
+		return address, true
+	}
+
+	return "", false
+}
+
+func (t localCloudReplayTarget) TopicTriggerUrl(topicName string) (string, bool) {
+	_, ok := t.localCloud.Topics.GetSubscribers()[topicName]
+	return t.localCloud.Gateway.GetTopicTriggerUrl(topicName), ok
+}
+
+func (t localCloudReplayTarget) ScheduleTriggerUrl(scheduleName string) (string, bool) {
+	_, ok := t.localCloud.Schedules.GetSchedules()[scheduleName]
+	return t.localCloud.Gateway.GetScheduleManualTriggerUrl(scheduleName), ok
+}
 
 var runCmd = &cobra.Command{
-	Use:         "run",
-	Short:       "Run your project locally for development and testing",
-	Long:        `Run your project locally for development and testing`,
-	Example:     `nitric run`,
+	Use:   "run",
+	Short: "Run your project locally for development and testing",
+	Long: `Run your project locally for development and testing.
+
+Services run in containers that connect to the nitric server started by the CLI on the host. By default containers use
+the bridge network and reach the host through host.docker.internal, on linux this is mapped to docker's host-gateway.
+When DOCKER_HOST points at a remote docker engine, containers on engines accessed over ssh connect back through an ssh tunnel,
+containers on other engines connect to the address of this machine on the route to the engine.
+When the host isn't reachable on these addresses, e.g. WSL2, set NITRIC_DOCKER_HOST to an address of the host that containers
+can reach, or use --network host to share the host's network so containers connect on localhost.
+Use --network <name> to run the containers on an existing docker network, alongside other containers on that network.
+
+Stopped service containers are kept in a warm pool and restarted by the next run, when the service's image and configuration
+are unchanged, so services that haven't changed start without being rebuilt or recreated.
+Use --no-warm-pool to remove the pooled containers and start every service in a new container.
+
+Use --service to build and run only the services matching a glob on their name or file path, and --exclude to skip services,
+e.g. to avoid the build time and ports of services you aren't working on.
+
+Use --debug to run services with a debugger attached, node and typescript services use --inspect, python services use debugpy,
+.NET services use vsdbg and custom runtimes can set a debugger, e.g. dlv for go. The debug port of each service is printed once
+it starts and VS Code configurations attaching to them are added to .vscode/launch.json.`,
+	Example: `nitric run
+
+# Run service containers on the host network
+nitric run --network host
+
+# Start every service in a new container
+nitric run --no-warm-pool
+
+# Run only the api service
+nitric run --service services/api.ts
+
+# Run services with their debuggers attached
+nitric run --debug`,
 	Annotations: map[string]string{"commonCommand": "yes"},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		err := docker.VerifyDockerIsAvailable()
+		err := project.ValidateNetworkMode(runNetwork)
 		tui.CheckErr(err)
 
 		fs := afero.NewOsFs()
@@ -59,6 +138,15 @@ var runCmd = &cobra.Command{
 		proj, err := project.FromFile(fs, "")
 		tui.CheckErr(err)
 
+		err = proj.SelectServices(serviceFilter, serviceExclude)
+		tui.CheckErr(err)
+
+		// verified once the project is loaded, since it can configure the container engines to use
+		err = docker.VerifyDockerIsAvailable()
+		tui.CheckErr(err)
+
+		applyBuildFlags(proj)
+
 		additionalEnvFiles := []string{}
 
 		if envFile != "" {
@@ -70,13 +158,24 @@ var runCmd = &cobra.Command{
 			tui.CheckErr(err)
 		}
 
+		apiWebhooks, err := proj.ApiWebhooks(loadEnv)
+		tui.CheckErr(err)
+
+		apiRequestValidators, err := proj.ApiRequestValidators(fs)
+		tui.CheckErr(err)
+
+		runDir, persist := localCloudPersistence(cmd, proj)
+
+		// namespace the local cloud so it can run alongside the local clouds of other projects
+		localEnvironment, unregisterLocalEnvironment, err := localenv.Register(proj.Name, proj.Directory, "run")
+		tui.CheckErr(err)
+		defer unregisterLocalEnvironment()
+
 		var tlsCredentials *gateway.TLSCredentials
+
+		tlsMessage := ""
 		if enableHttps {
-			createTlsCredentialsIfNotPresent(fs, proj.Directory)
-			tlsCredentials = &gateway.TLSCredentials{
-				CertFile: paths.NitricTlsCertFile(proj.Directory),
-				KeyFile:  paths.NitricTlsKeyFile(proj.Directory),
-			}
+			tlsCredentials, tlsMessage = localTlsCredentials(fs, proj.Directory)
 		}
 
 		logFilePath, err := paths.NewNitricLogFile(proj.Directory)
@@ -86,23 +185,63 @@ var runCmd = &cobra.Command{
 		tui.CheckErr(err)
 		defer logWriter.Close()
 
+		var replaySession *session.Session
+		if runReplay != "" {
+			replaySession, err = session.Load(runReplay)
+			tui.CheckErr(err)
+		}
+
+		var recorder *session.Recorder
+		if runRecord != "" {
+			recorder, err = session.NewRecorder(runRecord)
+			tui.CheckErr(err)
+		}
+
 		teaOptions := []tea.ProgramOption{}
-		if isNonInteractive() {
-			teaOptions = append(teaOptions, tea.WithoutRenderer(), tea.WithInput(nil))
+		if plainOutput() {
+			teaOptions = append(teaOptions, teax.PlainOptions()...)
 		}
 
-		runView := teax.NewProgram(local.NewLocalCloudStartModel(isNonInteractive()), teaOptions...)
+		runView := teax.NewProgram(local.NewLocalCloudStartModel(plainOutput()), teaOptions...)
 
 		var localCloud *cloud.LocalCloud
 		go func() {
 			// Start the local cloud service analogues
 			localCloud, err = cloud.New(proj.Name, cloud.LocalCloudOptions{
-				TLSCredentials:  tlsCredentials,
-				LogWriter:       logWriter,
-				LocalConfig:     proj.LocalConfig,
-				MigrationRunner: project.BuildAndRunMigrations,
+				TLSCredentials:       tlsCredentials,
+				LogWriter:            logWriter,
+				LocalConfig:          proj.LocalConfig,
+				MigrationRunner:      project.BuildAndRunMigrations,
+				Flags:                proj.Flags,
+				ApiRateLimits:        proj.ApiRateLimits(),
+				ApiKeyRequired:       proj.ApisRequiringApiKey(),
+				ValidateApiKey:       apikeys.NewValidator(proj.Directory),
+				ApiMiddleware:        proj.ApiMiddleware(),
+				ApiWebhooks:          apiWebhooks,
+				ApiRequestValidators: apiRequestValidators,
+				HttpProxyPorts:       proj.HttpProxyPorts(),
+				Namespace:            localEnvironment.Namespace,
+				RunDir:               runDir,
+				Persist:              persist,
+				Recorder:             recorder,
+				CaptureEmail:         proj.Email != nil,
+				Dependencies:         proj.Dependencies,
 			})
 			tui.CheckErr(err)
+
+			if recorder != nil {
+				// only manual triggers are recorded, schedules run by their cron/rate run again by themselves when replayed
+				localCloud.Schedules.SubscribeToAction(func(action schedules.ActionState) {
+					if !action.Manual {
+						return
+					}
+
+					recorder.Record(session.Trigger{
+						Type: session.TriggerType_Schedule,
+						Name: action.ScheduleName,
+					})
+				})
+			}
 			runView.Send(local.LocalCloudStartStatusMsg{Status: local.Done})
 		}()
 
@@ -116,14 +255,42 @@ var runCmd = &cobra.Command{
 		err = dash.Start()
 		tui.CheckErr(err)
 
-		updates, err := proj.BuildServices(fs)
+		err = localEnvironment.SetDashboard(dash.GetDashboardUrl(), dash.GetApiToken())
 		tui.CheckErr(err)
 
+		// share the local endpoints with nitric tunnel
+		stopPublishingEndpoints := tunnel.PublishEndpoints(proj.Directory, localCloud.Gateway)
+		defer stopPublishingEndpoints()
+
+		checkBuildDiskSpace(fs, proj, buildFlagOptions()...)
+
+		updates, err := proj.BuildServices(fs, buildFlagOptions()...)
+		tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
 		prog := teax.NewProgram(build.NewModel(updates, "Building Services"))
 		// blocks but quits once the above updates channel is closed by the build process
 		_, err = prog.Run()
 		tui.CheckErr(err)
 
+		runOptions := []project.RunContainerOption{project.WithNetwork(runNetwork), project.WithLocalEnvironment(localEnvironment)}
+
+		if runNoWarm {
+			err = project.ClearWarmPool(localEnvironment.Directory)
+			tui.CheckErr(err)
+		} else {
+			runOptions = append(runOptions, project.WithWarmPool())
+		}
+
+		debugMessages := []string{}
+
+		if runDebug {
+			targets, unsupported, err := proj.DebugTargets(localEnvironment)
+			tui.CheckErr(err)
+
+			runOptions = append(runOptions, project.WithDebugTargets(targets))
+			debugMessages = debugTargetMessages(fs, proj, targets, unsupported)
+		}
+
 		// Run the app code (project services)
 		stopChan := make(chan bool)
 		updatesChan := make(chan project.ServiceRunUpdate)
@@ -137,7 +304,7 @@ var runCmd = &cobra.Command{
 		}()
 
 		go func() {
-			err := proj.RunServices(localCloud, stopChan, updatesChan, loadEnv)
+			err := proj.RunServices(localCloud, stopChan, updatesChan, loadEnv, runOptions...)
 			if err != nil {
 				localCloud.Stop()
 
@@ -157,10 +324,44 @@ var runCmd = &cobra.Command{
 			}
 		})
 
-		allUpdates := lo.FanIn(10, updatesChan, systemChan)
+		if tlsMessage != "" {
+			system.Log(tlsMessage)
+		}
+
+		logEmailCapture(localCloud, dash.GetDashboardUrl())
+		logDependencies(localCloud)
+		seedLocalCloud(fs, proj, localCloud, updatesChan, loadEnv)
+
+		for _, msg := range debugMessages {
+			system.Log(msg)
+		}
+
+		if recorder != nil {
+			system.Log(fmt.Sprintf("recording session to %s", recorder.FilePath()))
+		}
+
+		if replaySession != nil {
+			go func() {
+				system.Log(fmt.Sprintf("replaying %d events from %s once services are ready", len(replaySession.Triggers), runReplay))
+
+				err := replaySession.Replay(cmd.Context(), localCloudReplayTarget{localCloud: localCloud}, func(trigger session.Trigger, err error) {
+					if err != nil {
+						system.Log(fmt.Sprintf("failed to replay %s: %v", trigger, err))
+					}
+				})
+				if err != nil {
+					system.Log(fmt.Sprintf("session replay stopped: %v", err))
+					return
+				}
+
+				system.Log("session replay complete")
+			}()
+		}
+
+		allUpdates := dash.CaptureLogs(lo.FanIn(10, updatesChan, systemChan))
 
 		// non-interactive environment
-		if isNonInteractive() {
+		if plainOutput() {
 			go func() {
 				sigChan := make(chan os.Signal, 1)
 				signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
@@ -168,7 +369,7 @@ var runCmd = &cobra.Command{
 				// Wait for a signal
 				<-sigChan
 
-				fmt.Println("Stopping local cloud")
+				fmt.Fprintln(progressOutput, "Stopping local cloud")
 
 				localCloud.Stop()
 
@@ -179,9 +380,9 @@ var runCmd = &cobra.Command{
 			for {
 				select {
 				case update := <-allUpdates:
-					fmt.Printf("%s [%s]: %s", update.ServiceName, update.Status, update.Message)
+					fmt.Fprintf(progressOutput, "%s [%s]: %s", update.ServiceName, update.Status, update.Message)
 				case <-stopChan:
-					fmt.Println("Shutting down services - exiting")
+					fmt.Fprintln(progressOutput, "Shutting down services - exiting")
 					return nil
 				}
 			}
@@ -198,14 +399,119 @@ var runCmd = &cobra.Command{
 	Args: cobra.ExactArgs(0),
 }
 
+// logEmailCapture - tells users where email sent by services is captured and can be viewed
+func logEmailCapture(localCloud *cloud.LocalCloud, dashboardUrl string) {
+	if localCloud.Email == nil {
+		return
+	}
+
+	system.Log(fmt.Sprintf("capturing email sent by services on smtp://localhost:%d, view it at %s/email", localCloud.Email.Port(), dashboardUrl))
+}
+
+// localCloudPersistence - returns the directory keeping the local cloud's state between runs and whether it's persisted,
+// the default run directory is used when it isn't. The --persist flag takes precedence over persist in local.nitric.yaml
+func localCloudPersistence(cmd *cobra.Command, proj *project.Project) (string, bool) {
+	persist := proj.LocalConfig.Persist
+	if cmd.Flags().Changed("persist") {
+		persist = runPersist
+	}
+
+	if !persist {
+		return "", false
+	}
+
+	return paths.NitricStateDir(proj.Directory), true
+}
+
+// seedLocalCloud - applies the project's seeds in the background as services declare the resources they populate
+func seedLocalCloud(fs afero.Fs, proj *project.Project, localCloud *cloud.LocalCloud, updates chan<- project.ServiceRunUpdate, env map[string]string) {
+	if len(proj.Seeds) == 0 {
+		return
+	}
+
+	go func() {
+		if err := proj.Seed(fs, localCloud, updates, env, runReseed); err != nil {
+			system.Log(err.Error())
+		}
+	}()
+}
+
+// logDependencies - tells users the host ports their dependency containers are published on
+func logDependencies(localCloud *cloud.LocalCloud) {
+	if localCloud.Dependencies == nil {
+		return
+	}
+
+	dependencyPorts := localCloud.Dependencies.Ports()
+	names := lo.Keys(dependencyPorts)
+	slices.Sort(names)
+
+	for _, name := range names {
+		ports := dependencyPorts[name]
+		containerPorts := lo.Keys(ports)
+		slices.Sort(containerPorts)
+
+		addresses := lo.Map(containerPorts, func(port int, _ int) string {
+			return fmt.Sprintf("localhost:%d (%d)", ports[port], port)
+		})
+
+		system.Log(fmt.Sprintf("dependency %s running on %s", name, strings.Join(addresses, ", ")))
+	}
+}
+
+// debugTargetMessages - adds launch configurations for the debug targets to the project's VS Code launch.json,
+// returning messages describing where to attach, the configurations are printed when launch.json can't be updated
+func debugTargetMessages(fs afero.Fs, proj *project.Project, targets []project.DebugTarget, unsupported []string) []string {
+	messages := []string{}
+
+	for _, target := range targets {
+		if target.Port == 0 {
+			messages = append(messages, fmt.Sprintf("%s can be debugged with %s through container %s", target.Service, target.Debugger, target.Container))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s can be debugged with %s on localhost:%d", target.Service, target.Debugger, target.Port))
+		}
+	}
+
+	if len(unsupported) > 0 {
+		messages = append(messages, fmt.Sprintf("debugging isn't supported by the runtimes of %s", strings.Join(unsupported, ", ")))
+	}
+
+	if len(targets) == 0 {
+		return messages
+	}
+
+	launchFile := filepath.Join(proj.Directory, ".vscode", "launch.json")
+
+	err := project.WriteLaunchConfigurations(fs, launchFile, targets)
+	if err == nil {
+		return append(messages, fmt.Sprintf("VS Code launch configurations written to %s", launchFile))
+	}
+
+	snippet, _ := project.MarshalLaunchJson(project.LaunchConfigurations(targets))
+
+	return append(messages, fmt.Sprintf("%v, add these configurations to it to attach from VS Code:\n%s", err, snippet))
+}
+
 func init() {
 	runCmd.Flags().StringVarP(&envFile, "env-file", "e", "", "--env-file config/.my-env")
-	runCmd.Flags().BoolVar(&enableHttps, "https-preview", false, "enable https support for local APIs (preview feature)")
+	runCmd.Flags().BoolVar(&enableHttps, "https", false, "serve local APIs, http proxies and websockets over https with a certificate signed by the local certificate authority")
+	runCmd.Flags().BoolVar(&enableHttps, "https-preview", false, "serve local APIs, http proxies and websockets over https")
+	tui.CheckErr(runCmd.Flags().MarkDeprecated("https-preview", "use --https instead"))
 	runCmd.PersistentFlags().BoolVar(
 		&runNoBrowser,
 		"no-browser",
 		false,
 		"disable browser opening for local dashboard, note: in CI mode the browser opening feature is disabled",
 	)
+	runCmd.Flags().StringVar(&runRecord, "record", "", "record inbound requests, topic events and manual schedule triggers to a session file, e.g. --record session.json")
+	runCmd.Flags().StringVar(&runReplay, "replay", "", "replay a recorded session file against the running services")
+	runCmd.Flags().StringVar(&runNetwork, "network", project.DefaultNetworkMode(), "network mode for service containers, one of bridge, host or the name of an existing docker network")
+	runCmd.Flags().StringSliceVar(&serviceFilter, "service", []string{}, "only build and run services matching a glob on their name or file path, can be repeated")
+	runCmd.Flags().StringSliceVar(&serviceExclude, "exclude", []string{}, "skip services matching a glob on their name or file path, can be repeated")
+	runCmd.Flags().BoolVar(&runDebug, "debug", false, "run services with an attachable debugger and add VS Code launch configurations for them to .vscode/launch.json")
+	runCmd.Flags().BoolVar(&runPersist, "persist", false, "keep the state of local buckets, key/value stores, secrets and queues between runs in .nitric/state, defaults to persist in local.nitric.yaml")
+	runCmd.Flags().BoolVar(&runReseed, "reseed", false, "apply the seeds in nitric.yaml again, even if they've already been applied to the local state")
+	runCmd.Flags().BoolVar(&runNoWarm, "no-warm-pool", false, "remove stopped service containers kept from previous runs and start services in new containers")
+	addBuildFlags(runCmd)
 	rootCmd.AddCommand(tui.AddDependencyCheck(runCmd, tui.Docker, tui.DockerBuildx))
 }
@@ -0,0 +1,563 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/nitrictech/cli/pkg/cloud/env"
+	"github.com/nitrictech/cli/pkg/cloud/secrets"
+	"github.com/nitrictech/cli/pkg/cloudsecrets"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/project/stack"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/view/tui/components/view"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
+)
+
+var (
+	secretRemote  string
+	secretVersion string
+)
+
+// secretStore - the secret manager read and written by nitric secret, the local cloud's secret store, the secret manager
+// of a deployed stack or a nitric server connected to a deployed stack
+type secretStore struct {
+	local  *secrets.DevSecretService
+	cloud  cloudsecrets.Store
+	remote secretspb.SecretManagerClient
+	conn   *grpc.ClientConn
+	// description of the store used in messages
+	target string
+}
+
+// deployedSecrets - true when nitric secret reads and writes the secrets of a deployed stack rather than the local cloud
+func deployedSecrets() bool {
+	return secretRemote != "" || stackFlag != ""
+}
+
+// openSecretStore - opens the secret manager of the stack selected with -s, the nitric server given with --remote, or
+// the local cloud's secret store, found in the same directory the local cloud uses when the project is run with the same
+// --persist setting
+func openSecretStore(cmd *cobra.Command, proj *project.Project) (*secretStore, error) {
+	if secretRemote == "" && stackFlag != "" {
+		stackConfig, err := stack.ConfigFromName[map[string]any](afero.NewOsFs(), stackFlag)
+		if err != nil {
+			return nil, err
+		}
+
+		// in CI the stack's OIDC settings provide short-lived credentials, otherwise the machine's credentials are used
+		federatedEnv, err := exchangeFederatedCredentials(stackConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range federatedEnv {
+			if err := os.Setenv(k, v); err != nil {
+				return nil, err
+			}
+		}
+
+		cloud, err := cloudsecrets.New(proj.Name, stackFlag, stackConfig.Provider, stackConfig.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		return &secretStore{cloud: cloud, target: "stack " + stackFlag}, nil
+	}
+
+	if secretRemote == "" {
+		secretsDir := env.LOCAL_SECRETS_DIR.String()
+		if runDir, _ := localCloudPersistence(cmd, proj); runDir != "" {
+			secretsDir = filepath.Join(runDir, "secrets")
+		}
+
+		local, err := secrets.NewSecretService(secretsDir)
+		if err != nil {
+			return nil, err
+		}
+
+		return &secretStore{local: local, target: "the local cloud"}, nil
+	}
+
+	// the connection isn't encrypted, so secret values are only sent to servers on this machine, e.g. a forwarded port
+	if host, _, err := net.SplitHostPort(secretRemote); err != nil || !isLoopback(host) {
+		return nil, fmt.Errorf("--remote connects without TLS and only accepts addresses on this machine, e.g. localhost:50051, use -s to reach a deployed stack's secret manager")
+	}
+
+	conn, err := grpc.NewClient(secretRemote, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", secretRemote, err)
+	}
+
+	return &secretStore{remote: secretspb.NewSecretManagerClient(conn), conn: conn, target: secretRemote}, nil
+}
+
+// isLoopback - true when the host is this machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *secretStore) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// put - stores a new version of the secret, returning the version
+func (s *secretStore) put(ctx context.Context, name string, value []byte) (string, error) {
+	req := &secretspb.SecretPutRequest{Secret: &secretspb.Secret{Name: name}, Value: value}
+
+	var (
+		resp *secretspb.SecretPutResponse
+		err  error
+	)
+
+	switch {
+	case s.local != nil:
+		resp, err = s.local.Put(ctx, req)
+	case s.cloud != nil:
+		resp, err = s.cloud.Put(ctx, req)
+	default:
+		resp, err = s.remote.Put(ctx, req)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("unable to set secret %s on %s: %w", name, s.target, err)
+	}
+
+	return resp.GetSecretVersion().GetVersion(), nil
+}
+
+// access - returns a version of the secret, nil when the secret has no value
+func (s *secretStore) access(ctx context.Context, name string, version string) (*secretspb.SecretAccessResponse, error) {
+	req := &secretspb.SecretAccessRequest{SecretVersion: &secretspb.SecretVersion{Secret: &secretspb.Secret{Name: name}, Version: version}}
+
+	var (
+		resp *secretspb.SecretAccessResponse
+		err  error
+	)
+
+	switch {
+	case s.local != nil:
+		resp, err = s.local.Access(ctx, req)
+	case s.cloud != nil:
+		resp, err = s.cloud.Access(ctx, req)
+	default:
+		resp, err = s.remote.Access(ctx, req)
+	}
+
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read secret %s from %s: %w", name, s.target, err)
+	}
+
+	return resp, nil
+}
+
+// declaredSecrets - returns the secrets declared by the project's services and the services declaring them, read from the
+// running local environment, or the last successful deployment of the stack selected with -s for a deployed stack.
+// Returns false when neither is available
+func declaredSecrets(proj *project.Project) (map[string][]string, bool, error) {
+	if deployedSecrets() {
+		if stackFlag == "" {
+			return nil, false, nil
+		}
+
+		deployment, err := deployedDigest(proj, stackFlag)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if deployment == nil {
+			return nil, false, fmt.Errorf("no successful deployment of stack %s was found, run nitric up -s %s to deploy it", stackFlag, stackFlag)
+		}
+
+		declared := map[string][]string{}
+
+		for _, res := range deployment.Declared {
+			if res.Type == resourcespb.ResourceType_Secret.String() {
+				declared[res.Name] = nil
+			}
+		}
+
+		return declared, true, nil
+	}
+
+	environment, err := runningEnvironment(proj)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	resp, err := environment.DashboardRequest(http.MethodGet, "/api/v1/resources", "", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unable to read the resources of %s from the local dashboard: %s", proj.Name, apiErrorMessage(resp))
+	}
+
+	resources := struct {
+		Secrets []struct {
+			Name               string   `json:"name"`
+			RequestingServices []string `json:"requestingServices"`
+		} `json:"secrets"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&resources); err != nil {
+		return nil, false, err
+	}
+
+	declared := map[string][]string{}
+
+	for _, secret := range resources.Secrets {
+		declared[secret.Name] = secret.RequestingServices
+	}
+
+	return declared, true, nil
+}
+
+// readSecretValue - prompts for the secret's value in interactive terminals, otherwise reads it from stdin without a trailing newline
+func readSecretValue(name string) ([]byte, error) {
+	if !isNonInteractive() {
+		value := ""
+
+		err := tui.AskOne(&survey.Password{Message: fmt.Sprintf("Value for secret %s", name)}, &value)
+
+		return []byte(value), err
+	}
+
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the value of secret %s from stdin: %w", name, err)
+	}
+
+	return []byte(strings.TrimSuffix(strings.TrimSuffix(string(value), "\n"), "\r")), nil
+}
+
+// secretResult - a version of a secret set or read by nitric secret, the value is only included when it's read
+type secretResult struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Value   string `json:"value,omitempty"`
+}
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Read and write the values of the secrets declared by your services",
+	Long: `Read and write the values of the secrets declared by your services, so values don't need to be kept in .env files.
+
+Values are stored in the local cloud's secret store by default, used by nitric run and nitric start. Services read the
+latest value when they access a secret, running services don't need to be restarted. Use --persist for the secrets of
+runs with --persist, kept between runs in .nitric/state.
+
+Use -s to read and write the secret manager of a deployed stack with this machine's cloud credentials, or short-lived
+credentials exchanged for the CI job's OIDC token when the stack configures oidc. Secrets are found by the tags the
+stack's provider adds to them, only stacks deployed to AWS Secrets Manager are currently supported.
+
+For other clouds use --remote with the address of a nitric server connected to the stack's resources on this machine,
+e.g. the runtime of the stack's provider run locally with the stack's credentials, or the nitric server port of a
+deployed service forwarded to this machine. The connection isn't encrypted, so only local addresses are accepted.
+
+These are the values of secret resources, use nitric env to manage the project's encrypted environment variables.`,
+	Example: `nitric secret set api-key
+nitric secret get api-key
+nitric secret list
+
+# Set the value of a deployed stack's secret from a file
+nitric secret set api-key -s aws < api-key.txt
+
+# Set the value through a nitric server forwarded to this machine
+nitric secret set api-key --remote localhost:50051 -s azure < api-key.txt`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name> [value] [-s stack]",
+	Short: "Store a new version of a secret",
+	Long: `Store a new version of a secret, which becomes the secret's latest version.
+
+The value is prompted for without being shown when it isn't given, or read from stdin in non-interactive terminals,
+keeping it out of your shell history. A trailing newline in stdin is removed.
+
+A warning is shown when the secret isn't declared by the project's services, while the project is running locally, or
+by the last deployment of the stack selected with -s.`,
+	Example: `nitric secret set api-key
+
+# Set the value from a file or another command
+nitric secret set api-key < api-key.txt
+op read op://dev/api-key | nitric secret set api-key
+
+# Set the value in a deployed stack
+nitric secret set api-key -s aws`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+		name := args[0]
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		var value []byte
+
+		if len(args) > 1 {
+			value = []byte(args[1])
+		} else {
+			value, err = readSecretValue(name)
+			tui.CheckErr(err)
+		}
+
+		if len(value) == 0 {
+			tui.CheckErr(fmt.Errorf("the value of secret %s is empty", name))
+		}
+
+		declared, known, err := declaredSecrets(proj)
+		tui.CheckErr(err)
+
+		if _, ok := declared[name]; known && !ok {
+			tui.Warning.Printfln("secret %s isn't declared by %s", name, lo.Ternary(deployedSecrets(), "the last deployment of stack "+stackFlag, "the project's services"))
+		}
+
+		store, err := openSecretStore(cmd, proj)
+		tui.CheckErr(err)
+		defer store.Close()
+
+		version, err := store.put(cmd.Context(), name, value)
+		tui.CheckErr(err)
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(secretResult{Name: name, Version: version}))
+
+			return
+		}
+
+		fmt.Fprintf(progressOutput, "Set secret %s to version %s on %s\n", name, version, store.target)
+	},
+	Args: cobra.RangeArgs(1, 2),
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print the value of a secret",
+	Long: `Print the value of a secret's latest version, or the version given with --version.
+
+The value is printed as it's stored, use -o json to output the value with its version.`,
+	Example: `nitric secret get api-key
+
+# Read from a deployed stack
+nitric secret get api-key -s aws`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+		name := args[0]
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		store, err := openSecretStore(cmd, proj)
+		tui.CheckErr(err)
+		defer store.Close()
+
+		resp, err := store.access(cmd.Context(), name, secretVersion)
+		tui.CheckErr(err)
+
+		if resp == nil {
+			tui.CheckErr(fmt.Errorf("secret %s has no %s version on %s, set it with nitric secret set %s", name, secretVersion, store.target, name))
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(secretResult{Name: name, Version: resp.GetSecretVersion().GetVersion(), Value: string(resp.Value)}))
+
+			return
+		}
+
+		_, err = os.Stdout.Write(resp.Value)
+		tui.CheckErr(err)
+
+		if tui.IsTerminal() {
+			fmt.Fprintln(progressOutput)
+		}
+	},
+	// the value is written exactly as it's stored, without the newline printed after other commands
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	Args:              cobra.ExactArgs(1),
+}
+
+// secretListEntry - a secret listed by nitric secret list
+type secretListEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// True when the secret has a value
+	Set bool `json:"set"`
+	// Services declaring the secret, when known
+	DeclaredBy []string `json:"declaredBy,omitempty"`
+	Declared   bool     `json:"declared"`
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list [-s stack]",
+	Short: "List the secrets declared by your services and whether they have a value",
+	Long: `List the secrets declared by your services and whether they have a value, with the version of each secret's latest value.
+
+The secrets declared while the project is running locally are listed, along with secrets in the local cloud's secret
+store. For a deployed stack, the secrets declared by the last deployment of the stack selected with -s are listed,
+since secret managers are read by secret name.`,
+	Example: `nitric secret list
+
+# List the secrets of a deployed stack
+nitric secret list -s aws`,
+	Aliases: []string{"ls"},
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		if secretRemote != "" && stackFlag == "" {
+			tui.CheckErr(fmt.Errorf("listing the secrets of a deployed stack requires -s, the secrets declared by the stack's last deployment are listed"))
+		}
+
+		declared, known, err := declaredSecrets(proj)
+		tui.CheckErr(err)
+
+		store, err := openSecretStore(cmd, proj)
+		tui.CheckErr(err)
+		defer store.Close()
+
+		names := lo.Keys(declared)
+
+		if store.local != nil {
+			stored, err := store.local.Names()
+			tui.CheckErr(err)
+
+			names = lo.Uniq(append(names, stored...))
+		}
+
+		slices.Sort(names)
+
+		entries := []secretListEntry{}
+
+		for _, name := range names {
+			resp, err := store.access(cmd.Context(), name, "latest")
+			tui.CheckErr(err)
+
+			services, isDeclared := declared[name]
+
+			entries = append(entries, secretListEntry{
+				Name:       name,
+				Version:    resp.GetSecretVersion().GetVersion(),
+				Set:        resp != nil,
+				DeclaredBy: services,
+				Declared:   isDeclared,
+			})
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(entries))
+
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintf(progressOutput, "No secrets found on %s%s\n", store.target, lo.Ternary(known || deployedSecrets(), "", ", start the project with nitric start to list the secrets declared by its services"))
+
+			return
+		}
+
+		nameLength := lo.Max(lo.Map(entries, func(e secretListEntry, _ int) int { return len(e.Name) }))
+
+		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(max(nameLength, len("secret")) + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		versionStyle := lipgloss.NewStyle().Width(38).PaddingLeft(1)
+		declaredStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("secret").WithStyle(nameStyle)
+		v.Add("version").WithStyle(versionStyle)
+		v.Addln("declared by").WithStyle(declaredStyle)
+		v.Break()
+
+		for _, e := range entries {
+			declaredBy := "-"
+
+			switch {
+			case len(e.DeclaredBy) > 0:
+				declaredBy = strings.Join(e.DeclaredBy, ", ")
+			case known && !e.Declared:
+				declaredBy = "not declared"
+			case e.Declared:
+				declaredBy = "stack " + stackFlag
+			}
+
+			v.Add("%s", e.Name).WithStyle(nameStyle)
+			v.Add("%s", lo.Ternary(e.Set, e.Version, "not set")).WithStyle(versionStyle.Copy().Foreground(lo.Ternary(e.Set, tui.Colors.Green, tui.Colors.Yellow)))
+			v.Addln("%s", declaredBy).WithStyle(declaredStyle.Copy().Faint(known && !e.Declared))
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+
+		if unset := lo.CountBy(entries, func(e secretListEntry) bool { return !e.Set }); unset > 0 {
+			tui.Warning.Printfln("%d secret(s) have no value, set them with nitric secret set <name>", unset)
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	secretCmd.PersistentFlags().StringVar(&secretRemote, "remote", "", "address on this machine of a nitric server connected to the deployed stack's resources, e.g. localhost:50051")
+	secretCmd.PersistentFlags().BoolVar(&runPersist, "persist", false, "use the local secrets kept between runs in .nitric/state, defaults to persist in local.nitric.yaml")
+
+	secretCmd.AddCommand(secretSetCmd)
+	tui.CheckErr(AddOptions(secretSetCmd, false))
+
+	secretCmd.AddCommand(secretGetCmd)
+	secretGetCmd.Flags().StringVar(&secretVersion, "version", "latest", "version of the secret to print")
+	tui.CheckErr(AddOptions(secretGetCmd, false))
+
+	secretCmd.AddCommand(secretListCmd)
+	tui.CheckErr(AddOptions(secretListCmd, false))
+
+	rootCmd.AddCommand(secretCmd)
+}
@@ -0,0 +1,295 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/rpc"
+	"github.com/nitrictech/cli/pkg/tunnel"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var (
+	serveApiAddress string
+	serveApiToken   string
+)
+
+// serveApiInfo - written to the project so local tools can discover the server
+type serveApiInfo struct {
+	Url   string `json:"url"`
+	Token string `json:"token"`
+	Pid   int    `json:"pid"`
+}
+
+type serveApiService struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"`
+	FilePath string `json:"filePath"`
+}
+
+type serveApiProject struct {
+	Name      string            `json:"name"`
+	Directory string            `json:"directory"`
+	Services  []serveApiService `json:"services"`
+	Stacks    []stackStatus     `json:"stacks"`
+}
+
+type serveApiJobOutput struct {
+	rpc.JobState
+	Output []string `json:"output"`
+	// Offset to request the next lines of output from
+	Next int `json:"next"`
+}
+
+// registerServeApiMethods - registers the methods of the serve-api server
+func registerServeApiMethods(server *rpc.Server, jobs *rpc.Jobs, fs afero.Fs, projectDir string) {
+	server.Register("project.load", func(json.RawMessage) (any, error) {
+		proj, err := project.FromFile(fs, "")
+		if err != nil {
+			return nil, err
+		}
+
+		stacks, err := stackStatuses(fs)
+		if err != nil {
+			return nil, err
+		}
+
+		services := []serveApiService{}
+		for _, service := range proj.GetServices() {
+			services = append(services, serveApiService{Name: service.Name, Type: service.Type, FilePath: service.GetFilePath()})
+		}
+
+		return serveApiProject{
+			Name:      proj.Name,
+			Directory: projectDir,
+			Services:  services,
+			Stacks:    stacks,
+		}, nil
+	})
+
+	server.Register("stack.list", func(json.RawMessage) (any, error) {
+		return stackStatuses(fs)
+	})
+
+	server.Register("build.start", func(json.RawMessage) (any, error) {
+		return jobs.Start(projectDir, "build", "build")
+	})
+
+	server.Register("run.start", func(params json.RawMessage) (any, error) {
+		p, err := rpc.Params[struct {
+			EnvFile string `json:"envFile"`
+			Start   bool   `json:"start"`
+		}](params)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(jobs.Running("run")) > 0 {
+			return nil, fmt.Errorf("the project is already running, stop job %s first", jobs.Running("run")[0].Id)
+		}
+
+		// nitric start runs services with their start commands rather than in containers
+		args := []string{"run", "--no-browser"}
+		if p.Start {
+			args = []string{"start", "--no-browser"}
+		}
+
+		if p.EnvFile != "" {
+			args = append(args, "--env-file", p.EnvFile)
+		}
+
+		return jobs.Start(projectDir, "run", args...)
+	})
+
+	server.Register("run.endpoints", func(json.RawMessage) (any, error) {
+		return tunnel.ReadEndpoints(projectDir)
+	})
+
+	server.Register("deploy.start", func(params json.RawMessage) (any, error) {
+		p, err := rpc.Params[struct {
+			Stack   string `json:"stack"`
+			EnvFile string `json:"envFile"`
+		}](params)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Stack == "" {
+			return nil, rpc.InvalidParams("stack is required")
+		}
+
+		args := []string{"stack", "update", "--stack", p.Stack}
+		if p.EnvFile != "" {
+			args = append(args, "--env-file", p.EnvFile)
+		}
+
+		return jobs.Start(projectDir, "deploy", args...)
+	})
+
+	server.Register("job.list", func(json.RawMessage) (any, error) {
+		return jobs.List(), nil
+	})
+
+	server.Register("job.status", func(params json.RawMessage) (any, error) {
+		p, err := rpc.Params[struct {
+			Id     string `json:"id"`
+			Offset int    `json:"offset"`
+		}](params)
+		if err != nil {
+			return nil, err
+		}
+
+		state, err := jobs.Get(p.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		output, next, err := jobs.Output(p.Id, p.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return serveApiJobOutput{JobState: state, Output: output, Next: next}, nil
+	})
+
+	server.Register("job.stop", func(params json.RawMessage) (any, error) {
+		p, err := rpc.Params[struct {
+			Id string `json:"id"`
+		}](params)
+		if err != nil {
+			return nil, err
+		}
+
+		return jobs.Stop(p.Id)
+	})
+}
+
+var serveApiCmd = &cobra.Command{
+	Use:   "serve-api",
+	Short: "Serve a local JSON-RPC API for controlling the CLI from other tools",
+	Long: `Serve a local JSON-RPC 2.0 API for controlling the CLI from other tools, e.g. GUIs and web dashboards.
+
+Requests are POSTed to the server's URL and must include the token in an Authorization: Bearer <token> header.
+The URL and token are printed on start and written to .nitric/serve-api.json in the project while the server is running.
+
+Builds, local runs and deployments run as background jobs, poll job.status for their status and output.
+
+Methods:
+  rpc.methods                         list the available methods
+  project.load                        the project's name, services and stacks
+  stack.list                          the status of the project's stacks
+  build.start                         build the project's services
+  run.start {envFile?, start?}        run the project locally, with nitric start when start is true
+  run.endpoints                       the endpoints of the running project
+  deploy.start {stack, envFile?}      deploy the project to a stack
+  job.list                            all jobs
+  job.status {id, offset?}            the status of a job and its output from the offset
+  job.stop {id}                       stop a running job`,
+	Example: `nitric serve-api
+
+# Call the API
+curl -H "Authorization: Bearer $TOKEN" -d '{"jsonrpc":"2.0","id":1,"method":"project.load"}' http://localhost:50060`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		projectDir, err := filepath.Abs(proj.Directory)
+		tui.CheckErr(err)
+
+		token := serveApiToken
+		if token == "" {
+			token = os.Getenv("NITRIC_SERVE_API_TOKEN")
+		}
+
+		if token == "" {
+			token, err = rpc.NewToken()
+			tui.CheckErr(err)
+		}
+
+		executable, err := os.Executable()
+		tui.CheckErr(err)
+
+		jobs := rpc.NewJobs(executable)
+		server := rpc.New(token)
+
+		registerServeApiMethods(server, jobs, fs, projectDir)
+
+		listener, err := net.Listen("tcp", serveApiAddress)
+		tui.CheckErr(err)
+
+		info := serveApiInfo{
+			Url:   fmt.Sprintf("http://%s", listener.Addr().String()),
+			Token: token,
+			Pid:   os.Getpid(),
+		}
+
+		infoFile := paths.NitricServeApiFile(projectDir)
+
+		contents, err := json.MarshalIndent(info, "", "  ")
+		tui.CheckErr(err)
+
+		tui.CheckErr(os.MkdirAll(filepath.Dir(infoFile), 0o700))
+		tui.CheckErr(os.WriteFile(infoFile, contents, 0o600))
+
+		defer os.Remove(infoFile)
+
+		httpServer := &http.Server{Handler: server} //nolint:gosec
+
+		go func() {
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+
+			<-sigChan
+
+			fmt.Fprintln(progressOutput, "Stopping jobs and shutting down")
+
+			jobs.StopAll()
+
+			_ = httpServer.Close()
+		}()
+
+		tui.Info.Printfln("Serving the nitric API for %s on %s", proj.Name, info.Url)
+		tui.Info.Printfln("Token: %s", token)
+
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			tui.CheckErr(err)
+		}
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	serveApiCmd.Flags().StringVar(&serveApiAddress, "address", "localhost:50060", "address to listen on, the API can deploy your project so only listen on trusted interfaces")
+	serveApiCmd.Flags().StringVar(&serveApiToken, "token", "", "token callers must provide, defaults to $NITRIC_SERVE_API_TOKEN or a random token")
+	rootCmd.AddCommand(serveApiCmd)
+}
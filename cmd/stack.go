@@ -17,18 +17,35 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/AlecAivazis/survey/v2"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/nitrictech/cli/pkg/budget"
 	"github.com/nitrictech/cli/pkg/collector"
+	"github.com/nitrictech/cli/pkg/credentials"
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/docker"
 	"github.com/nitrictech/cli/pkg/env"
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/i18n"
+	"github.com/nitrictech/cli/pkg/lock"
 	"github.com/nitrictech/cli/pkg/pflagx"
 	"github.com/nitrictech/cli/pkg/preview"
 	"github.com/nitrictech/cli/pkg/project"
@@ -44,26 +61,51 @@ import (
 	"github.com/nitrictech/cli/pkg/view/tui/components/list"
 	"github.com/nitrictech/cli/pkg/view/tui/components/view"
 	"github.com/nitrictech/cli/pkg/view/tui/teax"
+	"github.com/nitrictech/cli/pkg/watch"
 	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
 )
 
 var (
-	stackFlag     string // stack flag value
-	confirmDown   bool
-	forceStack    bool
-	forceNewStack bool
-	envFile       string
+	stackFlag   string // stack flag value
+	confirmDown bool
+	// overrides the provider in the stack file, e.g. noop to test the deployment pipeline without cloud credentials
+	providerOverride  string
+	forceStack        bool
+	forceNewStack     bool
+	envFile           string
+	rollbackOnFailure bool
+	// confirms the stack name for stacks that require it by a policy in nitric.yaml
+	confirmStackName string
 )
 
 var stackCmd = &cobra.Command{
-	Use:   "stack",
-	Short: "Manage stacks (the deployed app containing multiple resources e.g. services, buckets and topics)",
+	Use:     "stack",
+	Aliases: []string{"stacks"},
+	Short:   "Manage stacks (the deployed app containing multiple resources e.g. services, buckets and topics)",
 	Long: `Manage stacks (the deployed app containing multiple resources e.g. services, buckets and topics).
 
-A stack is a named update target, and a single project may have many of them.`,
+A stack is a named update target, and a single project may have many of them.
+
+Policies in nitric.yaml can require confirmation of the stack name, or restrict where commands run, before
+nitric stack up, down or gc --delete invoke the provider of a stack, e.g.
+
+  policies:
+    - stacks: [prod]
+      commands: [up, down, gc]
+      confirm: stack-name
+    - stacks: [prod]
+      commands: [down]
+      run-in: ci
+
+CI is detected with --ci or the CI environment variable. run-in guards against running a command in the wrong
+place by mistake, it isn't access control, as anyone able to run the command can set either. Restrict who can
+deploy with the permissions of the credentials used by the provider.`,
 	Example: `nitric stack up
 nitric stack down
 nitric stack list
+nitric stack status
+nitric stack gc -s prod
+nitric stack clone -s prod --as staging
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		if cmd.Root().PersistentPreRun != nil {
@@ -91,13 +133,405 @@ nitric stack list
 	},
 }
 
+var (
+	newStackTemplate string
+	skipCredentials  bool
+)
+
+// aliasRenamedResources - detects stateful resources that appear to have been renamed since the last successful deployment,
+// and offers to alias their existing state rather than destroying and recreating them when the stack's provider applies aliases
+func aliasRenamedResources(fs afero.Fs, proj *project.Project, stackConfig *stack.StackConfig[map[string]any], declared []digest.DeclaredResource) {
+	previous, err := digest.LatestSuccessful(proj.Name, stackConfig.Name)
+	if err != nil || previous == nil {
+		return
+	}
+
+	// aliases are advisory, providers that don't apply them destroy and recreate renamed resources
+	ignored, note := "", ""
+
+	for _, u := range provider.CheckSettings(stackConfig.Provider, []provider.Setting{provider.Setting_Aliases}) {
+		if u.Enforced {
+			ignored = u.Reason
+		} else {
+			note = fmt.Sprintf(", %s", u.Reason)
+		}
+	}
+
+	aliases := map[string]string{}
+
+	for _, rename := range digest.DetectRenames(previous.Declared, declared) {
+		if _, ok := stackConfig.Aliases[rename.AliasKey()]; ok {
+			continue
+		}
+
+		resourceType := strings.ToLower(rename.Type)
+
+		if ignored != "" {
+			tui.Warning.Printfln("%s '%s' appears to have been renamed to '%s' and will be destroyed and recreated, %s", resourceType, rename.From, rename.To, ignored)
+			continue
+		}
+
+		if isNonInteractive() {
+			tui.Warning.Printfln("%s '%s' appears to have been renamed to '%s' and will be destroyed and recreated, to keep its existing state add '%s: %s' to aliases in %s%s", resourceType, rename.From, rename.To, rename.AliasKey(), rename.From, stack.StackFileName(stackConfig.Name), note)
+			continue
+		}
+
+		alias := false
+		_ = tui.AskOne(&survey.Confirm{
+			Message: fmt.Sprintf("%s '%s' appears to have been renamed to '%s'. Keep its existing state instead of destroying and recreating it%s?", resourceType, rename.From, rename.To, note),
+			Default: true,
+		}, &alias)
+
+		if alias {
+			aliases[rename.AliasKey()] = rename.From
+		}
+	}
+
+	if len(aliases) == 0 {
+		return
+	}
+
+	err = stack.AddAliases(fs, stackConfig.Name, aliases)
+	tui.CheckErr(err)
+
+	stackConfig.Aliases = lo.Assign(stackConfig.Aliases, aliases)
+}
+
+// deployedDigest - returns the digest of the last successful deployment of a stack, the shared digest when it's readable and
+// more recent than the local digests, so resources deployed from other machines are compared against
+func deployedDigest(proj *project.Project, stackName string) (*digest.Digest, error) {
+	local, err := digest.LatestSuccessful(proj.Name, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadLocation := digestUploadLocation(proj.Digest)
+	if !digest.Readable(uploadLocation) {
+		return local, nil
+	}
+
+	shared, err := digest.LatestSuccessfulShared(context.Background(), uploadLocation, proj.Name, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if shared != nil && (local == nil || shared.EndTime.After(local.EndTime)) {
+		return shared, nil
+	}
+
+	return local, nil
+}
+
+// protectedOrphans - returns the keys of resources from the last successful deployment that are no longer declared but are protected
+// by the stack, these are retained by providers applying the retain setting rather than deleted
+func protectedOrphans(proj *project.Project, stackConfig *stack.StackConfig[map[string]any], spec *deploymentspb.Spec) []string {
+	previous, err := deployedDigest(proj, stackConfig.Name)
+	tui.CheckErr(err)
+
+	if previous == nil {
+		return nil
+	}
+
+	orphans := digest.Orphans(previous, spec, stackConfig.AliasedKeys())
+
+	return lo.FilterMap(orphans, func(orphan digest.Orphan, _ int) (string, bool) {
+		return orphan.Key(), stackConfig.IsProtected(orphan.Key())
+	})
+}
+
+// retainedAction - how protected orphans of the stack are shown, they're deleted by providers that don't apply the retain setting
+func retainedAction(stackConfig *stack.StackConfig[map[string]any]) string {
+	for _, u := range provider.CheckSettings(stackConfig.Provider, []provider.Setting{provider.Setting_Retain}) {
+		if u.Enforced {
+			return "delete (protected, not retained by the provider)"
+		}
+	}
+
+	return "retain (protected)"
+}
+
+// checkRetain - fails when protected resources would be deleted because the stack's provider doesn't apply the retain setting
+func checkRetain(stackConfig *stack.StackConfig[map[string]any], retained []string) {
+	unapplied := provider.CheckSettings(stackConfig.Provider, []provider.Setting{provider.Setting_Retain})
+
+	if len(unapplied) > 0 && unapplied[0].Enforced {
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, fmt.Errorf("protected resources %s are no longer declared and would be deleted, %s. Declare them in the project again to keep them, or remove them from protect in %s to delete them", strings.Join(retained, ", "), unapplied[0].Reason, stack.StackFileName(stackConfig.Name))))
+	}
+
+	if len(unapplied) > 0 {
+		tui.Warning.Printfln("%d protected resources are no longer declared and may be deleted, %s: %s", len(retained), unapplied[0].Reason, strings.Join(retained, ", "))
+		return
+	}
+
+	tui.Info.Printfln("%d protected resources are no longer declared and will be retained: %s", len(retained), strings.Join(retained, ", "))
+}
+
+// checkOrphanCleanup - fails unless deploying the spec only deletes orphaned resources, comparing its resources and service images
+// with the last successful deployment, so nitric stack gc --delete doesn't deploy changes waiting for nitric up
+func checkOrphanCleanup(proj *project.Project, stackConfig *stack.StackConfig[map[string]any], spec *deploymentspb.Spec, images map[string]string) {
+	previous, err := deployedDigest(proj, stackConfig.Name)
+	tui.CheckErr(err)
+
+	if previous == nil {
+		tui.CheckErr(fmt.Errorf("stack %s has not been deployed successfully, run nitric up -s %s first", stackConfig.Name, stackConfig.Name))
+	}
+
+	changes, err := digest.Plan(previous, spec, stackConfig.Aliases, stackConfig.IsProtected)
+	tui.CheckErr(err)
+
+	cleanupActions := []digest.ChangeAction{digest.ChangeAction_Unchanged, digest.ChangeAction_Delete, digest.ChangeAction_Retain}
+
+	pending := lo.FilterMap(changes, func(change digest.Change, _ int) (string, bool) {
+		return fmt.Sprintf("  %s: %s", change.Key(), change.Action), !slices.Contains(cleanupActions, change.Action)
+	})
+
+	imageRefs := lo.Keys(images)
+	slices.Sort(imageRefs)
+
+	for _, ref := range imageRefs {
+		if previous.Images[ref] != images[ref] {
+			pending = append(pending, fmt.Sprintf("  %s: new image", ref))
+		}
+	}
+
+	if len(pending) > 0 {
+		tui.CheckErr(exitcode.Wrap(exitcode.Drift, fmt.Errorf("stack %s has changes other than deleting orphaned resources, deploy them with nitric up -s %s before deleting orphans:\n%s", stackConfig.Name, stackConfig.Name, strings.Join(pending, "\n"))))
+	}
+}
+
+// enforceStackPolicies - applies the command policies in nitric.yaml to a stack before its provider is invoked,
+// exiting if the command isn't allowed or the stack name isn't confirmed
+func enforceStackPolicies(fs afero.Fs, command string, stackConfig *stack.StackConfig[map[string]any]) {
+	// simulated deployments don't change cloud resources
+	if stackConfig.Provider == provider.NoopProviderId {
+		return
+	}
+
+	projectConfig, err := project.ConfigurationFromFile(fs, "")
+	tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+	tui.CheckErr(exitcode.Wrap(exitcode.Config, projectConfig.ValidatePolicies()))
+
+	policies := projectConfig.CommandPolicies(command, stackConfig.Name)
+
+	tui.CheckErr(exitcode.Wrap(exitcode.Policy, project.CheckRunIn(policies, command, stackConfig.Name, CI)))
+
+	if !project.RequiresStackNameConfirmation(policies) || confirmStackName == stackConfig.Name {
+		return
+	}
+
+	if isNonInteractive() {
+		tui.CheckErr(exitcode.Wrap(exitcode.Policy, fmt.Errorf("nitric stack %s for stack %s requires confirmation, use --confirm-stack %s to confirm", command, stackConfig.Name, stackConfig.Name)))
+	}
+
+	typed := ""
+	_ = tui.AskOne(&survey.Input{
+		Message: fmt.Sprintf("Type the name of the stack to confirm nitric stack %s for %s", command, stackConfig.Name),
+	}, &typed)
+
+	if strings.TrimSpace(typed) != stackConfig.Name {
+		tui.CheckErr(exitcode.Wrap(exitcode.Policy, fmt.Errorf("the stack name didn't match, nitric stack %s was cancelled", command)))
+	}
+
+	// later commands run for the same stack, e.g. the update run by stack gc, don't ask again
+	confirmStackName = stackConfig.Name
+}
+
+// setupCredentials - an optional walkthrough that detects, logs in with and verifies the credentials needed to deploy with a provider
+func setupCredentials(providerName string) {
+	if !credentials.Supported(providerName) {
+		return
+	}
+
+	cloud := credentials.Name(providerName)
+
+	checkCredentials := false
+	_ = tui.AskOne(&survey.Confirm{
+		Message: fmt.Sprintf("Would you like to check your %s credentials now?", cloud),
+		Default: true,
+	}, &checkCredentials)
+
+	if !checkCredentials {
+		return
+	}
+
+	if credentials.Detect(providerName) {
+		identity, err := credentials.Verify(providerName)
+		if err == nil {
+			fmt.Fprintf(progressOutput, "%s credentials verified for %s\n", cloud, identity)
+			return
+		}
+
+		tui.Warning.Printfln("existing %s credentials could not be verified: %s", cloud, err)
+	} else {
+		fmt.Fprintf(progressOutput, "no %s credentials found\n", cloud)
+	}
+
+	loginCmd, err := credentials.LoginCommand(providerName)
+	tui.CheckErr(err)
+
+	login := false
+	_ = tui.AskOne(&survey.Confirm{
+		Message: fmt.Sprintf("Log in with `%s`? This may open your browser", loginCmd),
+		Default: true,
+	}, &login)
+
+	if !login {
+		fmt.Fprintf(progressOutput, "you can log in later with `%s`\n", loginCmd)
+		return
+	}
+
+	if err := credentials.Login(providerName); err != nil {
+		tui.Error.Println(err.Error())
+		return
+	}
+
+	identity, err := credentials.Verify(providerName)
+	if err != nil {
+		tui.Error.Printfln("unable to verify %s credentials: %s", cloud, err)
+		return
+	}
+
+	fmt.Fprintf(progressOutput, "%s credentials verified for %s\n", cloud, identity)
+}
+
+// warnQuotaIssues - warns about settings and resources of a stack that exceed the limits of its provider's cloud,
+// using the account's quotas when query is set. Deployments aren't stopped, as quotas can be raised and the known limits may be out of date
+func warnQuotaIssues(stackConfig *stack.StackConfig[map[string]any], spec *deploymentspb.Spec, query bool) {
+	for _, issue := range provider.CheckQuotas(stackConfig.Provider, stackConfig.Region, stackConfig.Config, spec, query) {
+		tui.Warning.Printfln("%s: %s, the deployment is likely to fail", issue.Subject, issue.Reason)
+	}
+}
+
+// stackSettings - returns the settings of the project and stack passed to its provider as deployment attributes
+func stackSettings(proj *project.Project, stackConfig *stack.StackConfig[map[string]any]) []provider.Setting {
+	settings := []provider.Setting{}
+
+	if len(stackConfig.Security) > 0 {
+		settings = append(settings, provider.Setting_Security)
+	}
+
+	if len(stackConfig.Aliases) > 0 {
+		settings = append(settings, provider.Setting_Aliases)
+	}
+
+	if len(proj.ApisRequiringApiKey()) > 0 {
+		settings = append(settings, provider.Setting_ApiKeyRequired)
+	}
+
+	if len(proj.ApiRateLimits()) > 0 {
+		settings = append(settings, provider.Setting_ApiRateLimits)
+	}
+
+	if len(stackConfig.AllRegions()) > 1 {
+		settings = append(settings, provider.Setting_Placement)
+	}
+
+	if stackConfig.Monitoring != nil {
+		settings = append(settings, provider.Setting_Monitoring)
+	}
+
+	if stackConfig.Logs != nil && stackConfig.Logs.RetentionDays > 0 {
+		settings = append(settings, provider.Setting_LogRetention)
+	}
+
+	if stackConfig.Encryption != nil {
+		settings = append(settings, provider.Setting_Encryption)
+	}
+
+	if proj.Email != nil {
+		settings = append(settings, provider.Setting_Email)
+	}
+
+	return settings
+}
+
+// checkStackSettings - fails when the stack's provider ignores a setting that leaves resources open when it isn't applied,
+// and warns about other settings the provider doesn't apply
+func checkStackSettings(stackConfig *stack.StackConfig[map[string]any], settings []provider.Setting) {
+	unapplied := provider.CheckSettings(stackConfig.Provider, settings)
+
+	enforced := lo.FilterMap(unapplied, func(u provider.UnappliedSetting, _ int) (string, bool) {
+		return fmt.Sprintf("  %s: %s", u.Setting, u.Reason), u.Enforced
+	})
+	if len(enforced) > 0 {
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, fmt.Errorf("stack %s has settings its provider doesn't apply, remove them from %s or use a provider that applies them, see nitric provider capabilities:\n%s", stackConfig.Name, stack.StackFileName(stackConfig.Name), strings.Join(enforced, "\n"))))
+	}
+
+	if structuredOutput() {
+		return
+	}
+
+	for _, u := range unapplied {
+		tui.Warning.Printfln("stack %s: %s", stackConfig.Name, u.Reason)
+	}
+}
+
+// federatedCredentials - exchanges the OIDC token of the CI job for short-lived cloud credentials when the stack configures oidc,
+// returning the environment variables that provide them to the provider. Existing credentials are used outside CI.
+func federatedCredentials(stackConfig *stack.StackConfig[map[string]any]) map[string]string {
+	federatedEnv, err := exchangeFederatedCredentials(stackConfig)
+	tui.CheckErr(exitcode.Wrap(exitcode.Deployment, err))
+
+	if len(federatedEnv) > 0 {
+		tui.Info.Printfln("using short-lived %s credentials exchanged for the CI job's OIDC token", credentials.Name(credentials.FederationCloud(stackConfig.Provider)))
+	}
+
+	return federatedEnv
+}
+
+// exchangeFederatedCredentials - the environment variables providing the credentials exchanged for the CI job's OIDC token,
+// empty when the stack doesn't configure oidc or nitric isn't running in CI
+func exchangeFederatedCredentials(stackConfig *stack.StackConfig[map[string]any]) (map[string]string, error) {
+	federation, ok := stackConfig.Federation()
+	if !ok || !federation.IdTokenAvailable() {
+		return map[string]string{}, nil
+	}
+
+	return federation.Exchange(credentials.FederationCloud(stackConfig.Provider))
+}
+
+// selectStack - asks which of the project's stacks to use, returning an empty string if none was selected
+func selectStack(prompt string, stackList []list.ListItem) string {
+	if tui.Accessible() {
+		choice := ""
+
+		tui.CheckErr(tui.AskOne(&survey.Select{
+			Message: prompt,
+			Options: lo.Map(stackList, func(item list.ListItem, _ int) string { return item.GetItemValue() }),
+		}, &choice))
+
+		return choice
+	}
+
+	selection, err := teax.NewProgram(stack_select.New(stack_select.Args{
+		Prompt:    prompt,
+		StackList: stackList,
+	})).Run()
+	tui.CheckErr(err)
+
+	return selection.(stack_select.Model).Choice()
+}
+
 var newStackCmd = &cobra.Command{
 	Use:   "new [stackName] [providerName]",
 	Short: "Create a new Nitric stack",
-	Long:  `Creates a new Nitric stack.`,
+	Long: `Creates a new Nitric stack.
+
+Use --template to create the stack from a preset with its provider configuration pre-filled, available templates are:
+` + strings.Join(lo.Map(stack.Presets, func(p stack.Preset, _ int) string {
+		return fmt.Sprintf("  %s: %s", p.Name, p.Description)
+	}), "\n"),
+	Example: `nitric stack new dev aws
+
+# Create a stack deploying to a local kubernetes cluster
+nitric stack new local-k8s kubernetes
+
+# Create a stack from a preset
+nitric stack new dev --template serverless-aws`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if !tui.IsTerminal() {
-			return fmt.Errorf("the stack new command does not support non-interactive environments")
+			return i18n.Errorf("stack.new.non_interactive")
 		}
 
 		stackName := ""
@@ -109,23 +543,99 @@ var newStackCmd = &cobra.Command{
 		if len(args) >= 2 {
 			providerName = args[1]
 		}
-		_, err := teax.NewProgram(stack_new.New(afero.NewOsFs(), stack_new.Args{
+		newArgs := stack_new.Args{
 			StackName:    stackName,
 			ProviderName: providerName,
+			Template:     newStackTemplate,
 			Force:        forceNewStack,
-		})).Run()
+		}
+		teaOptions := []tea.ProgramOption{}
+
+		if tui.Accessible() {
+			var err error
+
+			newArgs, err = stack_new.Ask(afero.NewOsFs(), newArgs)
+			if err != nil {
+				return err
+			}
 
-		return err
+			teaOptions = append(teaOptions, teax.PlainOptions()...)
+		}
+
+		model, err := teax.NewProgram(stack_new.New(afero.NewOsFs(), newArgs), teaOptions...).Run()
+		if err != nil {
+			return err
+		}
+
+		if stackModel, ok := model.(stack_new.Model); ok && stackModel.Created() && !skipCredentials {
+			setupCredentials(stackModel.Provider())
+		}
+
+		return nil
 	},
 	Args:        cobra.MaximumNArgs(2),
 	Annotations: map[string]string{"commonCommand": "yes"},
 }
 
 var stackUpdateCmd = &cobra.Command{
-	Use:     "update [-s stack]",
-	Short:   "Create or update a deployed stack",
-	Long:    `Create or update a deployed stack`,
-	Example: `nitric stack update -s aws`,
+	Use:   "update [-s stack]",
+	Short: "Create or update a deployed stack",
+	Long: `Create or update a deployed stack.
+
+Use --provider noop, or set noop as the provider of a stack, to simulate the deployment without cloud credentials.
+The noop provider records simulated resources in the .nitric directory and reports a deterministic summary,
+resources matching a pattern in the stack's fail list, e.g. bucket/*, are reported as failed. Deployments simulated
+with --provider noop aren't recorded in the stack's history or used as its rollback point.
+
+Set terraform/aws as the provider of a stack to generate a terraform configuration instead of deploying with pulumi.
+The configuration is written to terraform/<stack>, or the output-dir set in the stack file, and a terraform backend
+can be set with backend, e.g. backend: {s3: {bucket: my-state, key: app.tfstate, region: us-east-1}}.
+Service images are wrapped with the nitric AWS runtime set with runtime, a URL or path to the runtime binary.
+Set terraform/do to deploy services to DigitalOcean App Platform, buckets to Spaces and sql databases to Managed Postgres,
+images are pushed to the existing container registry set with registry. Its runtime isn't released by nitric yet,
+build it with the server package of github.com/nitrictech/nitric/core.
+Apply the generated configuration with terraform, websockets, http proxies and sql databases are not yet supported.
+
+Set cloudflare as the provider to generate a wrangler project deploying services as Cloudflare Containers behind a worker,
+buckets to R2, key value stores to KV and queues to Cloudflare Queues, in the account set with account-id.
+The project is written to cloudflare/<stack>, run its setup.sh once to create the stack's resources, then npx wrangler deploy.
+Services granted access to resources by a policy are given the worker's CLOUDFLARE_API_TOKEN, its permissions rather than
+the policies limit their access. The Cloudflare runtime isn't released by nitric yet, set runtime to one you've built.
+The terraform and cloudflare providers don't change cloud resources, so their runs aren't recorded in the stack's history.
+
+Set kubernetes/kind or kubernetes/k3d to deploy to a kubernetes cluster on this machine without cloud credentials, the cluster
+set with cluster is created when it doesn't exist. Services are deployed with the nitric kubernetes runtime set with runtime,
+which nitric doesn't release yet, build it with the server package of github.com/nitrictech/nitric/core.
+APIs and HTTP proxies are served by the cluster's ingress at http://<name>.localhost:<port> and schedules run as CronJobs.
+
+Transformers set in nitric.yaml run in order between collecting the spec and sending it to the provider, each receiving
+the spec as JSON on stdin and writing the transformed spec to stdout, e.g. to add resources mandated by your organization.
+
+Settings and resource counts exceeding the limits of AWS, Google Cloud or Azure are warned about before deploying,
+using the account's lambda concurrency and bucket quotas on AWS when the aws CLI is installed.
+
+Set oidc in the stack file to deploy from GitHub Actions or GitLab without stored cloud keys, the CI job's OIDC token
+is exchanged for short-lived credentials with the stack's cloud before the provider starts.
+
+With --rollback-on-failure, or rollback-on-failure: true in the stack file, a failed deployment is followed by re-applying
+the last successful deployment from this machine, including the images it deployed, rather than leaving the stack
+partially deployed. Database migrations that already ran aren't reverted.
+
+With build.licenses set in nitric.yaml, or --scan-licenses, service images are scanned for the licenses of their
+dependencies after they're built and the deployment stops when packages have denied licenses, see nitric build --help.
+
+The stack is locked while it's deployed, so deployments of the same stack by teammates or CI fail with exit code 8 until
+it's finished, see nitric stack unlock --help.`,
+	Example: `nitric stack update -s aws
+
+# Test the deployment pipeline in CI without cloud credentials
+nitric stack update -s aws --provider noop --ci
+
+# Generate a terraform configuration for the stack
+nitric stack update -s aws --provider terraform/aws
+
+# Output the deployment result, including the deployed API endpoints, as JSON
+nitric stack update -s aws -o json`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fs := afero.NewOsFs()
 
@@ -133,7 +643,7 @@ var stackUpdateCmd = &cobra.Command{
 		tui.CheckErr(err)
 
 		if len(stackFiles) == 0 {
-			tui.CheckErr(fmt.Errorf("no stacks found in project, to create a new one run `nitric stack new`"))
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
 		}
 
 		// Step 0. Get the stack file, or prompt if more than 1.
@@ -160,14 +670,7 @@ var stackUpdateCmd = &cobra.Command{
 					}
 				}
 
-				promptModel := stack_select.New(stack_select.Args{
-					Prompt:    "Which stack would you like to update?",
-					StackList: stackList,
-				})
-
-				selection, err := teax.NewProgram(promptModel).Run()
-				tui.CheckErr(err)
-				stackSelection = selection.(stack_select.Model).Choice()
+				stackSelection = selectStack(i18n.Message("stack.update.select_prompt"), stackList)
 				if stackSelection == "" {
 					return
 				}
@@ -180,13 +683,49 @@ var stackUpdateCmd = &cobra.Command{
 		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackSelection)
 		tui.CheckErr(err)
 
-		if !isNonInteractive() {
+		if providerOverride != "" {
+			stackConfig.Provider = providerOverride
+		}
+
+		err = stackConfig.ValidateSecurity()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		retryPolicy, err := stackConfig.RetryPolicy()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = stackConfig.ValidateRegions()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = stackConfig.ValidateCompliance()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = stackConfig.ValidateProtect()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = stackConfig.ValidateOidc()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		enforceStackPolicies(fs, "up", stackConfig)
+
+		// providers built into the CLI don't use pulumi state
+		if !isNonInteractive() && provider.UsesPulumi(stackConfig.Provider) {
 			_ = pulumi.EnsurePulumiPassphrase(fs)
 		}
 
 		proj, err := project.FromFile(fs, "")
 		tui.CheckErr(err)
 
+		applyBuildFlags(proj)
+
+		releaseLock := lockStack(proj, stackConfig.Name, "up")
+		defer releaseLock()
+
+		err = stackConfig.ValidateMonitoring(proj.Notifications.Webhooks)
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		err = stackConfig.ValidateEmail(lo.FromPtr(proj.Email).From)
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
 		// Step 0a. Locate/Download provider where applicable.
 		prov, err := provider.NewProvider(stackConfig.Provider, proj, fs)
 		tui.CheckErr(err)
@@ -195,35 +734,54 @@ var stackUpdateCmd = &cobra.Command{
 		tui.CheckErr(err)
 
 		// Build the Project's Services (Containers)
-		buildUpdates, err := proj.BuildServices(fs)
-		tui.CheckErr(err)
+		checkBuildDiskSpace(fs, proj, buildFlagOptions()...)
 
-		if isNonInteractive() {
-			fmt.Println("building project services")
+		buildUpdates, err := proj.BuildServices(fs, buildFlagOptions()...)
+		tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
+
+		if plainOutput() {
+			fmt.Fprintln(progressOutput, "building project services")
 			for _, service := range proj.GetServices() {
-				fmt.Printf("service matched '%s', auto-naming this service '%s'\n", service.GetFilePath(), service.Name)
+				fmt.Fprintf(progressOutput, "service matched '%s', auto-naming this service '%s'\n", service.GetFilePath(), service.Name)
 			}
 
 			// non-interactive environment
+			buildFailed := false
+
 			for update := range buildUpdates {
+				if update.Status == project.ServiceBuildStatus_Error {
+					buildFailed = true
+				}
+
+				// step names from progress updates are already included in the build logs
+				if update.Progress != nil {
+					continue
+				}
+
 				for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
-					fmt.Printf("%s [%s]: %s\n", update.ServiceName, update.Status, line)
+					fmt.Fprintf(progressOutput, "%s [%s]: %s\n", update.ServiceName, update.Status, line)
 				}
 			}
+
+			if buildFailed {
+				tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
+			}
 		} else {
 			prog := teax.NewProgram(build.NewModel(buildUpdates, "Building Services"))
 			// blocks but quits once the above updates channel is closed by the build process
 			buildModel, err := prog.Run()
 			tui.CheckErr(err)
 			if buildModel.(build.Model).Err != nil {
-				tui.CheckErr(fmt.Errorf("error building services"))
+				tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
 			}
 		}
 
+		checkLicenses(fs, proj)
+
 		// Step 2. Start the collectors and containers (respectively in pairs)
 		// Step 3. Merge requirements from collectors into a specification
 		serviceRequirements, err := proj.CollectServicesRequirements()
-		tui.CheckErr(err)
+		tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
 
 		additionalEnvFiles := []string{}
 
@@ -232,7 +790,7 @@ var stackUpdateCmd = &cobra.Command{
 		}
 
 		envVariables, err := env.ReadLocalEnv(additionalEnvFiles...)
-		if err != nil && os.IsNotExist(err) {
+		if err != nil {
 			if !os.IsNotExist(err) {
 				tui.CheckErr(err)
 			}
@@ -240,6 +798,9 @@ var stackUpdateCmd = &cobra.Command{
 			envVariables = map[string]string{}
 		}
 
+		// Stack flags override project flags, explicitly provided env variables take precedence over both
+		envVariables = lo.Assign(project.FlagsToEnv(proj.Flags, stackConfig.Flags), envVariables)
+
 		// Allow Beta providers to be run if 'beta-providers' is enabled in preview flags
 		if slices.Contains(proj.Preview, preview.Feature_BetaProviders) {
 			envVariables["NITRIC_BETA_PROVIDERS"] = "true"
@@ -251,44 +812,84 @@ var stackUpdateCmd = &cobra.Command{
 		}
 
 		migrationImageContexts, err := collector.GetMigrationImageBuildContexts(serviceRequirements, fs)
-		tui.CheckErr(err)
+		tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
 		// Build images from contexts and provide updates on the builds
 
 		if len(migrationImageContexts) > 0 {
-			migrationBuildUpdates, err := project.BuildMigrationImages(fs, migrationImageContexts)
-			tui.CheckErr(err)
+			migrationBuildUpdates, err := project.BuildMigrationImages(fs, migrationImageContexts, project.WithBuildConfiguration(proj.Build))
+			tui.CheckErr(exitcode.Wrap(exitcode.Build, err))
 
-			if isNonInteractive() {
-				fmt.Println("building project migration images")
+			if plainOutput() {
+				fmt.Fprintln(progressOutput, "building project migration images")
 				// non-interactive environment
+				buildFailed := false
+
 				for update := range migrationBuildUpdates {
+					if update.Status == project.ServiceBuildStatus_Error {
+						buildFailed = true
+					}
+
 					for _, line := range strings.Split(strings.TrimSuffix(update.Message, "\n"), "\n") {
-						fmt.Printf("%s [%s]: %s\n", update.ServiceName, update.Status, line)
+						fmt.Fprintf(progressOutput, "%s [%s]: %s\n", update.ServiceName, update.Status, line)
 					}
 				}
+
+				if buildFailed {
+					tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building database migrations")))
+				}
 			} else {
 				prog := teax.NewProgram(build.NewModel(migrationBuildUpdates, "Building Database Migrations"))
 				// blocks but quits once the above updates channel is closed by the build process
 				buildModel, err := prog.Run()
 				tui.CheckErr(err)
 				if buildModel.(build.Model).Err != nil {
-					tui.CheckErr(fmt.Errorf("error building services"))
+					tui.CheckErr(exitcode.Wrap(exitcode.Build, fmt.Errorf("error building services")))
 				}
 			}
 		}
 
 		spec, err := collector.ServiceRequirementsToSpec(proj.Name, envVariables, serviceRequirements, defaultImageName)
+		tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
+
+		spec, err = proj.TransformSpec(spec, stackConfig.Name)
+		tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
+
+		if !structuredOutput() {
+			warnQuotaIssues(stackConfig, spec, true)
+		}
+
+		checkStackSettings(stackConfig, stackSettings(proj, stackConfig))
+
+		declaredResources, err := digest.DeclaredResources(spec)
 		tui.CheckErr(err)
 
+		resourceHashes, err := digest.ResourceHashes(spec)
+		tui.CheckErr(err)
+
+		deployedImages := deployedImageIds(spec)
+
+		aliasRenamedResources(fs, proj, stackConfig, declaredResources)
+
+		// nitric stack gc --delete only deletes orphaned resources, other changes are deployed by nitric up
+		if orphanCleanup {
+			checkOrphanCleanup(proj, stackConfig, spec, deployedImages)
+		}
+
+		retained := protectedOrphans(proj, stackConfig, spec)
+		if len(retained) > 0 {
+			checkRetain(stackConfig, retained)
+		}
+
 		providerStdout := make(chan string)
 
 		// Step 4. Start the deployment provider server
 		providerAddress, err := prov.Start(&provider.StartOptions{
-			Env:    envVariables,
-			StdOut: providerStdout,
-			StdErr: providerStdout,
+			Env:          lo.Assign(envVariables, federatedCredentials(stackConfig)),
+			EnvAllowlist: stackConfig.ProviderEnv(),
+			StdOut:       providerStdout,
+			StdErr:       providerStdout,
 		})
-		tui.CheckErr(err)
+		tui.CheckErr(exitcode.Wrap(exitcode.Deployment, err))
 		defer func() {
 			err := prov.Stop()
 			tui.CheckErr(err)
@@ -306,70 +907,528 @@ var stackUpdateCmd = &cobra.Command{
 			attributes[k] = v
 		}
 
-		attributesStruct, err := structpb.NewStruct(attributes)
-		tui.CheckErr(err)
+		for k, v := range stackConfig.RegionAttributes() {
+			attributes[k] = v
+		}
 
-		eventChan, errorChan := deploymentClient.Up(&deploymentspb.DeploymentUpRequest{
-			Spec:        spec,
-			Attributes:  attributesStruct,
-			Interactive: true,
-		})
+		// providers applying the placement setting deploy each resource to the regions it's placed in
+		if len(stackConfig.AllRegions()) > 1 {
+			resourceKeys := lo.Map(spec.Resources, func(r *deploymentspb.Resource, _ int) string {
+				return digest.AliasKey(r.Id.Type.String(), r.Id.Name)
+			})
 
-		// Step 5b. Communicate with server to share progress of ...
-		if isNonInteractive() {
-			fmt.Printf("Deploying %s stack with provider %s\n", stackConfig.Name, stackConfig.Provider)
-			go func() {
-				for update := range errorChan {
-					fmt.Printf("Error: %s\n", update)
-				}
-			}()
+			attributes["placement"] = stackConfig.PlacementAttributes(resourceKeys)
+		}
 
-			go func() {
-				for outMessage := range providerStdout {
-					fmt.Printf("%s: %s\n", stackConfig.Provider, outMessage)
+		// providers applying the retain setting remove retained resources from the stack's state without deleting them
+		if len(retained) > 0 {
+			attributes["retain"] = lo.ToAnySlice(retained)
+		}
+
+		// providers applying the aliases setting reuse the existing state of renamed resources
+		if len(stackConfig.Aliases) > 0 {
+			attributes["aliases"] = lo.MapValues(stackConfig.Aliases, func(name string, _ string) interface{} { return name })
+		}
+
+		// providers applying the api-rate-limits setting configure rate limits on their API gateways
+		apiRateLimits := map[string]interface{}{}
+		for apiName, api := range proj.Apis {
+			if api.RateLimit != nil {
+				apiRateLimits[apiName] = map[string]interface{}{
+					"requests-per-second": api.RateLimit.RequestsPerSecond,
+					"burst":               api.RateLimit.Burst,
 				}
-			}()
+			}
+		}
 
-			// non-interactive environment
-			for update := range eventChan {
-				switch content := update.Content.(type) {
-				case *deploymentspb.DeploymentUpEvent_Message:
-					fmt.Printf("%s\n", content.Message)
-				case *deploymentspb.DeploymentUpEvent_Update:
-					updateResType := ""
-					updateResName := ""
-					if content.Update.Id != nil {
-						updateResType = content.Update.Id.Type.String()
-						updateResName = content.Update.Id.Name
-					}
+		if len(apiRateLimits) > 0 {
+			attributes["api-rate-limits"] = apiRateLimits
+		}
 
-					if updateResType == "" {
-						updateResType = "Stack"
-					}
-					if updateResName == "" {
-						updateResName = stackConfig.Name
-					}
-					if content.Update.SubResource != "" {
-						updateResName = fmt.Sprintf("%s:%s", updateResName, content.Update.SubResource)
-					}
+		// providers applying the security setting translate the policies to their WAF, Cloud Armor or Front Door equivalents
+		if len(stackConfig.Security) > 0 {
+			attributes["security"] = stackConfig.SecurityAttributes()
+		}
+
+		// providers applying the api-key-required setting create usage plans and keys for APIs requiring an API key
+		if apiNames := proj.ApisRequiringApiKey(); len(apiNames) > 0 {
+			attributes["api-key-required"] = lo.ToAnySlice(apiNames)
+		}
+
+		// providers applying the log-retention-days and encryption settings apply retention to their log groups and the key
+		// to log groups and data resources
+		for k, v := range stackConfig.ComplianceAttributes() {
+			attributes[k] = v
+		}
+
+		// providers applying the email setting verify the sending domain with SES or SendGrid and give services the SMTP endpoint and credentials
+		if proj.Email != nil {
+			attributes["email"] = stackConfig.EmailAttributes(proj.Email.From)
+		}
+
+		// providers applying the monitoring setting provision budget alerts and alarms on their native billing and monitoring services
+		if stackConfig.Monitoring != nil {
+			attributes["monitoring"] = stackConfig.MonitoringAttributes(proj.Notifications.Webhooks)
+		}
+
+		// providers supporting retries of individual resources use the same policy
+		attributes["retry"] = retryPolicy.Attributes()
 
-					fmt.Printf("%s:%s [%s]:%s %s\n", updateResType, updateResName, content.Update.Action, content.Update.Status, content.Update.Message)
-				case *deploymentspb.DeploymentUpEvent_Result:
-					fmt.Printf("\nResult: %s\n", content.Result.GetText())
+		attributesStruct, err := structpb.NewStruct(attributes)
+		tui.CheckErr(err)
+
+		if plainOutput() {
+			go func() {
+				for outMessage := range providerStdout {
+					fmt.Fprintf(progressOutput, "%s: %s\n", stackConfig.Provider, outMessage)
 				}
-			}
-		} else {
-			// interactive environment
-			// Step 5c. Start the stack up view
-			stackUp := stack_up.New(stackConfig.Provider, stackConfig.Name, eventChan, providerStdout, errorChan)
-			_, err = teax.NewProgram(stackUp).Run()
+			}()
+		}
+
+		var deploymentDigest *digest.Digest
+
+		deployStart := time.Now().UTC()
+		commit := proj.GitCommit()
+
+		for attempt := 1; ; attempt++ {
+			deploymentDigest = digest.New(proj.Name, stackConfig.Name, stackConfig.Provider)
+			deploymentDigest.StartTime = deployStart
+			deploymentDigest.Attempts = attempt
+			deploymentDigest.ConfigHash, err = stack.ConfigHash(fs, stackConfig.Name)
 			tui.CheckErr(err)
+			deploymentDigest.Declared = declaredResources
+			deploymentDigest.ResourceHashes = resourceHashes
+			deploymentDigest.Regions = stackConfig.AllRegions()
+			deploymentDigest.Commit = commit
+			deploymentDigest.Images = deployedImages
+
+			if plainOutput() {
+				fmt.Fprintf(progressOutput, "Deploying %s stack with provider %s%s\n", stackConfig.Name, stackConfig.Provider, regionsSuffix(stackConfig.AllRegions()))
+			}
+
+			// Step 5b. Communicate with server to share progress of ...
+			streamDeployment(deploymentClient, &deploymentspb.DeploymentUpRequest{
+				Spec:        spec,
+				Attributes:  attributesStruct,
+				Interactive: true,
+			}, deploymentDigest, stackConfig, providerStdout)
+
+			if deploymentDigest.Success || attempt >= retryPolicy.MaxAttempts {
+				break
+			}
+
+			reason, transient := retryPolicy.Transient(deploymentDigest.FailureMessages())
+			if !transient {
+				break
+			}
+
+			tui.Warning.Printfln("deployment failed with a transient error, retrying in %s (attempt %d of %d): %s", retryPolicy.Delay, attempt+1, retryPolicy.MaxAttempts, reason)
+			time.Sleep(retryPolicy.Delay)
+		}
+
+		// Step 6. Keep a record of the deployment
+		digestFile := ""
+		if recordsDeployment(stackConfig) {
+			digestFile = writeDigest(proj, deploymentDigest)
+		}
+
+		printDeploymentFailures(deploymentDigest, digestFile)
+
+		var rollback *stackRollbackResult
+
+		if deploymentDigest.Success {
+			if recordsDeployment(stackConfig) {
+				saveRollbackPoint(deploymentDigest, spec)
+			}
+		} else if rollbackOnFailure || stackConfig.RollbackOnFailure {
+			rollback = rollbackDeployment(proj, stackConfig, deploymentClient, attributesStruct, envVariables, providerStdout)
+		}
+
+		if warning, exceeded := budget.Check(budget.Deploy, deploymentDigest.EndTime.Sub(deploymentDigest.StartTime)); exceeded {
+			tui.Warning.Println(warning)
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(stackUpResult{
+				Project:   deploymentDigest.Project,
+				Stack:     deploymentDigest.Stack,
+				Provider:  deploymentDigest.Provider,
+				Regions:   deploymentDigest.Regions,
+				StartTime: deploymentDigest.StartTime,
+				EndTime:   deploymentDigest.EndTime,
+				Success:   deploymentDigest.Success,
+				Applied:   recordsDeployment(stackConfig) && stackConfig.Provider != provider.NoopProviderId,
+				Result:    deploymentDigest.Result,
+				Endpoints: deploymentDigest.Endpoints(),
+				Outputs:   deploymentDigest.Outputs,
+				Resources: deploymentDigest.Resources,
+				Errors:    deploymentDigest.Errors,
+				Attempts:  deploymentDigest.Attempts,
+				Digest:    digestFile,
+				Rollback:  rollback,
+			}))
+		}
+
+		if !deploymentDigest.Success {
+			exitCode = exitcode.Deployment
 		}
 	},
 	Args:    cobra.MinimumNArgs(0),
 	Aliases: []string{"up"},
 }
 
+// stackUpResult - the result of a deployment, output by nitric up with --output json or yaml
+type stackUpResult struct {
+	Project   string    `json:"project"`
+	Stack     string    `json:"stack"`
+	Provider  string    `json:"provider"`
+	Regions   []string  `json:"regions,omitempty"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Success   bool      `json:"success"`
+	// False when no cloud resources were changed, e.g. the provider generated configuration applied with other tools
+	Applied   bool                    `json:"applied"`
+	Result    string                  `json:"result,omitempty"`
+	Endpoints []string                `json:"endpoints"`
+	Outputs   map[string]string       `json:"outputs,omitempty"`
+	Resources []digest.ResourceDigest `json:"resources"`
+	Errors    []string                `json:"errors,omitempty"`
+	Attempts  int                     `json:"attempts"`
+	// Path of the deployment digest recorded for the deployment
+	Digest string `json:"digest,omitempty"`
+	// The rollback made after the deployment failed, with --rollback-on-failure
+	Rollback *stackRollbackResult `json:"rollback,omitempty"`
+}
+
+// stackRollbackResult - the result of re-applying the last successful deployment after a failed deployment
+type stackRollbackResult struct {
+	// ID of the deployment that was re-applied
+	Deployment string   `json:"deployment"`
+	Success    bool     `json:"success"`
+	Errors     []string `json:"errors,omitempty"`
+	Digest     string   `json:"digest,omitempty"`
+}
+
+// stackDownResult - the result of undeploying a stack, output by nitric down with --output json or yaml
+type stackDownResult struct {
+	Stack     string                  `json:"stack"`
+	Provider  string                  `json:"provider"`
+	Success   bool                    `json:"success"`
+	Resources []digest.ResourceDigest `json:"resources"`
+	Errors    []string                `json:"errors,omitempty"`
+
+	lock sync.Mutex
+}
+
+// recordUpdate - keeps the latest update reported for each resource
+func (r *stackDownResult) recordUpdate(update *deploymentspb.ResourceUpdate) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	resource := digest.ResourceDigest{
+		SubResource: update.SubResource,
+		Action:      update.Action.String(),
+		Status:      update.Status.String(),
+		Message:     update.Message,
+	}
+
+	if update.Id != nil {
+		resource.Type = update.Id.Type.String()
+		resource.Name = update.Id.Name
+	}
+
+	_, index, found := lo.FindIndexOf(r.Resources, func(existing digest.ResourceDigest) bool {
+		return existing.Type == resource.Type && existing.Name == resource.Name && existing.SubResource == resource.SubResource
+	})
+	if found {
+		r.Resources[index] = resource
+		return
+	}
+
+	r.Resources = append(r.Resources, resource)
+}
+
+func (r *stackDownResult) recordError(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.Errors = append(r.Errors, err.Error())
+	r.Success = false
+}
+
+// regionsSuffix - describes the regions a stack is deployed to in progress output
+func regionsSuffix(regions []string) string {
+	if len(regions) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" to %s", strings.Join(regions, ", "))
+}
+
+// streamDeployment - sends a deployment to the provider, recording it into the digest while its progress is shown
+func streamDeployment(deploymentClient *provider.DeploymentClient, request *deploymentspb.DeploymentUpRequest, deploymentDigest *digest.Digest, stackConfig *stack.StackConfig[map[string]any], providerStdout chan string) {
+	eventChan, errorChan := deploymentClient.Up(request)
+	eventChan = deploymentDigest.Record(eventChan)
+	errorChan = deploymentDigest.RecordErrors(errorChan)
+
+	if plainOutput() {
+		go func() {
+			for update := range errorChan {
+				fmt.Fprintf(progressOutput, "Error: %s\n", update)
+			}
+		}()
+
+		// non-interactive environment
+		for update := range eventChan {
+			switch content := update.Content.(type) {
+			case *deploymentspb.DeploymentUpEvent_Message:
+				fmt.Fprintf(progressOutput, "%s\n", content.Message)
+			case *deploymentspb.DeploymentUpEvent_Update:
+				updateResType := ""
+				updateResName := ""
+
+				if content.Update.Id != nil {
+					updateResType = content.Update.Id.Type.String()
+					updateResName = content.Update.Id.Name
+				}
+
+				if updateResType == "" {
+					updateResType = "Stack"
+				}
+
+				if updateResName == "" {
+					updateResName = stackConfig.Name
+				}
+
+				if content.Update.SubResource != "" {
+					updateResName = fmt.Sprintf("%s:%s", updateResName, content.Update.SubResource)
+				}
+
+				fmt.Fprintf(progressOutput, "%s:%s [%s]:%s %s\n", updateResType, updateResName, content.Update.Action, content.Update.Status, content.Update.Message)
+			case *deploymentspb.DeploymentUpEvent_Result:
+				fmt.Fprintf(progressOutput, "\nResult: %s\n", content.Result.GetText())
+			}
+		}
+	} else {
+		// interactive environment
+		// Step 5c. Start the stack up view
+		stackUp := stack_up.New(stackConfig.Provider, stackConfig.Name, stackConfig.AllRegions(), eventChan, providerStdout, errorChan)
+		_, err := teax.NewProgram(stackUp).Run()
+		tui.CheckErr(err)
+	}
+
+	deploymentDigest.Finish()
+}
+
+// deployedImageIds - returns the IDs of the local images referenced by a spec, keyed by image reference, so nitric images
+// list can show which deployments use each image
+func deployedImageIds(spec *deploymentspb.Spec) map[string]string {
+	ids := map[string]string{}
+
+	dockerClient, err := docker.NewBuilder()
+	if err != nil {
+		return ids
+	}
+
+	for _, image := range digest.SpecImages(spec) {
+		if id, err := dockerClient.ImageId(image); err == nil {
+			ids[image] = id
+		}
+	}
+
+	return ids
+}
+
+// saveRollbackPoint - keeps the spec and images of a successful deployment, to roll back to when a later deployment fails
+func saveRollbackPoint(deploymentDigest *digest.Digest, spec *deploymentspb.Spec) {
+	dockerClient, err := docker.New()
+	if err == nil {
+		err = digest.SaveRollbackPoint(deploymentDigest, spec, dockerClient.ImageTag)
+	}
+
+	if err != nil {
+		tui.Warning.Printfln("unable to record the deployment for rollbacks: %s", err)
+	}
+}
+
+// rollbackDeployment - re-applies the last successful deployment of a stack after a failed deployment, with the
+// attributes of the failed deployment since the stack file is unchanged
+func rollbackDeployment(proj *project.Project, stackConfig *stack.StackConfig[map[string]any], deploymentClient *provider.DeploymentClient, attributes *structpb.Struct, env map[string]string, providerStdout chan string) *stackRollbackResult {
+	point, err := digest.LatestRollbackPoint(proj.Name, stackConfig.Name)
+	if err != nil {
+		tui.Warning.Printfln("unable to roll back stack %s: %s", stackConfig.Name, err)
+		return nil
+	}
+
+	if point == nil {
+		tui.Warning.Printfln("stack %s has no successful deployment from this machine to roll back to", stackConfig.Name)
+		return nil
+	}
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		tui.Warning.Printfln("unable to roll back stack %s: %s", stackConfig.Name, err)
+		return nil
+	}
+
+	for _, image := range point.Images {
+		if _, err := dockerClient.ImageId(image); err != nil {
+			tui.Warning.Printfln("unable to roll back stack %s to deployment %s, image %s is no longer available", stackConfig.Name, point.Deployment, image)
+			return nil
+		}
+	}
+
+	spec, err := point.DeploymentSpec(env)
+	if err != nil {
+		tui.Warning.Printfln("unable to roll back stack %s: %s", stackConfig.Name, err)
+		return nil
+	}
+
+	rollbackDigest := digest.New(proj.Name, stackConfig.Name, stackConfig.Provider)
+	rollbackDigest.Attempts = 1
+	rollbackDigest.Rollback = point.Deployment
+	rollbackDigest.ConfigHash = point.ConfigHash
+	rollbackDigest.Commit = point.Commit
+	rollbackDigest.Regions = stackConfig.AllRegions()
+	rollbackDigest.Images = deployedImageIds(spec)
+
+	rollbackDigest.Declared, err = digest.DeclaredResources(spec)
+	tui.CheckErr(err)
+
+	rollbackDigest.ResourceHashes, err = digest.ResourceHashes(spec)
+	tui.CheckErr(err)
+
+	fmt.Fprintln(progressOutput)
+	tui.Info.Printfln("Rolling back %s stack to deployment %s", stackConfig.Name, point.Deployment)
+
+	streamDeployment(deploymentClient, &deploymentspb.DeploymentUpRequest{
+		Spec:        spec,
+		Attributes:  attributes,
+		Interactive: true,
+	}, rollbackDigest, stackConfig, providerStdout)
+
+	digestFile := writeDigest(proj, rollbackDigest)
+
+	printDeploymentFailures(rollbackDigest, digestFile)
+
+	if rollbackDigest.Success {
+		tui.Info.Printfln("Rolled back %s stack to deployment %s", stackConfig.Name, point.Deployment)
+	} else {
+		tui.Error.Printfln("rollback of %s stack to deployment %s failed, the stack may be partially deployed", stackConfig.Name, point.Deployment)
+	}
+
+	return &stackRollbackResult{
+		Deployment: point.Deployment,
+		Success:    rollbackDigest.Success,
+		Errors:     rollbackDigest.FailureMessages(),
+		Digest:     digestFile,
+	}
+}
+
+// digestUploadLocation - the shared location deployment digests are uploaded to, NITRIC_DIGEST_UPLOAD takes precedence over nitric.yaml
+func digestUploadLocation(config project.DigestConfiguration) string {
+	if envLocation := os.Getenv("NITRIC_DIGEST_UPLOAD"); envLocation != "" {
+		return envLocation
+	}
+
+	return config.Upload
+}
+
+// stackLockLocation - the shared location stack locks are held in, lock.location in nitric.yaml or the digest upload location when it's readable
+func stackLockLocation(lockConfig project.LockConfiguration, digestConfig project.DigestConfiguration) string {
+	if lockConfig.Location != "" {
+		return lockConfig.Location
+	}
+
+	if uploadLocation := digestUploadLocation(digestConfig); digest.Readable(uploadLocation) {
+		return uploadLocation
+	}
+
+	return ""
+}
+
+// lockStack - locks the stack for an operation, failing when another operation holds it. The returned function releases
+// the lock, it's also released when the command exits with an error
+func lockStack(proj *project.Project, stackName string, operation string) func() {
+	held, err := lock.Acquire(context.Background(), proj.Name, stackName, operation, stackLockLocation(proj.Lock, proj.Digest))
+
+	var lockedErr *lock.LockedError
+	if errors.As(err, &lockedErr) {
+		err = exitcode.Wrap(exitcode.Locked, err)
+	}
+
+	tui.CheckErr(err)
+
+	release := func() {
+		if err := held.Release(context.Background()); err != nil {
+			tui.Warning.Printfln("unable to release the lock of stack %s: %s", stackName, err)
+		}
+	}
+
+	tui.OnExit(release)
+
+	return release
+}
+
+// recordsDeployment - returns false when the deployment doesn't change the stack's cloud resources, e.g. simulating it with
+// --provider noop or generating configuration applied with other tools, so it isn't recorded in the stack's history,
+// the shared digest location or as its rollback point
+func recordsDeployment(stackConfig *stack.StackConfig[map[string]any]) bool {
+	if capabilities, ok := provider.CapabilitiesOf(stackConfig.Provider); ok && capabilities.GenerateOnly {
+		return false
+	}
+
+	return providerOverride == "" || stackConfig.Provider != provider.NoopProviderId
+}
+
+// writeDigest - writes the deployment digest to the local stack history and uploads it to the project's shared digest location, if one is configured
+func writeDigest(proj *project.Project, deploymentDigest *digest.Digest) string {
+	digestFile, err := deploymentDigest.Write()
+	if err != nil {
+		tui.Warning.Printfln("unable to write deployment digest: %s", err)
+
+		digestFile = ""
+	}
+
+	if uploadLocation := digestUploadLocation(proj.Digest); uploadLocation != "" {
+		if err := digest.Upload(context.Background(), uploadLocation, deploymentDigest); err != nil {
+			tui.Warning.Printfln("unable to upload deployment digest: %s", err)
+		}
+	}
+
+	return digestFile
+}
+
+// printDeploymentFailures - prints the details reported by the provider for each resource that failed to deploy,
+// so the cause of a failure can be found without searching the cloud console
+func printDeploymentFailures(deploymentDigest *digest.Digest, digestFile string) {
+	failures := deploymentDigest.Failures()
+	if len(failures) == 0 {
+		return
+	}
+
+	fmt.Fprintln(progressOutput)
+	tui.Error.Printfln("%d resource(s) failed to deploy:", len(failures))
+
+	for _, failure := range failures {
+		fmt.Fprintf(progressOutput, "\n  %s [%s]\n", failure, failure.Action)
+
+		if len(failure.Logs) == 0 {
+			fmt.Fprintln(progressOutput, "    no details were reported by the provider")
+		}
+
+		for _, log := range failure.Logs {
+			for _, line := range strings.Split(log, "\n") {
+				fmt.Fprintf(progressOutput, "    %s\n", line)
+			}
+		}
+	}
+
+	if digestFile != "" {
+		fmt.Fprintf(progressOutput, "\nThe full deployment record is available in %s\n", digestFile)
+	}
+}
+
 var stackDeleteCmd = &cobra.Command{
 	Use:   "down [-s stack]",
 	Short: "Undeploy a previously deployed stack, deleting resources",
@@ -377,7 +1436,10 @@ var stackDeleteCmd = &cobra.Command{
 	Example: `nitric stack down -s aws
 
 # To not be prompted, use -y
-nitric stack down -s aws -y`,
+nitric stack down -s aws -y
+
+# Output the result as YAML
+nitric stack down -s aws -y -o yaml`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fs := afero.NewOsFs()
 
@@ -385,7 +1447,7 @@ nitric stack down -s aws -y`,
 		tui.CheckErr(err)
 
 		if len(stackFiles) == 0 {
-			tui.CheckErr(fmt.Errorf("no stacks found in project root, to create a new one run `nitric stack new`"))
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
 		}
 
 		// Step 0. Get the stack file, or prompt if more than 1.
@@ -412,14 +1474,7 @@ nitric stack down -s aws -y`,
 					}
 				}
 
-				promptModel := stack_select.New(stack_select.Args{
-					Prompt:    "Which stack would you like to delete?",
-					StackList: stackList,
-				})
-
-				selection, err := teax.NewProgram(promptModel).Run()
-				tui.CheckErr(err)
-				stackSelection = selection.(stack_select.Model).Choice()
+				stackSelection = selectStack(i18n.Message("stack.down.select_prompt"), stackList)
 				if stackSelection == "" {
 					return
 				}
@@ -432,13 +1487,26 @@ nitric stack down -s aws -y`,
 		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackSelection)
 		tui.CheckErr(err)
 
-		if !isNonInteractive() {
+		if providerOverride != "" {
+			stackConfig.Provider = providerOverride
+		}
+
+		err = stackConfig.ValidateOidc()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		enforceStackPolicies(fs, "down", stackConfig)
+
+		// providers built into the CLI don't use pulumi state
+		if !isNonInteractive() && provider.UsesPulumi(stackConfig.Provider) {
 			_ = pulumi.EnsurePulumiPassphrase(fs)
 		}
 
 		proj, err := project.FromFile(fs, "")
 		tui.CheckErr(err)
 
+		releaseLock := lockStack(proj, stackConfig.Name, "down")
+		defer releaseLock()
+
 		// Step 0a. Locate/Download provider where applicable.
 		prov, err := provider.NewProvider(stackConfig.Provider, proj, fs)
 		tui.CheckErr(err)
@@ -454,7 +1522,7 @@ nitric stack down -s aws -y`,
 		}
 
 		envVariables, err := env.ReadLocalEnv(additionalEnvFiles...)
-		if err != nil && os.IsNotExist(err) {
+		if err != nil {
 			if !os.IsNotExist(err) {
 				tui.CheckErr(err)
 			}
@@ -469,11 +1537,12 @@ nitric stack down -s aws -y`,
 
 		// Step 4. Start the deployment provider server
 		providerAddress, err := prov.Start(&provider.StartOptions{
-			Env:    envVariables,
-			StdOut: providerStdout,
-			StdErr: providerStdout,
+			Env:          lo.Assign(envVariables, federatedCredentials(stackConfig)),
+			EnvAllowlist: stackConfig.ProviderEnv(),
+			StdOut:       providerStdout,
+			StdErr:       providerStdout,
 		})
-		tui.CheckErr(err)
+		tui.CheckErr(exitcode.Wrap(exitcode.Deployment, err))
 
 		defer func() {
 			err = prov.Stop()
@@ -492,6 +1561,10 @@ nitric stack down -s aws -y`,
 			attributes[k] = v
 		}
 
+		for k, v := range stackConfig.RegionAttributes() {
+			attributes[k] = v
+		}
+
 		attributesStruct, err := structpb.NewStruct(attributes)
 		tui.CheckErr(err)
 
@@ -500,17 +1573,24 @@ nitric stack down -s aws -y`,
 			Interactive: true,
 		})
 
-		if isNonInteractive() {
-			fmt.Printf("Deploying %s stack with provider %s\n", stackConfig.Name, stackConfig.Provider)
+		result := &stackDownResult{
+			Stack:     stackConfig.Name,
+			Provider:  stackConfig.Provider,
+			Resources: []digest.ResourceDigest{},
+		}
+
+		if plainOutput() {
+			fmt.Fprintf(progressOutput, "Deploying %s stack with provider %s%s\n", stackConfig.Name, stackConfig.Provider, regionsSuffix(stackConfig.AllRegions()))
 			go func() {
 				for update := range errorChan {
-					fmt.Printf("Error: %s\n", update)
+					result.recordError(update)
+					fmt.Fprintf(progressOutput, "Error: %s\n", update)
 				}
 			}()
 
 			go func() {
 				for outMessage := range providerStdout {
-					fmt.Printf("%s: %s\n", stackConfig.Provider, outMessage)
+					fmt.Fprintf(progressOutput, "%s: %s\n", stackConfig.Provider, outMessage)
 				}
 			}()
 
@@ -518,8 +1598,10 @@ nitric stack down -s aws -y`,
 			for update := range eventChannel {
 				switch content := update.Content.(type) {
 				case *deploymentspb.DeploymentDownEvent_Message:
-					fmt.Printf("%s\n", content.Message)
+					fmt.Fprintf(progressOutput, "%s\n", content.Message)
 				case *deploymentspb.DeploymentDownEvent_Update:
+					result.recordUpdate(content.Update)
+
 					updateResType := ""
 					updateResName := ""
 					if content.Update.Id != nil {
@@ -537,25 +1619,61 @@ nitric stack down -s aws -y`,
 						updateResName = fmt.Sprintf("%s:%s", updateResName, content.Update.SubResource)
 					}
 
-					fmt.Printf("%s:%s [%s]:%s %s\n", updateResType, updateResName, content.Update.Action, content.Update.Status, content.Update.Message)
+					fmt.Fprintf(progressOutput, "%s:%s [%s]:%s %s\n", updateResType, updateResName, content.Update.Action, content.Update.Status, content.Update.Message)
 				case *deploymentspb.DeploymentDownEvent_Result:
-					fmt.Println("\nStack down complete")
+					result.lock.Lock()
+					result.Success = len(result.Errors) == 0
+					result.lock.Unlock()
+
+					fmt.Fprintln(progressOutput, "\nStack down complete")
 				}
 			}
 		} else {
-			stackDown := stack_down.New(stackConfig.Provider, stackConfig.Name, eventChannel, providerStdout, errorChan)
+			stackDown := stack_down.New(stackConfig.Provider, stackConfig.Name, stackConfig.AllRegions(), eventChannel, providerStdout, errorChan)
 
-			_, err = teax.NewProgram(stackDown).Run()
+			downModel, err := teax.NewProgram(stackDown).Run()
 			tui.CheckErr(err)
+
+			result.lock.Lock()
+			result.Success = !downModel.(stack_down.Model).Failed()
+			result.lock.Unlock()
+		}
+
+		result.lock.Lock()
+		defer result.lock.Unlock()
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(result))
+		}
+
+		if !result.Success {
+			exitCode = exitcode.Deployment
 		}
 	},
 	Args: cobra.ExactArgs(0),
 }
 
-var stackListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List all stacks in the project",
-	Long:  `List all stacks in the project`,
+var (
+	gcDelete  bool
+	gcConfirm bool
+	// set by nitric stack gc --delete, so the stack is only updated when orphans are its only changes
+	orphanCleanup bool
+)
+
+var stackGcCmd = &cobra.Command{
+	Use:   "gc [-s stack]",
+	Short: "List or delete deployed resources that are no longer declared by the project",
+	Long: `List or delete deployed resources that are no longer declared by the project.
+
+Renamed and removed resources are compared against the last successful deployment of the stack, read from the shared
+digest location when it's readable, otherwise from the digests recorded on this machine. Resources matching a protect
+pattern in the stack file are retained in the cloud by providers applying the retain setting, all other orphaned
+resources are deleted with --delete. Deleting fails when the project has other changes since the last successful
+deployment, including new service images, deploy them with nitric up first.`,
+	Example: `nitric stack gc -s aws
+
+# Delete orphaned resources without being prompted
+nitric stack gc -s aws --delete -y`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fs := afero.NewOsFs()
 
@@ -563,77 +1681,1411 @@ var stackListCmd = &cobra.Command{
 		tui.CheckErr(err)
 
 		if len(stackFiles) == 0 {
-			// no stack files found
-			// print error with suggestion for user to run stack new
-			tui.CheckErr(fmt.Errorf("no stacks found in project root, to create a new one run `nitric stack new`"))
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
 		}
 
-		nameLength := 4 // start with the width of the column heading "name".
-		for _, stackFile := range stackFiles {
-			stackName, err := stack.GetStackNameFromFileName(stackFile)
-			tui.CheckErr(err)
-
-			if len(stackName) > nameLength {
-				nameLength = len(stackName)
+		stackSelection := stackFlag
+		if stackSelection == "" {
+			if len(stackFiles) > 1 {
+				tui.CheckErr(fmt.Errorf("multiple stacks found in project, please specify one with -s"))
 			}
+
+			stackSelection, err = stack.GetStackNameFromFileName(stackFiles[0])
+			tui.CheckErr(err)
 		}
 
-		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(nameLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
-		providerStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).PaddingLeft(1)
+		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackSelection)
+		tui.CheckErr(err)
 
-		v := view.New()
-		v.Break()
-		v.Add("name").WithStyle(nameStyle)
-		v.Addln("provider").WithStyle(providerStyle)
-		v.Break()
-		for _, stackFile := range stackFiles {
-			stackName, err := stack.GetStackNameFromFileName(stackFile)
-			tui.CheckErr(err)
+		err = stackConfig.ValidateProtect()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
 
-			stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackName)
-			tui.CheckErr(err)
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
 
-			v.Add(stackConfig.Name).WithStyle(nameStyle)
-			v.Addln(stackConfig.Provider).WithStyle(providerStyle)
-		}
-		fmt.Println(v.Render())
-	},
-}
+		previous, err := deployedDigest(proj, stackConfig.Name)
+		tui.CheckErr(err)
 
-func AddOptions(cmd *cobra.Command, providerOnly bool) error {
-	fs := afero.NewOsFs()
+		if previous == nil {
+			tui.CheckErr(fmt.Errorf("stack %s has not been deployed successfully, run nitric up -s %s first", stackConfig.Name, stackConfig.Name))
+		}
 
-	stacks, err := stack.GetAllStackNames(fs)
-	if err != nil {
-		return fmt.Errorf("failed to get stacks available for this project. %w", err)
-	}
+		spec, err := proj.TransformSpec(collectSpec(fs, envFile), stackConfig.Name)
+		tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
 
-	cmd.Flags().VarP(pflagx.NewStringEnumVar(&stackFlag, stacks, ""), "stack", "s", "specify a stack file, -s your_stack")
+		orphans := digest.Orphans(previous, spec, stackConfig.AliasedKeys())
+		if len(orphans) == 0 {
+			fmt.Fprintf(progressOutput, "No orphaned resources found in stack %s\n", stackConfig.Name)
+			return
+		}
 
-	return cmd.RegisterFlagCompletionFunc("stack", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return stacks, cobra.ShellCompDirectiveDefault
-	})
-}
+		deletable := lo.Filter(orphans, func(orphan digest.Orphan, _ int) bool {
+			return !stackConfig.IsProtected(orphan.Key())
+		})
 
-func init() {
+		if plainOutput() {
+			for _, orphan := range orphans {
+				fmt.Fprintf(progressOutput, "%s: %s\n", orphan.Key(), lo.Ternary(stackConfig.IsProtected(orphan.Key()), retainedAction(stackConfig), "delete"))
+			}
+		} else {
+			resourceLength := len("resource")
+			for _, orphan := range orphans {
+				resourceLength = max(resourceLength, len(orphan.Key()))
+			}
+
+			resourceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(resourceLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+			actionStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+			v := view.New()
+			v.Break()
+			v.Add("resource").WithStyle(resourceStyle)
+			v.Addln("action").WithStyle(actionStyle)
+			v.Break()
+
+			for _, orphan := range orphans {
+				v.Add(orphan.Key()).WithStyle(resourceStyle)
+
+				if stackConfig.IsProtected(orphan.Key()) {
+					v.Addln(retainedAction(stackConfig)).WithStyle(actionStyle.Copy().Foreground(tui.Colors.Yellow))
+				} else {
+					v.Addln("delete").WithStyle(actionStyle.Copy().Foreground(tui.Colors.Red))
+				}
+			}
+
+			fmt.Fprintln(progressOutput, v.Render())
+		}
+
+		if !gcDelete {
+			if len(deletable) > 0 {
+				fmt.Fprintf(progressOutput, "Run nitric stack gc -s %s --delete to delete %d orphaned resources, add patterns to protect in %s to retain them\n", stackConfig.Name, len(deletable), stack.StackFileName(stackConfig.Name))
+			}
+
+			return
+		}
+
+		if len(deletable) == 0 {
+			fmt.Fprintln(progressOutput, "All orphaned resources are protected, nothing to delete")
+			return
+		}
+
+		enforceStackPolicies(fs, "gc", stackConfig)
+
+		if !gcConfirm {
+			if isNonInteractive() {
+				tui.CheckErr(fmt.Errorf("deleting orphaned resources requires confirmation, use -y to confirm"))
+			}
+
+			_ = tui.AskOne(&survey.Confirm{
+				Message: fmt.Sprintf("Delete %d orphaned resources from stack %s? Data in stateful resources, such as buckets and databases, will be lost", len(deletable), stackConfig.Name),
+				Default: false,
+			}, &gcConfirm)
+
+			if !gcConfirm {
+				return
+			}
+		}
+
+		// updating the stack with the current spec deletes unprotected orphans and retains protected ones, it fails without
+		// deploying when the project has other changes since the last successful deployment
+		stackFlag = stackConfig.Name
+		orphanCleanup = true
+		stackUpdateCmd.Run(cmd, args)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var previewExitCode bool
+
+var stackPreviewCmd = &cobra.Command{
+	Use:   "preview [-s stack]",
+	Short: "Preview the changes nitric up would make to a stack",
+	Long: `Preview the changes nitric up would make to a stack, without deploying or contacting the cloud.
+
+The project's resources are compared against the last deployment of the stack, recorded in its deployment digest.
+Renamed resources with an alias keep their state when the provider applies aliases, resources matching a protect pattern in the stack file are retained.
+Changes to service code are deployed by nitric up as new images, and aren't shown in the preview.
+The preview fails when the project uses features the stack's provider doesn't support, see nitric provider capabilities.
+Settings and resource counts exceeding the documented limits of the cloud, e.g. the memory of a lambda, are warned about.
+Use --exit-code to exit with 6 when the stack has drifted from the project, e.g. to fail a CI check with changes to deploy.`,
+	Example: `nitric stack preview -s aws
+
+# Output machine readable JSON, e.g. to comment on a pull request
+nitric stack preview -s aws -o json
+
+# Fail when there are changes to deploy
+nitric stack preview -s aws --exit-code`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stackFiles, err := stack.GetAllStackFiles(fs)
+		tui.CheckErr(err)
+
+		if len(stackFiles) == 0 {
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
+		}
+
+		stackSelection := stackFlag
+		if stackSelection == "" {
+			if len(stackFiles) > 1 {
+				tui.CheckErr(fmt.Errorf("multiple stacks found in project, please specify one with -s"))
+			}
+
+			stackSelection, err = stack.GetStackNameFromFileName(stackFiles[0])
+			tui.CheckErr(err)
+		}
+
+		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackSelection)
+		tui.CheckErr(err)
+
+		err = stackConfig.ValidateProtect()
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		previous, err := digest.Latest(proj.Name, stackConfig.Name)
+		tui.CheckErr(err)
+
+		spec, err := proj.TransformSpec(collectSpec(fs, envFile), stackConfig.Name)
+		tui.CheckErr(exitcode.Wrap(exitcode.Collection, err))
+
+		// fail before deploying rather than part way through a deployment
+		if capabilities, ok := provider.CapabilitiesOf(stackConfig.Provider); ok {
+			if unsupported := capabilities.Check(spec); len(unsupported) > 0 {
+				reasons := lo.Map(unsupported, func(u provider.UnsupportedResource, _ int) string {
+					return fmt.Sprintf("  %s: %s", u.Resource, u.Reason)
+				})
+
+				tui.CheckErr(exitcode.Wrap(exitcode.Config, fmt.Errorf("stack %s uses features its provider doesn't support, see nitric provider capabilities %s:\n%s", stackConfig.Name, capabilities.Provider, strings.Join(reasons, "\n"))))
+			}
+		}
+
+		checkStackSettings(stackConfig, stackSettings(proj, stackConfig))
+
+		changes, err := digest.Plan(previous, spec, stackConfig.Aliases, stackConfig.IsProtected)
+		tui.CheckErr(err)
+
+		if previewExitCode && lo.ContainsBy(changes, func(change digest.Change) bool { return change.Action != digest.ChangeAction_Unchanged }) {
+			exitCode = exitcode.Drift
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(changes))
+
+			return
+		}
+
+		if previous == nil {
+			fmt.Fprintf(progressOutput, "Stack %s has not been deployed, all resources will be created\n", stackConfig.Name)
+		} else {
+			fmt.Fprintf(progressOutput, "Comparing with the deployment of stack %s on %s\n", stackConfig.Name, previous.EndTime.Local().Format(time.DateTime))
+
+			if configHash, err := stack.ConfigHash(fs, stackConfig.Name); err == nil && previous.ConfigHash != "" && previous.ConfigHash != configHash {
+				tui.Warning.Printfln("%s has changed since the last deployment, resources configured by the stack file may also be updated", stack.StackFileName(stackConfig.Name))
+			}
+		}
+
+		warnQuotaIssues(stackConfig, spec, false)
+
+		counts := lo.CountValuesBy(changes, func(change digest.Change) digest.ChangeAction { return change.Action })
+		shown := lo.Filter(changes, func(change digest.Change, _ int) bool { return change.Action != digest.ChangeAction_Unchanged })
+
+		actionText := func(change digest.Change) string {
+			switch change.Action {
+			case digest.ChangeAction_Rename:
+				return fmt.Sprintf("rename (from %s)", change.From)
+			case digest.ChangeAction_Retain:
+				return retainedAction(stackConfig)
+			case digest.ChangeAction_Unknown:
+				return "unknown (not recorded by the last deployment)"
+			default:
+				return string(change.Action)
+			}
+		}
+
+		if plainOutput() {
+			for _, change := range shown {
+				fmt.Fprintf(progressOutput, "%s: %s\n", change.Key(), actionText(change))
+			}
+		} else if len(shown) > 0 {
+			resourceLength := len("resource")
+			for _, change := range shown {
+				resourceLength = max(resourceLength, len(change.Key()))
+			}
+
+			resourceStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(resourceLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+			actionStyle := lipgloss.NewStyle().PaddingLeft(1)
+			actionColors := map[digest.ChangeAction]lipgloss.TerminalColor{
+				digest.ChangeAction_Create:  tui.Colors.Green,
+				digest.ChangeAction_Update:  tui.Colors.Yellow,
+				digest.ChangeAction_Delete:  tui.Colors.Red,
+				digest.ChangeAction_Retain:  tui.Colors.Yellow,
+				digest.ChangeAction_Rename:  tui.Colors.Purple,
+				digest.ChangeAction_Unknown: tui.Colors.Gray,
+			}
+
+			v := view.New()
+			v.Break()
+			v.Add("resource").WithStyle(resourceStyle)
+			v.Addln("action").WithStyle(actionStyle)
+			v.Break()
+
+			for _, change := range shown {
+				v.Add(change.Key()).WithStyle(resourceStyle)
+				v.Addln(actionText(change)).WithStyle(actionStyle.Copy().Foreground(actionColors[change.Action]))
+			}
+
+			fmt.Fprintln(progressOutput, v.Render())
+		}
+
+		fmt.Fprintf(progressOutput, "Plan: %d to create, %d to update, %d to delete, %d to rename, %d to retain, %d unchanged\n",
+			counts[digest.ChangeAction_Create],
+			counts[digest.ChangeAction_Update],
+			counts[digest.ChangeAction_Delete],
+			counts[digest.ChangeAction_Rename],
+			counts[digest.ChangeAction_Retain],
+			counts[digest.ChangeAction_Unchanged],
+		)
+
+		if counts[digest.ChangeAction_Unknown] > 0 {
+			fmt.Fprintf(progressOutput, "%d resources may be updated, their config wasn't recorded by the last deployment\n", counts[digest.ChangeAction_Unknown])
+		}
+	},
+	Args:    cobra.ExactArgs(0),
+	Aliases: []string{"plan"},
+}
+
+// stackStatus - the status of a stack, based on its most recent deployment digest
+type stackStatus struct {
+	Name         string     `json:"name"`
+	Provider     string     `json:"provider"`
+	Regions      []string   `json:"regions"`
+	LastDeployed *time.Time `json:"lastDeployed"`
+	Success      bool       `json:"success"`
+	Resources    int        `json:"resources"`
+	// Drift indicates the stack file has changed since the last deployment
+	Drift bool `json:"drift"`
+	// Where the status was read from, always the local deployment history
+	Source string `json:"source"`
+}
+
+// stackStatuses - returns the status of each stack in the project
+func stackStatuses(fs afero.Fs) ([]stackStatus, error) {
+	projectConfig, err := project.ConfigurationFromFile(fs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stackFiles, err := stack.GetAllStackFiles(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := []stackStatus{}
+
+	for _, stackFile := range stackFiles {
+		stackName, err := stack.GetStackNameFromFileName(stackFile)
+		if err != nil {
+			return nil, err
+		}
+
+		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackName)
+		if err != nil {
+			return nil, err
+		}
+
+		status := stackStatus{
+			Name:     stackConfig.Name,
+			Provider: stackConfig.Provider,
+			Regions:  stackConfig.AllRegions(),
+			Source:   "local",
+		}
+
+		latest, err := digest.Latest(projectConfig.Name, stackName)
+		if err != nil {
+			return nil, err
+		}
+
+		if latest != nil {
+			configHash, err := stack.ConfigHash(fs, stackName)
+			if err != nil {
+				return nil, err
+			}
+
+			status.LastDeployed = &latest.EndTime
+			status.Success = latest.Success
+			status.Resources = latest.ResourceCount()
+			status.Drift = latest.ConfigHash != "" && latest.ConfigHash != configHash
+		}
+
+		stacks = append(stacks, status)
+	}
+
+	return stacks, nil
+}
+
+var (
+	cloneStackAs    string
+	forceCloneStack bool
+)
+
+var stackCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Create a new stack from an existing stack's configuration",
+	Long: `Create a new stack from an existing stack's configuration.
+
+Secret values such as passwords, tokens and keys are not copied, you will be prompted for new values instead.
+In non-interactive environments secret values are left blank.`,
+	Example: `nitric stack clone -s prod --as staging`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		if stackFlag == "" {
+			tui.CheckErr(fmt.Errorf("please specify the stack to clone with -s"))
+		}
+
+		if !forceCloneStack {
+			if _, err := fs.Stat(stack.StackFileName(cloneStackAs)); err == nil {
+				tui.CheckErr(fmt.Errorf("stack '%s' already exists, use --force to overwrite it", cloneStackAs))
+			}
+		}
+
+		stackFilePath, err := stack.CloneStackFile(fs, stackFlag, cloneStackAs, func(keyPath string) string {
+			if isNonInteractive() {
+				return ""
+			}
+
+			value := ""
+			_ = tui.AskOne(&survey.Password{
+				Message: fmt.Sprintf("Value for %s in the %s stack (leave blank to set later)", keyPath, cloneStackAs),
+			}, &value)
+
+			return value
+		})
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Cloned stack %s to %s\n", stackFlag, stackFilePath)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var stackListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all stacks in the project",
+	Long: `List all stacks in the project, along with the status of their last deployment from this machine.
+
+Status is read from the local deployment history recorded by nitric up, so deployments made by teammates or CI
+aren't shown, run nitric stack status to include the shared digest location. A stack shows drift when its stack
+file has changed since it was last deployed from this machine.`,
+	Example: `nitric stack list
+
+# Output machine readable JSON
+nitric stack list -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stacks, err := stackStatuses(fs)
+		tui.CheckErr(err)
+
+		if len(stacks) == 0 {
+			// no stack files found
+			// print error with suggestion for user to run stack new
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(stacks))
+
+			return
+		}
+
+		nameLength := 4 // start with the width of the column heading "name".
+		providerLength := len("provider")
+		regionsLength := len("regions")
+
+		for _, s := range stacks {
+			nameLength = max(nameLength, len(s.Name))
+			providerLength = max(providerLength, len(s.Provider))
+			regionsLength = max(regionsLength, len(strings.Join(s.Regions, ", ")))
+		}
+
+		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(nameLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		providerStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(providerLength + 2).PaddingLeft(1)
+		regionsStyle := lipgloss.NewStyle().Width(regionsLength + 2).PaddingLeft(1)
+		deployedStyle := lipgloss.NewStyle().Width(22).PaddingLeft(1)
+		resourcesStyle := lipgloss.NewStyle().Width(11).PaddingLeft(1)
+		statusStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("name").WithStyle(nameStyle)
+		v.Add("provider").WithStyle(providerStyle)
+		v.Add("regions").WithStyle(regionsStyle)
+		v.Add("last deployed").WithStyle(deployedStyle)
+		v.Add("resources").WithStyle(resourcesStyle)
+		v.Addln("local status").WithStyle(statusStyle)
+		v.Break()
+
+		for _, s := range stacks {
+			v.Add(s.Name).WithStyle(nameStyle)
+			v.Add(s.Provider).WithStyle(providerStyle)
+			v.Add(lo.Ternary(len(s.Regions) > 0, strings.Join(s.Regions, ", "), "-")).WithStyle(regionsStyle)
+
+			if s.LastDeployed == nil {
+				v.Add("never").WithStyle(deployedStyle.Copy().Foreground(tui.Colors.Gray))
+				v.Add("-").WithStyle(resourcesStyle.Copy().Foreground(tui.Colors.Gray))
+				v.Addln("not deployed from here").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Gray))
+
+				continue
+			}
+
+			v.Add(s.LastDeployed.Local().Format(time.DateTime)).WithStyle(deployedStyle)
+			v.Add("%d", s.Resources).WithStyle(resourcesStyle)
+
+			if s.Success {
+				v.Add("deployed").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Green))
+			} else {
+				v.Add("failed").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Red))
+			}
+
+			if s.Drift {
+				v.Add(" (changed since last deployment)").WithStyle(lipgloss.NewStyle().Foreground(tui.Colors.Yellow))
+			}
+
+			v.Break()
+		}
+
+		v.Break()
+		v.Addln("Status is from this machine's deployment history, run nitric stack status to include deployments made elsewhere").WithStyle(lipgloss.NewStyle().Foreground(tui.Colors.Gray))
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+}
+
+// stackHealth - the aggregated status of a stack, from the latest deployment digest found locally or in the shared digest location
+type stackHealth struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// Provider used for the latest deployment, which may differ from the stack file
+	DeployedProvider string     `json:"deployedProvider,omitempty"`
+	LastDeployed     *time.Time `json:"lastDeployed"`
+	// Host the latest deployment was made from
+	DeployedFrom string `json:"deployedFrom,omitempty"`
+	// Where the latest digest was found, local or shared
+	Source  string       `json:"source,omitempty"`
+	Healthy bool         `json:"healthy"`
+	Summary string       `json:"summary"`
+	Check   *watch.Check `json:"check"`
+}
+
+// stackHealthStatuses - checks the health of every stack in the project concurrently
+func stackHealthStatuses(ctx context.Context, fs afero.Fs) ([]stackHealth, error) {
+	projectConfig, err := project.ConfigurationFromFile(fs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stackNames, err := stack.GetAllStackNames(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]stackHealth, len(stackNames))
+	errs := make([]error, len(stackNames))
+	wg := sync.WaitGroup{}
+
+	for i, stackName := range stackNames {
+		wg.Add(1)
+
+		go func(i int, stackName string) {
+			defer wg.Done()
+
+			stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackName)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			latest, source, err := digest.LatestLocalOrShared(ctx, projectConfig.Digest.Upload, projectConfig.Name, stackName)
+			if err != nil {
+				errs[i] = fmt.Errorf("unable to read the deployment digest of stack %s: %w", stackName, err)
+				return
+			}
+
+			check, err := watch.CheckDigest(ctx, fs, watch.Options{Project: projectConfig.Name, Stack: stackName, DigestLocation: projectConfig.Digest.Upload}, latest)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			status := stackHealth{
+				Name:     stackName,
+				Provider: stackConfig.Provider,
+				Healthy:  check.Healthy(),
+				Summary:  check.Summary(),
+				Check:    check,
+			}
+
+			if latest != nil {
+				status.DeployedProvider = latest.Provider
+				status.LastDeployed = &latest.EndTime
+				status.DeployedFrom = latest.Host
+				status.Source = source
+			}
+
+			statuses[i] = status
+		}(i, stackName)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return statuses, nil
+}
+
+var stackStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show an overview of the deployment and health of all stacks in the project",
+	Long: `Show an overview of the deployment and health of all stacks in the project.
+
+All stacks are checked concurrently. For each stack the most recent deployment digest is read, preferring digests
+uploaded to the shared digest location in nitric.yaml when they are newer than those recorded locally, so
+deployments made by teammates and CI are included. Only s3:// digest locations can be read.
+
+A stack is healthy when its last deployment succeeded, its stack file hasn't changed since and all of the
+endpoints output by the deployment are reachable.`,
+	Example: `nitric stack status
+
+# Output machine readable JSON
+nitric stack status -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stacks, err := stackHealthStatuses(cmd.Context(), fs)
+		tui.CheckErr(err)
+
+		if len(stacks) == 0 {
+			tui.CheckErr(i18n.Errorf("stack.none_found"))
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(stacks))
+
+			return
+		}
+
+		nameLength := len("name")
+		providerLength := len("provider")
+
+		for _, s := range stacks {
+			nameLength = max(nameLength, len(s.Name))
+			providerLength = max(providerLength, len(lo.Ternary(s.DeployedProvider != "", s.DeployedProvider, s.Provider)))
+		}
+
+		nameStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(nameLength + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		providerStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(providerLength + 2).PaddingLeft(1)
+		deployedStyle := lipgloss.NewStyle().Width(22).PaddingLeft(1)
+		sourceStyle := lipgloss.NewStyle().Width(8).PaddingLeft(1)
+		healthStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("name").WithStyle(nameStyle)
+		v.Add("provider").WithStyle(providerStyle)
+		v.Add("last deployed").WithStyle(deployedStyle)
+		v.Add("source").WithStyle(sourceStyle)
+		v.Addln("health").WithStyle(healthStyle)
+		v.Break()
+
+		for _, s := range stacks {
+			v.Add(s.Name).WithStyle(nameStyle)
+			v.Add(lo.Ternary(s.DeployedProvider != "", s.DeployedProvider, s.Provider)).WithStyle(providerStyle)
+
+			if s.LastDeployed == nil {
+				v.Add("never").WithStyle(deployedStyle.Copy().Foreground(tui.Colors.Gray))
+				v.Add("-").WithStyle(sourceStyle.Copy().Foreground(tui.Colors.Gray))
+				v.Addln("not deployed").WithStyle(healthStyle.Copy().Foreground(tui.Colors.Gray))
+
+				continue
+			}
+
+			v.Add(s.LastDeployed.Local().Format(time.DateTime)).WithStyle(deployedStyle)
+			v.Add(s.Source).WithStyle(sourceStyle)
+
+			if s.Healthy {
+				v.Addln("healthy").WithStyle(healthStyle.Copy().Foreground(tui.Colors.Green))
+			} else {
+				v.Addln(strings.TrimPrefix(s.Summary, fmt.Sprintf("stack %s is ", s.Name))).WithStyle(healthStyle.Copy().Foreground(tui.Colors.Red))
+			}
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+// historyStackName - the stack selected with -s, or the only stack in the project
+func historyStackName(fs afero.Fs) string {
+	if stackFlag != "" {
+		return stackFlag
+	}
+
+	stackFiles, err := stack.GetAllStackFiles(fs)
+	tui.CheckErr(err)
+
+	if len(stackFiles) == 0 {
+		tui.CheckErr(i18n.Errorf("stack.none_found"))
+	}
+
+	if len(stackFiles) > 1 {
+		tui.CheckErr(fmt.Errorf("multiple stacks found in project, please specify one with -s"))
+	}
+
+	stackName, err := stack.GetStackNameFromFileName(stackFiles[0])
+	tui.CheckErr(err)
+
+	return stackName
+}
+
+// stackHistoryEntry - a summary of a single past deployment, output by nitric stack history with --output json or yaml
+type stackHistoryEntry struct {
+	ID        string    `json:"id"`
+	Provider  string    `json:"provider"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	// Duration of the deployment in seconds
+	Duration  float64 `json:"duration"`
+	Success   bool    `json:"success"`
+	Resources int     `json:"resources"`
+	Attempts  int     `json:"attempts,omitempty"`
+	User      string  `json:"user,omitempty"`
+	Host      string  `json:"host,omitempty"`
+	Commit    string  `json:"commit,omitempty"`
+	// ID of the deployment re-applied, for rollbacks after a failed deployment
+	Rollback string `json:"rollback,omitempty"`
+}
+
+var stackHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past deployments of a stack",
+	Long: `List past deployments of a stack, most recent first.
+
+History is read from the deployment digests recorded by nitric up on this machine, including who ran each
+deployment and the git commit of the project that was deployed. Use nitric stack history show <id> for the
+resources and errors of a single deployment.`,
+	Example: `nitric stack history -s aws
+
+# Output machine readable JSON
+nitric stack history -s aws -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stackName := historyStackName(fs)
+
+		proj, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		history, err := digest.History(proj.Name, stackName)
+		tui.CheckErr(err)
+
+		entries := lo.Map(history, func(d *digest.Digest, _ int) stackHistoryEntry {
+			return stackHistoryEntry{
+				ID:        d.ID(),
+				Provider:  d.Provider,
+				StartTime: d.StartTime,
+				EndTime:   d.EndTime,
+				Duration:  d.Duration().Seconds(),
+				Success:   d.Success,
+				Resources: d.ResourceCount(),
+				Attempts:  d.Attempts,
+				User:      d.User,
+				Host:      d.Host,
+				Commit:    d.Commit,
+				Rollback:  d.Rollback,
+			}
+		})
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(entries))
+
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintf(progressOutput, "Stack %s has not been deployed from this machine\n", stackName)
+
+			return
+		}
+
+		userLength := len("user")
+
+		for _, e := range entries {
+			userLength = max(userLength, len(e.User))
+		}
+
+		idStyle := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Width(len("20060102T150405Z") + 1).PaddingRight(1).BorderRight(true).BorderStyle(lipgloss.NormalBorder()).BorderForeground(tui.Colors.Gray)
+		deployedStyle := lipgloss.NewStyle().Width(22).PaddingLeft(1)
+		durationStyle := lipgloss.NewStyle().Width(11).PaddingLeft(1)
+		resourcesStyle := lipgloss.NewStyle().Width(11).PaddingLeft(1)
+		userStyle := lipgloss.NewStyle().Foreground(tui.Colors.Purple).Width(userLength + 2).PaddingLeft(1)
+		commitStyle := lipgloss.NewStyle().Width(10).PaddingLeft(1)
+		statusStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+		v := view.New()
+		v.Break()
+		v.Add("id").WithStyle(idStyle)
+		v.Add("deployed").WithStyle(deployedStyle)
+		v.Add("duration").WithStyle(durationStyle)
+		v.Add("resources").WithStyle(resourcesStyle)
+		v.Add("user").WithStyle(userStyle)
+		v.Add("commit").WithStyle(commitStyle)
+		v.Addln("status").WithStyle(statusStyle)
+		v.Break()
+
+		for _, e := range entries {
+			v.Add(e.ID).WithStyle(idStyle)
+			v.Add(e.StartTime.Local().Format(time.DateTime)).WithStyle(deployedStyle)
+			v.Add(time.Duration(e.Duration * float64(time.Second)).Round(time.Second).String()).WithStyle(durationStyle)
+			v.Add("%d", e.Resources).WithStyle(resourcesStyle)
+			v.Add(lo.Ternary(e.User != "", e.User, "-")).WithStyle(userStyle)
+			v.Add(lo.Ternary(e.Commit != "", e.Commit, "-")).WithStyle(commitStyle)
+
+			if e.Rollback != "" {
+				v.Addln("rollback to %s", e.Rollback).WithStyle(statusStyle.Copy().Foreground(lo.Ternary(e.Success, tui.Colors.Yellow, tui.Colors.Red)))
+			} else if e.Success {
+				v.Addln("deployed").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Green))
+			} else {
+				v.Addln("failed").WithStyle(statusStyle.Copy().Foreground(tui.Colors.Red))
+			}
+		}
+
+		fmt.Fprintln(progressOutput, v.Render())
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var stackHistoryShowCmd = &cobra.Command{
+	Use:   "show <id> [-s stack]",
+	Short: "Show the details of a past deployment of a stack",
+	Long: `Show the details of a past deployment of a stack, including the final state of each resource and any errors.
+
+The images deployed are shown with the git commit, build time and CLI version read from their labels, while they're
+still available locally.
+
+The id is listed by nitric stack history, use latest for the most recent deployment.`,
+	Example: `nitric stack history show 20240131T090000Z -s aws
+
+# Output the full deployment digest as JSON
+nitric stack history show latest -s aws -o json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stackName := historyStackName(fs)
+
+		proj, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		var d *digest.Digest
+
+		if args[0] == "latest" {
+			d, err = digest.Latest(proj.Name, stackName)
+			tui.CheckErr(err)
+
+			if d == nil {
+				tui.CheckErr(fmt.Errorf("stack %s has not been deployed from this machine", stackName))
+			}
+		} else {
+			d, err = digest.Load(proj.Name, stackName, args[0])
+			tui.CheckErr(err)
+		}
+
+		if structuredOutput() {
+			tui.CheckErr(printResult(d))
+
+			return
+		}
+
+		fmt.Fprintf(progressOutput, "Deployment %s of stack %s\n\n", d.ID(), d.Stack)
+		fmt.Fprintf(progressOutput, "  provider:  %s%s\n", d.Provider, regionsSuffix(d.Regions))
+		fmt.Fprintf(progressOutput, "  started:   %s\n", d.StartTime.Local().Format(time.DateTime))
+		fmt.Fprintf(progressOutput, "  duration:  %s\n", d.Duration().Round(time.Second))
+		fmt.Fprintf(progressOutput, "  status:    %s\n", lo.Ternary(d.Success, "deployed", "failed"))
+
+		if d.Attempts > 1 {
+			fmt.Fprintf(progressOutput, "  attempts:  %d\n", d.Attempts)
+		}
+
+		if d.Rollback != "" {
+			fmt.Fprintf(progressOutput, "  rollback:  re-applied deployment %s\n", d.Rollback)
+		}
+
+		fmt.Fprintf(progressOutput, "  user:      %s\n", lo.Ternary(d.User != "", d.User, "-"))
+		fmt.Fprintf(progressOutput, "  host:      %s\n", lo.Ternary(d.Host != "", d.Host, "-"))
+		fmt.Fprintf(progressOutput, "  commit:    %s\n", lo.Ternary(d.Commit != "", d.Commit, "-"))
+
+		if len(d.Outputs) > 0 {
+			fmt.Fprintln(progressOutput, "\nOutputs:")
+
+			names := lo.Keys(d.Outputs)
+			slices.Sort(names)
+
+			for _, name := range names {
+				fmt.Fprintf(progressOutput, "  %s: %s\n", name, d.Outputs[name])
+			}
+		}
+
+		if len(d.Images) > 0 {
+			fmt.Fprintln(progressOutput, "\nImages:")
+
+			refs := lo.Keys(d.Images)
+			slices.Sort(refs)
+
+			for _, ref := range refs {
+				fmt.Fprintf(progressOutput, "  %s: %s\n", ref, describeDeployedImage(d.Images[ref]))
+			}
+		}
+
+		fmt.Fprintf(progressOutput, "\n%d resource(s):\n", d.ResourceCount())
+
+		for _, res := range d.Resources {
+			fmt.Fprintf(progressOutput, "  %s [%s]:%s\n", res, res.Action, res.Status)
+		}
+
+		if len(d.Errors) > 0 {
+			fmt.Fprintln(progressOutput, "\nErrors:")
+
+			for _, e := range d.Errors {
+				fmt.Fprintf(progressOutput, "  %s\n", e)
+			}
+		}
+
+		if d.Result != "" {
+			fmt.Fprintf(progressOutput, "\nResult: %s\n", d.Result)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+var unlockForce bool
+
+var stackUnlockCmd = &cobra.Command{
+	Use:   "unlock [-s stack]",
+	Short: "Show or remove the lock held on a stack by an operation in progress",
+	Long: `Show or remove the lock held on a stack by an operation in progress.
+
+nitric up and nitric down lock the stack while they run, so a second operation on the same stack fails rather than
+corrupting its deployment. Stacks are locked on this machine, and in lock.location in nitric.yaml, or digest.upload when
+it's an s3 location, so operations from other machines and CI are detected too. Locks held by processes on this machine
+that are no longer running are replaced automatically.
+
+Without --force the operation holding the lock is shown. Use --force to remove a lock left behind by an operation that
+was stopped, e.g. a cancelled CI job.`,
+	Example: `# Show who holds the lock of the aws stack
+nitric stack unlock -s aws
+
+# Remove the lock of a cancelled deployment
+nitric stack unlock -s aws --force`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stackName := historyStackName(fs)
+
+		proj, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		location := stackLockLocation(proj.Lock, proj.Digest)
+
+		holders, err := lock.Holders(context.Background(), proj.Name, stackName, location)
+		tui.CheckErr(err)
+
+		if len(holders) == 0 {
+			fmt.Fprintf(progressOutput, "Stack %s is not locked\n", stackName)
+
+			return
+		}
+
+		for _, held := range holders {
+			fmt.Fprintf(progressOutput, "Stack %s is locked by %s on %s, running nitric %s since %s (%s)\n", stackName, lo.Ternary(held.User != "", held.User, "unknown"),
+				lo.Ternary(held.Host != "", held.Host, "unknown"), held.Operation, held.Created.Local().Format(time.DateTime), held.Location)
+		}
+
+		if !unlockForce {
+			fmt.Fprintf(progressOutput, "\nUse nitric stack unlock -s %s --force to remove the lock if the operation is no longer running\n", stackName)
+
+			return
+		}
+
+		_, err = lock.ForceRelease(context.Background(), proj.Name, stackName, location)
+		tui.CheckErr(err)
+
+		tui.Warning.Printfln("removed the lock of stack %s, make sure the operation that held it isn't still running before deploying", stackName)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+var stackOutputFormat string
+
+// shellQuote - quotes a value for POSIX shells
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+var stackOutputCmd = &cobra.Command{
+	Use:   "output [name] [-s stack]",
+	Short: "Print the named outputs of a stack's last successful deployment",
+	Long: `Print the named outputs of a stack's last successful deployment, e.g. API URLs, bucket names and secret ARNs.
+
+Outputs are returned by the stack's provider in the deployment result and recorded in the deployment digest.
+With a name only that output's value is printed, so it can be used in scripts, e.g. API_URL=$(nitric stack output api.main -s aws).
+
+Use --format to print the outputs for other tools:
+  json   a JSON object of the outputs
+  env    NAME=value lines for .env files, names are upper cased with other characters replaced by _, e.g. API_MAIN
+  shell  export statements for POSIX shells, e.g. eval "$(nitric stack output -s aws --format shell)"
+
+Providers return outputs by ending their result text with an "Outputs:" line followed by indented "<name>: <value>" lines.`,
+	Example: `nitric stack output -s aws
+
+# Print the URL of the main API
+nitric stack output api.main -s aws
+
+# Write the outputs to a .env file for a frontend build
+nitric stack output -s aws --format env > frontend/.env.production
+
+# Export the outputs in a CI job
+eval "$(nitric stack output -s aws --format shell)"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !slices.Contains([]string{"", "json", "env", "shell"}, stackOutputFormat) {
+			tui.CheckErr(exitcode.Wrap(exitcode.Config, fmt.Errorf("invalid format %s, must be one of json, env or shell", stackOutputFormat)))
+		}
+
+		fs := afero.NewOsFs()
+
+		stackName := historyStackName(fs)
+
+		proj, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		history, err := digest.History(proj.Name, stackName)
+		tui.CheckErr(err)
+
+		deployment, ok := lo.Find(history, func(d *digest.Digest) bool { return d.Success })
+		if !ok {
+			tui.CheckErr(fmt.Errorf("stack %s has not been successfully deployed from this machine, run nitric up -s %s to deploy it", stackName, stackName))
+		}
+
+		outputs := lo.Ternary(deployment.Outputs != nil, deployment.Outputs, map[string]string{})
+
+		if len(args) > 0 {
+			value, ok := outputs[args[0]]
+			if !ok {
+				names := lo.Keys(outputs)
+				slices.Sort(names)
+
+				tui.CheckErr(fmt.Errorf("stack %s has no output %s, the outputs of deployment %s are: %s", stackName, args[0], deployment.ID(),
+					lo.Ternary(len(names) > 0, strings.Join(names, ", "), "none")))
+			}
+
+			outputs = map[string]string{args[0]: value}
+		}
+
+		names := lo.Keys(outputs)
+		slices.Sort(names)
+
+		switch {
+		case stackOutputFormat == "json":
+			data, err := json.MarshalIndent(outputs, "", "  ")
+			tui.CheckErr(err)
+
+			fmt.Fprintln(progressOutput, string(data))
+		case stackOutputFormat == "env" || stackOutputFormat == "shell":
+			for _, name := range names {
+				if stackOutputFormat == "shell" {
+					fmt.Fprintf(progressOutput, "export %s=%s\n", digest.OutputEnvName(name), shellQuote(outputs[name]))
+				} else {
+					fmt.Fprintf(progressOutput, "%s=%s\n", digest.OutputEnvName(name), outputs[name])
+				}
+			}
+		case structuredOutput():
+			tui.CheckErr(printResult(outputs))
+		case len(args) > 0:
+			fmt.Fprintln(progressOutput, outputs[args[0]])
+		case len(outputs) == 0:
+			fmt.Fprintf(progressOutput, "Deployment %s of stack %s has no outputs\n", deployment.ID(), stackName)
+		default:
+			nameLength := lo.Max(append(lo.Map(names, func(name string, _ int) int { return len(name) }), len("name")))
+			nameStyle := lipgloss.NewStyle().Foreground(tui.Colors.Blue).Width(nameLength + 2).PaddingLeft(1)
+			valueStyle := lipgloss.NewStyle().PaddingLeft(1)
+
+			v := view.New()
+			v.Break()
+			v.Add("name").WithStyle(nameStyle)
+			v.Addln("value").WithStyle(valueStyle)
+			v.Break()
+
+			for _, name := range names {
+				v.Add("%s", name).WithStyle(nameStyle)
+				v.Addln("%s", outputs[name]).WithStyle(valueStyle)
+			}
+
+			fmt.Fprintln(progressOutput, v.Render())
+		}
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+// stackConfigProblem - a problem with the contents of a stack file found by nitric stack config
+type stackConfigProblem struct {
+	Message string `json:"message"`
+	// Line of the stack file the problem is on, 0 when it isn't about a single line
+	Line int `json:"line,omitempty"`
+	// Documentation of the field the problem is about, from the provider's stack template
+	Doc string `json:"doc,omitempty"`
+}
+
+// stackConfigProblems - validates the contents of a stack file, returning the errors that must be fixed before it's saved
+// and warnings about fields that may be mistakes
+func stackConfigProblems(projectConfig *project.ProjectConfiguration, stackName string, contents []byte) ([]stackConfigProblem, []stackConfigProblem) {
+	stackConfig, err := stack.ParseConfig[map[string]any](stackName, contents)
+	if err != nil {
+		return []stackConfigProblem{{Message: fmt.Sprintf("invalid yaml: %s", err)}}, nil
+	}
+
+	if stackConfig.Provider == "" {
+		return []stackConfigProblem{{Message: "provider is required"}}, nil
+	}
+
+	_, retryErr := stackConfig.RetryPolicy()
+
+	validationErrors := []error{
+		stackConfig.ValidateSecurity(),
+		retryErr,
+		stackConfig.ValidateRegions(),
+		stackConfig.ValidateCompliance(),
+		stackConfig.ValidateProtect(),
+		stackConfig.ValidateOidc(),
+		stackConfig.ValidateMonitoring(projectConfig.Notifications.Webhooks),
+		stackConfig.ValidateEmail(lo.FromPtr(projectConfig.Email).From),
+	}
+
+	errs := []stackConfigProblem{}
+	warnings := []stackConfigProblem{}
+
+	for _, err := range validationErrors {
+		if err != nil {
+			errs = append(errs, stackConfigProblem{Message: err.Error()})
+		}
+	}
+
+	issues, err := stack.CheckFields(stackConfig.Provider, contents)
+	tui.CheckErr(err)
+
+	for _, issue := range issues {
+		problem := stackConfigProblem{Message: issue.Message, Line: issue.Line, Doc: stack.FieldDoc(stackConfig.Provider, issue.Path)}
+
+		if issue.Invalid {
+			errs = append(errs, problem)
+		} else {
+			warnings = append(warnings, problem)
+		}
+	}
+
+	for _, issue := range provider.CheckQuotas(stackConfig.Provider, stackConfig.Region, stackConfig.Config, nil, false) {
+		warnings = append(warnings, stackConfigProblem{
+			Message: fmt.Sprintf("%s is %s", issue.Subject, issue.Reason),
+			Doc:     stack.FieldDoc(stackConfig.Provider, issue.Subject),
+		})
+	}
+
+	return errs, warnings
+}
+
+// stackConfigAnnotation - lines added to the top of a stack file being edited, describing its problems
+const stackConfigAnnotation = "#! "
+
+// annotateStackConfig - adds the problems of a stack file to its top, so they can be fixed in the editor
+func annotateStackConfig(contents []byte, errs []stackConfigProblem, warnings []stackConfigProblem) []byte {
+	if len(errs) == 0 && len(warnings) == 0 {
+		return contents
+	}
+
+	type headerLine struct {
+		text    string
+		problem *stackConfigProblem
+	}
+
+	header := []headerLine{}
+
+	if len(errs) > 0 {
+		header = append(header, headerLine{text: "The stack file has errors, fix them and save to continue, or close the editor without changes to discard your edits"})
+	}
+
+	describe := func(title string, problems []stackConfigProblem) {
+		if len(problems) == 0 {
+			return
+		}
+
+		header = append(header, headerLine{text: title})
+
+		for i := range problems {
+			header = append(header, headerLine{text: "  - " + problems[i].Message, problem: &problems[i]})
+
+			if problems[i].Doc != "" {
+				header = append(header, headerLine{text: "      " + problems[i].Doc})
+			}
+		}
+	}
+
+	describe("Errors:", errs)
+	describe("Warnings:", warnings)
+
+	header = append(header, headerLine{text: "Lines starting with #! are removed when the file is saved"}, headerLine{})
+
+	var out strings.Builder
+
+	for _, line := range header {
+		text := line.text
+
+		// lines are numbered as the file is shown in the editor, following the header
+		if line.problem != nil && line.problem.Line > 0 {
+			text = fmt.Sprintf("%s (line %d)", text, line.problem.Line+len(header))
+		}
+
+		out.WriteString(strings.TrimRight(stackConfigAnnotation+text, " ") + "\n")
+	}
+
+	return append([]byte(out.String()), contents...)
+}
+
+// stripStackConfigAnnotations - removes the problems added to the top of a stack file by annotateStackConfig
+func stripStackConfigAnnotations(contents []byte) []byte {
+	lines := strings.SplitAfter(string(contents), "\n")
+
+	return []byte(strings.Join(lo.Filter(lines, func(line string, _ int) bool {
+		return !strings.HasPrefix(line, strings.TrimSpace(stackConfigAnnotation))
+	}), ""))
+}
+
+var stackConfigFields bool
+
+var stackConfigCmd = &cobra.Command{
+	Use:   "config [-s stack]",
+	Short: "Edit a stack file with validation and documentation of its provider's fields",
+	Long: `Edit a stack file with validation and documentation of its provider's fields.
+
+The stack file is opened with $VISUAL or $EDITOR and checked when the editor is closed. Invalid yaml, settings rejected by
+the stack's validation and values that don't match the type of the field are shown at the top of the file and the editor
+is reopened until they're fixed, along with the documentation of each field. Undocumented fields and settings exceeding
+the limits of the stack's cloud are warned about, but don't stop the file from being saved.
+
+Fields are documented by the stack templates of the nitric providers, use --fields to list the fields of the stack's provider.`,
+	Example: `nitric stack config -s aws
+
+# List the documented fields of the stack's provider
+nitric stack config -s aws --fields
+
+# Edit with a different editor
+EDITOR="code --wait" nitric stack config -s aws`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		stackName := historyStackName(fs)
+
+		stackConfig, err := stack.ConfigFromName[map[string]any](fs, stackName)
+		tui.CheckErr(err)
+
+		if stackConfigFields {
+			fields := stack.Fields(stackConfig.Provider)
+
+			if structuredOutput() {
+				tui.CheckErr(printResult(fields))
+
+				return
+			}
+
+			if len(fields) == 0 {
+				fmt.Fprintf(progressOutput, "No fields are documented for provider %s\n", stackConfig.Provider)
+
+				return
+			}
+
+			for _, field := range fields {
+				fmt.Fprintln(progressOutput, lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Blue).Render(field.Path)+lo.Ternary(field.Example != "", lipgloss.NewStyle().Foreground(tui.Colors.Gray).Render(" e.g. "+field.Example), ""))
+
+				if field.Doc != "" {
+					fmt.Fprintf(progressOutput, "  %s\n", field.Doc)
+				}
+			}
+
+			return
+		}
+
+		if isNonInteractive() {
+			tui.CheckErr(fmt.Errorf("nitric stack config requires an interactive terminal, use --fields to list the documented fields"))
+		}
+
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(err)
+
+		stackFile := stack.StackFileName(stackName)
+
+		original, err := afero.ReadFile(fs, stackFile)
+		tui.CheckErr(err)
+
+		tmpDir, err := os.MkdirTemp("", "nitric-stack-config-*")
+		tui.CheckErr(err)
+
+		defer os.RemoveAll(tmpDir)
+
+		// the file keeps the stack file name so editors highlight it as yaml
+		tmpFile := filepath.Join(tmpDir, stackFile)
+		edited := original
+
+		errs, warnings := stackConfigProblems(projectConfig, stackName, edited)
+
+		for {
+			annotated := annotateStackConfig(edited, errs, warnings)
+
+			err = os.WriteFile(tmpFile, annotated, 0o600)
+			tui.CheckErr(err)
+
+			err = openEditor(tmpFile)
+			tui.CheckErr(err)
+
+			updated, err := os.ReadFile(tmpFile)
+			tui.CheckErr(err)
+
+			if bytes.Equal(updated, annotated) && len(errs) > 0 && !bytes.Equal(edited, original) {
+				fmt.Fprintf(progressOutput, "Edit cancelled, no changes were saved to %s\n", stackFile)
+
+				return
+			}
+
+			edited = stripStackConfigAnnotations(updated)
+
+			if bytes.Equal(edited, original) {
+				fmt.Fprintf(progressOutput, "No changes made to %s\n", stackFile)
+
+				return
+			}
+
+			errs, warnings = stackConfigProblems(projectConfig, stackName, edited)
+			if len(errs) == 0 {
+				break
+			}
+
+			tui.Warning.Printfln("%s has %d error(s), reopening the editor", stackFile, len(errs))
+		}
+
+		err = afero.WriteFile(fs, stackFile, edited, os.ModePerm)
+		tui.CheckErr(err)
+
+		for _, warning := range warnings {
+			tui.Warning.Printfln("%s", warning.Message)
+		}
+
+		fmt.Fprintf(progressOutput, "Saved %s\n", stackFile)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func AddOptions(cmd *cobra.Command, providerOnly bool) error {
+	fs := afero.NewOsFs()
+
+	stacks, err := stack.GetAllStackNames(fs)
+	if err != nil {
+		return fmt.Errorf("failed to get stacks available for this project. %w", err)
+	}
+
+	cmd.Flags().VarP(pflagx.NewStringEnumVar(&stackFlag, stacks, ""), "stack", "s", "specify a stack file, -s your_stack")
+
+	return cmd.RegisterFlagCompletionFunc("stack", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return stacks, cobra.ShellCompDirectiveDefault
+	})
+}
+
+func init() {
 	// New Stack
 	stackCmd.AddCommand(newStackCmd)
 	newStackCmd.Flags().BoolVarP(&forceNewStack, "force", "f", false, "force stack creation.")
+	newStackCmd.Flags().BoolVar(&skipCredentials, "skip-credentials", false, "skip the provider credentials check after creating the stack")
+	newStackCmd.Flags().StringVarP(&newStackTemplate, "template", "t", "", "create the stack from a preset template, one of "+strings.Join(stack.PresetNames(), ", "))
+	tui.CheckErr(newStackCmd.RegisterFlagCompletionFunc("template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return stack.PresetNames(), cobra.ShellCompDirectiveNoFileComp
+	}))
 
 	// Update Stack (Up)
 	stackCmd.AddCommand(tui.AddDependencyCheck(stackUpdateCmd, tui.Docker, tui.DockerBuildx))
 	stackUpdateCmd.Flags().StringVarP(&envFile, "env-file", "e", "", "--env-file config/.my-env")
 	stackUpdateCmd.Flags().BoolVarP(&forceStack, "force", "f", false, "force override previous deployment")
+	addBuildFlags(stackUpdateCmd)
+	addLicenseFlags(stackUpdateCmd)
+	stackUpdateCmd.Flags().StringVar(&providerOverride, "provider", "", "override the provider in the stack file, use noop to simulate the deployment without cloud credentials")
+	stackUpdateCmd.Flags().StringVar(&confirmStackName, "confirm-stack", "", "confirm the stack name for stacks that require it by a policy in nitric.yaml")
+	stackUpdateCmd.Flags().BoolVar(&rollbackOnFailure, "rollback-on-failure", false, "re-apply the last successful deployment if the deployment fails, defaults to rollback-on-failure in the stack file")
 	tui.CheckErr(AddOptions(stackUpdateCmd, false))
 
 	// Delete Stack (Down)
 	stackCmd.AddCommand(tui.AddDependencyCheck(stackDeleteCmd))
 	stackDeleteCmd.Flags().BoolVarP(&confirmDown, "yes", "y", false, "confirm the destruction of the stack")
+	stackDeleteCmd.Flags().StringVar(&providerOverride, "provider", "", "override the provider in the stack file, use noop to simulate undeploying the stack")
+	stackDeleteCmd.Flags().StringVar(&confirmStackName, "confirm-stack", "", "confirm the stack name for stacks that require it by a policy in nitric.yaml")
 	tui.CheckErr(AddOptions(stackDeleteCmd, false))
 
+	// Clone Stack
+	stackCmd.AddCommand(stackCloneCmd)
+	stackCloneCmd.Flags().StringVar(&cloneStackAs, "as", "", "name of the new stack")
+	stackCloneCmd.Flags().BoolVarP(&forceCloneStack, "force", "f", false, "overwrite the new stack if it already exists")
+	tui.CheckErr(stackCloneCmd.MarkFlagRequired("as"))
+	tui.CheckErr(AddOptions(stackCloneCmd, false))
+
+	// Garbage Collect Stack
+	stackCmd.AddCommand(tui.AddDependencyCheck(stackGcCmd, tui.Docker, tui.DockerBuildx))
+	stackGcCmd.Flags().StringVarP(&envFile, "env-file", "e", "", "--env-file config/.my-env")
+	stackGcCmd.Flags().BoolVar(&gcDelete, "delete", false, "delete orphaned resources that aren't protected")
+	stackGcCmd.Flags().BoolVarP(&gcConfirm, "yes", "y", false, "confirm the deletion of orphaned resources")
+	stackGcCmd.Flags().StringVar(&confirmStackName, "confirm-stack", "", "confirm the stack name for stacks that require it by a policy in nitric.yaml")
+	tui.CheckErr(AddOptions(stackGcCmd, false))
+
+	// preview stack
+	stackCmd.AddCommand(tui.AddDependencyCheck(stackPreviewCmd, tui.Docker, tui.DockerBuildx))
+	stackPreviewCmd.Flags().StringVarP(&envFile, "env-file", "e", "", "--env-file config/.my-env")
+	stackPreviewCmd.Flags().BoolVar(&previewExitCode, "exit-code", false, "exit with 6 when there are changes to deploy")
+	addBuildFlags(stackPreviewCmd)
+	tui.CheckErr(AddOptions(stackPreviewCmd, false))
+
 	// List Stacks
 	stackCmd.AddCommand(stackListCmd)
 
+	// Stack Status
+	stackCmd.AddCommand(stackStatusCmd)
+
+	// Stack Unlock
+	stackCmd.AddCommand(stackUnlockCmd)
+	stackUnlockCmd.Flags().BoolVar(&unlockForce, "force", false, "remove the lock, even if the operation holding it is still running")
+	tui.CheckErr(AddOptions(stackUnlockCmd, false))
+
+	// Stack Output
+	stackCmd.AddCommand(stackOutputCmd)
+	stackOutputCmd.Flags().StringVar(&stackOutputFormat, "format", "", "format to print the outputs in, one of json, env or shell")
+	tui.CheckErr(AddOptions(stackOutputCmd, false))
+
+	// Stack Config
+	stackCmd.AddCommand(stackConfigCmd)
+	stackConfigCmd.Flags().BoolVar(&stackConfigFields, "fields", false, "list the documented fields of the stack's provider")
+	tui.CheckErr(AddOptions(stackConfigCmd, false))
+
+	// Stack History
+	stackCmd.AddCommand(stackHistoryCmd)
+	stackHistoryCmd.AddCommand(stackHistoryShowCmd)
+	tui.CheckErr(AddOptions(stackHistoryCmd, false))
+	tui.CheckErr(AddOptions(stackHistoryShowCmd, false))
+
 	// Add Stack Commands
 	rootCmd.AddCommand(stackCmd)
 
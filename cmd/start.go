@@ -17,19 +17,10 @@
 package cmd
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
 	"fmt"
-	"math/big"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -37,13 +28,17 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/nitrictech/cli/pkg/apikeys"
+	"github.com/nitrictech/cli/pkg/certs"
 	"github.com/nitrictech/cli/pkg/cloud"
 	"github.com/nitrictech/cli/pkg/cloud/gateway"
 	"github.com/nitrictech/cli/pkg/dashboard"
 	"github.com/nitrictech/cli/pkg/env"
+	"github.com/nitrictech/cli/pkg/localenv"
 	"github.com/nitrictech/cli/pkg/paths"
 	"github.com/nitrictech/cli/pkg/project"
 	"github.com/nitrictech/cli/pkg/system"
+	"github.com/nitrictech/cli/pkg/tunnel"
 	"github.com/nitrictech/cli/pkg/view/tui"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/local"
 	"github.com/nitrictech/cli/pkg/view/tui/commands/services"
@@ -53,79 +48,53 @@ import (
 
 var (
 	startNoBrowser bool
+	startWatch     bool
 	enableHttps    bool
+	serviceFilter  []string
+	serviceExclude []string
 )
 
-// generateSelfSignedCert generates a self-signed X.509 certificate and returns the PEM-encoded certificate and private key
-func generateSelfSignedCert() ([]byte, []byte, error) {
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	notBefore := time.Now()
-	notAfter := notBefore.Add(365 * 24 * time.Hour)
+// localTlsCredentials - returns the credentials of the project's local HTTPS endpoints, issuing a certificate signed by the
+// local certificate authority if the project doesn't have a valid one. The message is set when the authority isn't trusted yet.
+func localTlsCredentials(fs afero.Fs, projectDir string) (*gateway.TLSCredentials, string) {
+	authority, _, err := certs.LoadAuthority(fs, paths.NitricLocalCADir())
+	tui.CheckErr(err)
 
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		return nil, nil, err
+	credentials := &gateway.TLSCredentials{
+		CertFile: paths.NitricTlsCertFile(projectDir),
+		KeyFile:  paths.NitricTlsKeyFile(projectDir),
 	}
 
-	template := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{"Acme Co"},
-		},
-		NotBefore:             notBefore,
-		NotAfter:              notAfter,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
-		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
-	}
+	err = authority.EnsureCertificate(fs, credentials.CertFile, credentials.KeyFile, certs.LocalHosts)
+	tui.CheckErr(err)
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
-	if err != nil {
-		return nil, nil, err
+	if !authority.Trusted() {
+		return credentials, "the local certificate authority isn't trusted by this machine, run nitric local trust so browsers accept the local https endpoints"
 	}
 
-	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
-
-	privBytes, err := x509.MarshalECPrivateKey(priv)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privBytes})
-
-	return certPEM, keyPEM, nil
+	return credentials, ""
 }
 
-func createTlsCredentialsIfNotPresent(fs afero.Fs, projectDir string) {
-	certPath := paths.NitricTlsCertFile(projectDir)
-	keyPath := paths.NitricTlsKeyFile(projectDir)
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Run nitric services locally for development and testing",
+	Long: `Run nitric services locally for development and testing
 
-	if _, err := os.Stat(certPath); os.IsNotExist(err) {
-		certPEM, keyPEM, err := generateSelfSignedCert()
-		tui.CheckErr(err)
+Use --service to start only the services matching a glob on their name or file path, and --exclude to skip services,
+e.g. to avoid the startup time and ports of services you aren't working on.
 
-		// Make sure the credentials directory exists
-		err = fs.MkdirAll(paths.NitricTlsCredentialsPath(projectDir), 0o700)
-		tui.CheckErr(err)
+Use --watch to restart a service when its files change. Files ignored by the service's runtime, e.g. node_modules
+or __pycache__, and the entrypoints of other services don't restart it, so editing one service doesn't restart the others.`,
+	Example: `nitric start
 
-		err = afero.WriteFile(fs, certPath, certPEM, 0o600)
-		tui.CheckErr(err)
+# Start only the api service
+nitric start --service services/api.ts
 
-		err = afero.WriteFile(fs, keyPath, keyPEM, 0o600)
-		tui.CheckErr(err)
-	}
-}
+# Start every service except the workers
+nitric start --exclude "services/workers/*"
 
-var startCmd = &cobra.Command{
-	Use:         "start",
-	Short:       "Run nitric services locally for development and testing",
-	Long:        `Run nitric services locally for development and testing`,
-	Example:     `nitric start`,
+# Restart services when their files change
+nitric start --watch`,
 	Annotations: map[string]string{"commonCommand": "yes"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Divert default log output to pterm debug
@@ -136,9 +105,12 @@ var startCmd = &cobra.Command{
 		proj, err := project.FromFile(fs, "")
 		tui.CheckErr(err)
 
-		fmt.Print(fragments.NitricTag())
-		fmt.Println(" start")
-		fmt.Println()
+		err = proj.SelectServices(serviceFilter, serviceExclude)
+		tui.CheckErr(err)
+
+		fmt.Fprint(progressOutput, fragments.NitricTag())
+		fmt.Fprintln(progressOutput, " start")
+		fmt.Fprintln(progressOutput)
 
 		additionalEnvFiles := []string{}
 
@@ -151,13 +123,24 @@ var startCmd = &cobra.Command{
 			tui.CheckErr(err)
 		}
 
+		apiWebhooks, err := proj.ApiWebhooks(localEnv)
+		tui.CheckErr(err)
+
+		apiRequestValidators, err := proj.ApiRequestValidators(fs)
+		tui.CheckErr(err)
+
+		runDir, persist := localCloudPersistence(cmd, proj)
+
+		// namespace the local cloud so it can run alongside the local clouds of other projects
+		localEnvironment, unregisterLocalEnvironment, err := localenv.Register(proj.Name, proj.Directory, "start")
+		tui.CheckErr(err)
+		defer unregisterLocalEnvironment()
+
 		var tlsCredentials *gateway.TLSCredentials
+
+		tlsMessage := ""
 		if enableHttps {
-			createTlsCredentialsIfNotPresent(fs, proj.Directory)
-			tlsCredentials = &gateway.TLSCredentials{
-				CertFile: paths.NitricTlsCertFile(proj.Directory),
-				KeyFile:  paths.NitricTlsKeyFile(proj.Directory),
-			}
+			tlsCredentials, tlsMessage = localTlsCredentials(fs, proj.Directory)
 		}
 
 		logFilePath, err := paths.NewNitricLogFile(proj.Directory)
@@ -168,20 +151,33 @@ var startCmd = &cobra.Command{
 		defer logWriter.Close()
 
 		teaOptions := []tea.ProgramOption{}
-		if isNonInteractive() {
-			teaOptions = append(teaOptions, tea.WithoutRenderer(), tea.WithInput(nil))
+		if plainOutput() {
+			teaOptions = append(teaOptions, teax.PlainOptions()...)
 		}
 
-		runView := teax.NewProgram(local.NewLocalCloudStartModel(isNonInteractive()), teaOptions...)
+		runView := teax.NewProgram(local.NewLocalCloudStartModel(plainOutput()), teaOptions...)
 
 		var localCloud *cloud.LocalCloud
 		go func() {
 			// Start the local cloud service analogues
 			localCloud, err = cloud.New(proj.Name, cloud.LocalCloudOptions{
-				TLSCredentials:  tlsCredentials,
-				LogWriter:       logWriter,
-				LocalConfig:     proj.LocalConfig,
-				MigrationRunner: project.BuildAndRunMigrations,
+				TLSCredentials:       tlsCredentials,
+				LogWriter:            logWriter,
+				LocalConfig:          proj.LocalConfig,
+				MigrationRunner:      project.BuildAndRunMigrations,
+				Flags:                proj.Flags,
+				ApiRateLimits:        proj.ApiRateLimits(),
+				ApiKeyRequired:       proj.ApisRequiringApiKey(),
+				ValidateApiKey:       apikeys.NewValidator(proj.Directory),
+				ApiMiddleware:        proj.ApiMiddleware(),
+				ApiWebhooks:          apiWebhooks,
+				ApiRequestValidators: apiRequestValidators,
+				HttpProxyPorts:       proj.HttpProxyPorts(),
+				Namespace:            localEnvironment.Namespace,
+				RunDir:               runDir,
+				Persist:              persist,
+				CaptureEmail:         proj.Email != nil,
+				Dependencies:         proj.Dependencies,
 			})
 			tui.CheckErr(err)
 			runView.Send(local.LocalCloudStartStatusMsg{Status: local.Done})
@@ -197,12 +193,19 @@ var startCmd = &cobra.Command{
 		err = dash.Start()
 		tui.CheckErr(err)
 
+		err = localEnvironment.SetDashboard(dash.GetDashboardUrl(), dash.GetApiToken())
+		tui.CheckErr(err)
+
+		// share the local endpoints with nitric tunnel
+		stopPublishingEndpoints := tunnel.PublishEndpoints(proj.Directory, localCloud.Gateway)
+		defer stopPublishingEndpoints()
+
 		bold := lipgloss.NewStyle().Bold(true).Foreground(tui.Colors.Purple)
 		numServices := fmt.Sprintf("%d", len(proj.GetServices()))
 
-		fmt.Print("found ")
-		fmt.Print(bold.Render(numServices))
-		fmt.Print(" services in project\n")
+		fmt.Fprint(progressOutput, "found ")
+		fmt.Fprint(progressOutput, bold.Render(numServices))
+		fmt.Fprint(progressOutput, " services in project\n")
 
 		// Run the app code (project services)
 		stopChan := make(chan bool)
@@ -217,7 +220,12 @@ var startCmd = &cobra.Command{
 		}()
 
 		go func() {
-			err := proj.RunServicesWithCommand(localCloud, stopChan, updatesChan, localEnv)
+			runOptions := []project.RunCommandOption{}
+			if startWatch {
+				runOptions = append(runOptions, project.WithHotReload())
+			}
+
+			err := proj.RunServicesWithCommand(localCloud, stopChan, updatesChan, localEnv, runOptions...)
 			if err != nil {
 				localCloud.Stop()
 				tui.CheckErr(err)
@@ -236,10 +244,18 @@ var startCmd = &cobra.Command{
 			}
 		})
 
-		allUpdates := lo.FanIn(10, updatesChan, systemChan)
+		if tlsMessage != "" {
+			system.Log(tlsMessage)
+		}
+
+		logEmailCapture(localCloud, dash.GetDashboardUrl())
+		logDependencies(localCloud)
+		seedLocalCloud(fs, proj, localCloud, updatesChan, localEnv)
+
+		allUpdates := dash.CaptureLogs(lo.FanIn(10, updatesChan, systemChan))
 
 		// non-interactive environment
-		if isNonInteractive() {
+		if plainOutput() {
 			go func() {
 				sigChan := make(chan os.Signal, 1)
 				signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
@@ -247,7 +263,7 @@ var startCmd = &cobra.Command{
 				// Wait for a signal
 				<-sigChan
 
-				fmt.Println("Stopping local cloud")
+				fmt.Fprintln(progressOutput, "Stopping local cloud")
 
 				localCloud.Stop()
 
@@ -258,9 +274,9 @@ var startCmd = &cobra.Command{
 			for {
 				select {
 				case update := <-allUpdates:
-					fmt.Printf("%s [%s]: %s", update.ServiceName, update.Status, update.Message)
+					fmt.Fprintf(progressOutput, "%s [%s]: %s", update.ServiceName, update.Status, update.Message)
 				case <-stopChan:
-					fmt.Println("Shutting down services - exiting")
+					fmt.Fprintln(progressOutput, "Shutting down services - exiting")
 					return nil
 				}
 			}
@@ -281,7 +297,14 @@ var startCmd = &cobra.Command{
 
 func init() {
 	startCmd.Flags().StringVarP(&envFile, "env-file", "e", "", "--env-file config/.my-env")
-	startCmd.Flags().BoolVar(&enableHttps, "https-preview", false, "enable https support for local APIs (preview feature)")
+	startCmd.Flags().BoolVar(&enableHttps, "https", false, "serve local APIs, http proxies and websockets over https with a certificate signed by the local certificate authority")
+	startCmd.Flags().BoolVar(&enableHttps, "https-preview", false, "serve local APIs, http proxies and websockets over https")
+	tui.CheckErr(startCmd.Flags().MarkDeprecated("https-preview", "use --https instead"))
+	startCmd.Flags().BoolVar(&startWatch, "watch", false, "restart services when their files change")
+	startCmd.Flags().BoolVar(&runPersist, "persist", false, "keep the state of local buckets, key/value stores, secrets and queues between runs in .nitric/state, defaults to persist in local.nitric.yaml")
+	startCmd.Flags().BoolVar(&runReseed, "reseed", false, "apply the seeds in nitric.yaml again, even if they've already been applied to the local state")
+	startCmd.Flags().StringSliceVar(&serviceFilter, "service", []string{}, "only start services matching a glob on their name or file path, can be repeated")
+	startCmd.Flags().StringSliceVar(&serviceExclude, "exclude", []string{}, "skip services matching a glob on their name or file path, can be repeated")
 	startCmd.PersistentFlags().BoolVar(
 		&startNoBrowser,
 		"no-browser",
@@ -0,0 +1,117 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/tunnel"
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+var tunnelProvider string
+
+var tunnelCmd = &cobra.Command{
+	Use:   "tunnel [endpoint]",
+	Short: "Share a local API, websocket or HTTP proxy on a public URL",
+	Long: `Share a local API, websocket or HTTP proxy on a public URL, so webhooks from services like Stripe or GitHub can reach your local handlers.
+
+The endpoint is served by the project's running local cloud, start it with nitric start or nitric run first.
+Tunnels are created with cloudflared or ngrok, which must be installed and on your PATH.`,
+	Example: `# Share the main API
+nitric tunnel main
+
+# Share a websocket using ngrok
+nitric tunnel websocket/chat --provider ngrok`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		endpoints, err := tunnel.ReadEndpoints(proj.Directory)
+		tui.CheckErr(err)
+
+		if len(endpoints) == 0 {
+			tui.CheckErr(fmt.Errorf("the local cloud isn't serving any APIs, websockets or HTTP proxies yet"))
+		}
+
+		var endpoint tunnel.Endpoint
+
+		switch {
+		case len(args) > 0:
+			endpoint, err = tunnel.FindEndpoint(endpoints, args[0])
+			tui.CheckErr(err)
+		case len(endpoints) == 1:
+			endpoint = endpoints[0]
+		case isNonInteractive():
+			tui.CheckErr(fmt.Errorf("multiple local endpoints found, specify the endpoint to share e.g. nitric tunnel %s", endpoints[0].Name))
+		default:
+			choice := ""
+
+			err = tui.AskOne(&survey.Select{
+				Message: "Which endpoint would you like to share?",
+				Options: lo.Map(endpoints, func(e tunnel.Endpoint, _ int) string { return e.String() }),
+			}, &choice)
+			if err != nil {
+				return
+			}
+
+			endpoint, _ = lo.Find(endpoints, func(e tunnel.Endpoint) bool { return e.String() == choice })
+		}
+
+		provider, err := tunnel.FindProvider(tunnelProvider)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "Starting %s tunnel to %s (%s)\n", provider.Name, endpoint, endpoint.Url)
+
+		t, err := provider.Start(endpoint.Url)
+		tui.CheckErr(err)
+
+		defer t.Stop()
+
+		tui.Info.Printfln("Forwarding %s -> %s", t.PublicUrl, endpoint.Url)
+		fmt.Fprintln(progressOutput, "Anyone with the URL can reach this endpoint, press ctrl+c to stop the tunnel")
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+
+		select {
+		case <-sigChan:
+		case err := <-t.Done():
+			tui.CheckErr(fmt.Errorf("%s tunnel stopped: %w", provider.Name, err))
+		}
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+func init() {
+	tunnelCmd.Flags().StringVarP(&tunnelProvider, "provider", "p", "", "the tunnel provider, one of cloudflared or ngrok, defaults to the first installed")
+	tui.CheckErr(tunnelCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return tunnel.ProviderNames(), cobra.ShellCompDirectiveNoFileComp
+	}))
+	rootCmd.AddCommand(tunnelCmd)
+}
@@ -29,7 +29,7 @@ var versionCmd = &cobra.Command{
 	Short: "Print the version number of this CLI",
 	Long:  `All software has versions. This is Nitric's`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(version.Version)
+		fmt.Fprintln(progressOutput, version.Version)
 	},
 }
 
@@ -0,0 +1,127 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/watch"
+)
+
+var (
+	watchInterval  time.Duration
+	watchEndpoints []string
+	watchWebhooks  []string
+	watchOnce      bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Monitor a deployed stack for drift and endpoint health",
+	Long: `Monitor a deployed stack for drift and endpoint health.
+
+Periodically checks whether the stack file has changed since the stack was last deployed and whether the
+endpoints output by the last deployment are reachable. Notifications are sent to the webhooks configured under
+notifications in nitric.yaml, or provided with --webhook, whenever the health of the stack changes.
+
+The stack is checked against its most recent deployment digest, preferring the digest uploaded to the shared digest
+location under digest in nitric.yaml when it's newer than the local history, so deployments made in CI or by teammates
+are checked. Only s3:// digest locations can be read, without one only deployments made from this machine are found.`,
+	Example: `nitric watch --stack prod
+
+# Check every minute, including an additional endpoint
+nitric watch --stack prod --interval 1m --endpoint https://example.com/health
+
+# Run a single check, exiting with 6 if the stack has drifted or 5 if it's otherwise unhealthy
+nitric watch --stack prod --once`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fs := afero.NewOsFs()
+
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		tui.CheckErr(exitcode.Wrap(exitcode.Config, err))
+
+		if stackFlag == "" {
+			tui.CheckErr(fmt.Errorf("please specify the stack to watch with --stack"))
+		}
+
+		if watchInterval <= 0 {
+			tui.CheckErr(fmt.Errorf("--interval must be greater than 0"))
+		}
+
+		opts := watch.Options{
+			Project:        projectConfig.Name,
+			Stack:          stackFlag,
+			DigestLocation: projectConfig.Digest.Upload,
+			Interval:       watchInterval,
+			Endpoints:      watchEndpoints,
+			Webhooks:       append(projectConfig.Notifications.Webhooks, watchWebhooks...),
+			OnCheck: func(check *watch.Check) {
+				if check.Healthy() {
+					tui.Info.Printfln("%s %s", check.Time.Local().Format(time.DateTime), check.Summary())
+				} else {
+					tui.Warning.Printfln("%s %s", check.Time.Local().Format(time.DateTime), check.Summary())
+				}
+			},
+			OnError: func(err error) {
+				tui.Error.Println(err.Error())
+			},
+		}
+
+		if watchOnce {
+			check, err := watch.CheckStack(context.Background(), fs, opts)
+			tui.CheckErr(err)
+
+			opts.OnCheck(check)
+
+			switch {
+			case check.Drift:
+				exitCode = exitcode.Drift
+			case !check.Healthy():
+				exitCode = exitcode.Deployment
+			}
+
+			return
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer cancel()
+
+		err = watch.Watch(ctx, fs, opts)
+		tui.CheckErr(err)
+	},
+	Args: cobra.ExactArgs(0),
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Minute, "time between checks")
+	watchCmd.Flags().StringArrayVar(&watchEndpoints, "endpoint", []string{}, "additional endpoint to health check, may be repeated")
+	watchCmd.Flags().StringArrayVar(&watchWebhooks, "webhook", []string{}, "additional webhook to notify when the health of the stack changes, may be repeated")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "run a single check and exit")
+	tui.CheckErr(AddOptions(watchCmd, false))
+
+	rootCmd.AddCommand(watchCmd)
+}
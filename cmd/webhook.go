@@ -0,0 +1,192 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/cloud/gateway"
+	"github.com/nitrictech/cli/pkg/env"
+	"github.com/nitrictech/cli/pkg/project"
+	"github.com/nitrictech/cli/pkg/tunnel"
+	"github.com/nitrictech/cli/pkg/view/tui"
+	"github.com/nitrictech/cli/pkg/webhooks"
+)
+
+var (
+	webhookScheme  string
+	webhookSecret  string
+	webhookHeader  string
+	webhookData    string
+	webhookMethod  string
+	webhookHeaders []string
+	webhookUrl     string
+)
+
+var webhookCmd = &cobra.Command{
+	Use:     "webhook",
+	Short:   "Send signed webhooks to your APIs",
+	Long:    `Send signed webhooks to your APIs.`,
+	Example: `nitric webhook send main /webhooks/stripe -d @event.json`,
+}
+
+var webhookSendCmd = &cobra.Command{
+	Use:   "send [api] [path]",
+	Short: "Send a signed webhook request to an API route",
+	Long: `Send a signed webhook request to an API route of the running local cloud.
+
+The signing scheme and secret default to the webhook configured for the route in nitric.yaml, e.g.
+
+apis:
+  main:
+    webhooks:
+      - path: /webhooks/stripe
+        scheme: stripe
+        secret: ${STRIPE_WEBHOOK_SECRET}
+
+Supported schemes are ` + strings.Join(webhooks.SchemeNames, ", ") + `.`,
+	Example: `# Send a stripe event from a file
+nitric webhook send main /webhooks/stripe -d @event.json
+
+# Send a github webhook signed with an explicit secret to a deployed API
+nitric webhook send main /webhooks/github --scheme github --secret mysecret --url https://example.com -d '{"action":"opened"}'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		apiName, routePath := args[0], args[1]
+
+		fs := afero.NewOsFs()
+
+		proj, err := project.FromFile(fs, "")
+		tui.CheckErr(err)
+
+		localEnv, err := env.ReadLocalEnv()
+		if err != nil && !os.IsNotExist(err) {
+			tui.CheckErr(err)
+		}
+
+		apiWebhooks, err := proj.ApiWebhooks(localEnv)
+		tui.CheckErr(err)
+
+		hook, ok := lo.Find(apiWebhooks[apiName], func(w gateway.Webhook) bool {
+			match, _ := path.Match(w.Path, routePath)
+			return match
+		})
+
+		if webhookScheme != "" {
+			hook.Scheme, err = webhooks.GetScheme(webhookScheme, webhookHeader)
+			tui.CheckErr(err)
+		}
+
+		if webhookSecret != "" {
+			hook.Secret = webhookSecret
+		}
+
+		if hook.Scheme == nil || hook.Secret == "" {
+			tui.CheckErr(fmt.Errorf("no webhook is configured for %s on api %s in nitric.yaml, provide the --scheme and --secret to sign the request with", routePath, apiName))
+		}
+
+		body := []byte(webhookData)
+		if fileName, isFile := strings.CutPrefix(webhookData, "@"); isFile {
+			body, err = afero.ReadFile(fs, fileName)
+			tui.CheckErr(err)
+		}
+
+		baseUrl := webhookUrl
+		if !ok && baseUrl == "" {
+			tui.Warning.Printfln("no webhook is configured for %s on api %s, the local gateway won't verify its signature", routePath, apiName)
+		}
+
+		if baseUrl == "" {
+			endpoints, err := tunnel.ReadEndpoints(proj.Directory)
+			tui.CheckErr(err)
+
+			endpoint, err := tunnel.FindEndpoint(endpoints, string(tunnel.EndpointType_Api)+"/"+apiName)
+			tui.CheckErr(err)
+
+			baseUrl = endpoint.Url
+		}
+
+		req, err := http.NewRequest(strings.ToUpper(webhookMethod), strings.TrimSuffix(baseUrl, "/")+routePath, bytes.NewReader(body))
+		tui.CheckErr(err)
+
+		req.Header.Set("Content-Type", "application/json")
+
+		for _, header := range webhookHeaders {
+			key, value, found := strings.Cut(header, ":")
+			if !found {
+				tui.CheckErr(fmt.Errorf("invalid header %s, headers must be in the format 'Key: Value'", header))
+			}
+
+			req.Header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+
+		for k, v := range hook.Scheme.Sign(hook.Secret, body, time.Now()) {
+			req.Header.Set(k, v)
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		if webhookUrl == "" {
+			// the local gateway uses a self-signed certificate when https is enabled
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}} //nolint:gosec
+		}
+
+		resp, err := client.Do(req)
+		tui.CheckErr(err)
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		tui.CheckErr(err)
+
+		fmt.Fprintf(progressOutput, "%s %s signed with %s: %s\n", req.Method, req.URL, hook.Scheme.Name(), resp.Status)
+
+		if len(respBody) > 0 {
+			fmt.Fprintln(progressOutput, string(respBody))
+		}
+
+		if resp.StatusCode >= 400 {
+			tui.CheckErr(fmt.Errorf("webhook request failed with status %s", resp.Status))
+		}
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+func init() {
+	webhookSendCmd.Flags().StringVar(&webhookScheme, "scheme", "", "signing scheme, one of "+strings.Join(webhooks.SchemeNames, ", ")+", defaults to the scheme configured for the route")
+	webhookSendCmd.Flags().StringVar(&webhookSecret, "secret", "", "signing secret, defaults to the secret configured for the route")
+	webhookSendCmd.Flags().StringVar(&webhookHeader, "signature-header", "", "header containing the signature for the hmac-sha256 scheme")
+	webhookSendCmd.Flags().StringVarP(&webhookData, "data", "d", "{}", "request body, prefix with @ to read it from a file, e.g. @event.json")
+	webhookSendCmd.Flags().StringVarP(&webhookMethod, "method", "X", "POST", "request method")
+	webhookSendCmd.Flags().StringArrayVarP(&webhookHeaders, "header", "H", []string{}, "additional request headers, e.g. -H 'X-GitHub-Event: push'")
+	webhookSendCmd.Flags().StringVar(&webhookUrl, "url", "", "base URL of the API, defaults to the API of the running local cloud")
+	tui.CheckErr(webhookSendCmd.RegisterFlagCompletionFunc("scheme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return webhooks.SchemeNames, cobra.ShellCompDirectiveNoFileComp
+	}))
+
+	webhookCmd.AddCommand(webhookSendCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
@@ -0,0 +1,182 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+// Header - the request header API keys are read from, matching deployed API gateways
+const Header = "x-api-key"
+
+const keyPrefix = "nk_"
+
+// ApiKey - a key granting access to APIs that require an API key, only a hash of the key is stored
+type ApiKey struct {
+	Name string `json:"name"`
+	// The API the key grants access to, empty for all APIs
+	Api       string     `json:"api,omitempty"`
+	Hash      string     `json:"hash"`
+	Hint      string     `json:"hint"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+func (k *ApiKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Store - the local API keys of a project
+type Store struct {
+	path string
+	Keys []ApiKey
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load - loads the local API keys of the project in the given directory
+func Load(projectDir string) (*Store, error) {
+	store := &Store{
+		path: paths.NitricApiKeysFile(projectDir),
+		Keys: []ApiKey{},
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &store.Keys); err != nil {
+		return nil, fmt.Errorf("unable to parse api keys file %s: %w", store.path, err)
+	}
+
+	return store, nil
+}
+
+// Save - writes the keys to the project's api keys file
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.Keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// Create - creates a new API key, returning the key, which can't be retrieved from the store later
+func (s *Store) Create(name string, api string) (string, error) {
+	if slices.ContainsFunc(s.Keys, func(k ApiKey) bool { return k.Name == name && !k.Revoked() }) {
+		return "", fmt.Errorf("api key %s already exists", name)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	key := keyPrefix + hex.EncodeToString(secret)
+
+	s.Keys = append(s.Keys, ApiKey{
+		Name:      name,
+		Api:       api,
+		Hash:      hashKey(key),
+		Hint:      key[len(key)-4:],
+		CreatedAt: time.Now().UTC(),
+	})
+
+	return key, nil
+}
+
+// Revoke - revokes an API key by name
+func (s *Store) Revoke(name string) error {
+	for i, key := range s.Keys {
+		if key.Name == name && !key.Revoked() {
+			now := time.Now().UTC()
+			s.Keys[i].RevokedAt = &now
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("api key %s not found", name)
+}
+
+// Valid - returns true if the key grants access to the API
+func (s *Store) Valid(apiName string, key string) bool {
+	hash := hashKey(key)
+
+	return slices.ContainsFunc(s.Keys, func(k ApiKey) bool {
+		return !k.Revoked() && (k.Api == "" || k.Api == apiName) && subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hash)) == 1
+	})
+}
+
+// NewValidator - returns a function validating keys against the project's local API keys,
+// the keys are reloaded when the keys file changes so keys can be created and revoked while the project is running
+func NewValidator(projectDir string) func(apiName string, key string) bool {
+	var (
+		lock    sync.Mutex
+		store   = &Store{}
+		modTime time.Time
+	)
+
+	return func(apiName string, key string) bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		info, err := os.Stat(paths.NitricApiKeysFile(projectDir))
+		if err != nil {
+			// no keys have been created
+			return false
+		}
+
+		if !info.ModTime().Equal(modTime) {
+			loaded, err := Load(projectDir)
+			if err != nil {
+				return false
+			}
+
+			store = loaded
+			modTime = info.ModTime()
+		}
+
+		return store.Valid(apiName, key)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nitrictech/cli/pkg/preferences"
+)
+
+// Task - a unit of work performed by the CLI with an elapsed time budget
+type Task string
+
+const (
+	Build  Task = "build"
+	Deploy Task = "deploy"
+)
+
+var defaultThresholds = map[Task]time.Duration{
+	Build:  5 * time.Minute,
+	Deploy: 20 * time.Minute,
+}
+
+var descriptions = map[Task]string{
+	Build:  "image build",
+	Deploy: "deployment",
+}
+
+var hints = map[Task]string{
+	Build:  "consider enabling cache export",
+	Deploy: "check the provider logs for slow resources",
+}
+
+// Threshold - returns the time budget for a task, preferences override the defaults and a threshold of 0 disables the warning
+func Threshold(task Task) time.Duration {
+	prefs, err := preferences.Current()
+	if err == nil {
+		if threshold, ok := prefs.Budgets[string(task)]; ok {
+			return threshold
+		}
+	}
+
+	return defaultThresholds[task]
+}
+
+// Check - returns a warning if the elapsed time of a task exceeded its budget
+func Check(task Task, elapsed time.Duration) (string, bool) {
+	threshold := Threshold(task)
+	if threshold <= 0 || elapsed <= threshold {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s exceeded %s — %s", descriptions[task], threshold, hints[task]), true
+}
@@ -0,0 +1,285 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+)
+
+const (
+	caCertFile = "rootCA.pem"
+	caKeyFile  = "rootCA-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 825 * 24 * time.Hour
+	// leaf certificates expiring sooner than this are reissued
+	leafRenewal = 30 * 24 * time.Hour
+)
+
+// LocalHosts - the hosts local endpoints are served on
+var LocalHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Authority - a certificate authority, local to this machine, signing the certificates of local endpoints
+type Authority struct {
+	// CertFile - the path of the PEM encoded certificate of the authority
+	CertFile string
+
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), nil
+}
+
+func readCertificate(fs afero.Fs, file string) (*x509.Certificate, error) {
+	contents, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s doesn't contain a PEM encoded certificate", file)
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func readKey(fs afero.Fs, file string) (*ecdsa.PrivateKey, error) {
+	contents, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, fmt.Errorf("%s doesn't contain a PEM encoded private key", file)
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// authorityName - the name of the authority, including the user so it can be told apart in trust stores
+func authorityName() string {
+	name := "nitric local CA"
+
+	if u, err := user.Current(); err == nil {
+		name = fmt.Sprintf("%s %s", name, u.Username)
+	}
+
+	return name
+}
+
+// LoadAuthority - loads the authority in dir, creating it when it doesn't exist yet.
+// Returns true when the authority was created, in which case it isn't trusted by this machine yet.
+func LoadAuthority(fs afero.Fs, dir string) (*Authority, bool, error) {
+	certPath := filepath.Join(dir, caCertFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	exists, err := afero.Exists(fs, certPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if exists {
+		cert, err := readCertificate(fs, certPath)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to read the local certificate authority: %w", err)
+		}
+
+		key, err := readKey(fs, keyPath)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to read the local certificate authority: %w", err)
+		}
+
+		if time.Now().Before(cert.NotAfter) {
+			return &Authority{CertFile: certPath, cert: cert, key: key}, false, nil
+		}
+	}
+
+	authority, err := createAuthority(fs, certPath, keyPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to create the local certificate authority: %w", err)
+	}
+
+	return authority, true, nil
+}
+
+func createAuthority(fs afero.Fs, certPath string, keyPath string) (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	name := authorityName()
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"nitric local development CA"},
+			CommonName:   name,
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(certPath), 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = afero.WriteFile(fs, keyPath, keyPEM, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = afero.WriteFile(fs, certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authority{CertFile: certPath, cert: cert, key: key}, nil
+}
+
+// Name - the common name of the authority's certificate
+func (a *Authority) Name() string {
+	return a.cert.Subject.CommonName
+}
+
+// signed - returns true if cert was signed by this authority, covers hosts and isn't due for renewal
+func (a *Authority) signed(cert *x509.Certificate, hosts []string) bool {
+	if cert.CheckSignatureFrom(a.cert) != nil {
+		return false
+	}
+
+	if time.Now().Add(leafRenewal).After(cert.NotAfter) {
+		return false
+	}
+
+	return lo.EveryBy(hosts, func(host string) bool {
+		return cert.VerifyHostname(host) == nil
+	})
+}
+
+// EnsureCertificate - writes a certificate for hosts signed by the authority to certFile and keyFile,
+// unless they already contain one that is still valid.
+func (a *Authority) EnsureCertificate(fs afero.Fs, certFile string, keyFile string, hosts []string) error {
+	if existing, err := readCertificate(fs, certFile); err == nil && a.signed(existing, hosts) {
+		if _, err := readKey(fs, keyFile); err == nil {
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"nitric local development certificate"},
+		},
+		NotBefore:   time.Now(),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = fs.MkdirAll(filepath.Dir(certFile), 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = afero.WriteFile(fs, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes}), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, keyFile, keyPEM, 0o600)
+}
@@ -0,0 +1,102 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// linuxTrustStores - the directories and refresh commands of the system trust stores of common linux distributions
+var linuxTrustStores = []struct {
+	dir     string
+	refresh []string
+}{
+	{dir: "/usr/local/share/ca-certificates", refresh: []string{"update-ca-certificates"}},
+	{dir: "/etc/pki/ca-trust/source/anchors", refresh: []string{"update-ca-trust", "extract"}},
+	{dir: "/etc/ca-certificates/trust-source/anchors", refresh: []string{"trust", "extract-compat"}},
+}
+
+// Trusted - returns true if the system trusts certificates signed by the authority
+func (a *Authority) Trusted() bool {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return false
+	}
+
+	_, err = a.cert.Verify(x509.VerifyOptions{Roots: pool})
+
+	return err == nil
+}
+
+// trustCommands - the commands that add the authority to the system trust store
+func (a *Authority) trustCommands() ([][]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"sudo", "security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", "/Library/Keychains/System.keychain", a.CertFile}}, nil
+	case "windows":
+		return [][]string{{"certutil", "-addstore", "-user", "ROOT", a.CertFile}}, nil
+	case "linux":
+		for _, store := range linuxTrustStores {
+			if _, err := os.Stat(store.dir); err != nil {
+				continue
+			}
+
+			if _, err := exec.LookPath(store.refresh[0]); err != nil {
+				continue
+			}
+
+			target := filepath.Join(store.dir, strings.ReplaceAll(a.Name(), " ", "_")+".crt")
+
+			return [][]string{
+				{"sudo", "cp", a.CertFile, target},
+				append([]string{"sudo"}, store.refresh...),
+			}, nil
+		}
+
+		return nil, fmt.Errorf("unable to find a supported system trust store, add %s to your trust store manually", a.CertFile)
+	default:
+		return nil, fmt.Errorf("trusting certificates isn't supported on %s, add %s to your trust store manually", runtime.GOOS, a.CertFile)
+	}
+}
+
+// Trust - adds the authority to the system trust store, which may prompt for an administrator password.
+// Browsers with their own trust stores, e.g. Firefox, need the authority's certificate imported separately.
+func (a *Authority) Trust() error {
+	commands, err := a.trustCommands()
+	if err != nil {
+		return err
+	}
+
+	for _, args := range commands {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("unable to trust the local certificate authority, %s failed: %w", strings.Join(args, " "), err)
+		}
+	}
+
+	return nil
+}
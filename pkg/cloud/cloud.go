@@ -19,12 +19,17 @@ package cloud
 import (
 	"fmt"
 	"io"
+	"path/filepath"
 	"sync"
 
+	"github.com/samber/lo"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/nitrictech/cli/pkg/cloud/apis"
+	"github.com/nitrictech/cli/pkg/cloud/dependencies"
+	"github.com/nitrictech/cli/pkg/cloud/email"
+	"github.com/nitrictech/cli/pkg/cloud/env"
 	"github.com/nitrictech/cli/pkg/cloud/gateway"
 	"github.com/nitrictech/cli/pkg/cloud/http"
 	"github.com/nitrictech/cli/pkg/cloud/keyvalue"
@@ -35,11 +40,14 @@ import (
 	"github.com/nitrictech/cli/pkg/cloud/sql"
 	"github.com/nitrictech/cli/pkg/cloud/storage"
 	"github.com/nitrictech/cli/pkg/cloud/topics"
+	"github.com/nitrictech/cli/pkg/cloud/usage"
 	"github.com/nitrictech/cli/pkg/cloud/websockets"
 	"github.com/nitrictech/cli/pkg/grpcx"
 	"github.com/nitrictech/cli/pkg/netx"
 	"github.com/nitrictech/cli/pkg/project/localconfig"
+	"github.com/nitrictech/cli/pkg/session"
 	"github.com/nitrictech/nitric/core/pkg/logger"
+	sqlpb "github.com/nitrictech/nitric/core/pkg/proto/sql/v1"
 	"github.com/nitrictech/nitric/core/pkg/server"
 )
 
@@ -66,6 +74,14 @@ type LocalCloud struct {
 	Websockets *websockets.LocalWebsocketService
 	Queues     *queues.LocalQueuesService
 	Databases  *sql.LocalSqlServer
+	Usage      *usage.LocalUsageService
+	Email      *email.LocalEmailService
+	// Containers started alongside the local cloud, nil when the project has no dependencies
+	Dependencies *dependencies.LocalDependencies
+
+	runDir string
+	// persists the messages in queues when the local cloud stops, empty unless the local cloud state is persisted
+	queuesDir string
 
 	// Store all the plugins locally
 }
@@ -81,13 +97,55 @@ func (lc *LocalCloud) Stop() {
 		logger.Errorf("Error stopping gateway: %s", err.Error())
 	}
 
-	err = lc.Databases.Stop()
-	if err != nil {
-		logger.Errorf("Error stopping databases: %s", err.Error())
+	if lc.Email != nil {
+		err = lc.Email.Stop()
+		if err != nil {
+			logger.Errorf("Error stopping email: %s", err.Error())
+		}
+	}
+
+	if lc.Databases != nil {
+		err = lc.Databases.Stop()
+		if err != nil {
+			logger.Errorf("Error stopping databases: %s", err.Error())
+		}
+	}
+
+	if lc.Dependencies != nil {
+		err = lc.Dependencies.Stop()
+		if err != nil {
+			logger.Errorf("Error stopping dependencies: %s", err.Error())
+		}
+	}
+
+	if lc.queuesDir != "" {
+		err = lc.saveQueues(lc.queuesDir)
+		if err != nil {
+			logger.Errorf("Error saving queues: %s", err.Error())
+		}
 	}
 }
 
+// RunDir - returns the directory containing the files of local buckets, key/value stores and secrets
+func (lc *LocalCloud) RunDir() string {
+	return lc.runDir
+}
+
 func (lc *LocalCloud) AddService(serviceName string) (int, error) {
+	return lc.AddServiceWithPort(serviceName, 0)
+}
+
+// AddServiceWithPort - starts a nitric server for the service, listening on the preferred port when it is free
+func (lc *LocalCloud) AddServiceWithPort(serviceName string, preferredPort int) (int, error) {
+	return lc.addService(serviceName, preferredPort, false)
+}
+
+// AddServiceOnPort - starts a nitric server for the service listening on port, failing if the port is in use
+func (lc *LocalCloud) AddServiceOnPort(serviceName string, port int) (int, error) {
+	return lc.addService(serviceName, port, true)
+}
+
+func (lc *LocalCloud) addService(serviceName string, preferredPort int, fixed bool) (int, error) {
 	lc.serverLock.Lock()
 	defer lc.serverLock.Unlock()
 
@@ -96,12 +154,17 @@ func (lc *LocalCloud) AddService(serviceName string) (int, error) {
 	}
 
 	// get an available port
-	ports, err := netx.TakePort(1)
+	port, err := netx.TakePreferredPort(preferredPort)
 	if err != nil {
 		return 0, err
 	}
 
-	nitricRuntimeServer, _ := server.New(
+	if fixed && port != preferredPort {
+		// fixed ports from the local config can clash with other projects running locally
+		return 0, fmt.Errorf("error mapping service %s to port %d, the port may be used by another project, see nitric local ps", serviceName, preferredPort)
+	}
+
+	serverOpts := []server.ServerOption{
 		server.WithResourcesPlugin(lc.Resources),
 		server.WithApiPlugin(lc.Apis),
 		server.WithHttpPlugin(lc.Http),
@@ -110,8 +173,7 @@ func (lc *LocalCloud) AddService(serviceName string) (int, error) {
 		server.WithTopicsPlugin(lc.Topics),
 		server.WithStorageListenerPlugin(lc.Storage),
 		server.WithWebsocketListenerPlugin(lc.Websockets),
-		server.WithSqlPlugin(lc.Databases),
-		server.WithServiceAddress(fmt.Sprintf("0.0.0.0:%d", ports[0])),
+		server.WithServiceAddress(fmt.Sprintf("0.0.0.0:%d", port)),
 		server.WithSecretManagerPlugin(lc.Secrets),
 		server.WithStoragePlugin(lc.Storage),
 		server.WithKeyValuePlugin(lc.KeyValue),
@@ -119,7 +181,17 @@ func (lc *LocalCloud) AddService(serviceName string) (int, error) {
 		server.WithWebsocketPlugin(lc.Websockets),
 		server.WithQueuesPlugin(lc.Queues),
 		server.WithMinWorkers(0),
-		server.WithChildCommand([]string{}))
+		server.WithChildCommand([]string{}),
+	}
+
+	if lc.Databases != nil {
+		serverOpts = append(serverOpts, server.WithSqlPlugin(lc.Databases))
+	} else {
+		// databases are disabled, calls to the sql service return unimplemented errors
+		serverOpts = append(serverOpts, server.WithSqlPlugin(&sqlpb.UnimplementedSqlServer{}))
+	}
+
+	nitricRuntimeServer, _ := server.New(serverOpts...)
 
 	// Create a watcher that clears old resources when the service is restarted
 	_, err = resources.NewServiceResourceRefresher(serviceName, resources.NewServiceResourceRefresherArgs{
@@ -138,10 +210,11 @@ func (lc *LocalCloud) AddService(serviceName string) (int, error) {
 
 	go func() {
 		interceptor, streamInterceptor := grpcx.CreateServiceNameInterceptor(serviceName)
+		usageInterceptor, usageStreamInterceptor := lc.Usage.CreateInterceptors(serviceName)
 
 		srv := grpc.NewServer(
-			grpc.UnaryInterceptor(interceptor),
-			grpc.StreamInterceptor(streamInterceptor),
+			grpc.ChainUnaryInterceptor(interceptor, usageInterceptor),
+			grpc.ChainStreamInterceptor(streamInterceptor, usageStreamInterceptor),
 		)
 
 		// Enable reflection on the gRPC server for local testing
@@ -155,7 +228,7 @@ func (lc *LocalCloud) AddService(serviceName string) (int, error) {
 
 	lc.servers[serviceName] = nitricRuntimeServer
 
-	return ports[0], nil
+	return port, nil
 }
 
 type LocalCloudOptions struct {
@@ -163,6 +236,37 @@ type LocalCloudOptions struct {
 	LogWriter       io.Writer
 	LocalConfig     localconfig.LocalConfiguration
 	MigrationRunner sql.MigrationRunner
+	Flags           map[string]string
+	// Rate limits enforced by the local gateway, keyed by API name
+	ApiRateLimits map[string]gateway.RateLimit
+	// Names of APIs that require a valid API key
+	ApiKeyRequired []string
+	// Validates API keys for APIs that require them
+	ValidateApiKey func(apiName string, key string) bool
+	// Request and response transformations applied by the local gateway, keyed by API name
+	ApiMiddleware map[string][]gateway.Middleware
+	// Routes receiving signed webhooks, keyed by API name
+	ApiWebhooks map[string][]gateway.Webhook
+	// Validates requests to APIs against their OpenAPI documents, keyed by API name
+	ApiRequestValidators map[string]*gateway.RequestValidator
+	// Fixed ports of the HTTP proxies of services, keyed by the service's name in the local cloud
+	HttpProxyPorts map[string]int
+	// Records inbound triggers during the run so the session can be replayed
+	Recorder *session.Recorder
+	// Names the containers and volumes of the local cloud, defaults to the project name
+	Namespace string
+	// Directory for the files of local buckets, key/value stores and secrets, defaults to NITRIC_LOCAL_RUN_DIR
+	RunDir string
+	// Keeps the messages in queues between runs in RunDir, they're otherwise lost when the local cloud stops
+	Persist bool
+	// Skips starting the local postgres container, SQL databases are unavailable and Databases is nil
+	DisableDatabases bool
+	// Address of an existing postgres server to create SQL databases on, e.g. postgres:5432, rather than starting the local postgres container
+	DatabaseAddress string
+	// Starts the local SMTP server capturing email sent by services, Email is nil otherwise
+	CaptureEmail bool
+	// Containers started and checked for health before New returns, keyed by name
+	Dependencies map[string]dependencies.Dependency
 }
 
 func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
@@ -176,9 +280,20 @@ func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
 		return nil, err
 	}
 
+	runDir := env.NITRIC_LOCAL_RUN_DIR.String()
+	bucketsDir, dbDir, secretsDir := env.LOCAL_BUCKETS_DIR.String(), env.LOCAL_DB_DIR.String(), env.LOCAL_SECRETS_DIR.String()
+
+	if opts.RunDir != "" {
+		runDir = opts.RunDir
+		bucketsDir = filepath.Join(opts.RunDir, "buckets")
+		dbDir = filepath.Join(opts.RunDir, "kv")
+		secretsDir = filepath.Join(opts.RunDir, "secrets")
+	}
+
 	localStorage, err := storage.NewLocalStorageService(storage.StorageOptions{
-		AccessKey: "dummykey",
-		SecretKey: "dummysecret",
+		AccessKey:  "dummykey",
+		SecretKey:  "dummysecret",
+		BucketsDir: bucketsDir,
 	})
 	if err != nil {
 		return nil, err
@@ -189,7 +304,7 @@ func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
 	localSchedules := schedules.NewLocalSchedulesService()
 	localHttpProxy := http.NewLocalHttpProxyService()
 
-	localSecrets, err := secrets.NewSecretService()
+	localSecrets, err := secrets.NewSecretService(secretsDir)
 	if err != nil {
 		return nil, err
 	}
@@ -199,9 +314,18 @@ func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
 	}
 
 	localGateway, err := gateway.NewGateway(gateway.NewGatewayOpts{
-		TLSCredentials: opts.TLSCredentials,
-		LogWriter:      opts.LogWriter,
-		LocalConfig:    opts.LocalConfig,
+		TLSCredentials:    opts.TLSCredentials,
+		LogWriter:         opts.LogWriter,
+		LocalConfig:       opts.LocalConfig,
+		Flags:             opts.Flags,
+		RateLimits:        opts.ApiRateLimits,
+		ApiKeyRequired:    opts.ApiKeyRequired,
+		ValidateApiKey:    opts.ValidateApiKey,
+		Middleware:        opts.ApiMiddleware,
+		Webhooks:          opts.ApiWebhooks,
+		RequestValidators: opts.ApiRequestValidators,
+		HttpProxyPorts:    opts.HttpProxyPorts,
+		Recorder:          opts.Recorder,
 	})
 	if err != nil {
 		return nil, err
@@ -211,7 +335,7 @@ func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
 		Gateway: localGateway,
 	})
 
-	keyvalueService, err := keyvalue.NewBoltService()
+	keyvalueService, err := keyvalue.NewBoltService(dbDir)
 	if err != nil {
 		return nil, err
 	}
@@ -221,24 +345,66 @@ func New(projectName string, opts LocalCloudOptions) (*LocalCloud, error) {
 		return nil, err
 	}
 
-	localDatabaseService, err := sql.NewLocalSqlServer(projectName, localResources, opts.MigrationRunner)
+	queuesDir := ""
+
+	if opts.Persist {
+		queuesDir = filepath.Join(runDir, "queues")
+
+		if err := loadQueues(localQueueService, queuesDir); err != nil {
+			return nil, err
+		}
+	}
+
+	var localDatabaseService *sql.LocalSqlServer
+
+	switch {
+	case opts.DisableDatabases:
+	case opts.DatabaseAddress != "":
+		localDatabaseService, err = sql.NewExternalSqlServer(opts.DatabaseAddress, localResources, opts.MigrationRunner)
+	default:
+		localDatabaseService, err = sql.NewLocalSqlServer(lo.Ternary(opts.Namespace != "", opts.Namespace, projectName), localResources, opts.MigrationRunner)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	var localEmailService *email.LocalEmailService
+
+	if opts.CaptureEmail {
+		localEmailService, err = email.NewLocalEmailService()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var localDependencies *dependencies.LocalDependencies
+
+	if len(opts.Dependencies) > 0 {
+		localDependencies, err = dependencies.NewLocalDependencies(lo.Ternary(opts.Namespace != "", opts.Namespace, projectName), opts.Dependencies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &LocalCloud{
-		servers:    make(map[string]*server.NitricServer),
-		Apis:       localApis,
-		Http:       localHttpProxy,
-		Resources:  localResources,
-		Schedules:  localSchedules,
-		Storage:    localStorage,
-		Topics:     localTopics,
-		Websockets: localWebsockets,
-		Gateway:    localGateway,
-		Secrets:    localSecrets,
-		KeyValue:   keyvalueService,
-		Queues:     localQueueService,
-		Databases:  localDatabaseService,
+		servers:      make(map[string]*server.NitricServer),
+		Apis:         localApis,
+		Http:         localHttpProxy,
+		Resources:    localResources,
+		Schedules:    localSchedules,
+		Storage:      localStorage,
+		Topics:       localTopics,
+		Websockets:   localWebsockets,
+		Gateway:      localGateway,
+		Secrets:      localSecrets,
+		KeyValue:     keyvalueService,
+		Queues:       localQueueService,
+		Databases:    localDatabaseService,
+		Usage:        usage.NewLocalUsageService(),
+		Email:        localEmailService,
+		Dependencies: localDependencies,
+		runDir:       runDir,
+		queuesDir:    queuesDir,
 	}, nil
 }
@@ -0,0 +1,345 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dependencies
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-connections/nat"
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/localenv"
+	"github.com/nitrictech/cli/pkg/netx"
+	"github.com/nitrictech/nitric/core/pkg/logger"
+)
+
+// startTimeout - how long a dependency has to become healthy before the local cloud fails to start
+const startTimeout = 2 * time.Minute
+
+// Dependency - a container started alongside the local cloud, e.g. a database or cache used by services
+type Dependency struct {
+	Image string
+	// Ports the container listens on, each is published on a free port of the host
+	Ports []int
+	// Environment variables of the container
+	Env map[string]string
+	// Overrides the command of the image
+	Command []string
+	// Command run in the container to check it's healthy, the dependency is ready once a port accepts connections when empty
+	Healthcheck []string
+	// Path in the container persisted to a volume between runs
+	Volume string
+	// Environment variables passed to services, values are templates that can use .Host, .Port (the first port) and .Ports (keyed by container port)
+	ServiceEnv map[string]string
+}
+
+// TemplateData - the values available to the service environment variable templates of a dependency
+type TemplateData struct {
+	Host  string
+	Port  int
+	Ports map[int]int
+}
+
+// Validate - checks the dependency can be started and its service environment variables rendered
+func (d Dependency) Validate() error {
+	if d.Image == "" {
+		return fmt.Errorf("an image is required")
+	}
+
+	if len(d.Ports) == 0 && len(d.ServiceEnv) > 0 {
+		return fmt.Errorf("a port is required to pass the dependency's address to services")
+	}
+
+	for _, port := range d.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d", port)
+		}
+	}
+
+	_, err := d.renderEnv(TemplateData{
+		Host:  "localhost",
+		Port:  d.primaryPort(),
+		Ports: lo.SliceToMap(d.Ports, func(port int) (int, int) { return port, port }),
+	})
+
+	return err
+}
+
+// primaryPort - returns the first port of the dependency, used as .Port in templates, or 0 when it has none
+func (d Dependency) primaryPort() int {
+	if len(d.Ports) == 0 {
+		return 0
+	}
+
+	return d.Ports[0]
+}
+
+func (d Dependency) renderEnv(data TemplateData) (map[string]string, error) {
+	env := map[string]string{}
+
+	for name, value := range d.ServiceEnv {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for service env %s: %w", name, err)
+		}
+
+		var rendered bytes.Buffer
+
+		if err := tmpl.Execute(&rendered, data); err != nil {
+			return nil, fmt.Errorf("invalid template for service env %s: %w", name, err)
+		}
+
+		env[name] = rendered.String()
+	}
+
+	return env, nil
+}
+
+type runningDependency struct {
+	dependency  Dependency
+	containerId string
+	// host ports keyed by container port
+	ports map[int]int
+}
+
+// LocalDependencies - the dependency containers of a local cloud
+type LocalDependencies struct {
+	dockerClient *docker.Docker
+	running      map[string]*runningDependency
+}
+
+// Env - returns the environment variables passed to services for all dependencies, host is the address services reach the host on
+func (l *LocalDependencies) Env(host string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for name, dep := range l.running {
+		depEnv, err := dep.dependency.renderEnv(TemplateData{
+			Host:  host,
+			Port:  dep.ports[dep.dependency.primaryPort()],
+			Ports: dep.ports,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", name, err)
+		}
+
+		env = lo.Assign(env, depEnv)
+	}
+
+	return env, nil
+}
+
+// Ports - returns the host ports of each dependency keyed by container port
+func (l *LocalDependencies) Ports() map[string]map[int]int {
+	return lo.MapValues(l.running, func(dep *runningDependency, _ string) map[int]int {
+		return dep.ports
+	})
+}
+
+// Stop - stops the dependency containers, they're removed once stopped
+func (l *LocalDependencies) Stop() error {
+	var errs []error
+
+	for name, dep := range l.running {
+		err := l.dockerClient.ContainerStop(context.Background(), dep.containerId, container.StopOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dependency %s: %w", name, err))
+		}
+	}
+
+	l.running = map[string]*runningDependency{}
+
+	return errors.Join(errs...)
+}
+
+func (l *LocalDependencies) start(namespace string, name string, dep Dependency) error {
+	err := l.dockerClient.ImagePull(dep.Image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+
+	running := &runningDependency{
+		dependency: dep,
+		ports:      map[int]int{},
+	}
+
+	portBindings := nat.PortMap{}
+	exposedPorts := nat.PortSet{}
+
+	for _, port := range dep.Ports {
+		lis, err := netx.GetNextListener(netx.MinPort(port))
+		if err != nil {
+			return err
+		}
+
+		running.ports[port] = lis.Addr().(*net.TCPAddr).Port
+
+		_ = lis.Close()
+
+		containerPort := nat.Port(fmt.Sprintf("%d/tcp", port))
+		exposedPorts[containerPort] = struct{}{}
+		portBindings[containerPort] = []nat.PortBinding{{HostPort: strconv.Itoa(running.ports[port])}}
+	}
+
+	hostConfig := &container.HostConfig{
+		AutoRemove:   true,
+		PortBindings: portBindings,
+	}
+
+	if dep.Volume != "" {
+		vol, err := l.dockerClient.VolumeCreate(context.Background(), volume.CreateOptions{
+			Driver: "local",
+			Name:   fmt.Sprintf("%s-dep-%s", namespace, name),
+		})
+		if err != nil {
+			return err
+		}
+
+		hostConfig.Mounts = []mount.Mount{
+			{
+				Type:   mount.TypeVolume,
+				Source: vol.Name,
+				Target: dep.Volume,
+			},
+		}
+	}
+
+	containerConfig := &container.Config{
+		Image:        dep.Image,
+		Env:          lo.MapToSlice(dep.Env, func(k, v string) string { return k + "=" + v }),
+		ExposedPorts: exposedPorts,
+		Labels:       map[string]string{localenv.NamespaceLabel: namespace},
+	}
+
+	if len(dep.Command) > 0 {
+		containerConfig.Cmd = dep.Command
+	}
+
+	if len(dep.Healthcheck) > 0 {
+		containerConfig.Healthcheck = &container.HealthConfig{
+			Test:     append([]string{"CMD"}, dep.Healthcheck...),
+			Interval: time.Second,
+			Timeout:  5 * time.Second,
+			Retries:  int(startTimeout / time.Second),
+		}
+	}
+
+	containerName := fmt.Sprintf("nitric-%s-dep-%s", namespace, name)
+
+	// remove a container left behind by a local cloud that didn't stop cleanly
+	_ = l.dockerClient.ContainerRemove(context.Background(), containerName, container.RemoveOptions{Force: true})
+
+	running.containerId, err = l.dockerClient.ContainerCreate(containerConfig, hostConfig, nil, containerName)
+	if err != nil {
+		return err
+	}
+
+	err = l.dockerClient.ContainerStart(context.Background(), running.containerId, container.StartOptions{})
+	if err != nil {
+		return err
+	}
+
+	l.running[name] = running
+
+	return l.waitUntilHealthy(running)
+}
+
+func (l *LocalDependencies) waitUntilHealthy(dep *runningDependency) error {
+	deadline := time.Now().Add(startTimeout)
+
+	for time.Now().Before(deadline) {
+		inspect, err := l.dockerClient.ContainerInspect(context.Background(), dep.containerId)
+		if err != nil {
+			return err
+		}
+
+		if !inspect.State.Running {
+			return fmt.Errorf("container exited with code %d before becoming healthy, see docker logs %s", inspect.State.ExitCode, dep.containerId[:12])
+		}
+
+		if len(dep.dependency.Healthcheck) > 0 {
+			if inspect.State.Health != nil && inspect.State.Health.Status == types.Healthy {
+				return nil
+			}
+		} else if portOpen(dep.ports[dep.dependency.primaryPort()]) {
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return fmt.Errorf("not healthy after %s", startTimeout)
+}
+
+func portOpen(port int) bool {
+	// without ports there's nothing to wait for once the container is running
+	if port == 0 {
+		return true
+	}
+
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), time.Second)
+	if err != nil {
+		return false
+	}
+
+	_ = conn.Close()
+
+	return true
+}
+
+// NewLocalDependencies - starts the dependency containers and waits until they're healthy, namespace names the containers and volumes, see localenv.Environment
+func NewLocalDependencies(namespace string, deps map[string]Dependency) (*LocalDependencies, error) {
+	dockerClient, err := docker.New()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &LocalDependencies{
+		dockerClient: dockerClient,
+		running:      map[string]*runningDependency{},
+	}
+
+	names := lo.Keys(deps)
+	slices.Sort(names)
+
+	for _, name := range names {
+		logger.Debugf("starting dependency %s", name)
+
+		if err := l.start(namespace, name, deps[name]); err != nil {
+			stopErr := l.Stop()
+			if stopErr != nil {
+				logger.Errorf("Error stopping dependencies: %s", stopErr.Error())
+			}
+
+			return nil, fmt.Errorf("unable to start dependency %s: %w", name, err)
+		}
+	}
+
+	return l, nil
+}
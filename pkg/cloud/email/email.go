@@ -0,0 +1,413 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/netx"
+)
+
+// the number of captured messages kept, older messages are dropped
+const maxMessages = 500
+
+// the largest message accepted, matching the sending limit of SES
+const maxMessageSize = 10 * 1024 * 1024
+
+// DefaultPort - the port the local SMTP server prefers, the default of mailhog and mailpit so existing configuration works unchanged
+const DefaultPort = 1025
+
+// Message - an email sent by a service during a local run, captured rather than delivered
+type Message struct {
+	Id string
+	// Envelope sender and recipients, the recipients include bcc addresses that aren't in the headers
+	From       string
+	Recipients []string
+	Subject    string
+	// Address headers as they were sent, e.g. Jane <jane@example.com>
+	Headers  map[string]string
+	Text     string
+	Html     string
+	Raw      string
+	Received time.Time
+}
+
+type State = []Message
+
+// LocalEmailService - an SMTP server capturing email sent by services, so nothing is delivered during local runs
+type LocalEmailService struct {
+	lock     sync.RWMutex
+	messages []Message
+
+	listener net.Listener
+	bus      EventBus.Bus
+}
+
+const localEmailTopic = "local_email"
+
+func (s *LocalEmailService) SubscribeToState(subscription func(State)) {
+	// ignore the error, it's only returned if the fn param isn't a function
+	_ = s.bus.Subscribe(localEmailTopic, subscription)
+}
+
+// Port - the port services send email to using SMTP
+func (s *LocalEmailService) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// GetAll - returns the captured messages, most recent first
+func (s *LocalEmailService) GetAll() State {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	messages := make(State, len(s.messages))
+	for i, msg := range s.messages {
+		messages[len(s.messages)-1-i] = msg
+	}
+
+	return messages
+}
+
+// Get - returns a captured message by its id
+func (s *LocalEmailService) Get(id string) (Message, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, msg := range s.messages {
+		if msg.Id == id {
+			return msg, true
+		}
+	}
+
+	return Message{}, false
+}
+
+// Clear - removes all captured messages
+func (s *LocalEmailService) Clear() {
+	s.lock.Lock()
+	s.messages = []Message{}
+	s.lock.Unlock()
+
+	s.bus.Publish(localEmailTopic, s.GetAll())
+}
+
+func (s *LocalEmailService) capture(msg Message) {
+	s.lock.Lock()
+
+	s.messages = append(s.messages, msg)
+	if len(s.messages) > maxMessages {
+		s.messages = s.messages[len(s.messages)-maxMessages:]
+	}
+
+	s.lock.Unlock()
+
+	s.bus.Publish(localEmailTopic, s.GetAll())
+}
+
+func (s *LocalEmailService) Stop() error {
+	return s.listener.Close()
+}
+
+func (s *LocalEmailService) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// handle - speaks enough SMTP for SDK and library clients to send mail, authentication is accepted with any credentials
+func (s *LocalEmailService) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+
+	from := ""
+	recipients := []string{}
+
+	reply := func(format string, args ...any) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 localhost nitric local smtp ready") {
+		return
+	}
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply("250 localhost")
+		case "EHLO":
+			reply("250-localhost\r\n250-8BITMIME\r\n250-SIZE %d\r\n250 AUTH PLAIN LOGIN", maxMessageSize)
+		case "AUTH":
+			if !authenticate(tp, arg) {
+				return
+			}
+		case "MAIL":
+			from = addressArg(arg, "FROM:")
+			recipients = []string{}
+
+			reply("250 2.1.0 ok")
+		case "RCPT":
+			if from == "" {
+				reply("503 5.5.1 send MAIL first")
+				continue
+			}
+
+			recipients = append(recipients, addressArg(arg, "TO:"))
+
+			reply("250 2.1.5 ok")
+		case "DATA":
+			if len(recipients) == 0 {
+				reply("503 5.5.1 send RCPT first")
+				continue
+			}
+
+			if !reply("354 end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+
+			raw, err := io.ReadAll(io.LimitReader(tp.DotReader(), maxMessageSize+1))
+			if err != nil {
+				return
+			}
+
+			if len(raw) > maxMessageSize {
+				reply("552 5.3.4 message exceeds the %d byte limit", maxMessageSize)
+				continue
+			}
+
+			msg := parseMessage(raw)
+			msg.From = from
+			msg.Recipients = recipients
+
+			s.capture(msg)
+
+			from = ""
+			recipients = []string{}
+
+			reply("250 2.0.0 ok: queued as %s", msg.Id)
+		case "RSET":
+			from = ""
+			recipients = []string{}
+
+			reply("250 2.0.0 ok")
+		case "NOOP":
+			reply("250 2.0.0 ok")
+		case "QUIT":
+			reply("221 2.0.0 bye")
+			return
+		default:
+			reply("502 5.5.2 command not recognized")
+		}
+	}
+}
+
+// authenticate - completes the PLAIN and LOGIN exchanges, the credentials aren't checked
+func authenticate(tp *textproto.Conn, arg string) bool {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	var prompts []string
+
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		prompts = []string{""}
+	case "LOGIN":
+		// base64 encoded Username: and Password: prompts
+		prompts = []string{"VXNlcm5hbWU6", "UGFzc3dvcmQ6"}
+	default:
+		return tp.PrintfLine("504 5.5.4 unrecognized authentication type") == nil
+	}
+
+	// the credentials of PLAIN, or the username of LOGIN, can be sent with the command
+	if initial != "" {
+		prompts = prompts[1:]
+	}
+
+	for _, prompt := range prompts {
+		if err := tp.PrintfLine("334 %s", prompt); err != nil {
+			return false
+		}
+
+		if _, err := tp.ReadLine(); err != nil {
+			return false
+		}
+	}
+
+	return tp.PrintfLine("235 2.7.0 authentication successful") == nil
+}
+
+// addressArg - returns the address of a MAIL FROM:<address> or RCPT TO:<address> argument, ignoring parameters such as SIZE
+func addressArg(arg string, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+
+	address, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+
+	return strings.Trim(address, "<>")
+}
+
+var headerDecoder = &mime.WordDecoder{}
+
+// parseMessage - extracts the headers and the text and html bodies of a message, messages that can't be parsed are kept raw
+func parseMessage(raw []byte) Message {
+	msg := Message{
+		Id:       uuid.NewString(),
+		Headers:  map[string]string{},
+		Raw:      string(raw),
+		Received: time.Now(),
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		msg.Text = string(raw)
+		return msg
+	}
+
+	for _, name := range []string{"From", "To", "Cc", "Reply-To", "Date", "Message-Id"} {
+		if value := parsed.Header.Get(name); value != "" {
+			decoded, err := headerDecoder.DecodeHeader(value)
+			msg.Headers[name] = lo.Ternary(err == nil, decoded, value)
+		}
+	}
+
+	msg.Subject, err = headerDecoder.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		msg.Subject = parsed.Header.Get("Subject")
+	}
+
+	readBody(textproto.MIMEHeader(parsed.Header), parsed.Body, &msg)
+
+	return msg
+}
+
+// readBody - sets the first text and html parts of a body, searching nested multipart bodies
+func readBody(header textproto.MIMEHeader, body io.Reader, msg *Message) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				return
+			}
+
+			readBody(part.Header, part, msg)
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &lineJoiner{r: bufio.NewReader(body)})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	// attachments aren't displayed
+	if strings.HasPrefix(header.Get("Content-Disposition"), "attachment") {
+		return
+	}
+
+	contents, err := io.ReadAll(body)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return
+	}
+
+	switch {
+	case mediaType == "text/plain" && msg.Text == "":
+		msg.Text = string(contents)
+	case mediaType == "text/html" && msg.Html == "":
+		msg.Html = string(contents)
+	}
+}
+
+// lineJoiner - removes the line breaks of base64 encoded bodies, which the base64 decoder doesn't accept
+type lineJoiner struct {
+	r *bufio.Reader
+}
+
+func (l *lineJoiner) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return n, err
+		}
+
+		if b != '\r' && b != '\n' {
+			p[n] = b
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// NewLocalEmailService - starts the SMTP server, listening on the default port when it's free
+func NewLocalEmailService() (*LocalEmailService, error) {
+	listener, err := netx.GetNextListener(netx.MinPort(DefaultPort))
+	if err != nil {
+		return nil, fmt.Errorf("unable to start the local smtp server: %w", err)
+	}
+
+	service := &LocalEmailService{
+		messages: []Message{},
+		listener: listener,
+		bus:      EventBus.New(),
+	}
+
+	go service.serve()
+
+	return service, nil
+}
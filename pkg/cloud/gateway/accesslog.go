@@ -0,0 +1,210 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+
+	"github.com/nitrictech/cli/pkg/session"
+)
+
+// RequestIdHeader - the header used to correlate a request across the gateway access log and services,
+// an existing request id is kept so ids from upstream proxies and clients carry through
+const RequestIdHeader = "X-Request-Id"
+
+const (
+	AccessLogFormatCombined = "combined"
+	AccessLogFormatJson     = "json"
+	AccessLogFormatOff      = "off"
+)
+
+type accessLogger struct {
+	format string
+	writer io.Writer
+	// the access log file opened by the gateway, closed when the gateway stops
+	file io.Closer
+	lock sync.Mutex
+}
+
+// accessLogEntry - fields match those commonly available in cloud API gateway access logs
+type accessLogEntry struct {
+	RequestId      string `json:"requestId"`
+	TraceId        string `json:"traceId"`
+	Api            string `json:"api"`
+	Ip             string `json:"ip"`
+	RequestTime    string `json:"requestTime"`
+	HttpMethod     string `json:"httpMethod"`
+	Path           string `json:"path"`
+	Protocol       string `json:"protocol"`
+	Status         int    `json:"status"`
+	ResponseLength int    `json:"responseLength"`
+	LatencyMs      int64  `json:"responseLatency"`
+	UserAgent      string `json:"userAgent"`
+	Referer        string `json:"referer,omitempty"`
+}
+
+func newAccessLogger(format string, writer io.Writer) (*accessLogger, error) {
+	if format == "" {
+		format = AccessLogFormatCombined
+	}
+
+	switch format {
+	case AccessLogFormatCombined, AccessLogFormatJson, AccessLogFormatOff:
+	default:
+		return nil, fmt.Errorf("unsupported access log format %s, expected one of %s, %s or %s", format, AccessLogFormatCombined, AccessLogFormatJson, AccessLogFormatOff)
+	}
+
+	return &accessLogger{
+		format: format,
+		writer: writer,
+	}, nil
+}
+
+func (l *accessLogger) log(name string, ctx *fasthttp.RequestCtx, requestId string, traceId string, start time.Time) {
+	if l == nil || l.format == AccessLogFormatOff {
+		return
+	}
+
+	entry := accessLogEntry{
+		RequestId:      requestId,
+		TraceId:        traceId,
+		Api:            name,
+		Ip:             ctx.RemoteIP().String(),
+		RequestTime:    start.Format("02/Jan/2006:15:04:05 -0700"),
+		HttpMethod:     string(ctx.Method()),
+		Path:           string(ctx.RequestURI()),
+		Protocol:       string(ctx.Request.Header.Protocol()),
+		Status:         ctx.Response.StatusCode(),
+		ResponseLength: len(ctx.Response.Body()),
+		LatencyMs:      time.Since(start).Milliseconds(),
+		UserAgent:      string(ctx.UserAgent()),
+		Referer:        string(ctx.Referer()),
+	}
+
+	var line string
+
+	if l.format == AccessLogFormatJson {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+
+		line = string(data)
+	} else {
+		line = fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\" %s %s", entry.Ip, entry.RequestTime, entry.HttpMethod, entry.Path, entry.Protocol, entry.Status, entry.ResponseLength, orDash(entry.Referer), orDash(entry.UserAgent), entry.RequestId, entry.TraceId)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.writer == nil {
+		return
+	}
+
+	fmt.Fprintln(l.writer, line)
+}
+
+// close - closes the access log file opened by the gateway, requests handled while servers shut down aren't logged
+func (l *accessLogger) close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	err := l.file.Close()
+	l.writer = nil
+	l.file = nil
+
+	return err
+}
+
+// orDash - empty combined log fields are written as -
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+// withRequestContext - assigns a request id and trace context to each request before it's forwarded to services,
+// returning the request id in the response and recording the request in the access log and session recording
+func (s *LocalGatewayService) withRequestContext(name string, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+
+		requestId := string(ctx.Request.Header.Peek(RequestIdHeader))
+		if requestId == "" {
+			requestId = uuid.NewString()
+			ctx.Request.Header.Set(RequestIdHeader, requestId)
+		}
+
+		s.recordRequest(name, ctx)
+
+		traceparent, traceId := nextTraceparent(string(ctx.Request.Header.Peek(TraceparentHeader)))
+		ctx.Request.Header.Set(TraceparentHeader, traceparent)
+
+		handler(ctx)
+
+		ctx.Response.Header.Set(RequestIdHeader, requestId)
+
+		s.accessLog.log(name, ctx, requestId, traceId, start)
+	}
+}
+
+// recordRequest - records an inbound request for session replay, request ids and trace context are left out so replayed requests are assigned new ones
+func (s *LocalGatewayService) recordRequest(name string, ctx *fasthttp.RequestCtx) {
+	if s.recorder == nil {
+		return
+	}
+
+	headers := map[string][]string{}
+
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		header := string(key)
+		if header == RequestIdHeader || header == TraceparentHeader || header == fasthttp.HeaderHost || header == fasthttp.HeaderContentLength {
+			return
+		}
+
+		headers[header] = append(headers[header], string(value))
+	})
+
+	trigger := session.Trigger{
+		Type:    session.TriggerType_Api,
+		Name:    name,
+		Method:  string(ctx.Method()),
+		Path:    string(ctx.Request.URI().RequestURI()),
+		Headers: headers,
+		Body:    append([]byte(nil), ctx.Request.Body()...),
+	}
+
+	if name == "http" {
+		trigger.Type = session.TriggerType_Http
+		trigger.Name = ""
+	}
+
+	s.recorder.Record(trigger)
+}
@@ -24,6 +24,8 @@ import (
 	"log"
 	"net"
 	"net/url"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -35,8 +37,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 	"github.com/valyala/fasthttp"
+	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/nitrictech/cli/pkg/apikeys"
 	"github.com/nitrictech/cli/pkg/cloud/apis"
 	"github.com/nitrictech/cli/pkg/cloud/http"
 	"github.com/nitrictech/cli/pkg/cloud/schedules"
@@ -44,6 +48,7 @@ import (
 	"github.com/nitrictech/cli/pkg/cloud/websockets"
 	"github.com/nitrictech/cli/pkg/netx"
 	"github.com/nitrictech/cli/pkg/project/localconfig"
+	"github.com/nitrictech/cli/pkg/session"
 	"github.com/nitrictech/cli/pkg/system"
 	"github.com/nitrictech/cli/pkg/view/tui"
 
@@ -64,8 +69,9 @@ type apiServer struct {
 }
 
 type socketServer struct {
-	lis net.Listener
-	srv *fasthttp.Server
+	lis            net.Listener
+	srv            *fasthttp.Server
+	tlsCredentials *TLSCredentials
 
 	workerCount int
 }
@@ -95,6 +101,27 @@ type LocalGatewayService struct {
 
 	localConfig localconfig.LocalConfiguration
 
+	flags map[string]string
+
+	rateLimiters map[string]*rate.Limiter
+
+	apiKeyRequired []string
+	validateApiKey func(apiName string, key string) bool
+
+	middleware map[string]middlewareChain
+
+	webhooks map[string][]Webhook
+
+	requestValidators map[string]*RequestValidator
+
+	httpProxyPorts map[string]int
+	// the services of http workers, keyed by the host address of the worker
+	httpWorkerServices map[string]string
+
+	accessLog *accessLogger
+
+	recorder *session.Recorder
+
 	logWriter io.Writer
 
 	ApiTlsCredentials *TLSCredentials
@@ -163,6 +190,15 @@ func (s *LocalGatewayService) GetHttpWorkerAddresses() map[string]string {
 	return addresses
 }
 
+// GetWebsocketScheme - Returns the scheme clients connect to websockets with, wss when websockets are served over TLS
+func (s *LocalGatewayService) GetWebsocketScheme() string {
+	if s.ApiTlsCredentials != nil {
+		return "wss"
+	}
+
+	return "ws"
+}
+
 func (s *LocalGatewayService) GetWebsocketAddresses() map[string]string {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -206,6 +242,44 @@ func (s *LocalGatewayService) handleApiHttpRequest(apiName string) fasthttp.Requ
 			return
 		}
 
+		if slices.Contains(s.apiKeyRequired, apiName) {
+			key := string(ctx.Request.Header.Peek(apikeys.Header))
+
+			if key == "" || s.validateApiKey == nil || !s.validateApiKey(apiName, key) {
+				ctx.Error("Forbidden", fasthttp.StatusForbidden)
+				return
+			}
+		}
+
+		if err := checkWebhook(s.webhooks[apiName], &ctx.Request); err != nil {
+			ctx.Error(fmt.Sprintf("Invalid webhook signature: %v", err), fasthttp.StatusUnauthorized)
+			return
+		}
+
+		// enforce the API's rate limit, matching the throttling behavior of deployed API gateways
+		if limiter, ok := s.rateLimiters[apiName]; ok && !limiter.Allow() {
+			ctx.Response.Header.Set("Retry-After", "1")
+			ctx.Error("Too Many Requests", fasthttp.StatusTooManyRequests)
+
+			return
+		}
+
+		// requests are validated as they were sent, before middleware transforms them
+		if validator, ok := s.requestValidators[apiName]; ok {
+			if issues := validator.validate(ctx); len(issues) > 0 {
+				writeValidationError(ctx, issues)
+				return
+			}
+		}
+
+		middleware, err := s.middleware[apiName].applies(&ctx.Request)
+		if err != nil {
+			ctx.Error(fmt.Sprintf("Error applying middleware: %v", err), 500)
+			return
+		}
+
+		middleware.transformRequest(&ctx.Request)
+
 		headerMap := base_http.HttpHeadersToMap(&ctx.Request.Header)
 
 		headers := map[string]*apispb.HeaderValue{}
@@ -227,7 +301,7 @@ func (s *LocalGatewayService) handleApiHttpRequest(apiName string) fasthttp.Requ
 
 		path := string(ctx.URI().Path())
 
-		_, err := url.Parse(path)
+		_, err = url.Parse(path)
 		if err != nil {
 			ctx.Error(fmt.Sprintf("Bad Request: %v", err), 400)
 			return
@@ -265,6 +339,8 @@ func (s *LocalGatewayService) handleApiHttpRequest(apiName string) fasthttp.Requ
 			ctx.Response.SetStatusCode(int(http.Status))
 			ctx.Response.SetBody(resp.GetHttpResponse().GetBody())
 
+			middleware.transformResponse(&ctx.Response)
+
 			// publish ctx for history
 			s.apisPlugin.PublishActionState(apis.ApiRequestState{
 				Api:      apiName,
@@ -420,6 +496,12 @@ func (s *LocalGatewayService) handleTopicRequest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	s.recorder.Record(session.Trigger{
+		Type: session.TriggerType_Topic,
+		Name: topicName,
+		Body: append([]byte(nil), ctx.Request.Body()...),
+	})
+
 	_, err = s.topicsPlugin.Publish(ctx, &topicspb.TopicPublishRequest{
 		TopicName: topicName,
 		Message: &topicspb.TopicMessage{
@@ -456,6 +538,29 @@ func (s *LocalGatewayService) handleSchedulesTrigger(ctx *fasthttp.RequestCtx) {
 	ctx.SuccessString("text/plain", "Successfully triggered schedule")
 }
 
+// handleFlagsRequest - returns all project feature flags, or a single flag when a name is provided
+func (s *LocalGatewayService) handleFlagsRequest(ctx *fasthttp.RequestCtx) {
+	var body any = s.flags
+
+	if flagName, ok := ctx.UserValue("name").(string); ok {
+		value, ok := s.flags[flagName]
+		if !ok {
+			ctx.Error(fmt.Sprintf("Flag %s not found", flagName), 404)
+			return
+		}
+
+		body = map[string]string{flagName: value}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Error serializing flags: %v", err), 500)
+		return
+	}
+
+	ctx.Success("application/json", data)
+}
+
 func (s *LocalGatewayService) refreshApis(apiState apis.State) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -486,6 +591,7 @@ func (s *LocalGatewayService) refreshHttpWorkers(state http.State) {
 	defer s.lock.Unlock()
 
 	s.httpWorkers = make([]string, 0)
+	s.httpWorkerServices = lo.MapValues(state, func(proxy *http.HttpProxyService, _ string) string { return proxy.ServiceName })
 
 	uniqHttpWorkers := lo.Reduce(lo.Keys(state), func(agg []string, host string, idx int) []string {
 		if !lo.Contains(agg, host) {
@@ -550,7 +656,7 @@ func (s *LocalGatewayService) createApiServers() error {
 			IdleTimeout:     time.Second * 1,
 			CloseOnShutdown: true,
 			ReadBufferSize:  8192,
-			Handler:         s.handleApiHttpRequest(apiName),
+			Handler:         s.withRequestContext(apiName, s.handleApiHttpRequest(apiName)),
 			Logger:          log.New(s.logWriter, fmt.Sprintf("%s: ", lis.Addr().String()), 0),
 		}
 
@@ -592,7 +698,8 @@ func getListener(mapping map[string]localconfig.LocalResourceConfiguration, name
 		if config.Port != 0 {
 			list, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
 			if err != nil {
-				return nil, fmt.Errorf("error mapping %s to port %d, %s", name, config.Port, err.Error())
+				// fixed ports from the local config can clash with other projects running locally
+				return nil, fmt.Errorf("error mapping %s to port %d, the port may be used by another project, see nitric local ps: %w", name, config.Port, err)
 			}
 
 			return list, nil
@@ -624,13 +731,20 @@ func (s *LocalGatewayService) createWebsocketServers() error {
 			}
 
 			srv := &socketServer{
-				lis:         lis,
-				srv:         fhttp,
-				workerCount: 0,
+				lis:            lis,
+				srv:            fhttp,
+				tlsCredentials: s.ApiTlsCredentials,
+				workerCount:    0,
 			}
 
 			go func(srv *socketServer) {
-				err := srv.srv.Serve(srv.lis)
+				var err error
+				if srv.tlsCredentials != nil {
+					err = srv.srv.ServeTLS(srv.lis, srv.tlsCredentials.CertFile, srv.tlsCredentials.KeyFile)
+				} else {
+					err = srv.srv.Serve(srv.lis)
+				}
+
 				if err != nil {
 					fmt.Println(err)
 				}
@@ -654,21 +768,37 @@ func (s *LocalGatewayService) createWebsocketServers() error {
 	return nil
 }
 
-func (s *LocalGatewayService) createHttpServers() error {
-	// Expand servers to account for apis
-	lis, err := netx.GetNextListener()
-	if err != nil {
-		return err
+// httpProxyListener - returns a listener for the HTTP proxy of a worker, on the port fixed for its service if there is one
+func (s *LocalGatewayService) httpProxyListener(worker string) (net.Listener, error) {
+	serviceName := s.httpWorkerServices[worker]
+
+	if port, ok := s.httpProxyPorts[serviceName]; ok && port != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			// fixed ports from the local config can clash with other projects running locally
+			return nil, fmt.Errorf("error mapping the http proxy of %s to port %d, the port may be used by another project, see nitric local ps: %w", serviceName, port, err)
+		}
+
+		return lis, nil
 	}
 
-	// create an api server for every API worker
+	return netx.GetNextListener()
+}
+
+func (s *LocalGatewayService) createHttpServers() error {
+	// create an http proxy server for every HTTP worker
 	for len(s.httpServers) < len(s.httpWorkers) {
+		lis, err := s.httpProxyListener(s.httpWorkers[len(s.httpServers)])
+		if err != nil {
+			return err
+		}
+
 		fhttp := &fasthttp.Server{
 			ReadTimeout:     time.Second * 1,
 			IdleTimeout:     time.Second * 1,
 			CloseOnShutdown: true,
 			ReadBufferSize:  8192,
-			Handler:         s.handleHttpProxyRequest(len(s.httpServers)),
+			Handler:         s.withRequestContext("http", s.handleHttpProxyRequest(len(s.httpServers))),
 			Logger:          log.New(s.logWriter, fmt.Sprintf("%s: ", lis.Addr().String()), 0),
 		}
 
@@ -704,6 +834,7 @@ const nameParam = "{name}"
 const (
 	topicPath    = "/topics/" + nameParam
 	schedulePath = "/schedules/" + nameParam
+	flagsPath    = "/flags"
 )
 
 func (s *LocalGatewayService) GetTopicTriggerUrl(topicName string) string {
@@ -717,6 +848,11 @@ func (s *LocalGatewayService) GetScheduleManualTriggerUrl(scheduleName string) s
 	return endpoint
 }
 
+func (s *LocalGatewayService) GetFlagsUrl() string {
+	endpoint, _ := url.JoinPath("http://"+s.GetTriggerAddress(), flagsPath)
+	return endpoint
+}
+
 func (s *LocalGatewayService) Start(opts *gateway.GatewayStartOpts) error {
 	var err error
 	// Assign the pool and block
@@ -728,6 +864,9 @@ func (s *LocalGatewayService) Start(opts *gateway.GatewayStartOpts) error {
 	// Publish to a topic
 	r.POST(topicPath, s.handleTopicRequest)
 	r.POST(schedulePath, s.handleSchedulesTrigger)
+	// Query project feature flags
+	r.GET(flagsPath, s.handleFlagsRequest)
+	r.GET(flagsPath+"/"+nameParam, s.handleFlagsRequest)
 
 	s.serviceServer = &fasthttp.Server{
 		ReadTimeout:     time.Second * 1,
@@ -800,26 +939,106 @@ func (s *LocalGatewayService) Stop() error {
 		shutdownServer(ss.srv)
 	}
 
+	var err error
+
 	if s.serviceServer != nil {
-		return s.serviceServer.Shutdown()
+		err = s.serviceServer.Shutdown()
 	}
 
-	return nil
+	if closeErr := s.accessLog.close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("unable to close access log file: %w", closeErr)
+	}
+
+	return err
+}
+
+// RateLimit - the rate of requests allowed to an API
+type RateLimit struct {
+	RequestsPerSecond float64
+	// Maximum number of requests allowed in a burst above the sustained rate
+	Burst int
 }
 
 type NewGatewayOpts struct {
 	TLSCredentials *TLSCredentials
 	LogWriter      io.Writer
 	LocalConfig    localconfig.LocalConfiguration
+	Flags          map[string]string
+	// Rate limits enforced for APIs, keyed by API name
+	RateLimits map[string]RateLimit
+	// Names of APIs that require a valid API key
+	ApiKeyRequired []string
+	// Validates API keys for APIs that require them, all keys are rejected if not set
+	ValidateApiKey func(apiName string, key string) bool
+	// Request and response transformations applied to APIs, keyed by API name
+	Middleware map[string][]Middleware
+	// Routes receiving signed webhooks, keyed by API name
+	Webhooks map[string][]Webhook
+	// Validates requests against the OpenAPI documents of APIs, keyed by API name
+	RequestValidators map[string]*RequestValidator
+	// Fixed ports of the HTTP proxies of services, keyed by service name, other proxies listen on free ports
+	HttpProxyPorts map[string]int
+	// Records inbound requests and topic events so the session can be replayed, nothing is recorded if nil
+	Recorder *session.Recorder
 }
 
 // Create new HTTP gateway
 // XXX: No External Args for function atm (currently the plugin loader does not pass any argument information)
 func NewGateway(opts NewGatewayOpts) (*LocalGatewayService, error) {
+	rateLimiters := map[string]*rate.Limiter{}
+
+	for apiName, limit := range opts.RateLimits {
+		if limit.RequestsPerSecond <= 0 {
+			return nil, fmt.Errorf("invalid rate limit for api %s, requests per second must be greater than 0", apiName)
+		}
+
+		if limit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit for api %s, burst must be greater than 0", apiName)
+		}
+
+		rateLimiters[apiName] = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)
+	}
+
+	middleware := map[string]middlewareChain{}
+
+	for apiName, m := range opts.Middleware {
+		chain, err := compileMiddleware(apiName, m)
+		if err != nil {
+			return nil, err
+		}
+
+		middleware[apiName] = chain
+	}
+
+	accessLog, err := newAccessLogger(opts.LocalConfig.AccessLog.Format, opts.LogWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.LocalConfig.AccessLog.File != "" {
+		accessLogFile, err := os.OpenFile(opts.LocalConfig.AccessLog.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open access log file: %w", err)
+		}
+
+		accessLog.writer = accessLogFile
+		accessLog.file = accessLogFile
+	}
+
 	return &LocalGatewayService{
 		ApiTlsCredentials: opts.TLSCredentials,
 		bus:               EventBus.New(),
 		logWriter:         opts.LogWriter,
 		localConfig:       opts.LocalConfig,
+		flags:             opts.Flags,
+		rateLimiters:      rateLimiters,
+		apiKeyRequired:    opts.ApiKeyRequired,
+		validateApiKey:    opts.ValidateApiKey,
+		recorder:          opts.Recorder,
+		middleware:        middleware,
+		webhooks:          opts.Webhooks,
+		requestValidators: opts.RequestValidators,
+		httpProxyPorts:    opts.HttpProxyPorts,
+		accessLog:         accessLog,
 	}, nil
 }
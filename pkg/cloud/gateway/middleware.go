@@ -0,0 +1,158 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/valyala/fasthttp"
+)
+
+// Middleware - a transformation applied to API requests and responses by the local gateway, mirroring cloud API gateway transformations
+type Middleware struct {
+	// Expression that must be true for the middleware to apply, e.g. request.method == "POST" && request.path startsWith "/admin"
+	When                  string
+	SetRequestHeaders     map[string]string
+	RemoveRequestHeaders  []string
+	SetResponseHeaders    map[string]string
+	RemoveResponseHeaders []string
+	// Regular expression matched against the request path, replaced with RewritePathTo
+	RewritePathFrom string
+	// Replacement path, may reference capture groups of RewritePathFrom, e.g. /$1
+	RewritePathTo string
+}
+
+type compiledMiddleware struct {
+	Middleware
+	when        *vm.Program
+	rewritePath *regexp.Regexp
+}
+
+type middlewareChain []*compiledMiddleware
+
+func compileMiddleware(apiName string, middleware []Middleware) (middlewareChain, error) {
+	chain := middlewareChain{}
+
+	for i, m := range middleware {
+		compiled := &compiledMiddleware{Middleware: m}
+
+		if m.When != "" {
+			program, err := expr.Compile(m.When, expr.Env(requestEnv(&fasthttp.Request{})), expr.AsBool())
+			if err != nil {
+				return nil, fmt.Errorf("invalid when expression for middleware %d of api %s: %w", i, apiName, err)
+			}
+
+			compiled.when = program
+		}
+
+		if m.RewritePathFrom != "" {
+			rewritePath, err := regexp.Compile(m.RewritePathFrom)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path rewrite for middleware %d of api %s: %w", i, apiName, err)
+			}
+
+			compiled.rewritePath = rewritePath
+		}
+
+		chain = append(chain, compiled)
+	}
+
+	return chain, nil
+}
+
+// requestEnv - the variables available to middleware expressions
+func requestEnv(req *fasthttp.Request) map[string]interface{} {
+	headers := map[string]string{}
+
+	req.Header.VisitAll(func(key []byte, value []byte) {
+		headers[strings.ToLower(string(key))] = string(value)
+	})
+
+	query := map[string]string{}
+
+	req.URI().QueryArgs().VisitAll(func(key []byte, value []byte) {
+		query[string(key)] = string(value)
+	})
+
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"method":  string(req.Header.Method()),
+			"path":    string(req.URI().Path()),
+			"headers": headers,
+			"query":   query,
+		},
+	}
+}
+
+// applies - returns the middleware that apply to a request, evaluated before the request is transformed
+func (c middlewareChain) applies(req *fasthttp.Request) (middlewareChain, error) {
+	if len(c) == 0 {
+		return c, nil
+	}
+
+	env := requestEnv(req)
+	applied := middlewareChain{}
+
+	for _, m := range c {
+		if m.when != nil {
+			result, err := expr.Run(m.when, env)
+			if err != nil {
+				return nil, fmt.Errorf("error evaluating middleware expression %s: %w", m.When, err)
+			}
+
+			if !result.(bool) {
+				continue
+			}
+		}
+
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+func (c middlewareChain) transformRequest(req *fasthttp.Request) {
+	for _, m := range c {
+		for _, name := range m.RemoveRequestHeaders {
+			req.Header.Del(name)
+		}
+
+		for name, value := range m.SetRequestHeaders {
+			req.Header.Set(name, value)
+		}
+
+		if m.rewritePath != nil {
+			req.URI().SetPath(m.rewritePath.ReplaceAllString(string(req.URI().Path()), m.RewritePathTo))
+		}
+	}
+}
+
+func (c middlewareChain) transformResponse(resp *fasthttp.Response) {
+	for _, m := range c {
+		for _, name := range m.RemoveResponseHeaders {
+			resp.Header.Del(name)
+		}
+
+		for name, value := range m.SetResponseHeaders {
+			resp.Header.Set(name, value)
+		}
+	}
+}
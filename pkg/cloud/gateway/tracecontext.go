@@ -0,0 +1,53 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TraceparentHeader - the W3C trace context header, see https://www.w3.org/TR/trace-context/
+const TraceparentHeader = "traceparent"
+
+var traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// nextTraceparent - continues the incoming trace with a new span id for the gateway's hop,
+// starting a new sampled trace if the incoming traceparent is missing or invalid, as cloud API gateways do
+func nextTraceparent(incoming string) (traceparent string, traceId string) {
+	if match := traceparentPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(incoming))); match != nil {
+		version, traceId, parentId, flags := match[1], match[2], match[3], match[4]
+
+		if version != "ff" && traceId != strings.Repeat("0", 32) && parentId != strings.Repeat("0", 16) {
+			return fmt.Sprintf("00-%s-%s-%s", traceId, randomHex(8), flags), traceId
+		}
+	}
+
+	traceId = randomHex(16)
+
+	return fmt.Sprintf("00-%s-%s-01", traceId, randomHex(8)), traceId
+}
@@ -0,0 +1,148 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// RequestValidator - validates the parameters and bodies of requests to an API against its OpenAPI document,
+// as cloud API gateways do for APIs deployed with a request validator
+type RequestValidator struct {
+	router routers.Router
+}
+
+// ValidationIssue - a reason a request doesn't match the API's OpenAPI document
+type ValidationIssue struct {
+	// Where the problem is in the request, e.g. query.limit or body.items.0.name
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationErrorResponse - the body of 400 responses to requests that don't match the API's OpenAPI document
+type ValidationErrorResponse struct {
+	Message string            `json:"message"`
+	Errors  []ValidationIssue `json:"errors"`
+}
+
+// NewRequestValidator - creates a validator for the operations of an OpenAPI document
+func NewRequestValidator(doc *openapi3.T) (*RequestValidator, error) {
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// requests are routed by path alone, the local gateway serves each API on its own port without the base path of its servers
+	localDoc := *doc
+	localDoc.Servers = nil
+
+	router, err := gorillamux.NewRouter(&localDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestValidator{router: router}, nil
+}
+
+// validate - returns the issues with a request, requests for operations that aren't in the document aren't validated
+func (v *RequestValidator) validate(ctx *fasthttp.RequestCtx) []ValidationIssue {
+	req := &http.Request{}
+
+	if err := fasthttpadaptor.ConvertRequest(ctx, req, true); err != nil {
+		return []ValidationIssue{{Message: err.Error()}}
+	}
+
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return nil
+	}
+
+	err = openapi3filter.ValidateRequest(context.Background(), &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError: true,
+			// API keys and other security schemes are checked by the gateway, not the request validator
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	})
+	if err != nil {
+		return validationIssues(err, "")
+	}
+
+	return nil
+}
+
+// validationIssues - flattens the errors of a request's validation, the type of each error is checked directly
+// rather than with errors.As, as request errors unwrap to the errors of their schemas and would lose their location
+func validationIssues(err error, location string) []ValidationIssue {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		issues := []ValidationIssue{}
+
+		for _, inner := range e {
+			issues = append(issues, validationIssues(inner, location)...)
+		}
+
+		return issues
+	case *openapi3filter.RequestError:
+		switch {
+		case e.Parameter != nil:
+			location = e.Parameter.In + "." + e.Parameter.Name
+		case e.RequestBody != nil:
+			location = "body"
+		}
+
+		if e.Err == nil {
+			return []ValidationIssue{{Location: location, Message: e.Reason}}
+		}
+
+		return validationIssues(e.Err, location)
+	case *openapi3filter.ParseError:
+		return []ValidationIssue{{Location: location, Message: e.Error()}}
+	case *openapi3.SchemaError:
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			location = strings.Join(append([]string{location}, pointer...), ".")
+		}
+
+		return []ValidationIssue{{Location: location, Message: e.Reason}}
+	}
+
+	return []ValidationIssue{{Location: location, Message: err.Error()}}
+}
+
+// writeValidationError - responds with the issues found with a request and a 400 status
+func writeValidationError(ctx *fasthttp.RequestCtx, issues []ValidationIssue) {
+	body, _ := json.Marshal(ValidationErrorResponse{
+		Message: "Invalid request",
+		Errors:  issues,
+	})
+
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+	ctx.SetBody(body)
+}
@@ -0,0 +1,64 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/nitrictech/cli/pkg/webhooks"
+)
+
+// Webhook - a route of an API that receives signed webhooks
+type Webhook struct {
+	// Path of the route, may contain wildcards, e.g. /webhooks/*
+	Path   string
+	Scheme webhooks.Scheme
+	Secret string
+	// Sign requests that don't have a signature rather than rejecting them, so handlers verifying signatures can be called directly
+	Simulate bool
+}
+
+// checkWebhook - verifies the signature of requests to webhook routes, signing unsigned requests to simulated routes
+func checkWebhook(hooks []Webhook, req *fasthttp.Request) error {
+	for _, hook := range hooks {
+		if match, _ := path.Match(hook.Path, string(req.URI().Path())); !match {
+			continue
+		}
+
+		if hook.Simulate && len(req.Header.Peek(hook.Scheme.Header())) == 0 {
+			for k, v := range hook.Scheme.Sign(hook.Secret, req.Body(), time.Now()) {
+				req.Header.Set(k, v)
+			}
+
+			return nil
+		}
+
+		headers := http.Header{}
+
+		req.Header.VisitAll(func(key, value []byte) {
+			headers.Add(string(key), string(value))
+		})
+
+		return hook.Scheme.Verify(hook.Secret, req.Body(), headers, time.Now())
+	}
+
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -32,7 +33,6 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/structpb"
 
-	"github.com/nitrictech/cli/pkg/cloud/env"
 	grpc_errors "github.com/nitrictech/nitric/core/pkg/grpc/errors"
 	kvstorepb "github.com/nitrictech/nitric/core/pkg/proto/kvstore/v1"
 )
@@ -187,10 +187,8 @@ func (s *BoltDocService) DeleteKey(ctx context.Context, req *kvstorepb.KvStoreDe
 	return &kvstorepb.KvStoreDeleteKeyResponse{}, nil
 }
 
-// New - Create a new dev KV plugin
-func NewBoltService() (*BoltDocService, error) {
-	dbDir := env.LOCAL_DB_DIR.String()
-
+// New - Create a new dev KV plugin, storing its databases in dbDir
+func NewBoltService(dbDir string) (*BoltDocService, error) {
 	// Check whether file exists
 	_, err := os.Stat(dbDir)
 	if os.IsNotExist(err) {
@@ -260,6 +258,59 @@ func (s *BoltDocService) ScanKeys(req *kvstorepb.KvStoreScanKeysRequest, stream
 	return nil
 }
 
+// Stores - returns the names of the key/value stores with data
+func (s *BoltDocService) Stores() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dbDir, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	stores := []string{}
+
+	for _, file := range files {
+		stores = append(stores, strings.TrimSuffix(filepath.Base(file), ".db"))
+	}
+
+	return stores, nil
+}
+
+// ExportStore - writes a consistent copy of the store's database file
+func (s *BoltDocService) ExportStore(storeName string, w io.Writer) error {
+	db, err := s.getLocalKVDB(storeName)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	return db.Bolt.View(func(tx *bbolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// ImportStore - replaces the store's database file with one written by ExportStore
+func (s *BoltDocService) ImportStore(storeName string, r io.Reader) error {
+	tmp, err := os.CreateTemp(s.dbDir, "import-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// requests open the database file for each operation, so replacing it takes effect for the next operation
+	return os.Rename(tmp.Name(), filepath.Join(s.dbDir, strings.ToLower(storeName)+".db"))
+}
+
 func (s *BoltDocService) getLocalKVDB(storeName string) (*storm.DB, error) {
 	dbPath := filepath.Join(s.dbDir, strings.ToLower(storeName)+".db")
 
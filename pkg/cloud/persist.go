@@ -0,0 +1,90 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nitrictech/cli/pkg/cloud/queues"
+)
+
+// loadQueues - restores the messages of queues saved when the local cloud last stopped
+func loadQueues(localQueues *queues.LocalQueuesService, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		queueName := strings.TrimSuffix(entry.Name(), ".json")
+
+		contents, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		messages, err := unmarshalQueueMessages(contents)
+		if err != nil {
+			return fmt.Errorf("unable to load the messages of queue %s: %w", queueName, err)
+		}
+
+		localQueues.ReplaceMessages(queueName, messages)
+	}
+
+	return nil
+}
+
+// saveQueues - writes the messages in each queue to a file in the directory, replacing those saved previously.
+// Leased messages are saved too, as their leases end when the local cloud stops
+func (lc *LocalCloud) saveQueues(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for queueName, messages := range lc.Queues.Messages() {
+		if len(messages) == 0 {
+			continue
+		}
+
+		contents, err := marshalQueueMessages(messages)
+		if err != nil {
+			return fmt.Errorf("unable to save the messages of queue %s: %w", queueName, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, queueName+".json"), contents, 0o600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
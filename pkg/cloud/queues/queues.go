@@ -165,6 +165,28 @@ func (l *LocalQueuesService) Complete(ctx context.Context, req *queuespb.QueueCo
 	)
 }
 
+// Messages - returns the messages in each queue, including leased messages that haven't been completed
+func (l *LocalQueuesService) Messages() map[string][]*queuespb.QueueMessage {
+	l.queueLock.Lock()
+	defer l.queueLock.Unlock()
+
+	return lo.MapValues(l.queues, func(items []*QueueItem, _ string) []*queuespb.QueueMessage {
+		return lo.Map(items, func(item *QueueItem, _ int) *queuespb.QueueMessage {
+			return item.message
+		})
+	})
+}
+
+// ReplaceMessages - replaces the messages in a queue, e.g. when importing a snapshot of the local cloud
+func (l *LocalQueuesService) ReplaceMessages(queueName string, messages []*queuespb.QueueMessage) {
+	l.queueLock.Lock()
+	defer l.queueLock.Unlock()
+
+	l.queues[queueName] = lo.Map(messages, func(message *queuespb.QueueMessage, _ int) *QueueItem {
+		return &QueueItem{message: message}
+	})
+}
+
 // Create new Dev EventService
 func NewLocalQueuesService() (*LocalQueuesService, error) {
 	queueService := &LocalQueuesService{
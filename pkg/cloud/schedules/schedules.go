@@ -48,6 +48,8 @@ type State = map[scheduleName]*ScheduledService
 type ActionState struct {
 	ScheduleName string
 	Success      bool
+	// True when the schedule was triggered manually, e.g. from the dashboard, rather than by its cron or rate
+	Manual bool
 }
 type LocalSchedulesService struct {
 	*schedules.ScheduleWorkerManager
@@ -118,25 +120,30 @@ func (l *LocalSchedulesService) unregisterSchedule(serviceName string, registrat
 	l.publishState()
 }
 
+// HandleRequest - runs a schedule that was triggered manually
 func (l *LocalSchedulesService) HandleRequest(request *schedulespb.ServerMessage) (*schedulespb.ClientMessage, error) {
+	return l.handleRequest(request, true)
+}
+
+func (l *LocalSchedulesService) handleRequest(request *schedulespb.ServerMessage, manual bool) (*schedulespb.ClientMessage, error) {
 	resp, err := l.ScheduleWorkerManager.HandleRequest(request)
 
 	scheduleName := request.GetIntervalRequest().ScheduleName
 
-	l.publishAction(ActionState{ScheduleName: scheduleName, Success: true})
+	l.publishAction(ActionState{ScheduleName: scheduleName, Success: true, Manual: manual})
 
 	return resp, err
 }
 
 func (l *LocalSchedulesService) createCronSchedule(scheduleName, expression string) (cron.EntryID, error) {
 	return l.cron.AddFunc(expression, func() {
-		_, err := l.HandleRequest(&schedulespb.ServerMessage{
+		_, err := l.handleRequest(&schedulespb.ServerMessage{
 			Content: &schedulespb.ServerMessage_IntervalRequest{
 				IntervalRequest: &schedulespb.IntervalRequest{
 					ScheduleName: scheduleName,
 				},
 			},
-		})
+		}, false)
 		if err != nil {
 			logger.Errorf("Error handling schedule: %s", err.Error())
 		}
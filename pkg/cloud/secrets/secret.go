@@ -32,7 +32,6 @@ import (
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 
-	"github.com/nitrictech/cli/pkg/cloud/env"
 	grpc_errors "github.com/nitrictech/nitric/core/pkg/grpc/errors"
 	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
 )
@@ -288,6 +287,28 @@ func (s *DevSecretService) List(ctx context.Context, secretName string) ([]Secre
 	return resp, nil
 }
 
+// Names - returns the names of the secrets with a stored value, sorted by name
+func (s *DevSecretService) Names() ([]string, error) {
+	files, err := os.ReadDir(s.secDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, file := range files {
+		// the latest version file is written with every version
+		name, found := strings.CutSuffix(file.Name(), "_latest.txt")
+		if found {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
 // Delete a secret version, used by dashboard
 func (s *DevSecretService) Delete(ctx context.Context, secretName string, version string, latest bool) error {
 	s.mu.Lock()
@@ -375,9 +396,8 @@ func (s *DevSecretService) Delete(ctx context.Context, secretName string, versio
 	return nil
 }
 
-// Create new secret store
-func NewSecretService() (*DevSecretService, error) {
-	secDir := env.LOCAL_SECRETS_DIR.String()
+// Create new secret store, storing secret versions in secDir
+func NewSecretService(secDir string) (*DevSecretService, error) {
 	// Check whether file exists
 	_, err := os.Stat(secDir)
 	if os.IsNotExist(err) {
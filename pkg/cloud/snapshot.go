@@ -0,0 +1,384 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	queuespb "github.com/nitrictech/nitric/core/pkg/proto/queues/v1"
+)
+
+const snapshotManifestFile = "snapshot.json"
+
+// SnapshotManifest - describes the resources included in a snapshot of the local cloud's state
+type SnapshotManifest struct {
+	Project        string    `json:"project"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Buckets        []string  `json:"buckets"`
+	KeyValueStores []string  `json:"keyValueStores"`
+	Queues         []string  `json:"queues"`
+	Databases      []string  `json:"databases"`
+}
+
+type snapshotWriter struct {
+	tw *tar.Writer
+}
+
+func (s *snapshotWriter) write(name string, contents []byte) error {
+	err := s.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(contents)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.tw.Write(contents)
+
+	return err
+}
+
+// Export - writes a gzipped tar of the files in buckets, the key/value stores, the messages in queues and a dump of each
+// database, for restoring the same state with Import, e.g. on a teammate's machine
+func (lc *LocalCloud) Export(w io.Writer, projectName string) (*SnapshotManifest, error) {
+	manifest := &SnapshotManifest{
+		Project:        projectName,
+		CreatedAt:      time.Now().UTC(),
+		Buckets:        []string{},
+		KeyValueStores: []string{},
+		Queues:         []string{},
+		Databases:      []string{},
+	}
+
+	gw := gzip.NewWriter(w)
+	sw := &snapshotWriter{tw: tar.NewWriter(gw)}
+
+	if err := lc.exportBuckets(sw, manifest); err != nil {
+		return nil, fmt.Errorf("unable to export buckets: %w", err)
+	}
+
+	stores, err := lc.KeyValue.Stores()
+	if err != nil {
+		return nil, fmt.Errorf("unable to export key/value stores: %w", err)
+	}
+
+	for _, store := range stores {
+		var contents bytes.Buffer
+
+		if err := lc.KeyValue.ExportStore(store, &contents); err != nil {
+			return nil, fmt.Errorf("unable to export key/value store %s: %w", store, err)
+		}
+
+		if err := sw.write(path.Join("kv", store+".db"), contents.Bytes()); err != nil {
+			return nil, err
+		}
+
+		manifest.KeyValueStores = append(manifest.KeyValueStores, store)
+	}
+
+	for queueName, messages := range lc.Queues.Messages() {
+		contents, err := marshalQueueMessages(messages)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export queue %s: %w", queueName, err)
+		}
+
+		if err := sw.write(path.Join("queues", queueName+".json"), contents); err != nil {
+			return nil, err
+		}
+
+		manifest.Queues = append(manifest.Queues, queueName)
+	}
+
+	// databases on external servers are managed outside of the local cloud
+	if lc.Databases != nil && !lc.Databases.External() {
+		for databaseName := range lc.Databases.GetState() {
+			var contents bytes.Buffer
+
+			if err := lc.Databases.Dump(databaseName, &contents); err != nil {
+				return nil, fmt.Errorf("unable to export database %s: %w", databaseName, err)
+			}
+
+			if err := sw.write(path.Join("sql", databaseName+".sql"), contents.Bytes()); err != nil {
+				return nil, err
+			}
+
+			manifest.Databases = append(manifest.Databases, databaseName)
+		}
+	}
+
+	slices.Sort(manifest.Queues)
+	slices.Sort(manifest.Databases)
+
+	manifestContents, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sw.write(snapshotManifestFile, manifestContents); err != nil {
+		return nil, err
+	}
+
+	if err := sw.tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, gw.Close()
+}
+
+func (lc *LocalCloud) exportBuckets(sw *snapshotWriter, manifest *SnapshotManifest) error {
+	bucketsDir := lc.Storage.BucketsDir()
+
+	buckets, err := os.ReadDir(bucketsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, bucket := range buckets {
+		if !bucket.IsDir() {
+			continue
+		}
+
+		manifest.Buckets = append(manifest.Buckets, bucket.Name())
+
+		// directory entries restore empty buckets
+		err := sw.tw.WriteHeader(&tar.Header{
+			Name:     path.Join("buckets", bucket.Name()) + "/",
+			Typeflag: tar.TypeDir,
+			Mode:     0o755,
+			ModTime:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = filepath.WalkDir(filepath.Join(bucketsDir, bucket.Name()), func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			key, err := filepath.Rel(bucketsDir, file)
+			if err != nil {
+				return err
+			}
+
+			contents, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			return sw.write(path.Join("buckets", filepath.ToSlash(key)), contents)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func marshalQueueMessages(messages []*queuespb.QueueMessage) ([]byte, error) {
+	rawMessages := []json.RawMessage{}
+
+	for _, message := range messages {
+		raw, err := protojson.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+
+		rawMessages = append(rawMessages, raw)
+	}
+
+	return json.MarshalIndent(rawMessages, "", "  ")
+}
+
+func unmarshalQueueMessages(contents []byte) ([]*queuespb.QueueMessage, error) {
+	rawMessages := []json.RawMessage{}
+
+	if err := json.Unmarshal(contents, &rawMessages); err != nil {
+		return nil, err
+	}
+
+	messages := []*queuespb.QueueMessage{}
+
+	for _, raw := range rawMessages {
+		message := &queuespb.QueueMessage{}
+
+		if err := protojson.Unmarshal(raw, message); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// Import - restores the state written by Export, replacing the contents of the buckets, key/value stores, queues and
+// databases in the snapshot, resources that aren't in the snapshot are left unchanged
+func (lc *LocalCloud) Import(r io.Reader) (*SnapshotManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	imported := &SnapshotManifest{
+		Buckets:        []string{},
+		KeyValueStores: []string{},
+		Queues:         []string{},
+		Databases:      []string{},
+	}
+
+	tr := tar.NewReader(gr)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid snapshot: file %s is outside of the snapshot", header.Name)
+		}
+
+		kind, rest, _ := strings.Cut(name, "/")
+
+		if header.Typeflag == tar.TypeDir && (kind != "buckets" || rest == "") {
+			continue
+		}
+
+		switch kind {
+		case "buckets":
+			bucket, key, _ := strings.Cut(rest, "/")
+			bucketDir := filepath.Join(lc.Storage.BucketsDir(), bucket)
+
+			if !slices.Contains(imported.Buckets, bucket) {
+				// replace rather than merge the bucket's files
+				if err := os.RemoveAll(bucketDir); err != nil {
+					return nil, err
+				}
+
+				if err := os.MkdirAll(bucketDir, os.ModePerm); err != nil {
+					return nil, err
+				}
+
+				imported.Buckets = append(imported.Buckets, bucket)
+			}
+
+			if header.Typeflag == tar.TypeDir || key == "" {
+				continue
+			}
+
+			if err := writeSnapshotFile(filepath.Join(bucketDir, filepath.FromSlash(key)), tr); err != nil {
+				return nil, fmt.Errorf("unable to import bucket %s: %w", bucket, err)
+			}
+		case "kv":
+			store := strings.TrimSuffix(rest, ".db")
+
+			if err := lc.KeyValue.ImportStore(store, tr); err != nil {
+				return nil, fmt.Errorf("unable to import key/value store %s: %w", store, err)
+			}
+
+			imported.KeyValueStores = append(imported.KeyValueStores, store)
+		case "queues":
+			queueName := strings.TrimSuffix(rest, ".json")
+
+			contents, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			messages, err := unmarshalQueueMessages(contents)
+			if err != nil {
+				return nil, fmt.Errorf("unable to import queue %s: %w", queueName, err)
+			}
+
+			lc.Queues.ReplaceMessages(queueName, messages)
+			imported.Queues = append(imported.Queues, queueName)
+		case "sql":
+			databaseName := strings.TrimSuffix(rest, ".sql")
+
+			if lc.Databases == nil || lc.Databases.External() {
+				return nil, fmt.Errorf("unable to import database %s, databases are disabled or on an external server", databaseName)
+			}
+
+			if err := lc.Databases.Restore(databaseName, tr); err != nil {
+				return nil, fmt.Errorf("unable to import database %s: %w", databaseName, err)
+			}
+
+			imported.Databases = append(imported.Databases, databaseName)
+		case snapshotManifestFile:
+			manifest := &SnapshotManifest{}
+
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+			}
+
+			imported.Project = manifest.Project
+			imported.CreatedAt = manifest.CreatedAt
+		}
+	}
+
+	return imported, nil
+}
+
+func writeSnapshotFile(file string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -32,6 +32,7 @@ import (
 
 	"github.com/asaskevich/EventBus"
 	"github.com/gorilla/mux"
+	"github.com/samber/lo"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -85,6 +86,9 @@ type LocalStorageService struct {
 
 	storageListener net.Listener
 
+	// directory containing a sub-directory for the files of each bucket
+	bucketsDir string
+
 	bus EventBus.Bus
 }
 
@@ -218,8 +222,13 @@ func (r *LocalStorageService) Listen(stream storagepb.StorageListener_ListenServ
 	}
 }
 
+// BucketsDir - returns the directory containing a directory of files for each bucket
+func (r *LocalStorageService) BucketsDir() string {
+	return r.bucketsDir
+}
+
 func (r *LocalStorageService) ensureBucketExists(ctx context.Context, bucket string) error {
-	return os.MkdirAll(filepath.Join(env.LOCAL_BUCKETS_DIR.String(), bucket), os.ModePerm)
+	return os.MkdirAll(filepath.Join(r.bucketsDir, bucket), os.ModePerm)
 }
 
 func (r *LocalStorageService) triggerBucketNotifications(ctx context.Context, bucket string, key string, eventType storagepb.BlobEventType) {
@@ -251,7 +260,7 @@ func (r *LocalStorageService) Read(ctx context.Context, req *storagepb.StorageRe
 		)
 	}
 
-	fileRef := filepath.Join(env.LOCAL_BUCKETS_DIR.String(), req.BucketName, req.Key)
+	fileRef := filepath.Join(r.bucketsDir, req.BucketName, req.Key)
 
 	contents, err := os.ReadFile(fileRef)
 	if err != nil {
@@ -278,7 +287,7 @@ func (r *LocalStorageService) Read(ctx context.Context, req *storagepb.StorageRe
 func (r *LocalStorageService) Exists(ctx context.Context, req *storagepb.StorageExistsRequest) (*storagepb.StorageExistsResponse, error) {
 	newErr := grpc_errors.ErrorsWithScope("DevStorageService.Exists")
 
-	fileRef := filepath.Join(env.LOCAL_BUCKETS_DIR.String(), req.BucketName, req.Key)
+	fileRef := filepath.Join(r.bucketsDir, req.BucketName, req.Key)
 
 	_, err := os.Stat(fileRef)
 	if err != nil {
@@ -312,7 +321,7 @@ func (r *LocalStorageService) Write(ctx context.Context, req *storagepb.StorageW
 		)
 	}
 
-	fileRef := filepath.Join(env.LOCAL_BUCKETS_DIR.String(), req.BucketName, req.Key)
+	fileRef := filepath.Join(r.bucketsDir, req.BucketName, req.Key)
 
 	// Ensure the directory structure exists
 	err = os.MkdirAll(filepath.Dir(fileRef), os.ModePerm)
@@ -350,7 +359,7 @@ func (r *LocalStorageService) Delete(ctx context.Context, req *storagepb.Storage
 		)
 	}
 
-	fileRef := filepath.Join(env.LOCAL_BUCKETS_DIR.String(), req.BucketName, req.Key)
+	fileRef := filepath.Join(r.bucketsDir, req.BucketName, req.Key)
 
 	err = os.Remove(fileRef)
 	if err != nil {
@@ -380,7 +389,7 @@ func (r *LocalStorageService) ListBlobs(ctx context.Context, req *storagepb.Stor
 
 	blobs := []*storagepb.Blob{}
 
-	localBucket := filepath.Join(env.LOCAL_BUCKETS_DIR.String(), req.BucketName)
+	localBucket := filepath.Join(r.bucketsDir, req.BucketName)
 
 	err = filepath.Walk(localBucket, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -393,9 +402,14 @@ func (r *LocalStorageService) ListBlobs(ctx context.Context, req *storagepb.Stor
 				return err
 			}
 
-			blobs = append(blobs, &storagepb.Blob{
-				Key: filepath.ToSlash(relPath),
-			})
+			key := filepath.ToSlash(relPath)
+
+			// match cloud buckets, which only list blobs with keys starting with the prefix
+			if strings.HasPrefix(key, req.Prefix) {
+				blobs = append(blobs, &storagepb.Blob{
+					Key: key,
+				})
+			}
 		}
 
 		return nil
@@ -490,6 +504,8 @@ func (r *LocalStorageService) PreSignUrl(ctx context.Context, req *storagepb.Sto
 type StorageOptions struct {
 	AccessKey string
 	SecretKey string
+	// Directory the files of local buckets are stored in, defaults to LOCAL_BUCKETS_DIR
+	BucketsDir string
 }
 
 func corsMiddleware(next http.Handler) http.Handler {
@@ -511,8 +527,9 @@ func NewLocalStorageService(opts StorageOptions) (*LocalStorageService, error) {
 	var err error
 
 	storageService := &LocalStorageService{
-		listeners: map[string]map[string]int{},
-		bus:       EventBus.New(),
+		listeners:  map[string]map[string]int{},
+		bucketsDir: lo.Ternary(opts.BucketsDir != "", opts.BucketsDir, env.LOCAL_BUCKETS_DIR.String()),
+		bus:        EventBus.New(),
 	}
 
 	storageService.storageListener, err = net.Listen("tcp", ":0")
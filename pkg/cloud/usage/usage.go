@@ -0,0 +1,210 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asaskevich/EventBus"
+	"google.golang.org/grpc"
+
+	kvstorepb "github.com/nitrictech/nitric/core/pkg/proto/kvstore/v1"
+	queuespb "github.com/nitrictech/nitric/core/pkg/proto/queues/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
+	sqlpb "github.com/nitrictech/nitric/core/pkg/proto/sql/v1"
+	storagepb "github.com/nitrictech/nitric/core/pkg/proto/storage/v1"
+	topicspb "github.com/nitrictech/nitric/core/pkg/proto/topics/v1"
+	websocketspb "github.com/nitrictech/nitric/core/pkg/proto/websockets/v1"
+)
+
+// Edge - calls made by a service to a resource during a local run
+type Edge struct {
+	Service string
+	// Type of the resource, e.g. bucket, matching the resource types of policies
+	Type     string
+	Resource string
+	// Action the calls require permission for, e.g. BucketFileGet, empty for resources without permissions, e.g. sql databases
+	Action   string
+	Count    int
+	LastSeen time.Time
+}
+
+type State = []Edge
+
+type edgeKey struct {
+	service  string
+	typ      string
+	resource string
+	action   string
+}
+
+// LocalUsageService - records the resources that services actually call at runtime
+type LocalUsageService struct {
+	lock  sync.Mutex
+	edges map[edgeKey]*Edge
+
+	bus EventBus.Bus
+}
+
+const localUsageTopic = "local_usage"
+
+func (s *LocalUsageService) SubscribeToState(subscription func(State)) {
+	// ignore the error, it's only returned if the fn param isn't a function
+	_ = s.bus.Subscribe(localUsageTopic, subscription)
+}
+
+// GetAll - returns the recorded edges, sorted by service, resource and action
+func (s *LocalUsageService) GetAll() State {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	edges := make(State, 0, len(s.edges))
+	for _, edge := range s.edges {
+		edges = append(edges, *edge)
+	}
+
+	slices.SortFunc(edges, func(a, b Edge) int {
+		return strings.Compare(a.Service+"\x00"+a.Type+"\x00"+a.Resource+"\x00"+a.Action, b.Service+"\x00"+b.Type+"\x00"+b.Resource+"\x00"+b.Action)
+	})
+
+	return edges
+}
+
+func (s *LocalUsageService) record(serviceName string, resourceType resourcespb.ResourceType, resourceName string, action string) {
+	key := edgeKey{
+		service:  serviceName,
+		typ:      strings.ToLower(resourceType.String()),
+		resource: resourceName,
+		action:   action,
+	}
+
+	s.lock.Lock()
+
+	edge, ok := s.edges[key]
+	if !ok {
+		edge = &Edge{Service: key.service, Type: key.typ, Resource: key.resource, Action: key.action}
+		s.edges[key] = edge
+	}
+
+	edge.Count++
+	edge.LastSeen = time.Now()
+
+	s.lock.Unlock()
+
+	s.bus.Publish(localUsageTopic, s.GetAll())
+}
+
+// resourceAction - returns the resource a request is for and the action it requires permission for
+func resourceAction(req any) (resourcespb.ResourceType, string, string, bool) {
+	bucket := func(name string, action resourcespb.Action) (resourcespb.ResourceType, string, string, bool) {
+		return resourcespb.ResourceType_Bucket, name, action.String(), true
+	}
+
+	switch r := req.(type) {
+	case *storagepb.StorageReadRequest:
+		return bucket(r.BucketName, resourcespb.Action_BucketFileGet)
+	case *storagepb.StorageExistsRequest:
+		return bucket(r.BucketName, resourcespb.Action_BucketFileGet)
+	case *storagepb.StorageWriteRequest:
+		return bucket(r.BucketName, resourcespb.Action_BucketFilePut)
+	case *storagepb.StorageDeleteRequest:
+		return bucket(r.BucketName, resourcespb.Action_BucketFileDelete)
+	case *storagepb.StorageListBlobsRequest:
+		return bucket(r.BucketName, resourcespb.Action_BucketFileList)
+	case *storagepb.StoragePreSignUrlRequest:
+		if r.Operation == storagepb.StoragePreSignUrlRequest_WRITE {
+			return bucket(r.BucketName, resourcespb.Action_BucketFilePut)
+		}
+
+		return bucket(r.BucketName, resourcespb.Action_BucketFileGet)
+	case *topicspb.TopicPublishRequest:
+		return resourcespb.ResourceType_Topic, r.TopicName, resourcespb.Action_TopicPublish.String(), true
+	case *kvstorepb.KvStoreGetValueRequest:
+		return resourcespb.ResourceType_KeyValueStore, r.GetRef().GetStore(), resourcespb.Action_KeyValueStoreRead.String(), true
+	case *kvstorepb.KvStoreScanKeysRequest:
+		return resourcespb.ResourceType_KeyValueStore, r.GetStore().GetName(), resourcespb.Action_KeyValueStoreRead.String(), true
+	case *kvstorepb.KvStoreSetValueRequest:
+		return resourcespb.ResourceType_KeyValueStore, r.GetRef().GetStore(), resourcespb.Action_KeyValueStoreWrite.String(), true
+	case *kvstorepb.KvStoreDeleteKeyRequest:
+		return resourcespb.ResourceType_KeyValueStore, r.GetRef().GetStore(), resourcespb.Action_KeyValueStoreDelete.String(), true
+	case *queuespb.QueueEnqueueRequest:
+		return resourcespb.ResourceType_Queue, r.QueueName, resourcespb.Action_QueueEnqueue.String(), true
+	case *queuespb.QueueDequeueRequest:
+		return resourcespb.ResourceType_Queue, r.QueueName, resourcespb.Action_QueueDequeue.String(), true
+	case *queuespb.QueueCompleteRequest:
+		return resourcespb.ResourceType_Queue, r.QueueName, resourcespb.Action_QueueDequeue.String(), true
+	case *secretspb.SecretAccessRequest:
+		return resourcespb.ResourceType_Secret, r.GetSecretVersion().GetSecret().GetName(), resourcespb.Action_SecretAccess.String(), true
+	case *secretspb.SecretPutRequest:
+		return resourcespb.ResourceType_Secret, r.GetSecret().GetName(), resourcespb.Action_SecretPut.String(), true
+	case *websocketspb.WebsocketSendRequest:
+		return resourcespb.ResourceType_Websocket, r.SocketName, resourcespb.Action_WebsocketManage.String(), true
+	case *websocketspb.WebsocketCloseConnectionRequest:
+		return resourcespb.ResourceType_Websocket, r.SocketName, resourcespb.Action_WebsocketManage.String(), true
+	case *sqlpb.SqlConnectionStringRequest:
+		return resourcespb.ResourceType_SqlDatabase, r.DatabaseName, "", true
+	}
+
+	return 0, "", "", false
+}
+
+func (s *LocalUsageService) recordRequest(serviceName string, req any) {
+	if resourceType, resourceName, action, ok := resourceAction(req); ok && resourceName != "" {
+		s.record(serviceName, resourceType, resourceName, action)
+	}
+}
+
+type recordingStream struct {
+	grpc.ServerStream
+	record func(req any)
+}
+
+func (r *recordingStream) RecvMsg(m any) error {
+	err := r.ServerStream.RecvMsg(m)
+	if err == nil {
+		r.record(m)
+	}
+
+	return err
+}
+
+// CreateInterceptors - records the resources called through the nitric server of the service
+func (s *LocalUsageService) CreateInterceptors(serviceName string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	record := func(req any) {
+		s.recordRequest(serviceName, req)
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			record(req)
+
+			return handler(ctx, req)
+		}, func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, &recordingStream{ServerStream: ss, record: record})
+		}
+}
+
+func NewLocalUsageService() *LocalUsageService {
+	return &LocalUsageService{
+		edges: map[edgeKey]*Edge{},
+		bus:   EventBus.New(),
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudsecrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
+)
+
+// awsStore - the AWS Secrets Manager secrets of a stack
+type awsStore struct {
+	client *secretsmanager.SecretsManager
+	// prefix of the tag keys the stack's resources are tagged with, the stack ID ends with a random suffix
+	tagPrefix string
+}
+
+func newAwsStore(projectName string, stackName string, region string) (*awsStore, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS credentials: %w", err)
+	}
+
+	return &awsStore{
+		client:    secretsmanager.New(sess, aws.NewConfig().WithRegion(region)),
+		tagPrefix: fmt.Sprintf("x-nitric-%s-%s-", projectName, stackName),
+	}, nil
+}
+
+// secretArn - finds the secret of the stack with the given name, by the x-nitric-<stack id>-name and -type tags the
+// nitric AWS runtime discovers resources with
+func (s *awsStore) secretArn(ctx context.Context, name string) (string, error) {
+	arn := ""
+
+	err := s.client.ListSecretsPagesWithContext(ctx, &secretsmanager.ListSecretsInput{
+		Filters: []*secretsmanager.Filter{{Key: aws.String(secretsmanager.FilterNameStringTypeTagValue), Values: []*string{aws.String(name)}}},
+	}, func(page *secretsmanager.ListSecretsOutput, _ bool) bool {
+		for _, secret := range page.SecretList {
+			tags := map[string]string{}
+			for _, tag := range secret.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+
+			if isStackSecret(tags, s.tagPrefix, name) {
+				arn = aws.StringValue(secret.ARN)
+				return false
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to list secrets: %w", err)
+	}
+
+	if arn == "" {
+		return "", status.Errorf(codes.NotFound, "secret %s isn't deployed", name)
+	}
+
+	return arn, nil
+}
+
+// stackIdSuffix - the random suffix ending the stack IDs of nitric stacks, it never contains a dash
+var stackIdSuffix = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// isStackSecret - returns true if the tags are those of the named secret of the stack with the tag prefix. The rest of the
+// tag key must be exactly the stack ID suffix, so stack dev doesn't match the secrets of stack dev-eu
+func isStackSecret(tags map[string]string, tagPrefix string, name string) bool {
+	for key, value := range tags {
+		stackId, ok := strings.CutSuffix(key, "-name")
+		if !ok || value != name {
+			continue
+		}
+
+		suffix, ok := strings.CutPrefix(stackId, tagPrefix)
+		if ok && stackIdSuffix.MatchString(suffix) && tags[stackId+"-type"] == "secret" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *awsStore) Put(ctx context.Context, req *secretspb.SecretPutRequest) (*secretspb.SecretPutResponse, error) {
+	arn, err := s.secretArn(ctx, req.GetSecret().GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(arn),
+		SecretBinary: req.GetValue(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretspb.SecretPutResponse{
+		SecretVersion: &secretspb.SecretVersion{Secret: req.GetSecret(), Version: aws.StringValue(out.VersionId)},
+	}, nil
+}
+
+func (s *awsStore) Access(ctx context.Context, req *secretspb.SecretAccessRequest) (*secretspb.SecretAccessResponse, error) {
+	secret := req.GetSecretVersion().GetSecret()
+
+	arn, err := s.secretArn(ctx, secret.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(arn)}
+
+	if version := req.GetSecretVersion().GetVersion(); version != "" && version != "latest" {
+		input.VersionId = aws.String(version)
+	}
+
+	out, err := s.client.GetSecretValueWithContext(ctx, input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+		// secrets without a value have no current version
+		return nil, status.Errorf(codes.NotFound, "secret %s has no value", secret.GetName())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	value := out.SecretBinary
+	if value == nil {
+		value = []byte(aws.StringValue(out.SecretString))
+	}
+
+	return &secretspb.SecretAccessResponse{
+		SecretVersion: &secretspb.SecretVersion{Secret: secret, Version: aws.StringValue(out.VersionId)},
+		Value:         value,
+	}, nil
+}
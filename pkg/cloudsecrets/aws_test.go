@@ -0,0 +1,69 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudsecrets
+
+import (
+	"testing"
+)
+
+func TestIsStackSecret(t *testing.T) {
+	devTags := map[string]string{
+		"x-nitric-app-dev-a1b2c3d4-name": "api-key",
+		"x-nitric-app-dev-a1b2c3d4-type": "secret",
+	}
+	devEuTags := map[string]string{
+		"x-nitric-app-dev-eu-e5f6g7h8-name": "api-key",
+		"x-nitric-app-dev-eu-e5f6g7h8-type": "secret",
+	}
+
+	for _, tt := range []struct {
+		name      string
+		tags      map[string]string
+		tagPrefix string
+		secret    string
+		expected  bool
+	}{
+		{name: "secret of the stack", tags: devTags, tagPrefix: "x-nitric-app-dev-", secret: "api-key", expected: true},
+		{name: "secret of a stack named with the stack's name as a prefix", tags: devEuTags, tagPrefix: "x-nitric-app-dev-", secret: "api-key", expected: false},
+		{name: "secret of the longer named stack", tags: devEuTags, tagPrefix: "x-nitric-app-dev-eu-", secret: "api-key", expected: true},
+		{name: "other secret of the stack", tags: devTags, tagPrefix: "x-nitric-app-dev-", secret: "db-password", expected: false},
+		{name: "secret of another project", tags: devTags, tagPrefix: "x-nitric-other-dev-", secret: "api-key", expected: false},
+		{
+			name: "other resource type with the secret's name",
+			tags: map[string]string{
+				"x-nitric-app-dev-a1b2c3d4-name": "api-key",
+				"x-nitric-app-dev-a1b2c3d4-type": "bucket",
+			},
+			tagPrefix: "x-nitric-app-dev-",
+			secret:    "api-key",
+			expected:  false,
+		},
+		{
+			name:      "untagged secret",
+			tags:      map[string]string{},
+			tagPrefix: "x-nitric-app-dev-",
+			secret:    "api-key",
+			expected:  false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStackSecret(tt.tags, tt.tagPrefix, tt.secret); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
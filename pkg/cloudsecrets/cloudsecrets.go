@@ -0,0 +1,50 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudsecrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nitrictech/cli/pkg/credentials"
+	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
+)
+
+// Store - the secret manager of a deployed stack, read and written with the credentials of the machine running nitric
+type Store interface {
+	Put(ctx context.Context, req *secretspb.SecretPutRequest) (*secretspb.SecretPutResponse, error)
+	Access(ctx context.Context, req *secretspb.SecretAccessRequest) (*secretspb.SecretAccessResponse, error)
+}
+
+// New - opens the secret manager of a deployed stack, found by the tags the stack's provider adds to its secrets.
+// Only stacks deployed to AWS are supported, the secrets of other clouds are read and written through a nitric server
+// connected to the stack with nitric secret --remote
+func New(projectName string, stackName string, providerId string, region string) (Store, error) {
+	switch cloud := credentials.FederationCloud(providerId); {
+	case cloud == "aws" || strings.HasPrefix(providerId, "terraform/aws"):
+		if region == "" {
+			return nil, fmt.Errorf("stack %s has no region, set region in its stack file", stackName)
+		}
+
+		return newAwsStore(projectName, stackName, region)
+	case cloud != "":
+		return nil, fmt.Errorf("the secrets of %s stacks can't be read directly yet, use --remote with a nitric server connected to stack %s", credentials.Name(cloud), stackName)
+	default:
+		return nil, fmt.Errorf("the secrets of stacks using provider %s can't be read directly, use --remote with a nitric server connected to stack %s", providerId, stackName)
+	}
+}
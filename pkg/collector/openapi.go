@@ -0,0 +1,119 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// OpenApiDocuments - returns the OpenAPI 3 documents of the APIs in the spec, keyed by API name.
+//
+// When a service name is provided only the routes handled by that service are included, APIs without any of its routes are omitted.
+func OpenApiDocuments(spec *deploymentspb.Spec, serviceName string) (map[string]*openapi3.T, error) {
+	docs := map[string]*openapi3.T{}
+
+	for _, resource := range spec.Resources {
+		api := resource.GetApi()
+		if api == nil || api.GetOpenapi() == "" {
+			continue
+		}
+
+		doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read openapi document of api %s: %w", resource.Id.Name, err)
+		}
+
+		if serviceName != "" {
+			filterOperations(doc, serviceName)
+
+			if len(doc.Paths) == 0 {
+				continue
+			}
+		}
+
+		docs[resource.Id.Name] = doc
+	}
+
+	return docs, nil
+}
+
+// filterOperations - removes the operations of a document that aren't handled by the service, along with paths left without operations
+func filterOperations(doc *openapi3.T, serviceName string) {
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			if operationTarget(operation) != serviceName {
+				item.SetOperation(method, nil)
+			}
+		}
+
+		if len(item.Operations()) == 0 {
+			delete(doc.Paths, path)
+		}
+	}
+}
+
+// operationTarget - returns the name of the service handling an operation from its x-nitric-target extension
+func operationTarget(operation *openapi3.Operation) string {
+	ext, ok := operation.Extensions["x-nitric-target"]
+	if !ok {
+		return ""
+	}
+
+	// extensions loaded from a document are raw json
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return ""
+	}
+
+	target := struct {
+		Name string `json:"name"`
+	}{}
+
+	_ = json.Unmarshal(data, &target)
+
+	return target.Name
+}
+
+// MarshalOpenApiDocument - renders an OpenAPI document as indented json, or yaml when the format is yaml
+func MarshalOpenApiDocument(doc *openapi3.T, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		return data, nil
+	case "yaml":
+		// converted through a generic value, since the document only implements json marshalling
+		var value interface{}
+
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+
+		return yaml.Marshal(value)
+	default:
+		return nil, fmt.Errorf("unsupported openapi format %s, supported formats are json and yaml", format)
+	}
+}
@@ -41,6 +41,7 @@ type ServiceRequirements struct {
 	serviceName string
 	serviceType string
 	serviceFile string
+	image       string
 
 	resourceLock sync.Mutex
 
@@ -347,6 +348,11 @@ func (s *ServiceRequirements) HandleEvents(stream websocketspb.WebsocketHandler_
 	})
 }
 
+// SetImage - sets the image deployed for the service, defaults to the service name
+func (s *ServiceRequirements) SetImage(image string) {
+	s.image = image
+}
+
 func NewServiceRequirements(serviceName string, serviceFile string, serviceType string) *ServiceRequirements {
 	if serviceType == "" {
 		serviceType = "default"
@@ -354,6 +360,7 @@ func NewServiceRequirements(serviceName string, serviceFile string, serviceType
 
 	requirements := &ServiceRequirements{
 		serviceName:           serviceName,
+		image:                 serviceName,
 		serviceType:           serviceType,
 		serviceFile:           serviceFile,
 		resourceLock:          sync.Mutex{},
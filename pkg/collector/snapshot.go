@@ -0,0 +1,111 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+	"github.com/samber/lo"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// DefaultSpecSnapshotFile - the file spec snapshots are stored in, relative to the project directory
+const DefaultSpecSnapshotFile = "nitric-spec.snapshot.json"
+
+func resourceSortKey(resource *deploymentspb.Resource) string {
+	key := resource.Id.Type.String() + "/" + resource.Id.Name
+
+	if policy := resource.GetPolicy(); policy != nil {
+		// policy names are generated, so policies are ordered by what they grant instead
+		principals := lo.Map(policy.Principals, func(p *deploymentspb.Resource, _ int) string { return resourceSortKey(p) })
+		resources := lo.Map(policy.Resources, func(r *deploymentspb.Resource, _ int) string { return resourceSortKey(r) })
+		actions := lo.Map(policy.Actions, func(a resourcespb.Action, _ int) string { return a.String() })
+
+		key = resource.Id.Type.String() + "/" + strings.Join(principals, ",") + ":" + strings.Join(actions, ",") + ":" + strings.Join(resources, ",")
+	}
+
+	return key
+}
+
+func sortResources(resources []*deploymentspb.Resource) {
+	slices.SortFunc(resources, func(a *deploymentspb.Resource, b *deploymentspb.Resource) int {
+		return strings.Compare(resourceSortKey(a), resourceSortKey(b))
+	})
+}
+
+// SpecSnapshot - returns a canonical form of the spec, suitable for committing to a repository and comparing between builds.
+//
+// Resources are sorted, generated policy names are removed and service environment variables are removed
+// since they differ between environments and may contain secrets.
+func SpecSnapshot(spec *deploymentspb.Spec) ([]byte, error) {
+	canonical := proto.Clone(spec).(*deploymentspb.Spec)
+
+	for _, resource := range canonical.Resources {
+		if service := resource.GetService(); service != nil {
+			service.Env = nil
+		}
+
+		if policy := resource.GetPolicy(); policy != nil {
+			resource.Id.Name = ""
+
+			sortResources(policy.Principals)
+			sortResources(policy.Resources)
+			slices.Sort(policy.Actions)
+		}
+	}
+
+	sortResources(canonical.Resources)
+
+	protoJson, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson output isn't stable between versions, so it's re-encoded with sorted keys and consistent indentation
+	var doc interface{}
+	if err := json.Unmarshal(protoJson, &doc); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(snapshot, '\n'), nil
+}
+
+// DiffSpecSnapshots - returns a unified diff between two spec snapshots, or an empty string if they're the same
+func DiffSpecSnapshots(expectedName string, expected []byte, actualName string, actual []byte) string {
+	if string(expected) == string(actual) {
+		return ""
+	}
+
+	edits := myers.ComputeEdits(span.URIFromPath(expectedName), string(expected), string(actual))
+
+	return fmt.Sprint(gotextdiff.ToUnified(expectedName, actualName, string(expected), edits))
+}
@@ -953,7 +953,7 @@ func ServiceRequirementsToSpec(projectName string, environmentVariables map[stri
 				Service: &deploymentspb.Service{
 					Source: &deploymentspb.Service_Image{
 						Image: &deploymentspb.ImageSource{
-							Uri: fmt.Sprintf(serviceRequirements.serviceName),
+							Uri: serviceRequirements.image,
 						},
 					},
 					Workers: int32(serviceRequirements.WorkerCount()),
@@ -0,0 +1,367 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	kvstorepb "github.com/nitrictech/nitric/core/pkg/proto/kvstore/v1"
+	queuespb "github.com/nitrictech/nitric/core/pkg/proto/queues/v1"
+	secretspb "github.com/nitrictech/nitric/core/pkg/proto/secrets/v1"
+	storagepb "github.com/nitrictech/nitric/core/pkg/proto/storage/v1"
+	topicspb "github.com/nitrictech/nitric/core/pkg/proto/topics/v1"
+)
+
+// largeValueSize - larger than the item size limits of managed key/value stores, e.g. 400KB for DynamoDB
+const largeValueSize = 512 * 1024
+
+var suite = []Case{
+	{
+		Name:        "kv/get-missing-key",
+		Description: "get a key that was never set",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := kvstorepb.NewKvStoreClient(r.conn).GetValue(ctx, &kvstorepb.KvStoreGetValueRequest{
+				Ref: &kvstorepb.ValueRef{Store: r.resources.KeyValueStore, Key: r.key("missing")},
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "kv/set-get",
+		Description: "set a value and get it back",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := kvstorepb.NewKvStoreClient(r.conn)
+			ref := &kvstorepb.ValueRef{Store: r.resources.KeyValueStore, Key: r.key("set-get")}
+
+			content, err := structpb.NewStruct(map[string]any{"message": "hello", "count": 1})
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err := client.SetValue(ctx, &kvstorepb.KvStoreSetValueRequest{Ref: ref, Content: content}); err != nil {
+				return nil, err
+			}
+
+			resp, err := client.GetValue(ctx, &kvstorepb.KvStoreGetValueRequest{Ref: ref})
+			if err != nil {
+				return nil, err
+			}
+
+			fields := resp.GetValue().GetContent().AsMap()
+
+			return []string{valueString(fields["message"]), valueString(fields["count"])}, nil
+		},
+	},
+	{
+		Name:        "kv/delete-missing-key",
+		Description: "delete a key that was never set",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := kvstorepb.NewKvStoreClient(r.conn).DeleteKey(ctx, &kvstorepb.KvStoreDeleteKeyRequest{
+				Ref: &kvstorepb.ValueRef{Store: r.resources.KeyValueStore, Key: r.key("missing")},
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "kv/scan-keys-order",
+		Description: "scan keys set out of order, reporting the order keys are returned in",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := kvstorepb.NewKvStoreClient(r.conn)
+			prefix := r.key("scan/")
+
+			for _, key := range []string{"c", "a", "b"} {
+				_, err := client.SetValue(ctx, &kvstorepb.KvStoreSetValueRequest{
+					Ref:     &kvstorepb.ValueRef{Store: r.resources.KeyValueStore, Key: prefix + key},
+					Content: &structpb.Struct{},
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			stream, err := client.ScanKeys(ctx, &kvstorepb.KvStoreScanKeysRequest{
+				Store:  &kvstorepb.Store{Name: r.resources.KeyValueStore},
+				Prefix: prefix,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			keys := []string{}
+
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return keys, nil
+				}
+
+				if err != nil {
+					return keys, err
+				}
+
+				keys = append(keys, strings.TrimPrefix(resp.Key, prefix))
+			}
+		},
+	},
+	{
+		Name:        "kv/large-value",
+		Description: "set a value larger than the item size limits of managed key/value stores",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			content, err := structpb.NewStruct(map[string]any{"data": strings.Repeat("x", largeValueSize)})
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = kvstorepb.NewKvStoreClient(r.conn).SetValue(ctx, &kvstorepb.KvStoreSetValueRequest{
+				Ref:     &kvstorepb.ValueRef{Store: r.resources.KeyValueStore, Key: r.key("large")},
+				Content: content,
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "storage/read-missing",
+		Description: "read a blob that was never written",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := storagepb.NewStorageClient(r.conn).Read(ctx, &storagepb.StorageReadRequest{
+				BucketName: r.resources.Bucket,
+				Key:        r.key("missing"),
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "storage/exists-missing",
+		Description: "check whether a blob that was never written exists",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			resp, err := storagepb.NewStorageClient(r.conn).Exists(ctx, &storagepb.StorageExistsRequest{
+				BucketName: r.resources.Bucket,
+				Key:        r.key("missing"),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return []string{strconv.FormatBool(resp.Exists)}, nil
+		},
+	},
+	{
+		Name:        "storage/write-read",
+		Description: "write a blob and read it back",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := storagepb.NewStorageClient(r.conn)
+			key := r.key("write-read.txt")
+
+			_, err := client.Write(ctx, &storagepb.StorageWriteRequest{BucketName: r.resources.Bucket, Key: key, Body: []byte("hello")})
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := client.Read(ctx, &storagepb.StorageReadRequest{BucketName: r.resources.Bucket, Key: key})
+			if err != nil {
+				return nil, err
+			}
+
+			return []string{string(resp.Body)}, nil
+		},
+	},
+	{
+		Name:        "storage/list-order",
+		Description: "list blobs written out of order, reporting the order blobs are returned in",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := storagepb.NewStorageClient(r.conn)
+			prefix := r.key("list/")
+
+			for _, key := range []string{"c.txt", "a.txt", "b/d.txt"} {
+				_, err := client.Write(ctx, &storagepb.StorageWriteRequest{BucketName: r.resources.Bucket, Key: prefix + key, Body: []byte(key)})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			resp, err := client.ListBlobs(ctx, &storagepb.StorageListBlobsRequest{BucketName: r.resources.Bucket, Prefix: prefix})
+			if err != nil {
+				return nil, err
+			}
+
+			keys := []string{}
+			for _, blob := range resp.Blobs {
+				keys = append(keys, strings.TrimPrefix(blob.Key, prefix))
+			}
+
+			return keys, nil
+		},
+	},
+	{
+		Name:        "storage/delete-missing",
+		Description: "delete a blob that was never written",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := storagepb.NewStorageClient(r.conn).Delete(ctx, &storagepb.StorageDeleteRequest{
+				BucketName: r.resources.Bucket,
+				Key:        r.key("missing"),
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "queues/dequeue-order",
+		Description: "enqueue messages in a single request and dequeue them, reporting the order they're received in",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := queuespb.NewQueuesClient(r.conn)
+
+			messages := []*queuespb.QueueMessage{}
+
+			for i := 1; i <= 3; i++ {
+				payload, err := structpb.NewStruct(map[string]any{"run": r.prefix, "index": i})
+				if err != nil {
+					return nil, err
+				}
+
+				messages = append(messages, &queuespb.QueueMessage{Content: &queuespb.QueueMessage_StructPayload{StructPayload: payload}})
+			}
+
+			enqueued, err := client.Enqueue(ctx, &queuespb.QueueEnqueueRequest{QueueName: r.resources.Queue, Messages: messages})
+			if err != nil {
+				return nil, err
+			}
+
+			if len(enqueued.FailedMessages) > 0 {
+				return nil, errors.New(enqueued.FailedMessages[0].Details)
+			}
+
+			received := []string{}
+
+			// messages may not all be available to the first dequeue, e.g. with distributed queues
+			for attempt := 0; attempt < 5 && len(received) < len(messages); attempt++ {
+				resp, err := client.Dequeue(ctx, &queuespb.QueueDequeueRequest{QueueName: r.resources.Queue, Depth: 10})
+				if err != nil {
+					return received, err
+				}
+
+				for _, message := range resp.Messages {
+					fields := message.GetMessage().GetStructPayload().AsMap()
+
+					// messages from other runs are left to become visible again once their lease expires
+					if fields["run"] != r.prefix {
+						continue
+					}
+
+					received = append(received, valueString(fields["index"]))
+
+					_, err := client.Complete(ctx, &queuespb.QueueCompleteRequest{QueueName: r.resources.Queue, LeaseId: message.LeaseId})
+					if err != nil {
+						return received, err
+					}
+				}
+
+				if len(resp.Messages) == 0 {
+					time.Sleep(time.Second)
+				}
+			}
+
+			return received, nil
+		},
+	},
+	{
+		Name:        "queues/dequeue-depth-limit",
+		Description: "dequeue more messages at once than managed queues allow, e.g. 10 for SQS",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := queuespb.NewQueuesClient(r.conn).Dequeue(ctx, &queuespb.QueueDequeueRequest{QueueName: r.resources.Queue, Depth: 11})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "secrets/put-access-latest",
+		Description: "put a secret value and access the latest version",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			client := secretspb.NewSecretManagerClient(r.conn)
+			secret := &secretspb.Secret{Name: r.resources.Secret}
+
+			_, err := client.Put(ctx, &secretspb.SecretPutRequest{Secret: secret, Value: []byte(r.prefix)})
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := client.Access(ctx, &secretspb.SecretAccessRequest{SecretVersion: &secretspb.SecretVersion{Secret: secret, Version: "latest"}})
+			if err != nil {
+				return nil, err
+			}
+
+			return []string{strconv.FormatBool(string(resp.Value) == r.prefix)}, nil
+		},
+	},
+	{
+		Name:        "secrets/access-missing-version",
+		Description: "access a version of a secret that doesn't exist",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := secretspb.NewSecretManagerClient(r.conn).Access(ctx, &secretspb.SecretAccessRequest{
+				SecretVersion: &secretspb.SecretVersion{Secret: &secretspb.Secret{Name: r.resources.Secret}, Version: "999999"},
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "topics/publish",
+		Description: "publish a message to a topic",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := topicspb.NewTopicsClient(r.conn).Publish(ctx, &topicspb.TopicPublishRequest{
+				TopicName: r.resources.Topic,
+				Message:   &topicspb.TopicMessage{Content: &topicspb.TopicMessage_StructPayload{StructPayload: &structpb.Struct{}}},
+			})
+
+			return nil, err
+		},
+	},
+	{
+		Name:        "topics/publish-delay-limit",
+		Description: "publish a message with a delay longer than managed topics allow, e.g. 15 minutes for SNS and SQS",
+		run: func(ctx context.Context, r run) ([]string, error) {
+			_, err := topicspb.NewTopicsClient(r.conn).Publish(ctx, &topicspb.TopicPublishRequest{
+				TopicName: r.resources.Topic,
+				Message:   &topicspb.TopicMessage{Content: &topicspb.TopicMessage_StructPayload{StructPayload: &structpb.Struct{}}},
+				Delay:     durationpb.New(24 * time.Hour),
+			})
+
+			return nil, err
+		},
+	},
+}
+
+// valueString - formats values decoded from structs, numbers are decoded as floats so they're formatted without exponents
+func valueString(value any) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
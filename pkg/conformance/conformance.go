@@ -0,0 +1,186 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// DefaultResourceName - the name of the bucket, key/value store, queue, secret and topic used by the suite
+const DefaultResourceName = "conformance"
+
+// caseTimeout - how long each case can run before it's reported as timing out
+const caseTimeout = 30 * time.Second
+
+// Resources - the names of the resources the suite runs operations against, these must exist in deployed stacks
+type Resources struct {
+	Bucket        string `json:"bucket"`
+	KeyValueStore string `json:"keyValueStore"`
+	Queue         string `json:"queue"`
+	Secret        string `json:"secret"`
+	Topic         string `json:"topic"`
+}
+
+// NewResources - uses the same name for each type of resource
+func NewResources(name string) Resources {
+	return Resources{
+		Bucket:        name,
+		KeyValueStore: name,
+		Queue:         name,
+		Secret:        name,
+		Topic:         name,
+	}
+}
+
+// run - the context of a single run of the suite
+type run struct {
+	conn      grpc.ClientConnInterface
+	resources Resources
+	// prefixes keys and blobs written by the run, so runs against a deployed stack don't see each other's data
+	prefix string
+}
+
+func (r run) key(name string) string {
+	return r.prefix + name
+}
+
+type Case struct {
+	Name        string
+	Description string
+	// run performs the case's operations, returning the observed values, e.g. keys in the order they were listed
+	run func(ctx context.Context, r run) ([]string, error)
+}
+
+// Result - the observed behavior of a case, results of the same case are expected to match across targets
+type Result struct {
+	Case string `json:"case"`
+	// The gRPC status code returned by the operation under test, OK when it succeeded
+	Code   string   `json:"code"`
+	Values []string `json:"values,omitempty"`
+	// The error message, messages are reported but not compared since they differ between providers
+	Message string `json:"message,omitempty"`
+}
+
+// Difference - a case that behaved differently on the local cloud and the deployed stack
+type Difference struct {
+	Case   string `json:"case"`
+	Local  Result `json:"local"`
+	Remote Result `json:"remote"`
+}
+
+type Report struct {
+	Resources   Resources    `json:"resources"`
+	Local       []Result     `json:"local"`
+	Remote      []Result     `json:"remote"`
+	Differences []Difference `json:"differences"`
+}
+
+// Cases - the cases of the suite, in the order they run
+func Cases() []Case {
+	return slices.Clone(suite)
+}
+
+// Declare - declares the resources used by the suite, deployed runtimes typically ignore declarations of existing resources
+func Declare(ctx context.Context, conn grpc.ClientConnInterface, resources Resources) error {
+	client := resourcespb.NewResourcesClient(conn)
+
+	declarations := []*resourcespb.ResourceDeclareRequest{
+		{
+			Id:     &resourcespb.ResourceIdentifier{Type: resourcespb.ResourceType_Bucket, Name: resources.Bucket},
+			Config: &resourcespb.ResourceDeclareRequest_Bucket{Bucket: &resourcespb.BucketResource{}},
+		},
+		{
+			Id:     &resourcespb.ResourceIdentifier{Type: resourcespb.ResourceType_KeyValueStore, Name: resources.KeyValueStore},
+			Config: &resourcespb.ResourceDeclareRequest_KeyValueStore{KeyValueStore: &resourcespb.KeyValueStoreResource{}},
+		},
+		{
+			Id:     &resourcespb.ResourceIdentifier{Type: resourcespb.ResourceType_Queue, Name: resources.Queue},
+			Config: &resourcespb.ResourceDeclareRequest_Queue{Queue: &resourcespb.QueueResource{}},
+		},
+		{
+			Id:     &resourcespb.ResourceIdentifier{Type: resourcespb.ResourceType_Secret, Name: resources.Secret},
+			Config: &resourcespb.ResourceDeclareRequest_Secret{Secret: &resourcespb.SecretResource{}},
+		},
+		{
+			Id:     &resourcespb.ResourceIdentifier{Type: resourcespb.ResourceType_Topic, Name: resources.Topic},
+			Config: &resourcespb.ResourceDeclareRequest_Topic{Topic: &resourcespb.TopicResource{}},
+		},
+	}
+
+	for _, declaration := range declarations {
+		if _, err := client.Declare(ctx, declaration); err != nil {
+			return fmt.Errorf("unable to declare %s %s: %w", declaration.Id.Type, declaration.Id.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Run - runs each case of the suite against the nitric server on the connection
+func Run(ctx context.Context, conn grpc.ClientConnInterface, resources Resources) []Result {
+	r := run{
+		conn:      conn,
+		resources: resources,
+		prefix:    "conformance-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "/",
+	}
+
+	results := []Result{}
+
+	for _, c := range suite {
+		caseCtx, cancel := context.WithTimeout(ctx, caseTimeout)
+		values, err := c.run(caseCtx, r)
+
+		cancel()
+
+		results = append(results, Result{
+			Case:    c.Name,
+			Code:    status.Code(err).String(),
+			Values:  values,
+			Message: status.Convert(err).Message(),
+		})
+	}
+
+	return results
+}
+
+// Compare - returns the cases with results that differ in their status code or observed values
+func Compare(local []Result, remote []Result) []Difference {
+	differences := []Difference{}
+
+	for _, l := range local {
+		idx := slices.IndexFunc(remote, func(r Result) bool { return r.Case == l.Case })
+		if idx < 0 {
+			continue
+		}
+
+		r := remote[idx]
+		if l.Code != r.Code || !slices.Equal(l.Values, r.Values) {
+			differences = append(differences, Difference{Case: l.Case, Local: l, Remote: r})
+		}
+	}
+
+	return differences
+}
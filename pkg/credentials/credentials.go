@@ -0,0 +1,250 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// helper - describes how to detect, log in with and verify the credentials used by a provider
+type helper struct {
+	// Display name of the cloud
+	name string
+	// Name of the cloud's CLI, used to log in and verify access
+	cli        string
+	installUrl string
+	// Environment variables that provide credentials
+	envVars []string
+	// Credential files, relative to the user's home directory
+	files []string
+	login func() []string
+	// A lightweight call that fails when the credentials are missing or invalid
+	verify []string
+	// Returns the identity the credentials belong to, defaults to the output of verify
+	identity []string
+}
+
+var helpers = map[string]helper{
+	"aws": {
+		name:       "AWS",
+		cli:        "aws",
+		installUrl: "https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+		envVars:    []string{"AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_SESSION_TOKEN"},
+		files:      []string{".aws/credentials", ".aws/config"},
+		login: func() []string {
+			// use an existing SSO configuration if there is one, otherwise configure one
+			config, err := os.ReadFile(filepath.Join(homeDir(), ".aws", "config"))
+			if err == nil && (bytes.Contains(config, []byte("sso_start_url")) || bytes.Contains(config, []byte("sso_session"))) {
+				return []string{"aws", "sso", "login"}
+			}
+
+			return []string{"aws", "configure", "sso"}
+		},
+		verify: []string{"aws", "sts", "get-caller-identity", "--query", "Arn", "--output", "text"},
+	},
+	"gcp": {
+		name:       "Google Cloud",
+		cli:        "gcloud",
+		installUrl: "https://cloud.google.com/sdk/docs/install",
+		envVars:    []string{"GOOGLE_APPLICATION_CREDENTIALS", "GOOGLE_CREDENTIALS"},
+		files:      []string{".config/gcloud/application_default_credentials.json", "AppData/Roaming/gcloud/application_default_credentials.json"},
+		login: func() []string {
+			return []string{"gcloud", "auth", "application-default", "login"}
+		},
+		verify:   []string{"gcloud", "auth", "application-default", "print-access-token"},
+		identity: []string{"gcloud", "config", "get-value", "account"},
+	},
+	"azure": {
+		name:       "Azure",
+		cli:        "az",
+		installUrl: "https://learn.microsoft.com/en-us/cli/azure/install-azure-cli",
+		envVars:    []string{"ARM_CLIENT_ID", "AZURE_CLIENT_ID"},
+		files:      []string{".azure/azureProfile.json"},
+		login: func() []string {
+			return []string{"az", "login"}
+		},
+		verify: []string{"az", "account", "show", "--query", "user.name", "--output", "tsv"},
+	},
+	"do": {
+		name:       "DigitalOcean",
+		cli:        "doctl",
+		installUrl: "https://docs.digitalocean.com/reference/doctl/how-to/install/",
+		envVars:    []string{"DIGITALOCEAN_TOKEN", "DIGITALOCEAN_ACCESS_TOKEN"},
+		files:      []string{".config/doctl/config.yaml", "Library/Application Support/doctl/config.yaml"},
+		login: func() []string {
+			return []string{"doctl", "auth", "init"}
+		},
+		verify: []string{"doctl", "account", "get", "--format", "Email", "--no-header"},
+	},
+	"cloudflare": {
+		name:       "Cloudflare",
+		cli:        "wrangler",
+		installUrl: "https://developers.cloudflare.com/workers/wrangler/install-and-update/",
+		envVars:    []string{"CLOUDFLARE_API_TOKEN"},
+		files:      []string{".config/.wrangler/config/default.toml", "Library/Preferences/.wrangler/config/default.toml", ".wrangler/config/default.toml"},
+		login: func() []string {
+			return []string{"wrangler", "login"}
+		},
+		verify: []string{"wrangler", "whoami"},
+	},
+}
+
+// cloudName - maps a stack provider name to the cloud it deploys to, e.g. aws-tf -> aws
+func cloudName(provider string) string {
+	return strings.TrimSuffix(provider, "-tf")
+}
+
+func getHelper(provider string) (helper, error) {
+	h, ok := helpers[cloudName(provider)]
+	if !ok {
+		return helper{}, fmt.Errorf("credential setup is not supported for provider %s", provider)
+	}
+
+	return h, nil
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return home
+}
+
+// Supported - returns true if guided credential setup is available for the provider
+func Supported(provider string) bool {
+	_, err := getHelper(provider)
+	return err == nil
+}
+
+// Name - returns the display name of the cloud the provider deploys to
+func Name(provider string) string {
+	h, err := getHelper(provider)
+	if err != nil {
+		return provider
+	}
+
+	return h.name
+}
+
+// Detect - returns true if credentials for the provider appear to be configured, either in the environment or in the cloud CLI's config files
+func Detect(provider string) bool {
+	h, err := getHelper(provider)
+	if err != nil {
+		return false
+	}
+
+	for _, envVar := range h.envVars {
+		if os.Getenv(envVar) != "" {
+			return true
+		}
+	}
+
+	for _, file := range h.files {
+		if _, err := os.Stat(filepath.Join(homeDir(), file)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoginCommand - returns the cloud CLI command used to log in, which may open a browser for SSO
+func LoginCommand(provider string) (string, error) {
+	h, err := getHelper(provider)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(h.login(), " "), nil
+}
+
+func (h helper) checkCli() error {
+	if _, err := exec.LookPath(h.cli); err != nil {
+		return fmt.Errorf("the %s CLI is required to set up %s credentials. For installation instructions see: %s", h.cli, h.name, h.installUrl)
+	}
+
+	return nil
+}
+
+// Login - runs the cloud CLI's interactive login, attached to the current terminal
+func Login(provider string) error {
+	h, err := getHelper(provider)
+	if err != nil {
+		return err
+	}
+
+	if err := h.checkCli(); err != nil {
+		return err
+	}
+
+	login := h.login()
+
+	cmd := exec.Command(login[0], login[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", strings.Join(login, " "), err)
+	}
+
+	return nil
+}
+
+func run(args []string) (string, error) {
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Verify - makes a lightweight call to the cloud to check the credentials work, returning the identity they belong to
+func Verify(provider string) (string, error) {
+	h, err := getHelper(provider)
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.checkCli(); err != nil {
+		return "", err
+	}
+
+	identity, err := run(h.verify)
+	if err != nil {
+		return "", err
+	}
+
+	if h.identity != nil {
+		return run(h.identity)
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,372 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultIdTokenEnv - the environment variable holding the OIDC token of CI systems other than GitHub Actions,
+// e.g. declared with id_tokens in .gitlab-ci.yml
+const DefaultIdTokenEnv = "NITRIC_ID_TOKEN"
+
+// Federation - how the OIDC token of a CI job is exchanged for short-lived cloud credentials
+type Federation struct {
+	// Audience of the CI's token, defaults to the audience expected by the cloud
+	Audience string
+	// Environment variable holding the token when it isn't requested from GitHub Actions
+	TokenEnv string
+	// AWS - the IAM role assumed with the token
+	RoleArn string
+	// Google Cloud - the full name of the workload identity pool provider and the service account impersonated
+	WorkloadIdentityProvider string
+	ServiceAccount           string
+	// Azure - the application (client) and tenant the token is federated with
+	ClientId string
+	TenantId string
+}
+
+// the endpoints tokens are exchanged with
+var (
+	awsStsEndpoint       = "https://sts.amazonaws.com/"
+	gcpStsEndpoint       = "https://sts.googleapis.com/v1/token"
+	gcpIamEndpoint       = "https://iamcredentials.googleapis.com/v1"
+	azureLoginEndpoint   = "https://login.microsoftonline.com"
+	federationHttpClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+const gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// FederationCloud - returns the cloud a stack provider deploys to, e.g. nitric/aws@1.1.0 or nitric/awstf@1.1.0 -> aws,
+// or an empty string if token exchange isn't supported for it
+func FederationCloud(providerId string) string {
+	name, _, _ := strings.Cut(providerId, "@")
+
+	parts := strings.Split(name, "/")
+	cloud := strings.TrimSuffix(cloudName(parts[len(parts)-1]), "tf")
+
+	switch cloud {
+	case "aws", "gcp", "azure":
+		return cloud
+	default:
+		return ""
+	}
+}
+
+func (f Federation) tokenEnv() string {
+	if f.TokenEnv != "" {
+		return f.TokenEnv
+	}
+
+	return DefaultIdTokenEnv
+}
+
+func (f Federation) audience(cloud string) string {
+	if f.Audience != "" {
+		return f.Audience
+	}
+
+	switch cloud {
+	case "aws":
+		return "sts.amazonaws.com"
+	case "gcp":
+		return "//iam.googleapis.com/" + f.WorkloadIdentityProvider
+	case "azure":
+		return "api://AzureADTokenExchange"
+	default:
+		return ""
+	}
+}
+
+// IdTokenAvailable - returns true if the current process runs in a CI job that provides an OIDC token
+func (f Federation) IdTokenAvailable() bool {
+	if os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" && os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") != "" {
+		return true
+	}
+
+	return os.Getenv(f.tokenEnv()) != ""
+}
+
+// idToken - returns the OIDC token of the current CI job for the audience
+func (f Federation) idToken(audience string) (string, error) {
+	if token := os.Getenv(f.tokenEnv()); token != "" {
+		return token, nil
+	}
+
+	requestUrl := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+
+	if requestUrl == "" || requestToken == "" {
+		return "", fmt.Errorf("no OIDC token found, set %s or run in GitHub Actions with the id-token: write permission", f.tokenEnv())
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requestUrl+"&audience="+url.QueryEscape(audience), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+
+	body, err := doFederationRequest(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to request an OIDC token from GitHub Actions: %w", err)
+	}
+
+	response := struct {
+		Value string `json:"value"`
+	}{}
+
+	if err := json.Unmarshal(body, &response); err != nil || response.Value == "" {
+		return "", fmt.Errorf("unable to read the OIDC token returned by GitHub Actions")
+	}
+
+	return response.Value, nil
+}
+
+func doFederationRequest(req *http.Request) ([]byte, error) {
+	resp, err := federationHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		return body, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+// Validate - checks the federation has the settings required by the cloud
+func (f Federation) Validate(cloud string) error {
+	switch cloud {
+	case "aws":
+		if f.RoleArn == "" {
+			return fmt.Errorf("oidc requires the role-arn of the IAM role to assume")
+		}
+	case "gcp":
+		if f.WorkloadIdentityProvider == "" {
+			return fmt.Errorf("oidc requires the workload-identity-provider, e.g. projects/<number>/locations/global/workloadIdentityPools/<pool>/providers/<provider>")
+		}
+	case "azure":
+		if f.ClientId == "" || f.TenantId == "" {
+			return fmt.Errorf("oidc requires the client-id and tenant-id of the application with the federated credential")
+		}
+	default:
+		return fmt.Errorf("oidc is only supported for aws, gcp and azure stacks")
+	}
+
+	return nil
+}
+
+// Exchange - exchanges the OIDC token of the current CI job for short-lived credentials for the cloud,
+// returning the environment variables that provide them to the provider
+func (f Federation) Exchange(cloud string) (map[string]string, error) {
+	if err := f.Validate(cloud); err != nil {
+		return nil, err
+	}
+
+	token, err := f.idToken(f.audience(cloud))
+	if err != nil {
+		return nil, err
+	}
+
+	switch cloud {
+	case "aws":
+		return f.exchangeAws(token)
+	case "gcp":
+		return f.exchangeGcp(token)
+	default:
+		return f.exchangeAzure(token)
+	}
+}
+
+// exchangeAws - assumes the role with the token, the request is authorized by the token so it isn't signed
+func (f Federation) exchangeAws(token string) (map[string]string, error) {
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {f.RoleArn},
+		"RoleSessionName":  {"nitric-" + time.Now().UTC().Format("20060102T150405")},
+		"WebIdentityToken": {token},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, awsStsEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := doFederationRequest(req)
+	if err != nil {
+		stsErr := struct {
+			Message string `xml:"Error>Message"`
+		}{}
+
+		if xml.Unmarshal(body, &stsErr) == nil && stsErr.Message != "" {
+			return nil, fmt.Errorf("unable to assume role %s: %s", f.RoleArn, stsErr.Message)
+		}
+
+		return nil, fmt.Errorf("unable to assume role %s: %w", f.RoleArn, err)
+	}
+
+	response := struct {
+		AccessKeyId     string `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+		SecretAccessKey string `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+		SessionToken    string `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+	}{}
+
+	if err := xml.Unmarshal(body, &response); err != nil || response.AccessKeyId == "" {
+		return nil, fmt.Errorf("unable to read the credentials returned for role %s", f.RoleArn)
+	}
+
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     response.AccessKeyId,
+		"AWS_SECRET_ACCESS_KEY": response.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     response.SessionToken,
+		// credentials from the environment take precedence over profiles, clear it so they aren't confused
+		"AWS_PROFILE": "",
+	}, nil
+}
+
+func postJson(endpoint string, bearer string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	respBody, err := doFederationRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(respBody, response)
+}
+
+// exchangeGcp - exchanges the token for a federated access token, impersonating the service account when one is set
+func (f Federation) exchangeGcp(token string) (map[string]string, error) {
+	stsResponse := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err := postJson(gcpStsEndpoint, "", map[string]string{
+		"grantType":          "urn:ietf:params:oauth:grant-type:token-exchange",
+		"audience":           "//iam.googleapis.com/" + f.WorkloadIdentityProvider,
+		"scope":              gcpCloudPlatformScope,
+		"requestedTokenType": "urn:ietf:params:oauth:token-type:access_token",
+		"subjectTokenType":   "urn:ietf:params:oauth:token-type:jwt",
+		"subjectToken":       token,
+	}, &stsResponse)
+	if err != nil {
+		return nil, fmt.Errorf("unable to exchange the OIDC token with %s: %w", f.WorkloadIdentityProvider, err)
+	}
+
+	accessToken := stsResponse.AccessToken
+
+	if f.ServiceAccount != "" {
+		iamResponse := struct {
+			AccessToken string `json:"accessToken"`
+		}{}
+
+		err := postJson(fmt.Sprintf("%s/projects/-/serviceAccounts/%s:generateAccessToken", gcpIamEndpoint, f.ServiceAccount), accessToken, map[string]interface{}{
+			"scope": []string{gcpCloudPlatformScope},
+		}, &iamResponse)
+		if err != nil {
+			return nil, fmt.Errorf("unable to impersonate service account %s: %w", f.ServiceAccount, err)
+		}
+
+		accessToken = iamResponse.AccessToken
+	}
+
+	if accessToken == "" {
+		return nil, fmt.Errorf("no access token was returned by %s", f.WorkloadIdentityProvider)
+	}
+
+	return map[string]string{
+		"GOOGLE_OAUTH_ACCESS_TOKEN": accessToken,
+		// credential files take precedence over the access token in some tools, clear them
+		"GOOGLE_APPLICATION_CREDENTIALS": "",
+		"GOOGLE_CREDENTIALS":             "",
+	}, nil
+}
+
+// exchangeAzure - checks the token is accepted for the application, the azure providers exchange it themselves
+// as their access tokens are scoped to a single resource
+func (f Federation) exchangeAzure(token string) (map[string]string, error) {
+	form := url.Values{
+		"client_id":             {f.ClientId},
+		"scope":                 {"https://management.azure.com/.default"},
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {token},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s/oauth2/v2.0/token", azureLoginEndpoint, url.PathEscape(f.TenantId)), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := doFederationRequest(req)
+	if err != nil {
+		azureErr := struct {
+			Description string `json:"error_description"`
+		}{}
+
+		if json.Unmarshal(body, &azureErr) == nil && azureErr.Description != "" {
+			return nil, fmt.Errorf("unable to exchange the OIDC token for application %s: %s", f.ClientId, azureErr.Description)
+		}
+
+		return nil, fmt.Errorf("unable to exchange the OIDC token for application %s: %w", f.ClientId, err)
+	}
+
+	return map[string]string{
+		"ARM_USE_OIDC":    "true",
+		"ARM_OIDC_TOKEN":  token,
+		"ARM_CLIENT_ID":   f.ClientId,
+		"ARM_TENANT_ID":   f.TenantId,
+		"AZURE_CLIENT_ID": f.ClientId,
+		"AZURE_TENANT_ID": f.TenantId,
+	}, nil
+}
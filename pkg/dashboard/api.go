@@ -0,0 +1,396 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/project"
+)
+
+// maxLogEntries - the number of log lines kept in memory for the dashboard api
+const maxLogEntries = 1000
+
+// LogEntry - a line of output from a service, or from the CLI (service "nitric"), during a local run
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Status  string    `json:"status"`
+	Message string    `json:"message"`
+	// Detected from common log formats, see project.DetectLogLevel, stderr output without a recognizable level is error
+	Level string `json:"level,omitempty"`
+}
+
+// logLevel - returns the level of an update's message, stderr output without a detected level is treated as an error,
+// so stack traces aren't hidden by filtering
+func logLevel(update project.ServiceRunUpdate) project.LogLevel {
+	level, ok := project.DetectLogLevel(update.Message)
+	if !ok && update.Status == project.ServiceRunStatus_Error {
+		return project.LogLevel_Error
+	}
+
+	return level
+}
+
+type apiResource struct {
+	Name               string   `json:"name"`
+	RequestingServices []string `json:"requestingServices,omitempty"`
+	Address            string   `json:"address,omitempty"`
+}
+
+type apiSchedule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression,omitempty"`
+	Rate       string `json:"rate,omitempty"`
+	Target     string `json:"target,omitempty"`
+}
+
+type apiSubscription struct {
+	Topic  string `json:"topic"`
+	Target string `json:"target"`
+}
+
+// ResourcesResponse - the resources declared by the services of a local run, returned by GET /api/v1/resources
+type ResourcesResponse struct {
+	ProjectName   string            `json:"projectName"`
+	Services      []string          `json:"services"`
+	Apis          []apiResource     `json:"apis"`
+	Websockets    []apiResource     `json:"websockets"`
+	HttpProxies   []apiResource     `json:"httpProxies"`
+	Topics        []apiResource     `json:"topics"`
+	Subscriptions []apiSubscription `json:"subscriptions"`
+	Schedules     []apiSchedule     `json:"schedules"`
+	Buckets       []apiResource     `json:"buckets"`
+	Queues        []apiResource     `json:"queues"`
+	Stores        []apiResource     `json:"stores"`
+	SQLDatabases  []apiResource     `json:"sqlDatabases"`
+	Secrets       []apiResource     `json:"secrets"`
+}
+
+// CaptureLogs - keeps the most recent service and CLI output for the dashboard api, forwarding every update
+// output below the log level a service sets in nitric.yaml is dropped
+func (d *Dashboard) CaptureLogs(updates <-chan project.ServiceRunUpdate) <-chan project.ServiceRunUpdate {
+	forwarded := make(chan project.ServiceRunUpdate)
+	logLevels := d.project.ServiceLogLevels()
+
+	go func() {
+		defer close(forwarded)
+
+		for update := range updates {
+			level := logLevel(update)
+
+			// messages from the CLI and exit errors are always kept
+			if minimum, ok := logLevels[update.ServiceName]; ok && update.Label != "nitric" && update.Err == nil && !minimum.Includes(level) {
+				continue
+			}
+
+			d.logsLock.Lock()
+
+			d.logs = append(d.logs, LogEntry{
+				Time:    time.Now(),
+				Service: update.ServiceName,
+				Status:  string(update.Status),
+				Message: strings.TrimRight(update.Message, "\n"),
+				Level:   string(level),
+			})
+
+			if len(d.logs) > maxLogEntries {
+				d.logs = d.logs[len(d.logs)-maxLogEntries:]
+			}
+
+			d.logsLock.Unlock()
+
+			forwarded <- update
+		}
+	}()
+
+	return forwarded
+}
+
+func toApiResources[T any](specs []T, base func(T) *BaseResourceSpec, addresses map[string]string) []apiResource {
+	return lo.Map(specs, func(spec T, _ int) apiResource {
+		b := base(spec)
+
+		return apiResource{
+			Name:               b.Name,
+			RequestingServices: b.RequestingServices,
+			Address:            addresses[b.Name],
+		}
+	})
+}
+
+func (d *Dashboard) resourcesResponse() *ResourcesResponse {
+	d.resourcesLock.Lock()
+	defer d.resourcesLock.Unlock()
+
+	noAddresses := map[string]string{}
+
+	return &ResourcesResponse{
+		ProjectName: d.project.Name,
+		Services: lo.Map(d.project.GetServices(), func(service project.Service, _ int) string {
+			return service.GetFilePath()
+		}),
+		Apis:        toApiResources(d.apis, func(s ApiSpec) *BaseResourceSpec { return s.BaseResourceSpec }, d.gatewayService.GetApiAddresses()),
+		Websockets:  toApiResources(d.websockets, func(s WebsocketSpec) *BaseResourceSpec { return s.BaseResourceSpec }, d.gatewayService.GetWebsocketAddresses()),
+		HttpProxies: toApiResources(d.httpProxies, func(s *HttpProxySpec) *BaseResourceSpec { return s.BaseResourceSpec }, d.gatewayService.GetHttpWorkerAddresses()),
+		Topics:      toApiResources(d.topics, func(s *TopicSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+		Subscriptions: lo.Map(d.subscriptions, func(s *SubscriberSpec, _ int) apiSubscription {
+			return apiSubscription{Topic: s.Topic, Target: s.Target}
+		}),
+		Schedules: lo.Map(d.schedules, func(s ScheduleSpec, _ int) apiSchedule {
+			return apiSchedule{Name: s.Name, Expression: s.Expression, Rate: s.Rate, Target: s.Target}
+		}),
+		Buckets:      toApiResources(d.buckets, func(s *BucketSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+		Queues:       toApiResources(d.queues, func(s *QueueSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+		Stores:       toApiResources(d.stores, func(s *KeyValueSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+		SQLDatabases: toApiResources(d.sqlDatabases, func(s *SQLDatabaseSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+		Secrets:      toApiResources(d.secrets, func(s *SecretSpec) *BaseResourceSpec { return s.BaseResourceSpec }, noAddresses),
+	}
+}
+
+func (d *Dashboard) hasTopic(name string) bool {
+	d.resourcesLock.Lock()
+	defer d.resourcesLock.Unlock()
+
+	return lo.ContainsBy(d.topics, func(t *TopicSpec) bool { return t.Name == name })
+}
+
+func (d *Dashboard) hasSchedule(name string) bool {
+	d.resourcesLock.Lock()
+	defer d.resourcesLock.Unlock()
+
+	return lo.ContainsBy(d.schedules, func(s ScheduleSpec) bool { return s.Name == name })
+}
+
+func writeApiJson(w http.ResponseWriter, status int, body any) {
+	jsonResponse, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	handleResponseWriter(w, jsonResponse)
+}
+
+func writeApiError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeApiJson(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// serveMethods - serves a path with a handler for each of its methods
+func serveMethods(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	methods := lo.Keys(handlers)
+	slices.Sort(methods)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			writeApiError(w, http.StatusMethodNotAllowed, "method %s not allowed, expected %s", r.Method, strings.Join(methods, " or "))
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// withApi - serves a path of the dashboard api with a handler for a single method
+func (d *Dashboard) withApi(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return d.withApiMethods(map[string]http.HandlerFunc{method: handler})
+}
+
+// withApiMethods - serves a path of the dashboard api with a handler for each of its methods. Requests must include the api token
+// in an Authorization: Bearer <token> header, browsers may only call the api from the origins configured in nitric.yaml
+func (d *Dashboard) withApiMethods(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	methods := lo.Keys(handlers)
+	slices.Sort(methods)
+
+	serve := serveMethods(handlers)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(d.apiOrigins, origin) {
+				writeApiError(w, http.StatusForbidden, "origin %s isn't allowed to call the dashboard api, add it to dashboard.api-origins in nitric.yaml", origin)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", ")+", OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(d.apiToken)) != 1 {
+			writeApiError(w, http.StatusUnauthorized, "missing or invalid dashboard api token, see nitric local ps")
+			return
+		}
+
+		serve(w, r)
+	}
+}
+
+// forwardTrigger - sends a trigger to the local gateway, so it's handled and recorded the same way as triggers from the dashboard
+func forwardTrigger(w http.ResponseWriter, triggerUrl string, body []byte) {
+	resp, err := http.Post(triggerUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		writeApiError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+
+	defer resp.Body.Close()
+
+	message, err := io.ReadAll(resp.Body)
+	if err != nil {
+		writeApiError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+
+	if resp.StatusCode >= 300 {
+		writeApiError(w, resp.StatusCode, "%s", strings.TrimSpace(string(message)))
+		return
+	}
+
+	writeApiJson(w, http.StatusOK, map[string]string{"message": strings.TrimSpace(string(message))})
+}
+
+func (d *Dashboard) handleApiResources() http.HandlerFunc {
+	return d.withApi(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		writeApiJson(w, http.StatusOK, d.resourcesResponse())
+	})
+}
+
+func (d *Dashboard) handleApiUsage() http.HandlerFunc {
+	return d.withApi(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		writeApiJson(w, http.StatusOK, d.usageResponse())
+	})
+}
+
+func (d *Dashboard) handleApiTopicPublish() http.HandlerFunc {
+	return d.withApi(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		topicName := r.PathValue("name")
+
+		if !d.hasTopic(topicName) {
+			writeApiError(w, http.StatusNotFound, "topic %s not found", topicName)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			body = []byte("{}")
+		}
+
+		if !json.Valid(body) {
+			writeApiError(w, http.StatusBadRequest, "message must be a JSON object")
+			return
+		}
+
+		forwardTrigger(w, d.gatewayService.GetTopicTriggerUrl(topicName), body)
+	})
+}
+
+func (d *Dashboard) handleApiScheduleTrigger() http.HandlerFunc {
+	return d.withApi(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		scheduleName := r.PathValue("name")
+
+		if !d.hasSchedule(scheduleName) {
+			writeApiError(w, http.StatusNotFound, "schedule %s not found", scheduleName)
+			return
+		}
+
+		forwardTrigger(w, d.gatewayService.GetScheduleManualTriggerUrl(scheduleName), nil)
+	})
+}
+
+func (d *Dashboard) handleApiLogs() http.HandlerFunc {
+	return d.withApi(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var since time.Time
+
+		if value := query.Get("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				writeApiError(w, http.StatusBadRequest, "invalid since param, expected an RFC 3339 timestamp: %v", err)
+				return
+			}
+
+			since = parsed
+		}
+
+		limit := maxLogEntries
+
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				writeApiError(w, http.StatusBadRequest, "invalid limit param, expected a positive number")
+				return
+			}
+
+			limit = parsed
+		}
+
+		var minimumLevel project.LogLevel
+
+		if value := query.Get("level"); value != "" {
+			parsed, err := project.ParseLogLevel(value)
+			if err != nil {
+				writeApiError(w, http.StatusBadRequest, "invalid level param: %v", err)
+				return
+			}
+
+			minimumLevel = parsed
+		}
+
+		service := query.Get("service")
+
+		d.logsLock.Lock()
+		entries := lo.Filter(d.logs, func(entry LogEntry, _ int) bool {
+			return (service == "" || entry.Service == service) && entry.Time.After(since) &&
+				(minimumLevel == "" || minimumLevel.Includes(project.LogLevel(entry.Level)))
+		})
+		d.logsLock.Unlock()
+
+		if len(entries) > limit {
+			entries = entries[len(entries)-limit:]
+		}
+
+		writeApiJson(w, http.StatusOK, entries)
+	})
+}
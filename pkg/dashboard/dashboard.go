@@ -24,6 +24,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
@@ -37,12 +38,15 @@ import (
 
 	"github.com/nitrictech/cli/pkg/browser"
 	"github.com/nitrictech/cli/pkg/cloud"
+	"github.com/nitrictech/cli/pkg/cloud/usage"
 	"github.com/nitrictech/cli/pkg/collector"
 	"github.com/nitrictech/cli/pkg/netx"
+	"github.com/nitrictech/cli/pkg/rpc"
 	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
 	websocketspb "github.com/nitrictech/nitric/core/pkg/proto/websockets/v1"
 
 	"github.com/nitrictech/cli/pkg/cloud/apis"
+	"github.com/nitrictech/cli/pkg/cloud/email"
 	"github.com/nitrictech/cli/pkg/cloud/gateway"
 	httpproxy "github.com/nitrictech/cli/pkg/cloud/http"
 	"github.com/nitrictech/cli/pkg/cloud/resources"
@@ -146,6 +150,7 @@ type HttpProxySpec struct {
 type Dashboard struct {
 	resourcesLock          sync.Mutex
 	project                *project.Project
+	localCloud             *cloud.LocalCloud
 	storageService         *storage.LocalStorageService
 	gatewayService         *gateway.LocalGatewayService
 	databaseService        *sql.LocalSqlServer
@@ -165,6 +170,9 @@ type Dashboard struct {
 	httpProxies            []*HttpProxySpec
 	queues                 []*QueueSpec
 	policies               map[string]PolicySpec
+	usage                  usage.State
+	emails                 []EmailSpec
+	emailService           *email.LocalEmailService
 	envMap                 map[string]string
 
 	stackWebSocket   *melody.Melody
@@ -176,6 +184,11 @@ type Dashboard struct {
 	noBrowser        bool
 	browserLock      sync.Mutex
 	debouncedUpdate  func()
+	logs             []LogEntry
+	logsLock         sync.Mutex
+	// required by the documented api, see withApiMethods
+	apiToken   string
+	apiOrigins []string
 }
 
 type DashboardResponse struct {
@@ -196,9 +209,12 @@ type DashboardResponse struct {
 	Services []*ServiceSpec `json:"services"`
 
 	Policies            map[string]PolicySpec `json:"policies"`
+	Usage               *UsageResponse        `json:"usage"`
+	Emails              []EmailSpec           `json:"emails"`
 	ProjectName         string                `json:"projectName"`
 	ApiAddresses        map[string]string     `json:"apiAddresses"`
 	WebsocketAddresses  map[string]string     `json:"websocketAddresses"`
+	WebsocketScheme     string                `json:"websocketScheme"`
 	HttpWorkerAddresses map[string]string     `json:"httpWorkerAddresses"`
 	TriggerAddress      string                `json:"triggerAddress"`
 	StorageAddress      string                `json:"storageAddress"`
@@ -674,6 +690,19 @@ func (d *Dashboard) Start() error {
 
 	http.HandleFunc("/api/ws-clear-messages", d.handleWebsocketMessagesClear())
 
+	http.HandleFunc("/api/emails", d.handleEmails())
+	http.HandleFunc("/api/emails/{id}", d.handleEmail())
+
+	// documented api for tools integrating with local runs, see the dashboard api section of the README
+	http.HandleFunc("/api/v1/resources", d.handleApiResources())
+	http.HandleFunc("/api/v1/topics/{name}/publish", d.handleApiTopicPublish())
+	http.HandleFunc("/api/v1/schedules/{name}/trigger", d.handleApiScheduleTrigger())
+	http.HandleFunc("/api/v1/logs", d.handleApiLogs())
+	http.HandleFunc("/api/v1/usage", d.handleApiUsage())
+	http.HandleFunc("/api/v1/emails", d.handleApiEmails())
+	http.HandleFunc("/api/v1/emails/{id}", d.handleApiEmail())
+	http.HandleFunc("/api/v1/snapshot", d.handleApiSnapshot())
+
 	d.wsWebSocket.HandleConnect(func(s *melody.Session) {
 		// Send a welcome message to the client
 		err := d.sendWebsocketsUpdate()
@@ -729,6 +758,11 @@ func (d *Dashboard) GetDashboardUrl() string {
 	return fmt.Sprintf("http://localhost:%s", strconv.Itoa(d.port))
 }
 
+// GetApiToken - returns the token required by the dashboard api
+func (d *Dashboard) GetApiToken() string {
+	return d.apiToken
+}
+
 func handleResponseWriter(w http.ResponseWriter, data []byte) {
 	_, err := w.Write(data)
 	if err != nil {
@@ -754,6 +788,8 @@ func (d *Dashboard) sendStackUpdate() error {
 		Schedules:           d.schedules,
 		Websockets:          d.websockets,
 		Policies:            d.policies,
+		Usage:               d.usageResponse(),
+		Emails:              d.emails,
 		Queues:              d.queues,
 		Secrets:             d.secrets,
 		Services:            services,
@@ -763,6 +799,7 @@ func (d *Dashboard) sendStackUpdate() error {
 		ProjectName:         d.project.Name,
 		ApiAddresses:        d.gatewayService.GetApiAddresses(),
 		WebsocketAddresses:  d.gatewayService.GetWebsocketAddresses(),
+		WebsocketScheme:     d.gatewayService.GetWebsocketScheme(),
 		HttpWorkerAddresses: d.gatewayService.GetHttpWorkerAddresses(),
 		TriggerAddress:      d.gatewayService.GetTriggerAddress(),
 		// StorageAddress:      d.storageService.GetStorageEndpoint(),
@@ -822,10 +859,13 @@ func New(noBrowser bool, localCloud *cloud.LocalCloud, project *project.Project)
 
 	dash := &Dashboard{
 		project:                project,
+		localCloud:             localCloud,
 		storageService:         localCloud.Storage,
 		gatewayService:         localCloud.Gateway,
 		databaseService:        localCloud.Databases,
 		secretService:          localCloud.Secrets,
+		emailService:           localCloud.Email,
+		emails:                 []EmailSpec{},
 		apis:                   []ApiSpec{},
 		apiUseHttps:            localCloud.Gateway.ApiTlsCredentials != nil,
 		apiSecurityDefinitions: map[string]map[string]*resourcespb.ApiSecurityDefinitionResource{},
@@ -847,6 +887,17 @@ func New(noBrowser bool, localCloud *cloud.LocalCloud, project *project.Project)
 		policies:               map[string]PolicySpec{},
 		websocketsInfo:         map[string]*websockets.WebsocketInfo{},
 		noBrowser:              noBrowser,
+		apiToken:               os.Getenv("NITRIC_DASHBOARD_API_TOKEN"),
+		apiOrigins:             project.Dashboard.ApiOrigins,
+	}
+
+	if dash.apiToken == "" {
+		token, err := rpc.NewToken()
+		if err != nil {
+			return nil, err
+		}
+
+		dash.apiToken = token
 	}
 
 	debouncedUpdate, _ := lo.NewDebounce(300*time.Millisecond, func() {
@@ -869,6 +920,11 @@ func New(noBrowser bool, localCloud *cloud.LocalCloud, project *project.Project)
 	localCloud.Storage.SubscribeToState(dash.updateBucketNotifications)
 	localCloud.Http.SubscribeToState(dash.updateHttpProxies)
 	localCloud.Databases.SubscribeToState(dash.updateSqlDatabases)
+	localCloud.Usage.SubscribeToState(dash.updateUsage)
+
+	if localCloud.Email != nil {
+		localCloud.Email.SubscribeToState(dash.updateEmails)
+	}
 
 	// subscribe to history events from gateway
 	localCloud.Apis.SubscribeToAction(dash.handleApiHistory)
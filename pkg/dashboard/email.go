@@ -0,0 +1,122 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/cloud/email"
+)
+
+// EmailSpec - an email sent by a service during the local run, captured by the local SMTP server
+type EmailSpec struct {
+	Id         string            `json:"id"`
+	From       string            `json:"from"`
+	Recipients []string          `json:"recipients"`
+	Subject    string            `json:"subject"`
+	Headers    map[string]string `json:"headers"`
+	Text       string            `json:"text"`
+	Html       string            `json:"html"`
+	Received   time.Time         `json:"received"`
+	// The message as it was sent, only returned by GET /api/v1/emails/{id}
+	Raw string `json:"raw,omitempty"`
+}
+
+func toEmailSpec(msg email.Message) EmailSpec {
+	return EmailSpec{
+		Id:         msg.Id,
+		From:       msg.From,
+		Recipients: msg.Recipients,
+		Subject:    msg.Subject,
+		Headers:    msg.Headers,
+		Text:       msg.Text,
+		Html:       msg.Html,
+		Received:   msg.Received,
+	}
+}
+
+func (d *Dashboard) updateEmails(state email.State) {
+	d.resourcesLock.Lock()
+	d.emails = lo.Map(state, func(msg email.Message, _ int) EmailSpec {
+		return toEmailSpec(msg)
+	})
+	d.resourcesLock.Unlock()
+
+	d.refresh()
+}
+
+// handleApiEmails - lists the captured emails, most recent first, or clears them with DELETE
+func (d *Dashboard) handleApiEmails() http.HandlerFunc {
+	return d.withApiMethods(d.emailsHandlers())
+}
+
+// handleApiEmail - returns a captured email including the message as it was sent
+func (d *Dashboard) handleApiEmail() http.HandlerFunc {
+	return d.withApi(http.MethodGet, d.getEmail)
+}
+
+// handleEmails - serves the captured emails to the dashboard, which is served from the same origin so doesn't need the api token
+func (d *Dashboard) handleEmails() http.HandlerFunc {
+	return serveMethods(d.emailsHandlers())
+}
+
+// handleEmail - serves a captured email to the dashboard
+func (d *Dashboard) handleEmail() http.HandlerFunc {
+	return serveMethods(map[string]http.HandlerFunc{http.MethodGet: d.getEmail})
+}
+
+func (d *Dashboard) emailsHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+			d.resourcesLock.Lock()
+			defer d.resourcesLock.Unlock()
+
+			writeApiJson(w, http.StatusOK, d.emails)
+		},
+		http.MethodDelete: func(w http.ResponseWriter, r *http.Request) {
+			if d.emailService == nil {
+				writeApiError(w, http.StatusNotFound, "email isn't configured for this project")
+				return
+			}
+
+			d.emailService.Clear()
+
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+}
+
+func (d *Dashboard) getEmail(w http.ResponseWriter, r *http.Request) {
+	if d.emailService == nil {
+		writeApiError(w, http.StatusNotFound, "email isn't configured for this project")
+		return
+	}
+
+	msg, ok := d.emailService.Get(r.PathValue("id"))
+	if !ok {
+		writeApiError(w, http.StatusNotFound, "email %s not found", r.PathValue("id"))
+		return
+	}
+
+	spec := toEmailSpec(msg)
+	spec.Raw = msg.Raw
+
+	writeApiJson(w, http.StatusOK, spec)
+}
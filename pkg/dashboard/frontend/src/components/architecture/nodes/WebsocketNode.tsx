@@ -20,7 +20,7 @@ export const WebsocketNode: ComponentType<NodeProps<WebsocketNodeData>> = (
         icon: data.icon,
         nodeType: 'websocket',
         testHref: `/websockets`, // TODO add url param to switch to resource
-        address: `ws://${data.address}`,
+        address: data.address,
         services: data.resource.requestingServices,
         children: (
           <>
@@ -0,0 +1,182 @@
+import { useEffect, useState } from 'react'
+import { format } from 'date-fns/format'
+import toast from 'react-hot-toast'
+import { TrashIcon } from '@heroicons/react/24/outline'
+import type { Email } from '@/types'
+import { useWebSocket } from '@/lib/hooks/use-web-socket'
+import { cn, getHost } from '@/lib/utils'
+import AppLayout from '../layout/AppLayout'
+import BreadCrumbs from '../layout/BreadCrumbs'
+import { Loading } from '../shared'
+import SectionCard from '../shared/SectionCard'
+import { Button } from '../ui/button'
+import { ScrollArea } from '../ui/scroll-area'
+import { Tabs, TabsContent, TabsList, TabsTrigger } from '../ui/tabs'
+
+const EmailExplorer: React.FC = () => {
+  const { data, loading } = useWebSocket()
+  const [selectedId, setSelectedId] = useState<string>()
+  const [raw, setRaw] = useState<string>()
+
+  const emails = data?.emails ?? []
+  const selected = emails.find((e) => e.id === selectedId) ?? emails[0]
+
+  useEffect(() => {
+    setRaw(undefined)
+  }, [selected?.id])
+
+  const loadRaw = async (email: Email) => {
+    const resp = await fetch(`http://${getHost()}/api/emails/${email.id}`)
+
+    if (!resp.ok) {
+      toast.error('Unable to load the message source')
+      return
+    }
+
+    const full: Email = await resp.json()
+
+    setRaw(full.raw)
+  }
+
+  const clearEmails = async () => {
+    const resp = await fetch(`http://${getHost()}/api/emails`, {
+      method: 'DELETE',
+    })
+
+    if (!resp.ok) {
+      toast.error('Unable to clear captured email')
+      return
+    }
+
+    setSelectedId(undefined)
+    toast.success('Cleared captured email')
+  }
+
+  return (
+    <AppLayout
+      title="Email"
+      hideTitle
+      routePath="/email"
+      secondLevelNav={
+        emails.length > 0 && (
+          <>
+            <div className="flex min-h-12 items-center justify-between px-2 py-1">
+              <span className="text-lg">Inbox</span>
+              <Button
+                size="icon"
+                variant="ghost"
+                aria-label="Clear captured email"
+                onClick={clearEmails}
+              >
+                <TrashIcon className="h-5 w-5" />
+              </Button>
+            </div>
+            <ul className="flex flex-col gap-1">
+              {emails.map((email) => (
+                <li key={email.id}>
+                  <button
+                    className={cn(
+                      'w-full rounded-md px-2 py-1.5 text-left hover:bg-accent',
+                      email.id === selected?.id && 'bg-accent',
+                    )}
+                    onClick={() => setSelectedId(email.id)}
+                  >
+                    <div className="truncate text-sm font-semibold">
+                      {email.subject || '(no subject)'}
+                    </div>
+                    <div className="truncate text-xs text-muted-foreground">
+                      {email.recipients.join(', ')}
+                    </div>
+                  </button>
+                </li>
+              ))}
+            </ul>
+          </>
+        )
+      }
+    >
+      <Loading delay={400} conditionToShow={!loading}>
+        {selected ? (
+          <div className="flex max-w-[2000px] flex-col gap-8 md:pr-8">
+            <BreadCrumbs className="text-lg">
+              <span>Email</span>
+              <h2 className="font-body text-lg font-semibold">
+                {selected.subject || '(no subject)'}
+              </h2>
+            </BreadCrumbs>
+            <SectionCard title="Details">
+              <dl className="grid grid-cols-[auto_1fr] gap-x-4 gap-y-2 text-sm">
+                <dt className="font-semibold">From</dt>
+                <dd>{selected.headers['From'] || selected.from}</dd>
+                <dt className="font-semibold">To</dt>
+                <dd>
+                  {selected.headers['To'] || selected.recipients.join(', ')}
+                </dd>
+                {selected.headers['Cc'] && (
+                  <>
+                    <dt className="font-semibold">Cc</dt>
+                    <dd>{selected.headers['Cc']}</dd>
+                  </>
+                )}
+                <dt className="font-semibold">Recipients</dt>
+                <dd>{selected.recipients.join(', ')}</dd>
+                <dt className="font-semibold">Received</dt>
+                <dd>{format(new Date(selected.received), 'PPpp')}</dd>
+              </dl>
+            </SectionCard>
+            <Tabs
+              defaultValue={selected.html ? 'html' : 'text'}
+              key={selected.id}
+              onValueChange={(tab) => {
+                if (tab === 'raw' && raw === undefined) {
+                  loadRaw(selected)
+                }
+              }}
+            >
+              <TabsList>
+                <TabsTrigger value="html" disabled={!selected.html}>
+                  HTML
+                </TabsTrigger>
+                <TabsTrigger value="text" disabled={!selected.text}>
+                  Text
+                </TabsTrigger>
+                <TabsTrigger value="raw">Source</TabsTrigger>
+              </TabsList>
+              <TabsContent value="html">
+                <iframe
+                  title="Email HTML"
+                  className="h-[600px] w-full rounded-md border bg-white"
+                  sandbox=""
+                  srcDoc={selected.html}
+                />
+              </TabsContent>
+              <TabsContent value="text">
+                <pre className="whitespace-pre-wrap rounded-md border p-4 text-sm">
+                  {selected.text}
+                </pre>
+              </TabsContent>
+              <TabsContent value="raw">
+                <ScrollArea className="h-[600px] rounded-md border">
+                  <pre className="whitespace-pre-wrap p-4 text-xs">
+                    {raw ?? 'Loading...'}
+                  </pre>
+                </ScrollArea>
+              </TabsContent>
+            </Tabs>
+          </div>
+        ) : (
+          <div>
+            No email has been sent yet. Services send email to the local SMTP
+            server using the{' '}
+            <code className="font-mono">NITRIC_SMTP_HOST</code> and{' '}
+            <code className="font-mono">NITRIC_SMTP_PORT</code> environment
+            variables, once <code className="font-mono">email.from</code> is
+            set in nitric.yaml, and it's captured here rather than delivered.
+          </div>
+        )}
+      </Loading>
+    </AppLayout>
+  )
+}
+
+export default EmailExplorer
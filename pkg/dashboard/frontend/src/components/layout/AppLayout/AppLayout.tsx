@@ -14,6 +14,7 @@ import {
   HeartIcon,
   CircleStackIcon,
   LockClosedIcon,
+  EnvelopeIcon,
 } from '@heroicons/react/24/outline'
 import { cn } from '@/lib/utils'
 import { useWebSocket } from '../../../lib/hooks/use-web-socket'
@@ -141,6 +142,11 @@ const AppLayout: React.FC<Props> = ({
       href: '/websockets',
       icon: ChatBubbleLeftRightIcon,
     },
+    {
+      name: 'Email',
+      href: '/email',
+      icon: EnvelopeIcon,
+    },
     // { name: "Key Value Stores", href: "#", icon: FolderIcon, current: false },
   ]
 
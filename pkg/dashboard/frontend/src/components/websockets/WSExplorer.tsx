@@ -148,7 +148,7 @@ const WSExplorer = () => {
 
   const websocketAddress =
     selectedWebsocket && data?.websocketAddresses[selectedWebsocket?.name]
-      ? `ws://${generatePath(
+      ? `${data.websocketScheme}://${generatePath(
           data?.websocketAddresses[selectedWebsocket?.name],
           [],
           queryParams,
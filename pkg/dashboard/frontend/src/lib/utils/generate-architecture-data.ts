@@ -184,6 +184,11 @@ const actionVerbs = [
   'Access',
 ]
 
+// node types of the resource types recorded by usage, where they differ
+const usageNodeTypes: Record<string, keyof typeof nodeTypes> = {
+  sqldatabase: 'sql',
+}
+
 function verbFromNitricAction(action: string) {
   for (const verb of actionVerbs) {
     if (action.endsWith(verb)) {
@@ -249,6 +254,8 @@ export function generateArchitectureData(data: WebSocketResponse): {
   // Generate nodes from websockets
   data.websockets.forEach((ws) => {
     const wsAddress = data.websocketAddresses[ws.name]
+      ? `${data.websocketScheme}://${data.websocketAddresses[ws.name]}`
+      : undefined
 
     const events = Object.keys(ws.targets || {})
 
@@ -482,12 +489,21 @@ export function generateArchitectureData(data: WebSocketResponse): {
     nodes.push(node)
   })
 
+  const observed = data.usage?.observed ?? []
+
   edges.push(
     ...Object.entries(data.policies).map(([_, policy]) => {
       return {
         id: `e-${policy.name}`,
         source: policy.principals[0].name,
         target: `${policy.resources[0].type}-${policy.resources[0].name}`,
+        // policies used by calls during the run are animated
+        animated: observed.some(
+          (u) =>
+            u.service === policy.principals[0].name &&
+            u.type === policy.resources[0].type &&
+            u.resource === policy.resources[0].name,
+        ),
         markerEnd: {
           type: MarkerType.ArrowClosed,
         },
@@ -500,6 +516,39 @@ export function generateArchitectureData(data: WebSocketResponse): {
     }),
   )
 
+  // calls observed during the run that the service didn't declare, these would be denied once deployed
+  const undeclared = observed.filter((u) => !u.declared)
+
+  edges.push(
+    ...unique(undeclared, (u) => `${u.service}-${u.type}-${u.resource}`).map(
+      (u) => {
+        const actions = undeclared.filter(
+          (other) =>
+            other.service === u.service &&
+            other.type === u.type &&
+            other.resource === u.resource,
+        )
+
+        return {
+          id: `e-usage-${u.service}-${u.type}-${u.resource}`,
+          source: u.service,
+          target: `${usageNodeTypes[u.type] ?? u.type}-${u.resource}`,
+          animated: true,
+          style: { stroke: '#ef4444', strokeDasharray: '4 4' },
+          markerEnd: {
+            type: MarkerType.ArrowClosed,
+            color: '#ef4444',
+          },
+          label: `Undeclared ${actions
+            .map((a) =>
+              a.action ? verbFromNitricAction(a.action) : 'Connect',
+            )
+            .join(', ')}`,
+        } as Edge
+      },
+    ),
+  )
+
   data.services.forEach((service) => {
     const node: Node<ServiceNodeData> = {
       id: service.name,
@@ -105,6 +105,35 @@ export interface Policy extends BaseResource {
   actions: string[]
   resources: Resource[]
 }
+export interface Usage {
+  service: string
+  type: string
+  resource: string
+  action?: string
+  count: number
+  lastSeen: string
+  declared: boolean
+}
+
+export interface DeclaredUsage {
+  service: string
+  type: string
+  resource: string
+  action: string
+}
+
+export interface Email {
+  id: string
+  from: string
+  recipients: string[]
+  subject: string
+  headers: Record<string, string>
+  text: string
+  html: string
+  received: string
+  raw?: string
+}
+
 export interface WebSocketResponse {
   projectName: string
   buckets: Bucket[]
@@ -123,9 +152,15 @@ export interface WebSocketResponse {
   policies: {
     [name: string]: Policy
   }
+  usage?: {
+    observed: Usage[]
+    unused: DeclaredUsage[]
+  }
+  emails?: Email[]
   triggerAddress: string
   apiAddresses: Record<string, string>
   websocketAddresses: Record<string, string>
+  websocketScheme: 'ws' | 'wss'
   httpWorkerAddresses: Record<string, string>
   storageAddress: string
   currentVersion: string
@@ -0,0 +1,56 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// handleApiSnapshot - exports the state of the local cloud's resources as a gzipped tar with GET, or imports one sent in the request body with POST
+func (d *Dashboard) handleApiSnapshot() http.HandlerFunc {
+	return d.withApiMethods(map[string]http.HandlerFunc{
+		http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+			// buffered so failures can still be reported as errors
+			var snapshot bytes.Buffer
+
+			manifest, err := d.localCloud.Export(&snapshot, d.project.Name)
+			if err != nil {
+				writeApiError(w, http.StatusInternalServerError, "%s", err.Error())
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.tar.gz", d.project.Name, manifest.CreatedAt.Format("20060102-150405"))))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(snapshot.Bytes())
+		},
+		http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+			manifest, err := d.localCloud.Import(r.Body)
+			if err != nil {
+				writeApiError(w, http.StatusBadRequest, "%s", err.Error())
+				return
+			}
+
+			// bucket and key/value store contents shown by the dashboard may have changed
+			d.refresh()
+
+			writeApiJson(w, http.StatusOK, manifest)
+		},
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dashboard
+
+import (
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/cloud/usage"
+)
+
+// UsageSpec - calls observed from a service to a resource during the local run
+type UsageSpec struct {
+	Service  string    `json:"service"`
+	Type     string    `json:"type"`
+	Resource string    `json:"resource"`
+	Action   string    `json:"action,omitempty"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+	// Declared is false when the service calls the resource without declaring it needs to, the calls would be denied once deployed
+	Declared bool `json:"declared"`
+}
+
+// DeclaredUsageSpec - a permission declared by a service that hasn't been used during the local run
+type DeclaredUsageSpec struct {
+	Service  string `json:"service"`
+	Type     string `json:"type"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// UsageResponse - the resources services have called during the local run compared to the permissions they declared, returned by GET /api/v1/usage
+type UsageResponse struct {
+	Observed []UsageSpec         `json:"observed"`
+	Unused   []DeclaredUsageSpec `json:"unused"`
+}
+
+func (d *Dashboard) updateUsage(state usage.State) {
+	d.resourcesLock.Lock()
+	d.usage = state
+	d.resourcesLock.Unlock()
+
+	d.refresh()
+}
+
+// declaredUsage - returns the permissions granted by the policies declared by services
+func (d *Dashboard) declaredUsage() []DeclaredUsageSpec {
+	declared := []DeclaredUsageSpec{}
+
+	for _, policy := range d.policies {
+		for _, principal := range policy.Principals {
+			for _, resource := range policy.Resources {
+				for _, action := range policy.Actions {
+					declared = append(declared, DeclaredUsageSpec{
+						Service:  principal.Name,
+						Type:     resource.Type,
+						Resource: resource.Name,
+						Action:   action,
+					})
+				}
+			}
+		}
+	}
+
+	return lo.Uniq(declared)
+}
+
+// isDeclared - resources without permissions, such as sql databases, are declared by the services requesting them
+func (d *Dashboard) isDeclared(edge usage.Edge, declared []DeclaredUsageSpec) bool {
+	if edge.Action == "" {
+		return lo.ContainsBy(d.sqlDatabases, func(db *SQLDatabaseSpec) bool {
+			return db.Name == edge.Resource && slices.Contains(db.RequestingServices, edge.Service)
+		})
+	}
+
+	return slices.Contains(declared, DeclaredUsageSpec{Service: edge.Service, Type: edge.Type, Resource: edge.Resource, Action: edge.Action})
+}
+
+func (d *Dashboard) usageResponse() *UsageResponse {
+	d.resourcesLock.Lock()
+	defer d.resourcesLock.Unlock()
+
+	declared := d.declaredUsage()
+
+	observed := lo.Map(d.usage, func(edge usage.Edge, _ int) UsageSpec {
+		return UsageSpec{
+			Service:  edge.Service,
+			Type:     edge.Type,
+			Resource: edge.Resource,
+			Action:   edge.Action,
+			Count:    edge.Count,
+			LastSeen: edge.LastSeen,
+			Declared: d.isDeclared(edge, declared),
+		}
+	})
+
+	unused := lo.Filter(declared, func(permission DeclaredUsageSpec, _ int) bool {
+		return !lo.ContainsBy(d.usage, func(edge usage.Edge) bool {
+			return edge.Service == permission.Service && edge.Type == permission.Type && edge.Resource == permission.Resource && edge.Action == permission.Action
+		})
+	})
+
+	slices.SortFunc(unused, func(a, b DeclaredUsageSpec) int {
+		return compare(a.Service+a.Type+a.Resource+a.Action, b.Service+b.Type+b.Resource+b.Action)
+	})
+
+	return &UsageResponse{
+		Observed: observed,
+		Unused:   unused,
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/paths"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// ResourceDigest - the final state of a single resource reported by a provider during a deployment
+type ResourceDigest struct {
+	Type        string `json:"type,omitempty"`
+	Name        string `json:"name,omitempty"`
+	SubResource string `json:"subResource,omitempty"`
+	Action      string `json:"action"`
+	Status      string `json:"status"`
+	Message     string `json:"message,omitempty"`
+	// Distinct messages reported for the resource over the deployment, e.g. provider error details for failed resources
+	Logs []string `json:"logs,omitempty"`
+}
+
+func (r ResourceDigest) String() string {
+	resourceType := lo.Ternary(r.Type != "", r.Type, "Stack")
+	name := lo.Ternary(r.Name != "", r.Name, r.SubResource)
+
+	if r.Name != "" && r.SubResource != "" {
+		name = fmt.Sprintf("%s:%s", r.Name, r.SubResource)
+	}
+
+	return fmt.Sprintf("%s::%s", resourceType, name)
+}
+
+// Digest - a record of a single stack deployment
+type Digest struct {
+	Project  string   `json:"project"`
+	Stack    string   `json:"stack"`
+	Provider string   `json:"provider"`
+	Regions  []string `json:"regions,omitempty"`
+	Host     string   `json:"host,omitempty"`
+	// User that ran the deployment, the CI actor when deployed from CI
+	User string `json:"user,omitempty"`
+	// Git commit of the project that was deployed
+	Commit    string           `json:"commit,omitempty"`
+	StartTime time.Time        `json:"startTime"`
+	EndTime   time.Time        `json:"endTime"`
+	Success   bool             `json:"success"`
+	Result    string           `json:"result,omitempty"`
+	Errors    []string         `json:"errors,omitempty"`
+	Resources []ResourceDigest `json:"resources"`
+	// Hash of the stack file used for the deployment, used to detect changes since the last deployment
+	ConfigHash string `json:"configHash,omitempty"`
+	// Stateful resources declared in the deployed spec, used to detect renamed resources
+	Declared []DeclaredResource `json:"declared,omitempty"`
+	// Config hashes of all resources in the deployed spec, keyed by <type>/<name>, used to preview changes in later deployments
+	ResourceHashes map[string]string `json:"resourceHashes,omitempty"`
+	// Number of attempts made, deployments failing with transient errors are retried
+	Attempts int `json:"attempts,omitempty"`
+	// ID of the deployment re-applied by this deployment, set when a failed deployment was rolled back
+	Rollback string `json:"rollback,omitempty"`
+	// Named outputs returned by the provider in the result, e.g. API URLs and bucket names, see OutputsHeader
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// IDs of the service and migration images deployed, keyed by image reference, used to find the images deployments use
+	Images map[string]string `json:"images,omitempty"`
+
+	lock sync.Mutex
+}
+
+// Record - records deployment events into the digest, forwarding them to the returned channel
+func (d *Digest) Record(events <-chan *deploymentspb.DeploymentUpEvent) <-chan *deploymentspb.DeploymentUpEvent {
+	out := make(chan *deploymentspb.DeploymentUpEvent)
+
+	go func() {
+		defer close(out)
+
+		for event := range events {
+			d.recordEvent(event)
+
+			out <- event
+		}
+	}()
+
+	return out
+}
+
+// RecordErrors - records deployment errors into the digest, forwarding them to the returned channel
+func (d *Digest) RecordErrors(errs <-chan error) <-chan error {
+	out := make(chan error)
+
+	go func() {
+		defer close(out)
+
+		for err := range errs {
+			d.lock.Lock()
+			d.Errors = append(d.Errors, err.Error())
+			d.Success = false
+			d.lock.Unlock()
+
+			out <- err
+		}
+	}()
+
+	return out
+}
+
+func (d *Digest) recordEvent(event *deploymentspb.DeploymentUpEvent) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	switch content := event.Content.(type) {
+	case *deploymentspb.DeploymentUpEvent_Update:
+		if content.Update == nil {
+			return
+		}
+
+		resource := ResourceDigest{
+			SubResource: content.Update.SubResource,
+			Action:      content.Update.Action.String(),
+			Status:      content.Update.Status.String(),
+			Message:     content.Update.Message,
+		}
+
+		if content.Update.Id != nil {
+			resource.Type = content.Update.Id.Type.String()
+			resource.Name = content.Update.Id.Name
+		}
+
+		// keep only the latest update for each resource, along with the messages from earlier updates
+		for i, existing := range d.Resources {
+			if existing.Type == resource.Type && existing.Name == resource.Name && existing.SubResource == resource.SubResource {
+				resource.Logs = appendLog(existing.Logs, resource.Message)
+				d.Resources[i] = resource
+
+				return
+			}
+		}
+
+		resource.Logs = appendLog(nil, resource.Message)
+		d.Resources = append(d.Resources, resource)
+	case *deploymentspb.DeploymentUpEvent_Result:
+		d.Result = content.Result.GetText()
+		d.Outputs = ParseOutputs(d.Result)
+		d.Success = content.Result.GetSuccess() && len(d.Errors) == 0
+	}
+}
+
+// appendLog - appends a resource message to its logs, skipping empty and repeated messages
+func appendLog(logs []string, message string) []string {
+	message = strings.TrimSpace(message)
+	if message == "" || (len(logs) > 0 && logs[len(logs)-1] == message) {
+		return logs
+	}
+
+	return append(logs, message)
+}
+
+// Failures - the resources that failed to deploy
+func (d *Digest) Failures() []ResourceDigest {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return lo.Filter(d.Resources, func(r ResourceDigest, _ int) bool {
+		return r.Status == deploymentspb.ResourceDeploymentStatus_FAILED.String()
+	})
+}
+
+// FailureMessages - the errors and failed resource messages reported during the deployment
+func (d *Digest) FailureMessages() []string {
+	messages := []string{}
+
+	for _, failure := range d.Failures() {
+		if len(failure.Logs) == 0 {
+			messages = append(messages, fmt.Sprintf("%s failed", failure))
+			continue
+		}
+
+		messages = append(messages, failure.Logs[len(failure.Logs)-1])
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return append(messages, d.Errors...)
+}
+
+// Finish - marks the deployment as complete
+func (d *Digest) Finish() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.EndTime = time.Now().UTC()
+}
+
+// ID - identifies the deployment in the stack history, the start time of the deployment
+func (d *Digest) ID() string {
+	return d.StartTime.Format("20060102T150405Z")
+}
+
+// FileName - the name of the file the digest is written to
+func (d *Digest) FileName() string {
+	return fmt.Sprintf("%s.json", d.ID())
+}
+
+// Duration - how long the deployment took, including retried attempts
+func (d *Digest) Duration() time.Duration {
+	if d.EndTime.IsZero() {
+		return 0
+	}
+
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// Write - writes the digest to the local digest history for the stack, returning the written file path
+func (d *Digest) Write() (string, error) {
+	digestsDir, err := paths.NitricDigestsDir(d.Project, d.Stack)
+	if err != nil {
+		return "", err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	digestFile := filepath.Join(digestsDir, d.FileName())
+
+	return digestFile, os.WriteFile(digestFile, data, 0o600)
+}
+
+// ResourceCount - the number of resources that exist after the deployment
+func (d *Digest) ResourceCount() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return len(lo.Filter(d.Resources, func(r ResourceDigest, _ int) bool {
+		return r.Action != deploymentspb.ResourceDeploymentAction_DELETE.String()
+	}))
+}
+
+var endpointPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// Endpoints - the urls output by the provider in the result of the deployment, e.g. the urls of deployed APIs
+func (d *Digest) Endpoints() []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return endpointPattern.FindAllString(d.Result, -1)
+}
+
+// digestFiles - returns the digest files recorded for a project stack, oldest first
+func digestFiles(projectName string, stackName string) ([]string, error) {
+	// reading the history doesn't create the digest directory, stacks that were never deployed have no files
+	files, err := filepath.Glob(filepath.Join(paths.NitricDigestsPath(projectName, stackName), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	// digest file names are timestamps, so sorting them orders the deployments
+	slices.Sort(files)
+
+	return files, nil
+}
+
+func readDigest(digestFile string) (*Digest, error) {
+	data, err := os.ReadFile(digestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("unable to parse digest %s: %w", digestFile, err)
+	}
+
+	return d, nil
+}
+
+// Latest - returns the most recent digest for a project stack, or nil if it has never been deployed
+func Latest(projectName string, stackName string) (*Digest, error) {
+	files, err := digestFiles(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	return readDigest(files[len(files)-1])
+}
+
+// LatestSuccessful - returns the most recent digest of a successful deployment of a project stack, or nil if it has never been deployed successfully
+func LatestSuccessful(projectName string, stackName string) (*Digest, error) {
+	files, err := digestFiles(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		d, err := readDigest(files[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Success {
+			return d, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// History - returns the digests of all recorded deployments of a project stack, most recent first
+func History(projectName string, stackName string) ([]*Digest, error) {
+	files, err := digestFiles(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []*Digest{}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		d, err := readDigest(files[i])
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, d)
+	}
+
+	return history, nil
+}
+
+// Load - returns the digest of a single deployment of a project stack by its ID
+func Load(projectName string, stackName string, id string) (*Digest, error) {
+	digestFile := filepath.Join(paths.NitricDigestsPath(projectName, stackName), fmt.Sprintf("%s.json", strings.TrimSuffix(filepath.Base(id), ".json")))
+	if _, err := os.Stat(digestFile); err != nil {
+		return nil, fmt.Errorf("no deployment %s found for stack %s, run nitric stack history -s %s to list deployments", id, stackName, stackName)
+	}
+
+	return readDigest(digestFile)
+}
+
+// DeploymentUser - the user running the deployment, preferring the actor reported by CI
+func DeploymentUser() string {
+	for _, env := range []string{"GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BUILDKITE_BUILD_CREATOR", "BITBUCKET_STEP_TRIGGERER_UUID"} {
+		if actor := os.Getenv(env); actor != "" {
+			return actor
+		}
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
+
+// New - creates a new digest for a stack deployment starting now
+func New(projectName string, stackName string, providerName string) *Digest {
+	host, _ := os.Hostname()
+
+	return &Digest{
+		Project:   projectName,
+		Stack:     stackName,
+		Provider:  providerName,
+		Host:      host,
+		User:      DeploymentUser(),
+		StartTime: time.Now().UTC(),
+		Resources: []ResourceDigest{},
+	}
+}
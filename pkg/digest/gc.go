@@ -0,0 +1,75 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// Orphan - a resource from the last deployment that's no longer declared by the project's services
+type Orphan struct {
+	Type string
+	Name string
+}
+
+// Key - the <type>/<name> key of the resource, e.g. bucket/photos
+func (o Orphan) Key() string {
+	return AliasKey(o.Type, o.Name)
+}
+
+// Orphans - returns the resources reported in a deployment that aren't declared in the current spec.
+//
+// Policies are ignored since their names are generated, as are resources whose state is kept for a renamed resource through an alias.
+func Orphans(previous *Digest, spec *deploymentspb.Spec, aliasedKeys []string) []Orphan {
+	declared := lo.Map(spec.Resources, func(r *deploymentspb.Resource, _ int) string {
+		return AliasKey(r.Id.GetType().String(), r.Id.GetName())
+	})
+
+	orphans := []Orphan{}
+
+	previous.lock.Lock()
+	defer previous.lock.Unlock()
+
+	for _, resource := range previous.Resources {
+		if resource.Type == "" || resource.Name == "" || resource.Type == resourcespb.ResourceType_Policy.String() {
+			continue
+		}
+
+		if resource.Action == deploymentspb.ResourceDeploymentAction_DELETE.String() && resource.Status == deploymentspb.ResourceDeploymentStatus_SUCCESS.String() {
+			continue
+		}
+
+		orphan := Orphan{Type: resource.Type, Name: resource.Name}
+		if slices.Contains(declared, orphan.Key()) || slices.Contains(aliasedKeys, orphan.Key()) || slices.Contains(orphans, orphan) {
+			continue
+		}
+
+		orphans = append(orphans, orphan)
+	}
+
+	slices.SortFunc(orphans, func(a Orphan, b Orphan) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+
+	return orphans
+}
@@ -0,0 +1,92 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// OutputsHeader - the line of a deployment result starting its named outputs, each following indented line is an
+// output in the form <name>: <value>, e.g.
+//
+//	Outputs:
+//	  api.main: https://example.com
+//	  bucket.images: images-1a2b3c
+const OutputsHeader = "Outputs:"
+
+var outputLine = regexp.MustCompile(`^\s+([A-Za-z0-9_.\-/]+):\s+(.*?)\s*$`)
+
+// FormatOutputs - formats named outputs for a deployment result, so providers can return them in the result text
+func FormatOutputs(outputs map[string]string) []string {
+	if len(outputs) == 0 {
+		return []string{}
+	}
+
+	names := lo.Keys(outputs)
+	slices.Sort(names)
+
+	return append([]string{OutputsHeader}, lo.Map(names, func(name string, _ int) string {
+		return fmt.Sprintf("  %s: %s", name, outputs[name])
+	})...)
+}
+
+// ParseOutputs - returns the named outputs in the result text of a deployment, see OutputsHeader
+func ParseOutputs(result string) map[string]string {
+	outputs := map[string]string{}
+	inOutputs := false
+
+	for _, line := range strings.Split(result, "\n") {
+		if strings.TrimSpace(line) == OutputsHeader {
+			inOutputs = true
+
+			continue
+		}
+
+		if !inOutputs {
+			continue
+		}
+
+		match := outputLine.FindStringSubmatch(line)
+		if match == nil {
+			inOutputs = false
+
+			continue
+		}
+
+		outputs[match[1]] = match[2]
+	}
+
+	return outputs
+}
+
+var envNameChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// OutputEnvName - the environment variable name of an output, e.g. api.main is API_MAIN
+func OutputEnvName(name string) string {
+	envName := strings.Trim(envNameChars.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+
+	if envName != "" && envName[0] >= '0' && envName[0] <= '9' {
+		envName = "_" + envName
+	}
+
+	return envName
+}
@@ -0,0 +1,162 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+type ChangeAction string
+
+const (
+	ChangeAction_Create ChangeAction = "create"
+	ChangeAction_Update ChangeAction = "update"
+	ChangeAction_Delete ChangeAction = "delete"
+	// the resource is no longer declared but is protected, so it's kept in the cloud
+	ChangeAction_Retain ChangeAction = "retain"
+	// the resource keeps the state of a previous resource through an alias
+	ChangeAction_Rename    ChangeAction = "rename"
+	ChangeAction_Unchanged ChangeAction = "unchanged"
+	// the previous deployment didn't record the resource's config, so changes can't be detected
+	ChangeAction_Unknown ChangeAction = "unknown"
+)
+
+// Change - a planned change to a resource of a stack
+type Change struct {
+	Type   string       `json:"type"`
+	Name   string       `json:"name"`
+	Action ChangeAction `json:"action"`
+	// The <type>/<name> key of the previous resource of renamed resources
+	From string `json:"from,omitempty"`
+}
+
+// Key - the <type>/<name> key of the resource, e.g. bucket/photos
+func (c Change) Key() string {
+	return AliasKey(c.Type, c.Name)
+}
+
+// ResourceHashes - returns the config hashes of the resources in a deployment spec, keyed by <type>/<name>
+func ResourceHashes(spec *deploymentspb.Spec) (map[string]string, error) {
+	hashes := map[string]string{}
+
+	for _, res := range spec.Resources {
+		if res.Id == nil {
+			continue
+		}
+
+		hash, err := resourceConfigHash(res)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[AliasKey(res.Id.Type.String(), res.Id.Name)] = hash
+	}
+
+	return hashes, nil
+}
+
+// Plan - compares a deployment spec with the previous deployment of the stack, without contacting the provider.
+// aliases are the stack's resource aliases, keyed by <type>/<name> with the previous name as the value, and
+// isProtected reports whether an undeclared resource is kept in the cloud.
+//
+// Policies are ignored since their names are generated. When there's no previous deployment every resource is created.
+func Plan(previous *Digest, spec *deploymentspb.Spec, aliases map[string]string, isProtected func(key string) bool) ([]Change, error) {
+	hashes, err := ResourceHashes(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	previousHashes := map[string]string{}
+	previousKeys := []string{}
+
+	aliasedKeys := lo.MapToSlice(aliases, func(key string, previousName string) string {
+		resourceType, _, _ := strings.Cut(key, "/")
+		return AliasKey(resourceType, previousName)
+	})
+
+	if previous != nil {
+		for _, declared := range previous.Declared {
+			previousHashes[AliasKey(declared.Type, declared.Name)] = declared.ConfigHash
+		}
+
+		previousHashes = lo.Assign(previousHashes, previous.ResourceHashes)
+		previousKeys = lo.Keys(previousHashes)
+
+		previous.lock.Lock()
+		for _, resource := range previous.Resources {
+			deleted := resource.Action == deploymentspb.ResourceDeploymentAction_DELETE.String() && resource.Status == deploymentspb.ResourceDeploymentStatus_SUCCESS.String()
+			if resource.Type != "" && resource.Name != "" && !deleted {
+				previousKeys = append(previousKeys, AliasKey(resource.Type, resource.Name))
+			}
+		}
+		previous.lock.Unlock()
+	}
+
+	changes := []Change{}
+
+	for _, res := range spec.Resources {
+		if res.Id == nil || res.Id.Type == resourcespb.ResourceType_Policy {
+			continue
+		}
+
+		change := Change{Type: res.Id.Type.String(), Name: res.Id.Name}
+		key := change.Key()
+
+		previousName, aliased := aliases[key]
+		previousKey := lo.Ternary(aliased, AliasKey(change.Type, previousName), key)
+
+		switch {
+		case previous == nil:
+			change.Action = ChangeAction_Create
+		case aliased && slices.Contains(previousKeys, previousKey) && !slices.Contains(previousKeys, key):
+			change.Action = ChangeAction_Rename
+			change.From = previousKey
+		case !slices.Contains(previousKeys, key):
+			change.Action = ChangeAction_Create
+		case previousHashes[key] == "":
+			change.Action = ChangeAction_Unknown
+		case previousHashes[key] != hashes[key]:
+			change.Action = ChangeAction_Update
+		default:
+			change.Action = ChangeAction_Unchanged
+		}
+
+		changes = append(changes, change)
+	}
+
+	if previous != nil {
+		for _, orphan := range Orphans(previous, spec, aliasedKeys) {
+			changes = append(changes, Change{
+				Type:   orphan.Type,
+				Name:   orphan.Name,
+				Action: lo.Ternary(isProtected(orphan.Key()), ChangeAction_Retain, ChangeAction_Delete),
+			})
+		}
+	}
+
+	slices.SortFunc(changes, func(a Change, b Change) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+
+	return changes, nil
+}
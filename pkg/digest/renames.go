@@ -0,0 +1,198 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"google.golang.org/protobuf/proto"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// DeclaredResource - a stateful resource declared in a deployment spec
+type DeclaredResource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Hash of the resource's configuration, excluding its name
+	ConfigHash string `json:"configHash"`
+}
+
+// Rename - a resource that appears to have been renamed between deployments
+type Rename struct {
+	Type string
+	From string
+	To   string
+}
+
+// AliasKey - the key used to alias the renamed resource's existing state in a stack file, e.g. bucket/photos
+func (r Rename) AliasKey() string {
+	return AliasKey(r.Type, r.To)
+}
+
+// AliasKey - the key used to alias a resource's existing state in a stack file
+func AliasKey(resourceType string, name string) string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(resourceType), name)
+}
+
+// resources that hold data, recreating these would lose their stored data
+var statefulResourceTypes = []resourcespb.ResourceType{
+	resourcespb.ResourceType_Bucket,
+	resourcespb.ResourceType_Topic,
+	resourcespb.ResourceType_Queue,
+	resourcespb.ResourceType_KeyValueStore,
+	resourcespb.ResourceType_Secret,
+	resourcespb.ResourceType_SqlDatabase,
+}
+
+// DeclaredResources - returns the stateful resources declared in a deployment spec
+func DeclaredResources(spec *deploymentspb.Spec) ([]DeclaredResource, error) {
+	declared := []DeclaredResource{}
+
+	for _, res := range spec.Resources {
+		if res.Id == nil || !slices.Contains(statefulResourceTypes, res.Id.Type) {
+			continue
+		}
+
+		hash, err := resourceConfigHash(res)
+		if err != nil {
+			return nil, err
+		}
+
+		declared = append(declared, DeclaredResource{
+			Type:       res.Id.Type.String(),
+			Name:       res.Id.Name,
+			ConfigHash: hash,
+		})
+	}
+
+	return declared, nil
+}
+
+// resourceConfigHash - hashes the config without the resource identifier, so renamed resources with the same config have the same hash
+func resourceConfigHash(res *deploymentspb.Resource) (string, error) {
+	config := proto.Clone(res).(*deploymentspb.Resource)
+	config.Id = nil
+
+	configBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash config of %s %s: %w", res.Id.Type, res.Id.Name, err)
+	}
+
+	hash := sha256.Sum256(configBytes)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// DetectRenames - finds resources removed since the previous deployment that appear to have been renamed,
+// a removed resource is considered renamed when an added resource of the same type has the same config,
+// or when it is the only resource of that type to have been both removed and added
+func DetectRenames(previous []DeclaredResource, current []DeclaredResource) []Rename {
+	contains := func(resources []DeclaredResource, res DeclaredResource) bool {
+		return slices.ContainsFunc(resources, func(r DeclaredResource) bool {
+			return r.Type == res.Type && r.Name == res.Name
+		})
+	}
+
+	removed := []DeclaredResource{}
+	added := []DeclaredResource{}
+
+	for _, res := range previous {
+		if !contains(current, res) {
+			removed = append(removed, res)
+		}
+	}
+
+	for _, res := range current {
+		if !contains(previous, res) {
+			added = append(added, res)
+		}
+	}
+
+	renames := []Rename{}
+	matched := map[int]bool{}
+
+	for _, old := range removed {
+		best := -1
+
+		for i, res := range added {
+			if matched[i] || res.Type != old.Type || res.ConfigHash != old.ConfigHash {
+				continue
+			}
+
+			// prefer the most similarly named resource when several have the same config
+			if best == -1 || levenshtein(old.Name, res.Name) < levenshtein(old.Name, added[best].Name) {
+				best = i
+			}
+		}
+
+		if best == -1 {
+			removedOfType := lo.Filter(removed, func(r DeclaredResource, _ int) bool { return r.Type == old.Type })
+			addedOfType := lo.Filter(added, func(r DeclaredResource, _ int) bool { return r.Type == old.Type })
+
+			if len(removedOfType) == 1 && len(addedOfType) == 1 {
+				best = slices.IndexFunc(added, func(r DeclaredResource) bool { return r.Type == old.Type })
+			}
+		}
+
+		if best == -1 || matched[best] {
+			continue
+		}
+
+		matched[best] = true
+
+		renames = append(renames, Rename{
+			Type: old.Type,
+			From: old.Name,
+			To:   added[best].Name,
+		})
+	}
+
+	return renames
+}
+
+// levenshtein - the number of single character edits required to change one string into the other
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous = current
+	}
+
+	return previous[len(b)]
+}
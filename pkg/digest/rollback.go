@@ -0,0 +1,195 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/distribution/reference"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nitrictech/cli/pkg/paths"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// RollbackPoint - the spec of the last successful deployment of a stack, re-applied when a later deployment fails
+type RollbackPoint struct {
+	// ID of the deployment in the stack history
+	Deployment string `json:"deployment"`
+	ConfigHash string `json:"configHash,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	// Images of the deployment, tagged so they're kept when later deployments rebuild them
+	Images []string `json:"images"`
+	// The deployed spec without service environment variables, so secret values aren't written to disk
+	Spec json.RawMessage `json:"spec"`
+}
+
+// ImageTagger - tags a local image with an additional name
+type ImageTagger func(source string, target string) error
+
+// rollbackImage - the name an image is kept under for rolling back a stack
+func rollbackImage(image string, stackName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("unable to keep image %s for rollbacks: %w", image, err)
+	}
+
+	return fmt.Sprintf("%s:nitric-rollback-%s", reference.FamiliarName(named), stackName), nil
+}
+
+// rewriteImages - updates the locally built images referenced by a spec, the images of services and database migrations
+func rewriteImages(spec *deploymentspb.Spec, update func(uri string) (string, error)) error {
+	for _, res := range spec.Resources {
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Service:
+			image := config.Service.GetImage()
+			if image == nil {
+				continue
+			}
+
+			uri, err := update(image.Uri)
+			if err != nil {
+				return err
+			}
+
+			image.Uri = uri
+		case *deploymentspb.Resource_SqlDatabase:
+			migrations, ok := config.SqlDatabase.GetMigrations().(*deploymentspb.SqlDatabase_ImageUri)
+			if !ok {
+				continue
+			}
+
+			uri, err := update(migrations.ImageUri)
+			if err != nil {
+				return err
+			}
+
+			migrations.ImageUri = uri
+		}
+	}
+
+	return nil
+}
+
+// SpecImages - returns the locally built images referenced by a spec, the images of services and database migrations
+func SpecImages(spec *deploymentspb.Spec) []string {
+	images := []string{}
+
+	_ = rewriteImages(spec, func(uri string) (string, error) {
+		images = append(images, uri)
+
+		return uri, nil
+	})
+
+	return images
+}
+
+// SaveRollbackPoint - records the spec of a successful deployment as the point later failed deployments of the stack roll back to
+func SaveRollbackPoint(d *Digest, spec *deploymentspb.Spec, tag ImageTagger) error {
+	rollbackSpec := proto.Clone(spec).(*deploymentspb.Spec)
+	images := []string{}
+
+	for _, res := range rollbackSpec.Resources {
+		if service, ok := res.Config.(*deploymentspb.Resource_Service); ok {
+			service.Service.Env = nil
+		}
+	}
+
+	err := rewriteImages(rollbackSpec, func(uri string) (string, error) {
+		rollbackUri, err := rollbackImage(uri, d.Stack)
+		if err != nil {
+			return "", err
+		}
+
+		if err := tag(uri, rollbackUri); err != nil {
+			return "", err
+		}
+
+		images = append(images, rollbackUri)
+
+		return rollbackUri, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	specJson, err := protojson.Marshal(rollbackSpec)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(RollbackPoint{
+		Deployment: d.ID(),
+		ConfigHash: d.ConfigHash,
+		Commit:     d.Commit,
+		Images:     images,
+		Spec:       specJson,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	rollbackFile, err := paths.NitricRollbackFile(d.Project, d.Stack)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(rollbackFile, data, 0o600)
+}
+
+// LatestRollbackPoint - returns the last successful deployment of a project stack, or nil if none has been recorded
+func LatestRollbackPoint(projectName string, stackName string) (*RollbackPoint, error) {
+	rollbackFile, err := paths.NitricRollbackFile(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(rollbackFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	point := &RollbackPoint{}
+	if err := json.Unmarshal(data, point); err != nil {
+		return nil, fmt.Errorf("unable to parse rollback point %s: %w", rollbackFile, err)
+	}
+
+	return point, nil
+}
+
+// DeploymentSpec - the spec to re-apply, with the services given the environment variables of the current deployment
+func (r *RollbackPoint) DeploymentSpec(env map[string]string) (*deploymentspb.Spec, error) {
+	spec := &deploymentspb.Spec{}
+	if err := protojson.Unmarshal(r.Spec, spec); err != nil {
+		return nil, fmt.Errorf("unable to parse the spec of deployment %s: %w", r.Deployment, err)
+	}
+
+	for _, res := range spec.Resources {
+		if service, ok := res.Config.(*deploymentspb.Resource_Service); ok {
+			service.Service.Env = env
+		}
+	}
+
+	return spec, nil
+}
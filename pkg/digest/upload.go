@@ -0,0 +1,265 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Upload - uploads the digest to a shared location so deployments can be tracked across machines and CI
+//
+// Supported locations are:
+//   - s3://<bucket>/<prefix>, the digest is stored at <prefix>/<project>/<stack>/<timestamp>.json using the default AWS credentials
+//   - http(s)://<endpoint>, the digest is sent as a JSON POST request
+func Upload(ctx context.Context, location string, d *Digest) error {
+	d.lock.Lock()
+	data, err := json.Marshal(d)
+	d.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	locationUrl, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid digest upload location %s: %w", location, err)
+	}
+
+	switch locationUrl.Scheme {
+	case "s3":
+		key := path.Join(strings.TrimPrefix(locationUrl.Path, "/"), d.Project, d.Stack, d.FileName())
+
+		return uploadToS3(ctx, locationUrl.Host, key, data)
+	case "http", "https":
+		return uploadToHttp(ctx, location, data)
+	default:
+		return fmt.Errorf("unsupported digest upload location %s, expected an s3:// or http(s):// location", location)
+	}
+}
+
+func uploadToS3(ctx context.Context, bucket string, key string, data []byte) error {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return err
+	}
+
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, "us-east-1")
+	if err != nil {
+		return fmt.Errorf("unable to determine region of bucket %s: %w", bucket, err)
+	}
+
+	_, err = s3.New(sess, aws.NewConfig().WithRegion(region)).PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to upload digest to s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return nil
+}
+
+func uploadToHttp(ctx context.Context, endpoint string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to upload digest to %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to upload digest to %s: %s", endpoint, resp.Status)
+	}
+
+	return nil
+}
+
+// Readable - returns true if digests uploaded to the location can be read back, only s3 locations can be listed
+func Readable(location string) bool {
+	locationUrl, err := url.Parse(location)
+
+	return err == nil && locationUrl.Scheme == "s3"
+}
+
+// sharedHistory - the digests of a project stack uploaded to a shared s3 location
+type sharedHistory struct {
+	client *s3.S3
+	bucket string
+	// keys of the digests, most recent first
+	keys []string
+}
+
+func listShared(ctx context.Context, location string, projectName string, stackName string) (*sharedHistory, error) {
+	locationUrl, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest upload location %s: %w", location, err)
+	}
+
+	if locationUrl.Scheme != "s3" {
+		return nil, fmt.Errorf("unable to read digests from %s, only s3:// locations can be read", location)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := locationUrl.Host
+
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, "us-east-1")
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine region of bucket %s: %w", bucket, err)
+	}
+
+	history := &sharedHistory{
+		client: s3.New(sess, aws.NewConfig().WithRegion(region)),
+		bucket: bucket,
+	}
+	prefix := path.Join(strings.TrimPrefix(locationUrl.Path, "/"), projectName, stackName) + "/"
+
+	err = history.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			key := aws.StringValue(object.Key)
+			if strings.HasSuffix(key, ".json") {
+				history.keys = append(history.keys, key)
+			}
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list digests in s3://%s/%s: %w", bucket, prefix, err)
+	}
+
+	// digest file names are timestamps, so sorting them orders the deployments
+	slices.Sort(history.keys)
+	slices.Reverse(history.keys)
+
+	return history, nil
+}
+
+func (h *sharedHistory) read(ctx context.Context, key string) (*Digest, error) {
+	object, err := h.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(h.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to download digest s3://%s/%s: %w", h.bucket, key, err)
+	}
+	defer object.Body.Close()
+
+	data, err := io.ReadAll(object.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Digest{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("unable to parse digest s3://%s/%s: %w", h.bucket, key, err)
+	}
+
+	return d, nil
+}
+
+// LatestShared - returns the most recent digest for a project stack uploaded to a shared s3 location, or nil if none have been uploaded
+func LatestShared(ctx context.Context, location string, projectName string, stackName string) (*Digest, error) {
+	history, err := listShared(ctx, location, projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.keys) == 0 {
+		return nil, nil
+	}
+
+	return history.read(ctx, history.keys[0])
+}
+
+// LatestSuccessfulShared - returns the most recent digest of a successful deployment of a project stack uploaded to a shared s3 location,
+// or nil if no successful deployment has been uploaded
+func LatestSuccessfulShared(ctx context.Context, location string, projectName string, stackName string) (*Digest, error) {
+	history, err := listShared(ctx, location, projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range history.keys {
+		d, err := history.read(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Success {
+			return d, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// LatestLocalOrShared - returns the most recent of the local digest and the digest uploaded to a shared location for a project stack,
+// along with where it was found, local or shared. Only the local history is read when the location can't be read back
+func LatestLocalOrShared(ctx context.Context, location string, projectName string, stackName string) (*Digest, string, error) {
+	latest, err := Latest(projectName, stackName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if location == "" || !Readable(location) {
+		return latest, "local", nil
+	}
+
+	shared, err := LatestShared(ctx, location, projectName, stackName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if shared != nil && (latest == nil || shared.EndTime.After(latest.EndTime)) {
+		return shared, "shared", nil
+	}
+
+	return latest, "local", nil
+}
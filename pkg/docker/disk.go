@@ -0,0 +1,61 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"strings"
+)
+
+// AvailableDiskSpace - returns the bytes available in the data directory of the engine, false when it can't be determined,
+// e.g. for remote engines and engines running in a virtual machine such as docker desktop
+func (d *Docker) AvailableDiskSpace() (uint64, bool) {
+	if d.Client == nil {
+		return 0, false
+	}
+
+	if _, remote := GetRemoteEngine(); remote {
+		return 0, false
+	}
+
+	info, err := d.Info(context.Background())
+	if err != nil || info.DockerRootDir == "" || strings.Contains(info.OperatingSystem, "Docker Desktop") {
+		return 0, false
+	}
+
+	// the data directory of engines running in a virtual machine usually doesn't exist on this machine
+	available, err := freeDiskSpace(info.DockerRootDir)
+	if err != nil {
+		return 0, false
+	}
+
+	return available, true
+}
+
+// ImageSize - returns the size of a local image in bytes, false when the image doesn't exist
+func (d *Docker) ImageSize(imageTag string) (int64, bool) {
+	if d.Client == nil {
+		return 0, false
+	}
+
+	inspect, _, err := d.ImageInspectWithRaw(context.Background(), imageTag)
+	if err != nil {
+		return 0, false
+	}
+
+	return inspect.Size, true
+}
@@ -19,6 +19,7 @@ package docker
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,57 +27,77 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"gopkg.in/yaml.v2"
 )
 
 type Docker struct {
 	*client.Client
 	// logger ContainerLogger
+
+	// the container engine the client is connected to
+	engine Engine
 }
 
+// VerifyDockerIsAvailable - checks a container engine with a docker compatible API is available to run containers
 func VerifyDockerIsAvailable() error {
-	// Create a new Docker client
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	info, err := discover()
 	if err != nil {
-		return fmt.Errorf("error creating Docker client: %w", err)
+		return err
 	}
 
-	// Ensure the client is closed when the function exits
-	defer func() {
-		if closeErr := cli.Close(); closeErr != nil {
-			panic(closeErr)
-		}
-	}()
-
-	// Perform a Docker operation to verify availability
-	if _, pingErr := cli.Ping(context.Background()); pingErr != nil {
-		return fmt.Errorf("Docker daemon is not running, please start the docker daemon and try again")
+	if !info.engine.hasApi() {
+		return fmt.Errorf("%s can only be used to build images, running containers requires docker or podman", info.engine)
 	}
 
 	return nil
 }
 
+// New - connects to the docker compatible API of the first available container engine, see Discover
 func New() (*Docker, error) {
 	if err := VerifyDockerIsAvailable(); err != nil {
 		return nil, err
 	}
 
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	return NewBuilder()
+}
+
+// NewBuilder - connects to the first available container engine for building and inspecting images,
+// the API client is nil for engines without a docker compatible API, e.g. nerdctl
+func NewBuilder() (*Docker, error) {
+	info, err := discover()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Docker{Client: dockerClient}, err
+	if !info.engine.hasApi() {
+		return &Docker{engine: info.engine}, nil
+	}
+
+	dockerClient, err := newClient(info.host)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Docker{Client: dockerClient, engine: info.engine}, err
+}
+
+// Engine - the container engine in use
+func (d *Docker) Engine() Engine {
+	return d.engine
 }
 
 var builderLock = sync.Mutex{}
@@ -91,40 +112,158 @@ func (d *Docker) createBuider() error {
 	return cmd.Run()
 }
 
-func (d *Docker) Build(dockerfile, srcPath, imageTag string, buildArgs map[string]string, excludes []string, buildLogger io.Writer) error {
-	err := d.createBuider()
-	if err != nil {
-		return err
+// PruneBuildCache - removes the build cache of the nitric builder, including the package manager caches mounted into builds
+func (d *Docker) PruneBuildCache() error {
+	if d.engine != EngineDocker {
+		return fmt.Errorf("pruning the build cache requires docker, remove the build cache of %s with its own commands", d.engine)
 	}
-	// write a temporary dockerignore file
-	ignoreFile, err := os.Create(fmt.Sprintf("%s.dockerignore", dockerfile))
+
+	// nothing has been built yet when the builder doesn't exist
+	if err := exec.Command("docker", "buildx", "inspect", "nitric").Run(); err != nil {
+		return nil
+	}
+
+	out, err := exec.Command("docker", "buildx", "prune", "--builder", "nitric", "--force").CombinedOutput()
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to prune the build cache: %s", strings.TrimSpace(string(out)))
 	}
 
-	_, err = ignoreFile.Write([]byte(strings.Join(excludes, "\n")))
+	return nil
+}
+
+// createRemoteBuilder - creates a builder for a remote BuildKit instance, returning the name of the builder
+func (d *Docker) createRemoteBuilder(endpoint string) (string, error) {
+	builderLock.Lock()
+	defer builderLock.Unlock()
+
+	// builders are named after their endpoint so each remote instance keeps its own builder
+	name := fmt.Sprintf("nitric-remote-%x", sha256.Sum256([]byte(endpoint)))[:22]
+
+	// the builder is reused by later builds, creating it again would fail as the name already exists
+	if err := exec.Command("docker", "buildx", "inspect", name).Run(); err == nil {
+		return name, nil
+	}
+
+	out, err := exec.Command("docker", "buildx", "create", "--name", name, "--driver=remote", "--node", name+"0", endpoint).CombinedOutput()
 	if err != nil {
-		return err
+		return "", fmt.Errorf("unable to create a builder for the remote BuildKit instance %s: %s", endpoint, strings.TrimSpace(string(out)))
 	}
 
-	err = ignoreFile.Close()
+	return name, nil
+}
+
+// builder - returns the name of the builder to use, the nitric builder is used unless another builder or a remote BuildKit instance is provided
+func (d *Docker) builder(builder string) (string, error) {
+	if builder == "" {
+		return "nitric", d.createBuider()
+	}
+
+	if strings.Contains(builder, "://") {
+		return d.createRemoteBuilder(builder)
+	}
+
+	return builder, nil
+}
+
+type buildOptions struct {
+	reproducible    bool
+	sourceDateEpoch int64
+	onProgress      func(BuildProgress)
+	builder         string
+	platforms       []string
+	noCache         bool
+	labels          map[string]string
+}
+
+type BuildOption func(*buildOptions)
+
+// WithReproducible - builds the image with all timestamps, including file timestamps in image layers, set to sourceDateEpoch
+func WithReproducible(sourceDateEpoch int64) BuildOption {
+	return func(o *buildOptions) {
+		o.reproducible = true
+		o.sourceDateEpoch = sourceDateEpoch
+	}
+}
+
+// WithProgress - reports structured build progress, build logs are still written to the build logger
+func WithProgress(onProgress func(BuildProgress)) BuildOption {
+	return func(o *buildOptions) {
+		o.onProgress = onProgress
+	}
+}
+
+// WithBuilder - builds on an existing docker buildx builder, or on a remote BuildKit instance when given its address, e.g. tcp://buildkit.example.com:1234
+func WithBuilder(builder string) BuildOption {
+	return func(o *buildOptions) {
+		o.builder = builder
+	}
+}
+
+// WithPlatforms - builds the image for the given platforms, e.g. linux/amd64 and linux/arm64, defaults to linux/amd64
+// Loading images built for more than one platform requires the containerd image store of the docker engine
+func WithPlatforms(platforms ...string) BuildOption {
+	return func(o *buildOptions) {
+		o.platforms = platforms
+	}
+}
+
+// WithNoCache - builds every layer of the image, without using the builder's layer cache
+func WithNoCache() BuildOption {
+	return func(o *buildOptions) {
+		o.noCache = true
+	}
+}
+
+// WithLabels - sets labels on the built image
+func WithLabels(labels map[string]string) BuildOption {
+	return func(o *buildOptions) {
+		o.labels = labels
+	}
+}
+
+// labelArgs - returns the --label build arguments for the labels, sorted so the arguments are stable between builds
+func labelArgs(labels map[string]string) []string {
+	keys := lo.Keys(labels)
+	slices.Sort(keys)
+
+	return lo.FlatMap(keys, func(key string, _ int) []string {
+		return []string{"--label", fmt.Sprintf("%s=%s", key, labels[key])}
+	})
+}
+
+// DefaultPlatform - the platform images are built for when no platforms are provided, matching most cloud runtimes
+const DefaultPlatform = "linux/amd64"
+
+// buildxArgs - returns the docker buildx build arguments
+func (d *Docker) buildxArgs(dockerfile, srcPath, imageTag string, buildArgs []string, options *buildOptions) ([]string, error) {
+	builder, err := d.builder(options.builder)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer func() {
-		os.Remove(ignoreFile.Name())
-	}()
+	platforms := DefaultPlatform
+	if len(options.platforms) > 0 {
+		platforms = strings.Join(options.platforms, ",")
+	}
 
-	buildArgsCmd := make([]string, 0)
-	for k, v := range buildArgs {
-		buildArgsCmd = append(buildArgsCmd, "--build-arg", fmt.Sprintf("%s=%s", k, v))
+	output := "--load"
+	if options.reproducible {
+		output = "--output=type=docker,rewrite-timestamp=true"
 	}
 
 	args := []string{
-		"buildx", "build", srcPath, "-f", dockerfile, "-t", imageTag, "--load", "--builder=nitric", "--platform", "linux/amd64",
+		"buildx", "build", srcPath, "-f", dockerfile, "-t", imageTag, output, "--builder=" + builder, "--platform", platforms,
+	}
+	args = append(args, buildArgs...)
+	args = append(args, labelArgs(options.labels)...)
+
+	if options.onProgress != nil {
+		args = append(args, "--progress=rawjson")
+	}
+
+	if options.noCache {
+		args = append(args, "--no-cache")
 	}
-	args = append(args, buildArgsCmd...)
 
 	cacheTo := ""
 	cacheFrom := ""
@@ -159,16 +298,83 @@ func (d *Docker) Build(dockerfile, srcPath, imageTag string, buildArgs map[strin
 		args = append(args, cacheFrom)
 	}
 
-	cmd := exec.Command("docker", args...)
+	return args, nil
+}
+
+func (d *Docker) Build(dockerfile, srcPath, imageTag string, buildArgs map[string]string, excludes []string, buildLogger io.Writer, opts ...BuildOption) error {
+	options := &buildOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// write a temporary dockerignore file
+	ignoreFile, err := os.Create(fmt.Sprintf("%s.dockerignore", dockerfile))
+	if err != nil {
+		return err
+	}
+
+	_, err = ignoreFile.Write([]byte(strings.Join(excludes, "\n")))
+	if err != nil {
+		return err
+	}
+
+	err = ignoreFile.Close()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		os.Remove(ignoreFile.Name())
+	}()
+
+	buildArgsCmd := make([]string, 0)
+	for k, v := range buildArgs {
+		buildArgsCmd = append(buildArgsCmd, "--build-arg", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	var args []string
+
+	switch d.engine {
+	case EnginePodman:
+		args, err = podmanBuildArgs(dockerfile, srcPath, imageTag, buildArgsCmd, ignoreFile.Name(), options)
+	case EngineNerdctl:
+		args, err = nerdctlBuildArgs(dockerfile, srcPath, imageTag, buildArgsCmd, options)
+	default:
+		args, err = d.buildxArgs(dockerfile, srcPath, imageTag, buildArgsCmd, options)
+	}
+
+	if err != nil {
+		return err
+	}
 
 	if buildLogger == nil {
 		buildLogger = io.Discard
 	}
 
-	cmd.Stdout = buildLogger
-	cmd.Stderr = buildLogger
+	// base image pulls and cache exports can fail due to registry or network issues, retry when that's the cause
+	return withRetry(fmt.Sprintf("build of %s", imageTag), buildLogger, func() (string, error) {
+		cmd := exec.Command(string(d.engine), args...)
 
-	return cmd.Run()
+		if options.reproducible {
+			// buildx passes SOURCE_DATE_EPOCH through to BuildKit, which uses it for image and history timestamps
+			cmd.Env = append(os.Environ(), fmt.Sprintf("SOURCE_DATE_EPOCH=%d", options.sourceDateEpoch))
+		}
+
+		output := &tailWriter{size: outputTailSize}
+		logger := io.MultiWriter(buildLogger, output)
+
+		cmd.Stdout = logger
+		cmd.Stderr = logger
+
+		if options.onProgress != nil && d.engine == EngineDocker {
+			// progress is written to stderr
+			cmd.Stderr = newProgressWriter(logger, options.onProgress)
+		}
+
+		err := cmd.Run()
+
+		return output.String(), err
+	})
 }
 
 type ErrorLine struct {
@@ -177,9 +383,14 @@ type ErrorLine struct {
 }
 
 type ErrorDetail struct {
+	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
+func (e *ErrorDetail) Error() string {
+	return e.Message
+}
+
 type Line struct {
 	Stream string `json:"stream"`
 	Status string `json:"status"`
@@ -213,47 +424,256 @@ func print(rd io.Reader) error {
 	}
 
 	if errLine.Error != "" {
-		return errors.New(errLine.Error)
+		// keeps the status code of registry errors, used to decide if the operation can be retried
+		return &ErrorDetail{Code: errLine.ErrorDetail.Code, Message: errLine.Error}
 	}
 
 	return scanner.Err()
 }
 
-// func (d *Docker) ListImages(stackName, containerName string) ([]Image, error) {
-// 	opts := types.ImageListOptions{Filters: filters.NewArgs()}
-// 	opts.Filters.Add("reference", fmt.Sprintf("%s-%s-*", stackName, containerName))
+// Image - an image in the local store of the container engine
+type Image struct {
+	ID string `json:"id"`
+	// References of the image, e.g. my-service:latest
+	Tags    []string          `json:"tags"`
+	Created time.Time         `json:"created"`
+	Size    int64             `json:"size"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
 
-// 	imageSummaries, err := d.Client.ImageList(context.Background(), opts)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+// ListImages - returns the local images matching any of the references, e.g. my-service matches every tag of my-service
+func (d *Docker) ListImages(references ...string) ([]Image, error) {
+	images := []Image{}
+	seen := map[string]bool{}
 
-// 	imgs := []Image{}
+	for _, ref := range references {
+		matched, err := d.listImages("reference", ref)
+		if err != nil {
+			return nil, err
+		}
 
-// 	for _, i := range imageSummaries {
-// 		nameParts := strings.Split(i.RepoTags[0], ":")
-// 		id := strings.Split(i.ID, ":")[1][0:12]
+		for _, image := range matched {
+			if seen[image.ID] {
+				continue
+			}
 
-// 		imgs = append(imgs, Image{
-// 			ID:         id,
-// 			Repository: nameParts[0],
-// 			Tag:        nameParts[1],
-// 			CreatedAt:  time.Unix(i.Created, 0).Local().String(),
-// 		})
-// 	}
+			seen[image.ID] = true
 
-// 	return imgs, err
-// }
+			images = append(images, image)
+		}
+	}
 
-func (d *Docker) ImagePull(rawImage string, opts types.ImagePullOptions) error {
-	resp, err := d.Client.ImagePull(context.Background(), rawImage, opts)
+	return images, nil
+}
+
+// ListImagesWithLabel - returns the local images with the label set to the value
+func (d *Docker) ListImagesWithLabel(label, value string) ([]Image, error) {
+	return d.listImages("label", fmt.Sprintf("%s=%s", label, value))
+}
+
+// listImages - returns the local images matching an image list filter, e.g. reference=my-service or label=key=value
+func (d *Docker) listImages(filter, value string) ([]Image, error) {
+	// engines without a docker compatible API are listed with their CLI, then inspected for the details of each image
+	if d.Client == nil {
+		out, err := exec.Command(string(d.engine), "image", "ls", "--filter", filter+"="+value, "--format", "{{.ID}}").Output()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list images %s: %w", value, err)
+		}
+
+		ids := lo.Uniq(strings.Fields(string(out)))
+		if len(ids) == 0 {
+			return []Image{}, nil
+		}
+
+		out, err = exec.Command(string(d.engine), append([]string{"image", "inspect"}, ids...)...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("unable to inspect images %s: %w", value, err)
+		}
+
+		inspected := []types.ImageInspect{}
+		if err := json.Unmarshal(out, &inspected); err != nil {
+			return nil, fmt.Errorf("unable to read images %s: %w", value, err)
+		}
+
+		return lo.UniqBy(lo.Map(inspected, func(inspect types.ImageInspect, _ int) Image {
+			return imageFromInspect(inspect)
+		}), func(image Image) string { return image.ID }), nil
+	}
+
+	opts := types.ImageListOptions{Filters: filters.NewArgs()}
+	opts.Filters.Add(filter, value)
+
+	summaries, err := d.Client.ImageList(context.Background(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list images %s: %w", value, err)
+	}
+
+	return lo.Map(summaries, func(summary image.Summary, _ int) Image {
+		return Image{ID: summary.ID, Tags: summary.RepoTags, Created: time.Unix(summary.Created, 0), Size: summary.Size, Labels: summary.Labels}
+	}), nil
+}
+
+// InspectImage - returns the local image with the reference or ID
+func (d *Docker) InspectImage(ref string) (Image, error) {
+	if d.Client == nil {
+		out, err := exec.Command(string(d.engine), "image", "inspect", ref).Output()
+		if err != nil {
+			return Image{}, fmt.Errorf("unable to inspect image %s: %w", ref, err)
+		}
+
+		inspected := []types.ImageInspect{}
+		if err := json.Unmarshal(out, &inspected); err != nil {
+			return Image{}, fmt.Errorf("unable to read image %s: %w", ref, err)
+		}
+
+		if len(inspected) == 0 {
+			return Image{}, fmt.Errorf("image %s not found", ref)
+		}
+
+		return imageFromInspect(inspected[0]), nil
+	}
+
+	inspect, _, err := d.ImageInspectWithRaw(context.Background(), ref)
+	if err != nil {
+		return Image{}, err
+	}
+
+	return imageFromInspect(inspect), nil
+}
+
+func imageFromInspect(inspect types.ImageInspect) Image {
+	created, _ := time.Parse(time.RFC3339Nano, inspect.Created)
+
+	return Image{ID: inspect.ID, Tags: inspect.RepoTags, Created: created, Size: inspect.Size, Labels: lo.FromPtr(inspect.Config).Labels}
+}
+
+// ResolveImageDigest - returns the registry digest of the provided image reference, e.g. node:20-alpine -> sha256:...
+func (d *Docker) ResolveImageDigest(image string) (string, error) {
+	if d.engine == EnginePodman || d.engine == EngineNerdctl {
+		return d.resolvePulledImageDigest(image)
+	}
+
+	out, err := exec.Command("docker", "buildx", "imagetools", "inspect", image, "--format", "{{json .Manifest}}").Output()
 	if err != nil {
-		return errors.WithMessage(err, "Pull")
+		return "", fmt.Errorf("unable to resolve digest for image %s: %w", image, err)
 	}
 
-	defer resp.Close()
+	manifest := struct {
+		Digest string `json:"digest"`
+	}{}
+
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return "", fmt.Errorf("unable to parse manifest for image %s: %w", image, err)
+	}
+
+	if manifest.Digest == "" {
+		return "", fmt.Errorf("no digest found for image %s", image)
+	}
+
+	return manifest.Digest, nil
+}
+
+// resolvePulledImageDigest - pulls the image and returns its registry digest, for engines without buildx
+func (d *Docker) resolvePulledImageDigest(image string) (string, error) {
+	if out, err := exec.Command(string(d.engine), "pull", "--quiet", image).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("unable to resolve digest for image %s: %s", image, strings.TrimSpace(string(out)))
+	}
+
+	out, err := exec.Command(string(d.engine), "image", "inspect", image, "--format", "{{json .RepoDigests}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve digest for image %s: %w", image, err)
+	}
+
+	repoDigests := []string{}
+	if err := json.Unmarshal(out, &repoDigests); err != nil {
+		return "", fmt.Errorf("unable to parse digests for image %s: %w", image, err)
+	}
+
+	for _, repoDigest := range repoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("no digest found for image %s", image)
+}
+
+// ImageId - returns the content addressable ID of a local image
+func (d *Docker) ImageId(imageTag string) (string, error) {
+	if d.Client == nil {
+		out, err := exec.Command(string(d.engine), "image", "inspect", imageTag, "--format", "{{.ID}}").Output()
+		if err != nil {
+			return "", fmt.Errorf("unable to inspect image %s: %w", imageTag, err)
+		}
+
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	inspect, _, err := d.ImageInspectWithRaw(context.Background(), imageTag)
+	if err != nil {
+		return "", err
+	}
+
+	return inspect.ID, nil
+}
+
+// ImageTag - tags a local image with an additional name
+func (d *Docker) ImageTag(source string, target string) error {
+	if d.Client == nil {
+		out, err := exec.Command(string(d.engine), "tag", source, target).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("unable to tag image %s as %s: %s", source, target, strings.TrimSpace(string(out)))
+		}
+
+		return nil
+	}
+
+	return d.Client.ImageTag(context.Background(), source, target)
+}
+
+// ImageCommand - returns the entrypoint and command a local image runs, e.g. [node index.js]
+func (d *Docker) ImageCommand(imageTag string) ([]string, error) {
+	imageConfig := struct {
+		Entrypoint []string
+		Cmd        []string
+	}{}
+
+	if d.Client == nil {
+		out, err := exec.Command(string(d.engine), "image", "inspect", imageTag, "--format", "{{json .Config}}").Output()
+		if err != nil {
+			return nil, fmt.Errorf("unable to inspect image %s: %w", imageTag, err)
+		}
+
+		if err := json.Unmarshal(out, &imageConfig); err != nil {
+			return nil, fmt.Errorf("unable to parse the config of image %s: %w", imageTag, err)
+		}
+	} else {
+		inspect, _, err := d.ImageInspectWithRaw(context.Background(), imageTag)
+		if err != nil {
+			return nil, err
+		}
+
+		if inspect.Config != nil {
+			imageConfig.Entrypoint = inspect.Config.Entrypoint
+			imageConfig.Cmd = inspect.Config.Cmd
+		}
+	}
+
+	return append(imageConfig.Entrypoint, imageConfig.Cmd...), nil
+}
+
+func (d *Docker) ImagePull(rawImage string, opts types.ImagePullOptions) error {
+	// the docker engine keeps layers that were fully downloaded, so a retried pull resumes from the remaining layers
+	return withRetry(fmt.Sprintf("pull of %s", rawImage), log.Default().Writer(), func() (string, error) {
+		resp, err := d.Client.ImagePull(context.Background(), rawImage, opts)
+		if err != nil {
+			return "", errors.WithMessage(err, "Pull")
+		}
+
+		defer resp.Close()
 
-	return print(resp)
+		return "", print(resp)
+	})
 }
 
 func (d *Docker) ContainerCreate(config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name string) (string, error) {
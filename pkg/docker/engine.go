@@ -0,0 +1,290 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/client"
+	"github.com/samber/lo"
+)
+
+// Engine - a container engine used to build images and run containers
+type Engine string
+
+const (
+	EngineDocker Engine = "docker"
+	// Podman provides a docker compatible API, which is used to run containers
+	EnginePodman Engine = "podman"
+	// nerdctl doesn't provide a docker compatible API, so it can only be used to build images
+	EngineNerdctl Engine = "nerdctl"
+)
+
+// Engines - the supported container engines, in the order they're detected unless configured otherwise
+var Engines = []Engine{EngineDocker, EnginePodman, EngineNerdctl}
+
+// engineEnvVar - overrides the order container engines are detected in, e.g. NITRIC_CONTAINER_ENGINE=podman,docker
+const engineEnvVar = "NITRIC_CONTAINER_ENGINE"
+
+var (
+	engineLock       sync.Mutex
+	configuredOrder  = Engines
+	discoveredEngine = map[string]engineInfo{}
+)
+
+type engineInfo struct {
+	engine Engine
+	// address of the engine's docker compatible API, empty to use the docker environment defaults
+	host string
+}
+
+// ValidateEngine - checks that the name is a supported container engine
+func ValidateEngine(name string) error {
+	if !slices.Contains(Engines, Engine(name)) {
+		return fmt.Errorf("unsupported container engine %s, must be one of %s", name, strings.Join(lo.Map(Engines, func(e Engine, _ int) string { return string(e) }), ", "))
+	}
+
+	return nil
+}
+
+// SetEngineOrder - sets the order container engines are detected in, NITRIC_CONTAINER_ENGINE takes precedence over this order
+func SetEngineOrder(engines []string) error {
+	order := []Engine{}
+
+	for _, name := range engines {
+		if err := ValidateEngine(name); err != nil {
+			return err
+		}
+
+		order = append(order, Engine(name))
+	}
+
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	configuredOrder = lo.Ternary(len(order) > 0, order, Engines)
+
+	return nil
+}
+
+// EngineOrder - the order container engines are detected in
+func EngineOrder() ([]Engine, error) {
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	fromEnv := strings.TrimSpace(os.Getenv(engineEnvVar))
+	if fromEnv == "" {
+		return configuredOrder, nil
+	}
+
+	order := []Engine{}
+
+	for _, name := range strings.Split(fromEnv, ",") {
+		name = strings.TrimSpace(name)
+		if err := ValidateEngine(name); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", engineEnvVar, err)
+		}
+
+		order = append(order, Engine(name))
+	}
+
+	return order, nil
+}
+
+// Discover - returns the first available container engine, in the configured detection order
+func Discover() (Engine, error) {
+	info, err := discover()
+
+	return info.engine, err
+}
+
+func discover() (engineInfo, error) {
+	order, err := EngineOrder()
+	if err != nil {
+		return engineInfo{}, err
+	}
+
+	key := strings.Join(lo.Map(order, func(e Engine, _ int) string { return string(e) }), ",")
+
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	if info, ok := discoveredEngine[key]; ok {
+		return info, nil
+	}
+
+	unavailable := []string{}
+
+	for _, engine := range order {
+		host, err := engine.detect()
+		if err != nil {
+			unavailable = append(unavailable, err.Error())
+			continue
+		}
+
+		discoveredEngine[key] = engineInfo{engine: engine, host: host}
+
+		return discoveredEngine[key], nil
+	}
+
+	return engineInfo{}, fmt.Errorf("no container engine is available: %s", strings.Join(unavailable, ", "))
+}
+
+// detect - checks the engine is installed and running, returning the address of its docker compatible API
+func (e Engine) detect() (string, error) {
+	if _, err := exec.LookPath(string(e)); err != nil && e != EngineDocker {
+		return "", fmt.Errorf("%s is not installed", e)
+	}
+
+	switch e {
+	case EnginePodman:
+		host, err := podmanHost()
+		if err != nil {
+			return "", err
+		}
+
+		if err := ping(host); err != nil {
+			return "", fmt.Errorf("the podman API service isn't running at %s, start it with podman machine start or systemctl --user start podman.socket", host)
+		}
+
+		return host, nil
+	case EngineNerdctl:
+		if out, err := exec.Command("nerdctl", "version").CombinedOutput(); err != nil {
+			return "", fmt.Errorf("nerdctl is unable to connect to containerd: %s", strings.TrimSpace(string(out)))
+		}
+
+		return "", nil
+	default:
+		// the docker CLI isn't required to use a docker engine's API, e.g. with DOCKER_HOST
+		if err := ping(""); err != nil {
+			return "", fmt.Errorf("the docker daemon isn't running")
+		}
+
+		return "", nil
+	}
+}
+
+// hasApi - whether the engine provides a docker compatible API
+func (e Engine) hasApi() bool {
+	return e != EngineNerdctl
+}
+
+// podmanHost - returns the address of the podman API service, from CONTAINER_HOST or podman's configuration
+func podmanHost() (string, error) {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host, nil
+	}
+
+	out, err := exec.Command("podman", "info", "--format", "{{json .Host.RemoteSocket}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("podman is unable to connect to its service, start it with podman machine start")
+	}
+
+	socket := struct {
+		Path   string `json:"path"`
+		Exists bool   `json:"exists"`
+	}{}
+
+	if err := json.Unmarshal(out, &socket); err != nil || socket.Path == "" {
+		return "", fmt.Errorf("unable to find the podman API socket")
+	}
+
+	if !socket.Exists {
+		return "", fmt.Errorf("the podman API socket %s doesn't exist, start it with systemctl --user start podman.socket", socket.Path)
+	}
+
+	if !strings.Contains(socket.Path, "://") {
+		return "unix://" + socket.Path, nil
+	}
+
+	return socket.Path, nil
+}
+
+// newClient - creates a client for the docker compatible API at host, using the docker environment defaults when host is empty
+func newClient(host string) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+
+	return client.NewClientWithOpts(opts...)
+}
+
+func ping(host string) error {
+	cli, err := newClient(host)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	_, err = cli.Ping(context.Background())
+
+	return err
+}
+
+// podmanBuildArgs - returns the podman build arguments, podman reads ignore files from the build context so the ignore file is passed explicitly
+func podmanBuildArgs(dockerfile, srcPath, imageTag string, buildArgs []string, ignoreFile string, options *buildOptions) ([]string, error) {
+	if options.builder != "" {
+		return nil, fmt.Errorf("builders are only supported with docker, unset build.builder to build with podman")
+	}
+
+	if len(options.platforms) > 1 {
+		return nil, fmt.Errorf("building for more than one platform is only supported with docker")
+	}
+
+	args := []string{"build", srcPath, "-f", dockerfile, "-t", imageTag, "--ignorefile", ignoreFile, "--platform", lo.Ternary(len(options.platforms) > 0, strings.Join(options.platforms, ","), DefaultPlatform)}
+	args = append(args, buildArgs...)
+	args = append(args, labelArgs(options.labels)...)
+
+	if options.reproducible {
+		args = append(args, "--timestamp", fmt.Sprint(options.sourceDateEpoch))
+	}
+
+	if options.noCache {
+		args = append(args, "--no-cache")
+	}
+
+	return args, nil
+}
+
+// nerdctlBuildArgs - returns the nerdctl build arguments, nerdctl builds with BuildKit which reads the ignore file next to the dockerfile
+func nerdctlBuildArgs(dockerfile, srcPath, imageTag string, buildArgs []string, options *buildOptions) ([]string, error) {
+	if options.builder != "" {
+		return nil, fmt.Errorf("builders are only supported with docker, unset build.builder to build with nerdctl")
+	}
+
+	if options.reproducible {
+		return nil, fmt.Errorf("reproducible builds are only supported with docker and podman")
+	}
+
+	args := []string{"build", srcPath, "-f", dockerfile, "-t", imageTag, "--platform", lo.Ternary(len(options.platforms) > 0, strings.Join(options.platforms, ","), DefaultPlatform)}
+	args = append(args, buildArgs...)
+	args = append(args, labelArgs(options.labels)...)
+
+	if options.noCache {
+		args = append(args, "--no-cache")
+	}
+
+	return args, nil
+}
@@ -0,0 +1,250 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BuildProgress - a snapshot of the progress of an image build, sourced from BuildKit's progress output
+type BuildProgress struct {
+	// Name of the most recently started build step, e.g. [2/6] RUN npm install
+	Step string
+	// Number of build steps that have completed, including cached steps
+	CompletedSteps int
+	// Number of cached build steps
+	CachedSteps int
+	// Total number of build steps currently known to BuildKit
+	TotalSteps int
+	// Bytes transferred for in-progress steps, e.g. layers being pulled
+	Current int64
+	// Total bytes to be transferred for in-progress steps, when known
+	Total int64
+	// Estimated percentage of the build that is complete, between 0 and 100
+	Percent float64
+	// Estimated time remaining, zero when no estimate is available
+	ETA time.Duration
+}
+
+// solveStatus - the subset of BuildKit's SolveStatus written by `docker buildx build --progress=rawjson`
+type solveStatus struct {
+	Vertexes []struct {
+		Digest    string
+		Name      string
+		Started   *time.Time
+		Completed *time.Time
+		Cached    bool
+		Error     string
+	}
+	Statuses []struct {
+		ID        string
+		Vertex    string
+		Current   int64
+		Total     int64
+		Completed *time.Time
+	}
+	Logs []struct {
+		Vertex string
+		Data   []byte
+	}
+}
+
+type vertexState struct {
+	name      string
+	started   bool
+	completed bool
+	cached    bool
+}
+
+type statusState struct {
+	current   int64
+	total     int64
+	completed bool
+}
+
+// minProgressInterval - limits how often progress is reported while the current step is unchanged
+const minProgressInterval = 200 * time.Millisecond
+
+// progressWriter - parses rawjson progress output, reporting build progress and writing human readable logs
+type progressWriter struct {
+	logs       io.Writer
+	onProgress func(BuildProgress)
+
+	lock         sync.Mutex
+	buf          []byte
+	start        time.Time
+	lastReported time.Time
+	step         string
+	vertexes     map[string]*vertexState
+	statuses     map[string]map[string]*statusState
+}
+
+func newProgressWriter(logs io.Writer, onProgress func(BuildProgress)) *progressWriter {
+	return &progressWriter{
+		logs:       logs,
+		onProgress: onProgress,
+		start:      time.Now(),
+		vertexes:   map[string]*vertexState{},
+		statuses:   map[string]map[string]*statusState{},
+	}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := w.buf[:idx]
+		w.buf = w.buf[idx+1:]
+
+		if err := w.handleLine(line); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *progressWriter) handleLine(line []byte) error {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+
+	status := solveStatus{}
+	if err := json.Unmarshal(line, &status); err != nil {
+		// not progress output, e.g. an error from the docker cli, pass it through as is
+		_, err := fmt.Fprintf(w.logs, "%s\n", line)
+		return err
+	}
+
+	stepChanged := false
+
+	for _, v := range status.Vertexes {
+		state, ok := w.vertexes[v.Digest]
+		if !ok {
+			state = &vertexState{name: v.Name}
+			w.vertexes[v.Digest] = state
+		}
+
+		if v.Started != nil && !state.started {
+			state.started = true
+			w.step = v.Name
+			stepChanged = true
+
+			if _, err := fmt.Fprintf(w.logs, "%s\n", v.Name); err != nil {
+				return err
+			}
+		}
+
+		if (v.Completed != nil || v.Cached) && !state.completed {
+			state.completed = true
+			state.cached = v.Cached
+			stepChanged = true
+		}
+
+		if v.Error != "" {
+			if _, err := fmt.Fprintf(w.logs, "%s: %s\n", v.Name, v.Error); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range status.Statuses {
+		if w.statuses[s.Vertex] == nil {
+			w.statuses[s.Vertex] = map[string]*statusState{}
+		}
+
+		w.statuses[s.Vertex][s.ID] = &statusState{
+			current:   s.Current,
+			total:     s.Total,
+			completed: s.Completed != nil,
+		}
+	}
+
+	for _, l := range status.Logs {
+		if _, err := w.logs.Write(l.Data); err != nil {
+			return err
+		}
+	}
+
+	if stepChanged || time.Since(w.lastReported) >= minProgressInterval {
+		w.lastReported = time.Now()
+		w.onProgress(w.progress())
+	}
+
+	return nil
+}
+
+// progress - estimates build progress from completed steps, using transferred bytes to estimate progress of in-progress steps
+func (w *progressWriter) progress() BuildProgress {
+	progress := BuildProgress{
+		Step:       strings.TrimSpace(w.step),
+		TotalSteps: len(w.vertexes),
+	}
+
+	partialSteps := 0.0
+
+	for digest, vertex := range w.vertexes {
+		if vertex.completed {
+			progress.CompletedSteps++
+
+			if vertex.cached {
+				progress.CachedSteps++
+			}
+
+			continue
+		}
+
+		var current, total int64
+
+		for _, status := range w.statuses[digest] {
+			current += status.current
+			total += status.total
+		}
+
+		progress.Current += current
+		progress.Total += total
+
+		if total > 0 {
+			partialSteps += float64(min(current, total)) / float64(total)
+		}
+	}
+
+	if progress.TotalSteps > 0 {
+		progress.Percent = 100 * (float64(progress.CompletedSteps) + partialSteps) / float64(progress.TotalSteps)
+	}
+
+	if progress.Percent > 0 && progress.Percent < 100 {
+		elapsed := time.Since(w.start)
+		progress.ETA = time.Duration(float64(elapsed) * (100 - progress.Percent) / progress.Percent).Round(time.Second)
+	}
+
+	return progress
+}
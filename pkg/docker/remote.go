@@ -0,0 +1,119 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+// RemoteEngine - a docker engine running on another machine, from DOCKER_HOST
+type RemoteEngine struct {
+	url *url.URL
+}
+
+// GetRemoteEngine - returns the docker engine from DOCKER_HOST when it points at a remote machine
+func GetRemoteEngine() (*RemoteEngine, bool) {
+	dockerHost, ok := os.LookupEnv("DOCKER_HOST")
+	if !ok || dockerHost == "" {
+		return nil, false
+	}
+
+	engineUrl, err := url.Parse(dockerHost)
+	if err != nil || !slices.Contains([]string{"tcp", "ssh", "http", "https"}, engineUrl.Scheme) {
+		return nil, false
+	}
+
+	if slices.Contains([]string{"", "localhost", "127.0.0.1", "::1"}, engineUrl.Hostname()) {
+		return nil, false
+	}
+
+	return &RemoteEngine{url: engineUrl}, true
+}
+
+// Host - the hostname of the machine running the engine
+func (r *RemoteEngine) Host() string {
+	return r.url.Hostname()
+}
+
+// IsSSH - returns true if the engine is accessed over ssh
+func (r *RemoteEngine) IsSSH() bool {
+	return r.url.Scheme == "ssh"
+}
+
+// ReachableAddress - returns the address of this machine on the route to the engine, containers on the engine can reach servers listening on it
+func (r *RemoteEngine) ReachableAddress() (string, error) {
+	port := r.url.Port()
+	if port == "" {
+		port = "2375"
+	}
+
+	// dialing udp doesn't send any packets, it only resolves the route to the engine
+	conn, err := net.Dial("udp", net.JoinHostPort(r.Host(), port))
+	if err != nil {
+		return "", fmt.Errorf("unable to find a route to docker engine %s: %w", r.Host(), err)
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// ReverseTunnel - forwards a port on the engine's machine back to the same port on this machine over ssh,
+// returning a function that closes the tunnel
+func (r *RemoteEngine) ReverseTunnel(port string) (func(), error) {
+	if !r.IsSSH() {
+		return nil, fmt.Errorf("docker engine %s is not accessed over ssh", r.Host())
+	}
+
+	args := []string{"-N", "-o", "ExitOnForwardFailure=yes", "-o", "BatchMode=yes", "-R", fmt.Sprintf("%s:localhost:%s", port, port)}
+
+	if r.url.Port() != "" {
+		args = append(args, "-p", r.url.Port())
+	}
+
+	destination := r.Host()
+	if r.url.User != nil {
+		destination = r.url.User.Username() + "@" + destination
+	}
+
+	cmd := exec.Command("ssh", append(args, destination)...)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start ssh tunnel to docker engine %s: %w", r.Host(), err)
+	}
+
+	exited := make(chan error, 1)
+
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	// ssh exits shortly after starting when the port can't be forwarded
+	select {
+	case err := <-exited:
+		return nil, fmt.Errorf("unable to forward port %s from docker engine %s over ssh: %w", port, r.Host(), err)
+	case <-time.After(2 * time.Second):
+	}
+
+	return func() {
+		_ = cmd.Process.Kill()
+	}, nil
+}
@@ -0,0 +1,161 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+)
+
+const (
+	defaultRetries = 3
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	// amount of build output kept to determine if a failed build can be retried
+	outputTailSize = 8 * 1024
+)
+
+// transientStatus - registry responses that are likely to succeed on retry, as the status lines reported by the registry
+// clients of the engines and BuildKit, e.g. "unexpected status: 503 Service Unavailable"
+var transientStatus = regexp.MustCompile(`\b(429 Too Many Requests|502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout)\b`)
+
+// transientRegistryCode - registry error codes that are likely to succeed on retry, reported as "<code>: <message>",
+// see https://github.com/distribution/distribution/blob/main/registry/api/errcode/register.go
+var transientRegistryCode = regexp.MustCompile(`(^|[\s:])(toomanyrequests|unavailable): `)
+
+// transientNetwork - network failures as reported by the go net packages, e.g. "dial tcp 10.0.0.1:443: connect: connection refused"
+var transientNetwork = regexp.MustCompile(`\b(dial|read|write) (tcp|udp)[46]? \S+: (\S+: )?(i/o timeout|connection reset by peer|connection refused|broken pipe)` +
+	`|\blookup \S+( on \S+)?: (i/o timeout|server misbehaving|[Tt]emporary failure in name resolution)` +
+	`|net/http: TLS handshake timeout`)
+
+// transientCodes - the status codes of engine API and JSON stream errors that are likely to succeed on retry
+var transientCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// isTransientError - whether an error returned by the engine API was caused by a network or registry failure
+func isTransientError(err error) bool {
+	var (
+		netErr    net.Error
+		streamErr *ErrorDetail
+	)
+
+	switch {
+	case errdefs.IsUnavailable(err), errdefs.IsDeadline(err):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &streamErr) && slices.Contains(transientCodes, streamErr.Code):
+		return true
+	}
+
+	// registry errors are passed through by the engine as their message
+	return isTransientOutput(err.Error())
+}
+
+// isTransientOutput - whether the output of a failed engine command reports a network or registry failure
+func isTransientOutput(output string) bool {
+	return transientStatus.MatchString(output) || transientRegistryCode.MatchString(output) || transientNetwork.MatchString(output)
+}
+
+// maxRetries - the number of times registry operations are retried, configured with NITRIC_DOCKER_RETRIES
+func maxRetries() int {
+	retries, err := strconv.Atoi(os.Getenv("NITRIC_DOCKER_RETRIES"))
+	if err != nil || retries < 0 {
+		return defaultRetries
+	}
+
+	return retries
+}
+
+// backoff - exponential backoff with jitter for the given retry attempt, starting at 1
+func backoff(attempt int) time.Duration {
+	delay := initialBackoff << (attempt - 1)
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	// add up to 20% jitter to avoid concurrent builds retrying in lockstep
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// withRetry - runs the operation, retrying with exponential backoff while it fails with a transient error.
+// retried pulls and builds resume from the layers and build steps that already completed.
+func withRetry(operation string, logger io.Writer, fn func() (string, error)) error {
+	retries := maxRetries()
+
+	for attempt := 1; ; attempt++ {
+		output, err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if attempt > retries || !(isTransientError(err) || isTransientOutput(output)) {
+			return err
+		}
+
+		delay := backoff(attempt)
+
+		fmt.Fprintf(logger, "%s failed with a transient error, retrying in %s (%d/%d): %s\n", operation, delay.Round(time.Millisecond), attempt, retries, err)
+
+		time.Sleep(delay)
+	}
+}
+
+// tailWriter - keeps the most recent output written to it
+type tailWriter struct {
+	lock sync.Mutex
+	tail []byte
+	size int
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.tail = append(t.tail, p...)
+	if len(t.tail) > t.size {
+		t.tail = t.tail[len(t.tail)-t.size:]
+	}
+
+	return len(p), nil
+}
+
+func (t *tailWriter) String() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return string(t.tail)
+}
@@ -0,0 +1,190 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+)
+
+func TestIsTransientOutput(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		output    string
+		transient bool
+	}{
+		{
+			name:      "rate limited pull",
+			output:    "Error response from daemon: toomanyrequests: You have reached your pull rate limit. You may increase the limit by authenticating and upgrading: https://www.docker.com/increase-rate-limit",
+			transient: true,
+		},
+		{
+			name:      "buildkit registry unavailable",
+			output:    `ERROR: failed to solve: node:20-alpine: failed to resolve source metadata for docker.io/library/node:20-alpine: unexpected status from HEAD request to https://registry-1.docker.io/v2/library/node/manifests/20-alpine: 503 Service Unavailable`,
+			transient: true,
+		},
+		{
+			name:      "bad gateway",
+			output:    "error pushing image: received unexpected HTTP status: 502 Bad Gateway",
+			transient: true,
+		},
+		{
+			name:      "gateway timeout",
+			output:    "unknown: unexpected status: 504 Gateway Timeout",
+			transient: true,
+		},
+		{
+			name:      "registry unavailable code",
+			output:    "unavailable: service unavailable",
+			transient: true,
+		},
+		{
+			name:      "connection refused",
+			output:    `Error response from daemon: Get "https://registry-1.docker.io/v2/": dial tcp 54.236.113.205:443: connect: connection refused`,
+			transient: true,
+		},
+		{
+			name:      "connection reset",
+			output:    `failed to copy: read tcp 172.17.0.2:51234->104.18.123.25:443: read: connection reset by peer`,
+			transient: true,
+		},
+		{
+			name:      "dns timeout",
+			output:    `Error response from daemon: Get "https://registry-1.docker.io/v2/": dial tcp: lookup registry-1.docker.io on 127.0.0.53:53: server misbehaving`,
+			transient: true,
+		},
+		{
+			name:      "temporary dns failure",
+			output:    `dial tcp: lookup ghcr.io: Temporary failure in name resolution`,
+			transient: true,
+		},
+		{
+			name:      "tls handshake timeout",
+			output:    `Error response from daemon: Get "https://registry-1.docker.io/v2/": net/http: TLS handshake timeout`,
+			transient: true,
+		},
+		{
+			name:   "access denied",
+			output: "denied: requested access to the resource is denied",
+		},
+		{
+			name:   "unauthorized",
+			output: "unauthorized: authentication required",
+		},
+		{
+			name:   "ecr authorization token expired",
+			output: "denied: Your authorization token has expired. Reauthenticate and try again.",
+		},
+		{
+			name:   "manifest unknown",
+			output: "Error response from daemon: manifest for node:99 not found: manifest unknown: manifest unknown",
+		},
+		{
+			name:   "image not found",
+			output: "pull access denied for mycompany/missing, repository does not exist or may require 'docker login': denied: requested access to the resource is denied",
+		},
+		{
+			name:   "failed build step",
+			output: `ERROR: failed to solve: process "/bin/sh -c npm ci" did not complete successfully: exit code: 1`,
+		},
+		{
+			name:   "dockerfile syntax",
+			output: "ERROR: failed to solve: dockerfile parse error on line 3: unknown instruction: RUNN",
+		},
+		{
+			name:   "unknown host",
+			output: `dial tcp: lookup registry.example.invalid: no such host`,
+		},
+		{
+			name:   "status code in build output",
+			output: "npm ERR! 404 Not Found - GET https://registry.npmjs.org/missing-package",
+		},
+		{
+			name:   "transient code in a word",
+			output: "ERROR: toomanyrequestsfoo is not a valid tag",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if transient := isTransientOutput(tt.output); transient != tt.transient {
+				t.Errorf("expected transient to be %t for %q", tt.transient, tt.output)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{
+			name:      "engine unavailable",
+			err:       errdefs.Unavailable(errors.New("engine unavailable")),
+			transient: true,
+		},
+		{
+			name:      "deadline exceeded",
+			err:       errdefs.Deadline(context.DeadlineExceeded),
+			transient: true,
+		},
+		{
+			name:      "connection reset",
+			err:       fmt.Errorf("unable to push image: %w", syscall.ECONNRESET),
+			transient: true,
+		},
+		{
+			name:      "unexpected eof",
+			err:       fmt.Errorf("unable to read pull progress: %w", io.ErrUnexpectedEOF),
+			transient: true,
+		},
+		{
+			name:      "rate limited stream error",
+			err:       &ErrorDetail{Code: 429, Message: "rate limited"},
+			transient: true,
+		},
+		{
+			name:      "registry message",
+			err:       errors.New("toomanyrequests: retry later"),
+			transient: true,
+		},
+		{
+			name: "denied stream error",
+			err:  &ErrorDetail{Code: 403, Message: "denied: requested access to the resource is denied"},
+		},
+		{
+			name: "not found",
+			err:  errdefs.NotFound(errors.New("No such image: mycompany/missing:latest")),
+		},
+		{
+			name: "unauthorized",
+			err:  errdefs.Unauthorized(errors.New("unauthorized: authentication required")),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if transient := isTransientError(tt.err); transient != tt.transient {
+				t.Errorf("expected transient to be %t for %v", tt.transient, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/generate"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+type block interface{}
+
+type heading struct {
+	level int
+	text  string
+}
+
+type paragraph struct {
+	text string
+}
+
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+// Document - an architecture document describing a project's services, APIs, events, storage, schedules and permissions
+type Document struct {
+	title  string
+	blocks []block
+}
+
+func (d *Document) heading(level int, format string, a ...interface{}) {
+	d.blocks = append(d.blocks, heading{level: level, text: fmt.Sprintf(format, a...)})
+}
+
+func (d *Document) paragraph(format string, a ...interface{}) {
+	d.blocks = append(d.blocks, paragraph{text: fmt.Sprintf(format, a...)})
+}
+
+func (d *Document) table(headers []string, rows [][]string) {
+	if len(rows) == 0 {
+		d.paragraph("None declared.")
+		return
+	}
+
+	slices.SortFunc(rows, func(a []string, b []string) int {
+		return strings.Compare(strings.Join(a, "\x00"), strings.Join(b, "\x00"))
+	})
+
+	d.blocks = append(d.blocks, table{headers: headers, rows: rows})
+}
+
+func resourcesOfType(spec *deploymentspb.Spec, resourceType resourcespb.ResourceType) []*deploymentspb.Resource {
+	return lo.Filter(spec.Resources, func(r *deploymentspb.Resource, _ int) bool {
+		return r.Id.GetType() == resourceType
+	})
+}
+
+func resourceLabel(id *resourcespb.ResourceIdentifier) string {
+	return fmt.Sprintf("%s %s", strings.ToLower(id.GetType().String()), id.GetName())
+}
+
+// FromSpec - builds an architecture document from a project's collected deployment spec
+func FromSpec(projectName string, spec *deploymentspb.Spec) (*Document, error) {
+	doc := &Document{title: fmt.Sprintf("%s architecture", projectName)}
+
+	doc.paragraph("Generated by `nitric docs generate` from the resources declared by the project's services.")
+
+	doc.heading(2, "Services")
+	doc.table([]string{"Service", "Type", "Workers"}, lo.Map(resourcesOfType(spec, resourcespb.ResourceType_Service), func(r *deploymentspb.Resource, _ int) []string {
+		return []string{r.Id.Name, lo.Ternary(r.GetService().GetType() != "", r.GetService().GetType(), "default"), fmt.Sprint(r.GetService().GetWorkers())}
+	}))
+
+	resources, err := generate.FromSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.heading(2, "APIs")
+
+	if len(resources.Apis) == 0 {
+		doc.paragraph("None declared.")
+	}
+
+	for _, api := range resources.Apis {
+		doc.heading(3, "%s", api.Name)
+		doc.table([]string{"Method", "Path", "Service"}, lo.Map(api.Routes, func(route generate.Route, _ int) []string {
+			return []string{route.Method, route.Path, route.Service}
+		}))
+	}
+
+	if httpProxies := resourcesOfType(spec, resourcespb.ResourceType_Http); len(httpProxies) > 0 {
+		doc.heading(3, "HTTP proxies")
+		doc.table([]string{"Name", "Service"}, lo.Map(httpProxies, func(r *deploymentspb.Resource, _ int) []string {
+			return []string{r.Id.Name, r.GetHttp().GetTarget().GetService()}
+		}))
+	}
+
+	if websockets := resourcesOfType(spec, resourcespb.ResourceType_Websocket); len(websockets) > 0 {
+		doc.heading(3, "Websockets")
+		doc.table([]string{"Socket", "Connect", "Disconnect", "Message"}, lo.Map(websockets, func(r *deploymentspb.Resource, _ int) []string {
+			ws := r.GetWebsocket()
+			return []string{r.Id.Name, ws.GetConnectTarget().GetService(), ws.GetDisconnectTarget().GetService(), ws.GetMessageTarget().GetService()}
+		}))
+	}
+
+	doc.heading(2, "Events")
+	doc.heading(3, "Topics")
+	doc.table([]string{"Topic", "Subscribers"}, lo.Map(resourcesOfType(spec, resourcespb.ResourceType_Topic), func(r *deploymentspb.Resource, _ int) []string {
+		subscribers := lo.Map(r.GetTopic().GetSubscriptions(), func(s *deploymentspb.SubscriptionTarget, _ int) string { return s.GetService() })
+		slices.Sort(subscribers)
+
+		return []string{r.Id.Name, strings.Join(subscribers, ", ")}
+	}))
+
+	doc.heading(3, "Bucket notifications")
+
+	notifications := [][]string{}
+
+	for _, bucket := range resourcesOfType(spec, resourcespb.ResourceType_Bucket) {
+		for _, listener := range bucket.GetBucket().GetListeners() {
+			notifications = append(notifications, []string{
+				bucket.Id.Name,
+				strings.ToLower(listener.GetConfig().GetBlobEventType().String()),
+				lo.Ternary(listener.GetConfig().GetKeyPrefixFilter() != "", listener.GetConfig().GetKeyPrefixFilter(), "*"),
+				listener.GetService(),
+			})
+		}
+	}
+
+	doc.table([]string{"Bucket", "Event", "Prefix", "Service"}, notifications)
+
+	doc.heading(2, "Schedules")
+	doc.table([]string{"Schedule", "Cadence", "Service"}, lo.Map(resourcesOfType(spec, resourcespb.ResourceType_Schedule), func(r *deploymentspb.Resource, _ int) []string {
+		schedule := r.GetSchedule()
+		cadence := lo.Ternary(schedule.GetCron() != nil, "cron "+schedule.GetCron().GetExpression(), "every "+schedule.GetEvery().GetRate())
+
+		return []string{r.Id.Name, cadence, schedule.GetTarget().GetService()}
+	}))
+
+	doc.heading(2, "Storage")
+
+	storage := [][]string{}
+
+	for _, storageType := range []resourcespb.ResourceType{
+		resourcespb.ResourceType_Bucket, resourcespb.ResourceType_KeyValueStore, resourcespb.ResourceType_Queue,
+		resourcespb.ResourceType_SqlDatabase, resourcespb.ResourceType_Secret,
+	} {
+		for _, r := range resourcesOfType(spec, storageType) {
+			storage = append(storage, []string{r.Id.Name, strings.ToLower(storageType.String())})
+		}
+	}
+
+	doc.table([]string{"Resource", "Type"}, storage)
+
+	doc.heading(2, "Permissions")
+	doc.paragraph("Actions each service is allowed to perform on each resource.")
+	doc.permissions(spec)
+
+	return doc, nil
+}
+
+// permissions - adds a matrix of the actions granted to each service (columns) on each resource (rows)
+func (d *Document) permissions(spec *deploymentspb.Spec) {
+	grants := map[string]map[string][]string{}
+	services := []string{}
+
+	for _, r := range resourcesOfType(spec, resourcespb.ResourceType_Policy) {
+		policy := r.GetPolicy()
+
+		for _, principal := range policy.GetPrincipals() {
+			services = append(services, principal.Id.GetName())
+
+			for _, resource := range policy.GetResources() {
+				label := resourceLabel(resource.Id)
+				if grants[label] == nil {
+					grants[label] = map[string][]string{}
+				}
+
+				for _, action := range policy.GetActions() {
+					grants[label][principal.Id.GetName()] = append(grants[label][principal.Id.GetName()], action.String())
+				}
+			}
+		}
+	}
+
+	services = lo.Uniq(services)
+	slices.Sort(services)
+
+	rows := lo.MapToSlice(grants, func(label string, byService map[string][]string) []string {
+		return append([]string{label}, lo.Map(services, func(service string, _ int) string {
+			actions := lo.Uniq(byService[service])
+			slices.Sort(actions)
+
+			return strings.Join(actions, ", ")
+		})...)
+	})
+
+	d.table(append([]string{"Resource"}, services...), rows)
+}
@@ -0,0 +1,145 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docs
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Formats - the formats an architecture document can be rendered in
+const (
+	Format_Markdown = "markdown"
+	Format_Html     = "html"
+)
+
+var Formats = []string{Format_Markdown, Format_Html}
+
+// DefaultFileNames - the file each format is written to by default
+var DefaultFileNames = map[string]string{
+	Format_Markdown: "ARCHITECTURE.md",
+	Format_Html:     "architecture.html",
+}
+
+// Render - renders the document in the given format
+func (d *Document) Render(format string) (string, error) {
+	switch format {
+	case Format_Markdown:
+		return d.Markdown(), nil
+	case Format_Html:
+		return d.Html(), nil
+	}
+
+	return "", fmt.Errorf("unsupported format %s, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+func markdownCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// Markdown - renders the document as markdown
+func (d *Document) Markdown() string {
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, "# %s\n", d.title)
+
+	for _, b := range d.blocks {
+		switch b := b.(type) {
+		case heading:
+			fmt.Fprintf(sb, "\n%s %s\n", strings.Repeat("#", b.level), b.text)
+		case paragraph:
+			fmt.Fprintf(sb, "\n%s\n", b.text)
+		case table:
+			fmt.Fprintf(sb, "\n| %s |\n", strings.Join(b.headers, " | "))
+			fmt.Fprintf(sb, "|%s\n", strings.Repeat(" --- |", len(b.headers)))
+
+			for _, row := range b.rows {
+				cells := make([]string, len(row))
+				for i, cell := range row {
+					cells[i] = markdownCell(cell)
+				}
+
+				fmt.Fprintf(sb, "| %s |\n", strings.Join(cells, " | "))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+const htmlStyle = `body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 64rem; padding: 0 1rem; color: #1f2328; }
+table { border-collapse: collapse; margin: 1rem 0; }
+th, td { border: 1px solid #d0d7de; padding: 0.4rem 0.8rem; text-align: left; }
+th { background: #f6f8fa; }
+code { background: #f6f8fa; padding: 0.1rem 0.3rem; border-radius: 4px; }`
+
+// htmlText - escapes text, rendering `quoted` spans as code
+func htmlText(text string) string {
+	parts := strings.Split(html.EscapeString(text), "`")
+
+	for i := 1; i < len(parts); i += 2 {
+		parts[i] = "<code>" + parts[i] + "</code>"
+	}
+
+	return strings.Join(parts, "")
+}
+
+// Html - renders the document as a standalone html page
+func (d *Document) Html() string {
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", html.EscapeString(d.title), htmlStyle)
+	fmt.Fprintf(sb, "<h1>%s</h1>\n", html.EscapeString(d.title))
+
+	for _, b := range d.blocks {
+		switch b := b.(type) {
+		case heading:
+			fmt.Fprintf(sb, "<h%d>%s</h%d>\n", b.level, htmlText(b.text), b.level)
+		case paragraph:
+			fmt.Fprintf(sb, "<p>%s</p>\n", htmlText(b.text))
+		case table:
+			sb.WriteString("<table>\n<tr>")
+
+			for _, header := range b.headers {
+				fmt.Fprintf(sb, "<th>%s</th>", html.EscapeString(header))
+			}
+
+			sb.WriteString("</tr>\n")
+
+			for _, row := range b.rows {
+				sb.WriteString("<tr>")
+
+				for _, cell := range row {
+					fmt.Fprintf(sb, "<td>%s</td>", html.EscapeString(cell))
+				}
+
+				sb.WriteString("</tr>\n")
+			}
+
+			sb.WriteString("</table>\n")
+		}
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+
+	return sb.String()
+}
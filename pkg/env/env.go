@@ -20,6 +20,9 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/secrets"
 )
 
 var defaultEnv = ".env"
@@ -33,6 +36,8 @@ func ReadEnv(filePath string) (map[string]string, error) {
 	return godotenv.Parse(file)
 }
 
+// ReadLocalEnv - reads the default .env file, then the decrypted project secrets, then each additional env file,
+// with later values taking precedence
 func ReadLocalEnv(additionalFilePaths ...string) (map[string]string, error) {
 	envVariables, err := ReadEnv(defaultEnv)
 	if err != nil && !os.IsNotExist(err) {
@@ -43,6 +48,15 @@ func ReadLocalEnv(additionalFilePaths ...string) (map[string]string, error) {
 		envVariables = map[string]string{}
 	}
 
+	secretVariables, err := secrets.ReadEnv(afero.NewOsFs(), ".")
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range secretVariables {
+		envVariables[key] = value
+	}
+
 	for _, filePath := range additionalFilePaths {
 		additionalEnvVariables, err := ReadEnv(filePath)
 		if err != nil {
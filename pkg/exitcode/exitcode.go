@@ -0,0 +1,78 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exitcode
+
+import "errors"
+
+// Code - the exit status of the CLI, each kind of failure has its own code so CI pipelines can branch on them without parsing stderr
+type Code int
+
+const (
+	Success Code = 0
+	// Error - an unexpected error, or an invalid command or flag
+	Error Code = 1
+	// Config - the project or stack configuration is missing or invalid
+	Config Code = 2
+	// Build - a service or migration image failed to build
+	Build Code = 3
+	// Collection - the resource requirements of the project's services couldn't be collected
+	Collection Code = 4
+	// Deployment - the provider failed to start, or a deployment, undeployment or garbage collection of a stack failed
+	Deployment Code = 5
+	// Drift - the stack file has changed since the stack was last deployed
+	Drift Code = 6
+	// Policy - a command was blocked by the policies in nitric.yaml, e.g. a missing stack name confirmation
+	Policy Code = 7
+	// Locked - the stack is locked by another operation, e.g. a teammate's deployment
+	Locked Code = 8
+)
+
+type codedError struct {
+	code Code
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// Wrap - returns the error with the code the CLI exits with when it's reported, nil errors are returned as is
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &codedError{code: code, err: err}
+}
+
+// Of - returns the code the CLI exits with for an error, the outermost code is used when an error is wrapped more than once
+func Of(err error) Code {
+	if err == nil {
+		return Success
+	}
+
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	return Error
+}
@@ -0,0 +1,268 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+)
+
+// Languages - the languages typed resource accessors can be generated for
+const (
+	Language_TypeScript = "typescript"
+	Language_JavaScript = "javascript"
+	Language_Python     = "python"
+	Language_Go         = "go"
+)
+
+var Languages = []string{Language_TypeScript, Language_JavaScript, Language_Python, Language_Go}
+
+// FileNames - the file each language's accessors are written to, relative to the output directory
+var FileNames = map[string]string{
+	Language_TypeScript: "resources.ts",
+	Language_JavaScript: "resources.js",
+	Language_Python:     "resources.py",
+	Language_Go:         "resources/resources.go",
+}
+
+// LanguageFromExtension - returns the language of a service entrypoint, or an empty string if it isn't supported
+func LanguageFromExtension(ext string) string {
+	switch ext {
+	case ".ts", ".mts", ".cts":
+		return Language_TypeScript
+	case ".js", ".mjs", ".cjs":
+		return Language_JavaScript
+	case ".py":
+		return Language_Python
+	case ".go":
+		return Language_Go
+	}
+
+	return ""
+}
+
+// Generate - returns the source of typed resource accessors for a language
+func Generate(language string, resources *Resources) ([]byte, error) {
+	switch language {
+	case Language_TypeScript:
+		return []byte(generateJavaScript(resources, true)), nil
+	case Language_JavaScript:
+		return []byte(generateJavaScript(resources, false)), nil
+	case Language_Python:
+		return []byte(generatePython(resources)), nil
+	case Language_Go:
+		return format.Source([]byte(generateGo(resources)))
+	}
+
+	return nil, fmt.Errorf("unsupported language %s, expected one of %s", language, strings.Join(Languages, ", "))
+}
+
+var (
+	nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+	camelBoundary   = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+)
+
+// words - splits a resource name into lowercase words, e.g. user-uploads and userUploads are both [user uploads]
+func words(name string) []string {
+	name = camelBoundary.ReplaceAllString(name, "$1 $2")
+
+	return lo.Map(strings.Fields(nonAlphanumeric.ReplaceAllString(name, " ")), func(word string, _ int) string {
+		return strings.ToLower(word)
+	})
+}
+
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func identifier(name string) string {
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return "_" + name
+	}
+
+	return name
+}
+
+func camelCase(name string) string {
+	w := words(name)
+	if len(w) == 0 {
+		return "_"
+	}
+
+	return identifier(w[0] + strings.Join(lo.Map(w[1:], func(word string, _ int) string { return capitalize(word) }), ""))
+}
+
+func pascalCase(name string) string {
+	return identifier(strings.Join(lo.Map(words(name), func(word string, _ int) string { return capitalize(word) }), ""))
+}
+
+func upperSnakeCase(name string) string {
+	return identifier(strings.ToUpper(strings.Join(words(name), "_")))
+}
+
+// uniqueNames - converts names to identifiers, suffixing identifiers that would collide, e.g. user-uploads and user_uploads
+func uniqueNames(names []string, toIdentifier func(string) string) []lo.Tuple2[string, string] {
+	seen := map[string]int{}
+
+	return lo.Map(names, func(name string, _ int) lo.Tuple2[string, string] {
+		id := toIdentifier(name)
+
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s%d", id, seen[id])
+		}
+
+		return lo.T2(id, name)
+	})
+}
+
+func routeNames(api Api) []string {
+	return lo.Map(api.Routes, func(route Route, _ int) string { return route.Name() })
+}
+
+type resourceGroup struct {
+	name  string
+	names []string
+}
+
+func resourceGroups(r *Resources) []resourceGroup {
+	return lo.Filter([]resourceGroup{
+		{"buckets", r.Buckets},
+		{"topics", r.Topics},
+		{"queues", r.Queues},
+		{"key value stores", r.KeyValueStores},
+		{"secrets", r.Secrets},
+		{"sql databases", r.SqlDatabases},
+		{"websockets", r.Websockets},
+		{"schedules", r.Schedules},
+	}, func(group resourceGroup, _ int) bool {
+		return len(group.names) > 0
+	})
+}
+
+func generateJavaScript(r *Resources, typed bool) string {
+	asConst := lo.Ternary(typed, " as const", "")
+
+	sb := &strings.Builder{}
+	sb.WriteString("// Code generated by nitric generate. DO NOT EDIT.\n")
+
+	for _, group := range resourceGroups(r) {
+		fmt.Fprintf(sb, "\nexport const %s = {\n", camelCase(group.name))
+
+		for _, n := range uniqueNames(group.names, camelCase) {
+			fmt.Fprintf(sb, "  %s: %q,\n", n.A, n.B)
+		}
+
+		fmt.Fprintf(sb, "}%s;\n", asConst)
+	}
+
+	if len(r.Apis) > 0 {
+		sb.WriteString("\nexport const apis = {\n")
+
+		for i, n := range uniqueNames(lo.Map(r.Apis, func(api Api, _ int) string { return api.Name }), camelCase) {
+			api := r.Apis[i]
+
+			fmt.Fprintf(sb, "  %s: {\n    name: %q,\n    routes: {\n", n.A, n.B)
+
+			for j, route := range uniqueNames(routeNames(api), camelCase) {
+				fmt.Fprintf(sb, "      %s: { method: %q, path: %q },\n", route.A, api.Routes[j].Method, api.Routes[j].Path)
+			}
+
+			sb.WriteString("    },\n  },\n")
+		}
+
+		fmt.Fprintf(sb, "}%s;\n", asConst)
+	}
+
+	return sb.String()
+}
+
+func generatePython(r *Resources) string {
+	sb := &strings.Builder{}
+	sb.WriteString("# Code generated by nitric generate. DO NOT EDIT.\n")
+
+	if len(r.Apis) > 0 {
+		sb.WriteString("\nfrom typing import NamedTuple\n\n\nclass Route(NamedTuple):\n    method: str\n    path: str\n")
+	}
+
+	for _, group := range resourceGroups(r) {
+		fmt.Fprintf(sb, "\n\nclass %s:\n", pascalCase(group.name))
+
+		for _, n := range uniqueNames(group.names, upperSnakeCase) {
+			fmt.Fprintf(sb, "    %s = %q\n", n.A, n.B)
+		}
+	}
+
+	for i, n := range uniqueNames(lo.Map(r.Apis, func(api Api, _ int) string { return api.Name }), pascalCase) {
+		api := r.Apis[i]
+
+		fmt.Fprintf(sb, "\n\nclass %sApi:\n    NAME = %q\n", n.A, n.B)
+
+		for j, route := range uniqueNames(routeNames(api), upperSnakeCase) {
+			fmt.Fprintf(sb, "    %s = Route(%q, %q)\n", route.A, api.Routes[j].Method, api.Routes[j].Path)
+		}
+	}
+
+	return sb.String()
+}
+
+func generateGo(r *Resources) string {
+	sb := &strings.Builder{}
+	sb.WriteString("// Code generated by nitric generate. DO NOT EDIT.\n\n// Package resources contains the names of resources declared by the project's services.\npackage resources\n")
+
+	for _, group := range resourceGroups(r) {
+		prefix := pascalCase(strings.TrimSuffix(group.name, "s"))
+
+		fmt.Fprintf(sb, "\n// %s\nconst (\n", capitalize(group.name))
+
+		for _, n := range uniqueNames(group.names, pascalCase) {
+			fmt.Fprintf(sb, "\t%s%s = %q\n", prefix, strings.TrimPrefix(n.A, "_"), n.B)
+		}
+
+		sb.WriteString(")\n")
+	}
+
+	if len(r.Apis) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("\n// Route - a route registered on an API\ntype Route struct {\n\tMethod string\n\tPath   string\n}\n")
+
+	for i, n := range uniqueNames(lo.Map(r.Apis, func(api Api, _ int) string { return api.Name }), pascalCase) {
+		api := r.Apis[i]
+		apiId := "Api" + strings.TrimPrefix(n.A, "_")
+
+		fmt.Fprintf(sb, "\n// %s api\nconst %s = %q\n\nvar (\n", n.B, apiId, n.B)
+
+		for j, route := range uniqueNames(routeNames(api), pascalCase) {
+			fmt.Fprintf(sb, "\t%s%s = Route{Method: %q, Path: %q}\n", apiId, strings.TrimPrefix(route.A, "_"), api.Routes[j].Method, api.Routes[j].Path)
+		}
+
+		sb.WriteString(")\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,164 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// Route - a route registered on an API
+type Route struct {
+	Method string
+	// Path using the nitric SDK path parameter syntax, e.g. /customers/:id
+	Path string
+	// Service handling the route
+	Service string
+}
+
+// Api - an API and its routes
+type Api struct {
+	Name   string
+	Routes []Route
+}
+
+// Resources - the names of resources declared by a project's services
+type Resources struct {
+	Apis           []Api
+	Buckets        []string
+	Topics         []string
+	Queues         []string
+	KeyValueStores []string
+	Secrets        []string
+	SqlDatabases   []string
+	Websockets     []string
+	Schedules      []string
+}
+
+var openApiPathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// FromSpec - returns the resources declared in a deployment spec, sorted by name
+func FromSpec(spec *deploymentspb.Spec) (*Resources, error) {
+	resources := &Resources{}
+
+	for _, resource := range spec.Resources {
+		name := resource.Id.GetName()
+
+		switch resource.Id.GetType() {
+		case resourcespb.ResourceType_Api:
+			api, err := apiFromResource(name, resource.GetApi())
+			if err != nil {
+				return nil, err
+			}
+
+			resources.Apis = append(resources.Apis, api)
+		case resourcespb.ResourceType_Bucket:
+			resources.Buckets = append(resources.Buckets, name)
+		case resourcespb.ResourceType_Topic:
+			resources.Topics = append(resources.Topics, name)
+		case resourcespb.ResourceType_Queue:
+			resources.Queues = append(resources.Queues, name)
+		case resourcespb.ResourceType_KeyValueStore:
+			resources.KeyValueStores = append(resources.KeyValueStores, name)
+		case resourcespb.ResourceType_Secret:
+			resources.Secrets = append(resources.Secrets, name)
+		case resourcespb.ResourceType_SqlDatabase:
+			resources.SqlDatabases = append(resources.SqlDatabases, name)
+		case resourcespb.ResourceType_Websocket:
+			resources.Websockets = append(resources.Websockets, name)
+		case resourcespb.ResourceType_Schedule:
+			resources.Schedules = append(resources.Schedules, name)
+		}
+	}
+
+	slices.SortFunc(resources.Apis, func(a Api, b Api) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, names := range []*[]string{
+		&resources.Buckets, &resources.Topics, &resources.Queues, &resources.KeyValueStores,
+		&resources.Secrets, &resources.SqlDatabases, &resources.Websockets, &resources.Schedules,
+	} {
+		*names = lo.Uniq(*names)
+		slices.Sort(*names)
+	}
+
+	return resources, nil
+}
+
+func apiFromResource(name string, api *deploymentspb.Api) (Api, error) {
+	result := Api{Name: name, Routes: []Route{}}
+
+	if api.GetOpenapi() == "" {
+		return result, nil
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+	if err != nil {
+		return result, fmt.Errorf("unable to read openapi document of api %s: %w", name, err)
+	}
+
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			result.Routes = append(result.Routes, Route{
+				Method:  strings.ToUpper(method),
+				Path:    openApiPathParam.ReplaceAllString(path, ":$1"),
+				Service: routeTarget(operation),
+			})
+		}
+	}
+
+	slices.SortFunc(result.Routes, func(a Route, b Route) int {
+		return strings.Compare(a.Path+" "+a.Method, b.Path+" "+b.Method)
+	})
+
+	return result, nil
+}
+
+// routeTarget - returns the name of the service handling an operation from its x-nitric-target extension
+func routeTarget(operation *openapi3.Operation) string {
+	ext, ok := operation.Extensions["x-nitric-target"]
+	if !ok {
+		return ""
+	}
+
+	// extensions loaded from a document are raw json
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return ""
+	}
+
+	target := struct {
+		Name string `json:"name"`
+	}{}
+
+	_ = json.Unmarshal(data, &target)
+
+	return target.Name
+}
+
+// Name - a descriptive name for the route, e.g. GET /customers/:id is getCustomersId
+func (r Route) Name() string {
+	return strings.ToLower(r.Method) + " " + r.Path
+}
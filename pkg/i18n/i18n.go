@@ -0,0 +1,171 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package i18n - catalogs of the CLI's user facing messages, so prompts and errors can be translated without changing command code.
+//
+// Messages are looked up by key in the catalog of the selected locale, then the catalog of its language, e.g. pt-BR then pt,
+// then the english catalog. Catalogs are embedded from locales/<locale>.yaml, and can be added or overridden by files in
+// ~/.config/nitric/locales, so translations can be tried without rebuilding the CLI.
+package i18n
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/preferences"
+)
+
+// DefaultLocale - the locale messages fall back to when they haven't been translated
+const DefaultLocale = "en"
+
+//go:embed locales/*.yaml
+var embeddedLocales embed.FS
+
+// Catalog - messages keyed by their message key, e.g. stack.new.name_prompt
+type Catalog map[string]string
+
+var (
+	loadOnce sync.Once
+	catalogs []Catalog
+	locale   string
+)
+
+// Locale - returns the locale messages are shown in, set with the NITRIC_LOCALE environment variable or the locale preference,
+// defaulting to the locale of the environment, e.g. LANG=pt_BR.UTF-8 selects pt-BR
+func Locale() string {
+	for _, value := range []string{os.Getenv("NITRIC_LOCALE"), preferredLocale(), os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")} {
+		if l := normalize(value); l != "" {
+			return l
+		}
+	}
+
+	return DefaultLocale
+}
+
+func preferredLocale() string {
+	prefs, err := preferences.Current()
+	if err != nil {
+		return ""
+	}
+
+	return prefs.Locale
+}
+
+// normalize - converts a POSIX or BCP 47 locale to the name of its catalog, e.g. pt_BR.UTF-8 to pt-BR, C and POSIX select english
+func normalize(value string) string {
+	value, _, _ = strings.Cut(value, ".")
+	value, _, _ = strings.Cut(value, "@")
+
+	if value == "" || value == "C" || value == "POSIX" {
+		return ""
+	}
+
+	language, region, found := strings.Cut(strings.ReplaceAll(value, "_", "-"), "-")
+	if !found {
+		return strings.ToLower(language)
+	}
+
+	return strings.ToLower(language) + "-" + strings.ToUpper(region)
+}
+
+// readCatalog - reads the catalog of a locale, user catalogs take precedence over embedded catalogs
+func readCatalog(name string) (Catalog, error) {
+	catalog := Catalog{}
+
+	contents, err := embeddedLocales.ReadFile("locales/" + name + ".yaml")
+	if err == nil {
+		if err := yaml.Unmarshal(contents, &catalog); err != nil {
+			return nil, fmt.Errorf("unable to parse message catalog %s: %w", name, err)
+		}
+	}
+
+	contents, err = os.ReadFile(filepath.Join(paths.NitricConfigDir(), "locales", name+".yaml"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return catalog, nil
+		}
+
+		return nil, err
+	}
+
+	user := Catalog{}
+
+	if err := yaml.Unmarshal(contents, &user); err != nil {
+		return nil, fmt.Errorf("unable to parse message catalog %s: %w", name, err)
+	}
+
+	for key, message := range user {
+		catalog[key] = message
+	}
+
+	return catalog, nil
+}
+
+// load - loads the catalogs messages are looked up in, in order of precedence
+func load() {
+	locale = Locale()
+
+	names := []string{locale}
+	if language, _, found := strings.Cut(locale, "-"); found {
+		names = append(names, language)
+	}
+
+	if locale != DefaultLocale {
+		names = append(names, DefaultLocale)
+	}
+
+	for _, name := range names {
+		// unreadable catalogs are skipped, so a broken translation falls back to english rather than breaking the CLI
+		if catalog, err := readCatalog(name); err == nil {
+			catalogs = append(catalogs, catalog)
+		}
+	}
+}
+
+// Message - returns the message for a key in the current locale, formatted with args, e.g. Message("stack.not_found", name).
+// Messages use fmt verbs, translations can reorder arguments with explicit indexes, e.g. %[2]s %[1]s.
+// Keys without a message are returned as is, so missing messages are visible rather than blank.
+func Message(key string, args ...any) string {
+	loadOnce.Do(load)
+
+	format := key
+
+	for _, catalog := range catalogs {
+		if message, ok := catalog[key]; ok {
+			format = message
+			break
+		}
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+// Errorf - returns an error with the message for a key in the current locale, args are formatted as by fmt.Errorf so errors can be wrapped with %w
+func Errorf(key string, args ...any) error {
+	return fmt.Errorf(Message(key), args...)
+}
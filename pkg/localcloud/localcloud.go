@@ -0,0 +1,220 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package localcloud starts the nitric local cloud in-process, for integration tests of services written in Go.
+//
+// Each call to Start runs an isolated local cloud, stopped when the test finishes, e.g.
+//
+//	func TestOrders(t *testing.T) {
+//		lc := localcloud.Start(t)
+//
+//		for key, value := range lc.Env() {
+//			t.Setenv(key, value)
+//		}
+//
+//		// resources declared with the nitric SDK from here on are served by the local cloud
+//	}
+package localcloud
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nitrictech/cli/pkg/cloud"
+)
+
+// startTimeout - how long to wait for the nitric server of the local cloud to accept connections
+const startTimeout = 10 * time.Second
+
+// LocalCloud - a local cloud started in-process for a test
+type LocalCloud struct {
+	// Cloud - the underlying local cloud, exposing the state of its APIs, topics, buckets etc.
+	Cloud *cloud.LocalCloud
+
+	serviceAddress string
+	stopOnce       sync.Once
+}
+
+type options struct {
+	projectName string
+	serviceName string
+	cloud       cloud.LocalCloudOptions
+	databases   bool
+}
+
+type Option func(*options)
+
+// WithProjectName - names the project served by the local cloud, defaults to test
+func WithProjectName(projectName string) Option {
+	return func(o *options) {
+		o.projectName = projectName
+	}
+}
+
+// WithServiceName - names the service connecting to the local cloud, defaults to the name of the test
+func WithServiceName(serviceName string) Option {
+	return func(o *options) {
+		o.serviceName = serviceName
+	}
+}
+
+// WithLogWriter - writes the logs of the local cloud's gateway to w, logs are discarded by default
+func WithLogWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.cloud.LogWriter = w
+	}
+}
+
+// WithFlags - sets the feature flags served by the local cloud
+func WithFlags(flags map[string]string) Option {
+	return func(o *options) {
+		o.cloud.Flags = flags
+	}
+}
+
+// WithDatabases - starts a postgres container for SQL databases, this requires docker
+func WithDatabases() Option {
+	return func(o *options) {
+		o.databases = true
+	}
+}
+
+// WithLocalCloudOptions - starts the local cloud with the given options, e.g. to configure API middleware
+func WithLocalCloudOptions(opts cloud.LocalCloudOptions) Option {
+	return func(o *options) {
+		o.cloud = opts
+	}
+}
+
+// Start - starts a local cloud for the test, it's stopped when the test and its subtests complete
+//
+// Files of buckets, key/value stores and secrets are kept in a temporary directory removed after the test.
+func Start(t testing.TB, opts ...Option) *LocalCloud {
+	t.Helper()
+
+	o := &options{
+		projectName: "test",
+		serviceName: t.Name(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.cloud.RunDir == "" {
+		o.cloud.RunDir = t.TempDir()
+	}
+
+	o.cloud.DisableDatabases = !o.databases
+
+	lc, err := New(o.projectName, o.serviceName, o.cloud)
+	if err != nil {
+		t.Fatalf("unable to start local cloud: %v", err)
+	}
+
+	t.Cleanup(lc.Stop)
+
+	return lc
+}
+
+// New - starts a local cloud serving a single service, for use outside of tests, e.g. in TestMain
+//
+// The returned local cloud must be stopped by calling Stop.
+func New(projectName string, serviceName string, opts cloud.LocalCloudOptions) (*LocalCloud, error) {
+	localCloud, err := cloud.New(projectName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := localCloud.AddService(serviceName)
+	if err != nil {
+		localCloud.Stop()
+		return nil, err
+	}
+
+	lc := &LocalCloud{
+		Cloud:          localCloud,
+		serviceAddress: fmt.Sprintf("localhost:%d", port),
+	}
+
+	if err := waitForAddress(lc.serviceAddress, startTimeout); err != nil {
+		lc.Stop()
+		return nil, err
+	}
+
+	return lc, nil
+}
+
+func waitForAddress(address string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("local cloud not accepting connections on %s after %s: %w", address, timeout, err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// ServiceAddress - the address of the nitric server, SDK clients connect to this address
+func (lc *LocalCloud) ServiceAddress() string {
+	return lc.serviceAddress
+}
+
+// Env - the environment variables nitric SDKs use to connect to the local cloud
+func (lc *LocalCloud) Env() map[string]string {
+	return map[string]string{
+		"NITRIC_ENVIRONMENT": "run",
+		"SERVICE_ADDRESS":    lc.serviceAddress,
+	}
+}
+
+// ApiAddress - the local address of an API, available once a handler for the API has been registered
+func (lc *LocalCloud) ApiAddress(apiName string) (string, bool) {
+	address, ok := lc.Cloud.Gateway.GetApiAddresses()[apiName]
+	return address, ok
+}
+
+// WebsocketAddress - the local address of a websocket, available once a handler for the websocket has been registered
+func (lc *LocalCloud) WebsocketAddress(websocketName string) (string, bool) {
+	address, ok := lc.Cloud.Gateway.GetWebsocketAddresses()[websocketName]
+	return address, ok
+}
+
+// TopicTriggerUrl - the url that publishes to a topic when a message is posted to it
+func (lc *LocalCloud) TopicTriggerUrl(topicName string) string {
+	return lc.Cloud.Gateway.GetTopicTriggerUrl(topicName)
+}
+
+// ScheduleTriggerUrl - the url that runs a schedule when posted to
+func (lc *LocalCloud) ScheduleTriggerUrl(scheduleName string) string {
+	return lc.Cloud.Gateway.GetScheduleManualTriggerUrl(scheduleName)
+}
+
+// Stop - stops the local cloud, it's safe to call more than once
+func (lc *LocalCloud) Stop() {
+	lc.stopOnce.Do(lc.Cloud.Stop)
+}
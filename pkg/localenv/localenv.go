@@ -0,0 +1,212 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+// Docker labels added to the containers of a local environment
+const (
+	NamespaceLabel = "io.nitric.local.namespace"
+	ProjectLabel   = "io.nitric.local.project"
+)
+
+// Environment - a local cloud started by nitric run or nitric start
+type Environment struct {
+	// Names the containers, volumes and other resources of the environment, this is the project name unless a project
+	// with the same name is already running from another directory
+	Namespace string    `json:"namespace"`
+	Project   string    `json:"project"`
+	Directory string    `json:"directory"`
+	Command   string    `json:"command"`
+	Pid       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+	Dashboard string    `json:"dashboard,omitempty"`
+	// Token required by the dashboard api, the environment's record is only readable by the user that started it
+	DashboardToken string `json:"dashboardToken,omitempty"`
+}
+
+// Suffix - returns the suffix added to the names of resources to isolate them from those of other environments, empty for the default namespace
+func (e *Environment) Suffix() string {
+	if e.Namespace == e.Project {
+		return ""
+	}
+
+	return e.Namespace[len(e.Project):]
+}
+
+// Labels - returns the docker labels identifying containers of the environment
+func (e *Environment) Labels() map[string]string {
+	return map[string]string{
+		NamespaceLabel: e.Namespace,
+		ProjectLabel:   e.Directory,
+	}
+}
+
+func environmentsDir() string {
+	return filepath.Join(paths.NitricConfigDir(), "local")
+}
+
+func (e *Environment) file() string {
+	return filepath.Join(environmentsDir(), e.Namespace+".json")
+}
+
+func (e *Environment) write() error {
+	if err := os.MkdirAll(environmentsDir(), 0o700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(e.file(), contents, 0o600)
+}
+
+// SetDashboard - records the URL of the environment's dashboard and the token required by its api
+func (e *Environment) SetDashboard(url string, token string) error {
+	e.Dashboard = url
+	e.DashboardToken = token
+
+	return e.write()
+}
+
+// DashboardRequest - calls the dashboard api of the environment with its token, path is relative to the dashboard's URL
+func (e *Environment) DashboardRequest(method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, e.Dashboard+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+e.DashboardToken)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// processes are only found on windows when they're running
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = process.Signal(syscall.Signal(0))
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// List - returns the running local environments, removing the records of environments that have exited
+func List() ([]Environment, error) {
+	files, err := filepath.Glob(filepath.Join(environmentsDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	environments := []Environment{}
+
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		env := Environment{}
+		if err := json.Unmarshal(contents, &env); err != nil || !processRunning(env.Pid) {
+			_ = os.Remove(file)
+			continue
+		}
+
+		environments = append(environments, env)
+	}
+
+	sort.Slice(environments, func(i, j int) bool {
+		return environments[i].StartedAt.Before(environments[j].StartedAt)
+	})
+
+	return environments, nil
+}
+
+func directoryHash(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// Register - records a new local environment for the project, choosing a namespace that doesn't clash with other running environments.
+// Returns a function that removes the record once the environment is stopped
+func Register(projectName string, projectDir string, command string) (*Environment, func(), error) {
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	running, err := List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if existing, ok := lo.Find(running, func(e Environment) bool { return e.Directory == dir }); ok {
+		return nil, nil, fmt.Errorf("%s is already running from this directory with nitric %s (pid %d), stop it before starting another", existing.Project, existing.Command, existing.Pid)
+	}
+
+	namespace := projectName
+	if lo.ContainsBy(running, func(e Environment) bool { return e.Namespace == namespace }) {
+		namespace = fmt.Sprintf("%s-%s", projectName, directoryHash(dir))
+	}
+
+	env := &Environment{
+		Namespace: namespace,
+		Project:   projectName,
+		Directory: dir,
+		Command:   command,
+		Pid:       os.Getpid(),
+		StartedAt: time.Now(),
+	}
+
+	if err := env.write(); err != nil {
+		return nil, nil, err
+	}
+
+	return env, func() {
+		_ = os.Remove(env.file())
+	}, nil
+}
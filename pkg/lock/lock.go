@@ -0,0 +1,284 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+// Lock - an operation in progress on a stack, held in a lock file on this machine and in the shared lock location when one is configured
+type Lock struct {
+	ID        string    `json:"id"`
+	Project   string    `json:"project"`
+	Stack     string    `json:"stack"`
+	Operation string    `json:"operation"`
+	User      string    `json:"user,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Pid       int       `json:"pid"`
+	Created   time.Time `json:"created"`
+
+	// Where the lock is held, the lock file or the shared lock location
+	Location string `json:"-"`
+
+	shared      string
+	releaseOnce sync.Once
+}
+
+// LockedError - returned when another operation holds the lock of a stack
+type LockedError struct {
+	Lock *Lock
+}
+
+func (e *LockedError) Error() string {
+	held := e.Lock
+
+	return fmt.Sprintf("stack %s is locked by %s on %s, running nitric %s since %s (%s ago). Wait for it to finish, or run nitric stack unlock -s %s --force if it's no longer running",
+		held.Stack, orUnknown(held.User), orUnknown(held.Host), held.Operation, held.Created.Local().Format(time.DateTime),
+		time.Since(held.Created).Round(time.Second), held.Stack)
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
+
+// stale - returns true when the lock was held by a process on this machine that is no longer running
+func (l *Lock) stale() bool {
+	host, _ := os.Hostname()
+
+	return l.Host == host && !processRunning(l.Pid)
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// processes are only found on windows when they're running
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = process.Signal(syscall.Signal(0))
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func newLock(projectName string, stackName string, operation string) (*Lock, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+
+	return &Lock{
+		ID:        hex.EncodeToString(id),
+		Project:   projectName,
+		Stack:     stackName,
+		Operation: operation,
+		User:      digest.DeploymentUser(),
+		Host:      host,
+		Pid:       os.Getpid(),
+		Created:   time.Now().UTC(),
+	}, nil
+}
+
+func readLockFile(lockFile string) (*Lock, error) {
+	data, err := os.ReadFile(lockFile)
+	if err != nil {
+		return nil, err
+	}
+
+	held := &Lock{Location: lockFile}
+	if err := json.Unmarshal(data, held); err != nil {
+		return nil, fmt.Errorf("unable to read stack lock %s, remove it with nitric stack unlock --force: %w", lockFile, err)
+	}
+
+	return held, nil
+}
+
+// acquireLocal - creates the lock file, replacing it when the process holding it is no longer running
+func acquireLocal(lockFile string, l *Lock) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, err = file.Write(data)
+
+			return errors.Join(err, file.Close())
+		}
+
+		if !os.IsExist(err) {
+			return err
+		}
+
+		held, err := readLockFile(lockFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !held.stale() {
+			return &LockedError{Lock: held}
+		}
+
+		if err := os.Remove(lockFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("unable to lock stack %s, the lock file %s keeps changing", l.Stack, lockFile)
+}
+
+// Acquire - locks a stack for an operation, e.g. up, on this machine and in the shared location when it's set.
+// Fails with a *LockedError when another operation holds the lock, locks held by processes on this machine that are
+// no longer running are replaced.
+//
+// Supported shared locations are s3://<bucket>/<prefix>, the lock is stored at <prefix>/<project>/<stack>.lock using the default AWS credentials
+func Acquire(ctx context.Context, projectName string, stackName string, operation string, shared string) (*Lock, error) {
+	l, err := newLock(projectName, stackName, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	lockFile, err := paths.NitricStackLockFile(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := acquireLocal(lockFile, l); err != nil {
+		return nil, err
+	}
+
+	l.Location = lockFile
+
+	if shared != "" {
+		if err := acquireShared(ctx, shared, l); err != nil {
+			return nil, errors.Join(err, os.Remove(lockFile))
+		}
+
+		l.shared = shared
+	}
+
+	return l, nil
+}
+
+// Release - releases the lock, unless it's been forcibly taken by another operation. Releasing more than once has no effect
+func (l *Lock) Release(ctx context.Context) error {
+	var err error
+
+	l.releaseOnce.Do(func() {
+		if l.shared != "" {
+			err = releaseShared(ctx, l.shared, l)
+		}
+
+		lockFile, pathErr := paths.NitricStackLockFile(l.Project, l.Stack)
+		if pathErr != nil {
+			err = errors.Join(err, pathErr)
+
+			return
+		}
+
+		if held, readErr := readLockFile(lockFile); readErr == nil && held.ID == l.ID {
+			err = errors.Join(err, os.Remove(lockFile))
+		}
+	})
+
+	return err
+}
+
+// Holders - returns the locks held on a stack, on this machine and in the shared location when it's set
+func Holders(ctx context.Context, projectName string, stackName string, shared string) ([]*Lock, error) {
+	holders := []*Lock{}
+
+	lockFile, err := paths.NitricStackLockFile(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := readLockFile(lockFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if held != nil {
+		holders = append(holders, held)
+	}
+
+	if shared != "" {
+		held, err := readShared(ctx, shared, projectName, stackName)
+		if err != nil {
+			return nil, err
+		}
+
+		if held != nil {
+			holders = append(holders, held)
+		}
+	}
+
+	return holders, nil
+}
+
+// ForceRelease - removes the locks held on a stack, regardless of the operation holding them, returning the removed locks
+func ForceRelease(ctx context.Context, projectName string, stackName string, shared string) ([]*Lock, error) {
+	holders, err := Holders(ctx, projectName, stackName, shared)
+	if err != nil {
+		return nil, err
+	}
+
+	lockFile, err := paths.NitricStackLockFile(projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(lockFile); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if shared != "" {
+		if err := removeShared(ctx, shared, projectName, stackName); err != nil {
+			return nil, err
+		}
+	}
+
+	return holders, nil
+}
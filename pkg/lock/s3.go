@@ -0,0 +1,220 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// sharedLock - the object holding the lock of a stack in a shared s3 location
+type sharedLock struct {
+	client *s3.S3
+	bucket string
+	key    string
+}
+
+func (s *sharedLock) String() string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, s.key)
+}
+
+func openShared(ctx context.Context, location string, projectName string, stackName string) (*sharedLock, error) {
+	locationUrl, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stack lock location %s: %w", location, err)
+	}
+
+	if locationUrl.Scheme != "s3" {
+		return nil, fmt.Errorf("unsupported stack lock location %s, expected an s3:// location", location)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := locationUrl.Host
+
+	region, err := s3manager.GetBucketRegion(ctx, sess, bucket, "us-east-1")
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine region of bucket %s: %w", bucket, err)
+	}
+
+	return &sharedLock{
+		client: s3.New(sess, aws.NewConfig().WithRegion(region)),
+		bucket: bucket,
+		// kept beside the stack's digests rather than under their prefix, so listing digests doesn't find the lock
+		key: path.Join(strings.TrimPrefix(locationUrl.Path, "/"), projectName, stackName+".lock"),
+	}, nil
+}
+
+// read - returns the lock held in the shared location, or nil when it isn't locked
+func (s *sharedLock) read(ctx context.Context) (*Lock, error) {
+	object, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to read stack lock %s: %w", s, err)
+	}
+	defer object.Body.Close()
+
+	data, err := io.ReadAll(object.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	held := &Lock{Location: s.String()}
+	if err := json.Unmarshal(data, held); err != nil {
+		return nil, fmt.Errorf("unable to read stack lock %s, remove it with nitric stack unlock --force: %w", s, err)
+	}
+
+	return held, nil
+}
+
+func (s *sharedLock) remove(ctx context.Context) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove stack lock %s: %w", s, err)
+	}
+
+	return nil
+}
+
+// create - writes the lock only when the object doesn't exist, returning false when another lock is already held
+func (s *sharedLock) create(ctx context.Context, l *Lock) (bool, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return false, err
+	}
+
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	req.SetContext(ctx)
+	// s3 conditional writes fail when the object already exists, so only one operation can create the lock
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+
+	err = req.Send()
+	if err == nil {
+		return true, nil
+	}
+
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) && (requestErr.StatusCode() == http.StatusPreconditionFailed || requestErr.StatusCode() == http.StatusConflict) {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("unable to create stack lock %s: %w", s, err)
+}
+
+func acquireShared(ctx context.Context, location string, l *Lock) error {
+	s, err := openShared(ctx, location, l.Project, l.Stack)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.create(ctx, l)
+		if err != nil {
+			return err
+		}
+
+		if created {
+			return nil
+		}
+
+		held, err := s.read(ctx)
+		if err != nil {
+			return err
+		}
+
+		if held == nil {
+			continue
+		}
+
+		if !held.stale() {
+			return &LockedError{Lock: held}
+		}
+
+		if err := s.remove(ctx); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("unable to lock stack %s, the lock %s keeps changing", l.Stack, s)
+}
+
+// releaseShared - removes the shared lock when it's still held by the lock
+func releaseShared(ctx context.Context, location string, l *Lock) error {
+	s, err := openShared(ctx, location, l.Project, l.Stack)
+	if err != nil {
+		return err
+	}
+
+	held, err := s.read(ctx)
+	if err != nil || held == nil || held.ID != l.ID {
+		return err
+	}
+
+	return s.remove(ctx)
+}
+
+func readShared(ctx context.Context, location string, projectName string, stackName string) (*Lock, error) {
+	s, err := openShared(ctx, location, projectName, stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.read(ctx)
+}
+
+func removeShared(ctx context.Context, location string, projectName string, stackName string) error {
+	s, err := openShared(ctx, location, projectName, stackName)
+	if err != nil {
+		return err
+	}
+
+	return s.remove(ctx)
+}
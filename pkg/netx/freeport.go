@@ -18,6 +18,8 @@ package netx
 
 import (
 	"bufio"
+	"fmt"
+	"net"
 	"os"
 	"strings"
 
@@ -51,3 +53,21 @@ func TakePort(n int) ([]int, error) {
 
 	return ports, err
 }
+
+// TakePreferredPort - returns the preferred port when it is free, otherwise a free port
+func TakePreferredPort(port int) (int, error) {
+	if port > 0 {
+		if lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port)); err == nil {
+			lis.Close()
+
+			return port, nil
+		}
+	}
+
+	ports, err := TakePort(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return ports[0], nil
+}
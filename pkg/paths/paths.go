@@ -78,6 +78,57 @@ func NitricStacksDir() (string, error) {
 	return stacksDir, nil
 }
 
+// NitricDigestsPath returns the directory deployment digests are stored in for a project stack, which may not exist yet
+func NitricDigestsPath(projectName string, stackName string) string {
+	return filepath.Join(NitricHomeDir(), "stacks", projectName, stackName, "digests")
+}
+
+// NitricDigestsDir returns the directory to store deployment digests for a project stack, making it if it doesn't exist
+func NitricDigestsDir(projectName string, stackName string) (string, error) {
+	digestsDir := NitricDigestsPath(projectName, stackName)
+
+	err := os.MkdirAll(digestsDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return digestsDir, nil
+}
+
+// NitricRollbackFile returns the path of the file recording the last successful deployment of a project stack, making its directory if it doesn't exist
+func NitricRollbackFile(projectName string, stackName string) (string, error) {
+	stacksDir, err := NitricStacksDir()
+	if err != nil {
+		return "", err
+	}
+
+	stackDir := filepath.Join(stacksDir, projectName, stackName)
+
+	err = os.MkdirAll(stackDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(stackDir, "rollback.json"), nil
+}
+
+// NitricStackLockFile returns the path of the file locking a project stack while an operation runs on it, making its directory if it doesn't exist
+func NitricStackLockFile(projectName string, stackName string) (string, error) {
+	stacksDir, err := NitricStacksDir()
+	if err != nil {
+		return "", err
+	}
+
+	stackDir := filepath.Join(stacksDir, projectName, stackName)
+
+	err = os.MkdirAll(stackDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(stackDir, "lock.json"), nil
+}
+
 // NitricConfigDir returns the directory to find configuration.
 func NitricConfigDir() string {
 	if runtime.GOOS == "linux" {
@@ -92,6 +143,16 @@ func NitricConfigDir() string {
 	return NitricHomeDir()
 }
 
+// NitricLocalCADir returns the directory of the certificate authority that signs the certificates of local HTTPS endpoints
+func NitricLocalCADir() string {
+	return filepath.Join(NitricHomeDir(), "ca")
+}
+
+// NitricSecretsKeyFile returns the path of the age identity used to decrypt project secrets files, when no other identity is configured
+func NitricSecretsKeyFile() string {
+	return filepath.Join(NitricConfigDir(), "secrets", "key.txt")
+}
+
 func NitricLocalPassphrasePath() string {
 	return filepath.Join(NitricHomeDir(), ".local-stack-pass")
 }
@@ -133,6 +194,36 @@ func NitricTlsKeyFile(stackPath string) string {
 	return filepath.Join(NitricTlsCredentialsPath(stackPath), "./key.pem")
 }
 
+// NitricApiKeysFile returns the path of the file storing a project's local API keys
+func NitricApiKeysFile(stackPath string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "apikeys.json")
+}
+
+// NitricLocalEndpointsFile returns the path of the file listing the endpoints of a project's running local cloud
+func NitricLocalEndpointsFile(stackPath string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "local-endpoints.json")
+}
+
+// NitricServeApiFile returns the path of the file with the address and token of a project's running nitric serve-api server
+func NitricServeApiFile(stackPath string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "serve-api.json")
+}
+
+// NitricSecretsFile returns the path of a project's encrypted secrets file, see nitric env edit
+func NitricSecretsFile(stackPath string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "secrets.enc.yaml")
+}
+
+// NitricStateDir returns the directory keeping the state of a project's local cloud between runs, when it's persisted
+func NitricStateDir(stackPath string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "state")
+}
+
+// NitricNoopStateFile returns the path of the file storing the resources simulated by the noop provider for a stack
+func NitricNoopStateFile(stackPath string, stackName string) string {
+	return filepath.Join(NitricTmpDir(stackPath), "noop", fmt.Sprintf("%s.json", stackName))
+}
+
 // NitricHistoryFile returns a path to a request history file, making one if it doesn't exist
 func NitricHistoryFile(stackPath string, historyType string) (string, error) {
 	logDir := NitricTmpDir(stackPath)
@@ -0,0 +1,65 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preferences
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keychainService - the service name CLI secrets are stored under in the OS keychain
+const keychainService = "nitric"
+
+var (
+	ErrSecretNotFound      = errors.New("secret not found in keychain")
+	ErrKeychainUnavailable = errors.New("os keychain is unavailable")
+)
+
+// GetSecret - retrieves a secret stored by the CLI from the OS keychain (macOS Keychain, Windows Credential Manager or the linux Secret Service)
+func GetSecret(name string) (string, error) {
+	secret, err := keyring.Get(keychainService, name)
+	if err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return "", ErrSecretNotFound
+		}
+
+		return "", fmt.Errorf("%w: %w", ErrKeychainUnavailable, err)
+	}
+
+	return secret, nil
+}
+
+// SetSecret - stores a secret in the OS keychain, replacing any existing value
+func SetSecret(name string, value string) error {
+	if err := keyring.Set(keychainService, name, value); err != nil {
+		return fmt.Errorf("%w: %w", ErrKeychainUnavailable, err)
+	}
+
+	return nil
+}
+
+// DeleteSecret - removes a secret from the OS keychain, it is not an error if the secret doesn't exist
+func DeleteSecret(name string) error {
+	err := keyring.Delete(keychainService, name)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrKeychainUnavailable, err)
+	}
+
+	return nil
+}
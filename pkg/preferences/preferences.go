@@ -0,0 +1,177 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preferences
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+type BuildPreferences struct {
+	// Maximum number of service images built at the same time, defaults to the number of CPUs
+	Concurrency int `yaml:"concurrency,omitempty"`
+}
+
+// Preferences - defaults for the CLI, loaded from layered config files so they can be shared by a team or organization
+type Preferences struct {
+	// Set to false to disable calls made by the CLI that aren't required by a command, such as update checks
+	Telemetry *bool `yaml:"telemetry,omitempty"`
+	// Registry used for provider images that don't specify a registry host, e.g. an internal mirror of docker hub
+	Registry string `yaml:"registry,omitempty"`
+	// Provider versions used when creating new stacks, keyed by nitric provider name, e.g. aws: 1.12.0
+	Providers map[string]string `yaml:"providers,omitempty"`
+	Build     BuildPreferences  `yaml:"build,omitempty"`
+	// Elapsed time thresholds that emit a warning when exceeded, keyed by task (build, deploy), a threshold of 0 disables the warning
+	Budgets map[string]time.Duration `yaml:"budgets,omitempty"`
+	// Locale prompts and errors are shown in, e.g. pt-BR, defaults to the locale of the environment
+	Locale string `yaml:"locale,omitempty"`
+}
+
+const preferencesFileName = "config.yaml"
+
+// TelemetryEnabled - returns true unless telemetry has been explicitly disabled
+func (p *Preferences) TelemetryEnabled() bool {
+	return p.Telemetry == nil || *p.Telemetry
+}
+
+// ImageName - applies the preferred registry to an image name without a registry host
+func (p *Preferences) ImageName(imageName string) string {
+	if p.Registry == "" {
+		return imageName
+	}
+
+	// the first path component is a registry host if it contains a '.' or ':', or is localhost
+	if host, _, found := strings.Cut(imageName, "/"); found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return imageName
+	}
+
+	return strings.TrimSuffix(p.Registry, "/") + "/" + imageName
+}
+
+// merge - applies the values set in other over these preferences
+func (p *Preferences) merge(other *Preferences) {
+	if other.Telemetry != nil {
+		p.Telemetry = other.Telemetry
+	}
+
+	if other.Registry != "" {
+		p.Registry = other.Registry
+	}
+
+	for name, version := range other.Providers {
+		if p.Providers == nil {
+			p.Providers = map[string]string{}
+		}
+
+		p.Providers[name] = version
+	}
+
+	for task, threshold := range other.Budgets {
+		if p.Budgets == nil {
+			p.Budgets = map[string]time.Duration{}
+		}
+
+		p.Budgets[task] = threshold
+	}
+
+	if other.Locale != "" {
+		p.Locale = other.Locale
+	}
+
+	if other.Build.Concurrency > 0 {
+		p.Build.Concurrency = other.Build.Concurrency
+	}
+}
+
+// SystemPreferencesFile - the organization wide preferences file, e.g. /etc/nitric/config.yaml
+func SystemPreferencesFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "nitric", preferencesFileName)
+	}
+
+	return filepath.Join("/etc", "nitric", preferencesFileName)
+}
+
+// ProjectPreferencesFile - the preferences file shared by a project's developers, relative to the project directory
+func ProjectPreferencesFile() string {
+	return filepath.Join(".nitric", preferencesFileName)
+}
+
+// UserPreferencesFile - the personal preferences file of the current user
+func UserPreferencesFile() string {
+	return filepath.Join(paths.NitricConfigDir(), preferencesFileName)
+}
+
+func fromFile(filePath string) (*Preferences, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Preferences{}, nil
+		}
+
+		return nil, err
+	}
+
+	prefs := &Preferences{}
+
+	if err := yaml.Unmarshal(contents, prefs); err != nil {
+		return nil, fmt.Errorf("unable to parse preferences file %s: %w", filePath, err)
+	}
+
+	return prefs, nil
+}
+
+// Load - loads preferences from the system, project and user preferences files, later files take precedence
+func Load() (*Preferences, error) {
+	prefs := &Preferences{}
+
+	for _, file := range []string{SystemPreferencesFile(), ProjectPreferencesFile(), UserPreferencesFile()} {
+		layer, err := fromFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		prefs.merge(layer)
+	}
+
+	return prefs, nil
+}
+
+var (
+	loadOnce   sync.Once
+	current    *Preferences
+	currentErr error
+)
+
+// Current - returns the preferences for the current directory, loading them on first use
+func Current() (*Preferences, error) {
+	loadOnce.Do(func() {
+		current, currentErr = Load()
+	})
+
+	return current, currentErr
+}
@@ -16,6 +16,12 @@
 
 package preview
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 type Feature = string
 
 const (
@@ -23,3 +29,36 @@ const (
 	Feature_BetaProviders   Feature = "beta-providers"
 	Feature_SqlDatabases    Feature = "sql-databases"
 )
+
+var descriptions = map[Feature]string{
+	Feature_DockerProviders: "Use providers packaged as docker images, e.g. provider: docker://my-org/my-provider",
+	Feature_BetaProviders:   "Deploy with providers that are still in beta, such as the terraform providers aws-tf, gcp-tf and do",
+	Feature_SqlDatabases:    "Declare and deploy SQL databases from your services",
+}
+
+// Features returns all available preview features, sorted by name
+func Features() []Feature {
+	features := make([]Feature, 0, len(descriptions))
+
+	for f := range descriptions {
+		features = append(features, f)
+	}
+
+	slices.Sort(features)
+
+	return features
+}
+
+// Description returns a short, human readable description of a preview feature
+func Description(feature Feature) string {
+	return descriptions[feature]
+}
+
+// Validate returns an error if the feature isn't a known preview feature
+func Validate(feature Feature) error {
+	if _, ok := descriptions[feature]; !ok {
+		return fmt.Errorf("unknown preview feature '%s', available features are: %s", feature, strings.Join(Features(), ", "))
+	}
+
+	return nil
+}
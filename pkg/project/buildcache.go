@@ -0,0 +1,122 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// buildCacheEntry - the inputs and resulting image of a service's last successful build
+type buildCacheEntry struct {
+	Image    string `json:"image"`
+	ImageId  string `json:"imageId"`
+	BuildKey string `json:"buildKey"`
+}
+
+// buildCache - records the inputs of each service's last build, so services with unchanged inputs aren't rebuilt
+type buildCache struct {
+	lock     sync.Mutex
+	Services map[string]buildCacheEntry `json:"services"`
+}
+
+func GetBuildCacheFile() string {
+	return filepath.Join(tempBuildDir, "cache.json")
+}
+
+// loadBuildCache - reads the build cache, returning an empty cache if it doesn't exist or can't be read
+func loadBuildCache(fs afero.Fs) *buildCache {
+	cache := &buildCache{Services: map[string]buildCacheEntry{}}
+
+	data, err := afero.ReadFile(fs, GetBuildCacheFile())
+	if err != nil {
+		return cache
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil || cache.Services == nil {
+		return &buildCache{Services: map[string]buildCacheEntry{}}
+	}
+
+	return cache
+}
+
+// cachedImageId - returns the ID of the image built for the service when it was last built with the same inputs
+func (c *buildCache) cachedImageId(serviceName string, image string, buildKey string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.Services[serviceName]
+	if !ok || entry.Image != image || entry.BuildKey != buildKey {
+		return "", false
+	}
+
+	return entry.ImageId, true
+}
+
+func (c *buildCache) record(serviceName string, entry buildCacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Services[serviceName] = entry
+}
+
+func (c *buildCache) remove(serviceName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.Services, serviceName)
+}
+
+func (c *buildCache) toFile(fs afero.Fs) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := fs.MkdirAll(tempBuildDir, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("unable to create build directory %s: %w", tempBuildDir, err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, GetBuildCacheFile(), data, 0o644)
+}
+
+// buildKey - returns a hash of everything that affects the image built for a service,
+// the dockerfile, build args, build context and where and for which platforms it's built
+func (s *Service) buildKey(fs afero.Fs, dockerfileContents string, build BuildConfiguration) (string, error) {
+	contextHash, err := hashBuildContext(fs, s.buildContext.BaseDirectory, strings.Split(s.buildContext.IgnoreFileContents, "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	return hashString(strings.Join([]string{
+		hashString(dockerfileContents),
+		hashBuildArgs(s.buildContext.BuildArguments),
+		contextHash,
+		build.Builder,
+		strings.Join(build.Platforms, ","),
+	}, "\n")), nil
+}
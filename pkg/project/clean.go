@@ -0,0 +1,77 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"context"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/docker"
+)
+
+// RemoveServiceImages - removes the local images of the project's services and every image labeled with the project's name,
+// e.g. earlier builds and images kept for rollbacks, returning the names of the images that were removed
+func (p *Project) RemoveServiceImages() ([]string, error) {
+	dockerClient, err := docker.New()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := lo.Map(p.services, func(svc Service, _ int) string { return svc.Image })
+
+	labeled, err := dockerClient.ListImagesWithLabel(ImageProjectLabel, p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range labeled {
+		// images are removed by tag, removing an image by ID fails while it has more than one tag
+		refs = append(refs, lo.Ternary(len(image.Tags) > 0, image.Tags, []string{image.ID})...)
+	}
+
+	removed := []string{}
+
+	for _, ref := range lo.Uniq(refs) {
+		_, err := dockerClient.ImageRemove(context.Background(), ref, types.ImageRemoveOptions{PruneChildren: true})
+		if err != nil {
+			if client.IsErrNotFound(err) {
+				continue
+			}
+
+			return removed, err
+		}
+
+		removed = append(removed, ref)
+	}
+
+	return removed, nil
+}
+
+// RemoveBuildCache - removes the record of each service's last build, so every service is rebuilt by the next build
+func RemoveBuildCache(fs afero.Fs) error {
+	err := fs.Remove(GetBuildCacheFile())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// composeDockerfileDir - the directory service dockerfiles are written to, relative to the compose file
+const composeDockerfileDir = "nitric-compose"
+
+// composeServicePort - the port of the first service's nitric server in the local cloud container, each service has its own server
+const composeServicePort = 50051
+
+// composeApiPorts - the ports published for the APIs, websockets and http proxies of the local cloud container, these are
+// assigned in order from the start of the range unless they're configured in local.nitric.yaml
+const composeApiPorts = "4000-4019"
+
+type composeBuild struct {
+	Context    string            `yaml:"context"`
+	Dockerfile string            `yaml:"dockerfile"`
+	Args       map[string]string `yaml:"args,omitempty"`
+}
+
+type composeService struct {
+	Image       string            `yaml:"image,omitempty"`
+	Build       *composeBuild     `yaml:"build,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	EnvFile     []string          `yaml:"env_file,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+}
+
+type composeFile struct {
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes,omitempty"`
+}
+
+// ComposeOptions - configures the docker compose file exported for a project
+type ComposeOptions struct {
+	// Image of the nitric CLI used to run the local cloud, e.g. nitrictech/cli:1.50.0
+	CliImage string
+	// Env files added to the environment of every service, e.g. .env
+	EnvFiles []string
+}
+
+// ExportCompose - writes a docker compose file that runs the project's services without the nitric CLI installed,
+// returning the paths of the files written.
+//
+// Each service is built from its generated dockerfile, written to the nitric-compose directory next to the compose file.
+// The local cloud runs in a container of the nitric CLI image, serving each service and the project's APIs, with SQL
+// databases created on a postgres container.
+func (p *Project) ExportCompose(fs afero.Fs, composeFilePath string, opts ComposeOptions) ([]string, error) {
+	composeFilePath, err := filepath.Abs(composeFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	composeDir := filepath.Dir(composeFilePath)
+
+	projectDir, err := filepath.Abs(p.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// paths in compose files are relative to the directory of the file
+	relativePath := func(path string) (string, error) {
+		rel, err := filepath.Rel(composeDir, path)
+		if err != nil {
+			return "", err
+		}
+
+		if rel == "." {
+			return ".", nil
+		}
+
+		return "./" + filepath.ToSlash(rel), nil
+	}
+
+	relProjectDir, err := relativePath(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// the .env file is used by nitric run when it exists
+	sourceEnvFiles := opts.EnvFiles
+	if _, err := fs.Stat(".env"); err == nil {
+		sourceEnvFiles = append([]string{".env"}, sourceEnvFiles...)
+	}
+
+	envFiles := []string{}
+
+	for _, envFile := range sourceEnvFiles {
+		absEnvFile, err := filepath.Abs(envFile)
+		if err != nil {
+			return nil, err
+		}
+
+		relEnvFile, err := relativePath(absEnvFile)
+		if err != nil {
+			return nil, err
+		}
+
+		envFiles = append(envFiles, relEnvFile)
+	}
+
+	dockerfileDir := filepath.Join(composeDir, composeDockerfileDir)
+
+	err = fs.MkdirAll(dockerfileDir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create directory %s: %w", dockerfileDir, err)
+	}
+
+	written := []string{}
+	compose := composeFile{
+		Name:     p.Name,
+		Services: map[string]composeService{},
+		Volumes:  map[string]struct{}{"postgres": {}},
+	}
+
+	nitricCommand := []string{"local", "serve", "--database", "postgres:5432"}
+
+	services := slices.Clone(p.services)
+	slices.SortFunc(services, func(a Service, b Service) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for idx, svc := range services {
+		port := composeServicePort + idx
+
+		dockerfile := filepath.Join(dockerfileDir, fmt.Sprintf("%s.dockerfile", svc.Name))
+
+		err := afero.WriteFile(fs, dockerfile, []byte(svc.buildContext.DockerfileContents), 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write dockerfile for service %s: %w", svc.Name, err)
+		}
+
+		// buildkit reads the ignore file named after the dockerfile, rather than the .dockerignore of the context
+		err = afero.WriteFile(fs, dockerfile+".dockerignore", []byte(svc.buildContext.IgnoreFileContents), 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write dockerignore for service %s: %w", svc.Name, err)
+		}
+
+		written = append(written, dockerfile, dockerfile+".dockerignore")
+
+		contextDir, err := filepath.Abs(svc.buildContext.BaseDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		relContextDir, err := relativePath(contextDir)
+		if err != nil {
+			return nil, err
+		}
+
+		// the dockerfile path is relative to the build context
+		relDockerfile, err := filepath.Rel(contextDir, dockerfile)
+		if err != nil {
+			return nil, err
+		}
+
+		serviceEnvFiles := envFiles
+
+		// the service's env file is listed last, so it takes precedence over the project's
+		if svc.envFile != "" {
+			absEnvFile, err := filepath.Abs(svc.envFile)
+			if err != nil {
+				return nil, err
+			}
+
+			relEnvFile, err := relativePath(absEnvFile)
+			if err != nil {
+				return nil, err
+			}
+
+			serviceEnvFiles = append(slices.Clone(envFiles), relEnvFile)
+		}
+
+		compose.Services[svc.Name] = composeService{
+			Image: svc.Image,
+			Build: &composeBuild{
+				Context:    relContextDir,
+				Dockerfile: filepath.ToSlash(relDockerfile),
+				Args:       svc.buildContext.BuildArguments,
+			},
+			Environment: lo.Assign(FlagsToEnv(p.Flags), map[string]string{
+				"NITRIC_ENVIRONMENT":     "run",
+				"SERVICE_ADDRESS":        fmt.Sprintf("nitric:%d", port),
+				"NITRIC_SERVICE_HOST":    "nitric",
+				"NITRIC_SERVICE_PORT":    fmt.Sprint(port),
+				"NITRIC_HTTP_PROXY_PORT": "8080",
+			}, svc.env),
+			EnvFile:   serviceEnvFiles,
+			DependsOn: []string{"nitric"},
+		}
+
+		// the local cloud identifies services by their file path, as it does for nitric run
+		nitricCommand = append(nitricCommand, "--service", fmt.Sprintf("%s=%d", svc.GetFilePath(), port))
+	}
+
+	compose.Services["nitric"] = composeService{
+		Image:      opts.CliImage,
+		Command:    nitricCommand,
+		WorkingDir: "/project",
+		// the project directory provides nitric.yaml and keeps the state of local buckets, key value stores and secrets in .nitric/run
+		Volumes:   []string{relProjectDir + ":/project"},
+		Ports:     []string{composeApiPorts + ":" + composeApiPorts},
+		DependsOn: []string{"postgres"},
+	}
+
+	// the credentials expected by the local cloud's SQL server
+	compose.Services["postgres"] = composeService{
+		Image: "postgres:16",
+		Environment: map[string]string{
+			"POSTGRES_PASSWORD": "localsecret",
+			"PGDATA":            "/var/lib/postgresql/data/pgdata",
+		},
+		Volumes: []string{"postgres:/var/lib/postgresql/data"},
+	}
+
+	contents := bytes.NewBufferString(fmt.Sprintf("# Generated by nitric export compose, run the %s project with docker compose up --build\n", p.Name))
+
+	// compose files are conventionally indented with two spaces
+	encoder := yaml.NewEncoder(contents)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(compose); err != nil {
+		return nil, err
+	}
+
+	err = afero.WriteFile(fs, composeFilePath, contents.Bytes(), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write compose file %s: %w", composeFilePath, err)
+	}
+
+	return append(written, composeFilePath), nil
+}
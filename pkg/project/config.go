@@ -18,12 +18,14 @@ package project
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 
+	"github.com/nitrictech/cli/pkg/i18n"
 	"github.com/nitrictech/cli/pkg/preview"
 )
 
@@ -34,6 +36,10 @@ type RuntimeConfiguration struct {
 	Context string
 	// Additional args to pass to the custom runtime
 	Args map[string]string
+	// Debugger nitric run --debug attaches to services of the custom runtime, one of node, debugpy, dlv or vsdbg
+	Debugger string
+	// Directory the dockerfile copies the service's source files to, used to map breakpoints to local files, defaults to /
+	SourceRoot string
 }
 
 type ServiceConfiguration struct {
@@ -51,6 +57,174 @@ type ServiceConfiguration struct {
 
 	// This is a command that will be use to run these services when using nitric start
 	Start string `yaml:"start"`
+
+	// Overrides the name generated from the service's file path, only valid when the match resolves to a single file.
+	// The name is prefixed with the project name, e.g. api becomes my-project_api
+	Name string `yaml:"name,omitempty"`
+
+	// The engine used to run javascript and typescript services, one of node, deno or bun, defaults to node
+	Engine string `yaml:"engine,omitempty"`
+
+	// Environment variables of the services, these take precedence over the env file and the project's .env files
+	// They're also passed to image builds as build args, for dockerfiles that declare them with ARG
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// Path of a .env file with environment variables of the services, relative to the project directory, e.g. services/api/.env
+	// These take precedence over the project's .env files and are only read when services run
+	EnvFile string `yaml:"env-file,omitempty"`
+
+	// Minimum level of output shown from the services by nitric run, nitric start and nitric logs, one of debug, info, warn or error
+	// Also passed to the services as LOG_LEVEL, unless it's set in env, e.g. for logging libraries configured from LOG_LEVEL
+	LogLevel string `yaml:"log-level,omitempty"`
+}
+
+type RateLimitConfiguration struct {
+	// Sustained number of requests per second allowed to the API
+	RequestsPerSecond float64 `yaml:"requests-per-second"`
+	// Maximum number of requests allowed in a burst above the sustained rate, defaults to the sustained rate rounded up
+	Burst int `yaml:"burst,omitempty"`
+}
+
+type PathRewriteConfiguration struct {
+	// Regular expression matched against the request path
+	From string `yaml:"from"`
+	// Replacement path, may reference capture groups, e.g. /$1
+	To string `yaml:"to"`
+}
+
+type MiddlewareConfiguration struct {
+	// Expression that must be true for the middleware to apply, e.g. request.method == "POST" && request.path startsWith "/admin"
+	When                  string                    `yaml:"when,omitempty"`
+	SetRequestHeaders     map[string]string         `yaml:"set-request-headers,omitempty"`
+	RemoveRequestHeaders  []string                  `yaml:"remove-request-headers,omitempty"`
+	SetResponseHeaders    map[string]string         `yaml:"set-response-headers,omitempty"`
+	RemoveResponseHeaders []string                  `yaml:"remove-response-headers,omitempty"`
+	RewritePath           *PathRewriteConfiguration `yaml:"rewrite-path,omitempty"`
+}
+
+type WebhookConfiguration struct {
+	// Path of the route receiving webhooks, may contain wildcards, e.g. /webhooks/*
+	Path string `yaml:"path"`
+	// Signing scheme used by the sender, one of stripe, github, slack or hmac-sha256
+	Scheme string `yaml:"scheme"`
+	// Signing secret, may reference environment variables from the environment or .env files, e.g. ${STRIPE_WEBHOOK_SECRET}
+	Secret string `yaml:"secret"`
+	// Header containing the signature for the hmac-sha256 scheme, defaults to X-Webhook-Signature
+	Header string `yaml:"header,omitempty"`
+	// Sign requests without a signature rather than rejecting them, so handlers verifying signatures can be called directly during development
+	Simulate bool `yaml:"simulate,omitempty"`
+}
+
+type ApiConfiguration struct {
+	// Limits the rate of requests to the API, requests over the limit are rejected with a 429 status
+	// Deployed APIs are only limited by providers applying the api-rate-limits setting, see nitric provider capabilities
+	RateLimit *RateLimitConfiguration `yaml:"rate-limit,omitempty"`
+	// Requires requests to the API to include a valid API key in the x-api-key header, see nitric apikeys
+	// Keys are checked by the local gateway, deploying fails unless the stack's provider applies the api-key-required setting,
+	// which no cloud provider applies yet, see nitric provider capabilities
+	RequireApiKey bool `yaml:"require-api-key,omitempty"`
+	// Request and response transformations applied in order by the local gateway, mirroring transformations configured on cloud API gateways
+	Middleware []MiddlewareConfiguration `yaml:"middleware,omitempty"`
+	// Routes receiving signed webhooks, signatures are verified by the local gateway
+	Webhooks []WebhookConfiguration `yaml:"webhooks,omitempty"`
+	// Path of an OpenAPI document describing the API's parameters and request bodies, relative to the project directory
+	// The local gateway rejects requests that don't match it with a 400 response detailing the problems
+	OpenApi string `yaml:"openapi,omitempty"`
+}
+
+type DigestConfiguration struct {
+	// Shared location to upload deployment digests to, e.g. s3://my-bucket/digests or https://example.com/digests
+	Upload string `yaml:"upload,omitempty"`
+}
+
+type LockConfiguration struct {
+	// Shared location stack locks are held in, e.g. s3://my-bucket/locks, defaults to digest.upload when it's an s3 location
+	// Stacks are only locked on this machine when neither is set
+	Location string `yaml:"location,omitempty"`
+}
+
+type DashboardConfiguration struct {
+	// Origins allowed to call the dashboard API from a browser, e.g. http://localhost:3000 or chrome-extension://<id>
+	// Requests from other origins are rejected, every request must include the dashboard API token
+	ApiOrigins []string `yaml:"api-origins,omitempty"`
+}
+
+type NotificationConfiguration struct {
+	// Webhooks notified by nitric watch when the health of a stack changes, slack and teams incoming webhooks are supported
+	Webhooks []string `yaml:"webhooks,omitempty"`
+}
+
+type ImagesConfiguration struct {
+	// Template for the names of built service images, e.g. registry.example.com/my-team/{{.Service}}:{{.GitSha}}
+	// Templates can use .Project, .Service, .Name (the full service name), .GitSha and .Timestamp, defaults to the service name
+	Name string `yaml:"name,omitempty"`
+}
+
+type BuildConfiguration struct {
+	// Builds service images on an existing docker buildx builder, or on a remote BuildKit instance when set to its address, e.g. tcp://buildkit.example.com:1234
+	// Defaults to a builder running on the local docker engine
+	Builder string `yaml:"builder,omitempty"`
+	// Platforms to build service images for, e.g. [linux/amd64, linux/arm64], defaults to linux/amd64
+	// Building for more than one platform requires the containerd image store of the docker engine
+	Platforms []string `yaml:"platforms,omitempty"`
+	// Scans built service images for the licenses of their dependencies
+	Licenses LicenseConfiguration `yaml:"licenses,omitempty"`
+}
+
+type LicenseConfiguration struct {
+	// Scans service images with syft after they're built, scanning is also enabled by setting deny or using --scan-licenses
+	Scan bool `yaml:"scan,omitempty"`
+	// SPDX identifiers of licenses that aren't allowed, matched case insensitively, patterns ending in * match by prefix, e.g. [GPL-*, AGPL-3.0-only]
+	Deny []string `yaml:"deny,omitempty"`
+	// How packages with denied licenses are handled, fail (the default) fails the build and warn reports them
+	Action string `yaml:"action,omitempty"`
+	// Names of packages excluded from the scan, e.g. packages used under a commercial license
+	IgnorePackages []string `yaml:"ignore-packages,omitempty"`
+	// File the license report is written to, defaults to .nitric/build/licenses.json
+	Report string `yaml:"report,omitempty"`
+}
+
+type EmailConfiguration struct {
+	// Address services send email from, e.g. noreply@example.com, this must be a verified identity of the stack's email provider
+	From string `yaml:"from"`
+}
+
+type DependencyConfiguration struct {
+	// Starts the dependency from a preconfigured image, one of postgres, redis or mailpit, the other fields override the preset
+	Preset string `yaml:"preset,omitempty"`
+	// Image of the container, e.g. redis:7-alpine
+	Image string `yaml:"image,omitempty"`
+	// Ports the container listens on, each is published on a free port of the host
+	Ports []int `yaml:"ports,omitempty"`
+	// Environment variables of the container
+	Env map[string]string `yaml:"env,omitempty"`
+	// Overrides the command of the image
+	Command []string `yaml:"command,omitempty"`
+	// Command run in the container to check the dependency is healthy, e.g. [redis-cli, ping]
+	// Services start once it succeeds, or once the first port accepts connections when no health check is set
+	Healthcheck []string `yaml:"healthcheck,omitempty"`
+	// Path in the container persisted between runs, e.g. /data
+	Volume string `yaml:"volume,omitempty"`
+	// Environment variables passed to services, values can use .Host, .Port (the host port of the first port) and .Ports, e.g. redis://{{.Host}}:{{.Port}}
+	// Presets set <NAME>_URL, where NAME is the dependency name, e.g. CACHE_URL for a dependency named cache
+	ServiceEnv map[string]string `yaml:"service-env,omitempty"`
+}
+
+type SeedConfiguration struct {
+	// Name of a SQL database to run the sql file against, once the services declare it and its migrations are applied
+	Database string `yaml:"database,omitempty"`
+	// SQL file run against the database, relative to the project directory
+	Sql string `yaml:"sql,omitempty"`
+	// Name of a bucket to copy the files to
+	Bucket string `yaml:"bucket,omitempty"`
+	// Directory of files copied to the bucket, relative to the project directory, keys are the paths of the files in the directory
+	Files string `yaml:"files,omitempty"`
+	// Name of a key/value store to set the values in
+	KeyValueStore string `yaml:"kv,omitempty"`
+	// JSON file of an object mapping keys to their values, relative to the project directory, each value must be an object
+	Values string `yaml:"values,omitempty"`
+	// Command run from the project directory with the environment of services run by nitric start, e.g. node scripts/seed.js
+	Command string `yaml:"command,omitempty"`
 }
 
 type ProjectConfiguration struct {
@@ -60,6 +234,35 @@ type ProjectConfiguration struct {
 	Ports     map[string]int                  `yaml:"ports,omitempty"`
 	Runtimes  map[string]RuntimeConfiguration `yaml:"runtimes,omitempty"`
 	Preview   []preview.Feature               `yaml:"preview,omitempty"`
+	// Configuration for APIs declared in the project's services, keyed by API name
+	Apis map[string]ApiConfiguration `yaml:"apis,omitempty"`
+	// Feature flags exposed to services as NITRIC_FLAG_<NAME> environment variables, these can be overridden per stack
+	Flags map[string]string `yaml:"flags,omitempty"`
+	// Configures where a record of each deployment is kept, in addition to the local digest history
+	Digest DigestConfiguration `yaml:"digest,omitempty"`
+	// Configures where stack locks are held, so operations on the same stack from other machines and CI are detected
+	Lock LockConfiguration `yaml:"lock,omitempty"`
+	// Configures where notifications about deployed stacks are sent
+	Notifications NotificationConfiguration `yaml:"notifications,omitempty"`
+	// Configures the local dashboard served by nitric run and nitric start
+	Dashboard DashboardConfiguration `yaml:"dashboard,omitempty"`
+	// Configures how built service images are named and tagged
+	Images ImagesConfiguration `yaml:"images,omitempty"`
+	// Configures where service images are built
+	Build BuildConfiguration `yaml:"build,omitempty"`
+	// Order container engines are detected in, one or more of docker, podman or nerdctl, defaults to that order
+	// The NITRIC_CONTAINER_ENGINE environment variable takes precedence, e.g. NITRIC_CONTAINER_ENGINE=podman
+	ContainerEngines []string `yaml:"container-engines,omitempty"`
+	// Confirmations and restrictions enforced before destructive stack commands, e.g. requiring the stack name to be typed for prod
+	Policies []CommandPolicyConfiguration `yaml:"policies,omitempty"`
+	// Lets services send email over SMTP, captured by a local SMTP server during nitric run and sent with SES or SendGrid when deployed
+	Email *EmailConfiguration `yaml:"email,omitempty"`
+	// Containers started by nitric run and nitric start before services, e.g. databases and caches, keyed by name
+	Dependencies map[string]DependencyConfiguration `yaml:"dependencies,omitempty"`
+	// Data loaded into the local cloud after it starts, applied in order and once per local state, see nitric run --reseed
+	Seed []SeedConfiguration `yaml:"seed,omitempty"`
+	// Commands transforming the deployment spec before it's sent to the provider, applied in order, see nitric up
+	Transformers []SpecTransformerConfiguration `yaml:"transformers,omitempty"`
 }
 
 const defaultNitricYamlPath = "./nitric.yaml"
@@ -76,7 +279,7 @@ func (p ProjectConfiguration) ToFile(fs afero.Fs, filepath string) error {
 		return err
 	}
 
-	if err = afero.WriteFile(fs, nitricYamlPath, projectBytes, os.ModePerm); err != nil {
+	if err = afero.WriteFile(fs, nitricYamlPath, projectBytes, 0o644); err != nil {
 		return err
 	}
 
@@ -97,7 +300,7 @@ func ConfigurationFromFile(fs afero.Fs, filePath string) (*ProjectConfiguration,
 	info, err := fs.Stat(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("nitric.yaml not found in %s. Check that you are in the root directory of a nitric project", absProjectDir)
+			return nil, i18n.Errorf("project.not_found", absProjectDir)
 		}
 
 		return nil, err
@@ -121,5 +324,18 @@ func ConfigurationFromFile(fs afero.Fs, filePath string) (*ProjectConfiguration,
 
 	projectConfig.Directory = filepath.Dir(filePath)
 
+	defaultRateLimitBursts(projectConfig)
+
 	return projectConfig, nil
 }
+
+// defaultRateLimitBursts - sets the burst of API rate limits without one to the sustained rate, so the local gateway and providers apply the same burst
+func defaultRateLimitBursts(projectConfig *ProjectConfiguration) {
+	for _, api := range projectConfig.Apis {
+		if api.RateLimit == nil || api.RateLimit.Burst > 0 || api.RateLimit.RequestsPerSecond <= 0 {
+			continue
+		}
+
+		api.RateLimit.Burst = max(1, int(math.Ceil(api.RateLimit.RequestsPerSecond)))
+	}
+}
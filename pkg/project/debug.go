@@ -0,0 +1,366 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/localenv"
+	"github.com/nitrictech/cli/pkg/netx"
+	"github.com/nitrictech/cli/pkg/project/runtime"
+)
+
+// the ports debuggers listen on by default, services after the first use the next free port
+var defaultDebugPorts = map[runtime.Debugger]int{
+	runtime.Debugger_Node:    9229,
+	runtime.Debugger_Debugpy: 5678,
+	runtime.Debugger_Dlv:     2345,
+}
+
+const (
+	// vsdbg is installed once into a volume shared by the containers of debugged services, as the .NET runtime image can't download it
+	vsdbgVolume       = "nitric-vsdbg"
+	vsdbgPath         = "/vsdbg"
+	vsdbgInstallImage = "mcr.microsoft.com/dotnet/sdk:8.0"
+	launchConfigName  = "nitric: "
+)
+
+// vsdbgInstall - prevents services starting together from installing vsdbg into the shared volume at the same time
+var vsdbgInstall sync.Mutex
+
+// DebugTarget - a service run with an attached debugger by nitric run --debug
+type DebugTarget struct {
+	Service  string
+	Debugger runtime.Debugger
+	// host port the debugger listens on, debuggers attached through the container engine don't use a port
+	Port      int
+	Container string
+	// container engine used to attach debuggers that don't listen on a port, e.g. docker
+	Engine string
+	// directory the service's source files are copied to in its image
+	SourceRoot string
+	// directory of the service's source files, relative to the project directory
+	LocalRoot string
+}
+
+// DebugTargets - assigns a debug port to each service whose runtime supports debugging, returns the services that can't be debugged
+func (p *Project) DebugTargets(environment *localenv.Environment) ([]DebugTarget, []string, error) {
+	targets := []DebugTarget{}
+	unsupported := []string{}
+
+	engine := string(docker.EngineDocker)
+	if discovered, err := docker.Discover(); err == nil {
+		engine = string(discovered)
+	}
+
+	// the next port to prefer for each debugger, so services don't try the same port
+	nextPorts := lo.Assign(defaultDebugPorts)
+	taken := map[int]bool{}
+
+	for _, svc := range p.services {
+		debugger := svc.buildContext.Debugger
+		if debugger == "" {
+			unsupported = append(unsupported, svc.Name)
+			continue
+		}
+
+		target := DebugTarget{
+			Service:    svc.Name,
+			Debugger:   debugger,
+			Container:  svc.containerName(environment),
+			Engine:     engine,
+			SourceRoot: svc.buildContext.SourceRoot,
+			LocalRoot:  filepath.ToSlash(filepath.Clean(svc.buildContext.BaseDirectory)),
+		}
+
+		if preferred, ok := nextPorts[debugger]; ok {
+			port, err := netx.TakePreferredPort(preferred)
+			// ports are only reserved once the containers start, so a port taken by an earlier service must be skipped
+			for err == nil && taken[port] {
+				preferred++
+				port, err = netx.TakePreferredPort(preferred)
+			}
+
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to find a debug port for service %s: %w", svc.Name, err)
+			}
+
+			taken[port] = true
+			nextPorts[debugger] = port + 1
+			target.Port = port
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, unsupported, nil
+}
+
+// WithDebugTargets - runs the services of the targets with their debugger attached
+func WithDebugTargets(targets []DebugTarget) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.debugTargets = targets
+	}
+}
+
+// applyDebugger - injects the target's debugger into the container and exposes its debug port
+func (s *Service) applyDebugger(dockerClient *docker.Docker, target DebugTarget, containerConfig *container.Config, hostConfig *container.HostConfig) error {
+	listen := fmt.Sprintf("0.0.0.0:%d", target.Port)
+
+	switch target.Debugger {
+	case runtime.Debugger_Node:
+		containerConfig.Env = append(containerConfig.Env, "NODE_OPTIONS=--inspect="+listen)
+	case runtime.Debugger_Debugpy:
+		// debugpy is installed when the container starts, so the service's image is the same one deployed
+		containerConfig.Entrypoint = []string{"/bin/sh", "-c", fmt.Sprintf("pip install --quiet --disable-pip-version-check debugpy && exec python -m debugpy --listen %s $HANDLER", listen)}
+	case runtime.Debugger_Dlv:
+		command, err := dockerClient.ImageCommand(s.Image)
+		if err != nil {
+			return err
+		}
+
+		if len(command) == 0 {
+			return fmt.Errorf("unable to debug service %s, its image doesn't have an entrypoint for dlv to run", s.Name)
+		}
+
+		entrypoint := []string{"dlv", "exec", "--headless", "--listen=" + listen, "--api-version=2", "--accept-multiclient", "--continue", command[0]}
+		if len(command) > 1 {
+			entrypoint = append(append(entrypoint, "--"), command[1:]...)
+		}
+
+		containerConfig.Entrypoint = entrypoint
+		containerConfig.Cmd = []string{}
+	case runtime.Debugger_Vsdbg:
+		if err := installVsdbg(dockerClient); err != nil {
+			return fmt.Errorf("unable to install vsdbg to debug service %s: %w", s.Name, err)
+		}
+
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   vsdbgVolume,
+			Target:   vsdbgPath,
+			ReadOnly: true,
+		})
+	}
+
+	// vsdbg is attached through the container engine rather than a port, containers on the host network listen on the host directly
+	if target.Port == 0 || hostConfig.NetworkMode.IsHost() {
+		return nil
+	}
+
+	debugPort := nat.Port(fmt.Sprintf("%d/tcp", target.Port))
+
+	hostConfig.PortBindings = lo.Assign(hostConfig.PortBindings, nat.PortMap{
+		// debuggers run code sent to them, so they're only reachable from this machine
+		debugPort: []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: fmt.Sprint(target.Port)}},
+	})
+	containerConfig.ExposedPorts = lo.Assign(containerConfig.ExposedPorts, nat.PortSet{debugPort: struct{}{}})
+
+	return nil
+}
+
+// installVsdbg - installs vsdbg into the volume mounted by debugged .NET services, unless it's already installed
+func installVsdbg(dockerClient *docker.Docker) error {
+	vsdbgInstall.Lock()
+	defer vsdbgInstall.Unlock()
+
+	err := dockerClient.ImagePull(vsdbgInstallImage, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+
+	containerId, err := dockerClient.ContainerCreate(&container.Config{
+		Image: vsdbgInstallImage,
+		Cmd:   []string{"/bin/sh", "-c", fmt.Sprintf("test -f %[1]s/vsdbg || curl -sSL https://aka.ms/getvsdbgsh | bash /dev/stdin -v latest -l %[1]s", vsdbgPath)},
+	}, &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeVolume,
+				Source: vsdbgVolume,
+				Target: vsdbgPath,
+			},
+		},
+	}, nil, "")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = dockerClient.ContainerRemove(context.Background(), containerId, container.RemoveOptions{Force: true})
+	}()
+
+	if err := dockerClient.ContainerStart(context.Background(), containerId, container.StartOptions{}); err != nil {
+		return err
+	}
+
+	statusChan, errChan := dockerClient.ContainerWait(context.Background(), containerId, container.WaitConditionNotRunning)
+
+	select {
+	case err := <-errChan:
+		return err
+	case status := <-statusChan:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("the install exited with status %d", status.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+// LaunchConfigurations - returns VS Code launch configurations attaching to the debuggers of the targets
+func LaunchConfigurations(targets []DebugTarget) []map[string]any {
+	configurations := []map[string]any{}
+
+	for _, target := range targets {
+		localRoot := "${workspaceFolder}"
+		if filepath.IsAbs(target.LocalRoot) {
+			localRoot = target.LocalRoot
+		} else if target.LocalRoot != "." {
+			localRoot += "/" + target.LocalRoot
+		}
+
+		configuration := map[string]any{
+			"name":    launchConfigName + target.Service,
+			"request": "attach",
+		}
+
+		switch target.Debugger {
+		case runtime.Debugger_Node:
+			configuration = lo.Assign(configuration, map[string]any{
+				"type":       "node",
+				"address":    "localhost",
+				"port":       target.Port,
+				"localRoot":  localRoot,
+				"remoteRoot": target.SourceRoot,
+				// reattach when the service's container is restarted
+				"restart":   true,
+				"skipFiles": []string{"<node_internals>/**"},
+			})
+		case runtime.Debugger_Debugpy:
+			configuration = lo.Assign(configuration, map[string]any{
+				"type":         "debugpy",
+				"connect":      map[string]any{"host": "localhost", "port": target.Port},
+				"pathMappings": []map[string]string{{"localRoot": localRoot, "remoteRoot": target.SourceRoot}},
+				"justMyCode":   false,
+			})
+		case runtime.Debugger_Dlv:
+			configuration = lo.Assign(configuration, map[string]any{
+				"type":           "go",
+				"mode":           "remote",
+				"host":           "localhost",
+				"port":           target.Port,
+				"substitutePath": []map[string]string{{"from": localRoot, "to": target.SourceRoot}},
+			})
+		case runtime.Debugger_Vsdbg:
+			configuration = lo.Assign(configuration, map[string]any{
+				"type": "coreclr",
+				// the service's binary is the container's entrypoint
+				"processId": "1",
+				"pipeTransport": map[string]any{
+					"pipeProgram":  target.Engine,
+					"pipeArgs":     []string{"exec", "-i", target.Container},
+					"pipeCwd":      "${workspaceFolder}",
+					"debuggerPath": vsdbgPath + "/vsdbg",
+					"quoteArgs":    false,
+				},
+				"sourceFileMap": map[string]string{target.SourceRoot: localRoot},
+				"justMyCode":    false,
+			})
+		}
+
+		configurations = append(configurations, configuration)
+	}
+
+	return configurations
+}
+
+// WriteLaunchConfigurations - adds the launch configurations of the targets to a VS Code launch.json file,
+// replacing configurations written by previous runs and keeping any others
+func WriteLaunchConfigurations(fs afero.Fs, launchFile string, targets []DebugTarget) error {
+	launch := map[string]any{
+		"version":        "0.2.0",
+		"configurations": []any{},
+	}
+
+	contents, err := afero.ReadFile(fs, launchFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil {
+		// launch.json files edited in VS Code can contain comments, which can't be kept
+		if err := json.Unmarshal(contents, &launch); err != nil {
+			return fmt.Errorf("unable to parse %s, it may contain comments: %w", launchFile, err)
+		}
+	}
+
+	existing, _ := launch["configurations"].([]any)
+
+	configurations := lo.Filter(existing, func(configuration any, _ int) bool {
+		config, _ := configuration.(map[string]any)
+		name, _ := config["name"].(string)
+
+		return !strings.HasPrefix(name, launchConfigName)
+	})
+
+	for _, configuration := range LaunchConfigurations(targets) {
+		configurations = append(configurations, configuration)
+	}
+
+	launch["configurations"] = configurations
+
+	launchBytes, err := MarshalLaunchJson(launch)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(launchFile), os.ModePerm); err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, launchFile, launchBytes, 0o644)
+}
+
+// MarshalLaunchJson - formats launch configurations the way VS Code writes them, without escaping <node_internals>
+func MarshalLaunchJson(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/cloud/dependencies"
+)
+
+// dependencyPresets - preconfigured dependencies, service env names are prefixed with the dependency name when applied
+var dependencyPresets = map[string]dependencies.Dependency{
+	"postgres": {
+		Image:       "postgres:16-alpine",
+		Ports:       []int{5432},
+		Env:         map[string]string{"POSTGRES_PASSWORD": "localsecret"},
+		Healthcheck: []string{"pg_isready", "-U", "postgres"},
+		Volume:      "/var/lib/postgresql/data",
+		ServiceEnv: map[string]string{
+			"URL": "postgresql://postgres:localsecret@{{.Host}}:{{.Port}}/postgres?sslmode=disable",
+		},
+	},
+	"redis": {
+		Image:       "redis:7-alpine",
+		Ports:       []int{6379},
+		Healthcheck: []string{"redis-cli", "ping"},
+		Volume:      "/data",
+		ServiceEnv: map[string]string{
+			"URL": "redis://{{.Host}}:{{.Port}}",
+		},
+	},
+	"mailpit": {
+		Image:       "axllent/mailpit:latest",
+		Ports:       []int{1025, 8025},
+		Healthcheck: []string{"/mailpit", "readyz"},
+		ServiceEnv: map[string]string{
+			"URL":       "smtp://{{.Host}}:{{.Port}}",
+			"SMTP_HOST": "{{.Host}}",
+			"SMTP_PORT": "{{.Port}}",
+		},
+	},
+}
+
+// DependencyEnvName - returns the prefix of the service env set by dependency presets, e.g. my-cache -> MY_CACHE
+func DependencyEnvName(name string) string {
+	return invalidEnvChars.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+func (d DependencyConfiguration) dependency(name string) (dependencies.Dependency, error) {
+	dep := dependencies.Dependency{}
+
+	if d.Preset != "" {
+		preset, ok := dependencyPresets[d.Preset]
+		if !ok {
+			return dep, fmt.Errorf("unknown preset %s, available presets are %s", d.Preset, strings.Join(lo.Keys(dependencyPresets), ", "))
+		}
+
+		dep = preset
+		dep.ServiceEnv = lo.MapKeys(preset.ServiceEnv, func(_ string, key string) string {
+			return DependencyEnvName(name) + "_" + key
+		})
+	}
+
+	if d.Image != "" {
+		dep.Image = d.Image
+	}
+
+	if len(d.Ports) > 0 {
+		dep.Ports = d.Ports
+	}
+
+	if len(d.Command) > 0 {
+		dep.Command = d.Command
+	}
+
+	if len(d.Healthcheck) > 0 {
+		dep.Healthcheck = d.Healthcheck
+	}
+
+	if d.Volume != "" {
+		dep.Volume = d.Volume
+	}
+
+	dep.Env = lo.Assign(dep.Env, d.Env)
+	dep.ServiceEnv = lo.Assign(dep.ServiceEnv, d.ServiceEnv)
+
+	return dep, dep.Validate()
+}
+
+// dependencies - resolves the presets of the project's dependencies and validates them
+func (p ProjectConfiguration) dependencies() (map[string]dependencies.Dependency, error) {
+	deps := map[string]dependencies.Dependency{}
+
+	for name, config := range p.Dependencies {
+		dep, err := config.dependency(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency %s: %w", name, err)
+		}
+
+		deps[name] = dep
+	}
+
+	return deps, nil
+}
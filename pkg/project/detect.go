@@ -0,0 +1,427 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// detectionLanguage - describes how services written in a language are detected in existing code
+type detectionLanguage struct {
+	Name string
+	Ext  string
+	// Text found in files that import the nitric SDK
+	SdkMarkers []string
+	// Matches code that registers handlers or starts the service, files without a match are treated as shared modules
+	Entrypoint *regexp.Regexp
+	// Command used by nitric start to run the service
+	Start string
+	// Custom runtime required to build the service, languages with a standard runtime leave this empty
+	Runtime string
+	// Services are built from the directory containing the entrypoint, rather than the entrypoint file
+	DirectoryServices bool
+}
+
+var (
+	jsEntrypoint   = regexp.MustCompile(`\.(get|post|put|patch|delete|all|route|subscribe|every|cron|on|handler)\s*\(|\bhttp\s*\(`)
+	dartEntrypoint = regexp.MustCompile(`\.(get|post|put|patch|delete|all|route|subscribe|every|cron|on)\s*\(`)
+)
+
+var detectionLanguages = []detectionLanguage{
+	{Name: "typescript", Ext: ".ts", SdkMarkers: []string{"@nitric/sdk"}, Entrypoint: jsEntrypoint, Start: "npx tsx $SERVICE_PATH"},
+	{Name: "javascript", Ext: ".js", SdkMarkers: []string{"@nitric/sdk"}, Entrypoint: jsEntrypoint, Start: "node $SERVICE_PATH"},
+	{Name: "python", Ext: ".py", SdkMarkers: []string{"from nitric", "import nitric"}, Entrypoint: regexp.MustCompile(`Nitric\.run\s*\(`), Start: "python -u $SERVICE_PATH"},
+	{Name: "dart", Ext: ".dart", SdkMarkers: []string{"package:nitric_sdk"}, Entrypoint: dartEntrypoint, Start: "dart run $SERVICE_PATH"},
+	{Name: "csharp", Ext: ".csproj", SdkMarkers: []string{"Nitric.Sdk"}, Start: "dotnet run --project $SERVICE_PATH"},
+	{Name: "go", Ext: ".go", SdkMarkers: []string{"github.com/nitrictech/go-sdk"}, Entrypoint: regexp.MustCompile(`nitric\.Run\s*\(`), Start: "go run ./$SERVICE_PATH/...", Runtime: "go", DirectoryServices: true},
+}
+
+// DetectedRuntimes - custom runtimes proposed for detected services, keyed by runtime name
+var DetectedRuntimes = map[string]RuntimeConfiguration{
+	"go": {Dockerfile: "./golang.dockerfile"},
+}
+
+// ignoredDetectionDirs - directories that never contain project services
+var ignoredDetectionDirs = []string{"node_modules", "venv", "__pycache__", "dist", "build", "out", "target", "vendor", "bin", "obj"}
+
+// DetectedService - a group of service entrypoints found in existing code
+type DetectedService struct {
+	// The pattern matching the service entrypoints, relative to the scanned directory
+	Match       string
+	Language    string
+	Runtime     string
+	Start       string
+	Entrypoints []string
+}
+
+// Configuration - returns the service configuration for the detected service
+func (d DetectedService) Configuration() ServiceConfiguration {
+	return ServiceConfiguration{
+		Match:   d.Match,
+		Runtime: d.Runtime,
+		Start:   d.Start,
+	}
+}
+
+// DetectServices - scans a directory for service entrypoints using the nitric SDK, grouping them into match patterns.
+//
+// Entrypoints are matched with a pattern for their directory when every source file in the directory is an entrypoint,
+// otherwise each entrypoint is matched individually so shared modules aren't deployed as services.
+func DetectServices(afs afero.Fs, dir string) ([]DetectedService, error) {
+	type group struct {
+		language    detectionLanguage
+		dir         string
+		sourceFiles int
+		entrypoints []string
+	}
+
+	groups := map[string]*group{}
+
+	err := afero.Walk(afs, dir, func(filePath string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if filePath != dir && (strings.HasPrefix(info.Name(), ".") || slices.Contains(ignoredDetectionDirs, info.Name())) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		language, ok := lo.Find(detectionLanguages, func(l detectionLanguage) bool {
+			return filepath.Ext(filePath) == l.Ext
+		})
+		if !ok || strings.HasSuffix(filePath, ".d.ts") || strings.HasSuffix(filePath, "_test.go") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		relPath = filepath.ToSlash(relPath)
+		groupKey := slashPath(language.Name, filepath.Dir(relPath))
+
+		g, ok := groups[groupKey]
+		if !ok {
+			g = &group{language: language, dir: filepath.ToSlash(filepath.Dir(relPath))}
+			groups[groupKey] = g
+		}
+
+		g.sourceFiles++
+
+		contents, err := afero.ReadFile(afs, filePath)
+		if err != nil {
+			return err
+		}
+
+		if !lo.SomeBy(language.SdkMarkers, func(marker string) bool { return strings.Contains(string(contents), marker) }) {
+			return nil
+		}
+
+		if language.Entrypoint != nil && !language.Entrypoint.Match(contents) {
+			return nil
+		}
+
+		g.entrypoints = append(g.entrypoints, relPath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detected := []DetectedService{}
+
+	for _, g := range groups {
+		if len(g.entrypoints) == 0 {
+			continue
+		}
+
+		newService := func(match string, entrypoints []string) DetectedService {
+			return DetectedService{
+				Match:       match,
+				Language:    g.language.Name,
+				Runtime:     g.language.Runtime,
+				Start:       g.language.Start,
+				Entrypoints: entrypoints,
+			}
+		}
+
+		switch {
+		case g.language.DirectoryServices:
+			// each directory is a service, e.g. services/hello/main.go is matched by services/*
+			match := lo.Ternary(g.dir == ".", ".", slashPath(filepath.ToSlash(filepath.Dir(g.dir)), "*"))
+
+			existing, ok := lo.Find(detected, func(d DetectedService) bool { return d.Match == match })
+			if ok {
+				detected = lo.Reject(detected, func(d DetectedService, _ int) bool { return d.Match == match })
+
+				g.entrypoints = append(existing.Entrypoints, g.entrypoints...)
+			}
+
+			detected = append(detected, newService(match, g.entrypoints))
+		case len(g.entrypoints) == g.sourceFiles:
+			detected = append(detected, newService(slashPath(g.dir, "*"+g.language.Ext), g.entrypoints))
+		default:
+			for _, entrypoint := range g.entrypoints {
+				detected = append(detected, newService(entrypoint, []string{entrypoint}))
+			}
+		}
+	}
+
+	for i := range detected {
+		slices.Sort(detected[i].Entrypoints)
+	}
+
+	slices.SortFunc(detected, func(a DetectedService, b DetectedService) int {
+		return strings.Compare(a.Match, b.Match)
+	})
+
+	return detected, nil
+}
+
+// ProposeConfiguration - returns the project configuration with the detected services merged into its services, along with
+// any custom runtimes they require. Services that already match a detected service keep their settings, with the runtime
+// and start command filled in when they aren't set
+func ProposeConfiguration(existing ProjectConfiguration, detected []DetectedService) ProjectConfiguration {
+	proposed := existing
+	proposed.Services = slices.Clone(existing.Services)
+
+	for _, d := range detected {
+		i := slices.IndexFunc(proposed.Services, func(s ServiceConfiguration) bool { return serviceMatch(s) == d.Match })
+		if i < 0 {
+			proposed.Services = append(proposed.Services, d.Configuration())
+			continue
+		}
+
+		if proposed.Services[i].Runtime == "" {
+			proposed.Services[i].Runtime = d.Runtime
+		}
+
+		if proposed.Services[i].Start == "" {
+			proposed.Services[i].Start = d.Start
+		}
+	}
+
+	for _, d := range detected {
+		if d.Runtime == "" {
+			continue
+		}
+
+		if _, ok := proposed.Runtimes[d.Runtime]; ok {
+			continue
+		}
+
+		proposed.Runtimes = lo.Assign(proposed.Runtimes, map[string]RuntimeConfiguration{d.Runtime: DetectedRuntimes[d.Runtime]})
+	}
+
+	return proposed
+}
+
+// ProposeConfigurationFile - merges the detected services into the contents of a nitric.yaml like ProposeConfiguration,
+// editing the yaml document so the file's comments, formatting and any keys nitric doesn't know about are kept
+func ProposeConfigurationFile(contents []byte, detected []DetectedService) ([]byte, error) {
+	existing := ProjectConfiguration{}
+	if err := yaml.Unmarshal(contents, &existing); err != nil {
+		return nil, fmt.Errorf("unable to parse nitric.yaml: %w", err)
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, doc); err != nil {
+		return nil, fmt.Errorf("unable to parse nitric.yaml: %w", err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("nitric.yaml is not a yaml mapping")
+	}
+
+	proposed := ProposeConfiguration(existing, detected)
+	if reflect.DeepEqual(proposed, existing) {
+		return contents, nil
+	}
+
+	root := doc.Content[0]
+
+	services := mappingValue(root, "services", yaml.SequenceNode)
+
+	for i, service := range proposed.Services {
+		if i >= len(existing.Services) {
+			node := &yaml.Node{}
+			if err := node.Encode(service); err != nil {
+				return nil, err
+			}
+
+			services.Content = append(services.Content, node)
+
+			continue
+		}
+
+		if service.Runtime != existing.Services[i].Runtime {
+			setMappingValue(services.Content[i], "runtime", service.Runtime)
+		}
+
+		if service.Start != existing.Services[i].Start {
+			setMappingValue(services.Content[i], "start", service.Start)
+		}
+	}
+
+	if len(proposed.Runtimes) > len(existing.Runtimes) {
+		runtimes := mappingValue(root, "runtimes", yaml.MappingNode)
+
+		names := lo.Keys(proposed.Runtimes)
+		slices.Sort(names)
+
+		for _, name := range names {
+			if _, ok := existing.Runtimes[name]; ok {
+				continue
+			}
+
+			node := &yaml.Node{}
+			if err := node.Encode(proposed.Runtimes[name]); err != nil {
+				return nil, err
+			}
+
+			runtimes.Content = append(runtimes.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, node)
+		}
+	}
+
+	return encodeDocument(doc)
+}
+
+// PreviewConfigurationFile - sets the preview features of a nitric.yaml file, keeping its comments, formatting and the keys
+// not known to ProjectConfiguration. The preview key is removed when there are no features
+func PreviewConfigurationFile(contents []byte, features []string) ([]byte, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, doc); err != nil {
+		return nil, fmt.Errorf("unable to parse nitric.yaml: %w", err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("nitric.yaml is not a yaml mapping")
+	}
+
+	root := doc.Content[0]
+
+	existing := ProjectConfiguration{}
+	if err := yaml.Unmarshal(contents, &existing); err != nil {
+		return nil, fmt.Errorf("unable to parse nitric.yaml: %w", err)
+	}
+
+	if slices.Equal(existing.Preview, features) {
+		return contents, nil
+	}
+
+	if len(features) == 0 {
+		for i := 0; i < len(root.Content)-1; i += 2 {
+			if root.Content[i].Value == "preview" {
+				root.Content = slices.Delete(root.Content, i, i+2)
+				break
+			}
+		}
+
+		return encodeDocument(doc)
+	}
+
+	preview := mappingValue(root, "preview", yaml.SequenceNode)
+
+	// existing entries are kept, with their comments, and new features appended
+	items := lo.Filter(preview.Content, func(item *yaml.Node, _ int) bool { return slices.Contains(features, item.Value) })
+
+	for _, feature := range features {
+		if !lo.ContainsBy(items, func(item *yaml.Node) bool { return item.Value == feature }) {
+			items = append(items, &yaml.Node{Kind: yaml.ScalarNode, Value: feature})
+		}
+	}
+
+	preview.Content = items
+
+	return encodeDocument(doc)
+}
+
+// encodeDocument - encodes a yaml document with the indentation used by nitric.yaml files
+func encodeDocument(doc *yaml.Node) ([]byte, error) {
+	out := &bytes.Buffer{}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// serviceMatch - the pattern matching a service's entrypoints, relative to the project directory
+func serviceMatch(service ServiceConfiguration) string {
+	return slashPath(service.Basedir, service.Match)
+}
+
+// mappingValue - returns the value of a key in a yaml mapping, adding the key with an empty value of the given kind when
+// it's missing or empty
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+
+		if value := mapping.Content[i+1]; value.Kind == kind {
+			return value
+		}
+
+		mapping.Content[i+1] = &yaml.Node{Kind: kind}
+
+		return mapping.Content[i+1]
+	}
+
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+
+	return value
+}
+
+// setMappingValue - sets a key of a yaml mapping to a string value
+func setMappingValue(mapping *yaml.Node, key string, value string) {
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// slashPath - joins slash separated path elements, dropping the current directory
+func slashPath(elem ...string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Join(elem...)), "./")
+}
@@ -0,0 +1,94 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/docker"
+)
+
+// defaultImageSizeEstimate - the projected size of a service image that hasn't been built before
+const defaultImageSizeEstimate = 1 << 30
+
+// DiskSpaceCheck - compares the disk space available to the container engine with the space projected for building the project's services
+type DiskSpaceCheck struct {
+	Available uint64
+	Projected uint64
+	// Services that are projected to be rebuilt, services with unchanged build inputs use their cached image
+	Services []string
+}
+
+// Insufficient - returns true if the projected usage exceeds the available space
+func (c *DiskSpaceCheck) Insufficient() bool {
+	return c.Available < c.Projected
+}
+
+// Low - returns true if less than twice the projected usage is available, leaving little room for build layers and
+// the images being replaced
+func (c *DiskSpaceCheck) Low() bool {
+	return c.Available < 2*c.Projected
+}
+
+// CheckDiskSpace - projects the disk space used by building the project's services from the size of their previous images,
+// returns false when the space available to the container engine can't be determined
+func (p *Project) CheckDiskSpace(fs afero.Fs, opts ...BuildOption) (*DiskSpaceCheck, bool, error) {
+	options := &buildOptions{build: p.Build}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	dockerClient, err := docker.NewBuilder()
+	if err != nil {
+		return nil, false, err
+	}
+
+	available, ok := dockerClient.AvailableDiskSpace()
+	if !ok {
+		return nil, false, nil
+	}
+
+	check := &DiskSpaceCheck{Available: available, Services: []string{}}
+	cache := loadBuildCache(fs)
+
+	for _, svc := range p.services {
+		if !options.noCache && !options.reproducible {
+			buildKey, err := svc.buildKey(fs, svc.buildContext.DockerfileContents, options.build)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if cachedImageId, ok := cache.cachedImageId(svc.Name, svc.Image, buildKey); ok {
+				if imageId, err := dockerClient.ImageId(svc.Image); err == nil && imageId == cachedImageId {
+					continue
+				}
+			}
+		}
+
+		// rebuilt images are usually close to the size of the image they replace
+		size := int64(defaultImageSizeEstimate)
+		if previous, ok := dockerClient.ImageSize(svc.Image); ok {
+			size = previous
+		}
+
+		check.Projected += uint64(size)
+
+		check.Services = append(check.Services, svc.Name)
+	}
+
+	return check, true, nil
+}
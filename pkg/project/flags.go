@@ -0,0 +1,56 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"regexp"
+	"strings"
+)
+
+const flagEnvPrefix = "NITRIC_FLAG_"
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// FlagEnvName returns the environment variable used to expose a feature flag to services
+// e.g. new-checkout -> NITRIC_FLAG_NEW_CHECKOUT
+func FlagEnvName(flag string) string {
+	return flagEnvPrefix + invalidEnvChars.ReplaceAllString(strings.ToUpper(flag), "_")
+}
+
+// MergeFlags merges sets of feature flags, flags in later sets override those in earlier sets
+func MergeFlags(flagSets ...map[string]string) map[string]string {
+	merged := map[string]string{}
+
+	for _, flags := range flagSets {
+		for name, value := range flags {
+			merged[name] = value
+		}
+	}
+
+	return merged
+}
+
+// FlagsToEnv converts feature flags to environment variables, flags in later sets override those in earlier sets
+func FlagsToEnv(flagSets ...map[string]string) map[string]string {
+	env := map[string]string{}
+
+	for name, value := range MergeFlags(flagSets...) {
+		env[FlagEnvName(name)] = value
+	}
+
+	return env
+}
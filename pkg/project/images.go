@@ -0,0 +1,226 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/version"
+)
+
+// imageNameData - the values available to image name templates
+type imageNameData struct {
+	// Name of the project
+	Project string
+	// Name of the service without the project prefix, e.g. services-api
+	Service string
+	// Full name of the service, e.g. my-project_services-api
+	Name string
+	// Short SHA of the git commit checked out in the project directory
+	GitSha string
+	// UTC time the project was loaded, formatted as YYYYMMDDHHmmss
+	Timestamp string
+}
+
+// imageNamer - names service images using the project's image name template, images are named after their service when no template is set
+type imageNamer struct {
+	template *template.Template
+	data     imageNameData
+}
+
+func newImageNamer(projectConfig *ProjectConfiguration) (*imageNamer, error) {
+	if projectConfig.Images.Name == "" {
+		return &imageNamer{}, nil
+	}
+
+	tmpl, err := template.New("image").Option("missingkey=error").Parse(projectConfig.Images.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image name template %s: %w", projectConfig.Images.Name, err)
+	}
+
+	data := imageNameData{
+		Project:   projectConfig.Name,
+		Timestamp: time.Now().UTC().Format("20060102150405"),
+	}
+
+	if strings.Contains(projectConfig.Images.Name, "GitSha") {
+		data.GitSha, err = gitSha(projectConfig.Directory)
+		if err != nil {
+			return nil, fmt.Errorf("image name template %s uses GitSha: %w", projectConfig.Images.Name, err)
+		}
+	}
+
+	return &imageNamer{template: tmpl, data: data}, nil
+}
+
+// imageName - returns the name of the image built for the service with the given name
+func (n *imageNamer) imageName(projectName string, serviceName string) (string, error) {
+	if n.template == nil {
+		return serviceName, nil
+	}
+
+	data := n.data
+	data.Name = serviceName
+	data.Service = strings.TrimPrefix(serviceName, strings.ToLower(projectName)+"_")
+
+	var name bytes.Buffer
+
+	if err := n.template.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("unable to name image for service %s: %w", serviceName, err)
+	}
+
+	if _, err := reference.ParseNormalizedNamed(name.String()); err != nil {
+		return "", fmt.Errorf("image name %s for service %s is not a valid image reference: %w", name.String(), serviceName, err)
+	}
+
+	return name.String(), nil
+}
+
+// GitCommit - the short git commit of the project, empty when the project isn't in a git repository
+func (p *Project) GitCommit() string {
+	sha, err := gitSha(p.Directory)
+	if err != nil {
+		return ""
+	}
+
+	return sha
+}
+
+func gitSha(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to read the git commit of %s: %w", dir, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+const (
+	// ImageProjectLabel - the label holding the name of the project an image was built for
+	ImageProjectLabel = "dev.nitric.project"
+	// ImageServiceLabel - the label holding the name of the service an image was built for
+	ImageServiceLabel = "dev.nitric.service"
+	// ImageCliVersionLabel - the label holding the version of the CLI that built an image
+	ImageCliVersionLabel = "dev.nitric.cli-version"
+	// ImageRevisionLabel - the OCI label holding the git commit an image was built from
+	ImageRevisionLabel = "org.opencontainers.image.revision"
+	// ImageCreatedLabel - the OCI label holding the time an image was built
+	ImageCreatedLabel = "org.opencontainers.image.created"
+)
+
+// imageLabels - returns the labels set on every image built for the project, the service label is added by each build
+func (p *Project) imageLabels() map[string]string {
+	labels := map[string]string{
+		ImageProjectLabel:    p.Name,
+		ImageCliVersionLabel: version.Version,
+		ImageCreatedLabel:    time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if commit := p.GitCommit(); commit != "" {
+		labels[ImageRevisionLabel] = commit
+	}
+
+	return labels
+}
+
+// ServiceImage - a local image of one of the project's services, from the latest or an earlier build
+type ServiceImage struct {
+	Service string `json:"service"`
+	docker.Image
+	// Git commit the image was built from, read from its org.opencontainers.image.revision label
+	Commit string `json:"commit,omitempty"`
+	// True when the service's image name refers to the image, i.e. it's the latest build of the service
+	Current bool `json:"current"`
+}
+
+// ServiceImages - returns the local images of the project's services, every tag of each service's image repository is listed
+// so earlier builds and images kept for rollbacks are included, newest first for each service. Images labeled with the
+// project's name are included too, e.g. builds of removed services or builds named with an earlier image name template
+func (p *Project) ServiceImages() ([]ServiceImage, error) {
+	dockerClient, err := docker.NewBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	images := []ServiceImage{}
+	listed := map[string]bool{}
+
+	for _, svc := range p.services {
+		named, err := reference.ParseNormalizedNamed(svc.Image)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image name %s for service %s: %w", svc.Image, svc.Name, err)
+		}
+
+		serviceImages, err := dockerClient.ListImages(reference.FamiliarName(named))
+		if err != nil {
+			return nil, err
+		}
+
+		currentId, _ := dockerClient.ImageId(svc.Image)
+
+		slices.SortFunc(serviceImages, func(a, b docker.Image) int {
+			return b.Created.Compare(a.Created)
+		})
+
+		for _, image := range serviceImages {
+			listed[image.ID] = true
+
+			images = append(images, ServiceImage{
+				Service: lo.Ternary(image.Labels[ImageServiceLabel] != "", image.Labels[ImageServiceLabel], svc.Name),
+				Image:   image,
+				Commit:  image.Labels[ImageRevisionLabel],
+				Current: image.ID == currentId,
+			})
+		}
+	}
+
+	labeled, err := dockerClient.ListImagesWithLabel(ImageProjectLabel, p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(labeled, func(a, b docker.Image) int {
+		return b.Created.Compare(a.Created)
+	})
+
+	for _, image := range labeled {
+		if listed[image.ID] {
+			continue
+		}
+
+		images = append(images, ServiceImage{
+			Service: image.Labels[ImageServiceLabel],
+			Image:   image,
+			Commit:  image.Labels[ImageRevisionLabel],
+		})
+	}
+
+	return images, nil
+}
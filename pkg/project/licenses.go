@@ -0,0 +1,302 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/docker"
+)
+
+const (
+	LicenseActionFail = "fail"
+	LicenseActionWarn = "warn"
+)
+
+var errSyftMissing = fmt.Errorf("syft is required to scan images for licenses, for installation instructions see: https://github.com/anchore/syft#installation")
+
+// Enabled - returns true when built images should be scanned for licenses
+func (l LicenseConfiguration) Enabled() bool {
+	return l.Scan || len(l.Deny) > 0
+}
+
+// Validate - checks the license action is valid
+func (l LicenseConfiguration) Validate() error {
+	if !slices.Contains([]string{"", LicenseActionFail, LicenseActionWarn}, l.Action) {
+		return fmt.Errorf("invalid build.licenses.action %s, must be one of %s or %s", l.Action, LicenseActionFail, LicenseActionWarn)
+	}
+
+	return nil
+}
+
+// ReportFile - the path the license report is written to
+func (l LicenseConfiguration) ReportFile() string {
+	if l.Report != "" {
+		return l.Report
+	}
+
+	return filepath.Join(tempBuildDir, "licenses.json")
+}
+
+// PackageLicenses - the licenses of a package found in a service image
+type PackageLicenses struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Type     string   `json:"type"`
+	Licenses []string `json:"licenses"`
+	// The licenses of the package denied by build.licenses.deny, empty when the package is allowed
+	Denied []string `json:"denied,omitempty"`
+}
+
+// ServiceLicenses - the packages found in the image of a service
+type ServiceLicenses struct {
+	Service  string            `json:"service"`
+	Image    string            `json:"image"`
+	Packages []PackageLicenses `json:"packages"`
+}
+
+// LicenseViolation - a package with a denied license
+type LicenseViolation struct {
+	Service string
+	PackageLicenses
+}
+
+// LicenseReport - the licenses of the dependencies in each service image
+type LicenseReport struct {
+	Deny     []string          `json:"deny,omitempty"`
+	Services []ServiceLicenses `json:"services"`
+}
+
+// Violations - the packages of each service with denied licenses
+func (r *LicenseReport) Violations() []LicenseViolation {
+	violations := []LicenseViolation{}
+
+	for _, service := range r.Services {
+		for _, pkg := range service.Packages {
+			if len(pkg.Denied) > 0 {
+				violations = append(violations, LicenseViolation{Service: service.Service, PackageLicenses: pkg})
+			}
+		}
+	}
+
+	return violations
+}
+
+// Unlicensed - the number of packages with no detected license
+func (r *LicenseReport) Unlicensed() int {
+	return lo.SumBy(r.Services, func(service ServiceLicenses) int {
+		return lo.CountBy(service.Packages, func(pkg PackageLicenses) bool { return len(pkg.Licenses) == 0 })
+	})
+}
+
+// ToFile - writes the report to the configured report file
+func (r *LicenseReport) ToFile(fs afero.Fs, file string) error {
+	err := fs.MkdirAll(filepath.Dir(file), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("unable to create license report directory %s: %w", filepath.Dir(file), err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, file, data, os.ModePerm)
+}
+
+// syftPackage - a package found by syft, licenses are strings in older versions of syft and objects in newer versions
+type syftPackage struct {
+	Name     string            `json:"name"`
+	Version  string            `json:"version"`
+	Type     string            `json:"type"`
+	Licenses []json.RawMessage `json:"licenses"`
+}
+
+func (p syftPackage) licenses() []string {
+	licenses := []string{}
+
+	for _, raw := range p.Licenses {
+		var value string
+		if json.Unmarshal(raw, &value) == nil {
+			licenses = append(licenses, value)
+
+			continue
+		}
+
+		var license struct {
+			Value          string `json:"value"`
+			SpdxExpression string `json:"spdxExpression"`
+		}
+
+		if json.Unmarshal(raw, &license) == nil {
+			licenses = append(licenses, lo.Ternary(license.SpdxExpression != "", license.SpdxExpression, license.Value))
+		}
+	}
+
+	return lo.Uniq(lo.Compact(licenses))
+}
+
+// syftSource - the syft source scheme for images in the local store of the container engine
+func syftSource(image string) (string, error) {
+	engine, err := docker.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	switch engine {
+	case docker.EngineDocker:
+		return "docker:" + image, nil
+	case docker.EnginePodman:
+		return "podman:" + image, nil
+	default:
+		return "", fmt.Errorf("scanning images for licenses requires docker or podman, %s images can't be read by syft", engine)
+	}
+}
+
+// scanImage - lists the packages in an image and their licenses with syft
+func scanImage(image string) ([]syftPackage, error) {
+	if _, err := exec.LookPath("syft"); err != nil {
+		return nil, errSyftMissing
+	}
+
+	source, err := syftSource(image)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("syft", source, "--output", "syft-json", "--quiet")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("unable to scan image %s: %s", image, message)
+		}
+
+		return nil, fmt.Errorf("unable to scan image %s: %w", image, err)
+	}
+
+	sbom := struct {
+		Artifacts []syftPackage `json:"artifacts"`
+	}{}
+
+	if err := json.Unmarshal(stdout.Bytes(), &sbom); err != nil {
+		return nil, fmt.Errorf("unable to read the packages of image %s: %w", image, err)
+	}
+
+	return sbom.Artifacts, nil
+}
+
+// matchesLicense - returns true when a license identifier matches a deny pattern, patterns ending in * match by prefix
+func matchesLicense(license string, pattern string) bool {
+	license = strings.ToLower(license)
+	pattern = strings.ToLower(pattern)
+
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(license, prefix)
+	}
+
+	return license == pattern
+}
+
+var (
+	licenseOr   = regexp.MustCompile(`(?i)\s+OR\s+`)
+	licenseAnd  = regexp.MustCompile(`(?i)\s+AND\s+`)
+	licenseWith = regexp.MustCompile(`(?i)\s+WITH\s+.*$`)
+)
+
+// deniedLicenses - returns the licenses of an SPDX expression matching the deny patterns. An expression is only denied
+// when every alternative of an OR includes a denied license, e.g. MIT OR GPL-2.0-only is allowed when GPL-* is denied
+func deniedLicenses(expression string, deny []string) []string {
+	denied := []string{}
+
+	for _, alternative := range licenseOr.Split(expression, -1) {
+		alternativeDenied := []string{}
+
+		for _, license := range licenseAnd.Split(alternative, -1) {
+			license = strings.Trim(licenseWith.ReplaceAllString(strings.Trim(license, "() "), ""), "() ")
+
+			if lo.ContainsBy(deny, func(pattern string) bool { return matchesLicense(license, pattern) }) {
+				alternativeDenied = append(alternativeDenied, license)
+			}
+		}
+
+		if len(alternativeDenied) == 0 {
+			return nil
+		}
+
+		denied = append(denied, alternativeDenied...)
+	}
+
+	return lo.Uniq(denied)
+}
+
+// ScanLicenses - scans the built images of the project's services for the licenses of their dependencies,
+// marking the packages that have licenses denied by build.licenses.deny
+func (p *Project) ScanLicenses() (*LicenseReport, error) {
+	config := p.Build.Licenses
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &LicenseReport{Deny: config.Deny, Services: []ServiceLicenses{}}
+
+	for _, service := range p.GetServices() {
+		packages, err := scanImage(service.Image)
+		if err != nil {
+			return nil, err
+		}
+
+		serviceLicenses := ServiceLicenses{Service: service.Name, Image: service.Image, Packages: []PackageLicenses{}}
+
+		for _, pkg := range packages {
+			if slices.Contains(config.IgnorePackages, pkg.Name) {
+				continue
+			}
+
+			pkgLicenses := PackageLicenses{Name: pkg.Name, Version: pkg.Version, Type: pkg.Type, Licenses: pkg.licenses()}
+
+			for _, license := range pkgLicenses.Licenses {
+				pkgLicenses.Denied = append(pkgLicenses.Denied, deniedLicenses(license, config.Deny)...)
+			}
+
+			serviceLicenses.Packages = append(serviceLicenses.Packages, pkgLicenses)
+		}
+
+		slices.SortFunc(serviceLicenses.Packages, func(a, b PackageLicenses) int {
+			return strings.Compare(a.Name+"@"+a.Version, b.Name+"@"+b.Version)
+		})
+
+		report.Services = append(report.Services, serviceLicenses)
+	}
+
+	return report, nil
+}
@@ -19,7 +19,9 @@ package localconfig
 import (
 	"fmt"
 	"os"
+	"sort"
 
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
@@ -29,9 +31,29 @@ type LocalResourceConfiguration struct {
 	Port int `yaml:"port"`
 }
 
+type LocalServiceConfiguration struct {
+	// Port of the service's nitric server, services reach it with SERVICE_ADDRESS
+	Port int `yaml:"port,omitempty"`
+	// Port the local gateway serves the service's HTTP proxy on, for services using nitric http
+	HttpPort int `yaml:"http-port,omitempty"`
+}
+
+type AccessLogConfiguration struct {
+	// Format of access log entries, one of combined (default), json or off
+	Format string `yaml:"format,omitempty"`
+	// File access logs are appended to, defaults to the run log in the .nitric directory
+	File string `yaml:"file,omitempty"`
+}
+
 type LocalConfiguration struct {
 	Apis       map[string]LocalResourceConfiguration `yaml:"apis"`
 	Websockets map[string]LocalResourceConfiguration `yaml:"websockets"`
+	// Fixed ports of services, keyed by service name, so addresses in frontend .env files don't change between runs
+	Services map[string]LocalServiceConfiguration `yaml:"services,omitempty"`
+	// Configures the access logs written by the local gateway for API and HTTP requests
+	AccessLog AccessLogConfiguration `yaml:"access-log,omitempty"`
+	// Keeps the state of local buckets, key/value stores, secrets and queues between runs in .nitric/state, see nitric run --persist
+	Persist bool `yaml:"persist,omitempty"`
 }
 
 const defaultLocalNitricYamlPath = "./local.nitric.yaml"
@@ -67,5 +89,64 @@ func LocalConfigurationFromFile(fs afero.Fs, filePath string) (*LocalConfigurati
 		return nil, fmt.Errorf("unable to parse local.nitric.yaml: %w", err)
 	}
 
+	if err := localConfig.validate(); err != nil {
+		return nil, err
+	}
+
 	return localConfig, nil
 }
+
+// validate - checks fixed ports are valid and not assigned to more than one resource
+func (c *LocalConfiguration) validate() error {
+	assigned := map[int]string{}
+
+	assign := func(port int, resource string) error {
+		if port == 0 {
+			return nil
+		}
+
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("port %d of %s must be between 1 and 65535", port, resource)
+		}
+
+		if existing, ok := assigned[port]; ok {
+			return fmt.Errorf("port %d is assigned to both %s and %s", port, existing, resource)
+		}
+
+		assigned[port] = resource
+
+		return nil
+	}
+
+	// iterate in a stable order so the same conflict is always reported
+	for _, name := range sortedKeys(c.Apis) {
+		if err := assign(c.Apis[name].Port, "api "+name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(c.Websockets) {
+		if err := assign(c.Websockets[name].Port, "websocket "+name); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(c.Services) {
+		if err := assign(c.Services[name].Port, "service "+name); err != nil {
+			return err
+		}
+
+		if err := assign(c.Services[name].HttpPort, "the http proxy of service "+name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+
+	return keys
+}
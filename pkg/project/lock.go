@@ -0,0 +1,119 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const LockFileName = "nitric.lock"
+
+const lockFileHeader = "# Generated by nitric lock update, commit this file so all builds use the same base images\n"
+
+// LockFile - the digests of the base images used to build the project's services,
+// builds resolve base images from the lock file so teammates and CI build and collect identical services
+type LockFile struct {
+	// Base image digests keyed by the image referenced in the dockerfile, e.g. node:22.4.1-alpine
+	Images map[string]string `yaml:"images"`
+}
+
+// LockFileFromFile - loads the lock file from the project directory, returning nil if the project has no lock file
+func LockFileFromFile(fs afero.Fs, projectDir string) (*LockFile, error) {
+	lockFilePath := filepath.Join(projectDir, LockFileName)
+
+	contents, err := afero.ReadFile(fs, lockFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to read %s: %w", LockFileName, err)
+	}
+
+	lockFile := &LockFile{}
+
+	if err := yaml.Unmarshal(contents, lockFile); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", LockFileName, err)
+	}
+
+	if lockFile.Images == nil {
+		lockFile.Images = map[string]string{}
+	}
+
+	return lockFile, nil
+}
+
+// ToFile - writes the lock file to the project directory, returning the written file path
+func (l LockFile) ToFile(fs afero.Fs, projectDir string) (string, error) {
+	contents, err := yaml.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+
+	lockFilePath := filepath.Join(projectDir, LockFileName)
+
+	return lockFilePath, afero.WriteFile(fs, lockFilePath, append([]byte(lockFileHeader), contents...), 0o644)
+}
+
+// resolveDigest - resolves base image digests from the lock file, images missing from the lock file are an error so builds don't silently use unpinned images
+func (l LockFile) resolveDigest(serviceName string) func(image string) (string, error) {
+	return func(image string) (string, error) {
+		digest, ok := l.Images[image]
+		if !ok {
+			return "", fmt.Errorf("base image %s of service %s is not in %s, run nitric lock update to add it", image, serviceName, LockFileName)
+		}
+
+		return digest, nil
+	}
+}
+
+// LockBaseImages - resolves the digests of the base images used by the project's services,
+// returning the resolved digests and any images that can't be locked, e.g. images referenced by a build arg
+func (p *Project) LockBaseImages(resolveDigest func(image string) (string, error)) (*LockFile, []string, error) {
+	lockFile := &LockFile{Images: map[string]string{}}
+	unlocked := []string{}
+
+	// services commonly share base images, resolve each image once
+	resolveOnce := func(image string) (string, error) {
+		if digest, ok := lockFile.Images[image]; ok {
+			return digest, nil
+		}
+
+		return resolveDigest(image)
+	}
+
+	for _, service := range p.services {
+		_, pinned, unpinned, err := pinBaseImages(service.buildContext.DockerfileContents, resolveOnce)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to resolve base images for service %s: %w", service.Name, err)
+		}
+
+		for image, digest := range pinned {
+			lockFile.Images[image] = digest
+		}
+
+		unlocked = append(unlocked, unpinned...)
+	}
+
+	return lockFile, lo.Uniq(unlocked), nil
+}
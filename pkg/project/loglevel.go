@@ -0,0 +1,99 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// LogLevel - the severity of a line of service output, detected from common log formats
+type LogLevel string
+
+const (
+	LogLevel_Debug LogLevel = "debug"
+	LogLevel_Info  LogLevel = "info"
+	LogLevel_Warn  LogLevel = "warn"
+	LogLevel_Error LogLevel = "error"
+)
+
+var LogLevels = []LogLevel{LogLevel_Debug, LogLevel_Info, LogLevel_Warn, LogLevel_Error}
+
+// logLevelEnv - the environment variable services read their log level from by convention
+const logLevelEnv = "LOG_LEVEL"
+
+var (
+	// structured logs, e.g. {"level":"warn"} or level=warn
+	structuredLogLevel = regexp.MustCompile(`(?i)\b(?:level|severity|lvl)"?\s*[=:]\s*"?(trace|debug|info|warn|warning|error|fatal|critical)\b`)
+	// prefixed logs, e.g. [WARN] ..., WARNING:root:... or 2024-01-01T00:00:00Z error ...
+	prefixedLogLevel = regexp.MustCompile(`(?i)^[\d\-:.TZ ]*\[?(trace|debug|info|warn|warning|error|fatal|critical)\]?\b`)
+)
+
+// ParseLogLevel - parses a log level, accepting common aliases, e.g. warning for warn
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return LogLevel_Debug, nil
+	case "info":
+		return LogLevel_Info, nil
+	case "warn", "warning":
+		return LogLevel_Warn, nil
+	case "error", "fatal", "critical":
+		return LogLevel_Error, nil
+	}
+
+	return "", fmt.Errorf("invalid log level %s, expected one of %s", level, strings.Join(lo.Map(LogLevels, func(l LogLevel, _ int) string { return string(l) }), ", "))
+}
+
+// DetectLogLevel - returns the level of a line of output from structured or prefixed log formats, false when it can't be detected
+func DetectLogLevel(message string) (LogLevel, bool) {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+
+	for _, pattern := range []*regexp.Regexp{structuredLogLevel, prefixedLogLevel} {
+		if match := pattern.FindStringSubmatch(firstLine); match != nil {
+			level, err := ParseLogLevel(match[1])
+			return level, err == nil
+		}
+	}
+
+	return "", false
+}
+
+// Includes - returns true when the level is at or above the minimum level, output without a detected level is treated as info
+func (minimum LogLevel) Includes(level LogLevel) bool {
+	if level == "" {
+		level = LogLevel_Info
+	}
+
+	return lo.IndexOf(LogLevels, level) >= lo.IndexOf(LogLevels, minimum)
+}
+
+// ServiceLogLevels - returns the minimum log level of each service that sets one in nitric.yaml
+func (p *Project) ServiceLogLevels() map[string]LogLevel {
+	levels := map[string]LogLevel{}
+
+	for _, svc := range p.services {
+		if svc.logLevel != "" {
+			levels[svc.Name] = svc.logLevel
+		}
+	}
+
+	return levels
+}
@@ -24,6 +24,7 @@ import (
 	goruntime "runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/spf13/afero"
@@ -32,7 +33,6 @@ import (
 	"github.com/nitrictech/cli/pkg/collector"
 	"github.com/nitrictech/cli/pkg/docker"
 	"github.com/nitrictech/cli/pkg/project/runtime"
-	"github.com/nitrictech/nitric/core/pkg/env"
 	"github.com/nitrictech/nitric/core/pkg/logger"
 	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
 )
@@ -80,11 +80,16 @@ func BuildAndRunMigrations(fs afero.Fs, servers map[string]*sql.DatabaseServer,
 	return nil
 }
 
-func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.RuntimeBuildContext, logs io.Writer) error {
+func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.RuntimeBuildContext, logs io.Writer, opts ...BuildOption) error {
+	options := &buildOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	tempBuildDir := GetTempBuildDir()
 	svcName := migrationImageName(dbName)
 
-	dockerClient, err := docker.New()
+	dockerClient, err := docker.NewBuilder()
 	if err != nil {
 		return err
 	}
@@ -120,6 +125,8 @@ func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.Runti
 		buildContext.BuildArguments,
 		strings.Split(buildContext.IgnoreFileContents, "\n"),
 		logs,
+		docker.WithBuilder(options.build.Builder),
+		docker.WithPlatforms(options.build.Platforms...),
 	)
 	if err != nil {
 		return err
@@ -129,7 +136,7 @@ func BuildMigrationImage(fs afero.Fs, dbName string, buildContext *runtime.Runti
 }
 
 // FIXME: This is essentially a copy of the project.BuildServiceImages function
-func BuildMigrationImages(fs afero.Fs, migrationBuildContexts map[string]*runtime.RuntimeBuildContext) (chan ServiceBuildUpdate, error) {
+func BuildMigrationImages(fs afero.Fs, migrationBuildContexts map[string]*runtime.RuntimeBuildContext, opts ...BuildOption) (chan ServiceBuildUpdate, error) {
 	updatesChan := make(chan ServiceBuildUpdate)
 
 	maxConcurrentBuilds := make(chan struct{}, min(goruntime.NumCPU(), goruntime.GOMAXPROCS(0)))
@@ -146,22 +153,21 @@ func BuildMigrationImages(fs afero.Fs, migrationBuildContexts map[string]*runtim
 			// this will block once the buffer is full
 			maxConcurrentBuilds <- struct{}{}
 
+			buildStart := time.Now()
+
 			svcName := migrationImageName(dbName)
 
 			// Start goroutine
-			if err := BuildMigrationImage(fs, dbName, buildContext, writer); err != nil {
+			if err := BuildMigrationImage(fs, dbName, buildContext, writer, opts...); err != nil {
 				updatesChan <- ServiceBuildUpdate{
 					ServiceName: svcName,
 					Err:         err,
 					Message:     err.Error(),
 					Status:      ServiceBuildStatus_Error,
+					Elapsed:     time.Since(buildStart),
 				}
 			} else {
-				updatesChan <- ServiceBuildUpdate{
-					ServiceName: svcName,
-					Message:     "Build Complete",
-					Status:      ServiceBuildStatus_Complete,
-				}
+				updatesChan <- buildCompleteUpdate(svcName, time.Since(buildStart))
 			}
 
 			// release our lock
@@ -194,15 +200,12 @@ func RunMigration(databaseName string, connectionString string) error {
 	// Run the migrations
 	imageName := migrationImageName(databaseName)
 
-	// Update connection string for docker host...
-	dockerHost := "host.docker.internal"
-
-	if goruntime.GOOS == "linux" {
-		host := env.GetEnv("NITRIC_DOCKER_HOST", "172.17.0.1")
-
-		dockerHost = host.String()
+	hostConfig := &container.HostConfig{
+		AutoRemove: true,
 	}
 
+	// Update connection string for docker host...
+	dockerHost := applyNetworkMode(NetworkMode_Bridge, hostConfig)
 	dockerConnectionString := strings.Replace(connectionString, "localhost", dockerHost, 1)
 
 	// Create the container
@@ -212,9 +215,7 @@ func RunMigration(databaseName string, connectionString string) error {
 			fmt.Sprintf("NITRIC_DB_NAME=%s", databaseName),
 			fmt.Sprintf("DB_URL=%s", dockerConnectionString),
 		},
-	}, &container.HostConfig{
-		AutoRemove: true,
-	}, nil, fmt.Sprintf("nitric-%s-migrations-local-sql", databaseName))
+	}, hostConfig, nil, fmt.Sprintf("nitric-%s-migrations-local-sql", databaseName))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,127 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	goruntime "runtime"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/nitric/core/pkg/env"
+)
+
+// Service containers reach the nitric server running on the host using one of the following network modes:
+//
+//   - bridge (default): containers run on the default docker bridge network and connect to the server using host.docker.internal.
+//     Docker Desktop resolves host.docker.internal itself, on linux it's mapped to the host's gateway address (host-gateway).
+//     Set NITRIC_DOCKER_HOST to map it to another address of the host when that isn't reachable, e.g. WSL2 or remote docker engines.
+//   - host: containers share the host's network and connect to the server using localhost, no ports are published.
+//   - any other value is treated as the name of an existing docker network, containers connect to the server using host.docker.internal as with bridge.
+//
+// Containers on remote docker engines, from DOCKER_HOST, are connected to the server with connectRemoteEngine.
+const (
+	NetworkMode_Bridge = "bridge"
+	NetworkMode_Host   = "host"
+)
+
+// DefaultNetworkMode - the network mode used for service containers when none is provided, configurable with NITRIC_DOCKER_NETWORK
+func DefaultNetworkMode() string {
+	mode := env.GetEnv("NITRIC_DOCKER_NETWORK", NetworkMode_Bridge)
+
+	return mode.String()
+}
+
+// ValidateNetworkMode - ensures custom networks exist before service containers are started on them
+func ValidateNetworkMode(mode string) error {
+	if mode == "" || mode == NetworkMode_Bridge || mode == NetworkMode_Host {
+		return nil
+	}
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		return err
+	}
+
+	if _, err := dockerClient.NetworkInspect(context.Background(), mode, types.NetworkInspectOptions{}); err != nil {
+		return fmt.Errorf("unable to use docker network %s, create it with docker network create %s: %w", mode, mode, err)
+	}
+
+	return nil
+}
+
+// dockerHostGateway - the address mapped to host.docker.internal, docker resolves host-gateway to the host's address.
+// Returns false when Docker Desktop's own mapping can be used.
+func dockerHostGateway() (string, bool) {
+	if host, ok := os.LookupEnv("NITRIC_DOCKER_HOST"); ok {
+		return host, true
+	}
+
+	return "host-gateway", goruntime.GOOS == "linux"
+}
+
+// applyNetworkMode - configures a container to use the network mode, returning the host the container uses to reach the nitric server
+func applyNetworkMode(mode string, hostConfig *container.HostConfig) string {
+	if mode == NetworkMode_Host {
+		hostConfig.NetworkMode = container.NetworkMode(NetworkMode_Host)
+		return "localhost"
+	}
+
+	if mode != "" && mode != NetworkMode_Bridge {
+		hostConfig.NetworkMode = container.NetworkMode(mode)
+	}
+
+	if hostGateway, ok := dockerHostGateway(); ok {
+		// setup host.docker.internal to route to host gateway
+		// to access rpc server hosted by local CLI run
+		hostConfig.ExtraHosts = []string{"host.docker.internal:" + hostGateway}
+	}
+
+	return "host.docker.internal"
+}
+
+// connectRemoteEngine - configures a container on a remote docker engine to reach the nitric server listening on port,
+// returning the host the container uses to reach the server and a function that releases the connection.
+//
+// Engines accessed over ssh forward the port back to this machine with an ssh tunnel, the container shares the engine's host network to reach it.
+// Other engines map host.docker.internal to the address of this machine on the route to the engine.
+func connectRemoteEngine(engine *docker.RemoteEngine, port string, hostConfig *container.HostConfig) (string, func(), error) {
+	if engine.IsSSH() {
+		closeTunnel, err := engine.ReverseTunnel(port)
+		if err != nil {
+			return "", nil, err
+		}
+
+		hostConfig.NetworkMode = container.NetworkMode(NetworkMode_Host)
+		hostConfig.ExtraHosts = nil
+
+		return "localhost", closeTunnel, nil
+	}
+
+	address, err := engine.ReachableAddress()
+	if err != nil {
+		return "", nil, err
+	}
+
+	hostConfig.ExtraHosts = []string{"host.docker.internal:" + address}
+
+	return "host.docker.internal", func() {}, nil
+}
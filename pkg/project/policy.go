@@ -0,0 +1,133 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Commands that can be restricted by a policy
+var PolicyCommands = []string{"up", "down", "gc"}
+
+const (
+	// ConfirmStackName - the stack name must be typed, or passed with --confirm-stack, before the command runs
+	ConfirmStackName = "stack-name"
+
+	RunInCI    = "ci"
+	RunInLocal = "local"
+)
+
+type CommandPolicyConfiguration struct {
+	// Stacks the policy applies to, may contain wildcards, e.g. prod or prod-*
+	Stacks []string `yaml:"stacks"`
+	// Commands the policy applies to, one or more of up, down or gc (when deleting orphaned resources)
+	Commands []string `yaml:"commands"`
+	// Confirmation required before the command runs, stack-name requires the stack name to be typed
+	Confirm string `yaml:"confirm,omitempty"`
+	// Restricts where the command can run, either ci or local
+	// CI is detected with the --ci flag or the CI environment variable set by most CI systems. This guards against
+	// running commands in the wrong place by mistake, it isn't access control, anyone can set the flag or variable
+	RunIn string `yaml:"run-in,omitempty"`
+}
+
+// applies - returns true if the policy applies to the command for the stack
+func (c CommandPolicyConfiguration) applies(command string, stackName string) bool {
+	if !slices.Contains(c.Commands, command) {
+		return false
+	}
+
+	return lo.ContainsBy(c.Stacks, func(pattern string) bool {
+		match, _ := path.Match(pattern, stackName)
+		return match
+	})
+}
+
+// ValidatePolicies - validates the command policies of the project
+func (p ProjectConfiguration) ValidatePolicies() error {
+	for i, policy := range p.Policies {
+		if len(policy.Stacks) == 0 || len(policy.Commands) == 0 {
+			return fmt.Errorf("policy %d must list the stacks and commands it applies to", i+1)
+		}
+
+		for _, pattern := range policy.Stacks {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid stack pattern '%s' in policy %d: %w", pattern, i+1, err)
+			}
+		}
+
+		for _, command := range policy.Commands {
+			if !slices.Contains(PolicyCommands, command) {
+				return fmt.Errorf("invalid command '%s' in policy %d, valid commands are: %s", command, i+1, strings.Join(PolicyCommands, ", "))
+			}
+		}
+
+		if policy.Confirm != "" && policy.Confirm != ConfirmStackName {
+			return fmt.Errorf("invalid confirm '%s' in policy %d, valid confirmations are: %s", policy.Confirm, i+1, ConfirmStackName)
+		}
+
+		if policy.RunIn != "" && policy.RunIn != RunInCI && policy.RunIn != RunInLocal {
+			return fmt.Errorf("invalid run-in '%s' in policy %d, must be %s or %s", policy.RunIn, i+1, RunInCI, RunInLocal)
+		}
+	}
+
+	return nil
+}
+
+// CommandPolicies - returns the policies that apply to a command for a stack
+func (p ProjectConfiguration) CommandPolicies(command string, stackName string) []CommandPolicyConfiguration {
+	return lo.Filter(p.Policies, func(policy CommandPolicyConfiguration, _ int) bool {
+		return policy.applies(command, stackName)
+	})
+}
+
+// RunningInCI - returns true if the CI environment variable is set, as it is by github actions, gitlab, circleci and most other CI systems
+func RunningInCI() bool {
+	ci, err := strconv.ParseBool(os.Getenv("CI"))
+
+	return err == nil && ci
+}
+
+// CheckRunIn - returns an error if the command isn't allowed to run in the current environment by any of the policies,
+// ciFlag is whether the CLI was run with --ci, which is treated as running in CI regardless of the CI environment variable
+func CheckRunIn(policies []CommandPolicyConfiguration, command string, stackName string, ciFlag bool) error {
+	inCI := ciFlag || RunningInCI()
+
+	for _, policy := range policies {
+		switch {
+		case policy.RunIn == RunInCI && !inCI:
+			return fmt.Errorf("nitric stack %s can only be run for stack %s in CI, as required by the policies in nitric.yaml", command, stackName)
+		case policy.RunIn == RunInLocal && inCI:
+			return fmt.Errorf("nitric stack %s can't be run for stack %s in CI, as required by the policies in nitric.yaml", command, stackName)
+		}
+	}
+
+	return nil
+}
+
+// RequiresStackNameConfirmation - returns true if any of the policies require the stack name to be confirmed
+func RequiresStackNameConfirmation(policies []CommandPolicyConfiguration) bool {
+	return lo.ContainsBy(policies, func(policy CommandPolicyConfiguration) bool {
+		return policy.Confirm == ConfirmStackName
+	})
+}
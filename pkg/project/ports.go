@@ -0,0 +1,64 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/cloud"
+	"github.com/nitrictech/cli/pkg/project/localconfig"
+)
+
+// validateServicePorts - checks the services with fixed ports in the local configuration are in the project
+func validateServicePorts(localConfig *localconfig.LocalConfiguration, services []Service) error {
+	names := lo.Keys(localConfig.Services)
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !lo.ContainsBy(services, func(svc Service) bool { return svc.Name == name }) {
+			return fmt.Errorf("local.nitric.yaml sets the ports of service %s, which isn't a service in the project", name)
+		}
+	}
+
+	return nil
+}
+
+// HttpProxyPorts - returns the fixed ports of the HTTP proxies of services, keyed by the service's name in the local cloud
+func (p *Project) HttpProxyPorts() map[string]int {
+	ports := map[string]int{}
+
+	for _, svc := range p.services {
+		if port := p.LocalConfig.Services[svc.Name].HttpPort; port != 0 {
+			ports[svc.GetFilePath()] = port
+		}
+	}
+
+	return ports
+}
+
+// addService - starts the nitric server of a service, on its fixed port from the local configuration if it has one,
+// otherwise on the preferred port when it's free
+func (p *Project) addService(localCloud *cloud.LocalCloud, svc Service, preferredPort int) (int, error) {
+	if port := p.LocalConfig.Services[svc.Name].Port; port != 0 {
+		return localCloud.AddServiceOnPort(svc.GetFilePath(), port)
+	}
+
+	return localCloud.AddServiceWithPort(svc.GetFilePath(), preferredPort)
+}
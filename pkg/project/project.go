@@ -25,12 +25,17 @@ import (
 	"log"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/joho/godotenv"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"golang.org/x/sync/errgroup"
@@ -39,10 +44,16 @@ import (
 	goruntime "runtime"
 
 	"github.com/nitrictech/cli/pkg/cloud"
+	"github.com/nitrictech/cli/pkg/cloud/dependencies"
+	"github.com/nitrictech/cli/pkg/cloud/gateway"
 	"github.com/nitrictech/cli/pkg/collector"
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/preferences"
 	"github.com/nitrictech/cli/pkg/preview"
 	"github.com/nitrictech/cli/pkg/project/localconfig"
 	"github.com/nitrictech/cli/pkg/project/runtime"
+	"github.com/nitrictech/cli/pkg/webhooks"
 	"github.com/nitrictech/nitric/core/pkg/logger"
 	apispb "github.com/nitrictech/nitric/core/pkg/proto/apis/v1"
 	httppb "github.com/nitrictech/nitric/core/pkg/proto/http/v1"
@@ -58,27 +69,255 @@ import (
 )
 
 type Project struct {
-	Name        string
-	Directory   string
-	Preview     []preview.Feature
-	LocalConfig localconfig.LocalConfiguration
+	Name          string
+	Directory     string
+	Preview       []preview.Feature
+	Flags         map[string]string
+	Apis          map[string]ApiConfiguration
+	Digest        DigestConfiguration
+	Lock          LockConfiguration
+	Notifications NotificationConfiguration
+	Dashboard     DashboardConfiguration
+	Build         BuildConfiguration
+	Email         *EmailConfiguration
+	Dependencies  map[string]dependencies.Dependency
+	Seeds         []SeedConfiguration
+	Transformers  []SpecTransformerConfiguration
+	LocalConfig   localconfig.LocalConfiguration
 
 	services []Service
+	lockFile *LockFile
+}
+
+// ApiRateLimits - returns the rate limits configured for the project's APIs, keyed by API name
+func (p *Project) ApiRateLimits() map[string]gateway.RateLimit {
+	rateLimits := map[string]gateway.RateLimit{}
+
+	for apiName, api := range p.Apis {
+		if api.RateLimit == nil {
+			continue
+		}
+
+		rateLimits[apiName] = gateway.RateLimit{
+			RequestsPerSecond: api.RateLimit.RequestsPerSecond,
+			Burst:             api.RateLimit.Burst,
+		}
+	}
+
+	return rateLimits
+}
+
+// ApiMiddleware - returns the middleware configured for the project's APIs, keyed by API name
+func (p *Project) ApiMiddleware() map[string][]gateway.Middleware {
+	middleware := map[string][]gateway.Middleware{}
+
+	for apiName, api := range p.Apis {
+		for _, m := range api.Middleware {
+			gm := gateway.Middleware{
+				When:                  m.When,
+				SetRequestHeaders:     m.SetRequestHeaders,
+				RemoveRequestHeaders:  m.RemoveRequestHeaders,
+				SetResponseHeaders:    m.SetResponseHeaders,
+				RemoveResponseHeaders: m.RemoveResponseHeaders,
+			}
+
+			if m.RewritePath != nil {
+				gm.RewritePathFrom = m.RewritePath.From
+				gm.RewritePathTo = m.RewritePath.To
+			}
+
+			middleware[apiName] = append(middleware[apiName], gm)
+		}
+	}
+
+	return middleware
+}
+
+// ApiWebhooks - returns the webhook routes configured for the project's APIs, keyed by API name.
+// Secrets are expanded using the env variables, falling back to the environment.
+func (p *Project) ApiWebhooks(env map[string]string) (map[string][]gateway.Webhook, error) {
+	hooks := map[string][]gateway.Webhook{}
+
+	for apiName, api := range p.Apis {
+		for _, w := range api.Webhooks {
+			scheme, err := webhooks.GetScheme(w.Scheme, w.Header)
+			if err != nil {
+				return nil, fmt.Errorf("invalid webhook %s for api %s: %w", w.Path, apiName, err)
+			}
+
+			secret := os.Expand(w.Secret, func(name string) string {
+				return lo.Ternary(env[name] != "", env[name], os.Getenv(name))
+			})
+
+			if secret == "" {
+				return nil, fmt.Errorf("webhook %s for api %s has no secret", w.Path, apiName)
+			}
+
+			hooks[apiName] = append(hooks[apiName], gateway.Webhook{
+				Path:     w.Path,
+				Scheme:   scheme,
+				Secret:   secret,
+				Simulate: w.Simulate,
+			})
+		}
+	}
+
+	return hooks, nil
+}
+
+// ApiRequestValidators - returns validators for the APIs with an OpenAPI document, keyed by API name
+func (p *Project) ApiRequestValidators(fs afero.Fs) (map[string]*gateway.RequestValidator, error) {
+	validators := map[string]*gateway.RequestValidator{}
+
+	for apiName, api := range p.Apis {
+		if api.OpenApi == "" {
+			continue
+		}
+
+		contents, err := afero.ReadFile(fs, filepath.Join(p.Directory, api.OpenApi))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the openapi document of api %s: %w", apiName, err)
+		}
+
+		doc, err := openapi3.NewLoader().LoadFromData(contents)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the openapi document of api %s: %w", apiName, err)
+		}
+
+		validators[apiName], err = gateway.NewRequestValidator(doc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid openapi document for api %s: %w", apiName, err)
+		}
+	}
+
+	return validators, nil
+}
+
+// ApisRequiringApiKey - returns the names of the project's APIs that require an API key
+func (p *Project) ApisRequiringApiKey() []string {
+	apiNames := []string{}
+
+	for apiName, api := range p.Apis {
+		if api.RequireApiKey {
+			apiNames = append(apiNames, apiName)
+		}
+	}
+
+	slices.Sort(apiNames)
+
+	return apiNames
 }
 
 func (p *Project) GetServices() []Service {
 	return p.services
 }
 
+// SelectServices - limits the services built and run locally to those matching one of the include patterns, or all services when there are none,
+// that don't match any of the exclude patterns. Patterns are globs matched against the service name or file path, e.g. services/api.ts or services/*
+func (p *Project) SelectServices(include []string, exclude []string) error {
+	if len(p.services) == 0 {
+		return nil
+	}
+
+	matches := func(pattern string, svc Service) (bool, error) {
+		for _, value := range []string{svc.Name, filepath.ToSlash(svc.GetFilePath())} {
+			matched, err := path.Match(pattern, value)
+			if err != nil {
+				return false, fmt.Errorf("invalid service pattern %s: %w", pattern, err)
+			}
+
+			if matched {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	anyMatch := func(patterns []string, svc Service) (bool, error) {
+		for _, pattern := range patterns {
+			matched, err := matches(pattern, svc)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+
+		return false, nil
+	}
+
+	for _, pattern := range append(slices.Clone(include), exclude...) {
+		found := false
+
+		for _, svc := range p.services {
+			matched, err := matches(pattern, svc)
+			if err != nil {
+				return err
+			}
+
+			found = found || matched
+		}
+
+		if !found {
+			return fmt.Errorf("no services match %s, services are matched by name or file path, e.g. %s", pattern, filepath.ToSlash(p.services[0].GetFilePath()))
+		}
+	}
+
+	selected := []Service{}
+
+	for _, svc := range p.services {
+		included := len(include) == 0
+
+		if !included {
+			matched, err := anyMatch(include, svc)
+			if err != nil {
+				return err
+			}
+
+			included = matched
+		}
+
+		excluded, err := anyMatch(exclude, svc)
+		if err != nil {
+			return err
+		}
+
+		if included && !excluded {
+			selected = append(selected, svc)
+		}
+	}
+
+	if len(selected) == 0 {
+		return fmt.Errorf("all services are excluded, nothing to run")
+	}
+
+	p.services = selected
+
+	return nil
+}
+
 // BuildServices - Builds all the services in the project
-func (p *Project) BuildServices(fs afero.Fs) (chan ServiceBuildUpdate, error) {
+func (p *Project) BuildServices(fs afero.Fs, opts ...BuildOption) (chan ServiceBuildUpdate, error) {
 	updatesChan := make(chan ServiceBuildUpdate)
 
 	if len(p.services) == 0 {
 		return nil, fmt.Errorf("no services found in project, nothing to build. This may indicate misconfigured `match` patterns in your nitric.yaml file")
 	}
 
-	maxConcurrentBuilds := make(chan struct{}, min(goruntime.NumCPU(), goruntime.GOMAXPROCS(0)))
+	concurrency := min(goruntime.NumCPU(), goruntime.GOMAXPROCS(0))
+
+	prefs, err := preferences.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	if prefs.Build.Concurrency > 0 {
+		concurrency = prefs.Build.Concurrency
+	}
+
+	maxConcurrentBuilds := make(chan struct{}, concurrency)
+
+	cache := loadBuildCache(fs)
+	labels := p.imageLabels()
 
 	waitGroup := sync.WaitGroup{}
 
@@ -92,20 +331,28 @@ func (p *Project) BuildServices(fs afero.Fs) (chan ServiceBuildUpdate, error) {
 			// this will block once the buffer is full
 			maxConcurrentBuilds <- struct{}{}
 
+			buildStart := time.Now()
+
 			// Start goroutine
-			if err := svc.BuildImage(fs, writer); err != nil {
+			progressOpt := withBuildProgress(func(progress docker.BuildProgress) {
+				updatesChan <- ServiceBuildUpdate{
+					ServiceName: svc.Name,
+					Message:     progress.Step,
+					Status:      ServiceBuildStatus_InProgress,
+					Progress:    &progress,
+				}
+			})
+
+			if err := svc.BuildImage(fs, writer, append([]BuildOption{progressOpt, WithBuildConfiguration(p.Build), withLockFile(p.lockFile), withBuildCache(cache), withImageLabels(labels)}, opts...)...); err != nil {
 				updatesChan <- ServiceBuildUpdate{
 					ServiceName: svc.Name,
 					Err:         err,
 					Message:     err.Error(),
 					Status:      ServiceBuildStatus_Error,
+					Elapsed:     time.Since(buildStart),
 				}
 			} else {
-				updatesChan <- ServiceBuildUpdate{
-					ServiceName: svc.Name,
-					Message:     "Build Complete",
-					Status:      ServiceBuildStatus_Complete,
-				}
+				updatesChan <- buildCompleteUpdate(svc.Name, time.Since(buildStart))
 			}
 
 			// release our lock
@@ -122,6 +369,10 @@ func (p *Project) BuildServices(fs afero.Fs) (chan ServiceBuildUpdate, error) {
 			maxConcurrentBuilds <- struct{}{}
 		}
 
+		if err := cache.toFile(fs); err != nil {
+			logger.Errorf("unable to write build cache %s: %s", GetBuildCacheFile(), err)
+		}
+
 		close(updatesChan)
 	}()
 
@@ -130,6 +381,7 @@ func (p *Project) BuildServices(fs afero.Fs) (chan ServiceBuildUpdate, error) {
 
 func (p *Project) collectServiceRequirements(service Service) (*collector.ServiceRequirements, error) {
 	serviceRequirements := collector.NewServiceRequirements(service.Name, service.GetFilePath(), service.Type)
+	serviceRequirements.SetImage(service.Image)
 
 	// start a grpc service with this registered
 	grpcServer := grpc.NewServer()
@@ -211,7 +463,7 @@ func (p *Project) collectServiceRequirements(service Service) (*collector.Servic
 	}
 
 	if serviceRequirements.HasDatabases() && !slices.Contains(p.Preview, preview.Feature_SqlDatabases) {
-		return nil, fmt.Errorf("service %s requires a database, but the project does not have the 'sql-databases' preview feature enabled. Please add sql-databases to the preview field of your nitric.yaml file to enable this feature", service.GetFilePath())
+		return nil, fmt.Errorf("service %s requires a database, but the project does not have the 'sql-databases' preview feature enabled. Run `nitric preview enable sql-databases` to enable this feature", service.GetFilePath())
 	}
 
 	return serviceRequirements, nil
@@ -269,9 +521,27 @@ func (p *Project) DefaultMigrationImage(fs afero.Fs) (string, bool) {
 
 // RunServicesWithCommand - Runs all the services locally using a startup command
 // use the stop channel to stop all running services
-func (p *Project) RunServicesWithCommand(localCloud *cloud.LocalCloud, stop <-chan bool, updates chan<- ServiceRunUpdate, env map[string]string) error {
+func (p *Project) RunServicesWithCommand(localCloud *cloud.LocalCloud, stop <-chan bool, updates chan<- ServiceRunUpdate, env map[string]string, opts ...RunCommandOption) error {
 	stopChannels := lo.FanOut[bool](len(p.services), 1, stop)
 
+	options := &runCommandOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var watcher *serviceWatcher
+
+	if options.hotReload {
+		var err error
+
+		watcher, err = newServiceWatcher(p.services, updates)
+		if err != nil {
+			return err
+		}
+
+		defer watcher.Close()
+	}
+
 	group, _ := errgroup.WithContext(context.TODO())
 
 	for i, service := range p.services {
@@ -280,7 +550,7 @@ func (p *Project) RunServicesWithCommand(localCloud *cloud.LocalCloud, stop <-ch
 
 		// start the service with the given file reference from its projects CWD
 		group.Go(func() error {
-			port, err := localCloud.AddService(svc.GetFilePath())
+			port, err := p.addService(localCloud, svc, 0)
 			if err != nil {
 				return err
 			}
@@ -291,10 +561,36 @@ func (p *Project) RunServicesWithCommand(localCloud *cloud.LocalCloud, stop <-ch
 				"SERVICE_ADDRESS":    "localhost:" + strconv.Itoa(port),
 			}
 
+			if localCloud.Email != nil {
+				envVariables = lo.Assign(envVariables, emailEnv("localhost", localCloud.Email.Port(), lo.FromPtr(p.Email).From))
+			}
+
+			if localCloud.Dependencies != nil {
+				dependencyEnv, err := localCloud.Dependencies.Env("localhost")
+				if err != nil {
+					return err
+				}
+
+				envVariables = lo.Assign(envVariables, dependencyEnv)
+			}
+
+			for key, value := range FlagsToEnv(p.Flags) {
+				envVariables[key] = value
+			}
+
 			for key, value := range env {
 				envVariables[key] = value
 			}
 
+			// the service's own env takes precedence over the project's
+			for key, value := range svc.environment() {
+				envVariables[key] = value
+			}
+
+			if watcher != nil {
+				return svc.runWithReload(stopChannels[idx], watcher.restarts(svc.Name), updates, envVariables)
+			}
+
 			return svc.Run(stopChannels[idx], updates, envVariables)
 		})
 	}
@@ -304,9 +600,37 @@ func (p *Project) RunServicesWithCommand(localCloud *cloud.LocalCloud, stop <-ch
 
 // RunServices - Runs all the services as containers
 // use the stop channel to stop all running services
-func (p *Project) RunServices(localCloud *cloud.LocalCloud, stop <-chan bool, updates chan<- ServiceRunUpdate, env map[string]string) error {
+func (p *Project) RunServices(localCloud *cloud.LocalCloud, stop <-chan bool, updates chan<- ServiceRunUpdate, env map[string]string, opts ...RunContainerOption) error {
 	stopChannels := lo.FanOut[bool](len(p.services), 1, stop)
 
+	// explicitly provided env variables take precedence over feature flags
+	env = lo.Assign(FlagsToEnv(p.Flags), env)
+
+	if localCloud.Email != nil {
+		opts = append(opts, withEmail(localCloud.Email.Port(), lo.FromPtr(p.Email).From))
+	}
+
+	if localCloud.Dependencies != nil {
+		opts = append(opts, withDependencies(localCloud.Dependencies))
+	}
+
+	runtimeOptions := lo.ToPtr(defaultRunContainerOptions)
+
+	for _, opt := range opts {
+		opt(runtimeOptions)
+	}
+
+	var dockerClient *docker.Docker
+
+	if runtimeOptions.warmPool {
+		var err error
+
+		dockerClient, err = docker.New()
+		if err != nil {
+			return err
+		}
+	}
+
 	group, _ := errgroup.WithContext(context.TODO())
 
 	for i, service := range p.services {
@@ -314,19 +638,72 @@ func (p *Project) RunServices(localCloud *cloud.LocalCloud, stop <-chan bool, up
 		svc := service
 
 		group.Go(func() error {
-			port, err := localCloud.AddService(svc.GetFilePath())
+			// the service's own env takes precedence over the project's
+			runOpts := append([]RunContainerOption{WithEnvVars(lo.Assign(env, svc.environment()))}, opts...)
+
+			// reusing the ports of the service's pooled container keeps its configuration unchanged, so it can be restarted
+			preferredPort := 0
+
+			if runtimeOptions.warmPool {
+				if nitricPort, proxyPort, ok := warmContainerPorts(dockerClient, svc.containerName(runtimeOptions.environment)); ok {
+					preferredPort = nitricPort
+
+					runOpts = append(runOpts, withProxyPort(proxyPort))
+				}
+			}
+
+			port, err := p.addService(localCloud, svc, preferredPort)
 			if err != nil {
 				return err
 			}
 
-			return svc.RunContainer(stopChannels[idx], updates, WithNitricPort(strconv.Itoa(port)), WithEnvVars(env))
+			return svc.RunContainer(stopChannels[idx], updates, append(runOpts, WithNitricPort(strconv.Itoa(port)))...)
 		})
 	}
 
 	return group.Wait()
 }
 
+// emailEnv - returns the environment variables services use to send email through the local SMTP server,
+// providers set the same variables for the SMTP endpoint of SES or SendGrid when deployed
+func emailEnv(host string, port int, from string) map[string]string {
+	env := map[string]string{
+		"NITRIC_SMTP_HOST": host,
+		"NITRIC_SMTP_PORT": strconv.Itoa(port),
+	}
+
+	if from != "" {
+		env["NITRIC_EMAIL_FROM"] = from
+	}
+
+	return env
+}
+
+var validServiceName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// platforms are in the form os/arch[/variant], e.g. linux/arm64/v8
+var validPlatform = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+(/[a-z0-9]+)?$`)
+
+// ValidatePlatform - checks a platform to build images for is in the form os/arch[/variant]
+func ValidatePlatform(platform string) error {
+	if !validPlatform.MatchString(platform) {
+		return fmt.Errorf("invalid build platform %s, platforms must be in the form os/arch, e.g. linux/arm64", platform)
+	}
+
+	return nil
+}
+
 func (pc *ProjectConfiguration) pathToNormalizedServiceName(servicePath string) string {
+	// java services are named after the module containing their build file
+	if runtime.IsJavaBuildFile(servicePath) {
+		servicePath = filepath.Dir(servicePath)
+	}
+
+	// rust services are named after their package, e.g. services/hello/src/main.rs -> services/hello
+	if runtime.IsRustMainFile(servicePath) {
+		servicePath = filepath.Dir(filepath.Dir(servicePath))
+	}
+
 	// Add the project name as a prefix to group service images
 	servicePath = fmt.Sprintf("%s_%s", pc.Name, servicePath)
 	// replace path separators with dashes
@@ -346,6 +723,23 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 	services := []Service{}
 
 	matches := map[string]string{}
+	// the file each service name was generated from, names must be unique as they name the service's image, container and cloud resources
+	serviceFiles := map[string]string{}
+
+	images, err := newImageNamer(projectConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, platform := range projectConfig.Build.Platforms {
+		if err := ValidatePlatform(platform); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := docker.SetEngineOrder(projectConfig.ContainerEngines); err != nil {
+		return nil, fmt.Errorf("invalid container-engines in nitric.yaml: %w", err)
+	}
 
 	for _, serviceSpec := range projectConfig.Services {
 		serviceMatch := filepath.Join(serviceSpec.Basedir, serviceSpec.Match)
@@ -355,11 +749,44 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 			return nil, fmt.Errorf("unable to match service files for pattern %s: %w", serviceMatch, err)
 		}
 
+		if serviceSpec.Name != "" {
+			if !validServiceName.MatchString(serviceSpec.Name) {
+				return nil, fmt.Errorf("invalid service name %s for pattern %s, names must contain only lowercase letters, numbers and dashes", serviceSpec.Name, serviceSpec.Match)
+			}
+
+			if len(files) > 1 {
+				return nil, fmt.Errorf("service name %s can only be set on a pattern matching a single file, %s matches %d files", serviceSpec.Name, serviceSpec.Match, len(files))
+			}
+		}
+
+		envFileVars, err := serviceSpec.envFileVariables(fs, projectConfig.Directory)
+		if err != nil {
+			return nil, err
+		}
+
+		var logLevel LogLevel
+
+		if serviceSpec.LogLevel != "" {
+			logLevel, err = ParseLogLevel(serviceSpec.LogLevel)
+			if err != nil {
+				return nil, fmt.Errorf("services matching %s: %w", serviceSpec.Match, err)
+			}
+		}
+
 		for _, f := range files {
 			relativeServiceEntrypointPath, _ := filepath.Rel(filepath.Join(projectConfig.Directory, serviceSpec.Basedir), f)
 			projectRelativeServiceFile := filepath.Join(projectConfig.Directory, f)
 
 			serviceName := projectConfig.pathToNormalizedServiceName(projectRelativeServiceFile)
+			if serviceSpec.Name != "" {
+				serviceName = fmt.Sprintf("%s_%s", strings.ToLower(projectConfig.Name), serviceSpec.Name)
+			}
+
+			if existing, ok := serviceFiles[serviceName]; ok && existing != f {
+				return nil, fmt.Errorf("service files %s and %s both resolve to the service name %s, rename one of the files or set a name for it in nitric.yaml", existing, f, serviceName)
+			}
+
+			serviceFiles[serviceName] = f
 
 			var buildContext *runtime.RuntimeBuildContext
 
@@ -374,6 +801,10 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 					return nil, fmt.Errorf("unable to find runtime %s", serviceSpec.Runtime)
 				}
 
+				if serviceSpec.Engine != "" {
+					return nil, fmt.Errorf("services matching %s set both a runtime and an engine, the engine of a custom runtime is set by its dockerfile", serviceSpec.Match)
+				}
+
 				buildContext, err = runtime.NewBuildContext(
 					relativeServiceEntrypointPath,
 					customRuntime.Dockerfile,
@@ -382,6 +813,7 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 					customRuntime.Args,
 					otherEntryPointFiles,
 					fs,
+					runtime.WithDebugger(customRuntime.Debugger, customRuntime.SourceRoot),
 				)
 				if err != nil {
 					return nil, fmt.Errorf("unable to create build context for custom service file %s: %w", f, err)
@@ -394,6 +826,7 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 					map[string]string{},
 					otherEntryPointFiles,
 					fs,
+					runtime.WithEngine(serviceSpec.Engine),
 				)
 				if err != nil {
 					return nil, fmt.Errorf("unable to create build context for service file %s: %w", f, err)
@@ -412,6 +845,22 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 			}
 
 			newService := NewService(serviceName, serviceSpec.Type, relativeFilePath, *buildContext, serviceSpec.Start)
+			newService.env = serviceSpec.Env
+			newService.envFileVars = envFileVars
+			newService.logLevel = logLevel
+
+			if serviceSpec.EnvFile != "" {
+				newService.envFile = filepath.Join(projectConfig.Directory, serviceSpec.EnvFile)
+			}
+
+			// env set in nitric.yaml is available to dockerfiles declaring it with ARG, the runtime's args take precedence
+			// env files often hold secrets, so they're only read when services run
+			newService.buildContext.BuildArguments = lo.Assign(serviceSpec.Env, buildContext.BuildArguments)
+
+			newService.Image, err = images.imageName(projectConfig.Name, serviceName)
+			if err != nil {
+				return nil, err
+			}
 
 			if serviceSpec.Type == "" {
 				serviceSpec.Type = "default"
@@ -421,33 +870,94 @@ func fromProjectConfiguration(projectConfig *ProjectConfiguration, localConfig *
 		}
 	}
 
+	lockFile, err := LockFileFromFile(fs, projectConfig.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := projectConfig.dependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	seeds, err := projectConfig.seeds()
+	if err != nil {
+		return nil, err
+	}
+
+	transformers, err := projectConfig.transformers()
+	if err != nil {
+		return nil, err
+	}
+
 	// create an empty local configuration if none is provided
 	if localConfig == nil {
 		localConfig = &localconfig.LocalConfiguration{}
 	}
 
+	if err := validateServicePorts(localConfig, services); err != nil {
+		return nil, err
+	}
+
 	return &Project{
-		Name:        projectConfig.Name,
-		Directory:   projectConfig.Directory,
-		Preview:     projectConfig.Preview,
-		LocalConfig: *localConfig,
-		services:    services,
+		Name:          projectConfig.Name,
+		Directory:     projectConfig.Directory,
+		Preview:       projectConfig.Preview,
+		Flags:         projectConfig.Flags,
+		Apis:          projectConfig.Apis,
+		Digest:        projectConfig.Digest,
+		Lock:          projectConfig.Lock,
+		Notifications: projectConfig.Notifications,
+		Dashboard:     projectConfig.Dashboard,
+		Build:         projectConfig.Build,
+		Email:         projectConfig.Email,
+		Dependencies:  deps,
+		Seeds:         seeds,
+		Transformers:  transformers,
+		LocalConfig:   *localConfig,
+		services:      services,
+		lockFile:      lockFile,
 	}, nil
 }
 
+// envFileVariables - returns the environment variables in the env file of the services matched by the spec
+func (s ServiceConfiguration) envFileVariables(fs afero.Fs, projectDir string) (map[string]string, error) {
+	if s.EnvFile == "" {
+		return map[string]string{}, nil
+	}
+
+	envFile, err := fs.Open(filepath.Join(projectDir, s.EnvFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read env file for services matching %s: %w", s.Match, err)
+	}
+	defer envFile.Close()
+
+	envFileVars, err := godotenv.Parse(envFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse env file %s: %w", s.EnvFile, err)
+	}
+
+	return envFileVars, nil
+}
+
 // FromFile - Loads a nitric project from a nitric.yaml file
 // If no filepath is provided, the default location './nitric.yaml' is used
 func FromFile(fs afero.Fs, filepath string) (*Project, error) {
 	projectConfig, err := ConfigurationFromFile(fs, filepath)
 	if err != nil {
-		return nil, fmt.Errorf("error loading nitric.yaml: %w", err)
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("error loading nitric.yaml: %w", err))
 	}
 
 	// load local configuration
 	localConfig, err := localconfig.LocalConfigurationFromFile(fs, "")
 	if err != nil {
-		return nil, fmt.Errorf("error loading local.nitric.yaml: %w", err)
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("error loading local.nitric.yaml: %w", err))
+	}
+
+	proj, err := fromProjectConfiguration(projectConfig, localConfig, fs)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
 	}
 
-	return fromProjectConfiguration(projectConfig, localConfig, fs)
+	return proj, nil
 }
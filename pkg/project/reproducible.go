@@ -0,0 +1,285 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+)
+
+// BuildAttestation - a record of the inputs and output of a reproducible service build,
+// builds with matching inputs are expected to produce matching image IDs
+type BuildAttestation struct {
+	Service         string            `json:"service"`
+	ImageId         string            `json:"imageId"`
+	SourceDateEpoch int64             `json:"sourceDateEpoch"`
+	DockerfileHash  string            `json:"dockerfileHash"`
+	BuildArgsHash   string            `json:"buildArgsHash"`
+	ContextHash     string            `json:"contextHash"`
+	BaseImages      map[string]string `json:"baseImages"`
+	// Base images that couldn't be pinned to a digest, e.g. images referenced by a build arg
+	UnpinnedImages []string `json:"unpinnedImages,omitempty"`
+}
+
+func GetAttestationsDir() string {
+	return filepath.Join(tempBuildDir, "attestations")
+}
+
+// ToFile - writes the attestation to the attestations directory, returning the written file path
+func (a BuildAttestation) ToFile(fs afero.Fs) (string, error) {
+	attestationsDir := GetAttestationsDir()
+
+	err := fs.MkdirAll(attestationsDir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("unable to create attestations directory %s: %w", attestationsDir, err)
+	}
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	attestationFile := filepath.Join(attestationsDir, fmt.Sprintf("%s.json", a.Service))
+
+	return attestationFile, afero.WriteFile(fs, attestationFile, data, os.ModePerm)
+}
+
+// sourceDateEpoch - the timestamp used for reproducible builds, SOURCE_DATE_EPOCH if set, otherwise zero
+func sourceDateEpoch() (int64, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid SOURCE_DATE_EPOCH %s: %w", epoch, err)
+	}
+
+	return value, nil
+}
+
+// pinBaseImages - rewrites the FROM instructions in a dockerfile to reference base images by digest,
+// returning the updated dockerfile, the pinned images and any images that could not be pinned
+func pinBaseImages(dockerfileContents string, resolveDigest func(image string) (string, error)) (string, map[string]string, []string, error) {
+	pinned := map[string]string{}
+	unpinned := []string{}
+	stages := []string{}
+
+	lines := strings.Split(dockerfileContents, "\n")
+
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+
+		// skip flags such as --platform
+		imageIdx := 1
+		for imageIdx < len(fields)-1 && strings.HasPrefix(fields[imageIdx], "--") {
+			imageIdx++
+		}
+
+		image := fields[imageIdx]
+		isStage := slices.Contains(stages, strings.ToLower(image))
+
+		if len(fields) > imageIdx+2 && strings.EqualFold(fields[imageIdx+1], "AS") {
+			stages = append(stages, strings.ToLower(fields[imageIdx+2]))
+		}
+
+		if isStage || image == "scratch" || strings.Contains(image, "@") {
+			continue
+		}
+
+		if strings.Contains(image, "$") {
+			unpinned = append(unpinned, image)
+			continue
+		}
+
+		digest, ok := pinned[image]
+		if !ok {
+			var err error
+
+			digest, err = resolveDigest(image)
+			if err != nil {
+				return "", nil, nil, err
+			}
+
+			pinned[image] = digest
+		}
+
+		fields[imageIdx] = fmt.Sprintf("%s@%s", image, digest)
+		lines[i] = strings.Join(fields, " ")
+	}
+
+	return strings.Join(lines, "\n"), pinned, unpinned, nil
+}
+
+func hashString(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashBuildArgs - returns a stable hash of the provided build arguments
+func hashBuildArgs(buildArgs map[string]string) string {
+	keys := lo.Keys(buildArgs)
+	slices.Sort(keys)
+
+	pairs := lo.Map(keys, func(key string, _ int) string {
+		return fmt.Sprintf("%s=%s", key, buildArgs[key])
+	})
+
+	return hashString(strings.Join(pairs, "\n"))
+}
+
+// matchesIgnorePattern - whether a dockerignore pattern matches the path or any of its parent directories
+func matchesIgnorePattern(relPath string, pattern string) bool {
+	for p := relPath; p != "." && p != "/"; p = filepath.Dir(p) {
+		if matched, _ := filepath.Match(pattern, filepath.ToSlash(p)); matched {
+			return true
+		}
+
+		if matched, _ := filepath.Match(pattern, filepath.Base(p)); matched && !strings.Contains(pattern, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIgnored - approximates dockerignore matching, the last pattern matching the path decides whether it's ignored,
+// so ! patterns include paths excluded by earlier patterns
+func isIgnored(relPath string, ignores []string) bool {
+	ignored := false
+
+	for _, pattern := range ignores {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		negated := strings.HasPrefix(pattern, "!")
+
+		pattern = strings.Trim(strings.TrimPrefix(pattern, "!"), "/")
+		if pattern == "" {
+			continue
+		}
+
+		if matchesIgnorePattern(relPath, pattern) {
+			ignored = !negated
+		}
+	}
+
+	return ignored
+}
+
+// includesWithin - whether a ! pattern could include files within an ignored directory, patterns without a / can match in any directory
+func includesWithin(relDir string, ignores []string) bool {
+	dirSegments := strings.Split(filepath.ToSlash(relDir), "/")
+
+	return lo.ContainsBy(ignores, func(pattern string) bool {
+		pattern, negated := strings.CutPrefix(strings.TrimSpace(pattern), "!")
+
+		pattern = strings.Trim(pattern, "/")
+		if !negated || pattern == "" {
+			return false
+		}
+
+		patternSegments := strings.Split(pattern, "/")
+		if len(patternSegments) == 1 {
+			return true
+		}
+
+		for i, dirSegment := range dirSegments {
+			if patternSegments[i] == "**" {
+				return true
+			}
+
+			// the last segment of the pattern matches the included files, so the directory must match the segments above it
+			if i == len(patternSegments)-1 {
+				return false
+			}
+
+			if matched, _ := filepath.Match(patternSegments[i], dirSegment); !matched {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// hashBuildContext - returns a hash of the paths and contents of all files in the build context that aren't ignored
+func hashBuildContext(fs afero.Fs, baseDir string, ignores []string) (string, error) {
+	hash := sha256.New()
+
+	err := afero.Walk(fs, baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." {
+			return nil
+		}
+
+		if isIgnored(relPath, ignores) {
+			// files in ignored directories can still be included by ! patterns
+			if info.IsDir() && !includesWithin(relPath, ignores) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		fmt.Fprintf(hash, "%s\n", filepath.ToSlash(relPath))
+
+		_, err = io.Copy(hash, file)
+
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to hash build context %s: %w", baseDir, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,72 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"testing"
+)
+
+func TestIsIgnored(t *testing.T) {
+	ignores := []string{"node_modules/", "*.log", ".nitric/", "!.nitric/*.yaml", ".nitric/secrets.enc.yaml", "!important.log", "# a comment", ""}
+
+	for _, tt := range []struct {
+		path     string
+		expected bool
+	}{
+		{path: "index.ts", expected: false},
+		{path: "node_modules", expected: true},
+		{path: "node_modules/lodash/index.js", expected: true},
+		{path: "debug.log", expected: true},
+		{path: "logs/debug.log", expected: true},
+		{path: "important.log", expected: false},
+		{path: "logs/important.log", expected: false},
+		{path: ".nitric", expected: true},
+		{path: ".nitric/run/state.json", expected: true},
+		{path: ".nitric/stack.yaml", expected: false},
+		{path: ".nitric/secrets.enc.yaml", expected: true},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			if ignored := isIgnored(tt.path, ignores); ignored != tt.expected {
+				t.Errorf("expected ignored to be %t", tt.expected)
+			}
+		})
+	}
+}
+
+func TestIncludesWithin(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		dir      string
+		ignores  []string
+		expected bool
+	}{
+		{name: "no negations", dir: "node_modules", ignores: []string{"node_modules/"}, expected: false},
+		{name: "negation in directory", dir: ".nitric", ignores: []string{".nitric/", "!.nitric/*.yaml"}, expected: true},
+		{name: "negation in other directory", dir: "node_modules", ignores: []string{"node_modules/", "!.nitric/*.yaml"}, expected: false},
+		{name: "negation in parent directory", dir: ".nitric/run", ignores: []string{".nitric/", "!.nitric/*.yaml"}, expected: false},
+		{name: "negation in subdirectory", dir: "dist", ignores: []string{"dist/", "!dist/public/index.html"}, expected: true},
+		{name: "negation with wildcard directory", dir: "packages/api", ignores: []string{"packages/", "!packages/*/package.json"}, expected: true},
+		{name: "negation with double star", dir: "vendor/a/b", ignores: []string{"vendor/", "!vendor/**/LICENSE"}, expected: true},
+		{name: "negated file name", dir: "logs", ignores: []string{"logs/", "!important.log"}, expected: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if includes := includesWithin(tt.dir, tt.ignores); includes != tt.expected {
+				t.Errorf("expected includes within %s to be %t", tt.dir, tt.expected)
+			}
+		})
+	}
+}
@@ -29,12 +29,18 @@ func TestGenerate(t *testing.T) {
 	pythonFile, _ := os.ReadFile("python.dockerfile")
 	jsFile, _ := os.ReadFile("javascript.dockerfile")
 	jvmFile, _ := os.ReadFile("jvm.dockerfile")
+	mavenFile, _ := os.ReadFile("maven.dockerfile")
+	gradleFile, _ := os.ReadFile("gradle.dockerfile")
+	dartFile, _ := os.ReadFile("dart.dockerfile")
+	denoFile, _ := os.ReadFile("deno.dockerfile")
+	bunFile, _ := os.ReadFile("bun.dockerfile")
 
 	fs := afero.NewOsFs()
 
 	tests := []struct {
 		name        string
 		handler     string
+		opts        []BuildContextOption
 		wantFwriter string
 	}{
 		{
@@ -57,10 +63,43 @@ func TestGenerate(t *testing.T) {
 			handler:     "outout/fat.jar",
 			wantFwriter: string(jvmFile),
 		},
+		{
+			name:        "dart",
+			handler:     "services/api.dart",
+			wantFwriter: string(dartFile),
+		},
+		{
+			name:        "ts deno",
+			handler:     "functions/list.ts",
+			opts:        []BuildContextOption{WithEngine(Engine_Deno)},
+			wantFwriter: string(denoFile),
+		},
+		{
+			name:        "js bun",
+			handler:     "functions/list.js",
+			opts:        []BuildContextOption{WithEngine(Engine_Bun)},
+			wantFwriter: string(bunFile),
+		},
+		{
+			name:        "ts node",
+			handler:     "functions/list.ts",
+			opts:        []BuildContextOption{WithEngine(Engine_Node)},
+			wantFwriter: string(tsFile),
+		},
+		{
+			name:        "maven",
+			handler:     "services/hello/pom.xml",
+			wantFwriter: string(mavenFile),
+		},
+		{
+			name:        "gradle",
+			handler:     "services/hello/build.gradle.kts",
+			wantFwriter: string(gradleFile),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rt, err := NewBuildContext(tt.handler, "", ".", map[string]string{}, []string{}, fs)
+			rt, err := NewBuildContext(tt.handler, "", ".", map[string]string{}, []string{}, fs, tt.opts...)
 			if err != nil {
 				t.Error(err)
 			}
@@ -71,3 +110,51 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestRustWorkspace(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	_ = afero.WriteFile(fs, "Cargo.toml", []byte("[workspace]\nmembers = [\"services/*\", \"common\"]\n"), 0o644)
+	_ = afero.WriteFile(fs, "services/foo/Cargo.toml", []byte("[package]\nname = \"foo-service\"\n\n[dependencies]\ncommon = { path = \"../../common\" }\n"), 0o644)
+	_ = afero.WriteFile(fs, "services/foo/src/main.rs", []byte("fn main() {}\n"), 0o644)
+	_ = afero.WriteFile(fs, "services/foo/src/bin/worker.rs", []byte("fn main() {}\n"), 0o644)
+
+	tests := []struct {
+		name       string
+		handler    string
+		wantBinary string
+	}{
+		{
+			name:       "main",
+			handler:    "services/foo/src/main.rs",
+			wantBinary: "foo-service",
+		},
+		{
+			name:       "bin",
+			handler:    "services/foo/src/bin/worker.rs",
+			wantBinary: "worker",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := NewBuildContext(tt.handler, "", ".", map[string]string{}, []string{}, fs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rt.BaseDirectory != "." {
+				t.Errorf("expected the workspace root as the build context, got %s", rt.BaseDirectory)
+			}
+
+			wantArgs := map[string]string{
+				"HANDLER":  tt.handler,
+				"MANIFEST": "services/foo/Cargo.toml",
+				"BINARY":   tt.wantBinary,
+			}
+
+			if !cmp.Equal(rt.BuildArguments, wantArgs) {
+				t.Error(cmp.Diff(wantArgs, rt.BuildArguments))
+			}
+		})
+	}
+}
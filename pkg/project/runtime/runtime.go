@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/pelletier/go-toml/v2"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 )
@@ -31,6 +32,10 @@ type RuntimeBuildContext struct {
 	BaseDirectory      string
 	BuildArguments     map[string]string
 	IgnoreFileContents string
+	// Debugger nitric run --debug attaches to the service, empty when the runtime doesn't support debugging
+	Debugger Debugger
+	// Directory the service's source files are copied to in the image, used to map breakpoints to local files
+	SourceRoot string
 }
 
 type RuntimeExt = string
@@ -41,11 +46,61 @@ const (
 	RuntimePython     RuntimeExt = "py"
 	RuntimeCsharp     RuntimeExt = "cs"
 	RuntimeJvm        RuntimeExt = "jar"
+	RuntimeJava       RuntimeExt = "java"
+	RuntimeDart       RuntimeExt = "dart"
+	RuntimeRust       RuntimeExt = "rs"
 
 	RuntimeUnknown RuntimeExt = ""
 )
 
-var commonIgnore = []string{".nitric/", "!.nitric/*.yaml", ".git/", ".idea/", ".vscode/", ".github/", "*.dockerfile", "*.dockerignore"}
+// Engine - the runtime used to run javascript and typescript services
+type Engine = string
+
+const (
+	Engine_Node Engine = "node"
+	Engine_Deno Engine = "deno"
+	Engine_Bun  Engine = "bun"
+)
+
+var Engines = []Engine{Engine_Node, Engine_Deno, Engine_Bun}
+
+// Debugger - the debugger injected into a service's container by nitric run --debug
+type Debugger = string
+
+const (
+	Debugger_Node    Debugger = "node"
+	Debugger_Debugpy Debugger = "debugpy"
+	Debugger_Dlv     Debugger = "dlv"
+	Debugger_Vsdbg   Debugger = "vsdbg"
+)
+
+var Debuggers = []Debugger{Debugger_Node, Debugger_Debugpy, Debugger_Dlv, Debugger_Vsdbg}
+
+type buildContextOptions struct {
+	engine     Engine
+	debugger   Debugger
+	sourceRoot string
+}
+
+type BuildContextOption func(*buildContextOptions)
+
+// WithEngine - runs javascript and typescript services with the engine rather than node
+func WithEngine(engine Engine) BuildContextOption {
+	return func(o *buildContextOptions) {
+		o.engine = engine
+	}
+}
+
+// WithDebugger - sets the debugger of a custom runtime and the directory its image copies the service's source files to,
+// e.g. dlv for go binaries built with -gcflags="all=-N -l"
+func WithDebugger(debugger Debugger, sourceRoot string) BuildContextOption {
+	return func(o *buildContextOptions) {
+		o.debugger = debugger
+		o.sourceRoot = sourceRoot
+	}
+}
+
+var commonIgnore = []string{".nitric/", "!.nitric/*.yaml", ".nitric/secrets.enc.yaml", ".git/", ".idea/", ".vscode/", ".github/", "*.dockerfile", "*.dockerignore"}
 
 func getDockerIgnores(dockerIgnorePath string, fs afero.Fs) ([]string, error) {
 	// Check if the file exists
@@ -119,6 +174,8 @@ func csharpBuildContext(entrypointFilePath string, baseDir string, additionalIgn
 			"HANDLER": handler,
 		},
 		IgnoreFileContents: strings.Join(append(additionalIgnores, csharpIgnores...), "\n"),
+		Debugger:           Debugger_Vsdbg,
+		SourceRoot:         "/app",
 	}, nil
 }
 
@@ -137,6 +194,31 @@ func jvmBuildContext(entrypointFilePath string, baseDir string, additionalIgnore
 	}, nil
 }
 
+//go:embed maven.dockerfile
+var mavenDockerfile string
+
+//go:embed gradle.dockerfile
+var gradleDockerfile string
+var javaIgnores = append([]string{"target/", "build/", ".gradle/", "bin/"}, commonIgnore...)
+
+// IsJavaBuildFile - returns true if the entrypoint is a maven or gradle build file, java services are matched by their build file
+// as there's no single entrypoint source file
+func IsJavaBuildFile(entrypointFilePath string) bool {
+	return lo.Contains([]string{"pom.xml", "build.gradle", "build.gradle.kts"}, filepath.Base(entrypointFilePath))
+}
+
+// javaBuildContext - builds a service from its maven or gradle build file, e.g. services/hello/pom.xml
+func javaBuildContext(entrypointFilePath string, baseDir string, additionalIgnores []string) (*RuntimeBuildContext, error) {
+	return &RuntimeBuildContext{
+		DockerfileContents: lo.Ternary(filepath.Base(entrypointFilePath) == "pom.xml", mavenDockerfile, gradleDockerfile),
+		BaseDirectory:      baseDir, // use the nitric project directory
+		BuildArguments: map[string]string{
+			"HANDLER": filepath.ToSlash(entrypointFilePath),
+		},
+		IgnoreFileContents: strings.Join(append(additionalIgnores, javaIgnores...), "\n"),
+	}, nil
+}
+
 //go:embed python.dockerfile
 var pythonDockerfile string
 var pythonIgnores = append([]string{"__pycache__/", "*.py[cod]", "*$py.class"}, commonIgnore...)
@@ -149,6 +231,8 @@ func pythonBuildContext(entrypointFilePath string, baseDir string, additionalIgn
 			"HANDLER": filepath.ToSlash(entrypointFilePath),
 		},
 		IgnoreFileContents: strings.Join(append(additionalIgnores, pythonIgnores...), "\n"),
+		Debugger:           Debugger_Debugpy,
+		SourceRoot:         "/",
 	}, nil
 }
 
@@ -164,6 +248,8 @@ func javascriptBuildContext(entrypointFilePath string, baseDir string, additiona
 			"HANDLER": filepath.ToSlash(entrypointFilePath),
 		},
 		IgnoreFileContents: strings.Join(append(additionalIgnores, javascriptIgnores...), "\n"),
+		Debugger:           Debugger_Node,
+		SourceRoot:         "/",
 	}, nil
 }
 
@@ -178,12 +264,34 @@ func typescriptBuildContext(entrypointFilePath string, baseDir string, additiona
 			"HANDLER": filepath.ToSlash(entrypointFilePath),
 		},
 		IgnoreFileContents: strings.Join(append(additionalIgnores, javascriptIgnores...), "\n"),
+		Debugger:           Debugger_Node,
+		SourceRoot:         "/usr/app",
+	}, nil
+}
+
+//go:embed deno.dockerfile
+var denoDockerfile string
+
+//go:embed bun.dockerfile
+var bunDockerfile string
+
+// engineBuildContext - builds javascript and typescript services to run with deno or bun, both run typescript without a compile step
+func engineBuildContext(engine Engine, entrypointFilePath string, baseDir string, additionalIgnores []string) (*RuntimeBuildContext, error) {
+	return &RuntimeBuildContext{
+		DockerfileContents: lo.Ternary(engine == Engine_Deno, denoDockerfile, bunDockerfile),
+		BaseDirectory:      baseDir, // use the nitric project directory
+		BuildArguments: map[string]string{
+			"HANDLER": filepath.ToSlash(entrypointFilePath),
+		},
+		IgnoreFileContents: strings.Join(append(additionalIgnores, javascriptIgnores...), "\n"),
 	}, nil
 }
 
 //go:embed dart.dockerfile
 var dartDockerfile string
-var dartIgnores = append([]string{}, commonIgnore...)
+
+// the local package config references the host's pub cache, dependencies are resolved again in the container
+var dartIgnores = append([]string{".dart_tool/", "build/", ".packages"}, commonIgnore...)
 
 func dartBuildContext(entrypointFilePath string, baseDir string, additionalIgnores []string) (*RuntimeBuildContext, error) {
 	return &RuntimeBuildContext{
@@ -196,15 +304,130 @@ func dartBuildContext(entrypointFilePath string, baseDir string, additionalIgnor
 	}, nil
 }
 
+//go:embed rust.dockerfile
+var rustDockerfile string
+var rustIgnores = append([]string{"target/"}, commonIgnore...)
+
+type cargoManifest struct {
+	Package *struct {
+		Name string `toml:"name"`
+	} `toml:"package"`
+	Workspace *struct{} `toml:"workspace"`
+}
+
+// IsRustMainFile - returns true if the entrypoint is the main file of a rust package, e.g. services/hello/src/main.rs
+func IsRustMainFile(entrypointFilePath string) bool {
+	return filepath.Base(entrypointFilePath) == "main.rs" && filepath.Base(filepath.Dir(entrypointFilePath)) == "src"
+}
+
+func readCargoManifest(fs afero.Fs, manifestPath string) (*cargoManifest, error) {
+	contents, err := afero.ReadFile(fs, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &cargoManifest{}
+	if err := toml.Unmarshal(contents, manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", manifestPath, err)
+	}
+
+	return manifest, nil
+}
+
+// rustBuildContext - builds the binary of the package containing the entrypoint, e.g. services/hello/src/main.rs or services/hello/src/bin/api.rs.
+// packages in a cargo workspace are built from the workspace root, so path dependencies on other members and the shared Cargo.lock are available
+func rustBuildContext(entrypointFilePath string, baseDir string, additionalIgnores []string, fs afero.Fs) (*RuntimeBuildContext, error) {
+	entrypoint := filepath.Join(baseDir, entrypointFilePath)
+
+	packageDir := ""
+	workspaceDir := ""
+
+	// search the entrypoint's parent directories for its package and workspace manifests, stopping at the project directory
+	for dir := filepath.Dir(entrypoint); ; dir = filepath.Dir(dir) {
+		manifestPath := filepath.Join(dir, "Cargo.toml")
+
+		if exists, _ := afero.Exists(fs, manifestPath); exists {
+			manifest, err := readCargoManifest(fs, manifestPath)
+			if err != nil {
+				return nil, err
+			}
+
+			if packageDir == "" && manifest.Package != nil {
+				packageDir = dir
+			}
+
+			if manifest.Workspace != nil {
+				workspaceDir = dir
+				break
+			}
+		}
+
+		if dir == "." || dir == string(filepath.Separator) || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	if packageDir == "" {
+		return nil, fmt.Errorf("unable to find the Cargo.toml of the package containing %s", entrypoint)
+	}
+
+	manifest, err := readCargoManifest(fs, filepath.Join(packageDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	// the main file is built as the binary named after the package, other files in src/bin are built as binaries named after the file
+	binary := manifest.Package.Name
+	if !IsRustMainFile(entrypoint) {
+		binary = strings.TrimSuffix(filepath.Base(entrypoint), ".rs")
+	}
+
+	contextDir := lo.Ternary(workspaceDir != "", workspaceDir, packageDir)
+
+	handler, err := filepath.Rel(contextDir, entrypoint)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath, err := filepath.Rel(contextDir, filepath.Join(packageDir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RuntimeBuildContext{
+		DockerfileContents: rustDockerfile,
+		BaseDirectory:      contextDir, // use the workspace root, or the package directory outside of a workspace
+		BuildArguments: map[string]string{
+			"HANDLER":  filepath.ToSlash(handler),
+			"MANIFEST": filepath.ToSlash(manifestPath),
+			"BINARY":   binary,
+		},
+		IgnoreFileContents: strings.Join(append(additionalIgnores, rustIgnores...), "\n"),
+	}, nil
+}
+
 const customDockerfileDocLink = "https://nitric.io/docs/reference/custom-containers#create-a-dockerfile-template"
 
 // NewBuildContext - Creates a new runtime build context.
 // if a dockerfile path is provided a custom runtime is assumed, otherwise the entrypoint file is used for automatic detection of language runtime.
-func NewBuildContext(entrypointFilePath string, dockerfilePath string, baseDirectory string, buildArgs map[string]string, additionalIgnores []string, fs afero.Fs) (*RuntimeBuildContext, error) {
+func NewBuildContext(entrypointFilePath string, dockerfilePath string, baseDirectory string, buildArgs map[string]string, additionalIgnores []string, fs afero.Fs, opts ...BuildContextOption) (*RuntimeBuildContext, error) {
+	options := &buildContextOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	if baseDirectory == "" {
 		baseDirectory = "."
 	}
 
+	if options.engine != "" && !lo.Contains(Engines, options.engine) {
+		return nil, fmt.Errorf("unsupported engine %s, supported engines are %s", options.engine, strings.Join(Engines, ", "))
+	}
+
+	if options.debugger != "" && !lo.Contains(Debuggers, options.debugger) {
+		return nil, fmt.Errorf("unsupported debugger %s, supported debuggers are %s", options.debugger, strings.Join(Debuggers, ", "))
+	}
+
 	if dockerfilePath != "" {
 		dockerIgnorePath := fmt.Sprintf("%s.dockerignore", dockerfilePath)
 
@@ -215,7 +438,15 @@ func NewBuildContext(entrypointFilePath string, dockerfilePath string, baseDirec
 
 		additionalIgnores = append(additionalIgnores, dockerIgnores...)
 
-		return customBuildContext(entrypointFilePath, dockerfilePath, baseDirectory, buildArgs, additionalIgnores, fs)
+		buildContext, err := customBuildContext(entrypointFilePath, dockerfilePath, baseDirectory, buildArgs, additionalIgnores, fs)
+		if err != nil {
+			return nil, err
+		}
+
+		buildContext.Debugger = options.debugger
+		buildContext.SourceRoot = lo.Ternary(options.sourceRoot != "", options.sourceRoot, "/")
+
+		return buildContext, nil
 	}
 
 	if fi, err := fs.Stat(entrypointFilePath); err == nil && fi.IsDir() {
@@ -231,6 +462,18 @@ func NewBuildContext(entrypointFilePath string, dockerfilePath string, baseDirec
 
 	additionalIgnores = append(additionalIgnores, dockerIgnores...)
 
+	if options.engine != "" && options.engine != Engine_Node {
+		if ext != ".js" && ext != ".ts" {
+			return nil, fmt.Errorf("the %s engine can only run javascript and typescript services", options.engine)
+		}
+
+		return engineBuildContext(options.engine, entrypointFilePath, baseDirectory, additionalIgnores)
+	}
+
+	if IsJavaBuildFile(entrypointFilePath) {
+		return javaBuildContext(entrypointFilePath, baseDirectory, additionalIgnores)
+	}
+
 	switch ext {
 	case ".csproj":
 		return csharpBuildContext(entrypointFilePath, baseDirectory, additionalIgnores)
@@ -244,6 +487,8 @@ func NewBuildContext(entrypointFilePath string, dockerfilePath string, baseDirec
 		return typescriptBuildContext(entrypointFilePath, baseDirectory, additionalIgnores)
 	case ".dart":
 		return dartBuildContext(entrypointFilePath, baseDirectory, additionalIgnores)
+	case ".rs":
+		return rustBuildContext(entrypointFilePath, baseDirectory, additionalIgnores, fs)
 	default:
 		return nil, fmt.Errorf("nitric does not support files with extension %s by default", ext)
 	}
@@ -0,0 +1,367 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/nitrictech/cli/pkg/cloud"
+	kvstorepb "github.com/nitrictech/nitric/core/pkg/proto/kvstore/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// seedTimeout - how long a seed waits for the services to declare the database it populates
+const seedTimeout = 2 * time.Minute
+
+// seedServiceName - names the nitric server of seed commands and labels their output, which is shown with the run's nitric messages
+const seedServiceName = "seed"
+
+// kind - returns the kind of resource populated by the seed, or command for seeds that run a command
+func (s SeedConfiguration) kind() string {
+	switch {
+	case s.Database != "":
+		return "database"
+	case s.Bucket != "":
+		return "bucket"
+	case s.KeyValueStore != "":
+		return "kv"
+	default:
+		return "command"
+	}
+}
+
+// String - describes the seed, this also identifies the seed in the record of seeds applied to the local state
+func (s SeedConfiguration) String() string {
+	switch s.kind() {
+	case "database":
+		return fmt.Sprintf("database %s from %s", s.Database, s.Sql)
+	case "bucket":
+		return fmt.Sprintf("bucket %s from %s", s.Bucket, s.Files)
+	case "kv":
+		return fmt.Sprintf("kv store %s from %s", s.KeyValueStore, s.Values)
+	default:
+		return fmt.Sprintf("with %s", s.Command)
+	}
+}
+
+func (s SeedConfiguration) validate() error {
+	targets := lo.Filter([]string{s.Database, s.Bucket, s.KeyValueStore, s.Command}, func(target string, _ int) bool {
+		return target != ""
+	})
+
+	if len(targets) != 1 {
+		return fmt.Errorf("seeds must set exactly one of database, bucket, kv or command")
+	}
+
+	switch s.kind() {
+	case "database":
+		if s.Sql == "" {
+			return fmt.Errorf("seed for database %s is missing the sql file to run", s.Database)
+		}
+	case "bucket":
+		if s.Files == "" {
+			return fmt.Errorf("seed for bucket %s is missing the directory of files to copy", s.Bucket)
+		}
+	case "kv":
+		if s.Values == "" {
+			return fmt.Errorf("seed for kv store %s is missing the json file of values to set", s.KeyValueStore)
+		}
+	}
+
+	return nil
+}
+
+// seeds - returns the project's seeds, checking each populates a single resource or runs a command
+func (p ProjectConfiguration) seeds() ([]SeedConfiguration, error) {
+	for i, seed := range p.Seed {
+		if err := seed.validate(); err != nil {
+			return nil, fmt.Errorf("invalid seed %d: %w", i+1, err)
+		}
+	}
+
+	return p.Seed, nil
+}
+
+// seedRecord - the seeds applied to the local state of a project and when, keyed by their description.
+// It's kept with the files of local resources in the local cloud's run directory, so removing them applies the seeds again
+type seedRecord map[string]time.Time
+
+const seedRecordFile = "seeded.json"
+
+func readSeedRecord(fs afero.Fs, runDir string) (seedRecord, error) {
+	record := seedRecord{}
+
+	contents, err := afero.ReadFile(fs, filepath.Join(runDir, seedRecordFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return record, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(contents, &record); err != nil {
+		return nil, fmt.Errorf("unable to read the record of applied seeds: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r seedRecord) write(fs afero.Fs, runDir string) error {
+	contents, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := fs.MkdirAll(runDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, filepath.Join(runDir, seedRecordFile), contents, 0o600)
+}
+
+// seeder - applies seeds to a local cloud, seed commands share a nitric server started for the first command that runs
+type seeder struct {
+	project    *Project
+	fs         afero.Fs
+	localCloud *cloud.LocalCloud
+	updates    chan<- ServiceRunUpdate
+	env        map[string]string
+	port       int
+}
+
+// Seed - populates the local cloud's databases, buckets and key/value stores from the project's seeds and runs its seed commands, in order.
+// Each seed is applied once to the local state so data added during development isn't duplicated, unless reseed is set
+func (p *Project) Seed(fs afero.Fs, localCloud *cloud.LocalCloud, updates chan<- ServiceRunUpdate, env map[string]string, reseed bool) error {
+	if len(p.Seeds) == 0 {
+		return nil
+	}
+
+	record, err := readSeedRecord(fs, localCloud.RunDir())
+	if err != nil {
+		return err
+	}
+
+	s := &seeder{project: p, fs: fs, localCloud: localCloud, updates: updates, env: env}
+
+	for _, seed := range p.Seeds {
+		if _, applied := record[seed.String()]; applied && !reseed {
+			continue
+		}
+
+		if err := s.apply(seed); err != nil {
+			return fmt.Errorf("unable to seed %s: %w", seed, err)
+		}
+
+		updates <- ServiceRunUpdate{
+			ServiceName: "nitric",
+			Label:       "nitric",
+			Status:      ServiceRunStatus_Running,
+			Message:     fmt.Sprintf("seeded %s", seed),
+		}
+
+		record[seed.String()] = time.Now()
+
+		// recorded as each seed is applied, so seeds that succeeded aren't applied again if a later one fails
+		if err := record.write(fs, localCloud.RunDir()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *seeder) apply(seed SeedConfiguration) error {
+	switch seed.kind() {
+	case "database":
+		return s.seedDatabase(seed)
+	case "bucket":
+		return s.seedBucket(seed)
+	case "kv":
+		return s.seedKeyValueStore(seed)
+	default:
+		return s.runCommand(seed)
+	}
+}
+
+// seedDatabase - runs the seed's SQL file against the database once it's declared, applying the database's migrations first
+func (s *seeder) seedDatabase(seed SeedConfiguration) error {
+	if s.localCloud.Databases == nil {
+		return fmt.Errorf("sql databases are disabled")
+	}
+
+	contents, err := afero.ReadFile(s.fs, filepath.Join(s.project.Directory, seed.Sql))
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(seedTimeout)
+
+	for {
+		server, ok := s.localCloud.Databases.GetState()[seed.Database]
+		if ok && server.ConnectionString != "" {
+			if server.ResourceRegister.Resource.GetMigrations().GetMigrationsPath() != "" {
+				err := s.localCloud.Databases.BuildAndRunMigrations(s.fs, map[string]*resourcespb.SqlDatabaseResource{
+					seed.Database: server.ResourceRegister.Resource,
+				})
+				if err != nil {
+					return fmt.Errorf("unable to apply migrations: %w", err)
+				}
+			}
+
+			_, err := s.localCloud.Databases.Query(context.Background(), server.ConnectionString, string(contents))
+
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("database %s wasn't declared by the project's services within %s", seed.Database, seedTimeout)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// seedBucket - copies the files in the seed's directory to the bucket, keyed by their paths relative to the directory
+func (s *seeder) seedBucket(seed SeedConfiguration) error {
+	filesDir := filepath.Join(s.project.Directory, seed.Files)
+	bucketDir := filepath.Join(s.localCloud.Storage.BucketsDir(), seed.Bucket)
+
+	return afero.Walk(s.fs, filesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		key, err := filepath.Rel(filesDir, path)
+		if err != nil {
+			return err
+		}
+
+		contents, err := afero.ReadFile(s.fs, path)
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(bucketDir, key)
+
+		if err := s.fs.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+			return err
+		}
+
+		return afero.WriteFile(s.fs, dest, contents, os.ModePerm)
+	})
+}
+
+// seedKeyValueStore - sets the keys of the object in the seed's JSON file in the key/value store, each value must be an object
+func (s *seeder) seedKeyValueStore(seed SeedConfiguration) error {
+	contents, err := afero.ReadFile(s.fs, filepath.Join(s.project.Directory, seed.Values))
+	if err != nil {
+		return err
+	}
+
+	values := map[string]map[string]any{}
+	if err := json.Unmarshal(contents, &values); err != nil {
+		return fmt.Errorf("%s must contain an object mapping keys to object values: %w", seed.Values, err)
+	}
+
+	for key, value := range values {
+		content, err := structpb.NewStruct(value)
+		if err != nil {
+			return fmt.Errorf("invalid value of key %s: %w", key, err)
+		}
+
+		_, err = s.localCloud.KeyValue.SetValue(context.Background(), &kvstorepb.KvStoreSetValueRequest{
+			Ref:     &kvstorepb.ValueRef{Store: seed.KeyValueStore, Key: key},
+			Content: content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runCommand - runs the seed's command from the project directory with the environment of services run by nitric start,
+// so scripts using a nitric SDK can populate resources through the local cloud
+func (s *seeder) runCommand(seed SeedConfiguration) error {
+	if s.port == 0 {
+		port, err := s.localCloud.AddService(seedServiceName)
+		if err != nil {
+			return err
+		}
+
+		s.port = port
+	}
+
+	envVariables := map[string]string{
+		"PYTHONUNBUFFERED":   "TRUE",
+		"NITRIC_ENVIRONMENT": "run",
+		"SERVICE_ADDRESS":    "localhost:" + strconv.Itoa(s.port),
+	}
+
+	if s.localCloud.Dependencies != nil {
+		dependencyEnv, err := s.localCloud.Dependencies.Env("localhost")
+		if err != nil {
+			return err
+		}
+
+		envVariables = lo.Assign(envVariables, dependencyEnv)
+	}
+
+	envVariables = lo.Assign(envVariables, FlagsToEnv(s.project.Flags), s.env)
+
+	commandParts := strings.Fields(seed.Command)
+
+	cmd := exec.Command(commandParts[0], commandParts[1:]...)
+	cmd.Dir = s.project.Directory
+
+	cmd.Env = append([]string{}, os.Environ()...)
+
+	for k, v := range envVariables {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	cmd.Stdout = &ServiceRunUpdateWriter{
+		updates:     s.updates,
+		serviceName: "nitric",
+		label:       seedServiceName,
+		status:      ServiceRunStatus_Running,
+	}
+
+	cmd.Stderr = &ServiceRunUpdateWriter{
+		updates:     s.updates,
+		serviceName: "nitric",
+		label:       seedServiceName,
+		status:      ServiceRunStatus_Error,
+	}
+
+	return cmd.Run()
+}
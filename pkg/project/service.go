@@ -24,9 +24,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	goruntime "runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/pkg/stdcopy"
@@ -34,10 +34,12 @@ import (
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 
+	"github.com/nitrictech/cli/pkg/budget"
+	"github.com/nitrictech/cli/pkg/cloud/dependencies"
 	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/localenv"
 	"github.com/nitrictech/cli/pkg/netx"
 	"github.com/nitrictech/cli/pkg/project/runtime"
-	"github.com/nitrictech/nitric/core/pkg/env"
 	"github.com/nitrictech/nitric/core/pkg/logger"
 )
 
@@ -46,6 +48,8 @@ type ServiceBuildStatus string
 type Service struct {
 	Name string
 	Type string
+	// Name of the image built for the service, defaults to the service name
+	Image string
 
 	// filepath relative to the project root directory
 	basedir      string
@@ -53,6 +57,12 @@ type Service struct {
 	buildContext runtime.RuntimeBuildContext
 
 	startCmd string
+	// environment variables of the service set in nitric.yaml and read from its env file
+	env         map[string]string
+	envFile     string
+	envFileVars map[string]string
+	// minimum level of the service's output shown during local runs
+	logLevel LogLevel
 }
 
 const tempBuildDir = "./.nitric/build"
@@ -61,6 +71,17 @@ func GetTempBuildDir() string {
 	return tempBuildDir
 }
 
+// environment - returns the service's own environment variables, merged over the project's env when the service runs
+func (s *Service) environment() map[string]string {
+	env := map[string]string{}
+
+	if s.logLevel != "" {
+		env[logLevelEnv] = string(s.logLevel)
+	}
+
+	return lo.Assign(env, s.envFileVars, s.env)
+}
+
 func (s *Service) GetFilePath() string {
 	return filepath.Join(s.basedir, s.filepath)
 }
@@ -81,6 +102,29 @@ type ServiceBuildUpdate struct {
 	Message     string
 	Status      ServiceBuildStatus
 	Err         error
+	// Progress of the build, only set on progress updates
+	Progress *docker.BuildProgress
+	// Time taken by the build, only set once the build has finished
+	Elapsed time.Duration
+	// Set when the build exceeded its time budget
+	Warning string
+}
+
+// buildCompleteUpdate - returns the update for a finished build, including a warning if the build exceeded its time budget
+func buildCompleteUpdate(serviceName string, elapsed time.Duration) ServiceBuildUpdate {
+	update := ServiceBuildUpdate{
+		ServiceName: serviceName,
+		Message:     fmt.Sprintf("Build Complete in %s", elapsed.Round(time.Second)),
+		Status:      ServiceBuildStatus_Complete,
+		Elapsed:     elapsed,
+	}
+
+	if warning, exceeded := budget.Check(budget.Build, elapsed); exceeded {
+		update.Warning = warning
+		update.Message = fmt.Sprintf("%s\nWarning: %s", update.Message, warning)
+	}
+
+	return update
 }
 
 type ServiceRunStatus string
@@ -141,12 +185,141 @@ func NewBuildUpdateWriter(serviceName string, buildUpdateChan chan ServiceBuildU
 	}
 }
 
-func (s *Service) BuildImage(fs afero.Fs, logs io.Writer) error {
-	dockerClient, err := docker.New()
+type buildOptions struct {
+	reproducible bool
+	onProgress   func(docker.BuildProgress)
+	build        BuildConfiguration
+	lockFile     *LockFile
+	noCache      bool
+	buildCache   *buildCache
+	labels       map[string]string
+}
+
+type BuildOption func(*buildOptions)
+
+// WithReproducibleBuild - pins base images to their digests, zeroes timestamps and records a build attestation for each service
+func WithReproducibleBuild() BuildOption {
+	return func(o *buildOptions) {
+		o.reproducible = true
+	}
+}
+
+// WithBuildConfiguration - builds images with the builder and for the platforms of the project's build configuration
+func WithBuildConfiguration(config BuildConfiguration) BuildOption {
+	return func(o *buildOptions) {
+		o.build = config
+	}
+}
+
+// WithoutBuildCache - rebuilds every service, even when its inputs are unchanged since it was last built, without using docker's layer cache
+func WithoutBuildCache() BuildOption {
+	return func(o *buildOptions) {
+		o.noCache = true
+	}
+}
+
+// withBuildCache - skips builds of services with inputs unchanged since their last build, recording the inputs of new builds in the cache
+func withBuildCache(cache *buildCache) BuildOption {
+	return func(o *buildOptions) {
+		o.buildCache = cache
+	}
+}
+
+// withLockFile - pins base images to the digests in the project's lock file
+func withLockFile(lockFile *LockFile) BuildOption {
+	return func(o *buildOptions) {
+		o.lockFile = lockFile
+	}
+}
+
+// withImageLabels - sets the project's labels on the built image
+func withImageLabels(labels map[string]string) BuildOption {
+	return func(o *buildOptions) {
+		o.labels = labels
+	}
+}
+
+// withBuildProgress - reports structured progress while the service image is built
+func withBuildProgress(onProgress func(docker.BuildProgress)) BuildOption {
+	return func(o *buildOptions) {
+		o.onProgress = onProgress
+	}
+}
+
+func (s *Service) BuildImage(fs afero.Fs, logs io.Writer, opts ...BuildOption) error {
+	options := &buildOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	dockerClient, err := docker.NewBuilder()
 	if err != nil {
 		return err
 	}
 
+	dockerfileContents := s.buildContext.DockerfileContents
+	dockerBuildOpts := []docker.BuildOption{docker.WithBuilder(options.build.Builder), docker.WithPlatforms(options.build.Platforms...)}
+	attestation := BuildAttestation{Service: s.Name}
+
+	if options.onProgress != nil {
+		dockerBuildOpts = append(dockerBuildOpts, docker.WithProgress(options.onProgress))
+	}
+
+	if options.reproducible || options.lockFile != nil {
+		resolveDigest := dockerClient.ResolveImageDigest
+		if options.lockFile != nil {
+			resolveDigest = options.lockFile.resolveDigest(s.Name)
+		}
+
+		dockerfileContents, attestation.BaseImages, attestation.UnpinnedImages, err = pinBaseImages(dockerfileContents, resolveDigest)
+		if err != nil {
+			return fmt.Errorf("unable to pin base images for service %s: %w", s.Name, err)
+		}
+	}
+
+	if options.reproducible {
+		attestation.SourceDateEpoch, err = sourceDateEpoch()
+		if err != nil {
+			return err
+		}
+
+		dockerBuildOpts = append(dockerBuildOpts, docker.WithReproducible(attestation.SourceDateEpoch))
+	}
+
+	labels := lo.Assign(options.labels, map[string]string{ImageServiceLabel: s.Name})
+	if options.reproducible {
+		// the build time would change the image of every build, reproducible images are dated at the source date epoch
+		labels[ImageCreatedLabel] = time.Unix(attestation.SourceDateEpoch, 0).UTC().Format(time.RFC3339)
+	}
+
+	dockerBuildOpts = append(dockerBuildOpts, docker.WithLabels(labels))
+
+	if options.noCache {
+		dockerBuildOpts = append(dockerBuildOpts, docker.WithNoCache())
+	}
+
+	// reproducible builds always run, so an attestation is recorded for each build
+	useBuildCache := options.buildCache != nil && !options.noCache && !options.reproducible
+	buildKey := ""
+
+	if useBuildCache {
+		buildKey, err = s.buildKey(fs, dockerfileContents, options.build)
+		if err != nil {
+			return err
+		}
+
+		// the image is only reused when it's still available and tagged with the name it was built with
+		if cachedImageId, ok := options.buildCache.cachedImageId(s.Name, s.Image, buildKey); ok {
+			if imageId, err := dockerClient.ImageId(s.Image); err == nil && imageId == cachedImageId {
+				_, err = fmt.Fprintf(logs, "inputs unchanged since the last build, using cached image %s\n", s.Image)
+
+				return err
+			}
+		}
+
+		options.buildCache.remove(s.Name)
+	}
+
 	err = fs.MkdirAll(tempBuildDir, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("unable to create temporary build directory %s: %w", tempBuildDir, err)
@@ -157,7 +330,7 @@ func (s *Service) BuildImage(fs afero.Fs, logs io.Writer) error {
 		return fmt.Errorf("unable to create temporary dockerfile for service %s: %w", s.Name, err)
 	}
 
-	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(s.buildContext.DockerfileContents), os.ModePerm); err != nil {
+	if err := afero.WriteFile(fs, tmpDockerFile.Name(), []byte(dockerfileContents), os.ModePerm); err != nil {
 		return fmt.Errorf("unable to write temporary dockerfile for service %s: %w", s.Name, err)
 	}
 
@@ -174,29 +347,93 @@ func (s *Service) BuildImage(fs afero.Fs, logs io.Writer) error {
 	err = dockerClient.Build(
 		tmpDockerFile.Name(),
 		s.buildContext.BaseDirectory,
-		s.Name,
+		s.Image,
 		s.buildContext.BuildArguments,
 		strings.Split(s.buildContext.IgnoreFileContents, "\n"),
 		logs,
+		dockerBuildOpts...,
 	)
 	if err != nil {
 		return err
 	}
 
+	if useBuildCache {
+		imageId, err := dockerClient.ImageId(s.Image)
+		if err == nil {
+			options.buildCache.record(s.Name, buildCacheEntry{Image: s.Image, ImageId: imageId, BuildKey: buildKey})
+		}
+	}
+
+	if options.reproducible {
+		return s.writeBuildAttestation(fs, dockerClient, dockerfileContents, attestation, logs)
+	}
+
 	return nil
 }
 
+func (s *Service) writeBuildAttestation(fs afero.Fs, dockerClient *docker.Docker, dockerfileContents string, attestation BuildAttestation, logs io.Writer) error {
+	var err error
+
+	attestation.ImageId, err = dockerClient.ImageId(s.Image)
+	if err != nil {
+		return fmt.Errorf("unable to inspect image for service %s: %w", s.Name, err)
+	}
+
+	attestation.DockerfileHash = hashString(dockerfileContents)
+	attestation.BuildArgsHash = hashBuildArgs(s.buildContext.BuildArguments)
+
+	attestation.ContextHash, err = hashBuildContext(fs, s.buildContext.BaseDirectory, strings.Split(s.buildContext.IgnoreFileContents, "\n"))
+	if err != nil {
+		return err
+	}
+
+	attestationFile, err := attestation.ToFile(fs)
+	if err != nil {
+		return fmt.Errorf("unable to write build attestation for service %s: %w", s.Name, err)
+	}
+
+	_, err = fmt.Fprintf(logs, "image %s, attestation written to %s\n", attestation.ImageId, attestationFile)
+
+	return err
+}
+
+type runCommandOptions struct {
+	hotReload bool
+}
+
+type RunCommandOption func(*runCommandOptions)
+
+// WithHotReload - restarts services run with a start command when their files change
+func WithHotReload() RunCommandOption {
+	return func(o *runCommandOptions) {
+		o.hotReload = true
+	}
+}
+
 type runContainerOptions struct {
+	// host used by the container to reach the nitric server, defaults to the host for the network mode
 	nitricHost        string
+	network           string
 	nitricPort        string
 	nitricEnvironment string
 	envVars           map[string]string
+	environment       *localenv.Environment
+	// keeps the stopped container for reuse by later runs
+	warmPool  bool
+	proxyPort int
+	// services run with their debugger attached
+	debugTargets []DebugTarget
+	// port of the local SMTP server and the address services send email from
+	smtpPort  int
+	emailFrom string
+	// dependency containers whose connection details are passed to the service
+	dependencies *dependencies.LocalDependencies
 }
 
 type RunContainerOption func(*runContainerOptions)
 
 var defaultRunContainerOptions = runContainerOptions{
-	nitricHost:        "host.docker.internal",
+	nitricHost:        "",
 	nitricPort:        "50051",
 	nitricEnvironment: "run",
 	envVars:           map[string]string{},
@@ -208,6 +445,13 @@ func WithNitricHost(host string) RunContainerOption {
 	}
 }
 
+// WithNetwork - runs the container using a network mode, one of bridge, host or the name of an existing docker network
+func WithNetwork(network string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.network = network
+	}
+}
+
 func WithNitricPort(port string) RunContainerOption {
 	return func(o *runContainerOptions) {
 		o.nitricPort = port
@@ -220,12 +464,57 @@ func WithNitricEnvironment(environment string) RunContainerOption {
 	}
 }
 
+// WithLocalEnvironment - namespaces and labels the container so it doesn't clash with the containers of other running projects
+func WithLocalEnvironment(environment *localenv.Environment) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.environment = environment
+	}
+}
+
 func WithEnvVars(envVars map[string]string) RunContainerOption {
 	return func(o *runContainerOptions) {
 		o.envVars = envVars
 	}
 }
 
+// WithWarmPool - keeps the container once it stops and restarts it on later runs, as long as its image and configuration are unchanged
+func WithWarmPool() RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.warmPool = true
+	}
+}
+
+// withProxyPort - prefers a port for the service's http proxy, the container's previous port allows it to be reused
+func withProxyPort(port int) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.proxyPort = port
+	}
+}
+
+// withEmail - sets the port of the local SMTP server capturing email sent by the service
+func withEmail(smtpPort int, from string) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.smtpPort = smtpPort
+		o.emailFrom = from
+	}
+}
+
+// withDependencies - passes the connection details of the local cloud's dependency containers to the service
+func withDependencies(deps *dependencies.LocalDependencies) RunContainerOption {
+	return func(o *runContainerOptions) {
+		o.dependencies = deps
+	}
+}
+
+// containerName - returns the name of the service's container, namespaced by the local environment
+func (s *Service) containerName(environment *localenv.Environment) string {
+	if environment == nil {
+		return s.Name
+	}
+
+	return s.Name + environment.Suffix()
+}
+
 type writerFunc func(p []byte) (n int, err error)
 
 func (wf writerFunc) Write(p []byte) (n int, err error) {
@@ -311,6 +600,51 @@ func (s *Service) Run(stop <-chan bool, updates chan<- ServiceRunUpdate, env map
 	return err
 }
 
+// runWithReload - Runs the service using its start command, restarting it when signalled by the restart channel.
+// A service that exits is started again after its next change, rather than stopping the other services
+func (s *Service) runWithReload(stop <-chan bool, restart <-chan struct{}, updates chan<- ServiceRunUpdate, env map[string]string) error {
+	for {
+		runStop := make(chan bool)
+		exited := make(chan error, 1)
+
+		go func() {
+			exited <- s.Run(runStop, updates, env)
+		}()
+
+		select {
+		case <-stop:
+			close(runStop)
+
+			return <-exited
+		case <-restart:
+			updates <- ServiceRunUpdate{
+				ServiceName: s.Name,
+				Label:       "nitric",
+				Status:      ServiceRunStatus_Running,
+				Message:     fmt.Sprintf("restarting service %s after changes to its files", s.filepath),
+			}
+
+			close(runStop)
+			<-exited
+		case err := <-exited:
+			close(runStop)
+
+			updates <- ServiceRunUpdate{
+				ServiceName: s.Name,
+				Label:       "nitric",
+				Status:      ServiceRunStatus_Error,
+				Message:     fmt.Sprintf("service %s exited, it will be restarted when its files change", s.filepath),
+			}
+
+			select {
+			case <-stop:
+				return err
+			case <-restart:
+			}
+		}
+	}
+}
+
 // RunContainer - Runs a container for the service, blocking until the container exits
 func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate, opts ...RunContainerOption) error {
 	runtimeOptions := lo.ToPtr(defaultRunContainerOptions)
@@ -325,8 +659,8 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 	}
 
 	hostConfig := &container.HostConfig{
-		// TODO: make this configurable through an cmd param
-		AutoRemove: true,
+		// pooled containers are kept once they stop so they can be restarted
+		AutoRemove: !runtimeOptions.warmPool,
 		// LogConfig:  *f.ce.Logger(f.runCtx).Config(),
 		LogConfig: container.LogConfig{
 			Type: "json-file",
@@ -337,61 +671,140 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 		},
 	}
 
-	if goruntime.GOOS == "linux" {
-		dockerHost := env.GetEnv("NITRIC_DOCKER_HOST", "172.17.0.1")
+	network := lo.Ternary(runtimeOptions.network != "", runtimeOptions.network, DefaultNetworkMode())
+
+	nitricHost := applyNetworkMode(network, hostConfig)
 
-		// setup host.docker.internal to route to host gateway
-		// to access rpc server hosted by local CLI run
-		hostConfig.ExtraHosts = []string{"host.docker.internal:" + dockerHost.String()}
+	// explicit hosts take precedence, otherwise servers on this machine must be made reachable from remote engines
+	_, dockerHostSet := os.LookupEnv("NITRIC_DOCKER_HOST")
+	if engine, ok := docker.GetRemoteEngine(); ok && runtimeOptions.nitricHost == "" && !dockerHostSet {
+		host, disconnect, err := connectRemoteEngine(engine, runtimeOptions.nitricPort, hostConfig)
+		if err != nil {
+			return err
+		}
+		defer disconnect()
+
+		nitricHost = host
 	}
 
-	randomPort, _ := netx.TakePort(1)
-	hostProxyPort := fmt.Sprint(randomPort[0])
+	if runtimeOptions.nitricHost != "" {
+		nitricHost = runtimeOptions.nitricHost
+	}
+
+	proxyPort, err := netx.TakePreferredPort(runtimeOptions.proxyPort)
+	if err != nil {
+		return err
+	}
+
+	hostProxyPort := fmt.Sprint(proxyPort)
 	env := []string{
 		fmt.Sprintf("NITRIC_ENVIRONMENT=%s", runtimeOptions.nitricEnvironment),
 		// FIXME: Ensure environment variable consistency in all SDKs, then remove duplicates here.
-		fmt.Sprintf("SERVICE_ADDRESS=%s", fmt.Sprintf("%s:%s", runtimeOptions.nitricHost, runtimeOptions.nitricPort)),
+		fmt.Sprintf("SERVICE_ADDRESS=%s", fmt.Sprintf("%s:%s", nitricHost, runtimeOptions.nitricPort)),
 		fmt.Sprintf("NITRIC_SERVICE_PORT=%s", runtimeOptions.nitricPort),
-		fmt.Sprintf("NITRIC_SERVICE_HOST=%s", runtimeOptions.nitricHost),
-		fmt.Sprintf("NITRIC_HTTP_PROXY_PORT=%d", randomPort[0]),
+		fmt.Sprintf("NITRIC_SERVICE_HOST=%s", nitricHost),
+		fmt.Sprintf("NITRIC_HTTP_PROXY_PORT=%d", proxyPort),
+	}
+
+	if runtimeOptions.dependencies != nil {
+		dependencyEnv, err := runtimeOptions.dependencies.Env(nitricHost)
+		if err != nil {
+			return err
+		}
+
+		// added before the provided env variables, so they take precedence
+		for k, v := range dependencyEnv {
+			env = append(env, k+"="+v)
+		}
 	}
 
 	for k, v := range runtimeOptions.envVars {
 		env = append(env, k+"="+v)
 	}
 
-	hostConfig.PortBindings = nat.PortMap{
-		nat.Port(hostProxyPort): []nat.PortBinding{
-			{
-				HostPort: hostProxyPort,
-			},
-		},
+	if runtimeOptions.smtpPort > 0 {
+		for k, v := range emailEnv(nitricHost, runtimeOptions.smtpPort, runtimeOptions.emailFrom) {
+			env = append(env, k+"="+v)
+		}
 	}
 
 	containerConfig := &container.Config{
-		Image: s.Name, // Select an image to use based on the handler
+		Image: s.Image, // Select an image to use based on the handler
 		Env:   env,
-		ExposedPorts: nat.PortSet{
+	}
+
+	containerName := s.containerName(runtimeOptions.environment)
+
+	if runtimeOptions.environment != nil {
+		containerConfig.Labels = runtimeOptions.environment.Labels()
+	}
+
+	// containers on the host network listen on the host directly
+	if !hostConfig.NetworkMode.IsHost() {
+		hostConfig.PortBindings = nat.PortMap{
+			nat.Port(hostProxyPort): []nat.PortBinding{
+				{
+					HostPort: hostProxyPort,
+				},
+			},
+		}
+
+		containerConfig.ExposedPorts = nat.PortSet{
 			nat.Port(hostProxyPort): struct{}{},
-		},
+		}
 	}
 
-	// Create the container
-	containerId, err := dockerClient.ContainerCreate(
-		containerConfig,
-		hostConfig,
-		nil,
-		s.Name,
-	)
-	if err != nil {
-		updates <- ServiceRunUpdate{
-			ServiceName: s.Name,
-			Label:       s.GetFilePath(),
-			Status:      ServiceRunStatus_Error,
-			Err:         err,
+	if target, ok := lo.Find(runtimeOptions.debugTargets, func(t DebugTarget) bool { return t.Service == s.Name }); ok {
+		if err := s.applyDebugger(dockerClient, target, containerConfig, hostConfig); err != nil {
+			return err
 		}
+	}
 
-		return nil
+	warmKey := ""
+
+	if runtimeOptions.warmPool {
+		imageId, err := dockerClient.ImageId(s.Image)
+		if err != nil {
+			return err
+		}
+
+		warmKey, err = warmPoolKey(imageId, containerConfig, hostConfig)
+		if err != nil {
+			return err
+		}
+
+		containerConfig.Labels = lo.Assign(containerConfig.Labels, map[string]string{
+			warmPoolLabel:           "true",
+			warmPoolKeyLabel:        warmKey,
+			warmPoolNitricPortLabel: runtimeOptions.nitricPort,
+			warmPoolProxyPortLabel:  hostProxyPort,
+		})
+	}
+
+	// restart the pooled container when it's unchanged, otherwise it's removed so a new container can take its name
+	containerId, reused, err := reuseWarmContainer(dockerClient, containerName, warmKey)
+	if err != nil {
+		return err
+	}
+
+	if !reused {
+		// Create the container
+		containerId, err = dockerClient.ContainerCreate(
+			containerConfig,
+			hostConfig,
+			nil,
+			containerName,
+		)
+		if err != nil {
+			updates <- ServiceRunUpdate{
+				ServiceName: s.Name,
+				Label:       s.GetFilePath(),
+				Status:      ServiceRunStatus_Error,
+				Err:         err,
+			}
+
+			return nil
+		}
 	}
 
 	err = dockerClient.ContainerStart(context.TODO(), containerId, container.StartOptions{})
@@ -409,7 +822,7 @@ func (s *Service) RunContainer(stop <-chan bool, updates chan<- ServiceRunUpdate
 	updates <- ServiceRunUpdate{
 		ServiceName: s.Name,
 		Label:       s.GetFilePath(),
-		Message:     fmt.Sprintf("Service %s started", s.Name),
+		Message:     lo.Ternary(reused, fmt.Sprintf("Service %s started from the warm pool", s.Name), fmt.Sprintf("Service %s started", s.Name)),
 		Status:      ServiceRunStatus_Running,
 	}
 
@@ -516,6 +929,7 @@ func NewService(name, serviceType, filepath string, buildContext runtime.Runtime
 	return &Service{
 		Name:         name,
 		Type:         serviceType,
+		Image:        name,
 		filepath:     filepath,
 		buildContext: buildContext,
 		startCmd:     startCmd,
@@ -0,0 +1,94 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// maximum log retention supported by all providers, ten years
+const maxLogRetentionDays = 3653
+
+// EncryptedResourceTypes - the resources that can be encrypted with a customer managed key
+var EncryptedResourceTypes = []string{
+	"logs",
+	"bucket",
+	"sqldatabase",
+	"keyvaluestore",
+	"queue",
+	"topic",
+	"secret",
+}
+
+// LogsConfig - how logs of deployed services are kept
+type LogsConfig struct {
+	// Number of days logs are kept, providers round up to their nearest supported retention period
+	RetentionDays int `yaml:"retention-days,omitempty"`
+}
+
+// EncryptionConfig - customer managed encryption of deployed logs and data resources
+type EncryptionConfig struct {
+	// Customer managed key, e.g. an AWS KMS key ARN, a GCP Cloud KMS key name or an Azure Key Vault key id
+	Key string `yaml:"key"`
+	// Resource types encrypted with the key, defaults to logs and all data resources
+	Resources []string `yaml:"resources,omitempty"`
+}
+
+// ValidateCompliance - validates the log retention and encryption settings of a stack
+func (s *StackConfig[T]) ValidateCompliance() error {
+	if s.Logs != nil && (s.Logs.RetentionDays < 0 || s.Logs.RetentionDays > maxLogRetentionDays) {
+		return fmt.Errorf("invalid logs retention-days %d, expected between 1 and %d", s.Logs.RetentionDays, maxLogRetentionDays)
+	}
+
+	if s.Encryption == nil {
+		return nil
+	}
+
+	if s.Encryption.Key == "" {
+		return fmt.Errorf("encryption requires a key, set encryption.key to the id of a customer managed key")
+	}
+
+	for _, resourceType := range s.Encryption.Resources {
+		if !slices.Contains(EncryptedResourceTypes, resourceType) {
+			return fmt.Errorf("unknown encryption resource type '%s', expected one of %s", resourceType, strings.Join(EncryptedResourceTypes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// ComplianceAttributes - returns the log retention and encryption settings of a stack in the form passed to providers
+func (s *StackConfig[T]) ComplianceAttributes() map[string]interface{} {
+	attributes := map[string]interface{}{}
+
+	if s.Logs != nil && s.Logs.RetentionDays > 0 {
+		attributes["log-retention-days"] = s.Logs.RetentionDays
+	}
+
+	if s.Encryption != nil {
+		attributes["encryption"] = map[string]interface{}{
+			"key":       s.Encryption.Key,
+			"resources": lo.ToAnySlice(lo.Ternary(len(s.Encryption.Resources) > 0, s.Encryption.Resources, EncryptedResourceTypes)),
+		}
+	}
+
+	return attributes
+}
@@ -0,0 +1,121 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EmailProviders - the services deployed stacks can send email with
+var EmailProviders = []string{"ses", "sendgrid"}
+
+// EmailConfig - how services of a deployed stack send email over SMTP
+type EmailConfig struct {
+	// Service email is sent with, one of ses or sendgrid, defaults to ses for aws stacks
+	Provider string `yaml:"provider,omitempty"`
+	// Domain verified for sending email, providers output the DNS records required to verify it, defaults to the domain of the from address
+	Domain string `yaml:"domain,omitempty"`
+	// Environment variable holding the SendGrid API key, it's forwarded to the provider, defaults to SENDGRID_API_KEY
+	ApiKeyEnv string `yaml:"api-key-env,omitempty"`
+}
+
+// isAws - returns true if the stack deploys to aws, with a nitric aws provider or terraform/aws
+func (s *StackConfig[T]) isAws() bool {
+	return strings.HasPrefix(s.Provider, "nitric/aws") || strings.HasPrefix(s.Provider, "terraform/aws")
+}
+
+// emailProvider - returns the configured email provider, or the default for the stack's cloud
+func (s *StackConfig[T]) emailProvider() string {
+	if s.Email != nil && s.Email.Provider != "" {
+		return s.Email.Provider
+	}
+
+	if s.isAws() {
+		return "ses"
+	}
+
+	return ""
+}
+
+func (s *StackConfig[T]) emailApiKeyEnv() string {
+	if s.Email != nil && s.Email.ApiKeyEnv != "" {
+		return s.Email.ApiKeyEnv
+	}
+
+	return "SENDGRID_API_KEY"
+}
+
+// ValidateEmail - validates the email settings of a stack for a project sending email from an address, an empty address when the project doesn't send email
+func (s *StackConfig[T]) ValidateEmail(from string) error {
+	if from == "" {
+		if s.Email != nil {
+			return fmt.Errorf("the stack configures email but nitric.yaml doesn't, set email.from in nitric.yaml to the address services send email from")
+		}
+
+		return nil
+	}
+
+	if !strings.Contains(from, "@") {
+		return fmt.Errorf("invalid email from address '%s' in nitric.yaml", from)
+	}
+
+	provider := s.emailProvider()
+
+	switch {
+	case provider == "":
+		return fmt.Errorf("services send email but the stack doesn't configure a provider for it, set email.provider to one of %s", strings.Join(EmailProviders, ", "))
+	case !slices.Contains(EmailProviders, provider):
+		return fmt.Errorf("unknown email provider '%s', expected one of %s", provider, strings.Join(EmailProviders, ", "))
+	case provider == "ses" && !s.isAws():
+		return fmt.Errorf("the ses email provider is only available to aws stacks, use sendgrid for %s", s.Provider)
+	}
+
+	return nil
+}
+
+// EmailAttributes - returns the email settings of a stack in the form passed to providers, so they can verify the sending domain
+// and set the SMTP endpoint and credentials of the provider in the NITRIC_SMTP_* environment variables of services
+func (s *StackConfig[T]) EmailAttributes(from string) map[string]interface{} {
+	domain := from[strings.LastIndex(from, "@")+1:]
+	if s.Email != nil && s.Email.Domain != "" {
+		domain = s.Email.Domain
+	}
+
+	attributes := map[string]interface{}{
+		"provider": s.emailProvider(),
+		"from":     from,
+		"domain":   domain,
+	}
+
+	if s.emailProvider() == "sendgrid" {
+		attributes["api-key-env"] = s.emailApiKeyEnv()
+	}
+
+	return attributes
+}
+
+// ProviderEnv - returns the environment variables forwarded to the provider in addition to the defaults,
+// including the SendGrid API key used to send email
+func (s *StackConfig[T]) ProviderEnv() []string {
+	if s.emailProvider() != "sendgrid" {
+		return s.ForwardEnv
+	}
+
+	return append(slices.Clone(s.ForwardEnv), s.emailApiKeyEnv())
+}
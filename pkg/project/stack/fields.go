@@ -0,0 +1,322 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Field - a setting of a stack file, documented by the stack template of its provider
+type Field struct {
+	// Dot separated path of the field, with placeholders for names chosen by the user, e.g. config.<type>.lambda.memory
+	Path string `json:"path"`
+	Doc  string `json:"doc,omitempty"`
+	// Example value from the stack template, empty for fields holding other fields
+	Example string `json:"example,omitempty"`
+}
+
+// FieldIssue - a field of a stack file that isn't documented for its provider, or doesn't match the type of its documented example
+type FieldIssue struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	// The value doesn't match its documented type, rather than the field being unknown
+	Invalid bool   `json:"invalid"`
+	Message string `json:"message"`
+}
+
+// namedFields - fields keyed by names chosen by the user, with the placeholder documentation shows for the names
+var namedFields = map[string]string{
+	"config":         "<type>",
+	"security":       "<api>",
+	"apis":           "<api>",
+	"import.secrets": "<secret>",
+	"aliases":        "<resource>",
+	"flags":          "<flag>",
+}
+
+var (
+	templateKeyPattern  = regexp.MustCompile(`^( *)([a-z0-9][a-z0-9-]*):(?: +(.*))?$`)
+	templateListPattern = regexp.MustCompile(`^( *)- `)
+)
+
+// templateForProvider - the stack template documenting the fields of a provider, empty if there isn't one
+func templateForProvider(providerId string) string {
+	name, _, _ := strings.Cut(providerId, "@")
+
+	switch {
+	case name == "nitric/aws":
+		return awsConfigTemplate
+	case name == "nitric/awstf":
+		return awsTfConfigTemplate
+	case name == "nitric/gcp":
+		return gcpConfigTemplate
+	case name == "nitric/gcptf":
+		return gcpTfConfigTemplate
+	case name == "nitric/azure":
+		return azureConfigTemplate
+	case name == "cloudflare":
+		return cloudflareConfigTemplate
+	case name == "terraform/do":
+		return doConfigTemplate
+	case strings.HasPrefix(name, "kubernetes/"):
+		return kubernetesConfigTemplate
+	}
+
+	return ""
+}
+
+// fieldPath - joins the keys of a field, replacing names chosen by the user with placeholders
+func fieldPath(keys []string) string {
+	path := slices.Clone(keys)
+
+	for i := 1; i < len(path); i++ {
+		if placeholder, ok := namedFields[strings.Join(keys[:i], ".")]; ok {
+			path[i] = placeholder
+		}
+	}
+
+	return strings.Join(path, ".")
+}
+
+// commonFields - the top level fields of every stack file, whether or not the template of its provider documents them
+func commonFields() []string {
+	configType := reflect.TypeOf(StackConfig[map[string]any]{})
+	fields := []string{}
+
+	for i := 0; i < configType.NumField(); i++ {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// Fields - returns the fields documented for a provider, parsed from the comments of its stack template.
+// Optional fields are commented out in templates, so commented keys are documented the same as set keys.
+func Fields(providerId string) []Field {
+	template := applySettingBlocks(templateForProvider(providerId))
+	if template == "" {
+		return []Field{}
+	}
+
+	type parent struct {
+		indent int
+		key    string
+	}
+
+	fields := []Field{}
+	parents := []parent{}
+	docs := []string{}
+	// keys within list items are part of the list's value, rather than fields
+	listIndent := -1
+
+	for _, line := range strings.Split(template, "\n") {
+		if strings.TrimSpace(line) == "" {
+			docs = nil
+			continue
+		}
+
+		content := line
+		// a comment following the comment marker of a commented out block is documentation
+		isDoc := false
+
+		if strings.HasPrefix(line, "#") {
+			content = strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+
+			if trimmed := strings.TrimLeft(content, " "); strings.HasPrefix(trimmed, "#") {
+				content = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+				isDoc = true
+			} else if !templateKeyPattern.MatchString(content) && !templateListPattern.MatchString(content) {
+				content = strings.TrimSpace(content)
+				isDoc = true
+			}
+		}
+
+		if isDoc {
+			if listIndent < 0 && content != "" {
+				docs = append(docs, content)
+			}
+
+			continue
+		}
+
+		if match := templateListPattern.FindStringSubmatch(content); match != nil {
+			listIndent = len(match[1])
+			docs = nil
+
+			continue
+		}
+
+		match := templateKeyPattern.FindStringSubmatch(content)
+		if match == nil {
+			continue
+		}
+
+		indent := len(match[1])
+
+		if listIndent >= 0 && indent > listIndent {
+			continue
+		}
+
+		listIndent = -1
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+
+		keys := append(lo.Map(parents, func(p parent, _ int) string { return p.key }), match[2])
+
+		example, inlineDoc, _ := strings.Cut(match[3], " # ")
+		if strings.HasPrefix(match[3], "#") {
+			example, inlineDoc = "", strings.TrimPrefix(match[3], "#")
+		}
+
+		if inlineDoc != "" {
+			docs = append(docs, strings.TrimSpace(inlineDoc))
+		}
+
+		field := Field{
+			Path:    fieldPath(keys),
+			Doc:     strings.Join(docs, " "),
+			Example: strings.TrimSpace(example),
+		}
+
+		if existing, ok := lo.Find(fields, func(f Field) bool { return f.Path == field.Path }); ok {
+			// named fields repeat in templates, e.g. the default service type and an additional type
+			if existing.Doc == "" && field.Doc != "" {
+				fields = lo.Map(fields, func(f Field, _ int) Field {
+					return lo.Ternary(f.Path == field.Path, field, f)
+				})
+			}
+		} else {
+			fields = append(fields, field)
+		}
+
+		parents = append(parents, parent{indent: indent, key: match[2]})
+		docs = nil
+	}
+
+	return fields
+}
+
+// exampleType - the type of value a field takes, based on its documented example, empty when it can't be told
+func exampleType(example string) string {
+	if _, err := strconv.ParseFloat(example, 64); err == nil {
+		return "number"
+	}
+
+	if example == "true" || example == "false" {
+		return "boolean"
+	}
+
+	return ""
+}
+
+// CheckFields - checks the fields set in the contents of a stack file against the fields documented for its provider.
+// Returns no issues for providers without documented fields.
+func CheckFields(providerId string, contents []byte) ([]FieldIssue, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, doc); err != nil {
+		return nil, err
+	}
+
+	fields := Fields(providerId)
+	if len(fields) == 0 || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return []FieldIssue{}, nil
+	}
+
+	documented := lo.KeyBy(fields, func(f Field) string { return f.Path })
+	common := commonFields()
+
+	// fields are only reported as unknown when the template documents the fields of their parent
+	documentedParents := map[string]bool{}
+
+	for _, f := range fields {
+		if i := strings.LastIndex(f.Path, "."); i > 0 {
+			documentedParents[f.Path[:i]] = true
+		}
+	}
+
+	issues := []FieldIssue{}
+
+	var check func(node *yaml.Node, keys []string)
+
+	check = func(node *yaml.Node, keys []string) {
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			path := append(slices.Clone(keys), keyNode.Value)
+			name := strings.Join(path, ".")
+			field, isDocumented := documented[fieldPath(path)]
+
+			known := isDocumented
+
+			switch {
+			case len(path) == 1:
+				known = known || slices.Contains(common, keyNode.Value)
+			case namedFields[strings.Join(keys, ".")] != "":
+				known = true
+			default:
+				known = known || !documentedParents[fieldPath(keys)]
+			}
+
+			if !known {
+				issues = append(issues, FieldIssue{Path: name, Line: keyNode.Line, Message: fmt.Sprintf("%s is not a documented field of %s", name, providerId)})
+				continue
+			}
+
+			if valueNode.Kind == yaml.ScalarNode && valueNode.ShortTag() != "!!null" {
+				switch exampleType(field.Example) {
+				case "number":
+					if valueNode.ShortTag() != "!!int" && valueNode.ShortTag() != "!!float" {
+						issues = append(issues, FieldIssue{Path: name, Line: valueNode.Line, Invalid: true, Message: fmt.Sprintf("%s must be a number, got %q", name, valueNode.Value)})
+					}
+				case "boolean":
+					if valueNode.ShortTag() != "!!bool" {
+						issues = append(issues, FieldIssue{Path: name, Line: valueNode.Line, Invalid: true, Message: fmt.Sprintf("%s must be true or false, got %q", name, valueNode.Value)})
+					}
+				}
+			}
+
+			if valueNode.Kind == yaml.MappingNode {
+				check(valueNode, path)
+			}
+		}
+	}
+
+	check(doc.Content[0], nil)
+
+	return issues, nil
+}
+
+// FieldDoc - the documentation of the field at a dot separated path, empty if it isn't documented
+func FieldDoc(providerId string, path string) string {
+	field, _ := lo.Find(Fields(providerId), func(f Field) bool {
+		return f.Path == fieldPath(strings.Split(path, "."))
+	})
+
+	return field.Doc
+}
@@ -0,0 +1,154 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+const defaultAlarmPeriod = 5 * time.Minute
+
+var defaultBudgetThresholds = []float64{80, 100}
+
+// BudgetAlert - notifies when a stack's monthly spend reaches a percentage of its budget
+type BudgetAlert struct {
+	// Monthly budget in the account's billing currency
+	Amount float64 `yaml:"amount"`
+	// Percentages of the budget that trigger a notification, defaults to 80 and 100
+	Thresholds []float64 `yaml:"thresholds,omitempty"`
+}
+
+// AlarmConfig - notifies when an error rate stays above a threshold for a period
+type AlarmConfig struct {
+	// Percentage of requests or invocations that must fail to trigger the alarm
+	Threshold float64 `yaml:"threshold"`
+	// Period the error rate is evaluated over, defaults to 5m
+	Period time.Duration `yaml:"period,omitempty"`
+}
+
+// MonitoringConfig - baseline budget alerts and alarms provisioned with a stack
+type MonitoringConfig struct {
+	Budget *BudgetAlert `yaml:"budget,omitempty"`
+	// Alarms when the 5xx response rate of any API exceeds the threshold
+	Api5xx *AlarmConfig `yaml:"api-5xx,omitempty"`
+	// Alarms when the error rate of any service exceeds the threshold
+	ServiceErrors *AlarmConfig `yaml:"service-errors,omitempty"`
+	// Email addresses or webhook URLs notified by alerts and alarms, defaults to the project's notification webhooks
+	Notify []string `yaml:"notify,omitempty"`
+}
+
+func (m *MonitoringConfig) notifyTargets(defaultTargets []string) []string {
+	return lo.Ternary(len(m.Notify) > 0, m.Notify, defaultTargets)
+}
+
+func validateAlarm(name string, alarm *AlarmConfig) error {
+	if alarm == nil {
+		return nil
+	}
+
+	if alarm.Threshold <= 0 || alarm.Threshold > 100 {
+		return fmt.Errorf("invalid %s alarm threshold %v, expected a percentage greater than 0", name, alarm.Threshold)
+	}
+
+	if alarm.Period != 0 && alarm.Period < time.Minute {
+		return fmt.Errorf("invalid %s alarm period %s, expected at least 1m", name, alarm.Period)
+	}
+
+	return nil
+}
+
+// ValidateMonitoring - validates the monitoring configuration of a stack, defaultTargets are notified if the stack doesn't set its own
+func (s *StackConfig[T]) ValidateMonitoring(defaultTargets []string) error {
+	if s.Monitoring == nil {
+		return nil
+	}
+
+	if budget := s.Monitoring.Budget; budget != nil {
+		if budget.Amount <= 0 {
+			return fmt.Errorf("invalid budget amount %v, expected an amount greater than 0", budget.Amount)
+		}
+
+		for _, threshold := range budget.Thresholds {
+			if threshold <= 0 {
+				return fmt.Errorf("invalid budget threshold %v, expected a percentage greater than 0", threshold)
+			}
+		}
+	}
+
+	if err := validateAlarm("api-5xx", s.Monitoring.Api5xx); err != nil {
+		return err
+	}
+
+	if err := validateAlarm("service-errors", s.Monitoring.ServiceErrors); err != nil {
+		return err
+	}
+
+	targets := s.Monitoring.notifyTargets(defaultTargets)
+	if len(targets) == 0 {
+		return fmt.Errorf("monitoring requires at least one notify target, add an email address or webhook to monitoring.notify")
+	}
+
+	for _, target := range targets {
+		if _, err := mail.ParseAddress(target); err == nil {
+			continue
+		}
+
+		if u, err := url.Parse(target); err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" {
+			continue
+		}
+
+		return fmt.Errorf("invalid monitoring notify target '%s', expected an email address or webhook URL", target)
+	}
+
+	return nil
+}
+
+func alarmAttributes(alarm *AlarmConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"threshold": alarm.Threshold,
+		"period":    lo.Ternary(alarm.Period > 0, alarm.Period, defaultAlarmPeriod).String(),
+	}
+}
+
+// MonitoringAttributes - returns the monitoring configuration of a stack in the form passed to providers
+func (s *StackConfig[T]) MonitoringAttributes(defaultTargets []string) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"notify": lo.ToAnySlice(s.Monitoring.notifyTargets(defaultTargets)),
+	}
+
+	if budget := s.Monitoring.Budget; budget != nil {
+		attributes["budget"] = map[string]interface{}{
+			"amount":     budget.Amount,
+			"thresholds": lo.ToAnySlice(lo.Ternary(len(budget.Thresholds) > 0, budget.Thresholds, defaultBudgetThresholds)),
+		}
+	}
+
+	if s.Monitoring.Api5xx != nil {
+		attributes["api-5xx"] = alarmAttributes(s.Monitoring.Api5xx)
+	}
+
+	if s.Monitoring.ServiceErrors != nil {
+		attributes["service-errors"] = alarmAttributes(s.Monitoring.ServiceErrors)
+	}
+
+	return attributes
+}
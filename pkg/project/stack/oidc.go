@@ -0,0 +1,65 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"github.com/nitrictech/cli/pkg/credentials"
+)
+
+// OidcConfig - exchanges the OIDC token of a CI job, e.g. from GitHub Actions or GitLab, for short-lived cloud credentials
+// before the provider runs, so cloud keys don't need to be stored in CI
+type OidcConfig struct {
+	// Audience of the CI's token, defaults to the audience expected by the stack's cloud
+	Audience string `yaml:"audience,omitempty"`
+	// Environment variable holding the token for CI systems other than GitHub Actions, defaults to NITRIC_ID_TOKEN
+	TokenEnv string `yaml:"token-env,omitempty"`
+	// AWS - the IAM role assumed with the token
+	RoleArn string `yaml:"role-arn,omitempty"`
+	// Google Cloud - the full name of the workload identity pool provider, and optionally a service account to impersonate
+	WorkloadIdentityProvider string `yaml:"workload-identity-provider,omitempty"`
+	ServiceAccount           string `yaml:"service-account,omitempty"`
+	// Azure - the application (client) and tenant with a federated credential for the CI
+	ClientId string `yaml:"client-id,omitempty"`
+	TenantId string `yaml:"tenant-id,omitempty"`
+}
+
+// Federation - returns how the stack exchanges CI tokens for cloud credentials, false if it doesn't
+func (s *StackConfig[T]) Federation() (credentials.Federation, bool) {
+	if s.Oidc == nil {
+		return credentials.Federation{}, false
+	}
+
+	return credentials.Federation{
+		Audience:                 s.Oidc.Audience,
+		TokenEnv:                 s.Oidc.TokenEnv,
+		RoleArn:                  s.Oidc.RoleArn,
+		WorkloadIdentityProvider: s.Oidc.WorkloadIdentityProvider,
+		ServiceAccount:           s.Oidc.ServiceAccount,
+		ClientId:                 s.Oidc.ClientId,
+		TenantId:                 s.Oidc.TenantId,
+	}, true
+}
+
+// ValidateOidc - validates the OIDC settings of a stack for its provider's cloud
+func (s *StackConfig[T]) ValidateOidc() error {
+	federation, ok := s.Federation()
+	if !ok {
+		return nil
+	}
+
+	return federation.Validate(credentials.FederationCloud(s.Provider))
+}
@@ -0,0 +1,52 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// ValidateProtect - validates the protection rules of a stack
+func (s *StackConfig[T]) ValidateProtect() error {
+	for _, pattern := range s.Protect {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protect pattern '%s': %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// IsProtected - returns true if a resource, identified by its <type>/<name> key, must not be deleted from the cloud
+func (s *StackConfig[T]) IsProtected(resourceKey string) bool {
+	return lo.ContainsBy(s.Protect, func(pattern string) bool {
+		match, _ := path.Match(pattern, resourceKey)
+		return match
+	})
+}
+
+// AliasedKeys - the <type>/<name> keys of previous resources whose state is kept for renamed resources
+func (s *StackConfig[T]) AliasedKeys() []string {
+	return lo.MapToSlice(s.Aliases, func(key string, previousName string) string {
+		resourceType, _, _ := strings.Cut(key, "/")
+		return fmt.Sprintf("%s/%s", resourceType, previousName)
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Topologies - how resources placed in more than one region are deployed
+const (
+	// Topology_Single - deployed to a single region
+	Topology_Single = "single"
+	// Topology_ActiveActive - deployed to and serving from every region
+	Topology_ActiveActive = "active-active"
+	// Topology_PrimaryReplica - served from the first region, with read replicas or failover copies in the others
+	Topology_PrimaryReplica = "primary-replica"
+)
+
+var Topologies = []string{Topology_Single, Topology_ActiveActive, Topology_PrimaryReplica}
+
+// PlacementRule - places matching resources in a subset of a stack's regions
+type PlacementRule struct {
+	// Resources the rule applies to as <type>/<name> patterns, e.g. bucket/* or api/main
+	Resources []string `yaml:"resources"`
+	// Regions the resources are deployed to, the first is the primary, defaults to all of the stack's regions
+	Regions []string `yaml:"regions,omitempty"`
+	// How the resources are deployed across their regions, defaults to active-active for more than one region
+	Topology string `yaml:"topology,omitempty"`
+}
+
+// Placement - the regions a resource is deployed to
+type Placement struct {
+	Regions  []string
+	Topology string
+}
+
+// AllRegions - the regions the stack is deployed to, starting with the primary region
+func (s *StackConfig[T]) AllRegions() []string {
+	if s.Region == "" {
+		return s.Regions
+	}
+
+	return append([]string{s.Region}, lo.Without(s.Regions, s.Region)...)
+}
+
+// ValidateRegions - validates the regions and placement rules of a stack
+func (s *StackConfig[T]) ValidateRegions() error {
+	if len(s.Regions) > 0 && len(lo.Uniq(s.Regions)) != len(s.Regions) {
+		return fmt.Errorf("regions must not contain duplicates")
+	}
+
+	if s.Region != "" && len(s.Regions) > 0 && !slices.Contains(s.Regions, s.Region) {
+		return fmt.Errorf("region %s must be one of the stack's regions %s", s.Region, strings.Join(s.Regions, ", "))
+	}
+
+	regions := s.AllRegions()
+
+	for i, rule := range s.Placement {
+		if len(rule.Resources) == 0 {
+			return fmt.Errorf("placement rule %d must match at least one resource", i+1)
+		}
+
+		for _, pattern := range rule.Resources {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid placement resource pattern '%s': %w", pattern, err)
+			}
+		}
+
+		for _, region := range rule.Regions {
+			if !slices.Contains(regions, region) {
+				return fmt.Errorf("placement region %s is not one of the stack's regions %s", region, strings.Join(regions, ", "))
+			}
+		}
+
+		if rule.Topology != "" && !slices.Contains(Topologies, rule.Topology) {
+			return fmt.Errorf("unknown placement topology '%s', expected one of %s", rule.Topology, strings.Join(Topologies, ", "))
+		}
+
+		if rule.Topology != "" && rule.Topology != Topology_Single && len(lo.Ternary(len(rule.Regions) > 0, rule.Regions, regions)) < 2 {
+			return fmt.Errorf("placement topology %s requires at least two regions", rule.Topology)
+		}
+	}
+
+	return nil
+}
+
+// ResourcePlacement - returns the placement of a resource, identified by its <type>/<name> key, from the first matching rule.
+// Resources not matching any rule are deployed to the primary region.
+func (s *StackConfig[T]) ResourcePlacement(resourceKey string) Placement {
+	regions := s.AllRegions()
+
+	for _, rule := range s.Placement {
+		matched := lo.ContainsBy(rule.Resources, func(pattern string) bool {
+			match, _ := path.Match(pattern, resourceKey)
+			return match
+		})
+
+		if !matched {
+			continue
+		}
+
+		placement := Placement{
+			Regions:  lo.Ternary(len(rule.Regions) > 0, rule.Regions, regions),
+			Topology: rule.Topology,
+		}
+
+		if placement.Topology == "" {
+			placement.Topology = lo.Ternary(len(placement.Regions) > 1, Topology_ActiveActive, Topology_Single)
+		}
+
+		return placement
+	}
+
+	return Placement{
+		Regions:  lo.Subset(regions, 0, 1),
+		Topology: Topology_Single,
+	}
+}
+
+// RegionAttributes - returns the regions of a stack in the form passed to providers
+func (s *StackConfig[T]) RegionAttributes() map[string]interface{} {
+	attributes := map[string]interface{}{}
+
+	if s.Region != "" {
+		attributes["region"] = s.Region
+	}
+
+	if len(s.Regions) > 0 {
+		attributes["regions"] = lo.ToAnySlice(s.AllRegions())
+	}
+
+	return attributes
+}
+
+// PlacementAttributes - returns the placement of each resource in the form passed to providers, keyed by <type>/<name>
+func (s *StackConfig[T]) PlacementAttributes(resourceKeys []string) map[string]interface{} {
+	return lo.SliceToMap(resourceKeys, func(key string) (string, interface{}) {
+		placement := s.ResourcePlacement(key)
+
+		return key, map[string]interface{}{
+			"regions":  lo.ToAnySlice(placement.Regions),
+			"topology": placement.Topology,
+		}
+	})
+}
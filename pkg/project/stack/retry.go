@@ -0,0 +1,130 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// TransientErrorPatterns - provider errors known to be transient, such as IAM propagation delays and eventual consistency,
+// deployments failing only with these errors are safe to retry since provider deployments are idempotent
+var TransientErrorPatterns = []string{
+	// AWS IAM propagation
+	`(?i)role defined for the function cannot be assumed`,
+	`(?i)InvalidParameterValueException.*(role|execution role)`,
+	`(?i)not authorized to perform: sts:AssumeRole`,
+	// GCP IAM propagation and eventual consistency
+	`(?i)service account .* does not exist`,
+	`(?i)Permission '.*' denied on resource .* \(or it may not exist\)`,
+	`(?i)has not been used in project .* before or it is disabled`,
+	// Azure AD replication
+	`(?i)PrincipalNotFound`,
+	`(?i)does not exist in the directory`,
+	// Throttling and temporary unavailability
+	`(?i)ThrottlingException|Rate exceeded|TooManyRequests|429 Too Many Requests`,
+	`(?i)ServiceUnavailable|503 Service Unavailable|try again later`,
+	`(?i)OperationAborted|conflicting conditional operation`,
+	`(?i)connection reset by peer|i/o timeout`,
+}
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryDelay       = 30 * time.Second
+)
+
+// RetryConfig - how deployments failing with transient errors are retried
+type RetryConfig struct {
+	// Total number of deployment attempts, 1 disables retries
+	MaxAttempts int `yaml:"max-attempts,omitempty"`
+	// Time to wait between attempts, e.g. 30s
+	Delay time.Duration `yaml:"delay,omitempty"`
+	// Additional regular expressions matching provider errors that are safe to retry
+	TransientErrors []string `yaml:"transient-errors,omitempty"`
+}
+
+// RetryPolicy - the resolved retry configuration of a stack
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	patterns    []*regexp.Regexp
+}
+
+// RetryPolicy - returns the stack's retry policy, using defaults for anything not configured
+func (s *StackConfig[T]) RetryPolicy() (*RetryPolicy, error) {
+	config := RetryConfig{}
+	if s.Retry != nil {
+		config = *s.Retry
+	}
+
+	if config.MaxAttempts < 0 {
+		return nil, fmt.Errorf("invalid retry max-attempts %d, expected 1 or more", config.MaxAttempts)
+	}
+
+	if config.Delay < 0 {
+		return nil, fmt.Errorf("invalid retry delay %s, expected a positive duration", config.Delay)
+	}
+
+	policy := &RetryPolicy{
+		MaxAttempts: lo.Ternary(config.MaxAttempts > 0, config.MaxAttempts, defaultRetryMaxAttempts),
+		Delay:       lo.Ternary(config.Delay > 0, config.Delay, defaultRetryDelay),
+	}
+
+	for _, pattern := range append(TransientErrorPatterns, config.TransientErrors...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry transient-errors pattern '%s': %w", pattern, err)
+		}
+
+		policy.patterns = append(policy.patterns, re)
+	}
+
+	return policy, nil
+}
+
+// Transient - returns true if every message is a known transient error, along with the first matching message
+func (p *RetryPolicy) Transient(messages []string) (string, bool) {
+	if len(messages) == 0 {
+		return "", false
+	}
+
+	for _, message := range messages {
+		_, matched := lo.Find(p.patterns, func(re *regexp.Regexp) bool {
+			return re.MatchString(message)
+		})
+
+		if !matched {
+			return "", false
+		}
+	}
+
+	return messages[0], true
+}
+
+// Attributes - returns the retry policy in the form passed to providers, for providers that retry individual resources
+func (p *RetryPolicy) Attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"max-attempts": p.MaxAttempts,
+		"delay":        p.Delay.String(),
+		"transient-errors": lo.ToAnySlice(lo.Map(p.patterns, func(re *regexp.Regexp, _ int) string {
+			return re.String()
+		})),
+	}
+}
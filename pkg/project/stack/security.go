@@ -0,0 +1,78 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// ManagedRuleSets - provider neutral names of managed WAF rule sets, translated by providers to their equivalent
+// AWS WAF managed rule groups, Google Cloud Armor preconfigured rules or Azure Front Door managed rule sets
+var ManagedRuleSets = []string{
+	"core",
+	"sql-injection",
+	"cross-site-scripting",
+	"known-bad-inputs",
+	"ip-reputation",
+	"bot-control",
+}
+
+// ApiSecurityConfig - network security policy for a deployed API
+type ApiSecurityConfig struct {
+	// IP addresses or CIDR ranges allowed to access the API, all addresses are allowed if empty
+	IpAllowlist []string `yaml:"ip-allowlist,omitempty"`
+	// Managed WAF rule sets applied to the API
+	ManagedRuleSets []string `yaml:"managed-rule-sets,omitempty"`
+}
+
+// ValidateSecurity - validates the API security policies of a stack
+func (s *StackConfig[T]) ValidateSecurity() error {
+	for apiName, security := range s.Security {
+		for _, entry := range security.IpAllowlist {
+			if net.ParseIP(entry) != nil {
+				continue
+			}
+
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("invalid ip-allowlist entry '%s' for api %s, expected an IP address or CIDR range", entry, apiName)
+			}
+		}
+
+		for _, ruleSet := range security.ManagedRuleSets {
+			if !slices.Contains(ManagedRuleSets, ruleSet) {
+				return fmt.Errorf("unknown managed rule set '%s' for api %s, expected one of %s", ruleSet, apiName, strings.Join(ManagedRuleSets, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// SecurityAttributes - returns the API security policies of a stack in the form passed to providers
+func (s *StackConfig[T]) SecurityAttributes() map[string]interface{} {
+	return lo.MapValues(s.Security, func(security ApiSecurityConfig, _ string) interface{} {
+		return map[string]interface{}{
+			"ip-allowlist":      lo.ToAnySlice(security.IpAllowlist),
+			"managed-rule-sets": lo.ToAnySlice(security.ManagedRuleSets),
+		}
+	})
+}
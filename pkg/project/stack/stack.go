@@ -17,20 +17,61 @@
 package stack
 
 import (
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/exitcode"
+	"github.com/nitrictech/cli/pkg/preferences"
+	"github.com/nitrictech/cli/pkg/provider"
 )
 
 type StackConfig[T any] struct {
-	Name     string `yaml:-`
+	Name     string `yaml:"-"`
 	Provider string `yaml:"provider"`
-	Config   T      `yaml:",inline"`
+	// The primary region the stack is deployed to
+	Region string `yaml:"region,omitempty"`
+	// All regions the stack is deployed to, for multi-region stacks
+	Regions []string `yaml:"regions,omitempty"`
+	// Rules placing resources in a subset of the stack's regions, resources not matching a rule are deployed to the primary region
+	Placement []PlacementRule `yaml:"placement,omitempty"`
+	// Feature flags for this stack, these override flags of the same name in nitric.yaml
+	Flags map[string]string `yaml:"flags,omitempty"`
+	// Additional environment variables forwarded to the provider, patterns ending in * match by prefix
+	ForwardEnv []string `yaml:"forward-env,omitempty"`
+	// Existing resource state to reuse for renamed resources, keyed by <type>/<new name> with the previous name as the value
+	// Deploying fails unless the stack's provider applies the aliases setting, as renamed resources would be recreated
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// Resources that are kept in the cloud when they're no longer declared, as <type>/<name> patterns, e.g. bucket/* or sqldatabase/main
+	// Deploying fails when protected resources would be removed unless the stack's provider applies the retain setting
+	Protect []string `yaml:"protect,omitempty"`
+	// Network security policies for deployed APIs, keyed by API name
+	Security map[string]ApiSecurityConfig `yaml:"security,omitempty"`
+	// How deployments failing with transient provider errors are retried
+	Retry *RetryConfig `yaml:"retry,omitempty"`
+	// Re-apply the last successful deployment when a deployment fails, rather than leaving the stack partially deployed
+	RollbackOnFailure bool `yaml:"rollback-on-failure,omitempty"`
+	// Budget alerts and alarms provisioned with the stack
+	Monitoring *MonitoringConfig `yaml:"monitoring,omitempty"`
+	// Retention of logs from deployed services
+	Logs *LogsConfig `yaml:"logs,omitempty"`
+	// Customer managed encryption of logs and data resources
+	Encryption *EncryptionConfig `yaml:"encryption,omitempty"`
+	// How services send email, for projects configuring email in nitric.yaml
+	Email *EmailConfig `yaml:"email,omitempty"`
+	// Short-lived cloud credentials exchanged for the OIDC token of CI jobs, used when nitric runs in CI
+	Oidc   *OidcConfig `yaml:"oidc,omitempty"`
+	Config T           `yaml:",inline"`
 }
 
 //go:embed aws.config.yaml
@@ -42,12 +83,64 @@ var awsTfConfigTemplate string
 //go:embed azure.config.yaml
 var azureConfigTemplate string
 
+//go:embed cloudflare.config.yaml
+var cloudflareConfigTemplate string
+
+//go:embed kubernetes.config.yaml
+var kubernetesConfigTemplate string
+
+//go:embed do.config.yaml
+var doConfigTemplate string
+
 //go:embed gcp.config.yaml
 var gcpConfigTemplate string
 
 //go:embed gcptf.config.yaml
 var gcpTfConfigTemplate string
 
+//go:embed serverless-aws.config.yaml
+var serverlessAwsPresetTemplate string
+
+//go:embed containers-gcp.config.yaml
+var containersGcpPresetTemplate string
+
+//go:embed budget-azure.config.yaml
+var budgetAzurePresetTemplate string
+
+// Preset - a stack template with its provider configuration pre-filled with sensible defaults
+type Preset struct {
+	Name string
+	// The provider used by the preset, as accepted by NewStackFile
+	Provider    string
+	Description string
+	template    string
+}
+
+var Presets = []Preset{
+	{Name: "serverless-aws", Provider: "aws", Description: "AWS Lambda in us-east-1 with 512MB of memory and a 15s timeout", template: serverlessAwsPresetTemplate},
+	{Name: "containers-gcp", Provider: "gcp", Description: "Google Cloud Run in us-central1 with 1GB of memory, scaling from 0 to 20 instances", template: containersGcpPresetTemplate},
+	{Name: "budget-azure", Provider: "azure", Description: "Azure Container Apps in eastus with 1/4 vCPU and 0.5GB of memory, scaling from 0 to 3 replicas", template: budgetAzurePresetTemplate},
+}
+
+// GetPreset returns the preset with the given name
+func GetPreset(name string) (Preset, error) {
+	preset, ok := lo.Find(Presets, func(p Preset) bool {
+		return p.Name == name
+	})
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown stack template '%s', available templates are: %s", name, strings.Join(PresetNames(), ", "))
+	}
+
+	return preset, nil
+}
+
+// PresetNames returns the names of all available presets
+func PresetNames() []string {
+	return lo.Map(Presets, func(p Preset, _ int) string {
+		return p.Name
+	})
+}
+
 var fileNameRegex = regexp.MustCompile(`(?i)^nitric\.(\S+)\.ya?ml$`)
 
 func IsValidFileName(stackName string) bool {
@@ -72,14 +165,97 @@ func NewStackFile(fs afero.Fs, providerName string, stackName string, dir string
 		template = awsTfConfigTemplate
 	case "gcp-tf":
 		template = gcpTfConfigTemplate
+	case "do":
+		template = doConfigTemplate
+	case "cloudflare":
+		template = cloudflareConfigTemplate
+	case "kubernetes":
+		template = kubernetesConfigTemplate
 	}
 
+	return writeStackFile(fs, template, stackName, dir)
+}
+
+// NewStackFileFromPreset creates a new stack file using a preset template
+func NewStackFileFromPreset(fs afero.Fs, presetName string, stackName string, dir string) (string, error) {
+	preset, err := GetPreset(presetName)
+	if err != nil {
+		return "", err
+	}
+
+	if dir == "" {
+		dir = "./"
+	}
+
+	return writeStackFile(fs, preset.template, stackName, dir)
+}
+
+var nitricProviderLine = regexp.MustCompile(`(?m)^provider: nitric/([a-z0-9-]+)@(\S+)$`)
+
+// applyProviderVersions replaces nitric provider versions in a stack template with any set in the preferences
+func applyProviderVersions(template string, versions map[string]string) string {
+	return nitricProviderLine.ReplaceAllStringFunc(template, func(line string) string {
+		match := nitricProviderLine.FindStringSubmatch(line)
+
+		if version, ok := versions[match[1]]; ok && version != "" {
+			return fmt.Sprintf("provider: nitric/%s@%s", match[1], version)
+		}
+
+		return line
+	})
+}
+
+var (
+	providerLine       = regexp.MustCompile(`(?m)^provider: (\S+)$`)
+	settingBlockMarker = regexp.MustCompile(`^# @setting (\S+)$`)
+)
+
+// applySettingBlocks - keeps the template blocks documenting a stack setting, marked with # @setting <setting> as their first
+// line, only when the template's provider applies the setting. Blocks end at the next blank line, so stack files don't
+// suggest settings that would fail or be ignored by their provider
+func applySettingBlocks(template string) string {
+	providerId := ""
+	if match := providerLine.FindStringSubmatch(template); match != nil {
+		providerId = match[1]
+	}
+
+	capabilities, known := provider.CapabilitiesOf(providerId)
+
+	lines := []string{}
+	skipping := false
+
+	for _, line := range strings.Split(template, "\n") {
+		if match := settingBlockMarker.FindStringSubmatch(line); match != nil {
+			skipping = !known || !capabilities.Applies(provider.Setting(match[1]))
+			continue
+		}
+
+		if skipping {
+			// the blank line after a removed block is dropped too, so blocks stay separated by a single blank line
+			skipping = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func writeStackFile(fs afero.Fs, template string, stackName string, dir string) (string, error) {
 	fileName := StackFileName(stackName)
 
 	if !IsValidFileName(fileName) {
 		return "", fmt.Errorf("requested stack name '%s' is invalid", stackName)
 	}
 
+	prefs, err := preferences.Current()
+	if err != nil {
+		return "", err
+	}
+
+	template = applySettingBlocks(applyProviderVersions(template, prefs.Providers))
+
 	stackFilePath := filepath.Join(dir, fileName)
 	relativePath, _ := filepath.Rel(".", stackFilePath)
 
@@ -95,10 +271,184 @@ func StackFileName(stackName string) string {
 func ConfigFromName[T any](fs afero.Fs, stackName string) (*StackConfig[T], error) {
 	stackFile := StackFileName(stackName)
 	if !IsValidFileName(stackFile) {
-		return nil, fmt.Errorf("stack name '%s' is invalid", stackName)
+		return nil, exitcode.Wrap(exitcode.Config, fmt.Errorf("stack name '%s' is invalid", stackName))
 	}
 
-	return configFromFile[T](fs, filepath.Join("./", stackFile))
+	config, err := configFromFile[T](fs, filepath.Join("./", stackFile))
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Config, err)
+	}
+
+	return config, nil
+}
+
+// ConfigHash - returns a hash of the contents of a stack file
+func ConfigHash(fs afero.Fs, stackName string) (string, error) {
+	contents, err := afero.ReadFile(fs, filepath.Join("./", StackFileName(stackName)))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(contents)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readStackDocument - reads a stack file as a yaml document, so it can be modified without losing comments
+func readStackDocument(fs afero.Fs, stackName string) (*yaml.Node, error) {
+	stackFilePath := filepath.Join("./", StackFileName(stackName))
+
+	contents, err := afero.ReadFile(fs, stackFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, doc); err != nil {
+		return nil, fmt.Errorf("unable to parse stack file '%s': %w", stackFilePath, err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("stack file '%s' is not a yaml mapping", stackFilePath)
+	}
+
+	return doc, nil
+}
+
+func writeStackDocument(fs afero.Fs, stackName string, doc *yaml.Node) error {
+	var out strings.Builder
+
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, filepath.Join("./", StackFileName(stackName)), []byte(out.String()), os.ModePerm)
+}
+
+// AddAliases - adds resource aliases to a stack file, preserving the file's existing content and comments
+func AddAliases(fs afero.Fs, stackName string, aliases map[string]string) error {
+	doc, err := readStackDocument(fs, stackName)
+	if err != nil {
+		return err
+	}
+
+	root := doc.Content[0]
+
+	var aliasesNode *yaml.Node
+
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value == "aliases" {
+			aliasesNode = root.Content[i+1]
+		}
+	}
+
+	if aliasesNode == nil || aliasesNode.Kind != yaml.MappingNode {
+		aliasesNode = &yaml.Node{Kind: yaml.MappingNode}
+		root.Content = append(root.Content, &yaml.Node{
+			Kind:        yaml.ScalarNode,
+			Value:       "aliases",
+			HeadComment: "# Existing resource state reused by renamed resources, keyed by <type>/<new name>\n# Deploying fails unless the provider applies the aliases setting, see nitric provider capabilities",
+		}, aliasesNode)
+	}
+
+	keys := lo.Keys(aliases)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		updated := false
+
+		for i := 0; i < len(aliasesNode.Content)-1; i += 2 {
+			if aliasesNode.Content[i].Value == key {
+				aliasesNode.Content[i+1].Value = aliases[key]
+				updated = true
+			}
+		}
+
+		if updated {
+			continue
+		}
+
+		aliasesNode.Content = append(aliasesNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: aliases[key]},
+		)
+	}
+
+	return writeStackDocument(fs, stackName, doc)
+}
+
+var secretKeyPattern = regexp.MustCompile(`(?i)(secret|password|passwd|token|api[-_]?key|private[-_]?key|credential)`)
+
+// IsSecretKey - returns true if a stack config key is likely to hold a secret value
+func IsSecretKey(key string) bool {
+	return secretKeyPattern.MatchString(key)
+}
+
+// CloneStackFile - copies a stack file to a new stack, secret values are replaced with the result of secretValue,
+// which is called with the dot separated path of each secret key, e.g. config.default.api-key
+func CloneStackFile(fs afero.Fs, fromStack string, toStack string, secretValue func(keyPath string) string) (string, error) {
+	toFileName := StackFileName(toStack)
+	if !IsValidFileName(toFileName) {
+		return "", fmt.Errorf("requested stack name '%s' is invalid", toStack)
+	}
+
+	doc, err := readStackDocument(fs, fromStack)
+	if err != nil {
+		return "", err
+	}
+
+	root := doc.Content[0]
+
+	// aliases map renamed resources to the source stack's existing state, which doesn't exist for the new stack
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value == "aliases" {
+			root.Content = slices.Delete(root.Content, i, i+2)
+			break
+		}
+	}
+
+	replaceSecrets(root, "", secretValue)
+
+	if err := writeStackDocument(fs, toStack, doc); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(".%s%s", string(os.PathSeparator), toFileName), nil
+}
+
+func replaceSecrets(node *yaml.Node, path string, secretValue func(keyPath string) string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+
+			if value.Kind == yaml.ScalarNode && IsSecretKey(key.Value) {
+				value.Value = secretValue(keyPath)
+				value.Tag = "!!str"
+				value.Style = 0
+
+				if value.Value == "" {
+					value.Tag = "!!null"
+				}
+
+				continue
+			}
+
+			replaceSecrets(value, keyPath, secretValue)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			replaceSecrets(item, fmt.Sprintf("%s[%d]", path, i), secretValue)
+		}
+	}
 }
 
 // GetAllStackFiles returns a list of all stack files in the current directory
@@ -166,16 +516,28 @@ func configFromFile[T any](fs afero.Fs, filePath string) (*StackConfig[T], error
 		return nil, err
 	}
 
-	stackConfig := &StackConfig[T]{}
+	stackName, err := GetStackNameFromFileName(filePath)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := yaml.Unmarshal(stackFileContents, stackConfig); err != nil {
+	stackConfig, err := ParseConfig[T](stackName, stackFileContents)
+	if err != nil {
 		return nil, fmt.Errorf("unable to parse stack file '%s': %w", filePath, err)
 	}
 
-	stackConfig.Name, err = GetStackNameFromFileName(filePath)
-	if err != nil {
+	return stackConfig, nil
+}
+
+// ParseConfig returns a stack configuration from the contents of a stack file
+func ParseConfig[T any](stackName string, contents []byte) (*StackConfig[T], error) {
+	stackConfig := &StackConfig[T]{}
+
+	if err := yaml.Unmarshal(contents, stackConfig); err != nil {
 		return nil, err
 	}
 
+	stackConfig.Name = stackName
+
 	return stackConfig, nil
 }
@@ -0,0 +1,113 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplySettingBlocks(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name: "kept when the provider applies the setting",
+			template: `provider: noop
+
+# @setting security
+# # Network security policies
+# security: {}
+
+# retry: {}`,
+			expected: `provider: noop
+
+# # Network security policies
+# security: {}
+
+# retry: {}`,
+		},
+		{
+			name: "removed when the provider doesn't apply the setting",
+			template: `provider: nitric/aws@1.11.6
+
+# @setting security
+# # Network security policies
+# security: {}
+
+# retry: {}`,
+			expected: `provider: nitric/aws@1.11.6
+
+# retry: {}`,
+		},
+		{
+			name: "removed when the provider's capabilities aren't known",
+			template: `provider: acme/edge
+
+# @setting security
+# security: {}
+`,
+			expected: `provider: acme/edge
+`,
+		},
+		{
+			name: "removed at the end of the template",
+			template: `provider: nitric/gcp@1.11.6
+# @setting encryption
+# encryption: {}`,
+			expected: `provider: nitric/gcp@1.11.6`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, applySettingBlocks(tt.template)); diff != "" {
+				t.Errorf("unexpected template (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// stack files of each template must still parse once their setting blocks are applied
+func TestTemplatesParse(t *testing.T) {
+	for name, template := range map[string]string{
+		"aws":        awsConfigTemplate,
+		"awstf":      awsTfConfigTemplate,
+		"azure":      azureConfigTemplate,
+		"gcp":        gcpConfigTemplate,
+		"gcptf":      gcpTfConfigTemplate,
+		"cloudflare": cloudflareConfigTemplate,
+		"do":         doConfigTemplate,
+		"kubernetes": kubernetesConfigTemplate,
+	} {
+		t.Run(name, func(t *testing.T) {
+			applied := applySettingBlocks(template)
+
+			if strings.Contains(applied, "# @setting") {
+				t.Errorf("setting markers weren't removed")
+			}
+
+			config := StackConfig[map[string]any]{}
+			if err := yaml.Unmarshal([]byte(applied), &config); err != nil {
+				t.Errorf("unable to parse template: %s", err)
+			}
+		})
+	}
+}
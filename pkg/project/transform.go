@@ -0,0 +1,87 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// SpecTransformerConfiguration - a command transforming the deployment spec of a project between collection and deployment,
+// e.g. to add resources mandated by an organization or rewrite resource names
+type SpecTransformerConfiguration struct {
+	// Command run from the project directory, receiving the spec as JSON on stdin and writing the transformed spec as JSON to stdout
+	Command string `yaml:"command"`
+}
+
+func (p ProjectConfiguration) transformers() ([]SpecTransformerConfiguration, error) {
+	for i, transformer := range p.Transformers {
+		if strings.TrimSpace(transformer.Command) == "" {
+			return nil, fmt.Errorf("invalid transformer %d: a command is required", i+1)
+		}
+	}
+
+	return p.Transformers, nil
+}
+
+// TransformSpec - runs the project's transformers in order, each receiving the spec returned by the previous one.
+// Transformers are given the project and stack names in the NITRIC_PROJECT and NITRIC_STACK environment variables.
+func (p *Project) TransformSpec(spec *deploymentspb.Spec, stackName string) (*deploymentspb.Spec, error) {
+	for _, transformer := range p.Transformers {
+		input, err := protojson.Marshal(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		commandParts := strings.Fields(transformer.Command)
+
+		cmd := exec.Command(commandParts[0], commandParts[1:]...)
+		cmd.Dir = p.Directory
+		cmd.Env = append(os.Environ(), "NITRIC_PROJECT="+p.Name, "NITRIC_STACK="+stackName)
+		cmd.Stdin = bytes.NewReader(input)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			if output := strings.TrimSpace(stderr.String()); output != "" {
+				return nil, fmt.Errorf("transformer %s failed: %w\n%s", transformer.Command, err, output)
+			}
+
+			return nil, fmt.Errorf("transformer %s failed: %w", transformer.Command, err)
+		}
+
+		transformed := &deploymentspb.Spec{}
+
+		err = protojson.Unmarshal(stdout.Bytes(), transformed)
+		if err != nil {
+			return nil, fmt.Errorf("transformer %s didn't write a valid spec: %w", transformer.Command, err)
+		}
+
+		spec = transformed
+	}
+
+	return spec, nil
+}
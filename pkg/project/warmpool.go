@@ -0,0 +1,134 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/localenv"
+)
+
+// Docker labels added to service containers kept in the warm pool, stopped pooled containers are restarted by later runs
+// rather than recreated, as long as their image and configuration are unchanged
+const (
+	warmPoolLabel           = "io.nitric.local.warm-pool"
+	warmPoolKeyLabel        = "io.nitric.local.warm-pool.key"
+	warmPoolNitricPortLabel = "io.nitric.local.warm-pool.nitric-port"
+	warmPoolProxyPortLabel  = "io.nitric.local.warm-pool.proxy-port"
+)
+
+// warmContainerPorts - returns the nitric server and proxy ports of a pooled container, services listen on the same
+// ports when they're free so their pooled containers can be reused
+func warmContainerPorts(dockerClient *docker.Docker, containerName string) (int, int, bool) {
+	inspect, err := dockerClient.ContainerInspect(context.Background(), containerName)
+	if err != nil || inspect.Config == nil || inspect.Config.Labels[warmPoolLabel] != "true" {
+		return 0, 0, false
+	}
+
+	nitricPort, err := strconv.Atoi(inspect.Config.Labels[warmPoolNitricPortLabel])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	proxyPort, err := strconv.Atoi(inspect.Config.Labels[warmPoolProxyPortLabel])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return nitricPort, proxyPort, true
+}
+
+// warmPoolKey - hashes the image and configuration of a service container, pooled containers are only reused when their key matches
+func warmPoolKey(imageId string, config *container.Config, hostConfig *container.HostConfig) (string, error) {
+	env := slices.Clone(config.Env)
+	slices.Sort(env)
+
+	key, err := json.Marshal(map[string]interface{}{
+		"image":        imageId,
+		"env":          env,
+		"labels":       config.Labels,
+		"exposedPorts": config.ExposedPorts,
+		"networkMode":  hostConfig.NetworkMode,
+		"portBindings": hostConfig.PortBindings,
+		"extraHosts":   hostConfig.ExtraHosts,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(key)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// reuseWarmContainer - returns the ID of the stopped pooled container with the name when its key matches,
+// pooled containers that no longer match are removed so the name can be reused by a new container
+func reuseWarmContainer(dockerClient *docker.Docker, containerName string, key string) (string, bool, error) {
+	inspect, err := dockerClient.ContainerInspect(context.Background(), containerName)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	// containers that weren't created by the warm pool are left alone
+	if inspect.Config == nil || inspect.Config.Labels[warmPoolLabel] != "true" || inspect.State == nil || inspect.State.Running {
+		return "", false, nil
+	}
+
+	if key != "" && inspect.Config.Labels[warmPoolKeyLabel] == key {
+		return inspect.ID, true, nil
+	}
+
+	err = dockerClient.ContainerRemove(context.Background(), inspect.ID, container.RemoveOptions{Force: true})
+	if err != nil {
+		return "", false, fmt.Errorf("unable to remove pooled container %s: %w", containerName, err)
+	}
+
+	return "", false, nil
+}
+
+// ClearWarmPool - removes the pooled service containers of the project in the directory
+func ClearWarmPool(projectDir string) error {
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		return err
+	}
+
+	return dockerClient.RemoveByLabel(map[string]string{
+		warmPoolLabel:         "true",
+		localenv.ProjectLabel: dir,
+	})
+}
@@ -0,0 +1,224 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// changes are collected for a short time before services are restarted, so saving several files restarts services once
+const watchDebounce = 300 * time.Millisecond
+
+type watchedService struct {
+	name    string
+	baseDir string
+	ignores []string
+	restart chan struct{}
+}
+
+// serviceWatcher - watches the build context of each service, restarting a service when a file it doesn't ignore changes.
+// The ignores of each service include the runtime's ignores and the entrypoints of other services, so editing an entrypoint only restarts its service
+type serviceWatcher struct {
+	watcher  *fsnotify.Watcher
+	services []*watchedService
+	updates  chan<- ServiceRunUpdate
+
+	pendingLock sync.Mutex
+	pending     map[*watchedService]bool
+	timer       *time.Timer
+}
+
+func newServiceWatcher(services []Service, updates chan<- ServiceRunUpdate) (*serviceWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch service files: %w", err)
+	}
+
+	w := &serviceWatcher{
+		watcher: watcher,
+		updates: updates,
+		pending: map[*watchedService]bool{},
+	}
+
+	for _, svc := range services {
+		baseDir, err := filepath.Abs(svc.buildContext.BaseDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		w.services = append(w.services, &watchedService{
+			name:    svc.Name,
+			baseDir: baseDir,
+			ignores: strings.Split(svc.buildContext.IgnoreFileContents, "\n"),
+			restart: make(chan struct{}, 1),
+		})
+	}
+
+	for _, svc := range w.services {
+		if err := w.addDirectory(svc.baseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	go w.watch()
+
+	return w, nil
+}
+
+// restarts - signals when the named service should be restarted
+func (w *serviceWatcher) restarts(serviceName string) <-chan struct{} {
+	for _, svc := range w.services {
+		if svc.name == serviceName {
+			return svc.restart
+		}
+	}
+
+	return nil
+}
+
+// affected - returns the services that haven't ignored the file
+func (w *serviceWatcher) affected(path string) []*watchedService {
+	affected := []*watchedService{}
+
+	for _, svc := range w.services {
+		relPath, err := filepath.Rel(svc.baseDir, path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+
+		if !isIgnored(relPath, svc.ignores) {
+			affected = append(affected, svc)
+		}
+	}
+
+	return affected
+}
+
+// includesWithin - whether a service's ! patterns could include files within an ignored directory
+func (w *serviceWatcher) includesWithin(dir string) bool {
+	for _, svc := range w.services {
+		relDir, err := filepath.Rel(svc.baseDir, dir)
+		if err == nil && !strings.HasPrefix(relDir, "..") && includesWithin(relDir, svc.ignores) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addDirectory - watches the directory and its subdirectories, skipping directories ignored by every service
+func (w *serviceWatcher) addDirectory(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// directories can be removed while they're being walked
+			return nil
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != dir && len(w.affected(path)) == 0 && !w.includesWithin(path) {
+			return filepath.SkipDir
+		}
+
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("unable to watch %s: %w", path, err)
+		}
+
+		return nil
+	})
+}
+
+func (w *serviceWatcher) watch() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			// watch directories created after the watcher started
+			if event.Op.Has(fsnotify.Create) {
+				_ = w.addDirectory(event.Name)
+			}
+
+			w.changed(event.Name)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.updates <- ServiceRunUpdate{
+				ServiceName: "nitric",
+				Label:       "nitric",
+				Status:      ServiceRunStatus_Error,
+				Message:     fmt.Sprintf("error watching service files: %v", err),
+			}
+		}
+	}
+}
+
+func (w *serviceWatcher) changed(path string) {
+	affected := w.affected(path)
+	if len(affected) == 0 {
+		return
+	}
+
+	w.pendingLock.Lock()
+	defer w.pendingLock.Unlock()
+
+	for _, svc := range affected {
+		w.pending[svc] = true
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.timer = time.AfterFunc(watchDebounce, w.flush)
+}
+
+func (w *serviceWatcher) flush() {
+	w.pendingLock.Lock()
+	defer w.pendingLock.Unlock()
+
+	for svc := range w.pending {
+		// a restart that's already pending covers this change
+		select {
+		case svc.restart <- struct{}{}:
+		default:
+		}
+	}
+
+	w.pending = map[*watchedService]bool{}
+}
+
+func (w *serviceWatcher) Close() error {
+	return w.watcher.Close()
+}
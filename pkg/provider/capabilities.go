@@ -0,0 +1,340 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// Feature - a kind of nitric resource, or a feature of one, that a provider may be able to deploy
+type Feature string
+
+const (
+	Feature_Apis                Feature = "apis"
+	Feature_HttpProxies         Feature = "http-proxies"
+	Feature_Websockets          Feature = "websockets"
+	Feature_Schedules           Feature = "schedules"
+	Feature_Topics              Feature = "topics"
+	Feature_Queues              Feature = "queues"
+	Feature_Buckets             Feature = "buckets"
+	Feature_BucketNotifications Feature = "bucket-notifications"
+	Feature_KeyValueStores      Feature = "kv-stores"
+	Feature_Secrets             Feature = "secrets"
+	Feature_SqlDatabases        Feature = "sql-databases"
+)
+
+// Features - every feature, in the order they're listed by nitric provider capabilities
+var Features = []Feature{
+	Feature_Apis,
+	Feature_HttpProxies,
+	Feature_Websockets,
+	Feature_Schedules,
+	Feature_Topics,
+	Feature_Queues,
+	Feature_Buckets,
+	Feature_BucketNotifications,
+	Feature_KeyValueStores,
+	Feature_Secrets,
+	Feature_SqlDatabases,
+}
+
+// Setting - a setting of a stack file passed to providers as a deployment attribute, e.g. the security policies of APIs
+type Setting string
+
+const (
+	Setting_Security       Setting = "security"
+	Setting_Aliases        Setting = "aliases"
+	Setting_Retain         Setting = "retain"
+	Setting_ApiKeyRequired Setting = "api-key-required"
+	Setting_ApiRateLimits  Setting = "api-rate-limits"
+	Setting_Placement      Setting = "placement"
+	Setting_Monitoring     Setting = "monitoring"
+	Setting_LogRetention   Setting = "log-retention-days"
+	Setting_Encryption     Setting = "encryption"
+	Setting_Email          Setting = "email"
+)
+
+// Settings - every setting, in the order they're listed by nitric provider capabilities
+var Settings = []Setting{
+	Setting_Security,
+	Setting_Aliases,
+	Setting_Retain,
+	Setting_ApiKeyRequired,
+	Setting_ApiRateLimits,
+	Setting_Placement,
+	Setting_Monitoring,
+	Setting_LogRetention,
+	Setting_Encryption,
+	Setting_Email,
+}
+
+// enforcedSettings - settings that leave resources open, delete data or break compliance requirements when they're ignored,
+// deployments fail rather than warn when the stack's provider doesn't apply them
+var enforcedSettings = []Setting{
+	Setting_Security,
+	Setting_Aliases,
+	Setting_Retain,
+	Setting_ApiKeyRequired,
+	Setting_Encryption,
+}
+
+// Capabilities - the features a provider can deploy, services and policies are deployed by every provider
+type Capabilities struct {
+	Provider  string    `json:"provider"`
+	Supported []Feature `json:"supported"`
+	// Stack settings the provider applies, other settings are passed to the provider but ignored by it
+	Settings []Setting `json:"settings"`
+	// Shortest interval between the runs of a schedule, e.g. 1m, empty when schedules aren't supported
+	ScheduleGranularity string `json:"scheduleGranularity,omitempty"`
+	// True when the provider writes configuration that's applied with other tools, e.g. terraform, rather than deploying the stack
+	GenerateOnly bool `json:"generateOnly,omitempty"`
+}
+
+// Supports - returns true if the provider can deploy the feature
+func (c Capabilities) Supports(feature Feature) bool {
+	return slices.Contains(c.Supported, feature)
+}
+
+// Applies - returns true if the provider applies the stack setting
+func (c Capabilities) Applies(setting Setting) bool {
+	return slices.Contains(c.Settings, setting)
+}
+
+func without(features ...Feature) []Feature {
+	return slices.DeleteFunc(slices.Clone(Features), func(feature Feature) bool {
+		return slices.Contains(features, feature)
+	})
+}
+
+const kubernetesCapabilitiesKey = "kubernetes"
+
+// knownCapabilities - the capabilities of the nitric providers and the providers built into the CLI, keyed by provider name without its version
+var knownCapabilities = map[string]Capabilities{
+	"nitric/aws": {
+		Supported:           Features,
+		ScheduleGranularity: "1m",
+	},
+	"nitric/gcp": {
+		Supported:           without(Feature_Websockets),
+		ScheduleGranularity: "1m",
+	},
+	"nitric/azure": {
+		Supported:           without(Feature_Websockets, Feature_SqlDatabases),
+		ScheduleGranularity: "1m",
+	},
+	// deployments are simulated, so every setting is applied
+	NoopProviderId: {
+		Supported:           Features,
+		Settings:            Settings,
+		ScheduleGranularity: "1m",
+	},
+	CloudflareProviderId: {
+		Supported:           []Feature{Feature_Apis, Feature_HttpProxies, Feature_Schedules, Feature_Queues, Feature_Buckets, Feature_KeyValueStores},
+		ScheduleGranularity: "1m",
+		GenerateOnly:        true,
+	},
+	// shared by the kubernetes providers of each cluster tool, e.g. kubernetes/kind
+	kubernetesCapabilitiesKey: {
+		Supported:           []Feature{Feature_Apis, Feature_HttpProxies, Feature_Schedules},
+		ScheduleGranularity: "1m",
+	},
+	TerraformProviderPrefix + "aws": {
+		Supported:           []Feature{Feature_Apis, Feature_Schedules, Feature_Topics, Feature_Queues, Feature_Buckets, Feature_BucketNotifications, Feature_KeyValueStores, Feature_Secrets},
+		Settings:            []Setting{Setting_Email},
+		ScheduleGranularity: "1m",
+		GenerateOnly:        true,
+	},
+	TerraformProviderPrefix + "do": {
+		Supported:    []Feature{Feature_Apis, Feature_HttpProxies, Feature_Buckets, Feature_SqlDatabases},
+		GenerateOnly: true,
+	},
+}
+
+// KnownProviders - returns the names of the providers with known capabilities
+func KnownProviders() []string {
+	names := []string{}
+
+	for name := range knownCapabilities {
+		if name == kubernetesCapabilitiesKey {
+			for tool := range clusterTools {
+				names = append(names, KubernetesProviderPrefix+tool)
+			}
+
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// CapabilitiesOf - returns the capabilities of a provider, e.g. nitric/aws or nitric/aws@1.11.6.
+// The capabilities of provider plugins and docker providers aren't known, false is returned for them
+func CapabilitiesOf(providerId string) (Capabilities, bool) {
+	name, _, _ := strings.Cut(providerId, "@")
+
+	key := name
+	if _, ok := clusterTools[strings.TrimPrefix(name, KubernetesProviderPrefix)]; ok && strings.HasPrefix(name, KubernetesProviderPrefix) {
+		key = kubernetesCapabilitiesKey
+	}
+
+	capabilities, ok := knownCapabilities[key]
+	if !ok {
+		return Capabilities{}, false
+	}
+
+	capabilities.Provider = name
+	capabilities.Settings = lo.Ternary(capabilities.Settings != nil, capabilities.Settings, []Setting{})
+
+	return capabilities, true
+}
+
+// UnsupportedResource - a resource in a deployment spec that uses a feature its provider can't deploy
+type UnsupportedResource struct {
+	// The type and name of the resource, e.g. websocket/chat
+	Resource string  `json:"resource"`
+	Feature  Feature `json:"feature"`
+	Reason   string  `json:"reason"`
+}
+
+// resourceFeatures - returns the features a resource of a spec uses
+func resourceFeatures(res *deploymentspb.Resource) []Feature {
+	switch config := res.Config.(type) {
+	case *deploymentspb.Resource_Api:
+		return []Feature{Feature_Apis}
+	case *deploymentspb.Resource_Http:
+		return []Feature{Feature_HttpProxies}
+	case *deploymentspb.Resource_Websocket:
+		return []Feature{Feature_Websockets}
+	case *deploymentspb.Resource_Schedule:
+		return []Feature{Feature_Schedules}
+	case *deploymentspb.Resource_Topic:
+		return []Feature{Feature_Topics}
+	case *deploymentspb.Resource_Queue:
+		return []Feature{Feature_Queues}
+	case *deploymentspb.Resource_Bucket:
+		if len(config.Bucket.GetListeners()) > 0 {
+			return []Feature{Feature_Buckets, Feature_BucketNotifications}
+		}
+
+		return []Feature{Feature_Buckets}
+	case *deploymentspb.Resource_KeyValueStore:
+		return []Feature{Feature_KeyValueStores}
+	case *deploymentspb.Resource_Secret:
+		return []Feature{Feature_Secrets}
+	case *deploymentspb.Resource_SqlDatabase:
+		return []Feature{Feature_SqlDatabases}
+	default:
+		return []Feature{}
+	}
+}
+
+// Check - returns the resources of the spec that the provider can't deploy
+func (c Capabilities) Check(spec *deploymentspb.Spec) []UnsupportedResource {
+	unsupported := []UnsupportedResource{}
+
+	for _, res := range spec.GetResources() {
+		resource := fmt.Sprintf("%s/%s", strings.ToLower(res.GetId().GetType().String()), res.GetId().GetName())
+
+		for _, feature := range resourceFeatures(res) {
+			if !c.Supports(feature) {
+				unsupported = append(unsupported, UnsupportedResource{
+					Resource: resource,
+					Feature:  feature,
+					Reason:   fmt.Sprintf("%s are not supported by the %s provider", strings.ReplaceAll(string(feature), "-", " "), c.Provider),
+				})
+			}
+		}
+
+		if schedule := res.GetSchedule(); schedule != nil && c.Supports(Feature_Schedules) {
+			if reason := c.checkScheduleGranularity(schedule); reason != "" {
+				unsupported = append(unsupported, UnsupportedResource{
+					Resource: resource,
+					Feature:  Feature_Schedules,
+					Reason:   reason,
+				})
+			}
+		}
+	}
+
+	return unsupported
+}
+
+// checkScheduleGranularity - returns why the schedule runs more often than the provider can run it, or an empty string if it doesn't.
+// Cron expressions with a seconds field run more often than every minute
+func (c Capabilities) checkScheduleGranularity(schedule *deploymentspb.Schedule) string {
+	granularity, err := time.ParseDuration(c.ScheduleGranularity)
+	if err != nil || granularity < time.Minute {
+		return ""
+	}
+
+	if cron := schedule.GetCron(); cron != nil && len(strings.Fields(cron.GetExpression())) > 5 {
+		return fmt.Sprintf("cron expression %s has a seconds field, the %s provider runs schedules at most once every %s", cron.GetExpression(), c.Provider, c.ScheduleGranularity)
+	}
+
+	return ""
+}
+
+// UnappliedSetting - a setting of a stack that its provider doesn't apply, or may not apply when its capabilities aren't known
+type UnappliedSetting struct {
+	Setting Setting `json:"setting"`
+	// True when the deployment must fail rather than deploy without the setting
+	Enforced bool   `json:"enforced"`
+	Reason   string `json:"reason"`
+}
+
+// CheckSettings - returns the settings of a stack that its provider doesn't apply.
+// Settings are only enforced for providers with known capabilities, providers with unknown capabilities may apply them
+func CheckSettings(providerId string, settings []Setting) []UnappliedSetting {
+	capabilities, known := CapabilitiesOf(providerId)
+	unapplied := []UnappliedSetting{}
+
+	for _, setting := range settings {
+		if known && capabilities.Applies(setting) {
+			continue
+		}
+
+		if !known {
+			name, _, _ := strings.Cut(providerId, "@")
+
+			unapplied = append(unapplied, UnappliedSetting{
+				Setting: setting,
+				Reason:  fmt.Sprintf("the capabilities of %s aren't known, it may ignore the %s setting", name, setting),
+			})
+
+			continue
+		}
+
+		unapplied = append(unapplied, UnappliedSetting{
+			Setting:  setting,
+			Enforced: slices.Contains(enforcedSettings, setting),
+			Reason:   fmt.Sprintf("the %s provider doesn't apply the %s setting", capabilities.Provider, setting),
+		})
+	}
+
+	return unapplied
+}
@@ -0,0 +1,218 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc"
+
+	"github.com/nitrictech/cli/pkg/provider/cloudflare"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// CloudflareProviderId - the provider generating a wrangler project that deploys the stack to Cloudflare Workers
+const CloudflareProviderId = "cloudflare"
+
+// CloudflareProvider - generates a wrangler project deploying the project's services as Cloudflare Containers behind a worker,
+// with buckets, key value stores and queues deployed as R2 buckets, KV namespaces and Cloudflare Queues. The project is written
+// to cloudflare/<stack> in the project directory, or the output-dir set in the stack file, and deployed with wrangler.
+type CloudflareProvider struct {
+	deploymentspb.UnimplementedDeploymentServer
+
+	projectDir string
+	fs         afero.Fs
+	server     *grpc.Server
+}
+
+var _ Provider = (*CloudflareProvider)(nil)
+
+func NewCloudflareProvider(projectDir string, fs afero.Fs) *CloudflareProvider {
+	return &CloudflareProvider{
+		projectDir: projectDir,
+		fs:         fs,
+	}
+}
+
+func (c *CloudflareProvider) Install() error {
+	return nil
+}
+
+func (c *CloudflareProvider) Start(opts *StartOptions) (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("unable to start the cloudflare provider: %w", err)
+	}
+
+	c.server = grpc.NewServer()
+	deploymentspb.RegisterDeploymentServer(c.server, c)
+
+	go func() {
+		_ = c.server.Serve(lis)
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+func (c *CloudflareProvider) Stop() error {
+	if c.server != nil {
+		c.server.GracefulStop()
+	}
+
+	return nil
+}
+
+// outputDir - the directory the wrangler project of a stack is written to
+func (c *CloudflareProvider) outputDir(attributes map[string]any, stackName string) string {
+	outputDir, _ := attributes["output-dir"].(string)
+	if outputDir == "" {
+		outputDir = filepath.Join("cloudflare", stackName)
+	}
+
+	if filepath.IsAbs(outputDir) {
+		return outputDir
+	}
+
+	return filepath.Join(c.projectDir, outputDir)
+}
+
+func (c *CloudflareProvider) Up(req *deploymentspb.DeploymentUpRequest, stream deploymentspb.Deployment_UpServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+	projectName, _ := attributes["project"].(string)
+	accountId, _ := attributes["account-id"].(string)
+	config, _ := attributes["config"].(map[string]any)
+	runtime, _ := attributes["runtime"].(string)
+
+	if runtime == "" {
+		return unpublishedRuntimeError("cloudflare", "Cloudflare")
+	}
+
+	outputDir := c.outputDir(attributes, stackName)
+
+	if err := c.fs.MkdirAll(filepath.Join(outputDir, "src"), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create cloudflare output directory %s: %w", outputDir, err)
+	}
+
+	runtime, err := buildContextRuntime(c.fs, c.projectDir, runtime, outputDir)
+	if err != nil {
+		return err
+	}
+
+	_, commands, err := serviceImages(req.Spec)
+	if err != nil {
+		return err
+	}
+
+	worker, unsupported, err := cloudflare.Synthesize(req.Spec, cloudflare.Options{
+		Project:   projectName,
+		Stack:     stackName,
+		AccountId: accountId,
+		Config:    config,
+		Runtime:   runtime,
+		Commands:  commands,
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, contents := range worker.Files {
+		mode := lo.Ternary[os.FileMode](strings.HasSuffix(name, ".sh"), 0o755, 0o644)
+
+		if err := afero.WriteFile(c.fs, filepath.Join(outputDir, name), contents, mode); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+	}
+
+	for _, res := range req.Spec.Resources {
+		if res.Id == nil {
+			continue
+		}
+
+		// the configuration is applied with other tools, so resources are left pending rather than reported as deployed
+		status := deploymentspb.ResourceDeploymentStatus_PENDING
+		message := "generated, not applied"
+
+		if u, ok := lo.Find(unsupported, func(u cloudflare.Unsupported) bool { return u.Id == res.Id }); ok {
+			status = deploymentspb.ResourceDeploymentStatus_FAILED
+			message = u.Reason
+		}
+
+		if err := sendUpUpdate(stream, res.Id, deploymentspb.ResourceDeploymentAction_CREATE, status, message); err != nil {
+			return err
+		}
+	}
+
+	relativeDir, err := filepath.Rel(c.projectDir, outputDir)
+	if err != nil {
+		relativeDir = outputDir
+	}
+
+	summary := []string{
+		fmt.Sprintf("Wrangler project for stack %s written to %s", stackName, relativeDir),
+		"No cloud resources have been changed, create the stack's resources and set the worker's CLOUDFLARE_API_TOKEN secret once, then deploy the worker with wrangler, e.g.",
+		fmt.Sprintf("  cd %s", relativeDir),
+		"  sh setup.sh",
+		"  npx wrangler deploy",
+		"Service images are built by wrangler, which requires docker and node",
+	}
+
+	if len(unsupported) > 0 {
+		summary = append(summary, fmt.Sprintf("%d resources are not supported by the cloudflare provider and were left out", len(unsupported)))
+	}
+
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Result{
+			Result: &deploymentspb.UpResult{
+				Success: len(unsupported) == 0,
+				Content: &deploymentspb.UpResult_Text{Text: strings.Join(summary, "\n")},
+			},
+		},
+	})
+}
+
+func (c *CloudflareProvider) Down(req *deploymentspb.DeploymentDownRequest, stream deploymentspb.Deployment_DownServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+
+	relativeDir, err := filepath.Rel(c.projectDir, c.outputDir(attributes, stackName))
+	if err != nil {
+		return err
+	}
+
+	// the worker and its resources are deployed by wrangler, which the CLI doesn't track
+	err = stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Message{
+			Message: fmt.Sprintf("stacks using the cloudflare provider are deleted with wrangler, run npx wrangler delete in %s and delete the resources created by its setup.sh", relativeDir),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Result{
+			Result: &deploymentspb.DownResult{},
+		},
+	})
+}
@@ -0,0 +1,543 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudflare
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+//go:embed worker.js.tmpl
+var workerTemplateSource string
+
+var workerTemplate = template.Must(template.New("worker").Parse(workerTemplateSource))
+
+type Options struct {
+	Project string
+	Stack   string
+	// ID of the Cloudflare account the worker and its resources are deployed to
+	AccountId string
+	// Deployment configuration keyed by service type, as set under config in the stack file, e.g. {"default": {"containers": {"max-instances": 5}}}
+	Config map[string]any
+	// URL of the nitric Cloudflare runtime, or the name of a file in the output directory, added to service images as their entrypoint
+	Runtime string
+	// Entrypoint and command of the service images, keyed by image URI, run by the runtime
+	Commands map[string][]string
+}
+
+// Unsupported - a resource in the spec that can't be deployed to Cloudflare
+type Unsupported struct {
+	Id     *resourcespb.ResourceIdentifier
+	Reason string
+}
+
+// Worker - a generated wrangler project, deploying the project's services as Cloudflare Containers behind a gateway worker
+type Worker struct {
+	// Files of the project keyed by their path relative to the project directory, e.g. wrangler.json and src/index.js
+	Files map[string][]byte
+	// Commands creating the R2 buckets, KV namespaces and queues used by the services, run once before the worker is first deployed
+	Setup []string
+}
+
+type route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Prefix  string `json:"prefix"`
+	Binding string `json:"binding"`
+}
+
+type schedule struct {
+	Name    string `json:"name"`
+	Cron    string `json:"cron"`
+	Binding string `json:"binding"`
+}
+
+type container struct {
+	Class      string
+	Binding    string
+	SleepAfter string
+	// Token is true when the service is given the worker's api token, to access the resources granted by its policies
+	Token bool
+}
+
+// workerSynth - collects the containers, routes and resources of a spec
+type workerSynth struct {
+	opts        Options
+	worker      *Worker
+	containers  []container
+	wrangler    []any
+	routes      []route
+	fallback    *route
+	schedules   []schedule
+	env         map[string]map[string]string
+	resources   map[string]string
+	granted     map[string]bool
+	unsupported []Unsupported
+}
+
+// Synthesize - converts a deployment spec into a wrangler project, returning the resources that couldn't be converted.
+//
+// Services are deployed as Cloudflare Containers, wrapped with the nitric Cloudflare runtime, behind a worker that routes API
+// and HTTP proxy requests and cron triggers to them. Buckets are deployed as R2 buckets, key value stores as KV namespaces
+// and queues as Cloudflare Queues, their names are passed to services as environment variables.
+func Synthesize(spec *deploymentspb.Spec, opts Options) (*Worker, []Unsupported, error) {
+	if opts.AccountId == "" {
+		return nil, nil, fmt.Errorf("an account id is required by the cloudflare provider, set account-id in the stack file to your Cloudflare account id")
+	}
+
+	s := &workerSynth{
+		opts:      opts,
+		worker:    &Worker{Files: map[string][]byte{}, Setup: []string{}},
+		env:       map[string]map[string]string{},
+		resources: map[string]string{},
+		granted:   grantedServices(spec),
+		// empty rather than nil, so the worker script gets arrays when there are no routes or schedules
+		routes:    []route{},
+		schedules: []schedule{},
+	}
+
+	// resources and services are converted first, since services are given the names of every resource
+	for _, res := range spec.Resources {
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Bucket:
+			if len(config.Bucket.Listeners) > 0 {
+				s.unsupport(res.Id, "bucket notifications are not supported by the cloudflare provider")
+				continue
+			}
+
+			s.resource(res.Id.Name, "NITRIC_BUCKET_", "r2 bucket create")
+		case *deploymentspb.Resource_KeyValueStore:
+			s.resource(res.Id.Name, "NITRIC_KEYVALUE_", "kv namespace create")
+		case *deploymentspb.Resource_Queue:
+			s.resource(res.Id.Name, "NITRIC_QUEUE_", "queues create")
+		}
+	}
+
+	for _, res := range spec.Resources {
+		if service := res.GetService(); service != nil {
+			if err := s.service(res.Id.Name, service); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, res := range spec.Resources {
+		var err error
+
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Service, *deploymentspb.Resource_Bucket, *deploymentspb.Resource_KeyValueStore, *deploymentspb.Resource_Queue:
+			continue
+		case *deploymentspb.Resource_Policy:
+			// services granted access by a policy share the worker's api token, so their access is limited by the token's
+			// permissions rather than their policies
+			continue
+		case *deploymentspb.Resource_Api:
+			err = s.api(res.Id.Name, config.Api)
+		case *deploymentspb.Resource_Http:
+			s.http(res.Id, config.Http)
+		case *deploymentspb.Resource_Schedule:
+			err = s.schedule(res.Id.Name, config.Schedule)
+		default:
+			s.unsupport(res.Id, fmt.Sprintf("%s resources are not supported by the cloudflare provider", strings.ToLower(res.Id.Type.String())))
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := s.files(); err != nil {
+		return nil, nil, err
+	}
+
+	return s.worker, s.unsupported, nil
+}
+
+// grantedServices - the services granted access to a bucket, key value store or queue by a policy, which
+// are accessed through the Cloudflare API with the worker's api token
+func grantedServices(spec *deploymentspb.Spec) map[string]bool {
+	granted := map[string]bool{}
+
+	for _, res := range spec.Resources {
+		policy := res.GetPolicy()
+		if policy == nil {
+			continue
+		}
+
+		accessesApi := lo.ContainsBy(policy.Resources, func(r *deploymentspb.Resource) bool {
+			return slices.Contains([]resourcespb.ResourceType{
+				resourcespb.ResourceType_Bucket, resourcespb.ResourceType_KeyValueStore, resourcespb.ResourceType_Queue,
+			}, r.Id.GetType())
+		})
+
+		if !accessesApi {
+			continue
+		}
+
+		for _, principal := range policy.Principals {
+			granted[principal.Id.GetName()] = true
+		}
+	}
+
+	return granted
+}
+
+func (s *workerSynth) unsupport(id *resourcespb.ResourceIdentifier, reason string) {
+	s.unsupported = append(s.unsupported, Unsupported{Id: id, Reason: reason})
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// physicalName - a name for a deployed resource that is unique to the stack, lowercase alphanumeric and dashes of at most 63 characters
+func (s *workerSynth) physicalName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(strings.Join([]string{s.opts.Project, s.opts.Stack, name}, "-")), "-")
+
+	return strings.Trim(name[:min(len(name), 63)], "-")
+}
+
+// identifier - a javascript identifier for a service, e.g. MyProjectApi for my-project_api
+func identifier(name string) string {
+	parts := invalidNameChars.Split(strings.ToLower(name), -1)
+
+	return strings.Join(lo.Map(parts, func(part string, _ int) string {
+		if part == "" {
+			return ""
+		}
+
+		return strings.ToUpper(part[:1]) + part[1:]
+	}), "")
+}
+
+// envName - an environment variable or binding name, e.g. NITRIC_BUCKET_MY_IMAGES for the bucket my-images
+func envName(prefix string, name string) string {
+	return prefix + strings.Trim(strings.ToUpper(regexp.MustCompile(`[^a-zA-Z0-9]+`).ReplaceAllString(name, "_")), "_")
+}
+
+// resource - records a resource created with wrangler during setup, e.g. npx wrangler r2 bucket create my-project-dev-images
+func (s *workerSynth) resource(name string, envPrefix string, create string) {
+	physical := s.physicalName(name)
+
+	s.resources[envName(envPrefix, name)] = physical
+	s.worker.Setup = append(s.worker.Setup, fmt.Sprintf("npx wrangler %s %s", create, physical))
+}
+
+// containersConfig - returns a Cloudflare Containers setting for a service type from the stack config, falling back to the default type
+func (s *workerSynth) containersConfig(serviceType string, key string, fallback any) any {
+	for _, t := range []string{serviceType, "default"} {
+		typeConfig, _ := s.opts.Config[t].(map[string]any)
+		containersConfig, _ := typeConfig["containers"].(map[string]any)
+
+		if value, ok := containersConfig[key]; ok {
+			return value
+		}
+	}
+
+	return fallback
+}
+
+// dockerfile - a dockerfile wrapping a service image with the nitric runtime, which starts the service's command as a child process
+func (s *workerSynth) dockerfile(imageUri string) ([]byte, error) {
+	cmd, err := json.Marshal(s.opts.Commands[imageUri])
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join([]string{
+		fmt.Sprintf("FROM %s", imageUri),
+		fmt.Sprintf("ADD --chmod=755 %s /bin/runtime", s.opts.Runtime),
+		`ENTRYPOINT ["/bin/runtime"]`,
+		fmt.Sprintf("CMD %s", cmd),
+		"",
+	}, "\n")), nil
+}
+
+func (s *workerSynth) service(name string, service *deploymentspb.Service) error {
+	class := identifier(name)
+	binding := envName("", name)
+	file := strings.ToLower(binding) + ".dockerfile"
+
+	dockerfile, err := s.dockerfile(service.GetImage().GetUri())
+	if err != nil {
+		return err
+	}
+
+	s.worker.Files[file] = dockerfile
+
+	s.containers = append(s.containers, container{
+		Class:      class,
+		Binding:    binding,
+		SleepAfter: fmt.Sprint(s.containersConfig(service.Type, "sleep-after", "5m")),
+		Token:      s.granted[name],
+	})
+
+	s.wrangler = append(s.wrangler, map[string]any{
+		"class_name":    class,
+		"image":         "./" + file,
+		"instance_type": s.containersConfig(service.Type, "instance-type", "basic"),
+		"max_instances": s.containersConfig(service.Type, "max-instances", 5),
+	})
+
+	env := map[string]string{
+		"NITRIC_STACK_ID":       s.physicalName(""),
+		"NITRIC_ENVIRONMENT":    "cloud",
+		"MIN_WORKERS":           fmt.Sprintf("%d", max(service.Workers, 1)),
+		"CLOUDFLARE_ACCOUNT_ID": s.opts.AccountId,
+		"R2_ENDPOINT":           fmt.Sprintf("https://%s.r2.cloudflarestorage.com", s.opts.AccountId),
+	}
+
+	for k, v := range s.resources {
+		env[k] = v
+	}
+
+	for k, v := range service.Env {
+		env[k] = v
+	}
+
+	s.env[binding] = env
+
+	return nil
+}
+
+// operationTarget - returns the name of the service handling an operation from its x-nitric-target extension
+func operationTarget(operation *openapi3.Operation) string {
+	data, err := json.Marshal(operation.Extensions["x-nitric-target"])
+	if err != nil {
+		return ""
+	}
+
+	target := struct {
+		Name string `json:"name"`
+	}{}
+
+	_ = json.Unmarshal(data, &target)
+
+	return target.Name
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// api - routes the operations of an API under /<name> to the services handling them
+func (s *workerSynth) api(name string, api *deploymentspb.Api) error {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+	if err != nil {
+		return fmt.Errorf("unable to read openapi document of api %s: %w", name, err)
+	}
+
+	prefix := "/" + name
+
+	for path, item := range doc.Paths {
+		for method, operation := range item.Operations() {
+			target := operationTarget(operation)
+			if _, ok := s.env[envName("", target)]; !ok {
+				continue
+			}
+
+			s.routes = append(s.routes, route{
+				Method: method,
+				// URLPattern uses :name for parameters, e.g. /orders/:id
+				Path:    prefix + pathParam.ReplaceAllString(path, ":$1"),
+				Prefix:  prefix,
+				Binding: envName("", target),
+			})
+		}
+	}
+
+	return nil
+}
+
+// http - routes requests that don't match an API route to the service proxied by the HTTP resource
+func (s *workerSynth) http(id *resourcespb.ResourceIdentifier, http *deploymentspb.Http) {
+	if s.fallback != nil {
+		s.unsupport(id, "only one http proxy is supported by the cloudflare provider")
+		return
+	}
+
+	s.fallback = &route{Method: "*", Path: "/*", Binding: envName("", http.GetTarget().GetService())}
+}
+
+// cronExpression - converts a nitric rate or cron schedule to a cron trigger
+func cronExpression(sched *deploymentspb.Schedule) (string, error) {
+	every := sched.GetEvery()
+	if every == nil {
+		return sched.GetCron().GetExpression(), nil
+	}
+
+	amount, unit, ok := strings.Cut(strings.TrimSpace(every.Rate), " ")
+	if !ok {
+		return "", fmt.Errorf("invalid schedule rate %s", every.Rate)
+	}
+
+	step := lo.Ternary(amount == "1", "*", "*/"+amount)
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute":
+		return fmt.Sprintf("%s * * * *", step), nil
+	case "hour":
+		return fmt.Sprintf("0 %s * * *", step), nil
+	case "day":
+		return fmt.Sprintf("0 0 %s * *", step), nil
+	}
+
+	return "", fmt.Errorf("invalid schedule rate %s, rates must be in minutes, hours or days", every.Rate)
+}
+
+func (s *workerSynth) schedule(name string, sched *deploymentspb.Schedule) error {
+	cron, err := cronExpression(sched)
+	if err != nil {
+		return fmt.Errorf("unable to convert schedule %s: %w", name, err)
+	}
+
+	s.schedules = append(s.schedules, schedule{Name: name, Cron: cron, Binding: envName("", sched.GetTarget().GetService())})
+
+	return nil
+}
+
+// files - renders the wrangler configuration, worker script and setup script of the project
+func (s *workerSynth) files() error {
+	// more specific routes are matched first, e.g. /orders/new before /orders/:id
+	slices.SortFunc(s.routes, func(a, b route) int {
+		if n := strings.Count(a.Path, ":") - strings.Count(b.Path, ":"); n != 0 {
+			return n
+		}
+
+		return strings.Compare(a.Path+a.Method, b.Path+b.Method)
+	})
+
+	if s.fallback != nil {
+		s.routes = append(s.routes, *s.fallback)
+	}
+
+	classes := lo.Map(s.containers, func(c container, _ int) string { return c.Class })
+
+	wrangler := map[string]any{
+		"name":               s.physicalName(""),
+		"main":               "src/index.js",
+		"compatibility_date": "2025-06-01",
+		"account_id":         s.opts.AccountId,
+		"observability":      map[string]any{"enabled": true},
+	}
+
+	if len(s.containers) > 0 {
+		wrangler["containers"] = s.wrangler
+		wrangler["durable_objects"] = map[string]any{
+			"bindings": lo.Map(s.containers, func(c container, _ int) any {
+				return map[string]any{"name": c.Binding, "class_name": c.Class}
+			}),
+		}
+		// each container class is added with its own migration, so services added later don't change earlier migrations
+		wrangler["migrations"] = lo.Map(classes, func(class string, _ int) any {
+			return map[string]any{"tag": class, "new_sqlite_classes": []string{class}}
+		})
+	}
+
+	if len(s.schedules) > 0 {
+		crons := lo.Uniq(lo.Map(s.schedules, func(sched schedule, _ int) string { return sched.Cron }))
+		wrangler["triggers"] = map[string]any{"crons": crons}
+	}
+
+	config, err := marshal(wrangler)
+	if err != nil {
+		return err
+	}
+
+	s.worker.Files["wrangler.json"] = config
+
+	routes, err := marshal(s.routes)
+	if err != nil {
+		return err
+	}
+
+	schedules, err := marshal(s.schedules)
+	if err != nil {
+		return err
+	}
+
+	env, err := marshal(s.env)
+	if err != nil {
+		return err
+	}
+
+	script := &bytes.Buffer{}
+
+	err = workerTemplate.Execute(script, map[string]any{
+		"Stack":     s.opts.Stack,
+		"Routes":    strings.TrimSpace(string(routes)),
+		"Schedules": strings.TrimSpace(string(schedules)),
+		"Env":       strings.TrimSpace(string(env)),
+		"Services":  s.containers,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.worker.Files["src/index.js"] = script.Bytes()
+
+	s.worker.Files["package.json"], err = marshal(map[string]any{
+		"name":            s.physicalName(""),
+		"private":         true,
+		"dependencies":    map[string]string{"@cloudflare/containers": "latest"},
+		"devDependencies": map[string]string{"wrangler": "latest"},
+	})
+	if err != nil {
+		return err
+	}
+
+	setup := []string{
+		"#!/bin/sh",
+		"# Creates the resources used by the worker, run once before it's first deployed",
+		"set -e",
+		"npm install",
+	}
+	setup = append(setup, s.worker.Setup...)
+	setup = append(setup,
+		"# The api token is passed to services granted access to resources by a policy, create one limited to the account's",
+		"# R2, Workers KV and Queues, see https://developers.cloudflare.com/fundamentals/api/get-started/create-token/",
+		"npx wrangler secret put CLOUDFLARE_API_TOKEN",
+		"",
+	)
+
+	s.worker.Files["setup.sh"] = []byte(strings.Join(setup, "\n"))
+
+	return nil
+}
+
+// marshal - indented JSON without HTML escaping, so generated files stay readable
+func marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nitrictech/cli/pkg/view/tui"
+)
+
+// SpecVersion - the version of the deployment spec and protocol (nitric.proto.deployments.v1) sent to providers by this CLI
+const SpecVersion = 1
+
+// SpecVersionLabel - the docker image label provider images can set to declare the spec version they support. The label is
+// defined by this CLI rather than by nitric's published providers, which don't declare a spec version, so only images
+// setting it are checked
+const SpecVersionLabel = "io.nitric.spec-version"
+
+const upgradeGuidance = "run `nitric version` to check your CLI version and see https://nitric.io/docs/get-started/installation to upgrade"
+
+// checkSpecVersion - verifies a provider's declared spec version is supported by this CLI.
+// Mismatches are errors unless NITRIC_PROVIDER_VERSION_CHECK=warn is set, in which case a warning is printed instead.
+func checkSpecVersion(providerId string, declaredVersion int) error {
+	var err error
+
+	switch {
+	case declaredVersion > SpecVersion:
+		err = fmt.Errorf("provider %s requires deployment spec v%d but this CLI supports v%d, upgrade the CLI to use this provider: %s", providerId, declaredVersion, SpecVersion, upgradeGuidance)
+	case declaredVersion < SpecVersion:
+		err = fmt.Errorf("provider %s supports deployment spec v%d but this CLI requires v%d, update the provider in your stack file to a newer version", providerId, declaredVersion, SpecVersion)
+	default:
+		return nil
+	}
+
+	if strings.EqualFold(os.Getenv("NITRIC_PROVIDER_VERSION_CHECK"), "warn") {
+		tui.Warning.Println(err.Error())
+		return nil
+	}
+
+	return err
+}
+
+// parseSpecVersion - parses a spec version declaration such as "1" or "v1"
+func parseSpecVersion(version string) (int, error) {
+	specVersion, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid deployment spec version %s: %w", version, err)
+	}
+
+	return specVersion, nil
+}
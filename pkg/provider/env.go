@@ -0,0 +1,77 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"os"
+	"strings"
+)
+
+// defaultEnvAllowlist - the environment variables forwarded to providers by default, patterns ending in * match by prefix.
+// These cover the system variables needed to run a process and the credentials used by the standard nitric providers.
+var defaultEnvAllowlist = []string{
+	// system
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LC_*", "TZ", "TMPDIR", "TEMP", "TMP", "XDG_*",
+	// windows
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "APPDATA", "LOCALAPPDATA", "USERPROFILE", "PROGRAMDATA", "PROGRAMFILES*",
+	// networking
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "SSL_CERT_FILE", "SSL_CERT_DIR",
+	// tooling
+	"NITRIC_*", "PULUMI_*", "TF_*", "DOCKER_*", "KUBECONFIG", "CI",
+	// cloud credentials
+	"AWS_*", "AZURE_*", "ARM_*", "GOOGLE_*", "GCLOUD_*", "CLOUDSDK_*",
+}
+
+func envAllowed(name string, allowlist []string) bool {
+	name = strings.ToUpper(name)
+
+	for _, pattern := range allowlist {
+		pattern = strings.ToUpper(pattern)
+
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// providerEnv - returns the environment of the current process restricted to the default and additional allowlisted variables,
+// with the provided variables added
+func providerEnv(additionalAllowlist []string, env map[string]string) []string {
+	allowlist := append(append([]string{}, defaultEnvAllowlist...), additionalAllowlist...)
+
+	providerEnv := []string{}
+
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+
+		if envAllowed(name, allowlist) {
+			providerEnv = append(providerEnv, kv)
+		}
+	}
+
+	for k, v := range env {
+		providerEnv = append(providerEnv, k+"="+v)
+	}
+
+	return providerEnv
+}
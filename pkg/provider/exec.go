@@ -35,6 +35,7 @@ type ProviderProcess struct {
 	providerPath string
 	process      *os.Process
 	envMap       map[string]string
+	envAllowlist []string
 	Address      string
 	stdout       chan<- string
 	stderr       chan<- string
@@ -60,15 +61,8 @@ func (p *ProviderProcess) startProcess() error {
 	// TODO: consider prefixing with NITRIC_ to avoid collisions
 	p.envMap["PORT"] = fmt.Sprint(tcpAddr.Port)
 
-	if len(p.envMap) > 0 {
-		env := os.Environ()
-
-		for k, v := range p.envMap {
-			env = append(env, k+"="+v)
-		}
-
-		cmd.Env = env
-	}
+	// only forward allowlisted variables from the current environment
+	cmd.Env = providerEnv(p.envAllowlist, p.envMap)
 
 	err = lis.Close()
 	if err != nil {
@@ -133,6 +127,13 @@ func WithStderr(stderr chan<- string) ProviderExecutableOption {
 	}
 }
 
+// WithEnvAllowlist - forwards additional variables from the current environment, as StartOptions.EnvAllowlist
+func WithEnvAllowlist(allowlist []string) ProviderExecutableOption {
+	return func(pp *ProviderProcess) {
+		pp.envAllowlist = allowlist
+	}
+}
+
 func StartProviderExecutable(fs afero.Fs, executablePath string, opts ...ProviderExecutableOption) (*ProviderProcess, error) {
 	fileInfo, err := fs.Stat(executablePath)
 	if err != nil {
@@ -28,6 +28,7 @@ import (
 	"github.com/docker/go-connections/nat"
 
 	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/view/tui"
 )
 
 type ProviderImage struct {
@@ -44,22 +45,44 @@ func (pi *ProviderImage) Install() error {
 	}
 
 	_, _, err = d.ImageInspectWithRaw(context.Background(), pi.imageName)
-	if err == nil {
-		return nil
+	if err != nil {
+		if !client.IsErrNotFound(err) {
+			return fmt.Errorf("error inspecting image: %w", err)
+		}
+
+		fmt.Fprintf(tui.Output(), "provider image %s not found locally, pulling\n", pi.imageName)
+
+		err = d.ImagePull(pi.imageName, types.ImagePullOptions{})
+		if err != nil {
+			return fmt.Errorf("error pulling image: %w", err)
+		}
 	}
 
-	if !client.IsErrNotFound(err) {
+	return pi.checkSpecVersion(d)
+}
+
+// checkSpecVersion - verifies the spec version declared by the image's io.nitric.spec-version label, if it has one
+func (pi *ProviderImage) checkSpecVersion(d *docker.Docker) error {
+	inspect, _, err := d.ImageInspectWithRaw(context.Background(), pi.imageName)
+	if err != nil {
 		return fmt.Errorf("error inspecting image: %w", err)
 	}
 
-	fmt.Printf("provider image %s not found locally, pulling\n", pi.imageName)
+	if inspect.Config == nil {
+		return nil
+	}
+
+	declaredVersion, ok := inspect.Config.Labels[SpecVersionLabel]
+	if !ok {
+		return nil
+	}
 
-	err = d.ImagePull(pi.imageName, types.ImagePullOptions{})
+	specVersion, err := parseSpecVersion(declaredVersion)
 	if err != nil {
-		return fmt.Errorf("error pulling image: %w", err)
+		return fmt.Errorf("provider image %s has an invalid %s label: %w", pi.imageName, SpecVersionLabel, err)
 	}
 
-	return nil
+	return checkSpecVersion("docker://"+pi.imageName, specVersion)
 }
 
 func (pi *ProviderImage) Start(options *StartOptions) (string, error) {
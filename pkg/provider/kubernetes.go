@@ -0,0 +1,417 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc"
+
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/provider/kubernetes"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// KubernetesProviderPrefix - prefix of the providers deploying to local kubernetes clusters, e.g. kubernetes/kind
+const KubernetesProviderPrefix = "kubernetes/"
+
+// kindConfig - a single node kind cluster with its ingress port mapped to a port of the host, see https://kind.sigs.k8s.io/docs/user/ingress/
+const kindConfig = `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+  - role: control-plane
+    kubeadmConfigPatches:
+      - |
+        kind: InitConfiguration
+        nodeRegistration:
+          kubeletExtraArgs:
+            node-labels: "ingress-ready=true"
+    extraPortMappings:
+      - containerPort: 80
+        hostPort: %d
+        protocol: TCP
+`
+
+// kindIngressManifest - the ingress-nginx manifest for kind, pinned to a release so clusters get the same controller every time
+const kindIngressManifest = "https://raw.githubusercontent.com/kubernetes/ingress-nginx/controller-v1.11.3/deploy/static/provider/kind/deploy.yaml"
+
+// clusterTool - the commands of a tool running kubernetes clusters in docker
+type clusterTool struct {
+	// Ingress class of the ingress controller of clusters created by the tool
+	ingressClass string
+	// kubectl context of a cluster
+	context func(cluster string) string
+	exists  func(cluster string) bool
+	// create - creates a cluster with its ingress served on a port of the host
+	create func(cluster string, port int) error
+	// load - loads a local image into a cluster
+	load func(cluster string, image string) error
+}
+
+// runTool - runs a command, returning its output as part of the error when it fails
+func runTool(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	output := &bytes.Buffer{}
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return output.String(), fmt.Errorf("%s %s failed: %w\n%s", name, strings.Join(args, " "), err, strings.TrimSpace(output.String()))
+	}
+
+	return output.String(), nil
+}
+
+var clusterTools = map[string]clusterTool{
+	"kind": {
+		ingressClass: "nginx",
+		context:      func(cluster string) string { return "kind-" + cluster },
+		exists: func(cluster string) bool {
+			output, err := runTool("", "kind", "get", "clusters")
+
+			return err == nil && slices.Contains(strings.Fields(output), cluster)
+		},
+		create: func(cluster string, port int) error {
+			if _, err := runTool(fmt.Sprintf(kindConfig, port), "kind", "create", "cluster", "--name", cluster, "--config", "-"); err != nil {
+				return err
+			}
+
+			// kind clusters don't include an ingress controller
+			if _, err := runTool("", "kubectl", "--context", "kind-"+cluster, "apply", "-f", kindIngressManifest); err != nil {
+				return err
+			}
+
+			_, err := runTool("", "kubectl", "--context", "kind-"+cluster, "wait", "--namespace", "ingress-nginx", "--for=condition=ready", "pod", "--selector=app.kubernetes.io/component=controller", "--timeout=180s")
+
+			return err
+		},
+		load: func(cluster string, image string) error {
+			_, err := runTool("", "kind", "load", "docker-image", image, "--name", cluster)
+
+			return err
+		},
+	},
+	"k3d": {
+		ingressClass: "traefik",
+		context:      func(cluster string) string { return "k3d-" + cluster },
+		exists: func(cluster string) bool {
+			_, err := runTool("", "k3d", "cluster", "get", cluster)
+
+			return err == nil
+		},
+		create: func(cluster string, port int) error {
+			_, err := runTool("", "k3d", "cluster", "create", cluster, "-p", fmt.Sprintf("%d:80@loadbalancer", port), "--wait")
+
+			return err
+		},
+		load: func(cluster string, image string) error {
+			_, err := runTool("", "k3d", "image", "import", image, "-c", cluster)
+
+			return err
+		},
+	},
+}
+
+// KubernetesProvider - deploys the stack to a kubernetes cluster running on the local machine with kind or k3d, so stacks can
+// be tested with real ingress and cron on a laptop without cloud credentials. The cluster is created when it doesn't exist,
+// service images are wrapped with the nitric runtime and loaded into the cluster, and the stack's manifests are applied with
+// kubectl to a namespace of their own. Manifests are written to kubernetes/<stack> in the project directory, or the output-dir
+// set in the stack file.
+type KubernetesProvider struct {
+	deploymentspb.UnimplementedDeploymentServer
+
+	tool       string
+	projectDir string
+	fs         afero.Fs
+	server     *grpc.Server
+}
+
+var _ Provider = (*KubernetesProvider)(nil)
+
+func NewKubernetesProvider(providerId string, projectDir string, fs afero.Fs) (*KubernetesProvider, error) {
+	tool := strings.TrimPrefix(providerId, KubernetesProviderPrefix)
+
+	if _, ok := clusterTools[tool]; !ok {
+		tools := lo.Keys(clusterTools)
+		slices.Sort(tools)
+
+		return nil, fmt.Errorf("the kubernetes provider doesn't support %s, supported cluster tools are: %s", tool, strings.Join(tools, ", "))
+	}
+
+	return &KubernetesProvider{
+		tool:       tool,
+		projectDir: projectDir,
+		fs:         fs,
+	}, nil
+}
+
+func (k *KubernetesProvider) Install() error {
+	return nil
+}
+
+func (k *KubernetesProvider) Start(opts *StartOptions) (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("unable to start the kubernetes provider: %w", err)
+	}
+
+	k.server = grpc.NewServer()
+	deploymentspb.RegisterDeploymentServer(k.server, k)
+
+	go func() {
+		_ = k.server.Serve(lis)
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+func (k *KubernetesProvider) Stop() error {
+	if k.server != nil {
+		k.server.GracefulStop()
+	}
+
+	return nil
+}
+
+// outputDir - the directory the manifests and image build contexts of a stack are written to
+func (k *KubernetesProvider) outputDir(attributes map[string]any, stackName string) string {
+	outputDir, _ := attributes["output-dir"].(string)
+	if outputDir == "" {
+		outputDir = filepath.Join("kubernetes", stackName)
+	}
+
+	if filepath.IsAbs(outputDir) {
+		return outputDir
+	}
+
+	return filepath.Join(k.projectDir, outputDir)
+}
+
+// cluster - the name of the cluster the stack is deployed to and the host port its ingress is served on
+func clusterAttributes(attributes map[string]any) (string, int) {
+	cluster, _ := attributes["cluster"].(string)
+	if cluster == "" {
+		cluster = "nitric"
+	}
+
+	port := 8000
+	if p, ok := attributes["port"].(float64); ok && p > 0 {
+		port = int(p)
+	}
+
+	return cluster, port
+}
+
+func sendMessage(stream deploymentspb.Deployment_UpServer, format string, args ...any) error {
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Message{Message: fmt.Sprintf(format, args...)},
+	})
+}
+
+func (k *KubernetesProvider) Up(req *deploymentspb.DeploymentUpRequest, stream deploymentspb.Deployment_UpServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+	projectName, _ := attributes["project"].(string)
+	config, _ := attributes["config"].(map[string]any)
+	runtime, _ := attributes["runtime"].(string)
+	cluster, port := clusterAttributes(attributes)
+	tool := clusterTools[k.tool]
+
+	if runtime == "" {
+		return unpublishedRuntimeError("kubernetes/"+k.tool, "kubernetes")
+	}
+
+	outputDir := k.outputDir(attributes, stackName)
+
+	if err := k.fs.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create kubernetes output directory %s: %w", outputDir, err)
+	}
+
+	runtime, err := buildContextRuntime(k.fs, k.projectDir, runtime, outputDir)
+	if err != nil {
+		return err
+	}
+
+	ids, commands, err := serviceImages(req.Spec)
+	if err != nil {
+		return err
+	}
+
+	manifests, unsupported, err := kubernetes.Synthesize(req.Spec, kubernetes.Options{
+		Project:      projectName,
+		Stack:        stackName,
+		IngressClass: tool.ingressClass,
+		Domain:       "localhost",
+		Config:       config,
+		Runtime:      runtime,
+		ImageIds:     ids,
+		Commands:     commands,
+	})
+	if err != nil {
+		return err
+	}
+
+	contents, err := manifests.Marshal()
+	if err != nil {
+		return err
+	}
+
+	manifestsFile := filepath.Join(outputDir, "manifests.yaml")
+
+	if err := afero.WriteFile(k.fs, manifestsFile, contents, 0o644); err != nil {
+		return fmt.Errorf("unable to write kubernetes manifests: %w", err)
+	}
+
+	if !tool.exists(cluster) {
+		if err := sendMessage(stream, "creating %s cluster %s, with its ingress on port %d", k.tool, cluster, port); err != nil {
+			return err
+		}
+
+		if err := tool.create(cluster, port); err != nil {
+			return fmt.Errorf("unable to create %s cluster %s: %w", k.tool, cluster, err)
+		}
+	}
+
+	builder, err := docker.NewBuilder()
+	if err != nil {
+		return err
+	}
+
+	for name, image := range manifests.Images {
+		if err := sendMessage(stream, "building and loading the image of service %s", name); err != nil {
+			return err
+		}
+
+		dockerfile := filepath.Join(outputDir, image.File)
+
+		if err := afero.WriteFile(k.fs, dockerfile, image.Dockerfile, 0o644); err != nil {
+			return fmt.Errorf("unable to write dockerfile of service %s: %w", name, err)
+		}
+
+		if err := builder.Build(dockerfile, outputDir, image.Tag, map[string]string{}, []string{}, nil); err != nil {
+			return fmt.Errorf("unable to build the image of service %s: %w", name, err)
+		}
+
+		if err := tool.load(cluster, image.Tag); err != nil {
+			return fmt.Errorf("unable to load the image of service %s into cluster %s: %w", name, cluster, err)
+		}
+	}
+
+	context := tool.context(cluster)
+	stackLabel := fmt.Sprintf("%s=%s", kubernetes.StackLabel, manifests.Namespace)
+
+	// objects of the stack that are no longer in the manifests are pruned, e.g. the deployment of a removed service
+	if _, err := runTool("", "kubectl", "--context", context, "apply", "-f", manifestsFile, "--prune", "-l", stackLabel); err != nil {
+		return err
+	}
+
+	for _, res := range req.Spec.Resources {
+		if res.Id == nil {
+			continue
+		}
+
+		status := deploymentspb.ResourceDeploymentStatus_SUCCESS
+		message := "deployed"
+
+		if u, ok := lo.Find(unsupported, func(u kubernetes.Unsupported) bool { return u.Id == res.Id }); ok {
+			status = deploymentspb.ResourceDeploymentStatus_FAILED
+			message = u.Reason
+		}
+
+		// services are deployed once their pods are ready
+		if res.Id.Type == resourcespb.ResourceType_Service {
+			if _, err := runTool("", "kubectl", "--context", context, "-n", manifests.Namespace, "rollout", "status", "deployment/"+kubernetes.Name(res.Id.Name), "--timeout=180s"); err != nil {
+				status = deploymentspb.ResourceDeploymentStatus_FAILED
+				message = err.Error()
+			}
+		}
+
+		if status == deploymentspb.ResourceDeploymentStatus_FAILED {
+			unsupported = append(unsupported, kubernetes.Unsupported{Id: res.Id, Reason: message})
+		}
+
+		if err := sendUpUpdate(stream, res.Id, deploymentspb.ResourceDeploymentAction_CREATE, status, message); err != nil {
+			return err
+		}
+	}
+
+	summary := []string{
+		fmt.Sprintf("Stack %s deployed to namespace %s of the %s cluster %s, inspect it with kubectl --context %s -n %s get all", stackName, manifests.Namespace, k.tool, cluster, context, manifests.Namespace),
+	}
+
+	summary = append(summary, digest.FormatOutputs(lo.MapValues(manifests.Urls, func(url string, _ string) string {
+		return fmt.Sprintf("%s:%d", url, port)
+	}))...)
+
+	if len(unsupported) > 0 {
+		summary = append(summary, fmt.Sprintf("%d resources are not supported by the kubernetes/%s provider or failed to deploy", len(unsupported), k.tool))
+	}
+
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Result{
+			Result: &deploymentspb.UpResult{
+				Success: len(unsupported) == 0,
+				Content: &deploymentspb.UpResult_Text{Text: strings.Join(summary, "\n")},
+			},
+		},
+	})
+}
+
+func (k *KubernetesProvider) Down(req *deploymentspb.DeploymentDownRequest, stream deploymentspb.Deployment_DownServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+	projectName, _ := attributes["project"].(string)
+	cluster, _ := clusterAttributes(attributes)
+	tool := clusterTools[k.tool]
+	namespace := kubernetes.Name(projectName + "-" + stackName)
+
+	// every object of the stack is in its namespace, the cluster is kept since it may be shared by other stacks
+	if _, err := runTool("", "kubectl", "--context", tool.context(cluster), "delete", "namespace", namespace, "--ignore-not-found"); err != nil {
+		return err
+	}
+
+	err := stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Message{
+			Message: fmt.Sprintf("deleted namespace %s, the %s cluster %s was kept, delete it with %s delete cluster %s", namespace, k.tool, cluster, k.tool, lo.Ternary(k.tool == "kind", "--name "+cluster, cluster)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Result{
+			Result: &deploymentspb.DownResult{},
+		},
+	})
+}
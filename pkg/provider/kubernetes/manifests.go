@@ -0,0 +1,486 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// StackLabel - label set on every object of a stack, used to prune objects that are no longer part of the stack
+const StackLabel = "nitric.io/stack"
+
+// servicePort - the port the runtime serves services on
+const servicePort = 8080
+
+type Options struct {
+	Project string
+	Stack   string
+	// Ingress class of the cluster's ingress controller, e.g. nginx or traefik
+	IngressClass string
+	// Domain APIs and HTTP proxies are served under, e.g. localhost serves the API main at main.localhost
+	Domain string
+	// Deployment configuration keyed by service type, as set under config in the stack file, e.g. {"default": {"kubernetes": {"replicas": 2}}}
+	Config map[string]any
+	// URL of the nitric kubernetes runtime, or the name of a file in the output directory, added to service images as their entrypoint
+	Runtime string
+	// IDs of the local service images keyed by image URI, used to tag the wrapped images by their contents
+	ImageIds map[string]string
+	// Entrypoint and command of the service images, keyed by image URI, run by the runtime
+	Commands map[string][]string
+}
+
+// Unsupported - a resource in the spec that can't be deployed to the cluster
+type Unsupported struct {
+	Id     *resourcespb.ResourceIdentifier
+	Reason string
+}
+
+// Image - a service image wrapped with the nitric runtime, built and loaded into the cluster before the manifests are applied
+type Image struct {
+	Tag string
+	// Name of the image's dockerfile in the output directory
+	File       string
+	Dockerfile []byte
+}
+
+// Manifests - the kubernetes objects of a stack, deployed to a namespace of their own
+type Manifests struct {
+	Namespace string
+	Objects   []map[string]any
+	// Images to build keyed by the name of the service they're for
+	Images map[string]Image
+	// URLs APIs and HTTP proxies are served at, keyed by <kind>.<name>, e.g. api.main
+	Urls map[string]string
+}
+
+// Marshal - returns the objects as a multi document YAML file, as accepted by kubectl apply -f
+func (m *Manifests) Marshal() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	for _, obj := range m.Objects {
+		buf.WriteString("---\n")
+
+		encoder := yaml.NewEncoder(buf)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+type manifestSynth struct {
+	opts        Options
+	manifests   *Manifests
+	services    map[string]string
+	unsupported []Unsupported
+}
+
+// Synthesize - converts a deployment spec into kubernetes objects, returning the resources that couldn't be converted.
+//
+// Services are deployed as Deployments of their image wrapped with the nitric runtime, APIs and HTTP proxies as Ingresses
+// served at <name>.<domain> and schedules as CronJobs calling the service they target.
+func Synthesize(spec *deploymentspb.Spec, opts Options) (*Manifests, []Unsupported, error) {
+	s := &manifestSynth{
+		opts: opts,
+		manifests: &Manifests{
+			Namespace: Name(opts.Project + "-" + opts.Stack),
+			Images:    map[string]Image{},
+			Urls:      map[string]string{},
+		},
+		services: map[string]string{},
+	}
+
+	s.add(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"name": s.manifests.Namespace, "labels": s.labels(nil)},
+	})
+
+	// services are converted first, so resources targeting them can reference their kubernetes services
+	for _, res := range spec.Resources {
+		if service := res.GetService(); service != nil {
+			if err := s.service(res.Id.Name, service); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, res := range spec.Resources {
+		var err error
+
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Service:
+			continue
+		case *deploymentspb.Resource_Policy:
+			// the cluster has no identity and access management, so services aren't restricted by policies
+			continue
+		case *deploymentspb.Resource_Api:
+			err = s.api(res.Id, config.Api)
+		case *deploymentspb.Resource_Http:
+			s.http(res.Id, config.Http)
+		case *deploymentspb.Resource_Schedule:
+			err = s.schedule(res.Id.Name, config.Schedule)
+		default:
+			s.unsupport(res.Id, fmt.Sprintf("%s resources are not supported by the kubernetes provider", strings.ToLower(res.Id.Type.String())))
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return s.manifests, s.unsupported, nil
+}
+
+func (s *manifestSynth) unsupport(id *resourcespb.ResourceIdentifier, reason string) {
+	s.unsupported = append(s.unsupported, Unsupported{Id: id, Reason: reason})
+}
+
+func (s *manifestSynth) add(obj map[string]any) {
+	s.manifests.Objects = append(s.manifests.Objects, obj)
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Name - converts a name to a valid kubernetes object name, lowercase alphanumeric and dashes of at most 63 characters,
+// e.g. the deployment of the service my-project_api is named my-project-api
+func Name(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+
+	return strings.Trim(name[:min(len(name), 63)], "-")
+}
+
+func (s *manifestSynth) labels(extra map[string]string) map[string]string {
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "nitric",
+		StackLabel:                     Name(s.opts.Project + "-" + s.opts.Stack),
+	}
+
+	for k, v := range extra {
+		labels[k] = v
+	}
+
+	return labels
+}
+
+func (s *manifestSynth) metadata(name string, extra map[string]string) map[string]any {
+	return map[string]any{
+		"name":      name,
+		"namespace": s.manifests.Namespace,
+		"labels":    s.labels(extra),
+	}
+}
+
+// kubernetesConfig - returns a kubernetes setting for a service type from the stack config, falling back to the default type
+func (s *manifestSynth) kubernetesConfig(serviceType string, key string, fallback any) any {
+	for _, t := range []string{serviceType, "default"} {
+		typeConfig, _ := s.opts.Config[t].(map[string]any)
+		kubernetesConfig, _ := typeConfig["kubernetes"].(map[string]any)
+
+		if value, ok := kubernetesConfig[key]; ok {
+			return value
+		}
+	}
+
+	return fallback
+}
+
+func (s *manifestSynth) service(name string, service *deploymentspb.Service) error {
+	uri := service.GetImage().GetUri()
+	serviceName := Name(name)
+
+	cmd, err := json.Marshal(s.opts.Commands[uri])
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256([]byte(s.opts.ImageIds[uri] + s.opts.Runtime))
+	tag := fmt.Sprintf("nitric-%s:%s", Name(strings.Join([]string{s.opts.Project, s.opts.Stack, name}, "-")), hex.EncodeToString(hash[:])[:12])
+
+	s.manifests.Images[name] = Image{
+		Tag:  tag,
+		File: serviceName + ".dockerfile",
+		Dockerfile: []byte(strings.Join([]string{
+			fmt.Sprintf("FROM %s", uri),
+			fmt.Sprintf("ADD --chmod=755 %s /bin/runtime", s.opts.Runtime),
+			`ENTRYPOINT ["/bin/runtime"]`,
+			fmt.Sprintf("CMD %s", cmd),
+			"",
+		}, "\n")),
+	}
+
+	env := map[string]string{
+		"NITRIC_STACK_ID":    s.manifests.Namespace,
+		"NITRIC_ENVIRONMENT": "cloud",
+		"MIN_WORKERS":        fmt.Sprintf("%d", max(service.Workers, 1)),
+		"PORT":               fmt.Sprint(servicePort),
+	}
+
+	for k, v := range service.Env {
+		env[k] = v
+	}
+
+	keys := lo.Keys(env)
+	slices.Sort(keys)
+
+	selector := map[string]string{"app.kubernetes.io/name": serviceName}
+
+	container := map[string]any{
+		"name":  serviceName,
+		"image": tag,
+		// images are loaded into the cluster rather than pulled from a registry
+		"imagePullPolicy": "IfNotPresent",
+		"ports":           []any{map[string]any{"containerPort": servicePort}},
+		"env": lo.Map(keys, func(k string, _ int) any {
+			return map[string]any{"name": k, "value": env[k]}
+		}),
+	}
+
+	resources := map[string]any{}
+
+	for _, key := range []string{"cpu", "memory"} {
+		if value := s.kubernetesConfig(service.Type, key, nil); value != nil {
+			resources[key] = fmt.Sprint(value)
+		}
+	}
+
+	if len(resources) > 0 {
+		container["resources"] = map[string]any{"requests": resources, "limits": resources}
+	}
+
+	s.add(map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   s.metadata(serviceName, selector),
+		"spec": map[string]any{
+			"replicas": s.kubernetesConfig(service.Type, "replicas", 1),
+			"selector": map[string]any{"matchLabels": selector},
+			"template": map[string]any{
+				"metadata": map[string]any{"labels": s.labels(selector)},
+				"spec":     map[string]any{"containers": []any{container}},
+			},
+		},
+	})
+
+	s.add(map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   s.metadata(serviceName, selector),
+		"spec": map[string]any{
+			"selector": selector,
+			"ports":    []any{map[string]any{"port": servicePort, "targetPort": servicePort}},
+		},
+	})
+
+	s.services[name] = serviceName
+
+	return nil
+}
+
+// operationTarget - returns the name of the service handling an operation from its x-nitric-target extension
+func operationTarget(operation *openapi3.Operation) string {
+	data, err := json.Marshal(operation.Extensions["x-nitric-target"])
+	if err != nil {
+		return ""
+	}
+
+	target := struct {
+		Name string `json:"name"`
+	}{}
+
+	_ = json.Unmarshal(data, &target)
+
+	return target.Name
+}
+
+type ingressPath struct {
+	path     string
+	pathType string
+	service  string
+}
+
+// ingressPathOf - the ingress path matching an openapi path, paths with parameters are matched by their literal prefix,
+// e.g. /orders/{id} matches /orders/ as a prefix
+func ingressPathOf(path string) (string, string) {
+	literal, _, hasParams := strings.Cut(path, "{")
+	if !hasParams {
+		return path, "Exact"
+	}
+
+	literal = literal[:strings.LastIndex(literal, "/")+1]
+
+	return literal, "Prefix"
+}
+
+// ingress - an ingress serving paths at <name>.<domain>
+func (s *manifestSynth) ingress(kind string, name string, paths []ingressPath) {
+	host := fmt.Sprintf("%s.%s", Name(name), s.opts.Domain)
+
+	s.add(map[string]any{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   s.metadata(Name(kind+"-"+name), nil),
+		"spec": map[string]any{
+			"ingressClassName": s.opts.IngressClass,
+			"rules": []any{map[string]any{
+				"host": host,
+				"http": map[string]any{
+					"paths": lo.Map(paths, func(p ingressPath, _ int) any {
+						return map[string]any{
+							"path":     p.path,
+							"pathType": p.pathType,
+							"backend": map[string]any{
+								"service": map[string]any{"name": p.service, "port": map[string]any{"number": servicePort}},
+							},
+						}
+					}),
+				},
+			}},
+		},
+	})
+
+	s.manifests.Urls[kind+"."+name] = "http://" + host
+}
+
+func (s *manifestSynth) api(id *resourcespb.ResourceIdentifier, api *deploymentspb.Api) error {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+	if err != nil {
+		return fmt.Errorf("unable to read openapi document of api %s: %w", id.Name, err)
+	}
+
+	paths := map[string]ingressPath{}
+
+	for path, item := range doc.Paths {
+		for _, operation := range item.Operations() {
+			service, ok := s.services[operationTarget(operation)]
+			if !ok {
+				continue
+			}
+
+			match, pathType := ingressPathOf(path)
+			key := pathType + " " + match
+
+			if existing, ok := paths[key]; ok && existing.service != service {
+				s.unsupport(id, fmt.Sprintf("path %s is handled by more than one service, which can't be routed by an ingress", match))
+				return nil
+			}
+
+			paths[key] = ingressPath{path: match, pathType: pathType, service: service}
+		}
+	}
+
+	keys := lo.Keys(paths)
+	slices.Sort(keys)
+
+	s.ingress("api", id.Name, lo.Map(keys, func(key string, _ int) ingressPath { return paths[key] }))
+
+	return nil
+}
+
+func (s *manifestSynth) http(id *resourcespb.ResourceIdentifier, http *deploymentspb.Http) {
+	service, ok := s.services[http.GetTarget().GetService()]
+	if !ok {
+		s.unsupport(id, fmt.Sprintf("http proxy target %s is not a service of the stack", http.GetTarget().GetService()))
+		return
+	}
+
+	s.ingress("http", id.Name, []ingressPath{{path: "/", pathType: "Prefix", service: service}})
+}
+
+// cronSchedule - converts a nitric rate or cron schedule to a CronJob schedule
+func cronSchedule(sched *deploymentspb.Schedule) (string, error) {
+	every := sched.GetEvery()
+	if every == nil {
+		return sched.GetCron().GetExpression(), nil
+	}
+
+	amount, unit, ok := strings.Cut(strings.TrimSpace(every.Rate), " ")
+	if !ok {
+		return "", fmt.Errorf("invalid schedule rate %s", every.Rate)
+	}
+
+	step := lo.Ternary(amount == "1", "*", "*/"+amount)
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute":
+		return fmt.Sprintf("%s * * * *", step), nil
+	case "hour":
+		return fmt.Sprintf("0 %s * * *", step), nil
+	case "day":
+		return fmt.Sprintf("0 0 %s * *", step), nil
+	}
+
+	return "", fmt.Errorf("invalid schedule rate %s, rates must be in minutes, hours or days", every.Rate)
+}
+
+// schedule - a CronJob calling the schedule's service through its kubernetes service
+func (s *manifestSynth) schedule(name string, sched *deploymentspb.Schedule) error {
+	cron, err := cronSchedule(sched)
+	if err != nil {
+		return fmt.Errorf("unable to convert schedule %s: %w", name, err)
+	}
+
+	service, ok := s.services[sched.GetTarget().GetService()]
+	if !ok {
+		return fmt.Errorf("schedule %s targets %s, which is not a service of the stack", name, sched.GetTarget().GetService())
+	}
+
+	s.add(map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"metadata":   s.metadata(Name("schedule-"+name), nil),
+		"spec": map[string]any{
+			"schedule":          cron,
+			"concurrencyPolicy": "Forbid",
+			"jobTemplate": map[string]any{
+				"spec": map[string]any{
+					"backoffLimit": 0,
+					"template": map[string]any{
+						"metadata": map[string]any{"labels": s.labels(nil)},
+						"spec": map[string]any{
+							"restartPolicy": "Never",
+							"containers": []any{map[string]any{
+								"name":  "trigger",
+								"image": "curlimages/curl:8.8.0",
+								"args":  []string{"-fsS", "-X", "POST", fmt.Sprintf("http://%s:%d/x-nitric-schedule/%s", service, servicePort, name)},
+							}},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	return nil
+}
@@ -0,0 +1,341 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc"
+
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/paths"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// NoopProviderId - the provider simulating deployments, set as the provider of a stack or with --provider noop
+const NoopProviderId = "noop"
+
+// noopResource - a resource simulated by the noop provider
+type noopResource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+// NoopProvider - simulates deployments without contacting a cloud or needing credentials, so the pipeline from collection to
+// deployment digest can be tested end to end in CI. Simulated resources are recorded in the project's .nitric directory,
+// later deployments of the stack report resources as updated, unchanged or deleted.
+//
+// Resources matching a pattern in the stack's fail list, e.g. bucket/*, are reported as failed.
+type NoopProvider struct {
+	deploymentspb.UnimplementedDeploymentServer
+
+	projectDir string
+	fs         afero.Fs
+	server     *grpc.Server
+}
+
+var _ Provider = (*NoopProvider)(nil)
+
+func NewNoopProvider(projectDir string, fs afero.Fs) *NoopProvider {
+	return &NoopProvider{
+		projectDir: projectDir,
+		fs:         fs,
+	}
+}
+
+func (n *NoopProvider) Install() error {
+	return nil
+}
+
+func (n *NoopProvider) Start(opts *StartOptions) (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("unable to start the noop provider: %w", err)
+	}
+
+	n.server = grpc.NewServer()
+	deploymentspb.RegisterDeploymentServer(n.server, n)
+
+	go func() {
+		_ = n.server.Serve(lis)
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+func (n *NoopProvider) Stop() error {
+	if n.server != nil {
+		n.server.GracefulStop()
+	}
+
+	return nil
+}
+
+func (n *NoopProvider) Up(req *deploymentspb.DeploymentUpRequest, stream deploymentspb.Deployment_UpServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+
+	state, err := n.readState(stackName)
+	if err != nil {
+		return err
+	}
+
+	hashes, err := digest.ResourceHashes(req.Spec)
+	if err != nil {
+		return err
+	}
+
+	ids := map[string]*resourcespb.ResourceIdentifier{}
+	apis := []string{}
+
+	for _, res := range req.Spec.Resources {
+		if res.Id == nil {
+			continue
+		}
+
+		ids[digest.AliasKey(res.Id.Type.String(), res.Id.Name)] = res.Id
+
+		if res.Id.Type == resourcespb.ResourceType_Api {
+			apis = append(apis, res.Id.Name)
+		}
+	}
+
+	retained := attributeList(attributes["retain"])
+	failing := attributeList(attributes["fail"])
+
+	err = stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Message{
+			Message: fmt.Sprintf("simulating deployment of stack %s, no cloud resources will be changed", stackName),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	counts := map[deploymentspb.ResourceDeploymentAction]int{}
+	failures := 0
+	next := map[string]noopResource{}
+
+	// resources are simulated in a stable order so the output of each deployment is deterministic
+	keys := lo.Keys(hashes)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		id := ids[key]
+		previous, exists := state[key]
+
+		action := deploymentspb.ResourceDeploymentAction_CREATE
+		if exists {
+			action = lo.Ternary(previous.Hash == hashes[key], deploymentspb.ResourceDeploymentAction_SAME, deploymentspb.ResourceDeploymentAction_UPDATE)
+		}
+
+		status := deploymentspb.ResourceDeploymentStatus_SUCCESS
+		message := "simulated"
+
+		if matchesAny(failing, key) {
+			status = deploymentspb.ResourceDeploymentStatus_FAILED
+			message = "simulated failure"
+			failures++
+
+			// failed resources keep their previous state
+			if exists {
+				next[key] = previous
+			}
+		} else {
+			next[key] = noopResource{Type: id.Type.String(), Name: id.Name, Hash: hashes[key]}
+			counts[action]++
+		}
+
+		if err := sendUpUpdate(stream, id, action, status, message); err != nil {
+			return err
+		}
+	}
+
+	removed := lo.Filter(lo.Keys(state), func(key string, _ int) bool {
+		_, declared := hashes[key]
+		return !declared
+	})
+	slices.Sort(removed)
+
+	retainedCount := 0
+
+	for _, key := range removed {
+		if slices.Contains(retained, key) {
+			retainedCount++
+			continue
+		}
+
+		resource := state[key]
+		id := &resourcespb.ResourceIdentifier{Name: resource.Name, Type: resourcespb.ResourceType(resourcespb.ResourceType_value[resource.Type])}
+
+		if err := sendUpUpdate(stream, id, deploymentspb.ResourceDeploymentAction_DELETE, deploymentspb.ResourceDeploymentStatus_SUCCESS, "simulated"); err != nil {
+			return err
+		}
+
+		counts[deploymentspb.ResourceDeploymentAction_DELETE]++
+	}
+
+	if err := n.writeState(stackName, next); err != nil {
+		return err
+	}
+
+	summary := []string{
+		fmt.Sprintf("Simulated deployment of stack %s with the noop provider", stackName),
+		fmt.Sprintf("%d created, %d updated, %d deleted, %d unchanged, %d retained, %d failed",
+			counts[deploymentspb.ResourceDeploymentAction_CREATE],
+			counts[deploymentspb.ResourceDeploymentAction_UPDATE],
+			counts[deploymentspb.ResourceDeploymentAction_DELETE],
+			counts[deploymentspb.ResourceDeploymentAction_SAME],
+			retainedCount,
+			failures,
+		),
+	}
+
+	outputs := map[string]string{}
+
+	for _, api := range apis {
+		outputs["api."+api] = fmt.Sprintf("https://noop.invalid/%s/apis/%s", stackName, api)
+	}
+
+	summary = append(summary, digest.FormatOutputs(outputs)...)
+
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Result{
+			Result: &deploymentspb.UpResult{
+				Success: failures == 0,
+				Content: &deploymentspb.UpResult_Text{Text: strings.Join(summary, "\n")},
+			},
+		},
+	})
+}
+
+func (n *NoopProvider) Down(req *deploymentspb.DeploymentDownRequest, stream deploymentspb.Deployment_DownServer) error {
+	stackName, _ := req.Attributes.AsMap()["stack"].(string)
+
+	state, err := n.readState(stackName)
+	if err != nil {
+		return err
+	}
+
+	keys := lo.Keys(state)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		resource := state[key]
+
+		err := stream.Send(&deploymentspb.DeploymentDownEvent{
+			Content: &deploymentspb.DeploymentDownEvent_Update{
+				Update: &deploymentspb.ResourceUpdate{
+					Id:      &resourcespb.ResourceIdentifier{Name: resource.Name, Type: resourcespb.ResourceType(resourcespb.ResourceType_value[resource.Type])},
+					Action:  deploymentspb.ResourceDeploymentAction_DELETE,
+					Status:  deploymentspb.ResourceDeploymentStatus_SUCCESS,
+					Message: "simulated",
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = n.fs.Remove(paths.NitricNoopStateFile(n.projectDir, stackName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove noop provider state for stack %s: %w", stackName, err)
+	}
+
+	return stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Result{
+			Result: &deploymentspb.DownResult{},
+		},
+	})
+}
+
+func (n *NoopProvider) readState(stackName string) (map[string]noopResource, error) {
+	state := map[string]noopResource{}
+
+	contents, err := afero.ReadFile(n.fs, paths.NitricNoopStateFile(n.projectDir, stackName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+
+		return nil, fmt.Errorf("unable to read noop provider state for stack %s: %w", stackName, err)
+	}
+
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return nil, fmt.Errorf("unable to parse noop provider state for stack %s: %w", stackName, err)
+	}
+
+	return state, nil
+}
+
+func (n *NoopProvider) writeState(stackName string, state map[string]noopResource) error {
+	stateFile := paths.NitricNoopStateFile(n.projectDir, stackName)
+
+	if err := n.fs.MkdirAll(filepath.Dir(stateFile), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create noop provider state directory: %w", err)
+	}
+
+	contents, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(n.fs, stateFile, contents, 0o600)
+}
+
+func sendUpUpdate(stream deploymentspb.Deployment_UpServer, id *resourcespb.ResourceIdentifier, action deploymentspb.ResourceDeploymentAction, status deploymentspb.ResourceDeploymentStatus, message string) error {
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Update{
+			Update: &deploymentspb.ResourceUpdate{
+				Id:      id,
+				Action:  action,
+				Status:  status,
+				Message: message,
+			},
+		},
+	})
+}
+
+// attributeList - returns the strings in a list attribute, e.g. the resources to retain
+func attributeList(value any) []string {
+	list, _ := value.([]any)
+
+	return lo.FilterMap(list, func(item any, _ int) (string, bool) {
+		s, ok := item.(string)
+		return s, ok
+	})
+}
+
+// matchesAny - whether a <type>/<name> resource key matches any of the patterns, e.g. bucket/*
+func matchesAny(patterns []string, key string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	})
+}
@@ -0,0 +1,257 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nitrictech/cli/pkg/iox"
+	"github.com/nitrictech/cli/pkg/netx"
+)
+
+// PluginPrefix - prefix of the executables on the PATH that are used as provider plugins, e.g. nitric-provider-acme
+const PluginPrefix = "nitric-provider-"
+
+// PluginProtocolVersion - version of the handshake between the CLI and provider plugins
+const PluginProtocolVersion = "1"
+
+// pluginHandshakePrefix - prefix of the line a plugin writes to stdout once it's ready, e.g. NITRIC_PROVIDER|1|127.0.0.1:50051
+const pluginHandshakePrefix = "NITRIC_PROVIDER|"
+
+const pluginStartTimeout = 30 * time.Second
+
+// PluginProvider - a provider shipped as a separate nitric-provider-<name> executable on the PATH, rather than a nitric release.
+//
+// The CLI starts the plugin with NITRIC_PROVIDER_PROTOCOL set to the protocol version and PORT set to a free port. The plugin
+// serves the nitric.proto.deployments.v1.Deployment gRPC service and writes a handshake line with its address to stdout once
+// it's ready, e.g. NITRIC_PROVIDER|1|127.0.0.1:50051. Anything else written to stdout or stderr is shown as provider output,
+// the plugin is killed once the deployment completes.
+type PluginProvider struct {
+	name    string
+	path    string
+	process *os.Process
+}
+
+var _ Provider = (*PluginProvider)(nil)
+
+// pluginName - the plugin name of a provider ID, e.g. acme/edge -> acme-edge
+func pluginName(providerId string) string {
+	return strings.ReplaceAll(strings.TrimSpace(providerId), "/", "-")
+}
+
+func NewPluginProvider(providerId string) *PluginProvider {
+	return &PluginProvider{
+		name: pluginName(providerId),
+	}
+}
+
+func (p *PluginProvider) Install() error {
+	path, err := exec.LookPath(PluginPrefix + p.name)
+	if err != nil {
+		return fmt.Errorf("provider plugin %s%s was not found on the PATH, install it or use a versioned provider such as nitric/aws@1.11.6", PluginPrefix, p.name)
+	}
+
+	p.path = path
+
+	return nil
+}
+
+// parseHandshake - returns the address in a plugin handshake line
+func parseHandshake(line string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid provider plugin handshake %q", line)
+	}
+
+	if parts[1] != PluginProtocolVersion {
+		return "", fmt.Errorf("provider plugin uses protocol version %s, this version of the CLI supports version %s", parts[1], PluginProtocolVersion)
+	}
+
+	if _, _, err := net.SplitHostPort(parts[2]); err != nil {
+		return "", fmt.Errorf("invalid provider plugin address %s: %w", parts[2], err)
+	}
+
+	return parts[2], nil
+}
+
+func (p *PluginProvider) Start(opts *StartOptions) (string, error) {
+	if p.path == "" {
+		if err := p.Install(); err != nil {
+			return "", err
+		}
+	}
+
+	lis, err := netx.GetNextListener()
+	if err != nil {
+		return "", err
+	}
+
+	port := lis.Addr().(*net.TCPAddr).Port
+
+	if err := lis.Close(); err != nil {
+		return "", err
+	}
+
+	env := map[string]string{
+		"PORT":                     fmt.Sprint(port),
+		"NITRIC_PROVIDER_PROTOCOL": PluginProtocolVersion,
+	}
+
+	for k, v := range opts.Env {
+		env[k] = v
+	}
+
+	cmd := exec.Command(p.path)
+	// only forward allowlisted variables from the current environment
+	cmd.Env = providerEnv(opts.EnvAllowlist, env)
+
+	cmd.Stderr = io.Discard
+	if opts.StdErr != nil {
+		cmd.Stderr = iox.NewChannelWriter(opts.StdErr)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("unable to start provider plugin %s: %w", p.path, err)
+	}
+
+	p.process = cmd.Process
+
+	handshake := make(chan string, 1)
+	exited := make(chan []string, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		// output is held until the plugin is ready, since it's only read once Start returns
+		pending := []string{}
+		ready := false
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if !ready && strings.HasPrefix(line, pluginHandshakePrefix) {
+				ready = true
+				handshake <- line
+
+				if opts.StdOut != nil {
+					for _, p := range pending {
+						opts.StdOut <- p
+					}
+				}
+
+				pending = nil
+
+				continue
+			}
+
+			if !ready {
+				pending = append(pending, line)
+				continue
+			}
+
+			if opts.StdOut != nil {
+				opts.StdOut <- line
+			}
+		}
+
+		exited <- pending
+	}()
+
+	select {
+	case line := <-handshake:
+		address, err := parseHandshake(line)
+		if err != nil {
+			_ = p.Stop()
+			return "", err
+		}
+
+		return address, nil
+	case output := <-exited:
+		return "", fmt.Errorf("provider plugin %s exited before it was ready: %s", p.path, strings.Join(output, "\n"))
+	case <-time.After(pluginStartTimeout):
+		_ = p.Stop()
+		return "", fmt.Errorf("provider plugin %s didn't write a handshake to stdout within %s", p.path, pluginStartTimeout)
+	}
+}
+
+func (p *PluginProvider) Stop() error {
+	if p.process != nil {
+		err := p.process.Kill()
+		if err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("failed to stop provider plugin: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Plugin - a provider plugin found on the PATH
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// ListPlugins - returns the provider plugins on the PATH, plugins earlier on the PATH take precedence over later ones with the same name
+func ListPlugins() []Plugin {
+	plugins := []Plugin{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			if !ok || name == "" {
+				continue
+			}
+
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, ".exe")
+			}
+
+			info, err := entry.Info()
+			if err != nil || !isExecAny(info.Mode()) {
+				continue
+			}
+
+			if slices.ContainsFunc(plugins, func(p Plugin) bool { return p.Name == name }) {
+				continue
+			}
+
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+
+	return plugins
+}
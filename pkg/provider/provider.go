@@ -23,6 +23,7 @@ import (
 
 	"github.com/spf13/afero"
 
+	"github.com/nitrictech/cli/pkg/preferences"
 	"github.com/nitrictech/cli/pkg/preview"
 	"github.com/nitrictech/cli/pkg/project"
 )
@@ -34,29 +35,64 @@ type Provider interface {
 }
 
 type StartOptions struct {
-	Env    map[string]string
-	StdOut chan<- string
-	StdErr chan<- string
+	Env map[string]string
+	// Additional environment variables to forward from the current environment, in addition to the default allowlist.
+	// Patterns ending in * match by prefix, e.g. MY_ORG_*
+	EnvAllowlist []string
+	StdOut       chan<- string
+	StdErr       chan<- string
 }
 
 const nitricOrg = "nitric"
 
+// UsesPulumi - returns false for the providers built into the CLI, which don't keep pulumi state
+func UsesPulumi(providerId string) bool {
+	return providerId != NoopProviderId && providerId != CloudflareProviderId &&
+		!strings.HasPrefix(providerId, TerraformProviderPrefix) && !strings.HasPrefix(providerId, KubernetesProviderPrefix)
+}
+
 // NewProvider - Returns a new provider instance based on the given providerId string
-// The providerId string is in the form of <org-name>/<provider-name>@<version>
+// The providerId string is in the form of <org-name>/<provider-name>@<version>, or <name> for provider plugins on the PATH
 func NewProvider(providerId string, project *project.Project, fs afero.Fs) (Provider, error) {
+	if providerId == NoopProviderId {
+		return NewNoopProvider(project.Directory, fs), nil
+	}
+
+	if strings.HasPrefix(providerId, TerraformProviderPrefix) {
+		return NewTerraformProvider(providerId, project.Directory, fs)
+	}
+
+	if strings.HasPrefix(providerId, KubernetesProviderPrefix) {
+		return NewKubernetesProvider(providerId, project.Directory, fs)
+	}
+
+	if providerId == CloudflareProviderId {
+		return NewCloudflareProvider(project.Directory, fs), nil
+	}
+
 	if strings.HasPrefix(providerId, "docker://") {
 		if !slices.Contains(project.Preview, preview.Feature_DockerProviders) {
-			return nil, fmt.Errorf("your stack specifies %s as the provider, docker providers are not enabled for this project. See https://nitric.io/docs/reference/providers/install/docker", providerId)
+			return nil, fmt.Errorf("your stack specifies %s as the provider, docker providers are not enabled for this project. Run `nitric preview enable docker-providers` to enable them, see https://nitric.io/docs/reference/providers/install/docker", providerId)
 		}
 
 		// remove the prefix and return a new image provider with the URI
 		dockerUri := strings.Replace(providerId, "docker://", "", 1)
 
+		prefs, err := preferences.Current()
+		if err != nil {
+			return nil, err
+		}
+
 		return &ProviderImage{
-			imageName: dockerUri,
+			imageName: prefs.ImageName(dockerUri),
 		}, nil
 	}
 
+	// providers without a version are plugins found on the PATH, e.g. acme/edge runs nitric-provider-acme-edge
+	if !strings.Contains(providerId, "@") {
+		return NewPluginProvider(providerId), nil
+	}
+
 	// Default to standard provider
 	provider, err := NewStandardProvider(providerId, fs)
 	if err != nil {
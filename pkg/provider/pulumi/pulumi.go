@@ -19,17 +19,22 @@ package pulumi
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/afero"
 
 	"github.com/nitrictech/cli/pkg/paths"
+	"github.com/nitrictech/cli/pkg/preferences"
 	"github.com/nitrictech/nitric/core/pkg/logger"
 )
 
 const passphraseBytes = 32
 
+// passphraseSecretName - the name of the pulumi passphrase in the os keychain
+const passphraseSecretName = "pulumi-passphrase"
+
 func randomString() (string, error) {
 	b := make([]byte, passphraseBytes)
 
@@ -46,6 +51,16 @@ func EnsurePulumiPassphrase(fs afero.Fs) error {
 		return nil
 	}
 
+	passphrase, err := getOrGenerateKeychainPassphrase(fs)
+	if err == nil {
+		os.Setenv("PULUMI_CONFIG_PASSPHRASE", passphrase)
+
+		return nil
+	}
+
+	// the keychain isn't available in all environments, e.g. headless linux or containers, so fall back to the passphrase file
+	logger.Debugf("unable to use os keychain for passphrase, falling back to passphrase file: %s", err)
+
 	path, err := GetOrGeneratePassphraseFile(fs, false)
 	if err != nil {
 		return fmt.Errorf("error ensuring nitric pulumi passphrase file: %w", err)
@@ -56,10 +71,88 @@ func EnsurePulumiPassphrase(fs afero.Fs) error {
 	return nil
 }
 
+// passphraseFileMode - the passphrase file is only readable by the current user
+const passphraseFileMode = 0o600
+
+// getOrGenerateKeychainPassphrase - returns the passphrase stored in the os keychain.
+// If there isn't one, the existing passphrase file is moved to the keychain, otherwise a new passphrase is generated.
+// The passphrase file is removed once the keychain holds the same passphrase, so it's only kept when the keychain can't be used
+func getOrGenerateKeychainPassphrase(fs afero.Fs) (string, error) {
+	path := paths.NitricLocalPassphrasePath()
+
+	passphrase, err := preferences.GetSecret(passphraseSecretName)
+	if err == nil {
+		removePassphraseFile(fs, path, passphrase)
+
+		return passphrase, nil
+	}
+
+	if !errors.Is(err, preferences.ErrSecretNotFound) {
+		return "", err
+	}
+
+	existing, err := afero.ReadFile(fs, path)
+	if err == nil {
+		logger.Debugf("moving passphrase file %s to os keychain", path)
+
+		passphrase = string(existing)
+	} else {
+		logger.Debugf("generating new passphrase in os keychain")
+
+		passphrase, err = randomString()
+		if err != nil {
+			return "", fmt.Errorf("error generating passphrase: %w", err)
+		}
+	}
+
+	if err := preferences.SetSecret(passphraseSecretName, passphrase); err != nil {
+		return "", err
+	}
+
+	// the file is only removed once the passphrase can be read back, so the state it encrypts is never left without it
+	stored, err := preferences.GetSecret(passphraseSecretName)
+	if err != nil {
+		return "", err
+	}
+
+	removePassphraseFile(fs, path, stored)
+
+	return stored, nil
+}
+
+// removePassphraseFile - removes the passphrase file when it holds the keychain passphrase. A file holding a different
+// passphrase is kept, as it may still encrypt state, and only made private
+func removePassphraseFile(fs afero.Fs, path string, passphrase string) {
+	existing, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return
+	}
+
+	if string(existing) != passphrase {
+		logger.Debugf("passphrase file %s doesn't match the os keychain passphrase, keeping it", path)
+
+		if err := fs.Chmod(path, passphraseFileMode); err != nil {
+			logger.Debugf("unable to restrict passphrase file %s: %s", path, err)
+		}
+
+		return
+	}
+
+	if err := fs.Remove(path); err != nil {
+		logger.Debugf("unable to remove passphrase file %s: %s", path, err)
+	}
+}
+
 func GetOrGeneratePassphraseFile(fs afero.Fs, isNonInteractive bool) (string, error) {
 	path := paths.NitricLocalPassphrasePath()
 	if exists, err := afero.Exists(fs, path); err == nil && exists {
 		logger.Debugf("using existing passphrase file: %s", path)
+
+		// files written by earlier versions were readable by every user
+		if err := fs.Chmod(path, passphraseFileMode); err != nil {
+			logger.Debugf("unable to restrict passphrase file %s: %s", path, err)
+		}
+
 		return path, nil
 	}
 
@@ -70,7 +163,7 @@ func GetOrGeneratePassphraseFile(fs afero.Fs, isNonInteractive bool) (string, er
 		return "", fmt.Errorf("error generating passphrase: %w", err)
 	}
 
-	err = afero.WriteFile(fs, path, []byte(newPassphrase), os.ModePerm)
+	err = afero.WriteFile(fs, path, []byte(newPassphrase), passphraseFileMode)
 	if err != nil {
 		return "", err
 	}
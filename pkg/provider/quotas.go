@@ -0,0 +1,296 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// QuotaIssue - a setting of a stack, or a number of resources, that exceeds a limit of the provider's cloud
+type QuotaIssue struct {
+	// The setting or resource type the issue is about, e.g. config.default.lambda.memory or bucket
+	Subject string `json:"subject"`
+	Reason  string `json:"reason"`
+}
+
+// settingLimit - the allowed range of a numeric setting of a service type, e.g. the memory of a lambda
+type settingLimit struct {
+	// Path of the setting within a service type's config, e.g. lambda.memory
+	setting string
+	min     float64
+	max     float64
+	unit    string
+}
+
+// cloudLimits - the documented limits of a cloud, and the account quotas that can be queried with its CLI
+type cloudLimits struct {
+	// Name of the compute service the limits apply to, e.g. AWS Lambda
+	service  string
+	settings []settingLimit
+	// Default quotas on the number of resources of a type in an account, project or environment
+	resources map[resourcespb.ResourceType]int
+	// The setting reserving concurrent executions from the account's quota, if there is one
+	reservedConcurrency string
+	// Default quota on concurrent executions, 0 if there isn't one
+	concurrentExecutions int
+	// Returns the account's quotas, keyed the same as the defaults, in place of the defaults
+	query func(ctx context.Context, region string) accountQuotas
+}
+
+// accountQuotas - quotas of the account the stack is deployed to
+type accountQuotas struct {
+	resources            map[resourcespb.ResourceType]int
+	concurrentExecutions int
+}
+
+// awsUnreservedConcurrency - the concurrent executions lambda keeps unreserved in every account
+const awsUnreservedConcurrency = 100
+
+var awsLimits = cloudLimits{
+	service: "AWS Lambda",
+	settings: []settingLimit{
+		{setting: "lambda.memory", min: 128, max: 10240, unit: "MB"},
+		{setting: "lambda.timeout", min: 1, max: 900, unit: "seconds"},
+		{setting: "lambda.ephemeral-storage", min: 512, max: 10240, unit: "MB"},
+		{setting: "lambda.provisioned-concurrency", min: 0, max: 1000},
+	},
+	resources: map[resourcespb.ResourceType]int{
+		resourcespb.ResourceType_Bucket: 10000,
+		resourcespb.ResourceType_Api:    600,
+	},
+	reservedConcurrency:  "lambda.provisioned-concurrency",
+	concurrentExecutions: 1000,
+	query:                queryAwsQuotas,
+}
+
+var gcpLimits = cloudLimits{
+	service: "Cloud Run",
+	settings: []settingLimit{
+		{setting: "cloudrun.memory", min: 128, max: 32768, unit: "MiB"},
+		{setting: "cloudrun.cpus", min: 1, max: 8},
+		{setting: "cloudrun.timeout", min: 1, max: 3600, unit: "seconds"},
+		{setting: "cloudrun.concurrency", min: 1, max: 1000},
+		{setting: "cloudrun.min-instances", min: 0, max: 1000},
+		{setting: "cloudrun.max-instances", min: 1, max: 1000},
+	},
+	resources: map[resourcespb.ResourceType]int{
+		resourcespb.ResourceType_Service: 1000,
+	},
+}
+
+var azureLimits = cloudLimits{
+	service: "Azure Container Apps",
+	settings: []settingLimit{
+		{setting: "containerapps.cpu", min: 0.25, max: 4, unit: "vCPU"},
+		{setting: "containerapps.memory", min: 0.5, max: 8, unit: "GB"},
+		{setting: "containerapps.min-replicas", min: 0, max: 300},
+		{setting: "containerapps.max-replicas", min: 1, max: 300},
+	},
+	resources: map[resourcespb.ResourceType]int{
+		resourcespb.ResourceType_Service: 100,
+	},
+}
+
+// knownLimits - the limits of the clouds deployed to by the nitric providers, keyed by provider name without its version
+var knownLimits = map[string]cloudLimits{
+	"nitric/aws":   awsLimits,
+	"nitric/awstf": awsLimits,
+	"nitric/gcp":   gcpLimits,
+	"nitric/gcptf": gcpLimits,
+	"nitric/azure": azureLimits,
+}
+
+// resourceTypeName - the name of a resource type as used in stack files, e.g. sqldatabase
+func resourceTypeName(resourceType resourcespb.ResourceType) string {
+	return strings.ToLower(resourceType.String())
+}
+
+// numericSetting - returns the setting at a dot separated path of a service type's config, false if it isn't set or isn't a number
+func numericSetting(config map[string]interface{}, path string) (float64, bool) {
+	parts := strings.Split(path, ".")
+
+	var current interface{} = config
+
+	for _, part := range parts {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+
+		current = m[part]
+	}
+
+	switch v := current.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// CheckQuotas - returns the settings of a stack, and the resources of its spec, that exceed the limits of its provider's cloud.
+// The account's quotas are queried with the cloud's CLI when query is set and the CLI is available, otherwise the defaults are used.
+// No issues are returned for providers with unknown limits
+func CheckQuotas(providerId string, region string, stackConfig map[string]interface{}, spec *deploymentspb.Spec, query bool) []QuotaIssue {
+	name, _, _ := strings.Cut(providerId, "@")
+
+	limits, ok := knownLimits[name]
+	if !ok {
+		return []QuotaIssue{}
+	}
+
+	quotas := accountQuotas{resources: limits.resources, concurrentExecutions: limits.concurrentExecutions}
+	quotaSource := "the default quota"
+
+	if query && limits.query != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		queried := limits.query(ctx, region)
+
+		if len(queried.resources) > 0 || queried.concurrentExecutions > 0 {
+			quotaSource = "the account's quota"
+			quotas.resources = lo.Assign(quotas.resources, queried.resources)
+
+			if queried.concurrentExecutions > 0 {
+				quotas.concurrentExecutions = queried.concurrentExecutions
+			}
+		}
+	}
+
+	issues := []QuotaIssue{}
+
+	serviceTypes, _ := stackConfig["config"].(map[string]interface{})
+
+	typeNames := lo.Keys(serviceTypes)
+	slices.Sort(typeNames)
+
+	servicesOfType := lo.CountValuesBy(lo.Filter(spec.GetResources(), func(res *deploymentspb.Resource, _ int) bool {
+		return res.GetService() != nil
+	}), func(res *deploymentspb.Resource) string {
+		return lo.Ternary(res.GetService().GetType() != "", res.GetService().GetType(), "default")
+	})
+
+	reserved := 0.0
+
+	for _, typeName := range typeNames {
+		typeConfig, ok := serviceTypes[typeName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, limit := range limits.settings {
+			value, ok := numericSetting(typeConfig, limit.setting)
+			if !ok {
+				continue
+			}
+
+			if value < limit.min || value > limit.max {
+				issues = append(issues, QuotaIssue{
+					Subject: fmt.Sprintf("config.%s.%s", typeName, limit.setting),
+					Reason:  strings.TrimSpace(fmt.Sprintf("set to %s, %s allows %s to %s %s", formatNumber(value), limits.service, formatNumber(limit.min), formatNumber(limit.max), limit.unit)),
+				})
+			}
+
+			if limit.setting == limits.reservedConcurrency {
+				reserved += value * float64(servicesOfType[typeName])
+			}
+		}
+	}
+
+	// reserving concurrency leaves less of the account's quota for other functions, and fails when it leaves too little
+	if quotas.concurrentExecutions > 0 && reserved > float64(quotas.concurrentExecutions-awsUnreservedConcurrency) {
+		issues = append(issues, QuotaIssue{
+			Subject: limits.reservedConcurrency,
+			Reason: fmt.Sprintf("services reserve %s concurrent executions, %s of %d leaves at most %d to reserve", formatNumber(reserved), quotaSource,
+				quotas.concurrentExecutions, quotas.concurrentExecutions-awsUnreservedConcurrency),
+		})
+	}
+
+	counts := lo.CountValuesBy(spec.GetResources(), func(res *deploymentspb.Resource) resourcespb.ResourceType {
+		return res.GetId().GetType()
+	})
+
+	resourceTypes := lo.Keys(quotas.resources)
+	slices.Sort(resourceTypes)
+
+	for _, resourceType := range resourceTypes {
+		if count := counts[resourceType]; count > quotas.resources[resourceType] {
+			issues = append(issues, QuotaIssue{
+				Subject: resourceTypeName(resourceType),
+				Reason:  fmt.Sprintf("the project declares %d %s resources, %s is %d", count, resourceTypeName(resourceType), quotaSource, quotas.resources[resourceType]),
+			})
+		}
+	}
+
+	return issues
+}
+
+func runCli(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// queryAwsQuotas - queries the lambda concurrency and bucket quotas of the account with the aws CLI, quotas that can't be queried are left unset
+func queryAwsQuotas(ctx context.Context, region string) accountQuotas {
+	quotas := accountQuotas{resources: map[resourcespb.ResourceType]int{}}
+
+	if _, err := exec.LookPath("aws"); err != nil {
+		return quotas
+	}
+
+	regionArgs := []string{}
+	if region != "" {
+		regionArgs = []string{"--region", region}
+	}
+
+	if out, err := runCli(ctx, append([]string{"aws", "lambda", "get-account-settings", "--query", "AccountLimit.ConcurrentExecutions", "--output", "text"}, regionArgs...)...); err == nil {
+		if concurrency, err := strconv.Atoi(out); err == nil {
+			quotas.concurrentExecutions = concurrency
+		}
+	}
+
+	// L-DC2B2D3D is the quota on general purpose buckets
+	if out, err := runCli(ctx, append([]string{"aws", "service-quotas", "get-service-quota", "--service-code", "s3", "--quota-code", "L-DC2B2D3D", "--query", "Quota.Value", "--output", "text"}, regionArgs...)...); err == nil {
+		if buckets, err := strconv.ParseFloat(out, 64); err == nil {
+			quotas.resources[resourcespb.ResourceType_Bucket] = int(buckets)
+		}
+	}
+
+	return quotas
+}
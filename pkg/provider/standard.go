@@ -149,15 +149,8 @@ func (sp *StandardProvider) Start(opts *StartOptions) (string, error) {
 		containerEnv[k] = v
 	}
 
-	if len(containerEnv) > 0 {
-		env := os.Environ()
-
-		for k, v := range containerEnv {
-			env = append(env, k+"="+v)
-		}
-
-		cmd.Env = env
-	}
+	// only forward allowlisted variables from the current environment
+	cmd.Env = providerEnv(opts.EnvAllowlist, containerEnv)
 
 	err = lis.Close()
 	if err != nil {
@@ -215,3 +208,51 @@ func NewStandardProvider(providerId string, fs afero.Fs) (*StandardProvider, err
 		fs:           fs,
 	}, nil
 }
+
+// binaryFileRegex - matches the file names of downloaded providers, e.g. aws-1.11.6
+var binaryFileRegex = regexp.MustCompile(`^(\w+)-(.+?)(\.exe)?$`)
+
+// DownloadedProvider - a standard provider downloaded to the nitric providers directory
+type DownloadedProvider struct {
+	Id   string `json:"id"`
+	Path string `json:"path"`
+}
+
+// ListDownloaded - returns the standard providers that have been downloaded, e.g. nitric/aws@1.11.6
+func ListDownloaded() ([]DownloadedProvider, error) {
+	downloaded := []DownloadedProvider{}
+
+	orgDirs, err := os.ReadDir(paths.NitricProviderDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return downloaded, nil
+		}
+
+		return nil, err
+	}
+
+	for _, orgDir := range orgDirs {
+		if !orgDir.IsDir() {
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(paths.NitricProviderDir(), orgDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			match := binaryFileRegex.FindStringSubmatch(file.Name())
+			if match == nil || file.IsDir() {
+				continue
+			}
+
+			downloaded = append(downloaded, DownloadedProvider{
+				Id:   fmt.Sprintf("%s/%s@%s", orgDir.Name(), match[1], match[2]),
+				Path: filepath.Join(paths.NitricProviderDir(), orgDir.Name(), file.Name()),
+			})
+		}
+	}
+
+	return downloaded, nil
+}
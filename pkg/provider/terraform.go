@@ -0,0 +1,347 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"google.golang.org/grpc"
+
+	"github.com/nitrictech/cli/pkg/docker"
+	"github.com/nitrictech/cli/pkg/provider/terraform"
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+)
+
+// TerraformProviderPrefix - prefix of the providers that generate terraform configuration, e.g. terraform/aws
+const TerraformProviderPrefix = "terraform/"
+
+// terraformSynthesizers - converts specs into terraform configuration, keyed by the cloud they generate configuration for
+var terraformSynthesizers = map[string]func(*deploymentspb.Spec, terraform.Options) (*terraform.Config, []terraform.Unsupported, error){
+	"aws": terraform.SynthesizeAws,
+	"do":  terraform.SynthesizeDigitalOcean,
+}
+
+// terraformTools - the tools needed to apply the generated configuration of each cloud
+var terraformTools = map[string]string{
+	"aws": "Service images are pushed from the machine running terraform, which requires docker and the aws cli",
+	"do":  "Service images are pushed from the machine running terraform, which requires docker and doctl, and the DIGITALOCEAN_TOKEN, SPACES_ACCESS_KEY_ID and SPACES_SECRET_ACCESS_KEY environment variables",
+}
+
+// TerraformProvider - generates a terraform configuration for the stack instead of deploying it with pulumi, so projects can be
+// deployed with existing terraform state and review tooling. The configuration is written to terraform/<stack> in the project
+// directory, or the output-dir set in the stack file, and applied with terraform rather than by the CLI.
+type TerraformProvider struct {
+	deploymentspb.UnimplementedDeploymentServer
+
+	cloud      string
+	projectDir string
+	fs         afero.Fs
+	server     *grpc.Server
+}
+
+var _ Provider = (*TerraformProvider)(nil)
+
+func NewTerraformProvider(providerId string, projectDir string, fs afero.Fs) (*TerraformProvider, error) {
+	cloud := strings.TrimPrefix(providerId, TerraformProviderPrefix)
+
+	if _, ok := terraformSynthesizers[cloud]; !ok {
+		clouds := lo.Keys(terraformSynthesizers)
+		slices.Sort(clouds)
+
+		return nil, fmt.Errorf("the terraform provider doesn't support %s, supported clouds are: %s", cloud, strings.Join(clouds, ", "))
+	}
+
+	return &TerraformProvider{
+		cloud:      cloud,
+		projectDir: projectDir,
+		fs:         fs,
+	}, nil
+}
+
+func (t *TerraformProvider) Install() error {
+	return nil
+}
+
+func (t *TerraformProvider) Start(opts *StartOptions) (string, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("unable to start the terraform provider: %w", err)
+	}
+
+	t.server = grpc.NewServer()
+	deploymentspb.RegisterDeploymentServer(t.server, t)
+
+	go func() {
+		_ = t.server.Serve(lis)
+	}()
+
+	return lis.Addr().String(), nil
+}
+
+func (t *TerraformProvider) Stop() error {
+	if t.server != nil {
+		t.server.GracefulStop()
+	}
+
+	return nil
+}
+
+// outputDir - the directory the configuration of a stack is written to
+func (t *TerraformProvider) outputDir(attributes map[string]any, stackName string) string {
+	outputDir, _ := attributes["output-dir"].(string)
+	if outputDir == "" {
+		outputDir = filepath.Join("terraform", stackName)
+	}
+
+	if filepath.IsAbs(outputDir) {
+		return outputDir
+	}
+
+	return filepath.Join(t.projectDir, outputDir)
+}
+
+// serviceImages - the IDs and commands of the local service and migration images, so pushed images are tagged by their
+// contents and service images wrapped with the runtime
+func serviceImages(spec *deploymentspb.Spec) (map[string]string, map[string][]string, error) {
+	ids := map[string]string{}
+	commands := map[string][]string{}
+
+	client, err := docker.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, res := range spec.Resources {
+		// migration images are pushed as is, so only need to be tagged
+		if migrations := res.GetSqlDatabase().GetImageUri(); migrations != "" {
+			id, err := client.ImageId(migrations)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to find the migrations image of database %s: %w", res.Id.Name, err)
+			}
+
+			ids[migrations] = id
+		}
+
+		uri := res.GetService().GetImage().GetUri()
+		if uri == "" {
+			continue
+		}
+
+		id, err := client.ImageId(uri)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to find the image of service %s: %w", res.Id.Name, err)
+		}
+
+		command, err := client.ImageCommand(uri)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to inspect the image of service %s: %w", res.Id.Name, err)
+		}
+
+		ids[uri] = id
+		commands[uri] = command
+	}
+
+	return ids, commands, nil
+}
+
+// runtime - the runtime added to service images
+func (t *TerraformProvider) runtime(attributes map[string]any, outputDir string) (string, error) {
+	runtime, _ := attributes["runtime"].(string)
+	if runtime == "" && t.cloud == "do" {
+		return "", unpublishedRuntimeError("terraform/do", "DigitalOcean")
+	}
+
+	if runtime == "" {
+		return "", fmt.Errorf("the terraform/%s provider requires the nitric %s runtime to wrap service images with, set runtime in the stack file to its URL or path", t.cloud, t.cloud)
+	}
+
+	return buildContextRuntime(t.fs, t.projectDir, runtime, outputDir)
+}
+
+// buildContextRuntime - returns the runtime to add to service images, URLs are downloaded by docker during the build and
+// local files are copied into the output directory so they're part of the build context
+func buildContextRuntime(fs afero.Fs, projectDir string, runtime string, outputDir string) (string, error) {
+	if strings.HasPrefix(runtime, "https://") || strings.HasPrefix(runtime, "http://") {
+		return runtime, nil
+	}
+
+	if !filepath.IsAbs(runtime) {
+		runtime = filepath.Join(projectDir, runtime)
+	}
+
+	contents, err := afero.ReadFile(fs, runtime)
+	if err != nil {
+		return "", fmt.Errorf("unable to read runtime %s: %w", runtime, err)
+	}
+
+	if err := afero.WriteFile(fs, filepath.Join(outputDir, "runtime"), contents, 0o755); err != nil {
+		return "", fmt.Errorf("unable to copy runtime: %w", err)
+	}
+
+	return "runtime", nil
+}
+
+// unpublishedRuntimeError - the error of a provider missing its runtime when nitric doesn't release one for its platform
+func unpublishedRuntimeError(providerId string, platform string) error {
+	return fmt.Errorf("the %s provider requires a nitric %s runtime to wrap service images with, nitric doesn't release one yet, "+
+		"build it with the server package of github.com/nitrictech/nitric/core, as the runtimes in the cloud directory of github.com/nitrictech/nitric are, "+
+		"and set runtime in the stack file to its URL or path", providerId, platform)
+}
+
+func (t *TerraformProvider) Up(req *deploymentspb.DeploymentUpRequest, stream deploymentspb.Deployment_UpServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+	projectName, _ := attributes["project"].(string)
+	region, _ := attributes["region"].(string)
+	backend, _ := attributes["backend"].(map[string]any)
+	config, _ := attributes["config"].(map[string]any)
+	registry, _ := attributes["registry"].(string)
+	database, _ := attributes["database"].(map[string]any)
+	email, _ := attributes["email"].(map[string]any)
+
+	if region == "" {
+		return fmt.Errorf("a region is required by the terraform/%s provider, set region in the stack file", t.cloud)
+	}
+
+	outputDir := t.outputDir(attributes, stackName)
+
+	if err := t.fs.MkdirAll(outputDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create terraform output directory %s: %w", outputDir, err)
+	}
+
+	runtime, err := t.runtime(attributes, outputDir)
+	if err != nil {
+		return err
+	}
+
+	ids, commands, err := serviceImages(req.Spec)
+	if err != nil {
+		return err
+	}
+
+	tfConfig, unsupported, err := terraformSynthesizers[t.cloud](req.Spec, terraform.Options{
+		Project:  projectName,
+		Stack:    stackName,
+		Region:   region,
+		Backend:  backend,
+		Config:   config,
+		Runtime:  runtime,
+		Registry: registry,
+		Database: database,
+		Email:    email,
+		ImageIds: ids,
+		Commands: commands,
+	})
+	if err != nil {
+		return err
+	}
+
+	contents, err := tfConfig.Marshal()
+	if err != nil {
+		return err
+	}
+
+	configFile := filepath.Join(outputDir, "main.tf.json")
+
+	if err := afero.WriteFile(t.fs, configFile, contents, 0o644); err != nil {
+		return fmt.Errorf("unable to write terraform configuration: %w", err)
+	}
+
+	for name, contents := range tfConfig.Files {
+		if err := afero.WriteFile(t.fs, filepath.Join(outputDir, name), contents, 0o644); err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+	}
+
+	for _, res := range req.Spec.Resources {
+		if res.Id == nil {
+			continue
+		}
+
+		// the configuration is applied with other tools, so resources are left pending rather than reported as deployed
+		status := deploymentspb.ResourceDeploymentStatus_PENDING
+		message := "generated, not applied"
+
+		if u, ok := lo.Find(unsupported, func(u terraform.Unsupported) bool { return u.Id == res.Id }); ok {
+			status = deploymentspb.ResourceDeploymentStatus_FAILED
+			message = u.Reason
+		}
+
+		if err := sendUpUpdate(stream, res.Id, deploymentspb.ResourceDeploymentAction_CREATE, status, message); err != nil {
+			return err
+		}
+	}
+
+	relativeDir, err := filepath.Rel(t.projectDir, outputDir)
+	if err != nil {
+		relativeDir = outputDir
+	}
+
+	summary := []string{
+		fmt.Sprintf("Terraform configuration for stack %s written to %s", stackName, configFile),
+		"No cloud resources have been changed, review and apply the configuration with terraform, e.g.",
+		fmt.Sprintf("  terraform -chdir=%s init", relativeDir),
+		fmt.Sprintf("  terraform -chdir=%s apply", relativeDir),
+		terraformTools[t.cloud],
+	}
+
+	if len(unsupported) > 0 {
+		summary = append(summary, fmt.Sprintf("%d resources are not supported by the terraform/%s provider and were left out", len(unsupported), t.cloud))
+	}
+
+	return stream.Send(&deploymentspb.DeploymentUpEvent{
+		Content: &deploymentspb.DeploymentUpEvent_Result{
+			Result: &deploymentspb.UpResult{
+				Success: len(unsupported) == 0,
+				Content: &deploymentspb.UpResult_Text{Text: strings.Join(summary, "\n")},
+			},
+		},
+	})
+}
+
+func (t *TerraformProvider) Down(req *deploymentspb.DeploymentDownRequest, stream deploymentspb.Deployment_DownServer) error {
+	attributes := req.Attributes.AsMap()
+	stackName, _ := attributes["stack"].(string)
+
+	relativeDir, err := filepath.Rel(t.projectDir, t.outputDir(attributes, stackName))
+	if err != nil {
+		return err
+	}
+
+	// resources deployed from the configuration are managed by terraform state, which the CLI doesn't have access to
+	err = stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Message{
+			Message: fmt.Sprintf("stacks using the terraform/%s provider are deleted with terraform, run terraform -chdir=%s destroy", t.cloud, relativeDir),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return stream.Send(&deploymentspb.DeploymentDownEvent{
+		Content: &deploymentspb.DeploymentDownEvent_Result{
+			Result: &deploymentspb.DownResult{},
+		},
+	})
+}
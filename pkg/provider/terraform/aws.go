@@ -0,0 +1,672 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+	storagepb "github.com/nitrictech/nitric/core/pkg/proto/storage/v1"
+)
+
+// Unsupported - a resource in the spec that can't be represented in the generated configuration
+type Unsupported struct {
+	Id     *resourcespb.ResourceIdentifier
+	Reason string
+}
+
+// awsSynth - collects the terraform resources for a spec
+type awsSynth struct {
+	config   *Config
+	opts     Options
+	services map[string]string
+	// SMTP settings added to the environment of every service, when the project sends email
+	emailEnv map[string]any
+}
+
+// SynthesizeAws - converts a deployment spec into a terraform configuration for AWS, returning the resources that couldn't be converted.
+//
+// Services are deployed as container image lambdas, wrapped with the nitric AWS runtime and pushed to ECR with docker during
+// the apply, other resources are tagged so the runtime can discover them.
+func SynthesizeAws(spec *deploymentspb.Spec, opts Options) (*Config, []Unsupported, error) {
+	s := &awsSynth{
+		config:   newConfig(),
+		opts:     opts,
+		services: map[string]string{},
+		emailEnv: map[string]any{},
+	}
+
+	s.config.Terraform["required_providers"] = map[string]any{
+		"aws":    map[string]any{"source": "hashicorp/aws", "version": ">= 5.0"},
+		"random": map[string]any{"source": "hashicorp/random", "version": ">= 3.0"},
+		"null":   map[string]any{"source": "hashicorp/null", "version": ">= 3.0"},
+	}
+
+	if len(opts.Backend) > 0 {
+		s.config.Terraform["backend"] = opts.Backend
+	}
+
+	s.config.Provider["aws"] = map[string]any{"region": opts.Region}
+
+	// a random suffix keeps the names of globally unique resources like buckets from colliding
+	s.config.addResource("random_id", "stack", map[string]any{"byte_length": 4})
+	s.config.Locals["stack_id"] = fmt.Sprintf("%s-%s-${random_id.stack.hex}", escape(opts.Project), escape(opts.Stack))
+
+	unsupported := []Unsupported{}
+
+	if len(opts.Email) > 0 {
+		s.email()
+	}
+
+	// services are converted first, since other resources reference their lambdas
+	for _, res := range spec.Resources {
+		if service := res.GetService(); service != nil {
+			if err := s.service(res.Id.Name, service); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, res := range spec.Resources {
+		var err error
+
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Service:
+			continue
+		case *deploymentspb.Resource_Bucket:
+			s.bucket(res.Id.Name, config.Bucket)
+		case *deploymentspb.Resource_Topic:
+			s.topic(res.Id.Name, config.Topic)
+		case *deploymentspb.Resource_Queue:
+			s.queue(res.Id.Name)
+		case *deploymentspb.Resource_KeyValueStore:
+			s.keyValueStore(res.Id.Name)
+		case *deploymentspb.Resource_Secret:
+			s.secret(res.Id.Name)
+		case *deploymentspb.Resource_Api:
+			err = s.api(res.Id.Name, config.Api)
+		case *deploymentspb.Resource_Schedule:
+			err = s.schedule(res.Id.Name, config.Schedule)
+		case *deploymentspb.Resource_Policy:
+			s.policy(res.Id.Name, config.Policy)
+		default:
+			unsupported = append(unsupported, Unsupported{
+				Id:     res.Id,
+				Reason: fmt.Sprintf("%s resources are not supported by the terraform/aws provider", strings.ToLower(res.Id.Type.String())),
+			})
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return s.config, unsupported, nil
+}
+
+// tags - the tags used by the nitric AWS runtime to discover deployed resources
+func (s *awsSynth) tags(name string, resourceType string) map[string]any {
+	return map[string]any{
+		"x-nitric-${local.stack_id}-name": escape(name),
+		"x-nitric-${local.stack_id}-type": resourceType,
+	}
+}
+
+// physicalName - a name for a deployed resource that is unique to the stack
+func physicalName(name string) string {
+	return fmt.Sprintf("%s-${random_id.stack.hex}", escape(strings.ToLower(name)))
+}
+
+func (s *awsSynth) service(name string, service *deploymentspb.Service) error {
+	tfName := resourceName(name)
+	imageUri := service.GetImage().GetUri()
+	tag := imageTag(s.opts.ImageIds[imageUri], s.opts.Runtime)
+
+	dockerfile, err := runtimeDockerfile(s.opts.Runtime, s.opts.Commands[imageUri])
+	if err != nil {
+		return err
+	}
+
+	s.config.Files[tfName+".dockerfile"] = dockerfile
+
+	repository := s.config.addResource("aws_ecr_repository", tfName, map[string]any{
+		"name":         physicalName(name),
+		"force_delete": true,
+		"tags":         s.tags(name, "service"),
+	})
+
+	remoteImage := fmt.Sprintf("%s:%s", ref(repository, "repository_url"), tag)
+	registry := fmt.Sprintf("${split(\"/\", %s.repository_url)[0]}", repository)
+
+	// the image is wrapped with the runtime and pushed from the machine running terraform whenever it changes
+	push := s.config.addResource("null_resource", tfName+"_image", map[string]any{
+		"triggers": map[string]any{"image": remoteImage},
+		"provisioner": []any{map[string]any{
+			"local-exec": map[string]any{
+				"command": strings.Join([]string{
+					fmt.Sprintf("docker build --platform linux/amd64 --build-arg BASE_IMAGE=%s -f ${path.module}/%s.dockerfile -t %s ${path.module}", escape(imageUri), tfName, remoteImage),
+					fmt.Sprintf("aws ecr get-login-password --region %s | docker login --username AWS --password-stdin %s", escape(s.opts.Region), registry),
+					pushCommand(remoteImage),
+				}, " && "),
+			},
+		}},
+	})
+
+	role := s.config.addResource("aws_iam_role", tfName, map[string]any{
+		"name_prefix": truncate(resourceName(name), 32),
+		"assume_role_policy": jsonPolicy(map[string]any{
+			"Effect":    "Allow",
+			"Principal": map[string]any{"Service": "lambda.amazonaws.com"},
+			"Action":    "sts:AssumeRole",
+		}),
+		"tags": s.tags(name, "service"),
+	})
+
+	s.config.addResource("aws_iam_role_policy_attachment", tfName+"_basic_execution", map[string]any{
+		"role":       ref(role, "name"),
+		"policy_arn": "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
+	})
+
+	// the runtime looks up resources by their tags
+	s.config.addResource("aws_iam_role_policy", tfName+"_discovery", map[string]any{
+		"role": ref(role, "name"),
+		"policy": jsonPolicy(map[string]any{
+			"Effect":   "Allow",
+			"Action":   []string{"tag:GetResources", "apigateway:GET"},
+			"Resource": "*",
+		}),
+	})
+
+	env := map[string]any{
+		"NITRIC_STACK_ID":    "${local.stack_id}",
+		"NITRIC_ENVIRONMENT": "cloud",
+		"MIN_WORKERS":        fmt.Sprintf("%d", max(service.Workers, 1)),
+	}
+
+	for k, v := range s.emailEnv {
+		env[k] = v
+	}
+
+	for k, v := range service.Env {
+		env[k] = escape(v)
+	}
+
+	lambda := s.config.addResource("aws_lambda_function", tfName, map[string]any{
+		"function_name": physicalName(truncate(name, 55)),
+		"package_type":  "Image",
+		"image_uri":     remoteImage,
+		"role":          ref(role, "arn"),
+		"memory_size":   s.lambdaConfig(service.Type, "memory", 512),
+		"timeout":       s.lambdaConfig(service.Type, "timeout", 15),
+		"environment":   map[string]any{"variables": env},
+		"tags":          s.tags(name, "service"),
+		"depends_on":    []string{push},
+	})
+
+	s.services[name] = lambda
+
+	return nil
+}
+
+// email - verifies the sending domain with SES and creates SMTP credentials for services, or passes them the SendGrid SMTP relay
+// with an API key set when terraform is applied
+func (s *awsSynth) email() {
+	provider, _ := s.opts.Email["provider"].(string)
+	from, _ := s.opts.Email["from"].(string)
+	domain, _ := s.opts.Email["domain"].(string)
+
+	s.emailEnv["NITRIC_EMAIL_FROM"] = escape(from)
+	s.emailEnv["NITRIC_SMTP_PORT"] = "587"
+
+	if provider == "sendgrid" {
+		s.config.Variable["sendgrid_api_key"] = map[string]any{
+			"type":        "string",
+			"sensitive":   true,
+			"description": "SendGrid API key services send email with, e.g. set TF_VAR_sendgrid_api_key",
+		}
+
+		s.emailEnv["NITRIC_SMTP_HOST"] = "smtp.sendgrid.net"
+		s.emailEnv["NITRIC_SMTP_USER"] = "apikey"
+		s.emailEnv["NITRIC_SMTP_PASSWORD"] = "${var.sendgrid_api_key}"
+
+		return
+	}
+
+	identity := s.config.addResource("aws_sesv2_email_identity", "email", map[string]any{
+		"email_identity": escape(domain),
+	})
+
+	// SES SMTP credentials are the access key of a user allowed to send from the verified domain
+	user := s.config.addResource("aws_iam_user", "smtp", map[string]any{
+		"name": "${local.stack_id}-smtp",
+	})
+
+	s.config.addResource("aws_iam_user_policy", "smtp", map[string]any{
+		"user": ref(user, "name"),
+		"policy": jsonPolicy(map[string]any{
+			"Effect":   "Allow",
+			"Action":   []string{"ses:SendRawEmail"},
+			"Resource": ref(identity, "arn"),
+		}),
+	})
+
+	accessKey := s.config.addResource("aws_iam_access_key", "smtp", map[string]any{
+		"user": ref(user, "name"),
+	})
+
+	s.emailEnv["NITRIC_SMTP_HOST"] = fmt.Sprintf("email-smtp.%s.amazonaws.com", escape(s.opts.Region))
+	s.emailEnv["NITRIC_SMTP_USER"] = ref(accessKey, "id")
+	s.emailEnv["NITRIC_SMTP_PASSWORD"] = ref(accessKey, "ses_smtp_password_v4")
+
+	// the domain can send once these records are added to its DNS zone
+	s.config.Output["email_dkim_records"] = map[string]any{
+		"description": fmt.Sprintf("CNAME records verifying %s with SES", escape(domain)),
+		"value":       fmt.Sprintf("${[for token in %s.dkim_signing_attributes[0].tokens : \"${token}._domainkey.%s CNAME ${token}.dkim.amazonses.com\"]}", identity, escape(domain)),
+	}
+}
+
+// lambdaConfig - returns a lambda setting for a service type from the stack config, falling back to the default type
+func (s *awsSynth) lambdaConfig(serviceType string, key string, fallback any) any {
+	return serviceConfig(s.opts.Config, "lambda", serviceType, key, fallback)
+}
+
+// invokePermission - allows an AWS service to invoke the lambda of a nitric service
+func (s *awsSynth) invokePermission(name string, serviceName string, principal string, sourceArn string) {
+	s.config.addResource("aws_lambda_permission", name, map[string]any{
+		"action":        "lambda:InvokeFunction",
+		"function_name": ref(s.services[serviceName], "function_name"),
+		"principal":     principal,
+		"source_arn":    sourceArn,
+	})
+}
+
+func (s *awsSynth) bucket(name string, bucket *deploymentspb.Bucket) {
+	tfName := resourceName(name)
+
+	address := s.config.addResource("aws_s3_bucket", tfName, map[string]any{
+		// bucket names can't contain underscores
+		"bucket": physicalName(strings.ReplaceAll(truncate(name, 54), "_", "-")),
+		"tags":   s.tags(name, "bucket"),
+	})
+
+	if len(bucket.Listeners) == 0 {
+		return
+	}
+
+	notifications := []any{}
+	permissions := []string{}
+
+	for i, listener := range bucket.Listeners {
+		permission := fmt.Sprintf("%s_%s_%d", tfName, resourceName(listener.GetService()), i)
+		s.invokePermission(permission, listener.GetService(), "s3.amazonaws.com", ref(address, "arn"))
+		permissions = append(permissions, "aws_lambda_permission."+permission)
+
+		events := lo.Ternary(listener.GetConfig().GetBlobEventType() == storagepb.BlobEventType_Deleted, "s3:ObjectRemoved:*", "s3:ObjectCreated:*")
+
+		notifications = append(notifications, map[string]any{
+			"lambda_function_arn": ref(s.services[listener.GetService()], "arn"),
+			"events":              []string{events},
+			"filter_prefix":       escape(listener.GetConfig().GetKeyPrefixFilter()),
+		})
+	}
+
+	s.config.addResource("aws_s3_bucket_notification", tfName, map[string]any{
+		"bucket":          ref(address, "id"),
+		"lambda_function": notifications,
+		"depends_on":      permissions,
+	})
+}
+
+func (s *awsSynth) topic(name string, topic *deploymentspb.Topic) {
+	tfName := resourceName(name)
+
+	address := s.config.addResource("aws_sns_topic", tfName, map[string]any{
+		"name": physicalName(truncate(name, 245)),
+		"tags": s.tags(name, "topic"),
+	})
+
+	for i, subscription := range topic.Subscriptions {
+		subscriptionName := fmt.Sprintf("%s_%s_%d", tfName, resourceName(subscription.GetService()), i)
+
+		s.invokePermission(subscriptionName, subscription.GetService(), "sns.amazonaws.com", ref(address, "arn"))
+
+		s.config.addResource("aws_sns_topic_subscription", subscriptionName, map[string]any{
+			"topic_arn": ref(address, "arn"),
+			"protocol":  "lambda",
+			"endpoint":  ref(s.services[subscription.GetService()], "arn"),
+		})
+	}
+}
+
+func (s *awsSynth) queue(name string) {
+	s.config.addResource("aws_sqs_queue", resourceName(name), map[string]any{
+		"name": physicalName(truncate(name, 70)),
+		"tags": s.tags(name, "queue"),
+	})
+}
+
+func (s *awsSynth) keyValueStore(name string) {
+	s.config.addResource("aws_dynamodb_table", resourceName(name), map[string]any{
+		"name":         physicalName(name),
+		"billing_mode": "PAY_PER_REQUEST",
+		"hash_key":     "_pk",
+		"range_key":    "_sk",
+		"attribute": []any{
+			map[string]any{"name": "_pk", "type": "S"},
+			map[string]any{"name": "_sk", "type": "S"},
+		},
+		"tags": s.tags(name, "kvstore"),
+	})
+}
+
+func (s *awsSynth) secret(name string) {
+	s.config.addResource("aws_secretsmanager_secret", resourceName(name), map[string]any{
+		"name": physicalName(name),
+		"tags": s.tags(name, "secret"),
+	})
+}
+
+// api - deploys an API as an API Gateway HTTP API, importing its OpenAPI document with lambda integrations for each operation
+func (s *awsSynth) api(name string, api *deploymentspb.Api) error {
+	tfName := resourceName(name)
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+	if err != nil {
+		return fmt.Errorf("unable to read openapi document of api %s: %w", name, err)
+	}
+
+	targets := []string{}
+
+	for _, item := range doc.Paths {
+		for _, operation := range item.Operations() {
+			target := operationTarget(operation)
+			if _, ok := s.services[target]; !ok {
+				continue
+			}
+
+			targets = append(targets, target)
+
+			if operation.Extensions == nil {
+				operation.Extensions = map[string]any{}
+			}
+
+			// replaced with a reference to the lambda once the document is escaped
+			operation.Extensions["x-amazon-apigateway-integration"] = map[string]any{
+				"type":                 "aws_proxy",
+				"httpMethod":           "POST",
+				"payloadFormatVersion": "2.0",
+				"uri":                  integrationPlaceholder(target),
+			}
+		}
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	slices.Sort(targets)
+	targets = slices.Compact(targets)
+
+	replacements := []string{}
+	for _, target := range targets {
+		replacements = append(replacements, integrationPlaceholder(target), ref(s.services[target], "invoke_arn"))
+	}
+
+	address := s.config.addResource("aws_apigatewayv2_api", tfName, map[string]any{
+		"name":          physicalName(name),
+		"protocol_type": "HTTP",
+		"body":          strings.NewReplacer(replacements...).Replace(escape(string(body))),
+		"tags":          s.tags(name, "api"),
+	})
+
+	s.config.addResource("aws_apigatewayv2_stage", tfName, map[string]any{
+		"api_id":      ref(address, "id"),
+		"name":        "$default",
+		"auto_deploy": true,
+	})
+
+	for _, target := range targets {
+		s.invokePermission(fmt.Sprintf("%s_%s", tfName, resourceName(target)), target, "apigateway.amazonaws.com", fmt.Sprintf("%s/*/*", ref(address, "execution_arn")))
+	}
+
+	s.config.Output["api_"+tfName] = map[string]any{
+		"description": fmt.Sprintf("Endpoint of the %s API", escape(name)),
+		"value":       ref(address, "api_endpoint"),
+	}
+
+	return nil
+}
+
+func integrationPlaceholder(serviceName string) string {
+	return fmt.Sprintf("__nitric_integration_%s__", serviceName)
+}
+
+// operationTarget - returns the name of the service handling an operation from its x-nitric-target extension
+func operationTarget(operation *openapi3.Operation) string {
+	data, err := json.Marshal(operation.Extensions["x-nitric-target"])
+	if err != nil {
+		return ""
+	}
+
+	target := struct {
+		Name string `json:"name"`
+	}{}
+
+	_ = json.Unmarshal(data, &target)
+
+	return target.Name
+}
+
+// scheduleExpression - converts a nitric rate or cron schedule to an EventBridge Scheduler expression
+func scheduleExpression(schedule *deploymentspb.Schedule) (string, error) {
+	if every := schedule.GetEvery(); every != nil {
+		amount, unit, ok := strings.Cut(strings.TrimSpace(every.Rate), " ")
+		if !ok {
+			return "", fmt.Errorf("invalid schedule rate %s", every.Rate)
+		}
+
+		// EventBridge requires singular units for a rate of 1, e.g. rate(1 minute)
+		if amount == "1" {
+			unit = strings.TrimSuffix(unit, "s")
+		} else if !strings.HasSuffix(unit, "s") {
+			unit += "s"
+		}
+
+		return fmt.Sprintf("rate(%s %s)", amount, unit), nil
+	}
+
+	fields := strings.Fields(schedule.GetCron().GetExpression())
+	if len(fields) != 5 {
+		return "", fmt.Errorf("invalid cron expression %s", schedule.GetCron().GetExpression())
+	}
+
+	// EventBridge cron expressions have a year field, and one of day of month or day of week must be ?
+	if fields[4] == "*" {
+		fields[4] = "?"
+	} else if fields[2] == "*" {
+		fields[2] = "?"
+	}
+
+	return fmt.Sprintf("cron(%s *)", strings.Join(fields, " ")), nil
+}
+
+func (s *awsSynth) schedule(name string, schedule *deploymentspb.Schedule) error {
+	tfName := resourceName(name)
+	target := schedule.GetTarget().GetService()
+
+	expression, err := scheduleExpression(schedule)
+	if err != nil {
+		return fmt.Errorf("unable to convert schedule %s: %w", name, err)
+	}
+
+	role := s.config.addResource("aws_iam_role", tfName+"_schedule", map[string]any{
+		"name_prefix": truncate(resourceName(name), 32),
+		"assume_role_policy": jsonPolicy(map[string]any{
+			"Effect":    "Allow",
+			"Principal": map[string]any{"Service": "scheduler.amazonaws.com"},
+			"Action":    "sts:AssumeRole",
+		}),
+	})
+
+	s.config.addResource("aws_iam_role_policy", tfName+"_schedule", map[string]any{
+		"role": ref(role, "name"),
+		"policy": jsonPolicy(map[string]any{
+			"Effect":   "Allow",
+			"Action":   "lambda:InvokeFunction",
+			"Resource": ref(s.services[target], "arn"),
+		}),
+	})
+
+	input, err := json.Marshal(map[string]string{"x-nitric-schedule": name})
+	if err != nil {
+		return err
+	}
+
+	s.config.addResource("aws_scheduler_schedule", tfName, map[string]any{
+		"name":                physicalName(truncate(name, 54)),
+		"schedule_expression": expression,
+		"flexible_time_window": map[string]any{
+			"mode": "OFF",
+		},
+		"target": map[string]any{
+			"arn":      ref(s.services[target], "arn"),
+			"role_arn": ref(role, "arn"),
+			"input":    escape(string(input)),
+		},
+	})
+
+	return nil
+}
+
+// actionResourceTypes - the type of resource each nitric action applies to
+var actionResourceTypes = map[resourcespb.Action]resourcespb.ResourceType{
+	resourcespb.Action_BucketFileList:      resourcespb.ResourceType_Bucket,
+	resourcespb.Action_BucketFileGet:       resourcespb.ResourceType_Bucket,
+	resourcespb.Action_BucketFilePut:       resourcespb.ResourceType_Bucket,
+	resourcespb.Action_BucketFileDelete:    resourcespb.ResourceType_Bucket,
+	resourcespb.Action_TopicPublish:        resourcespb.ResourceType_Topic,
+	resourcespb.Action_KeyValueStoreRead:   resourcespb.ResourceType_KeyValueStore,
+	resourcespb.Action_KeyValueStoreWrite:  resourcespb.ResourceType_KeyValueStore,
+	resourcespb.Action_KeyValueStoreDelete: resourcespb.ResourceType_KeyValueStore,
+	resourcespb.Action_SecretPut:           resourcespb.ResourceType_Secret,
+	resourcespb.Action_SecretAccess:        resourcespb.ResourceType_Secret,
+	resourcespb.Action_QueueEnqueue:        resourcespb.ResourceType_Queue,
+	resourcespb.Action_QueueDequeue:        resourcespb.ResourceType_Queue,
+}
+
+// awsActions - the IAM actions and resource ARNs granted for a nitric action on a resource
+func (s *awsSynth) awsActions(action resourcespb.Action, res *deploymentspb.Resource) ([]string, []string) {
+	// a policy can list actions for several types of resource, each only applies to its own type
+	if resourceType, ok := actionResourceTypes[action]; !ok || resourceType != res.Id.Type {
+		return nil, nil
+	}
+
+	name := resourceName(res.Id.Name)
+
+	switch action {
+	case resourcespb.Action_BucketFileList:
+		return []string{"s3:ListBucket"}, []string{ref("aws_s3_bucket."+name, "arn")}
+	case resourcespb.Action_BucketFileGet:
+		return []string{"s3:GetObject"}, []string{ref("aws_s3_bucket."+name, "arn") + "/*"}
+	case resourcespb.Action_BucketFilePut:
+		return []string{"s3:PutObject"}, []string{ref("aws_s3_bucket."+name, "arn") + "/*"}
+	case resourcespb.Action_BucketFileDelete:
+		return []string{"s3:DeleteObject"}, []string{ref("aws_s3_bucket."+name, "arn") + "/*"}
+	case resourcespb.Action_TopicPublish:
+		return []string{"sns:Publish"}, []string{ref("aws_sns_topic."+name, "arn")}
+	case resourcespb.Action_KeyValueStoreRead:
+		return []string{"dynamodb:GetItem", "dynamodb:Query", "dynamodb:Scan"}, []string{ref("aws_dynamodb_table."+name, "arn")}
+	case resourcespb.Action_KeyValueStoreWrite:
+		return []string{"dynamodb:PutItem", "dynamodb:UpdateItem"}, []string{ref("aws_dynamodb_table."+name, "arn")}
+	case resourcespb.Action_KeyValueStoreDelete:
+		return []string{"dynamodb:DeleteItem"}, []string{ref("aws_dynamodb_table."+name, "arn")}
+	case resourcespb.Action_SecretPut:
+		return []string{"secretsmanager:PutSecretValue"}, []string{ref("aws_secretsmanager_secret."+name, "arn")}
+	case resourcespb.Action_SecretAccess:
+		return []string{"secretsmanager:GetSecretValue"}, []string{ref("aws_secretsmanager_secret."+name, "arn")}
+	case resourcespb.Action_QueueEnqueue:
+		return []string{"sqs:SendMessage"}, []string{ref("aws_sqs_queue."+name, "arn")}
+	case resourcespb.Action_QueueDequeue:
+		return []string{"sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:ChangeMessageVisibility"}, []string{ref("aws_sqs_queue."+name, "arn")}
+	}
+
+	return nil, nil
+}
+
+// policy - grants the roles of the principal services access to the policy's resources
+func (s *awsSynth) policy(name string, policy *deploymentspb.Policy) {
+	actions := []string{}
+	resources := []string{}
+
+	for _, res := range policy.Resources {
+		for _, action := range policy.Actions {
+			awsActions, arns := s.awsActions(action, res)
+			actions = append(actions, awsActions...)
+			resources = append(resources, arns...)
+		}
+	}
+
+	if len(actions) == 0 {
+		return
+	}
+
+	slices.Sort(actions)
+	slices.Sort(resources)
+
+	for _, principal := range policy.Principals {
+		if _, ok := s.services[principal.Id.Name]; !ok {
+			continue
+		}
+
+		principalName := resourceName(principal.Id.Name)
+
+		s.config.addResource("aws_iam_role_policy", fmt.Sprintf("%s_%s", principalName, resourceName(name)), map[string]any{
+			"role": ref("aws_iam_role."+principalName, "name"),
+			"policy": jsonPolicy(map[string]any{
+				"Effect":   "Allow",
+				"Action":   slices.Compact(actions),
+				"Resource": slices.Compact(resources),
+			}),
+		})
+	}
+}
+
+// jsonPolicy - an IAM policy document containing a single statement
+func jsonPolicy(statement map[string]any) string {
+	data, _ := json.Marshal(map[string]any{
+		"Version":   "2012-10-17",
+		"Statement": []any{statement},
+	})
+
+	return string(data)
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+
+	return value
+}
@@ -0,0 +1,194 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+	storagepb "github.com/nitrictech/nitric/core/pkg/proto/storage/v1"
+)
+
+const testOpenapi = `{
+  "openapi": "3.0.1",
+  "info": {"title": "main", "version": "v1"},
+  "paths": {
+    "/orders": {
+      "get": {
+        "operationId": "orders-get",
+        "x-nitric-target": {"name": "orders", "type": "service"},
+        "responses": {"default": {"description": "default response"}}
+      }
+    }
+  }
+}`
+
+func testSpec() *deploymentspb.Spec {
+	resource := func(name string, resourceType resourcespb.ResourceType) *resourcespb.ResourceIdentifier {
+		return &resourcespb.ResourceIdentifier{Name: name, Type: resourceType}
+	}
+
+	return &deploymentspb.Spec{
+		Resources: []*deploymentspb.Resource{
+			{
+				Id: resource("orders", resourcespb.ResourceType_Service),
+				Config: &deploymentspb.Resource_Service{Service: &deploymentspb.Service{
+					Source:  &deploymentspb.Service_Image{Image: &deploymentspb.ImageSource{Uri: "orders-image"}},
+					Type:    "default",
+					Workers: 2,
+					Env:     map[string]string{"LOG_LEVEL": "debug"},
+				}},
+			},
+			{
+				Id:     resource("main", resourcespb.ResourceType_Api),
+				Config: &deploymentspb.Resource_Api{Api: &deploymentspb.Api{Document: &deploymentspb.Api_Openapi{Openapi: testOpenapi}}},
+			},
+			{
+				Id: resource("receipts", resourcespb.ResourceType_Bucket),
+				Config: &deploymentspb.Resource_Bucket{Bucket: &deploymentspb.Bucket{Listeners: []*deploymentspb.BucketListener{{
+					Config: &storagepb.RegistrationRequest{BlobEventType: storagepb.BlobEventType_Created, KeyPrefixFilter: "uploads/"},
+					Target: &deploymentspb.BucketListener_Service{Service: "orders"},
+				}}}},
+			},
+			{
+				Id: resource("updates", resourcespb.ResourceType_Topic),
+				Config: &deploymentspb.Resource_Topic{Topic: &deploymentspb.Topic{Subscriptions: []*deploymentspb.SubscriptionTarget{{
+					Target: &deploymentspb.SubscriptionTarget_Service{Service: "orders"},
+				}}}},
+			},
+			{
+				Id:     resource("jobs", resourcespb.ResourceType_Queue),
+				Config: &deploymentspb.Resource_Queue{Queue: &deploymentspb.Queue{}},
+			},
+			{
+				Id:     resource("carts", resourcespb.ResourceType_KeyValueStore),
+				Config: &deploymentspb.Resource_KeyValueStore{KeyValueStore: &deploymentspb.KeyValueStore{}},
+			},
+			{
+				Id:     resource("api-key", resourcespb.ResourceType_Secret),
+				Config: &deploymentspb.Resource_Secret{Secret: &deploymentspb.Secret{}},
+			},
+			{
+				Id: resource("nightly", resourcespb.ResourceType_Schedule),
+				Config: &deploymentspb.Resource_Schedule{Schedule: &deploymentspb.Schedule{
+					Target:  &deploymentspb.ScheduleTarget{Target: &deploymentspb.ScheduleTarget_Service{Service: "orders"}},
+					Cadence: &deploymentspb.Schedule_Cron{Cron: &deploymentspb.ScheduleCron{Expression: "0 2 * * *"}},
+				}},
+			},
+			{
+				Id: resource("orders-access", resourcespb.ResourceType_Policy),
+				Config: &deploymentspb.Resource_Policy{Policy: &deploymentspb.Policy{
+					Principals: []*deploymentspb.Resource{{Id: resource("orders", resourcespb.ResourceType_Service)}},
+					Actions: []resourcespb.Action{
+						resourcespb.Action_BucketFileGet,
+						resourcespb.Action_BucketFilePut,
+						resourcespb.Action_TopicPublish,
+						resourcespb.Action_QueueEnqueue,
+						resourcespb.Action_KeyValueStoreRead,
+						resourcespb.Action_SecretAccess,
+					},
+					Resources: []*deploymentspb.Resource{
+						{Id: resource("receipts", resourcespb.ResourceType_Bucket)},
+						{Id: resource("updates", resourcespb.ResourceType_Topic)},
+						{Id: resource("jobs", resourcespb.ResourceType_Queue)},
+						{Id: resource("carts", resourcespb.ResourceType_KeyValueStore)},
+						{Id: resource("api-key", resourcespb.ResourceType_Secret)},
+					},
+				}},
+			},
+			{
+				Id:     resource("events", resourcespb.ResourceType_Websocket),
+				Config: &deploymentspb.Resource_Websocket{Websocket: &deploymentspb.Websocket{}},
+			},
+		},
+	}
+}
+
+func TestSynthesizeAws(t *testing.T) {
+	awsFile, _ := os.ReadFile("aws.tf.json")
+	sesFile, _ := os.ReadFile("aws-ses.tf.json")
+	dockerfile, _ := os.ReadFile("aws.dockerfile")
+
+	opts := Options{
+		Project:  "shop",
+		Stack:    "prod",
+		Region:   "us-east-1",
+		Backend:  map[string]any{"s3": map[string]any{"bucket": "shop-state", "key": "prod.tfstate"}},
+		Config:   map[string]any{"default": map[string]any{"lambda": map[string]any{"memory": 1024}}},
+		Runtime:  "https://github.com/nitrictech/nitric/releases/download/v1.0.0/runtime-aws",
+		ImageIds: map[string]string{"orders-image": "sha256:0123456789abcdef"},
+		Commands: map[string][]string{"orders-image": {"node", "orders.js"}},
+	}
+
+	sesOpts := opts
+	sesOpts.Email = map[string]any{"provider": "ses", "from": "noreply@example.com", "domain": "example.com"}
+
+	tests := []struct {
+		name            string
+		opts            Options
+		wantConfig      string
+		wantUnsupported []string
+	}{
+		{
+			name:            "aws",
+			opts:            opts,
+			wantConfig:      string(awsFile),
+			wantUnsupported: []string{"events"},
+		},
+		{
+			name:            "ses",
+			opts:            sesOpts,
+			wantConfig:      string(sesFile),
+			wantUnsupported: []string{"events"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, unsupported, err := SynthesizeAws(testSpec(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := config.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.wantConfig, string(got)); diff != "" {
+				t.Errorf("SynthesizeAws() mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(string(dockerfile), string(config.Files["orders.dockerfile"])); diff != "" {
+				t.Errorf("SynthesizeAws() dockerfile mismatch (-want +got):\n%s", diff)
+			}
+
+			gotUnsupported := []string{}
+			for _, u := range unsupported {
+				gotUnsupported = append(gotUnsupported, u.Id.Name)
+			}
+
+			if diff := cmp.Diff(tt.wantUnsupported, gotUnsupported); diff != "" {
+				t.Errorf("SynthesizeAws() unsupported mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
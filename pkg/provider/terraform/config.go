@@ -0,0 +1,174 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Options - stack settings a terraform configuration is generated with
+type Options struct {
+	Project string
+	Stack   string
+	Region  string
+	// Terraform backend to store state in, keyed by backend type e.g. {"s3": {"bucket": "my-state", "key": "app.tfstate"}}
+	Backend map[string]any
+	// Deployment configuration keyed by service type, as set under config in the stack file, e.g. {"default": {"lambda": {"memory": 512}}}
+	Config map[string]any
+	// URL of the nitric runtime for the cloud, or the name of a file in the output directory, added to service images as their entrypoint
+	Runtime string
+	// Name of an existing container registry images are pushed to, for clouds without a registry per service, e.g. DigitalOcean
+	Registry string
+	// Database cluster settings as set under database in the stack file, e.g. {"size": "db-s-1vcpu-2gb"}
+	Database map[string]any
+	// Content addressable IDs of the service and migration images, keyed by image URI, used to tag pushed images
+	ImageIds map[string]string
+	// Entrypoint and command of the service images, keyed by image URI, run by the runtime
+	Commands map[string][]string
+	// How services send email as set under email in the stack file, with the from address in nitric.yaml, e.g. {"provider": "ses", "from": "noreply@example.com", "domain": "example.com"}
+	Email map[string]any
+}
+
+// Config - a terraform configuration in the JSON configuration syntax, see https://developer.hashicorp.com/terraform/language/syntax/json
+type Config struct {
+	Terraform map[string]any            `json:"terraform"`
+	Provider  map[string]any            `json:"provider"`
+	Locals    map[string]any            `json:"locals,omitempty"`
+	Resource  map[string]map[string]any `json:"resource"`
+	Output    map[string]any            `json:"output,omitempty"`
+	Variable  map[string]any            `json:"variable,omitempty"`
+	// Additional files written alongside the configuration, keyed by file name, e.g. the dockerfiles of service images
+	Files map[string][]byte `json:"-"`
+}
+
+func newConfig() *Config {
+	return &Config{
+		Terraform: map[string]any{},
+		Provider:  map[string]any{},
+		Locals:    map[string]any{},
+		Resource:  map[string]map[string]any{},
+		Output:    map[string]any{},
+		Variable:  map[string]any{},
+		Files:     map[string][]byte{},
+	}
+}
+
+// addResource - adds a resource to the configuration, returning its address e.g. aws_s3_bucket.images
+func (c *Config) addResource(resourceType string, name string, body map[string]any) string {
+	if _, ok := c.Resource[resourceType]; !ok {
+		c.Resource[resourceType] = map[string]any{}
+	}
+
+	c.Resource[resourceType][name] = body
+
+	return fmt.Sprintf("%s.%s", resourceType, name)
+}
+
+// Marshal - renders the configuration as indented JSON, ready to be written to a .tf.json file
+func (c *Config) Marshal() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	// commands and policies are easier to review without & and > escaped
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// resourceName - converts a nitric resource name to a valid terraform resource name
+func resourceName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+
+	// terraform names must start with a letter or underscore
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// ref - returns an interpolated reference to an attribute of a resource
+func ref(address string, attribute string) string {
+	return fmt.Sprintf("${%s.%s}", address, attribute)
+}
+
+// escape - escapes template sequences in a literal string, so terraform doesn't interpret them
+func escape(value string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(value)
+}
+
+// pushRetries - the number of times image pushes are retried with a growing delay, registries skip layers that were
+// already uploaded so a retried push resumes from the remaining layers
+const pushRetries = 3
+
+// pushCommand - a shell command pushing an image, retried when the push fails, e.g. due to a registry or network error
+func pushCommand(image string) string {
+	return fmt.Sprintf("for attempt in $(seq 0 %d); do [ $attempt -gt 0 ] && sleep $((attempt * 10)); docker push %s && break; [ $attempt -eq %d ] && exit 1; done", pushRetries, image, pushRetries)
+}
+
+// runtimeDockerfile - a dockerfile wrapping a service image with the nitric runtime for a cloud, which starts the service's
+// original command as a child process, runtime is a URL or a file in the build context
+func runtimeDockerfile(runtime string, command []string) ([]byte, error) {
+	cmd, err := json.Marshal(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join([]string{
+		"ARG BASE_IMAGE",
+		"FROM ${BASE_IMAGE}",
+		fmt.Sprintf("ADD --chmod=755 %s /bin/runtime", runtime),
+		`ENTRYPOINT ["/bin/runtime"]`,
+		fmt.Sprintf("CMD %s", cmd),
+		"",
+	}, "\n")), nil
+}
+
+// imageTag - a tag for a service image wrapped with the runtime, changing whenever the image or runtime does
+func imageTag(imageId string, runtime string) string {
+	hash := sha256.Sum256([]byte(imageId + runtime))
+
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// serviceConfig - returns a setting from a section of the stack config for a service type, falling back to the default type,
+// e.g. config.default.lambda.memory
+func serviceConfig(config map[string]any, section string, serviceType string, key string, fallback any) any {
+	for _, t := range []string{serviceType, "default"} {
+		typeConfig, _ := config[t].(map[string]any)
+		sectionConfig, _ := typeConfig[section].(map[string]any)
+
+		if value, ok := sectionConfig[key]; ok {
+			return value
+		}
+	}
+
+	return fallback
+}
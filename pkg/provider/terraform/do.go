@@ -0,0 +1,565 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/samber/lo"
+
+	deploymentspb "github.com/nitrictech/nitric/core/pkg/proto/deployments/v1"
+	resourcespb "github.com/nitrictech/nitric/core/pkg/proto/resources/v1"
+)
+
+// doComponent - an App Platform service or job, rendered into the app spec once every resource has been converted
+type doComponent struct {
+	attributes map[string]any
+	env        map[string]string
+	secrets    map[string]bool
+}
+
+// doRoute - an App Platform ingress rule routing a path prefix to a service
+type doRoute struct {
+	prefix  string
+	service string
+	rewrite string
+}
+
+// doSynth - collects the terraform resources and App Platform components for a spec
+type doSynth struct {
+	config      *Config
+	opts        Options
+	services    map[string]*doComponent
+	jobs        []*doComponent
+	databases   []any
+	buckets     map[string]string
+	routes      map[string]doRoute
+	grants      map[string]map[string]string
+	pushes      []string
+	unsupported []Unsupported
+}
+
+// SynthesizeDigitalOcean - converts a deployment spec into a terraform configuration for DigitalOcean, returning the resources
+// that couldn't be converted.
+//
+// Services are deployed as the services of a single App Platform app, wrapped with the nitric runtime and pushed to an existing
+// container registry with docker during the apply. Buckets are deployed as Spaces and sql databases share a managed Postgres
+// cluster, their names and credentials are passed to services as environment variables.
+func SynthesizeDigitalOcean(spec *deploymentspb.Spec, opts Options) (*Config, []Unsupported, error) {
+	if opts.Registry == "" {
+		return nil, nil, fmt.Errorf("a container registry is required by the terraform/do provider, set registry in the stack file to the name of your DigitalOcean container registry")
+	}
+
+	s := &doSynth{
+		config:   newConfig(),
+		opts:     opts,
+		services: map[string]*doComponent{},
+		buckets:  map[string]string{},
+		routes:   map[string]doRoute{},
+		grants:   map[string]map[string]string{},
+	}
+
+	s.config.Terraform["required_providers"] = map[string]any{
+		"digitalocean": map[string]any{"source": "digitalocean/digitalocean", "version": ">= 2.42"},
+		"random":       map[string]any{"source": "hashicorp/random", "version": ">= 3.0"},
+		"null":         map[string]any{"source": "hashicorp/null", "version": ">= 3.0"},
+	}
+
+	if len(opts.Backend) > 0 {
+		s.config.Terraform["backend"] = opts.Backend
+	}
+
+	// credentials are read from the DIGITALOCEAN_TOKEN, SPACES_ACCESS_KEY_ID and SPACES_SECRET_ACCESS_KEY environment variables
+	s.config.Provider["digitalocean"] = map[string]any{}
+
+	// a random suffix keeps the names of globally unique resources like buckets from colliding
+	s.config.addResource("random_id", "stack", map[string]any{"byte_length": 4})
+	s.config.Locals["stack_id"] = fmt.Sprintf("%s-%s-${random_id.stack.hex}", escape(opts.Project), escape(opts.Stack))
+
+	// services are converted first, since other resources route to or grant access to them
+	for _, res := range spec.Resources {
+		if service := res.GetService(); service != nil {
+			if err := s.service(res.Id.Name, service); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// policies are converted last, since they grant access to the buckets
+	policies := []*deploymentspb.Policy{}
+
+	for _, res := range spec.Resources {
+		var err error
+
+		switch config := res.Config.(type) {
+		case *deploymentspb.Resource_Service:
+			continue
+		case *deploymentspb.Resource_Bucket:
+			s.bucket(res.Id, config.Bucket)
+		case *deploymentspb.Resource_SqlDatabase:
+			s.sqlDatabase(res.Id.Name, config.SqlDatabase)
+		case *deploymentspb.Resource_Api:
+			err = s.api(res.Id, config.Api)
+		case *deploymentspb.Resource_Http:
+			s.route(res.Id, "/", config.Http.GetTarget().GetService(), "")
+		case *deploymentspb.Resource_Policy:
+			policies = append(policies, config.Policy)
+		default:
+			s.unsupported = append(s.unsupported, Unsupported{
+				Id:     res.Id,
+				Reason: fmt.Sprintf("%s resources are not supported by the terraform/do provider", strings.ToLower(res.Id.Type.String())),
+			})
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, policy := range policies {
+		s.policy(policy)
+	}
+
+	s.spacesKeys()
+	s.app()
+
+	return s.config, s.unsupported, nil
+}
+
+var invalidComponentChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// componentName - a valid App Platform component name, lowercase alphanumeric and dashes of at most 32 characters
+func componentName(name string) string {
+	name = invalidComponentChars.ReplaceAllString(strings.ToLower(name), "-")
+
+	return strings.Trim(truncate(name, 32), "-")
+}
+
+// envName - an environment variable name for a resource, e.g. NITRIC_BUCKET_MY_IMAGES for the bucket my-images
+func envName(prefix string, name string) string {
+	return prefix + strings.ToUpper(invalidNameChars.ReplaceAllString(strings.ReplaceAll(name, "-", "_"), "_"))
+}
+
+// appPlatformConfig - returns an App Platform setting for a service type from the stack config, falling back to the default type
+func (s *doSynth) appPlatformConfig(serviceType string, key string, fallback any) any {
+	return serviceConfig(s.opts.Config, "app-platform", serviceType, key, fallback)
+}
+
+// remoteImage - the registry image an image is pushed to, returning its repository and tag
+func (s *doSynth) remoteImage(name string, tag string) (string, string, string) {
+	repository := componentName(fmt.Sprintf("%s-%s", s.opts.Stack, name))
+
+	return fmt.Sprintf("registry.digitalocean.com/%s/%s:%s", escape(s.opts.Registry), repository, tag), repository, tag
+}
+
+// push - pushes an image to the registry from the machine running terraform whenever it changes, after running the prepare command
+func (s *doSynth) push(tfName string, remoteImage string, prepare string) {
+	push := s.config.addResource("null_resource", tfName+"_image", map[string]any{
+		"triggers": map[string]any{"image": remoteImage},
+		"provisioner": []any{map[string]any{
+			"local-exec": map[string]any{
+				"command": strings.Join([]string{
+					prepare,
+					"doctl registry login",
+					pushCommand(remoteImage),
+				}, " && "),
+			},
+		}},
+	})
+
+	s.pushes = append(s.pushes, push)
+}
+
+func (s *doSynth) service(name string, service *deploymentspb.Service) error {
+	tfName := resourceName(name)
+	imageUri := service.GetImage().GetUri()
+
+	dockerfile, err := runtimeDockerfile(s.opts.Runtime, s.opts.Commands[imageUri])
+	if err != nil {
+		return err
+	}
+
+	s.config.Files[tfName+".dockerfile"] = dockerfile
+
+	remoteImage, repository, tag := s.remoteImage(name, imageTag(s.opts.ImageIds[imageUri], s.opts.Runtime))
+
+	// the image is wrapped with the runtime before it's pushed
+	s.push(tfName, remoteImage, fmt.Sprintf("docker build --platform linux/amd64 --build-arg BASE_IMAGE=%s -f ${path.module}/%s.dockerfile -t %s ${path.module}", escape(imageUri), tfName, remoteImage))
+
+	component := &doComponent{
+		attributes: map[string]any{
+			"name": componentName(name),
+			"image": []any{map[string]any{
+				"registry_type": "DOCR",
+				"repository":    repository,
+				"tag":           tag,
+			}},
+			"instance_size_slug": s.appPlatformConfig(service.Type, "instance-size", "apps-s-1vcpu-0.5gb"),
+			"instance_count":     s.appPlatformConfig(service.Type, "instance-count", 1),
+			// App Platform sets PORT to the http port, which the runtime listens on
+			"http_port": 8080,
+		},
+		env: map[string]string{
+			"NITRIC_STACK_ID":    "${local.stack_id}",
+			"NITRIC_ENVIRONMENT": "cloud",
+			"MIN_WORKERS":        fmt.Sprintf("%d", max(service.Workers, 1)),
+		},
+		secrets: map[string]bool{},
+	}
+
+	for k, v := range service.Env {
+		component.env[k] = escape(v)
+	}
+
+	s.services[name] = component
+
+	return nil
+}
+
+// bucket - deploys a bucket as a Space, which doesn't support event notifications
+func (s *doSynth) bucket(id *resourcespb.ResourceIdentifier, bucket *deploymentspb.Bucket) {
+	if len(bucket.Listeners) > 0 {
+		s.unsupported = append(s.unsupported, Unsupported{
+			Id:     id,
+			Reason: "bucket notifications are not supported by DigitalOcean Spaces",
+		})
+
+		return
+	}
+
+	s.buckets[id.Name] = s.config.addResource("digitalocean_spaces_bucket", resourceName(id.Name), map[string]any{
+		// bucket names can't contain underscores
+		"name":   physicalName(strings.ReplaceAll(truncate(id.Name, 54), "_", "-")),
+		"region": escape(s.opts.Region),
+	})
+}
+
+// databaseConfig - returns a setting of the database cluster from the stack file
+func (s *doSynth) databaseConfig(key string, fallback any) any {
+	if value, ok := s.opts.Database[key]; ok {
+		return value
+	}
+
+	return fallback
+}
+
+// sqlDatabase - deploys a sql database to a managed Postgres cluster shared by the stack, attached to the app so services
+// are given its connection string
+func (s *doSynth) sqlDatabase(name string, database *deploymentspb.SqlDatabase) {
+	tfName := resourceName(name)
+	cluster := "digitalocean_database_cluster.sql"
+
+	if len(s.databases) == 0 {
+		s.config.addResource("digitalocean_database_cluster", "sql", map[string]any{
+			"name":       physicalName(truncate(s.opts.Project+"-"+s.opts.Stack, 54)),
+			"engine":     "pg",
+			"version":    s.databaseConfig("version", "16"),
+			"size":       s.databaseConfig("size", "db-s-1vcpu-1gb"),
+			"node_count": s.databaseConfig("node-count", 1),
+			"region":     escape(s.opts.Region),
+		})
+	}
+
+	db := s.config.addResource("digitalocean_database_db", tfName, map[string]any{
+		"cluster_id": ref(cluster, "id"),
+		"name":       escape(name),
+	})
+
+	component := componentName("db-" + name)
+
+	s.databases = append(s.databases, map[string]any{
+		"name":         component,
+		"engine":       "PG",
+		"production":   true,
+		"cluster_name": ref(cluster, "name"),
+		"db_name":      ref(db, "name"),
+		"db_user":      ref(cluster, "user"),
+	})
+
+	// App Platform resolves bindable variables like ${db.DATABASE_URL} when the app is deployed
+	url := escape(fmt.Sprintf("${%s.DATABASE_URL}", component))
+
+	for _, service := range s.services {
+		key := envName("NITRIC_DATABASE_", name) + "_URL"
+		service.env[key] = url
+		service.secrets[key] = true
+	}
+
+	migrations := database.GetImageUri()
+	if migrations == "" {
+		return
+	}
+
+	remoteImage, repository, tag := s.remoteImage(name+"-migrations", imageTag(s.opts.ImageIds[migrations], ""))
+
+	s.push(tfName+"_migrations", remoteImage, fmt.Sprintf("docker tag %s %s", escape(migrations), remoteImage))
+
+	// migrations are applied before each deployment of the app
+	s.jobs = append(s.jobs, &doComponent{
+		attributes: map[string]any{
+			"name": componentName(name + "-migrations"),
+			"kind": "PRE_DEPLOY",
+			"image": []any{map[string]any{
+				"registry_type": "DOCR",
+				"repository":    repository,
+				"tag":           tag,
+			}},
+			"instance_size_slug": "apps-s-1vcpu-0.5gb",
+		},
+		env:     map[string]string{"DB_URL": url},
+		secrets: map[string]bool{"DB_URL": true},
+	})
+}
+
+// route - routes requests with a path prefix to a service using the app's ingress, returning false when another service is
+// already routed the prefix
+func (s *doSynth) route(id *resourcespb.ResourceIdentifier, prefix string, service string, rewrite string) bool {
+	if _, ok := s.services[service]; !ok {
+		return true
+	}
+
+	if existing, ok := s.routes[prefix]; ok && existing.service != service {
+		s.unsupported = append(s.unsupported, Unsupported{
+			Id:     id,
+			Reason: fmt.Sprintf("requests to %s are handled by both %s and %s, which App Platform ingress can't route between", prefix, existing.service, service),
+		})
+
+		return false
+	}
+
+	s.routes[prefix] = doRoute{prefix: prefix, service: service, rewrite: rewrite}
+
+	return true
+}
+
+// pathPrefix - the literal prefix of an OpenAPI path before its first parameter, e.g. /orders for /orders/{id}
+func pathPrefix(path string) string {
+	if i := strings.Index(path, "{"); i >= 0 {
+		path = path[:i]
+	}
+
+	return strings.TrimSuffix(path, "/")
+}
+
+// api - routes an API under /<name> in the app's ingress, App Platform routes by path prefix so every operation under a prefix
+// must be handled by the same service
+func (s *doSynth) api(id *resourcespb.ResourceIdentifier, api *deploymentspb.Api) error {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(api.GetOpenapi()))
+	if err != nil {
+		return fmt.Errorf("unable to read openapi document of api %s: %w", id.Name, err)
+	}
+
+	paths := lo.Keys(doc.Paths)
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		prefix := pathPrefix(path)
+
+		for _, operation := range doc.Paths[path].Operations() {
+			if !s.route(id, "/"+escape(id.Name)+prefix, operationTarget(operation), lo.Ternary(prefix == "", "/", prefix)) {
+				return nil
+			}
+		}
+	}
+
+	s.config.Output["api_"+resourceName(id.Name)] = map[string]any{
+		"description": fmt.Sprintf("Endpoint of the %s API", escape(id.Name)),
+		"value":       fmt.Sprintf("%s/%s", ref("digitalocean_app.app", "live_url"), escape(id.Name)),
+	}
+
+	return nil
+}
+
+// permission - the Spaces permission granted for a nitric bucket action
+func permission(action resourcespb.Action) string {
+	switch action {
+	case resourcespb.Action_BucketFileList, resourcespb.Action_BucketFileGet:
+		return "read"
+	case resourcespb.Action_BucketFilePut, resourcespb.Action_BucketFileDelete:
+		return "readwrite"
+	}
+
+	return ""
+}
+
+// policy - records the bucket permissions granted to the principal services, which are given Spaces keys limited to them
+func (s *doSynth) policy(policy *deploymentspb.Policy) {
+	for _, principal := range policy.Principals {
+		if _, ok := s.services[principal.Id.Name]; !ok {
+			continue
+		}
+
+		for _, res := range policy.Resources {
+			if _, ok := s.buckets[res.Id.Name]; !ok {
+				continue
+			}
+
+			for _, action := range policy.Actions {
+				granted := permission(action)
+				if granted == "" {
+					continue
+				}
+
+				if s.grants[principal.Id.Name] == nil {
+					s.grants[principal.Id.Name] = map[string]string{}
+				}
+
+				if s.grants[principal.Id.Name][res.Id.Name] != "readwrite" {
+					s.grants[principal.Id.Name][res.Id.Name] = granted
+				}
+			}
+		}
+	}
+}
+
+// spacesKeys - creates a Spaces access key for each service granted access to buckets, limited to those buckets
+func (s *doSynth) spacesKeys() {
+	for _, name := range lo.Keys(s.grants) {
+		service := s.services[name]
+		tfName := resourceName(name)
+
+		grants := []any{}
+		buckets := lo.Keys(s.grants[name])
+		slices.Sort(buckets)
+
+		for _, bucket := range buckets {
+			grants = append(grants, map[string]any{
+				"bucket":     ref(s.buckets[bucket], "name"),
+				"permission": s.grants[name][bucket],
+			})
+
+			service.env[envName("NITRIC_BUCKET_", bucket)] = ref(s.buckets[bucket], "name")
+		}
+
+		key := s.config.addResource("digitalocean_spaces_key", tfName, map[string]any{
+			"name":  physicalName(truncate(name, 54)),
+			"grant": grants,
+		})
+
+		service.env["SPACES_ENDPOINT"] = fmt.Sprintf("https://%s.digitaloceanspaces.com", escape(s.opts.Region))
+		service.env["SPACES_REGION"] = escape(s.opts.Region)
+		service.env["SPACES_ACCESS_KEY_ID"] = ref(key, "access_key")
+		service.env["SPACES_SECRET_ACCESS_KEY"] = ref(key, "secret_key")
+		service.secrets["SPACES_SECRET_ACCESS_KEY"] = true
+	}
+}
+
+// render - the component as an app spec block, with its environment variables in a stable order
+func (c *doComponent) render() map[string]any {
+	env := []any{}
+	keys := lo.Keys(c.env)
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		env = append(env, map[string]any{
+			"key":   key,
+			"value": c.env[key],
+			"scope": "RUN_TIME",
+			"type":  lo.Ternary(c.secrets[key], "SECRET", "GENERAL"),
+		})
+	}
+
+	block := map[string]any{"env": env}
+	for k, v := range c.attributes {
+		block[k] = v
+	}
+
+	return block
+}
+
+// app - deploys the services, jobs, databases and ingress routes as a single App Platform app
+func (s *doSynth) app() {
+	if len(s.services) == 0 {
+		return
+	}
+
+	services := []any{}
+	names := lo.Keys(s.services)
+	slices.Sort(names)
+
+	for _, name := range names {
+		services = append(services, s.services[name].render())
+	}
+
+	spec := map[string]any{
+		// app names are limited to 32 characters, including the 8 character random suffix
+		"name": fmt.Sprintf("%s-${random_id.stack.hex}", componentName(truncate(s.opts.Project+"-"+s.opts.Stack, 23))),
+		// app platform regions are the datacenter region without its number, e.g. nyc for nyc3
+		"region":  strings.TrimRight(s.opts.Region, "0123456789"),
+		"service": services,
+	}
+
+	if len(s.jobs) > 0 {
+		spec["job"] = lo.Map(s.jobs, func(job *doComponent, _ int) any { return job.render() })
+	}
+
+	if len(s.databases) > 0 {
+		spec["database"] = s.databases
+	}
+
+	if len(s.routes) > 0 {
+		routes := lo.Values(s.routes)
+
+		// longer prefixes are listed first so they take precedence over the prefixes containing them
+		sort.Slice(routes, func(i, j int) bool {
+			if len(routes[i].prefix) != len(routes[j].prefix) {
+				return len(routes[i].prefix) > len(routes[j].prefix)
+			}
+
+			return routes[i].prefix < routes[j].prefix
+		})
+
+		rules := []any{}
+
+		for _, route := range routes {
+			component := map[string]any{"name": componentName(route.service)}
+			if route.rewrite != "" {
+				component["rewrite"] = route.rewrite
+			}
+
+			rules = append(rules, map[string]any{
+				"component": []any{component},
+				"match":     []any{map[string]any{"path": []any{map[string]any{"prefix": route.prefix}}}},
+			})
+		}
+
+		spec["ingress"] = []any{map[string]any{"rule": rules}}
+	}
+
+	app := s.config.addResource("digitalocean_app", "app", map[string]any{
+		"spec":       []any{spec},
+		"depends_on": s.pushes,
+	})
+
+	// only allow the app to connect to the database cluster
+	if len(s.databases) > 0 {
+		s.config.addResource("digitalocean_database_firewall", "sql", map[string]any{
+			"cluster_id": ref("digitalocean_database_cluster.sql", "id"),
+			"rule":       []any{map[string]any{"type": "app", "value": ref(app, "id")}},
+		})
+	}
+
+	s.config.Output["app_url"] = map[string]any{
+		"description": "Live URL of the App Platform app",
+		"value":       ref(app, "live_url"),
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+type JobStatus string
+
+const (
+	JobStatus_Running   JobStatus = "running"
+	JobStatus_Succeeded JobStatus = "succeeded"
+	JobStatus_Failed    JobStatus = "failed"
+	JobStatus_Stopped   JobStatus = "stopped"
+)
+
+// JobState - a snapshot of a job, as returned to callers
+type JobState struct {
+	Id        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Args      []string   `json:"args"`
+	Status    JobStatus  `json:"status"`
+	ExitCode  int        `json:"exitCode"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+// job - a CLI command running in the background, e.g. nitric up
+type job struct {
+	mu      sync.Mutex
+	state   JobState
+	output  []string
+	cmd     *exec.Cmd
+	stopped bool
+	done    chan struct{}
+}
+
+func (j *job) snapshot() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state
+}
+
+// Jobs - runs CLI commands as background jobs, capturing their output
+type Jobs struct {
+	mu         sync.Mutex
+	executable string
+	nextId     int
+	jobs       map[string]*job
+}
+
+// NewJobs - returns a job runner for commands of the given CLI executable
+func NewJobs(executable string) *Jobs {
+	return &Jobs{
+		executable: executable,
+		jobs:       map[string]*job{},
+	}
+}
+
+// Start - starts the CLI with the given args in non-interactive mode
+func (j *Jobs) Start(dir string, kind string, args ...string) (JobState, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.nextId++
+
+	cmd := exec.Command(j.executable, append(args, "--ci")...)
+	cmd.Dir = dir
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	newJob := &job{
+		state: JobState{
+			Id:        strconv.Itoa(j.nextId),
+			Kind:      kind,
+			Args:      args,
+			Status:    JobStatus_Running,
+			StartedAt: time.Now(),
+		},
+		output: []string{},
+		cmd:    cmd,
+		done:   make(chan struct{}),
+	}
+
+	if err := cmd.Start(); err != nil {
+		return JobState{}, fmt.Errorf("unable to start %s: %w", kind, err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			newJob.mu.Lock()
+			newJob.output = append(newJob.output, scanner.Text())
+			newJob.mu.Unlock()
+		}
+	}()
+
+	go func() {
+		err := cmd.Wait()
+		_ = writer.Close()
+
+		newJob.mu.Lock()
+		defer newJob.mu.Unlock()
+
+		endedAt := time.Now()
+		newJob.state.EndedAt = &endedAt
+		newJob.state.ExitCode = cmd.ProcessState.ExitCode()
+
+		switch {
+		case newJob.stopped:
+			newJob.state.Status = JobStatus_Stopped
+		case err != nil:
+			newJob.state.Status = JobStatus_Failed
+		default:
+			newJob.state.Status = JobStatus_Succeeded
+		}
+
+		close(newJob.done)
+	}()
+
+	j.jobs[newJob.state.Id] = newJob
+
+	return newJob.snapshot(), nil
+}
+
+func (j *Jobs) get(id string) (*job, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	found, ok := j.jobs[id]
+	if !ok {
+		return nil, InvalidParams("job %s not found", id)
+	}
+
+	return found, nil
+}
+
+// Get - returns the state of a job
+func (j *Jobs) Get(id string) (JobState, error) {
+	found, err := j.get(id)
+	if err != nil {
+		return JobState{}, err
+	}
+
+	return found.snapshot(), nil
+}
+
+// Output - returns the lines of output of a job from the offset, along with the offset of the next line
+func (j *Jobs) Output(id string, offset int) ([]string, int, error) {
+	found, err := j.get(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	found.mu.Lock()
+	defer found.mu.Unlock()
+
+	offset = lo.Clamp(offset, 0, len(found.output))
+
+	return append([]string{}, found.output[offset:]...), len(found.output), nil
+}
+
+// List - returns the state of all jobs, oldest first
+func (j *Jobs) List() []JobState {
+	j.mu.Lock()
+	jobs := lo.Values(j.jobs)
+	j.mu.Unlock()
+
+	states := lo.Map(jobs, func(item *job, _ int) JobState { return item.snapshot() })
+
+	sort.Slice(states, func(a, b int) bool {
+		return states[a].StartedAt.Before(states[b].StartedAt)
+	})
+
+	return states
+}
+
+// Running - returns the running jobs of a kind
+func (j *Jobs) Running(kind string) []JobState {
+	return lo.Filter(j.List(), func(state JobState, _ int) bool {
+		return state.Kind == kind && state.Status == JobStatus_Running
+	})
+}
+
+// Stop - interrupts a running job, giving it time to clean up, e.g. stopping the local cloud, before it's killed
+func (j *Jobs) Stop(id string) (JobState, error) {
+	found, err := j.get(id)
+	if err != nil {
+		return JobState{}, err
+	}
+
+	found.mu.Lock()
+	if found.state.Status != JobStatus_Running {
+		found.mu.Unlock()
+		return found.state, nil
+	}
+
+	found.stopped = true
+	found.mu.Unlock()
+
+	// interrupts aren't supported on windows
+	if runtime.GOOS == "windows" || found.cmd.Process.Signal(os.Interrupt) != nil {
+		_ = found.cmd.Process.Kill()
+	}
+
+	select {
+	case <-found.done:
+	case <-time.After(30 * time.Second):
+		if err := found.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return JobState{}, err
+		}
+
+		<-found.done
+	}
+
+	return found.snapshot(), nil
+}
+
+// StopAll - stops all running jobs
+func (j *Jobs) StopAll() {
+	for _, state := range j.List() {
+		if state.Status == JobStatus_Running {
+			_, _ = j.Stop(state.Id)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+const Version = "2.0"
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	ErrorCode_ParseError     = -32700
+	ErrorCode_InvalidRequest = -32600
+	ErrorCode_MethodNotFound = -32601
+	ErrorCode_InvalidParams  = -32602
+	ErrorCode_InternalError  = -32603
+)
+
+type Request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type Response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InvalidParams - returns an error reporting invalid method params to the caller
+func InvalidParams(format string, a ...any) error {
+	return &Error{Code: ErrorCode_InvalidParams, Message: fmt.Sprintf(format, a...)}
+}
+
+// Handler - handles calls to a method, params is empty when the call has none
+type Handler func(params json.RawMessage) (any, error)
+
+// Params - decodes the params of a call into a handler specific struct
+func Params[T any](params json.RawMessage) (T, error) {
+	var p T
+
+	if len(params) == 0 {
+		return p, nil
+	}
+
+	if err := json.Unmarshal(params, &p); err != nil {
+		return p, InvalidParams("invalid params: %s", err)
+	}
+
+	return p, nil
+}
+
+// Server - a JSON-RPC 2.0 server over HTTP, callers authenticate with a bearer token
+type Server struct {
+	token   string
+	methods map[string]Handler
+}
+
+var _ http.Handler = (*Server)(nil)
+
+// NewToken - returns a random token for authenticating callers
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func New(token string) *Server {
+	s := &Server{
+		token:   token,
+		methods: map[string]Handler{},
+	}
+
+	s.Register("rpc.methods", func(json.RawMessage) (any, error) {
+		return s.Methods(), nil
+	})
+
+	return s
+}
+
+// Register - registers the handler for a method, replacing any existing handler
+func (s *Server) Register(method string, handler Handler) {
+	s.methods[method] = handler
+}
+
+// Methods - returns the names of the registered methods
+func (s *Server) Methods() []string {
+	methods := lo.Keys(s.methods)
+	sort.Strings(methods)
+
+	return methods
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	// a server without a token rejects every caller rather than accepting an empty bearer token
+	return ok && s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) call(req Request) Response {
+	resp := Response{JsonRpc: Version, Id: req.Id}
+
+	if req.JsonRpc != Version || req.Method == "" {
+		resp.Error = &Error{Code: ErrorCode_InvalidRequest, Message: "invalid request, expected a JSON-RPC 2.0 request with a method"}
+		return resp
+	}
+
+	handler, ok := s.methods[req.Method]
+	if !ok {
+		resp.Error = &Error{Code: ErrorCode_MethodNotFound, Message: fmt.Sprintf("method %s not found", req.Method)}
+		return resp
+	}
+
+	result, err := handler(req.Params)
+	if err != nil {
+		rpcErr := &Error{}
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: ErrorCode_InternalError, Message: err.Error()}
+		}
+
+		resp.Error = rpcErr
+
+		return resp
+	}
+
+	resp.Result = result
+
+	return resp
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJson(w, Response{JsonRpc: Version, Id: json.RawMessage("null"), Error: &Error{Code: ErrorCode_ParseError, Message: err.Error()}})
+		return
+	}
+
+	// batch requests are answered with an array of responses
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "[") {
+		reqs := []Request{}
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			writeJson(w, Response{JsonRpc: Version, Id: json.RawMessage("null"), Error: &Error{Code: ErrorCode_ParseError, Message: err.Error()}})
+			return
+		}
+
+		writeJson(w, lo.Map(reqs, func(req Request, _ int) Response { return s.call(req) }))
+
+		return
+	}
+
+	req := Request{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJson(w, Response{JsonRpc: Version, Id: json.RawMessage("null"), Error: &Error{Code: ErrorCode_InvalidRequest, Message: err.Error()}})
+		return
+	}
+
+	writeJson(w, s.call(req))
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,183 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServeHTTPAuthorization(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	for _, tt := range []struct {
+		name          string
+		serverToken   string
+		method        string
+		authorization string
+		expected      int
+	}{
+		{
+			name:          "valid token",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: "Bearer " + token,
+			expected:      http.StatusOK,
+		},
+		{
+			name:        "missing authorization",
+			serverToken: token,
+			method:      http.MethodPost,
+			expected:    http.StatusUnauthorized,
+		},
+		{
+			name:          "wrong token",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: "Bearer fedcba9876543210",
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "token prefix",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: "Bearer " + token[:8],
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "token without bearer scheme",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: token,
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "basic authorization",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: "Basic " + token,
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "empty bearer token",
+			serverToken:   token,
+			method:        http.MethodPost,
+			authorization: "Bearer ",
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "server without a token",
+			serverToken:   "",
+			method:        http.MethodPost,
+			authorization: "Bearer ",
+			expected:      http.StatusUnauthorized,
+		},
+		{
+			name:          "get request",
+			serverToken:   token,
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			expected:      http.StatusMethodNotAllowed,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := New(tt.serverToken)
+
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"rpc.methods"}`))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeHTTPCalls(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	server := New(token)
+	server.Register("echo", func(params json.RawMessage) (any, error) {
+		p, err := Params[struct {
+			Message string `json:"message"`
+		}](params)
+		if err != nil {
+			return nil, err
+		}
+
+		return p.Message, nil
+	})
+
+	for _, tt := range []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "call",
+			body:     `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"hello"}}`,
+			expected: `{"jsonrpc":"2.0","id":1,"result":"hello"}`,
+		},
+		{
+			name:     "batch",
+			body:     `[{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"a"}},{"jsonrpc":"2.0","id":2,"method":"rpc.methods"}]`,
+			expected: `[{"jsonrpc":"2.0","id":1,"result":"a"},{"jsonrpc":"2.0","id":2,"result":["echo","rpc.methods"]}]`,
+		},
+		{
+			name:     "unknown method",
+			body:     `{"jsonrpc":"2.0","id":1,"method":"missing"}`,
+			expected: `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method missing not found"}}`,
+		},
+		{
+			name:     "invalid params",
+			body:     `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":1}}`,
+			expected: `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params: json: cannot unmarshal number into Go struct field .message of type string"}}`,
+		},
+		{
+			name:     "wrong version",
+			body:     `{"jsonrpc":"1.0","id":1,"method":"echo"}`,
+			expected: `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request, expected a JSON-RPC 2.0 request with a method"}}`,
+		},
+		{
+			name:     "malformed json",
+			body:     `{"jsonrpc":`,
+			expected: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"unexpected EOF"}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+
+			if diff := cmp.Diff(tt.expected, strings.TrimSpace(rec.Body.String())); diff != "" {
+				t.Errorf("unexpected response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+const (
+	// KeyEnv - environment variable holding the contents of an age identity, e.g. a CI secret
+	KeyEnv = "NITRIC_SECRETS_KEY"
+	// KeyFileEnv - environment variable holding the path of an age or ssh identity
+	KeyFileEnv = "NITRIC_SECRETS_KEY_FILE"
+)
+
+// ErrNoIdentity - no identity is configured or found in the default locations
+var ErrNoIdentity = fmt.Errorf("no identity found to decrypt secrets, run nitric env edit to create one or set %s", KeyFileEnv)
+
+var errAgeMissing = fmt.Errorf("age is required to encrypt and decrypt project secrets, for installation instructions see: https://github.com/FiloSottile/age#installation")
+
+// runAge - runs an age command with stdin, returning stdout
+func runAge(binary string, stdin string, args ...string) (string, error) {
+	if _, err := exec.LookPath(binary); err != nil {
+		return "", errAgeMissing
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s: %s", binary, message)
+		}
+
+		return "", fmt.Errorf("%s: %w", binary, err)
+	}
+
+	return stdout.String(), nil
+}
+
+func encrypt(plaintext string, recipients []string) (string, error) {
+	args := []string{"--encrypt", "--armor"}
+
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+
+	return runAge("age", plaintext, args...)
+}
+
+func decrypt(ciphertext string, identityFile string) (string, error) {
+	return runAge("age", ciphertext, "--decrypt", "--identity", identityFile)
+}
+
+// identityCandidates - the identity files checked in order when no identity is configured
+func identityCandidates() []string {
+	candidates := []string{paths.NitricSecretsKeyFile()}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".ssh", "id_ed25519"), filepath.Join(home, ".ssh", "id_rsa"))
+	}
+
+	return candidates
+}
+
+// IdentityFile - returns the path of the identity used to decrypt secrets, along with a function to clean up temporary identities.
+// Identities are read from NITRIC_SECRETS_KEY, NITRIC_SECRETS_KEY_FILE, the nitric secrets key file, then the default ssh keys.
+func IdentityFile() (string, func(), error) {
+	noCleanup := func() {}
+
+	if key := os.Getenv(KeyEnv); key != "" {
+		f, err := os.CreateTemp("", "nitric-secrets-key-*")
+		if err != nil {
+			return "", noCleanup, err
+		}
+
+		defer f.Close()
+
+		if _, err := f.WriteString(strings.TrimSpace(key) + "\n"); err != nil {
+			os.Remove(f.Name())
+			return "", noCleanup, err
+		}
+
+		return f.Name(), func() { os.Remove(f.Name()) }, nil
+	}
+
+	if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+		if _, err := os.Stat(keyFile); err != nil {
+			return "", noCleanup, fmt.Errorf("identity %s set by %s does not exist", keyFile, KeyFileEnv)
+		}
+
+		return keyFile, noCleanup, nil
+	}
+
+	for _, candidate := range identityCandidates() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, noCleanup, nil
+		}
+	}
+
+	return "", noCleanup, ErrNoIdentity
+}
+
+// PublicKey - returns the recipient for an identity file, the public key of an age identity or the matching .pub file of an ssh key
+func PublicKey(identityFile string) (string, error) {
+	data, err := os.ReadFile(identityFile)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.Contains(string(data), "AGE-SECRET-KEY-") {
+		pub, err := os.ReadFile(identityFile + ".pub")
+		if err != nil {
+			return "", fmt.Errorf("unable to read the public key of %s: %w", identityFile, err)
+		}
+
+		return strings.TrimSpace(string(pub)), nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if publicKey, ok := strings.CutPrefix(strings.TrimSpace(line), "# public key: "); ok {
+			return publicKey, nil
+		}
+	}
+
+	publicKey, err := runAge("age-keygen", "", "-y", identityFile)
+
+	return strings.TrimSpace(publicKey), err
+}
+
+// GenerateIdentity - creates a new age identity at the nitric secrets key file, returning its path
+func GenerateIdentity() (string, error) {
+	keyFile := paths.NitricSecretsKeyFile()
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o700); err != nil {
+		return "", err
+	}
+
+	if _, err := runAge("age-keygen", "", "-o", keyFile); err != nil {
+		return "", err
+	}
+
+	return keyFile, nil
+}
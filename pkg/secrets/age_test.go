@@ -0,0 +1,278 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// requireTools - skips the test when the binaries it shells out to aren't installed
+func requireTools(t *testing.T, binaries ...string) {
+	t.Helper()
+
+	for _, binary := range binaries {
+		if _, err := exec.LookPath(binary); err != nil {
+			t.Skipf("%s is not installed", binary)
+		}
+	}
+}
+
+// ageIdentity - generates an age identity, returning its path and recipient
+func ageIdentity(t *testing.T) (string, string) {
+	t.Helper()
+
+	identityFile := filepath.Join(t.TempDir(), "key.txt")
+
+	if _, err := runAge("age-keygen", "", "-o", identityFile); err != nil {
+		t.Fatal(err)
+	}
+
+	recipient, err := PublicKey(identityFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identityFile, recipient
+}
+
+// sshIdentity - generates an ssh key of the given type, returning its path and public key
+func sshIdentity(t *testing.T, keyType string) (string, string) {
+	t.Helper()
+
+	identityFile := filepath.Join(t.TempDir(), "id_"+keyType)
+
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", keyType, "-N", "", "-C", "", "-f", identityFile).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+
+	recipient, err := PublicKey(identityFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identityFile, recipient
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		tools    []string
+		identity func(t *testing.T) (string, string)
+	}{
+		{
+			name:     "age identity",
+			tools:    []string{"age", "age-keygen"},
+			identity: ageIdentity,
+		},
+		{
+			name:     "ssh ed25519 key",
+			tools:    []string{"age", "ssh-keygen"},
+			identity: func(t *testing.T) (string, string) { return sshIdentity(t, "ed25519") },
+		},
+		{
+			name:     "ssh rsa key",
+			tools:    []string{"age", "ssh-keygen"},
+			identity: func(t *testing.T) (string, string) { return sshIdentity(t, "rsa") },
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			requireTools(t, tt.tools...)
+
+			identityFile, recipient := tt.identity(t)
+			edited := &Plaintext{
+				Recipients: []string{recipient},
+				Secrets:    map[string]string{"API_KEY": "s3cr3t", "DB_PASSWORD": "p@ss word\n"},
+			}
+
+			f := &File{}
+			if err := f.Encrypt(nil, edited); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, ciphertext := range f.Secrets {
+				if ciphertext == edited.Secrets[name] {
+					t.Fatalf("secret %s was not encrypted", name)
+				}
+			}
+
+			decrypted, err := f.Decrypt(identityFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(edited, decrypted); diff != "" {
+				t.Errorf("unexpected decrypted secrets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecryptWithOtherIdentity(t *testing.T) {
+	requireTools(t, "age", "age-keygen", "ssh-keygen")
+
+	_, recipient := ageIdentity(t)
+
+	for _, tt := range []struct {
+		name     string
+		identity func(t *testing.T) (string, string)
+	}{
+		{
+			name:     "age identity",
+			identity: ageIdentity,
+		},
+		{
+			name:     "ssh ed25519 key",
+			identity: func(t *testing.T) (string, string) { return sshIdentity(t, "ed25519") },
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			otherIdentity, _ := tt.identity(t)
+
+			f := &File{}
+			if err := f.Encrypt(nil, &Plaintext{Recipients: []string{recipient}, Secrets: map[string]string{"API_KEY": "s3cr3t"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.Decrypt(otherIdentity); err == nil {
+				t.Error("expected decrypting with an identity that isn't a recipient to fail")
+			}
+		})
+	}
+}
+
+func TestEncryptKeepsUnchangedCiphertexts(t *testing.T) {
+	requireTools(t, "age", "age-keygen")
+
+	_, recipient := ageIdentity(t)
+	_, otherRecipient := ageIdentity(t)
+
+	previous := &Plaintext{
+		Recipients: []string{recipient},
+		Secrets:    map[string]string{"API_KEY": "s3cr3t", "DB_PASSWORD": "password"},
+	}
+
+	for _, tt := range []struct {
+		name      string
+		edited    *Plaintext
+		unchanged []string
+	}{
+		{
+			name:      "changed secret",
+			edited:    &Plaintext{Recipients: []string{recipient}, Secrets: map[string]string{"API_KEY": "s3cr3t", "DB_PASSWORD": "changed"}},
+			unchanged: []string{"API_KEY"},
+		},
+		{
+			name:      "changed recipients",
+			edited:    &Plaintext{Recipients: []string{recipient, otherRecipient}, Secrets: previous.Secrets},
+			unchanged: []string{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{}
+			if err := f.Encrypt(nil, previous); err != nil {
+				t.Fatal(err)
+			}
+
+			before := map[string]string{}
+			for name, ciphertext := range f.Secrets {
+				before[name] = ciphertext
+			}
+
+			if err := f.Encrypt(previous, tt.edited); err != nil {
+				t.Fatal(err)
+			}
+
+			unchanged := []string{}
+
+			for _, name := range sortedNames(f.Secrets) {
+				if f.Secrets[name] == before[name] {
+					unchanged = append(unchanged, name)
+				}
+			}
+
+			if diff := cmp.Diff(tt.unchanged, unchanged); diff != "" {
+				t.Errorf("unexpected unchanged ciphertexts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIdentityFile(t *testing.T) {
+	requireTools(t, "age-keygen")
+
+	keyFile, _ := ageIdentity(t)
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		key     string
+		keyFile string
+		wantErr bool
+	}{
+		{
+			name: "key from environment",
+			key:  string(key),
+		},
+		{
+			name:    "key file from environment",
+			keyFile: keyFile,
+		},
+		{
+			name:    "missing key file",
+			keyFile: filepath.Join(t.TempDir(), "missing.txt"),
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(KeyEnv, tt.key)
+			t.Setenv(KeyFileEnv, tt.keyFile)
+
+			identityFile, cleanup, err := IdentityFile()
+			defer cleanup()
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			identity, err := os.ReadFile(identityFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(string(key), string(identity)); diff != "" {
+				t.Errorf("unexpected identity (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
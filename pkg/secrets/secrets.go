@@ -0,0 +1,209 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/samber/lo"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+const fileHeader = "# Project secrets, encrypted with age for each recipient. Edit with nitric env edit\n"
+
+// File - an encrypted secrets file, values are encrypted individually so changes to a secret only change its own lines
+type File struct {
+	// Public keys able to decrypt the secrets, age recipients or ssh public keys
+	Recipients []string `yaml:"recipients"`
+	// Armored age ciphertexts, keyed by environment variable name
+	Secrets map[string]string `yaml:"secrets"`
+}
+
+// Plaintext - the decrypted contents of a secrets file, as edited by nitric env edit
+type Plaintext struct {
+	Recipients []string          `yaml:"recipients"`
+	Secrets    map[string]string `yaml:"secrets"`
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate - checks the recipients and secret names of edited secrets
+func (p *Plaintext) Validate() error {
+	if len(p.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required to encrypt the secrets")
+	}
+
+	for _, name := range sortedNames(p.Secrets) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("secret name %s is not a valid environment variable name", name)
+		}
+	}
+
+	return nil
+}
+
+// FromFile - reads the secrets file at path, returning nil if it doesn't exist
+func FromFile(fs afero.Fs, path string) (*File, error) {
+	data, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("unable to parse secrets file %s: %w", path, err)
+	}
+
+	if f.Secrets == nil {
+		f.Secrets = map[string]string{}
+	}
+
+	return f, nil
+}
+
+// Decrypt - decrypts all secrets in the file with the identity file
+func (f *File) Decrypt(identityFile string) (*Plaintext, error) {
+	plaintext := &Plaintext{
+		Recipients: f.Recipients,
+		Secrets:    map[string]string{},
+	}
+
+	for _, name := range sortedNames(f.Secrets) {
+		value, err := decrypt(f.Secrets[name], identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt secret %s: %w", name, err)
+		}
+
+		plaintext.Secrets[name] = value
+	}
+
+	return plaintext, nil
+}
+
+// Encrypt - encrypts the edited secrets into the file. Unchanged secrets keep their existing ciphertext,
+// unless the recipients changed, in which case all secrets are encrypted for the new recipients.
+func (f *File) Encrypt(previous *Plaintext, edited *Plaintext) error {
+	recipientsChanged := previous == nil || !slices.Equal(previous.Recipients, edited.Recipients)
+
+	encrypted := map[string]string{}
+
+	for _, name := range sortedNames(edited.Secrets) {
+		value := edited.Secrets[name]
+
+		if !recipientsChanged {
+			if previousValue, ok := previous.Secrets[name]; ok && previousValue == value {
+				encrypted[name] = f.Secrets[name]
+				continue
+			}
+		}
+
+		ciphertext, err := encrypt(value, edited.Recipients)
+		if err != nil {
+			return fmt.Errorf("unable to encrypt secret %s: %w", name, err)
+		}
+
+		encrypted[name] = ciphertext
+	}
+
+	f.Recipients = edited.Recipients
+	f.Secrets = encrypted
+
+	return nil
+}
+
+// Save - writes the secrets file to path
+func (f *File) Save(fs afero.Fs, path string) error {
+	data, err := Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, append([]byte(fileHeader), data...), 0o644)
+}
+
+// Marshal - encodes secrets as yaml indented by two spaces
+func Marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), encoder.Close()
+}
+
+var (
+	envCache = map[string]map[string]string{}
+	envLock  sync.Mutex
+)
+
+// ReadEnv - decrypts the secrets file of the project in projectDir into environment variables,
+// returning no variables when the project doesn't have a secrets file.
+// Decrypted secrets are cached for the life of the process, so passphrase protected identities are only unlocked once.
+func ReadEnv(fs afero.Fs, projectDir string) (map[string]string, error) {
+	path := paths.NitricSecretsFile(projectDir)
+
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	if env, ok := envCache[path]; ok {
+		return maps.Clone(env), nil
+	}
+
+	f, err := FromFile(fs, path)
+	if err != nil || f == nil {
+		return map[string]string{}, err
+	}
+
+	identityFile, cleanup, err := IdentityFile()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s: %w", path, err)
+	}
+	defer cleanup()
+
+	plaintext, err := f.Decrypt(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt %s: %w", path, err)
+	}
+
+	envCache[path] = plaintext.Secrets
+
+	return maps.Clone(plaintext.Secrets), nil
+}
+
+func sortedNames(secrets map[string]string) []string {
+	names := lo.Keys(secrets)
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,267 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TriggerType - the kind of inbound event that triggered services during a run
+type TriggerType string
+
+const (
+	TriggerType_Api      TriggerType = "api"
+	TriggerType_Http     TriggerType = "http"
+	TriggerType_Topic    TriggerType = "topic"
+	TriggerType_Schedule TriggerType = "schedule"
+)
+
+// Trigger - a single recorded inbound event
+type Trigger struct {
+	// Time since the start of the session
+	Offset time.Duration `json:"offset"`
+	Time   time.Time     `json:"time"`
+	Type   TriggerType   `json:"type"`
+	// Name of the API, topic or schedule
+	Name    string              `json:"name,omitempty"`
+	Method  string              `json:"method,omitempty"`
+	Path    string              `json:"path,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    []byte              `json:"body,omitempty"`
+}
+
+func (t Trigger) String() string {
+	switch t.Type {
+	case TriggerType_Api:
+		return fmt.Sprintf("api %s %s %s", t.Name, t.Method, t.Path)
+	case TriggerType_Http:
+		return fmt.Sprintf("http %s %s", t.Method, t.Path)
+	}
+
+	return fmt.Sprintf("%s %s", t.Type, t.Name)
+}
+
+// Session - the inbound events of a run, in the order they occurred
+type Session struct {
+	StartTime time.Time `json:"startTime"`
+	Triggers  []Trigger `json:"triggers"`
+}
+
+// Recorder - records inbound events during a run, appending each event to the session file as it occurs.
+//
+// Session files are a stream of JSON values, starting with the session, followed by a trigger per line
+type Recorder struct {
+	lock      sync.Mutex
+	filePath  string
+	startTime time.Time
+}
+
+// NewRecorder - starts recording a new session to the given file
+func NewRecorder(filePath string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		filePath:  filePath,
+		startTime: time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(Session{StartTime: r.startTime, Triggers: []Trigger{}})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, os.WriteFile(r.filePath, append(data, '\n'), 0o600)
+}
+
+// Record - records an inbound event, a nil recorder ignores events so recording can be optional
+func (r *Recorder) Record(trigger Trigger) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now().UTC()
+	trigger.Time = now
+	trigger.Offset = now.Sub(r.startTime)
+
+	_ = r.append(trigger)
+}
+
+// FilePath - the file the session is recorded to
+func (r *Recorder) FilePath() string {
+	return r.filePath
+}
+
+func (r *Recorder) append(trigger Trigger) error {
+	data, err := json.Marshal(trigger)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(r.filePath, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+
+	return err
+}
+
+// Load - loads a recorded session
+func Load(filePath string) (*Session, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+
+	s := &Session{}
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("unable to parse session %s: %w", filePath, err)
+	}
+
+	// triggers recorded after the session started follow it, sessions recorded by earlier versions hold them in the session
+	for decoder.More() {
+		trigger := Trigger{}
+		if err := decoder.Decode(&trigger); err != nil {
+			return nil, fmt.Errorf("unable to parse session %s: %w", filePath, err)
+		}
+
+		s.Triggers = append(s.Triggers, trigger)
+	}
+
+	return s, nil
+}
+
+// Target - the running local cloud events are replayed against, each function returns false until the resource is ready
+type Target interface {
+	ApiAddress(apiName string) (string, bool)
+	HttpAddress() (string, bool)
+	TopicTriggerUrl(topicName string) (string, bool)
+	ScheduleTriggerUrl(scheduleName string) (string, bool)
+}
+
+func triggerUrl(target Target, trigger Trigger) (string, bool) {
+	switch trigger.Type {
+	case TriggerType_Api:
+		address, ok := target.ApiAddress(trigger.Name)
+		return address + trigger.Path, ok
+	case TriggerType_Http:
+		address, ok := target.HttpAddress()
+		return address + trigger.Path, ok
+	case TriggerType_Topic:
+		return target.TopicTriggerUrl(trigger.Name)
+	case TriggerType_Schedule:
+		return target.ScheduleTriggerUrl(trigger.Name)
+	}
+
+	return "", false
+}
+
+// waitUntilReady - waits for all resources triggered in the session to be available, e.g. services have registered their APIs
+func (s *Session) waitUntilReady(ctx context.Context, target Target, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		pending := ""
+
+		for _, trigger := range s.Triggers {
+			if _, ok := triggerUrl(target, trigger); !ok {
+				pending = fmt.Sprintf("%s %s", trigger.Type, trigger.Name)
+				break
+			}
+		}
+
+		if pending == "" {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to be available", pending)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// Replay - replays the session's events against the target with their original timing, once all triggered resources are ready
+func (s *Session) Replay(ctx context.Context, target Target, onReplay func(trigger Trigger, err error)) error {
+	if err := s.waitUntilReady(ctx, target, 2*time.Minute); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	for _, trigger := range s.Triggers {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(trigger.Offset))):
+		}
+
+		onReplay(trigger, replayTrigger(ctx, target, trigger))
+	}
+
+	return nil
+}
+
+func replayTrigger(ctx context.Context, target Target, trigger Trigger) error {
+	url, _ := triggerUrl(target, trigger)
+
+	method := trigger.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(trigger.Body))
+	if err != nil {
+		return err
+	}
+
+	for name, values := range trigger.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
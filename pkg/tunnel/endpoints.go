@@ -0,0 +1,173 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/nitrictech/cli/pkg/cloud/gateway"
+	"github.com/nitrictech/cli/pkg/paths"
+)
+
+type EndpointType string
+
+const (
+	EndpointType_Api       EndpointType = "api"
+	EndpointType_Websocket EndpointType = "websocket"
+	EndpointType_Http      EndpointType = "http"
+)
+
+// Endpoint - a local gateway endpoint that can be shared with a tunnel
+type Endpoint struct {
+	Type EndpointType `json:"type"`
+	Name string       `json:"name"`
+	// The local URL of the endpoint, e.g. http://localhost:4001
+	Url string `json:"url"`
+}
+
+func (e Endpoint) String() string {
+	return fmt.Sprintf("%s %s", e.Type, e.Name)
+}
+
+// Endpoints - returns the endpoints currently served by a local gateway
+func Endpoints(gw *gateway.LocalGatewayService) []Endpoint {
+	endpoints := []Endpoint{}
+
+	for name, url := range gw.GetApiAddresses() {
+		endpoints = append(endpoints, Endpoint{Type: EndpointType_Api, Name: name, Url: url})
+	}
+
+	// websocket upgrades are forwarded by http tunnels
+	websocketScheme := "http://"
+	if gw.GetWebsocketScheme() == "wss" {
+		websocketScheme = "https://"
+	}
+
+	for name, address := range gw.GetWebsocketAddresses() {
+		endpoints = append(endpoints, Endpoint{Type: EndpointType_Websocket, Name: name, Url: websocketScheme + address})
+	}
+
+	for name, url := range gw.GetHttpWorkerAddresses() {
+		endpoints = append(endpoints, Endpoint{Type: EndpointType_Http, Name: name, Url: url})
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].String() < endpoints[j].String()
+	})
+
+	return endpoints
+}
+
+// PublishEndpoints - keeps the endpoints file for a project up to date with the endpoints served by the local gateway,
+// returning a function that stops publishing and removes the file
+func PublishEndpoints(projectDir string, gw *gateway.LocalGatewayService) func() {
+	endpointsFile := paths.NitricLocalEndpointsFile(projectDir)
+	stop := make(chan bool)
+	done := make(chan bool)
+
+	go func() {
+		defer close(done)
+
+		published := []Endpoint{}
+		ticker := time.NewTicker(time.Second)
+
+		defer ticker.Stop()
+
+		for {
+			if endpoints := Endpoints(gw); !reflect.DeepEqual(endpoints, published) {
+				if err := writeEndpoints(endpointsFile, endpoints); err == nil {
+					published = endpoints
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		_ = os.Remove(endpointsFile)
+	}
+}
+
+func writeEndpoints(endpointsFile string, endpoints []Endpoint) error {
+	if err := os.MkdirAll(filepath.Dir(endpointsFile), 0o700); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(endpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(endpointsFile, contents, 0o600)
+}
+
+// ReadEndpoints - returns the endpoints published by a project's running local cloud
+func ReadEndpoints(projectDir string) ([]Endpoint, error) {
+	contents, err := os.ReadFile(paths.NitricLocalEndpointsFile(projectDir))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no running local cloud found for this project, start it with nitric start or nitric run")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []Endpoint{}
+	if err := json.Unmarshal(contents, &endpoints); err != nil {
+		return nil, fmt.Errorf("unable to read local endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// FindEndpoint - returns the endpoint with the name, optionally prefixed with its type, e.g. main or api/main
+func FindEndpoint(endpoints []Endpoint, name string) (Endpoint, error) {
+	endpointType, endpointName, hasType := strings.Cut(name, "/")
+	if !hasType {
+		endpointName = endpointType
+	}
+
+	matches := lo.Filter(endpoints, func(e Endpoint, _ int) bool {
+		return e.Name == endpointName && (!hasType || string(e.Type) == endpointType)
+	})
+
+	switch len(matches) {
+	case 0:
+		return Endpoint{}, fmt.Errorf("no local endpoint named %s, available endpoints are: %s", name, strings.Join(lo.Map(endpoints, func(e Endpoint, _ int) string { return string(e.Type) + "/" + e.Name }), ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return Endpoint{}, fmt.Errorf("multiple local endpoints are named %s, prefix the name with its type, e.g. api/%s", name, endpointName)
+	}
+}
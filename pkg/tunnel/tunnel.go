@@ -0,0 +1,163 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Provider - an external tool that exposes local endpoints on a public URL
+type Provider struct {
+	Name   string
+	Binary string
+	args   func(localUrl string) []string
+	// matches the public URL in the tool's output
+	urlPattern *regexp.Regexp
+}
+
+var Providers = []Provider{
+	{
+		Name:   "cloudflared",
+		Binary: "cloudflared",
+		args: func(localUrl string) []string {
+			args := []string{"tunnel", "--no-autoupdate", "--url", localUrl}
+			if strings.HasPrefix(localUrl, "https://") {
+				// the local gateway uses a self-signed certificate
+				args = append(args, "--no-tls-verify")
+			}
+
+			return args
+		},
+		urlPattern: regexp.MustCompile(`https://[a-z0-9-]+\.trycloudflare\.com`),
+	},
+	{
+		Name:   "ngrok",
+		Binary: "ngrok",
+		args: func(localUrl string) []string {
+			return []string{"http", localUrl, "--log", "stdout", "--log-format", "logfmt"}
+		},
+		urlPattern: regexp.MustCompile(`url=(https://\S+)`),
+	},
+}
+
+// ProviderNames - the names of the supported tunnel providers
+func ProviderNames() []string {
+	return lo.Map(Providers, func(p Provider, _ int) string { return p.Name })
+}
+
+// FindProvider - returns the named provider, or the first provider installed when name is empty
+func FindProvider(name string) (Provider, error) {
+	if name != "" {
+		provider, ok := lo.Find(Providers, func(p Provider) bool { return p.Name == name })
+		if !ok {
+			return Provider{}, fmt.Errorf("unknown tunnel provider %s, supported providers are: %s", name, strings.Join(ProviderNames(), ", "))
+		}
+
+		if _, err := exec.LookPath(provider.Binary); err != nil {
+			return Provider{}, fmt.Errorf("%s is not installed, install it and make sure it's on your PATH", provider.Binary)
+		}
+
+		return provider, nil
+	}
+
+	for _, provider := range Providers {
+		if _, err := exec.LookPath(provider.Binary); err == nil {
+			return provider, nil
+		}
+	}
+
+	return Provider{}, fmt.Errorf("no tunnel provider found, install one of %s and make sure it's on your PATH", strings.Join(ProviderNames(), " or "))
+}
+
+// Tunnel - a running tunnel to a local endpoint
+type Tunnel struct {
+	PublicUrl string
+	cmd       *exec.Cmd
+	exited    chan error
+}
+
+// Start - starts a tunnel to the local URL, waiting until the provider reports its public URL
+func (p Provider) Start(localUrl string) (*Tunnel, error) {
+	cmd := exec.Command(p.Binary, p.args(localUrl)...)
+
+	output, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start %s: %w", p.Name, err)
+	}
+
+	tunnel := &Tunnel{cmd: cmd, exited: make(chan error, 1)}
+	publicUrl := make(chan string, 1)
+	lastLine := atomic.Value{}
+	lastLine.Store("")
+
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = fmt.Errorf("%s exited", p.Name)
+		}
+
+		_ = writer.Close()
+		tunnel.exited <- err
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(output)
+		for scanner.Scan() {
+			lastLine.Store(scanner.Text())
+
+			if match := p.urlPattern.FindStringSubmatch(scanner.Text()); match != nil {
+				publicUrl <- match[len(match)-1]
+				break
+			}
+		}
+
+		// keep draining the provider's output so it doesn't block
+		_, _ = io.Copy(io.Discard, output)
+	}()
+
+	select {
+	case tunnel.PublicUrl = <-publicUrl:
+		return tunnel, nil
+	case err := <-tunnel.exited:
+		return nil, fmt.Errorf("%s exited before the tunnel was ready, %s: %w", p.Name, lastLine.Load(), err)
+	case <-time.After(30 * time.Second):
+		tunnel.Stop()
+		return nil, fmt.Errorf("timed out waiting for %s to report the tunnel's public URL", p.Name)
+	}
+}
+
+// Done - receives when the tunnel's provider exits
+func (t *Tunnel) Done() <-chan error {
+	return t.exited
+}
+
+// Stop - stops the tunnel
+func (t *Tunnel) Stop() {
+	_ = t.cmd.Process.Kill()
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -27,6 +28,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/samber/lo"
 
+	"github.com/nitrictech/cli/pkg/docker"
 	"github.com/nitrictech/cli/pkg/project"
 	tui "github.com/nitrictech/cli/pkg/view/tui"
 	"github.com/nitrictech/cli/pkg/view/tui/components/view"
@@ -38,6 +40,7 @@ import (
 type Model struct {
 	title               string
 	serviceBuildUpdates map[string][]project.ServiceBuildUpdate
+	serviceProgress     map[string]*docker.BuildProgress
 	windowSize          tea.WindowSizeMsg
 
 	serviceBuildUpdatesChannel <-chan project.ServiceBuildUpdate
@@ -71,6 +74,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, teax.Quit
 		}
 
+		// progress updates replace the previous progress, rather than being added to the service's update history
+		if msg.Value.Progress != nil {
+			m.serviceProgress[msg.Value.ServiceName] = msg.Value.Progress
+
+			return m, reactive.AwaitChannel(msg.Source)
+		}
+
 		if m.serviceBuildUpdates[msg.Value.ServiceName] == nil {
 			m.serviceBuildUpdates[msg.Value.ServiceName] = make([]project.ServiceBuildUpdate, 0)
 		}
@@ -161,7 +171,21 @@ func (m Model) View() string {
 			}
 
 			serviceUpdates.Add("%s ", serviceName)
-			serviceUpdates.Addln(strings.ToLower(string(latestUpdate.Status))).WithStyle(lipgloss.NewStyle().Foreground(statusColor))
+
+			if latestUpdate.Elapsed > 0 {
+				serviceUpdates.Add(strings.ToLower(string(latestUpdate.Status))).WithStyle(lipgloss.NewStyle().Foreground(statusColor))
+				serviceUpdates.Addln(" %s", latestUpdate.Elapsed.Round(time.Second)).WithStyle(lipgloss.NewStyle().Foreground(tui.Colors.Gray))
+			} else {
+				serviceUpdates.Addln(strings.ToLower(string(latestUpdate.Status))).WithStyle(lipgloss.NewStyle().Foreground(statusColor))
+			}
+
+			if latestUpdate.Warning != "" {
+				serviceUpdates.Addln("  %s", latestUpdate.Warning).WithStyle(lipgloss.NewStyle().Foreground(tui.Colors.Yellow))
+			}
+
+			if progress, ok := m.serviceProgress[serviceName]; ok && latestUpdate.Status == project.ServiceBuildStatus_InProgress {
+				serviceUpdates.Addln("  %s", progressBar(progress)).WithStyle(lipgloss.NewStyle().Foreground(tui.Colors.Blue))
+			}
 		}
 
 		if m.Err != nil {
@@ -184,11 +208,46 @@ func (m Model) View() string {
 	return v.Render()
 }
 
+const progressBarWidth = 20
+
+func formatBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(b)/float64(div), "kMGTPE"[exp])
+}
+
+// progressBar - renders build progress, e.g. [========            ] 42% step 3/9 12.1MB/30.2MB eta 12s
+func progressBar(progress *docker.BuildProgress) string {
+	filled := min(progressBarWidth, int(progress.Percent/100*progressBarWidth))
+
+	bar := fmt.Sprintf("[%s%s] %3.0f%% step %d/%d", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), progress.Percent, progress.CompletedSteps, progress.TotalSteps)
+
+	if progress.Total > 0 {
+		bar += fmt.Sprintf(" %s/%s", formatBytes(progress.Current), formatBytes(progress.Total))
+	}
+
+	if progress.ETA > 0 {
+		bar += fmt.Sprintf(" eta %s", progress.ETA)
+	}
+
+	return bar
+}
+
 func NewModel(serviceBuildUpdates <-chan project.ServiceBuildUpdate, title string) Model {
 	return Model{
 		title:                      title,
 		spinner:                    spinner.New(spinner.WithSpinner(spinner.Ellipsis)),
 		serviceBuildUpdatesChannel: serviceBuildUpdates,
 		serviceBuildUpdates:        make(map[string][]project.ServiceBuildUpdate),
+		serviceProgress:            make(map[string]*docker.BuildProgress),
 	}
 }
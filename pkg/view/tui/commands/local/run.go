@@ -155,7 +155,7 @@ func (t *TuiModel) ReactiveUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for api, host := range t.localCloud.Gateway.GetWebsocketAddresses() {
 			newWebsocketsSummary = append(newWebsocketsSummary, WebsocketSummary{
 				name: api,
-				url:  fmt.Sprintf("ws://%s", host),
+				url:  fmt.Sprintf("%s://%s", t.localCloud.Gateway.GetWebsocketScheme(), host),
 			})
 		}
 
@@ -17,18 +17,20 @@
 package project
 
 import (
-	"fmt"
 	"path"
 	"path/filepath"
 	"time"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/samber/lo"
 	"github.com/spf13/afero"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/goombaio/namegenerator"
 
+	"github.com/nitrictech/cli/pkg/i18n"
 	"github.com/nitrictech/cli/pkg/project"
 	"github.com/nitrictech/cli/pkg/project/templates"
 	tui "github.com/nitrictech/cli/pkg/view/tui"
@@ -75,6 +77,11 @@ func (m Model) ProjectName() string {
 	return m.namePrompt.Value()
 }
 
+// Created - returns true if the project was created
+func (m Model) Created() bool {
+	return m.status == Done
+}
+
 // TemplateName returns the project template name selected by the user
 func (m Model) TemplateName() string {
 	template := m.downloader.GetByLabel(m.templatePrompt.Choice())
@@ -256,7 +263,7 @@ func New(fs afero.Fs, args Args) (Model, error) {
 	nameInFlightValidator := validation.ComposeValidators(projectNameInFlightValidators...)
 
 	namePrompt := textprompt.NewTextPrompt("projectName", textprompt.TextPromptArgs{
-		Prompt:            "What should we name this project?",
+		Prompt:            i18n.Message("project.new.name_prompt"),
 		Tag:               "name",
 		Placeholder:       placeholderName,
 		Validator:         nameValidator,
@@ -278,7 +285,7 @@ func New(fs afero.Fs, args Args) (Model, error) {
 	}
 
 	templatePrompt := listprompt.NewListPrompt(listprompt.ListPromptArgs{
-		Prompt:            "Which template should we start with?",
+		Prompt:            i18n.Message("project.new.template_prompt"),
 		Tag:               "tmpl",
 		Items:             templateItems,
 		MaxDisplayedItems: len(templates),
@@ -303,7 +310,7 @@ func New(fs afero.Fs, args Args) (Model, error) {
 		template := downloadr.Get(args.TemplateName)
 		if template == nil {
 			return Model{
-				err: fmt.Errorf("template \"%s\" could not be found", args.TemplateName),
+				err: i18n.Errorf("project.new.template_not_found", args.TemplateName),
 			}, nil
 		}
 
@@ -335,6 +342,44 @@ func New(fs afero.Fs, args Args) (Model, error) {
 	}, nil
 }
 
+// Ask - asks for the project name and template missing from args as plain questions, used instead of the interactive prompts in accessible mode
+func Ask(args Args) (Args, error) {
+	nameValidator := validation.ComposeValidators(projectNameValidators...)
+
+	if args.ProjectName == "" {
+		err := tui.AskOne(&survey.Input{Message: i18n.Message("project.new.name_prompt")}, &args.ProjectName, survey.WithValidator(func(ans interface{}) error {
+			name, _ := ans.(string)
+			return nameValidator(name)
+		}))
+		if err != nil {
+			return args, err
+		}
+	}
+
+	if args.TemplateName == "" {
+		downloadr := templates.NewDownloader()
+
+		available, err := downloadr.Templates()
+		if err != nil {
+			return args, err
+		}
+
+		label := ""
+
+		err = tui.AskOne(&survey.Select{
+			Message: i18n.Message("project.new.template_prompt"),
+			Options: lo.Map(available, func(t templates.TemplateInfo, _ int) string { return t.Label }),
+		}, &label)
+		if err != nil {
+			return args, err
+		}
+
+		args.TemplateName = downloadr.GetByLabel(label).Name
+	}
+
+	return args, nil
+}
+
 type projectCreateResultMsg struct {
 	err error
 }
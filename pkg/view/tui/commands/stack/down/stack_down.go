@@ -18,6 +18,7 @@ package stack_down
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -37,6 +38,7 @@ import (
 
 type Model struct {
 	provider           string
+	regions            []string
 	stack              *stack.Resource
 	defaultParent      *stack.Resource
 	updatesChan        <-chan *deploymentspb.DeploymentDownEvent
@@ -191,6 +193,10 @@ func (m Model) View() string {
 	v.Add(fragments.Tag("down"))
 	v.Add("  tearing down with %s", m.provider)
 
+	if len(m.regions) > 0 {
+		v.Add(" to %s", strings.Join(m.regions, ", "))
+	}
+
 	if m.done {
 		v.Break()
 	} else {
@@ -261,7 +267,12 @@ func (m Model) View() string {
 	return v.Render()
 }
 
-func New(providerName string, stackName string, updatesChan <-chan *deploymentspb.DeploymentDownEvent, providerStdoutChan <-chan string, errorChan <-chan error) Model {
+// Failed - returns true if the provider reported errors while the stack was being undeployed
+func (m Model) Failed() bool {
+	return len(m.errs) > 0
+}
+
+func New(providerName string, stackName string, regions []string, updatesChan <-chan *deploymentspb.DeploymentDownEvent, providerStdoutChan <-chan string, errorChan <-chan error) Model {
 	orphanParent := &stack.Resource{
 		Name:     fmt.Sprintf("Stack::%s", stackName),
 		Message:  "",
@@ -272,6 +283,7 @@ func New(providerName string, stackName string, updatesChan <-chan *deploymentsp
 
 	return Model{
 		provider:           providerName,
+		regions:            regions,
 		spinner:            spinner.New(spinner.WithSpinner(spinner.Ellipsis)),
 		updatesChan:        updatesChan,
 		providerStdoutChan: providerStdoutChan,
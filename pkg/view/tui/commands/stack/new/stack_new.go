@@ -23,12 +23,14 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 
+	"github.com/nitrictech/cli/pkg/i18n"
 	"github.com/nitrictech/cli/pkg/preview"
 	"github.com/nitrictech/cli/pkg/project"
 	"github.com/nitrictech/cli/pkg/project/stack"
@@ -64,6 +66,7 @@ type Model struct {
 	spinner        spinner.Model
 	status         NewStackStatus
 	provider       string
+	template       string
 	projectConfig  *project.ProjectConfiguration
 	nonInteractive bool
 
@@ -84,6 +87,16 @@ func (m Model) ProviderName() string {
 	return m.providerPrompt.Choice()
 }
 
+// Created - returns true if the stack file was created
+func (m Model) Created() bool {
+	return m.status == Done
+}
+
+// Provider - returns the name of the provider the stack was created for, e.g. aws
+func (m Model) Provider() string {
+	return providerLabelToValue(m.ProviderName())
+}
+
 // Init initializes the model, used by Bubbletea
 func (m Model) Init() tea.Cmd {
 	if m.err != nil {
@@ -226,9 +239,9 @@ func (m Model) View() string {
 		provName := providerLabelToValue(m.ProviderName())
 
 		if (provName == "aws-tf" || provName == "gcp-tf") && !slices.Contains(m.projectConfig.Preview, preview.Feature_BetaProviders) {
-			indent.Add("Add ")
-			indent.Add("beta-providers").WithStyle(highlightStyle)
-			indent.Add(" to your nitric.yaml preview config to enable preview provider support.")
+			indent.Add("Run ")
+			indent.Add("nitric preview enable beta-providers").WithStyle(highlightStyle)
+			indent.Add(" to enable preview provider support.")
 			indent.Break()
 			indent.Break()
 		}
@@ -251,7 +264,9 @@ func (m Model) View() string {
 type Args struct {
 	StackName    string
 	ProviderName string
-	Force        bool
+	// Template is the name of a stack preset to create the stack from, the preset determines the provider
+	Template string
+	Force    bool
 }
 
 type RegionItem struct {
@@ -284,9 +299,12 @@ const (
 	Gcp   = "GCP"
 	AwsTf = "AWS - Terraform (Preview)"
 	GcpTf = "GCP - Terraform (Preview)"
+	Do    = "DigitalOcean - Terraform (Preview)"
+	Cf    = "Cloudflare Workers (Preview)"
+	K8s   = "Local Kubernetes - kind or k3d (Preview)"
 )
 
-var availableProviders = []string{Aws, Gcp, Azure, AwsTf, GcpTf}
+var availableProviders = []string{Aws, Gcp, Azure, AwsTf, GcpTf, Do, Cf, K8s}
 
 func New(fs afero.Fs, args Args) Model {
 	// Load and update the project name in the template's nitric.yaml
@@ -301,7 +319,7 @@ func New(fs afero.Fs, args Args) Model {
 	nameInFlightValidator := validation.ComposeValidators(validators.ProjectNameValidators...)
 
 	namePrompt := textprompt.NewTextPrompt("stackName", textprompt.TextPromptArgs{
-		Prompt:            "What should we name this stack?",
+		Prompt:            i18n.Message("stack.new.name_prompt"),
 		Tag:               "name",
 		Validator:         nameValidator,
 		Placeholder:       "dev",
@@ -310,7 +328,7 @@ func New(fs afero.Fs, args Args) Model {
 	namePrompt.Focus()
 
 	providerPrompt := listprompt.NewListPrompt(listprompt.ListPromptArgs{
-		Prompt: "Which provider do you want to deploy with?",
+		Prompt: i18n.Message("stack.new.provider_prompt"),
 		Tag:    "prov",
 		Items:  list.StringsToListItems(availableProviders),
 	})
@@ -331,15 +349,32 @@ func New(fs afero.Fs, args Args) Model {
 	}
 
 	if args.ProviderName != "" {
-		if !lo.Contains([]string{"aws", "azure", "gcp", "aws-tf"}, args.ProviderName) {
+		if !lo.Contains([]string{"aws", "azure", "gcp", "aws-tf", "do", "cloudflare", "kubernetes"}, args.ProviderName) {
 			return Model{
-				err: fmt.Errorf("cloud name is not valid, must be aws, azure, gcp, or aws-tf"),
+				err: i18n.Errorf("stack.new.invalid_provider"),
 			}
 		}
 
 		providerPrompt.SetChoice(args.ProviderName)
 	}
 
+	if args.Template != "" {
+		preset, err := stack.GetPreset(args.Template)
+		if err != nil {
+			return Model{
+				err: err,
+			}
+		}
+
+		if args.ProviderName != "" && args.ProviderName != preset.Provider {
+			return Model{
+				err: fmt.Errorf("stack template %s uses the %s provider, which doesn't match the requested provider %s", preset.Name, preset.Provider, args.ProviderName),
+			}
+		}
+
+		providerPrompt.SetChoice(preset.Provider)
+	}
+
 	isNonInteractive := false
 	stackStatus := NameInput
 
@@ -349,18 +384,64 @@ func New(fs afero.Fs, args Args) Model {
 		namePrompt.Blur()
 	}
 
+	// the stack is created without prompting when the name and provider are known, e.g. from Ask
+	if args.StackName != "" && (args.ProviderName != "" || args.Template != "") {
+		isNonInteractive = true
+		stackStatus = Pending
+	}
+
 	return Model{
 		fs:             fs,
 		namePrompt:     namePrompt,
 		providerPrompt: providerPrompt,
 		nonInteractive: isNonInteractive,
 		status:         stackStatus,
+		template:       args.Template,
+		provider:       providerPrompt.Choice(),
 		projectConfig:  projectConfig,
 		spinner:        s,
 		err:            nil,
 	}
 }
 
+// Ask - asks for the stack name and provider missing from args as plain questions, used instead of the interactive prompts in accessible mode
+func Ask(fs afero.Fs, args Args) (Args, error) {
+	nameValidators := slices.Clone(validators.ProjectNameValidators)
+
+	if !args.Force {
+		projectConfig, err := project.ConfigurationFromFile(fs, "")
+		if err != nil {
+			return args, err
+		}
+
+		nameValidators = append(nameValidators, stackNameExistsValidator(projectConfig.Directory))
+	}
+
+	nameValidator := validation.ComposeValidators(nameValidators...)
+
+	if args.StackName == "" {
+		err := tui.AskOne(&survey.Input{Message: i18n.Message("stack.new.name_prompt")}, &args.StackName, survey.WithValidator(func(ans interface{}) error {
+			name, _ := ans.(string)
+			return nameValidator(name)
+		}))
+		if err != nil {
+			return args, err
+		}
+	}
+
+	if args.ProviderName == "" && args.Template == "" {
+		provider := ""
+
+		if err := tui.AskOne(&survey.Select{Message: i18n.Message("stack.new.provider_prompt"), Options: availableProviders}, &provider); err != nil {
+			return args, err
+		}
+
+		args.ProviderName = providerLabelToValue(provider)
+	}
+
+	return args, nil
+}
+
 type stackCreateResultMsg struct {
 	err      error
 	filePath string
@@ -378,6 +459,12 @@ func providerLabelToValue(provider string) string {
 		return "aws-tf"
 	case GcpTf:
 		return "gcp-tf"
+	case Do:
+		return "do"
+	case Cf:
+		return "cloudflare"
+	case K8s:
+		return "kubernetes"
 	}
 
 	return strings.ToLower(provider)
@@ -386,6 +473,15 @@ func providerLabelToValue(provider string) string {
 // createStack returns a command that will create the stack on disk using the inputs gathered
 func (m Model) createStack() tea.Cmd {
 	return func() tea.Msg {
+		if m.template != "" {
+			filePath, err := stack.NewStackFileFromPreset(m.fs, m.template, m.StackName(), "")
+
+			return stackCreateResultMsg{
+				err:      err,
+				filePath: filePath,
+			}
+		}
+
 		filePath, err := stack.NewStackFile(m.fs, providerLabelToValue(m.provider), m.StackName(), "")
 
 		return stackCreateResultMsg{
@@ -18,6 +18,7 @@ package stack_up
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -37,6 +38,7 @@ import (
 
 type Model struct {
 	provider           string
+	regions            []string
 	stack              *stack.Resource
 	defaultParent      *stack.Resource
 	updatesChan        <-chan *deploymentspb.DeploymentUpEvent
@@ -180,6 +182,10 @@ func (m Model) View() string {
 	v.Add(fragments.Tag("up"))
 	v.Add("  Deploying with %s", m.provider)
 
+	if len(m.regions) > 0 {
+		v.Add(" to %s", strings.Join(m.regions, ", "))
+	}
+
 	if m.done {
 		v.Break()
 	} else {
@@ -256,7 +262,7 @@ func (m Model) View() string {
 	return v.Render()
 }
 
-func New(providerName string, stackName string, updatesChan <-chan *deploymentspb.DeploymentUpEvent, providerStdoutChan <-chan string, errorChan <-chan error) Model {
+func New(providerName string, stackName string, regions []string, updatesChan <-chan *deploymentspb.DeploymentUpEvent, providerStdoutChan <-chan string, errorChan <-chan error) Model {
 	orphanParent := &stack.Resource{
 		Name:     fmt.Sprintf("Stack::%s", stackName),
 		Message:  "",
@@ -267,6 +273,7 @@ func New(providerName string, stackName string, updatesChan <-chan *deploymentsp
 
 	return Model{
 		provider:           providerName,
+		regions:            regions,
 		spinner:            spinner.New(spinner.WithSpinner(spinner.Ellipsis)),
 		updatesChan:        updatesChan,
 		providerStdoutChan: providerStdoutChan,
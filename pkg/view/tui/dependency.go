@@ -18,13 +18,14 @@ package tui
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
+
+	"github.com/nitrictech/cli/pkg/docker"
 )
 
 type Dependency struct {
@@ -34,6 +35,9 @@ type Dependency struct {
 	// The command to run for the prequisite
 	command string
 
+	// Checks for the prerequisite when set, instead of running the command
+	check func() error
+
 	// The function to run to help out
 	assist func() error
 }
@@ -43,7 +47,7 @@ var Pulumi = &Dependency{
 	command: "pulumi version",
 	assist: func() error {
 		var resp bool
-		_ = survey.AskOne(&survey.Confirm{
+		_ = AskOne(&survey.Confirm{
 			Message: fmt.Sprintf("Pulumi is required by %s but is not installed, would you like to install it?", "command"),
 			Default: false,
 		}, &resp)
@@ -57,15 +61,15 @@ var Pulumi = &Dependency{
 		switch platform {
 		case "darwin", "linux":
 			cmd := exec.Command("sh", "-c", "curl -fsSL https://get.pulumi.com | sh")
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = output
 			installErr = cmd.Run()
 		case "windows":
 			cmd := exec.Command("powershell", "-NoProfile", "-InputFormat", "None", "-ExecutionPolicy", "Bypass", "-Command", "[Net.ServicePointManager]::SecurityProtocol = [Net.SecurityProtocolType]::Tls12; iex ((New-Object System.Net.WebClient).DownloadString('https://get.pulumi.com/install.ps1'))")
-			cmd.Stdout = os.Stdout
+			cmd.Stdout = output
 			installErr = cmd.Run()
 			if installErr == nil {
 				cmd := exec.Command("powershell", "SET", "PATH='%PATH%;%USERPROFILE%\\.pulumi\\bin'")
-				cmd.Stdout = os.Stdout
+				cmd.Stdout = output
 				installErr = cmd.Run()
 			}
 		default:
@@ -76,22 +80,45 @@ var Pulumi = &Dependency{
 	},
 }
 
+// Docker - a container engine, either docker, podman or nerdctl
 var Docker = &Dependency{
-	name:    "Docker",
-	command: "docker version",
+	name: "Docker",
+	check: func() error {
+		_, err := docker.Discover()
+		return err
+	},
 	assist: func() error {
-		return fmt.Errorf("docker is required to run this command. For installation instructions see: https://docs.docker.com/engine/install/")
+		_, err := docker.Discover()
+
+		return fmt.Errorf("%w. Docker, podman or nerdctl is required to run this command, for docker installation instructions see: https://docs.docker.com/engine/install/", err)
 	},
 }
 
+// DockerBuildx - docker buildx, only required when docker is the container engine
 var DockerBuildx = &Dependency{
-	name:    "Docker Buildx",
-	command: "docker buildx version",
+	name: "Docker Buildx",
+	check: func() error {
+		engine, err := docker.Discover()
+		if err != nil || engine != docker.EngineDocker {
+			return nil
+		}
+
+		return exec.Command("docker", "buildx", "version").Run()
+	},
 	assist: func() error {
 		return fmt.Errorf("docker buildx is required to run this command. For installation instructions see: https://github.com/docker/buildx")
 	},
 }
 
+// Age - the age encryption tool, used to encrypt project secrets files
+var Age = &Dependency{
+	name:    "age",
+	command: "age --version",
+	assist: func() error {
+		return fmt.Errorf("age is required to run this command. For installation instructions see: https://github.com/FiloSottile/age#installation")
+	},
+}
+
 // AddDependencyCheck - Wraps a cobra command with a pre-run that
 // will check for dependencies
 func AddDependencyCheck(cmd *cobra.Command, deps ...*Dependency) *cobra.Command {
@@ -111,10 +138,15 @@ func checkDependencies(deps ...*Dependency) error {
 	missing := make([]*Dependency, 0)
 
 	for _, p := range deps {
-		cmdParts := strings.Split(p.command, " ")
-		cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
+		var err error
+
+		if p.check != nil {
+			err = p.check()
+		} else {
+			cmdParts := strings.Split(p.command, " ")
+			err = exec.Command(cmdParts[0], cmdParts[1:]...).Run()
+		}
 
-		err := cmd.Run()
 		if err != nil {
 			missing = append(missing, p)
 		}
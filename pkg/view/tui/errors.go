@@ -18,11 +18,37 @@ package tui
 
 import (
 	"os"
+	"sync"
+
+	"github.com/nitrictech/cli/pkg/exitcode"
+)
+
+var (
+	exitHooks     []func()
+	exitHooksLock sync.Mutex
 )
 
+// OnExit - registers a function run before CheckErr exits, e.g. to release a lock that deferred calls would otherwise leave held
+func OnExit(hook func()) {
+	exitHooksLock.Lock()
+	defer exitHooksLock.Unlock()
+
+	exitHooks = append(exitHooks, hook)
+}
+
+// CheckErr - prints the error and exits with its exit code, see exitcode.Wrap
 func CheckErr(err error) {
 	if err != nil {
 		Error.Println(err.Error())
-		os.Exit(1)
+
+		exitHooksLock.Lock()
+		hooks := exitHooks
+		exitHooksLock.Unlock()
+
+		for _, hook := range hooks {
+			hook()
+		}
+
+		os.Exit(int(exitcode.Of(err)))
 	}
 }
@@ -35,6 +35,9 @@ var (
 	Warning = TagPrinter{
 		Prefix: addPrefix("warning", Colors.Black, Colors.Yellow),
 	}
+	Info = TagPrinter{
+		Prefix: addPrefix("info", Colors.White, Colors.Blue),
+	}
 
 	width = 0
 )
@@ -44,11 +47,11 @@ type TagPrinter struct {
 }
 
 func (t *TagPrinter) Println(message string) {
-	fmt.Println(t.Prefix, message)
+	fmt.Fprintln(output, t.Prefix, message)
 }
 
 func (t *TagPrinter) Printfln(message string, a ...interface{}) {
-	fmt.Println(t.Prefix, fmt.Sprintf(message, a...))
+	fmt.Fprintln(output, t.Prefix, fmt.Sprintf(message, a...))
 }
 
 func addPrefix(text string, foreground lipgloss.CompleteColor, background lipgloss.CompleteColor) string {
@@ -0,0 +1,250 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/samber/lo"
+	"golang.org/x/term"
+)
+
+var accessible bool
+
+// SetAccessible - enables accessible mode, set with --accessible or the NITRIC_ACCESSIBLE environment variable.
+// In accessible mode prompts are asked as plain sequential questions, answered by typing a value or the number of an option,
+// and output isn't styled, so the CLI can be used with screen readers.
+func SetAccessible(enabled bool) {
+	accessible = enabled
+
+	if Accessible() {
+		lipgloss.SetColorProfile(termenv.Ascii)
+		// styles aren't rendered, so the terminal doesn't need to be queried for its background color
+		lipgloss.SetHasDarkBackground(true)
+	}
+}
+
+// Accessible - returns true when prompts and progress are shown as plain text, see SetAccessible
+func Accessible() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("NITRIC_ACCESSIBLE"))
+
+	return accessible || enabled
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine - reads an answer, returning the default when the answer is blank
+func readLine(defaultValue string) (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return defaultValue, nil
+	}
+
+	return line, nil
+}
+
+// chooseOption - reads the number or value of one of the options
+func chooseOption(options []string, answer string) (string, bool) {
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], true
+	}
+
+	for _, option := range options {
+		if strings.EqualFold(option, answer) {
+			return option, true
+		}
+	}
+
+	return "", false
+}
+
+func printOptions(options []string) {
+	for i, option := range options {
+		fmt.Fprintf(output, "  %d. %s\n", i+1, option)
+	}
+}
+
+// askPlain - asks a survey prompt as a plain question, returning the answer as the type survey would write to the response
+func askPlain(prompt survey.Prompt) (any, error) {
+	switch p := prompt.(type) {
+	case *survey.Confirm:
+		fmt.Fprintf(output, "%s Type yes or no, blank for %s: ", p.Message, lo.Ternary(p.Default, "yes", "no"))
+
+		for {
+			answer, err := readLine(lo.Ternary(p.Default, "yes", "no"))
+			if err != nil {
+				return nil, err
+			}
+
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+				return true, nil
+			case "n", "no":
+				return false, nil
+			}
+
+			fmt.Fprint(output, "Type yes or no: ")
+		}
+	case *survey.Input:
+		fmt.Fprint(output, p.Message)
+
+		if p.Default != "" {
+			fmt.Fprintf(output, " Blank for %s", p.Default)
+		}
+
+		fmt.Fprint(output, ": ")
+
+		return readLine(p.Default)
+	case *survey.Password:
+		fmt.Fprintf(output, "%s: ", p.Message)
+
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			value, err := term.ReadPassword(int(os.Stdin.Fd()))
+
+			fmt.Fprintln(output)
+
+			return string(value), err
+		}
+
+		return readLine("")
+	case *survey.Select:
+		defaultValue, _ := p.Default.(string)
+
+		fmt.Fprintf(output, "%s %d options:\n", p.Message, len(p.Options))
+		printOptions(p.Options)
+
+		for {
+			fmt.Fprint(output, "Type the number of an option")
+
+			if defaultValue != "" {
+				fmt.Fprintf(output, ", blank for %s", defaultValue)
+			}
+
+			fmt.Fprint(output, ": ")
+
+			answer, err := readLine(defaultValue)
+			if err != nil {
+				return nil, err
+			}
+
+			if option, ok := chooseOption(p.Options, answer); ok {
+				return option, nil
+			}
+		}
+	case *survey.MultiSelect:
+		defaults, _ := p.Default.([]string)
+
+		fmt.Fprintf(output, "%s %d options:\n", p.Message, len(p.Options))
+		printOptions(p.Options)
+
+		for {
+			fmt.Fprint(output, "Type the numbers of the options separated by commas")
+
+			if len(defaults) > 0 {
+				fmt.Fprintf(output, ", blank for %s", strings.Join(defaults, ", "))
+			}
+
+			fmt.Fprint(output, ": ")
+
+			answer, err := readLine(strings.Join(defaults, ","))
+			if err != nil {
+				return nil, err
+			}
+
+			chosen := []string{}
+
+			for _, part := range strings.Split(answer, ",") {
+				if strings.TrimSpace(part) == "" {
+					continue
+				}
+
+				if option, ok := chooseOption(p.Options, strings.TrimSpace(part)); ok {
+					chosen = append(chosen, option)
+				}
+			}
+
+			if answer == "" || len(chosen) > 0 {
+				return chosen, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("the %T prompt is not supported in accessible mode", prompt)
+}
+
+// AskOne - asks a survey prompt, as a plain question in accessible mode, see SetAccessible
+func AskOne(prompt survey.Prompt, response any, opts ...survey.AskOpt) error {
+	if !Accessible() {
+		return survey.AskOne(prompt, response, opts...)
+	}
+
+	options := &survey.AskOptions{}
+
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return err
+		}
+	}
+
+	for {
+		answer, err := askPlain(prompt)
+		if err != nil {
+			return err
+		}
+
+		invalid := false
+
+		for _, validate := range options.Validators {
+			if err := validate(answer); err != nil {
+				fmt.Fprintf(output, "Invalid answer, %s\n", err)
+
+				invalid = true
+
+				break
+			}
+		}
+
+		if invalid {
+			continue
+		}
+
+		switch r := response.(type) {
+		case *bool:
+			*r, _ = answer.(bool)
+		case *string:
+			*r, _ = answer.(string)
+		case *[]string:
+			*r, _ = answer.([]string)
+		default:
+			return fmt.Errorf("unsupported prompt response type %T", response)
+		}
+
+		return nil
+	}
+}
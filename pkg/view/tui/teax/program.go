@@ -18,10 +18,21 @@ package teax
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
 )
 
+// output - where the final views of programs, and plain programs, are written
+var output io.Writer = os.Stdout
+
+// SetOutput - sets where the final views of programs, and plain programs, are written
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 // FullViewProgram is a program that will print the full view for the model as the program terminates.
 //
 // Bubbletea programs limit the output of the view to the terminal size, which fixes issues with rerendering,
@@ -38,7 +49,7 @@ func (p *FullViewProgram) Run() (tea.Model, error) {
 	quittingModel := model.(fullHeightModel)
 
 	quittingModel.quitting = false
-	fmt.Println(quittingModel.View())
+	fmt.Fprintln(output, quittingModel.View())
 
 	return quittingModel.Model, err
 }
@@ -46,3 +57,13 @@ func (p *FullViewProgram) Run() (tea.Model, error) {
 func NewProgram(model tea.Model, opts ...tea.ProgramOption) *FullViewProgram {
 	return &FullViewProgram{tea.NewProgram(fullHeightModel{model, false}, opts...)}
 }
+
+// PlainOptions - options running a program without rendering its view or reading input, only the final view is printed by
+// FullViewProgram. The terminal isn't queried for its colors, so nothing but plain text is written.
+func PlainOptions() []tea.ProgramOption {
+	return []tea.ProgramOption{
+		tea.WithoutRenderer(),
+		tea.WithInput(nil),
+		tea.WithOutput(termenv.NewOutput(output, termenv.WithProfile(termenv.Ascii))),
+	}
+}
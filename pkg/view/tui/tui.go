@@ -17,11 +17,29 @@
 package tui
 
 import (
+	"io"
 	"os"
 
 	"github.com/mattn/go-isatty"
+
+	"github.com/nitrictech/cli/pkg/view/tui/teax"
 )
 
+// output - where printers, prompts and programs write progress
+var output io.Writer = os.Stdout
+
+// SetOutput - sets where printers, prompts and programs write progress, e.g. stderr when stdout only contains a command's result
+func SetOutput(w io.Writer) {
+	output = w
+
+	teax.SetOutput(w)
+}
+
+// Output - where printers, prompts and programs write progress
+func Output() io.Writer {
+	return output
+}
+
 // IsTerminal returns true if the current process is running in an interactive terminal
 func IsTerminal() bool {
 	return isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd())
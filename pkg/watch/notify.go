@@ -0,0 +1,62 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// notification - the payload sent to webhooks, the text field makes it compatible with slack and teams incoming webhooks
+type notification struct {
+	Text    string `json:"text"`
+	Healthy bool   `json:"healthy"`
+	Check   *Check `json:"check"`
+}
+
+func notify(ctx context.Context, webhook string, check *Check) error {
+	data, err := json.Marshal(notification{
+		Text:    fmt.Sprintf("[%s] %s", check.Project, check.Summary()),
+		Healthy: check.Healthy(),
+		Check:   check,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send notification to %s: %w", webhook, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to send notification to %s: %s", webhook, resp.Status)
+	}
+
+	return nil
+}
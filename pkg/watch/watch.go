@@ -0,0 +1,236 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/nitrictech/cli/pkg/digest"
+	"github.com/nitrictech/cli/pkg/project/stack"
+)
+
+// EndpointHealth - the result of a health check against a single deployed endpoint
+type EndpointHealth struct {
+	Url        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Healthy    bool   `json:"healthy"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Check - the result of a single drift and health check of a deployed stack
+type Check struct {
+	Project string    `json:"project"`
+	Stack   string    `json:"stack"`
+	Time    time.Time `json:"time"`
+	// Regions the stack was last deployed to
+	Regions []string `json:"regions,omitempty"`
+	// True if no deployment of the stack was found in the local history or the shared digest location
+	NotDeployed bool `json:"notDeployed,omitempty"`
+	// True if only the local deployment history was checked, as no readable shared digest location is configured
+	LocalOnly bool `json:"localOnly,omitempty"`
+	// True if the last deployment of the stack failed
+	DeploymentFailed bool `json:"deploymentFailed,omitempty"`
+	// True if the stack file has changed since the stack was last deployed
+	Drift     bool             `json:"drift"`
+	Endpoints []EndpointHealth `json:"endpoints"`
+}
+
+// Healthy - returns true if the stack is deployed without drift and all of its endpoints are healthy
+func (c *Check) Healthy() bool {
+	if c.NotDeployed || c.DeploymentFailed || c.Drift {
+		return false
+	}
+
+	return !slices.ContainsFunc(c.Endpoints, func(e EndpointHealth) bool { return !e.Healthy })
+}
+
+// Summary - a short human readable description of the check
+func (c *Check) Summary() string {
+	switch {
+	case c.NotDeployed && c.LocalOnly:
+		return fmt.Sprintf("stack %s has not been deployed from this machine", c.Stack)
+	case c.NotDeployed:
+		return fmt.Sprintf("stack %s has not been deployed", c.Stack)
+	case c.DeploymentFailed:
+		return fmt.Sprintf("the last deployment of stack %s failed", c.Stack)
+	}
+
+	stackName := c.Stack
+	if len(c.Regions) > 0 {
+		stackName = fmt.Sprintf("%s (%s)", c.Stack, strings.Join(c.Regions, ", "))
+	}
+
+	summary := fmt.Sprintf("stack %s is healthy", stackName)
+	if !c.Healthy() {
+		summary = fmt.Sprintf("stack %s is unhealthy", stackName)
+	}
+
+	if c.Drift {
+		summary += ", the stack file has changed since it was last deployed"
+	}
+
+	for _, endpoint := range c.Endpoints {
+		if !endpoint.Healthy {
+			summary += fmt.Sprintf(", %s is unreachable", endpoint.Url)
+			if endpoint.Error == "" {
+				summary += fmt.Sprintf(" (%d)", endpoint.StatusCode)
+			}
+		}
+	}
+
+	return summary
+}
+
+// changed - returns true if the health of the stack changed between checks, used to avoid repeated notifications
+func (c *Check) changed(previous *Check) bool {
+	if previous == nil {
+		return !c.Healthy()
+	}
+
+	return c.Summary() != previous.Summary()
+}
+
+type Options struct {
+	Project string
+	Stack   string
+	// Shared location deployment digests are uploaded to, as set under digest in nitric.yaml, so deployments made on other
+	// machines are checked. Only s3:// locations can be read, otherwise only the local deployment history is checked
+	DigestLocation string
+	// Time between checks
+	Interval time.Duration
+	// Endpoints to check in addition to those found in the result of the last deployment
+	Endpoints []string
+	// Webhooks notified when the health of the stack changes
+	Webhooks []string
+	// Called with the result of every check
+	OnCheck func(check *Check)
+	// Called when a notification could not be sent
+	OnError func(err error)
+}
+
+func checkEndpoint(ctx context.Context, url string) EndpointHealth {
+	health := EndpointHealth{Url: url}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	defer resp.Body.Close()
+
+	health.StatusCode = resp.StatusCode
+	// 4xx responses still show the endpoint is up, e.g. routes requiring authentication or unmatched paths
+	health.Healthy = resp.StatusCode < 500
+
+	return health
+}
+
+// CheckStack - runs a single drift and health check of a deployed stack, against its most recent local or shared digest
+func CheckStack(ctx context.Context, fs afero.Fs, opts Options) (*Check, error) {
+	latest, _, err := digest.LatestLocalOrShared(ctx, opts.DigestLocation, opts.Project, opts.Stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return CheckDigest(ctx, fs, opts, latest)
+}
+
+// CheckDigest - runs a single drift and health check of a stack against a given deployment digest, e.g. one uploaded from another machine
+func CheckDigest(ctx context.Context, fs afero.Fs, opts Options, latest *digest.Digest) (*Check, error) {
+	check := &Check{
+		Project:   opts.Project,
+		Stack:     opts.Stack,
+		Time:      time.Now().UTC(),
+		LocalOnly: !digest.Readable(opts.DigestLocation),
+		Endpoints: []EndpointHealth{},
+	}
+
+	if latest == nil {
+		check.NotDeployed = true
+		return check, nil
+	}
+
+	check.DeploymentFailed = !latest.Success
+	check.Regions = latest.Regions
+
+	configHash, err := stack.ConfigHash(fs, opts.Stack)
+	if err != nil {
+		return nil, err
+	}
+
+	check.Drift = latest.ConfigHash != "" && configHash != latest.ConfigHash
+
+	endpoints := append(latest.Endpoints(), opts.Endpoints...)
+	slices.Sort(endpoints)
+
+	for _, url := range slices.Compact(endpoints) {
+		check.Endpoints = append(check.Endpoints, checkEndpoint(ctx, url))
+	}
+
+	return check, nil
+}
+
+// Watch - periodically checks a deployed stack until the context is cancelled, sending notifications when its health changes
+func Watch(ctx context.Context, fs afero.Fs, opts Options) error {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	var previous *Check
+
+	for {
+		check, err := CheckStack(ctx, fs, opts)
+		if err != nil {
+			return err
+		}
+
+		if opts.OnCheck != nil {
+			opts.OnCheck(check)
+		}
+
+		if check.changed(previous) {
+			for _, webhook := range opts.Webhooks {
+				if err := notify(ctx, webhook, check); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+
+		previous = check
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
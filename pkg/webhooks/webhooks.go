@@ -0,0 +1,212 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Tolerance - how old a timestamped signature can be before it's rejected, matching the default of the stripe and slack SDKs
+const Tolerance = 5 * time.Minute
+
+// Scheme - a webhook signing scheme used by a webhook sender
+type Scheme interface {
+	// Name of the scheme, as used in nitric.yaml
+	Name() string
+	// Header containing the signature
+	Header() string
+	// Sign - returns the headers of a signed webhook request
+	Sign(secret string, body []byte, now time.Time) map[string]string
+	// Verify - returns an error if the request headers don't contain a valid signature of the body
+	Verify(secret string, body []byte, headers http.Header, now time.Time) error
+}
+
+func hmacSha256(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyTimestamp(timestamp string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp %s", timestamp)
+	}
+
+	if age := now.Sub(time.Unix(seconds, 0)); age > Tolerance || age < -Tolerance {
+		return fmt.Errorf("signature timestamp is outside the tolerance of %s", Tolerance)
+	}
+
+	return nil
+}
+
+// stripe signs the timestamp and body, https://docs.stripe.com/webhooks#verify-manually
+type stripe struct{}
+
+func (stripe) Name() string   { return "stripe" }
+func (stripe) Header() string { return "Stripe-Signature" }
+
+func (s stripe) Sign(secret string, body []byte, now time.Time) map[string]string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	return map[string]string{
+		s.Header(): fmt.Sprintf("t=%s,v1=%s", timestamp, hmacSha256(secret, timestamp+"."+string(body))),
+	}
+}
+
+func (s stripe) Verify(secret string, body []byte, headers http.Header, now time.Time) error {
+	timestamp := ""
+	signatures := []string{}
+
+	for _, part := range strings.Split(headers.Get(s.Header()), ",") {
+		key, value, _ := strings.Cut(part, "=")
+
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("missing %s header", s.Header())
+	}
+
+	if err := verifyTimestamp(timestamp, now); err != nil {
+		return err
+	}
+
+	expected := hmacSha256(secret, timestamp+"."+string(body))
+
+	if !lo.ContainsBy(signatures, func(signature string) bool { return hmac.Equal([]byte(signature), []byte(expected)) }) {
+		return fmt.Errorf("signature doesn't match the body")
+	}
+
+	return nil
+}
+
+// github signs the body, https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+type github struct{}
+
+func (github) Name() string   { return "github" }
+func (github) Header() string { return "X-Hub-Signature-256" }
+
+func (g github) Sign(secret string, body []byte, now time.Time) map[string]string {
+	return map[string]string{
+		g.Header(): "sha256=" + hmacSha256(secret, string(body)),
+	}
+}
+
+func (g github) Verify(secret string, body []byte, headers http.Header, now time.Time) error {
+	return verifyHeader(headers.Get(g.Header()), g.Header(), "sha256="+hmacSha256(secret, string(body)))
+}
+
+// slack signs the version, timestamp and body, https://api.slack.com/authentication/verifying-requests-from-slack
+type slack struct{}
+
+const slackTimestampHeader = "X-Slack-Request-Timestamp"
+
+func (slack) Name() string   { return "slack" }
+func (slack) Header() string { return "X-Slack-Signature" }
+
+func (s slack) Sign(secret string, body []byte, now time.Time) map[string]string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	return map[string]string{
+		slackTimestampHeader: timestamp,
+		s.Header():           "v0=" + hmacSha256(secret, "v0:"+timestamp+":"+string(body)),
+	}
+}
+
+func (s slack) Verify(secret string, body []byte, headers http.Header, now time.Time) error {
+	timestamp := headers.Get(slackTimestampHeader)
+	if timestamp == "" {
+		return fmt.Errorf("missing %s header", slackTimestampHeader)
+	}
+
+	if err := verifyTimestamp(timestamp, now); err != nil {
+		return err
+	}
+
+	return verifyHeader(headers.Get(s.Header()), s.Header(), "v0="+hmacSha256(secret, "v0:"+timestamp+":"+string(body)))
+}
+
+// hmacScheme signs the body with an HMAC-SHA256 signature in a configurable header, used by many other senders
+type hmacScheme struct {
+	header string
+}
+
+// DefaultHmacHeader - the header used by the hmac-sha256 scheme when none is configured
+const DefaultHmacHeader = "X-Webhook-Signature"
+
+func (hmacScheme) Name() string     { return "hmac-sha256" }
+func (h hmacScheme) Header() string { return h.header }
+
+func (h hmacScheme) Sign(secret string, body []byte, now time.Time) map[string]string {
+	return map[string]string{
+		h.Header(): "sha256=" + hmacSha256(secret, string(body)),
+	}
+}
+
+func (h hmacScheme) Verify(secret string, body []byte, headers http.Header, now time.Time) error {
+	// accept signatures with or without the algorithm prefix
+	signature := strings.TrimPrefix(headers.Get(h.Header()), "sha256=")
+
+	return verifyHeader(signature, h.Header(), hmacSha256(secret, string(body)))
+}
+
+func verifyHeader(actual string, header string, expected string) error {
+	if actual == "" {
+		return fmt.Errorf("missing %s header", header)
+	}
+
+	if !hmac.Equal([]byte(actual), []byte(expected)) {
+		return fmt.Errorf("signature doesn't match the body")
+	}
+
+	return nil
+}
+
+// SchemeNames - the names of the supported signing schemes
+var SchemeNames = []string{"stripe", "github", "slack", "hmac-sha256"}
+
+// GetScheme - returns the named signing scheme, header is only used by the hmac-sha256 scheme and defaults to DefaultHmacHeader
+func GetScheme(name string, header string) (Scheme, error) {
+	switch name {
+	case "stripe":
+		return stripe{}, nil
+	case "github":
+		return github{}, nil
+	case "slack":
+		return slack{}, nil
+	case "hmac-sha256":
+		return hmacScheme{header: lo.Ternary(header != "", header, DefaultHmacHeader)}, nil
+	default:
+		return nil, fmt.Errorf("unknown webhook signing scheme %s, supported schemes are: %s", name, strings.Join(SchemeNames, ", "))
+	}
+}
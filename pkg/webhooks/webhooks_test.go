@@ -0,0 +1,206 @@
+// Copyright Nitric Pty Ltd.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhooks
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+
+	body := []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	now := time.Unix(1700000000, 0)
+
+	for _, tt := range []struct {
+		name string
+		// signs and then alters the request, returning the body and headers that are verified
+		request func(scheme Scheme) ([]byte, http.Header)
+		// schemes that reject the request, all others accept it
+		rejectedBy []string
+	}{
+		{
+			name: "valid signature",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return body, signedHeaders(scheme, secret, body, now)
+			},
+		},
+		{
+			name: "tampered body",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return []byte(`{"id":"evt_1","type":"payment_intent.failed"}`), signedHeaders(scheme, secret, body, now)
+			},
+			rejectedBy: SchemeNames,
+		},
+		{
+			name: "tampered signature",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				headers := signedHeaders(scheme, secret, body, now)
+				signature := headers.Get(scheme.Header())
+				headers.Set(scheme.Header(), signature[:len(signature)-1]+lastHexDigitSwapped(signature))
+
+				return body, headers
+			},
+			rejectedBy: SchemeNames,
+		},
+		{
+			name: "wrong secret",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return body, signedHeaders(scheme, "whsec_other", body, now)
+			},
+			rejectedBy: SchemeNames,
+		},
+		{
+			name: "missing signature",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				headers := signedHeaders(scheme, secret, body, now)
+				headers.Del(scheme.Header())
+
+				return body, headers
+			},
+			rejectedBy: SchemeNames,
+		},
+		{
+			name: "signed within the tolerance",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return body, signedHeaders(scheme, secret, body, now.Add(-Tolerance+time.Second))
+			},
+		},
+		{
+			name: "signed before the tolerance",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return body, signedHeaders(scheme, secret, body, now.Add(-Tolerance-time.Second))
+			},
+			rejectedBy: []string{"stripe", "slack"},
+		},
+		{
+			name: "signed after the tolerance",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				return body, signedHeaders(scheme, secret, body, now.Add(Tolerance+time.Second))
+			},
+			rejectedBy: []string{"stripe", "slack"},
+		},
+		{
+			name: "replayed signature with a new timestamp",
+			request: func(scheme Scheme) ([]byte, http.Header) {
+				headers := signedHeaders(scheme, secret, body, now.Add(-time.Hour))
+				fresh := signedHeaders(scheme, secret, body, now)
+
+				switch scheme.Name() {
+				case "stripe":
+					_, signature, _ := strings.Cut(headers.Get(scheme.Header()), ",")
+					freshTimestamp, _, _ := strings.Cut(fresh.Get(scheme.Header()), ",")
+					headers.Set(scheme.Header(), freshTimestamp+","+signature)
+				case "slack":
+					headers.Set(slackTimestampHeader, fresh.Get(slackTimestampHeader))
+				}
+
+				return body, headers
+			},
+			rejectedBy: []string{"stripe", "slack"},
+		},
+	} {
+		for _, name := range SchemeNames {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				scheme, err := GetScheme(name, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				requestBody, headers := tt.request(scheme)
+				err = scheme.Verify(secret, requestBody, headers, now)
+
+				rejected := false
+				for _, rejectedBy := range tt.rejectedBy {
+					rejected = rejected || rejectedBy == name
+				}
+
+				if rejected && err == nil {
+					t.Error("expected the request to be rejected")
+				}
+
+				if !rejected && err != nil {
+					t.Errorf("expected the request to be accepted, got: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyKnownSignatures(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		scheme  string
+		secret  string
+		body    string
+		headers map[string]string
+	}{
+		{
+			// example from https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+			name:    "github documentation example",
+			scheme:  "github",
+			secret:  "It's a Secret to Everybody",
+			body:    "Hello, World!",
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"},
+		},
+		{
+			name:    "hmac-sha256 without the algorithm prefix",
+			scheme:  "hmac-sha256",
+			secret:  "It's a Secret to Everybody",
+			body:    "Hello, World!",
+			headers: map[string]string{DefaultHmacHeader: "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := GetScheme(tt.scheme, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			headers := http.Header{}
+			for key, value := range tt.headers {
+				headers.Set(key, value)
+			}
+
+			if err := scheme.Verify(tt.secret, []byte(tt.body), headers, time.Now()); err != nil {
+				t.Errorf("expected the signature to be valid, got: %v", err)
+			}
+		})
+	}
+}
+
+func signedHeaders(scheme Scheme, secret string, body []byte, at time.Time) http.Header {
+	headers := http.Header{}
+
+	for key, value := range scheme.Sign(secret, body, at) {
+		headers.Set(key, value)
+	}
+
+	return headers
+}
+
+// lastHexDigitSwapped - a different hex digit to the last character of the signature
+func lastHexDigitSwapped(signature string) string {
+	if strings.HasSuffix(signature, "0") {
+		return "1"
+	}
+
+	return "0"
+}